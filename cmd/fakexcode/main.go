@@ -0,0 +1,153 @@
+// Command fakexcode stands in for xcodebuild, xcrun, simctl, xcresulttool
+// and xccov so the server can be tested end to end where Xcode is missing.
+// Link or copy it under those names into a directory ahead of the real
+// tools in PATH; it emulates the tool it was run as, or the one named by
+// its first argument when run as fakexcode.
+//
+// FAKEXCODE_SCENARIO names a JSON scenario file describing the project,
+// the simulators and how build, test and clean end: success, compile
+// errors, test failures, a crash or a hang. Simulator changes made by
+// simctl (boot, install, ...) are kept in the file named by FAKEXCODE_STATE
+// so they carry over to the next invocation.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+func main() {
+	tool, args := filepath.Base(os.Args[0]), os.Args[1:]
+	if tool == "fakexcode" {
+		if len(args) == 0 {
+			fmt.Fprintln(os.Stderr, "usage: fakexcode <xcodebuild|xcrun|simctl|xcresulttool|xccov> [args...]")
+			os.Exit(64)
+		}
+		tool, args = args[0], args[1:]
+	}
+
+	f, err := newFake(os.Getenv("FAKEXCODE_SCENARIO"), os.Getenv("FAKEXCODE_STATE"), os.Stdout, os.Stderr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "fakexcode: %v\n", err)
+		os.Exit(1)
+	}
+	os.Exit(f.run(tool, args))
+}
+
+// fake emulates the Xcode tools for one invocation
+type fake struct {
+	scenario *Scenario
+	state    *state
+	stdout   io.Writer
+	stderr   io.Writer
+	// now is the time reported in logs and result bundles
+	now func() time.Time
+}
+
+func newFake(scenarioPath, statePath string, stdout, stderr io.Writer) (*fake, error) {
+	scenario, err := loadScenario(scenarioPath)
+	if err != nil {
+		return nil, err
+	}
+	state, err := loadState(statePath, scenario)
+	if err != nil {
+		return nil, err
+	}
+	return &fake{
+		scenario: scenario,
+		state:    state,
+		stdout:   stdout,
+		stderr:   stderr,
+		now:      time.Now,
+	}, nil
+}
+
+// run emulates tool and returns its exit status
+func (f *fake) run(tool string, args []string) int {
+	switch tool {
+	case "xcodebuild":
+		return f.xcodebuild(args)
+	case "xcrun":
+		return f.xcrun(args)
+	case "simctl":
+		return f.simctl(args)
+	case "xcresulttool":
+		return f.xcresulttool(args)
+	case "xccov":
+		return f.xccov(args)
+	default:
+		fmt.Fprintf(f.stderr, "fakexcode: %s is not emulated\n", tool)
+		return 64
+	}
+}
+
+// xcrun runs the developer tool named by its first argument
+func (f *fake) xcrun(args []string) int {
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		switch args[0] {
+		case "--sdk", "-sdk", "--toolchain", "-toolchain":
+			if len(args) < 2 {
+				fmt.Fprintf(f.stderr, "xcrun: error: missing argument for %s\n", args[0])
+				return 64
+			}
+			args = args[2:]
+		case "--find", "-f":
+			if len(args) < 2 {
+				fmt.Fprintf(f.stderr, "xcrun: error: missing argument for %s\n", args[0])
+				return 64
+			}
+			fmt.Fprintln(f.stdout, filepath.Join(filepath.Dir(os.Args[0]), args[1]))
+			return 0
+		default:
+			args = args[1:]
+		}
+	}
+	if len(args) == 0 {
+		fmt.Fprintln(f.stderr, "xcrun: error: no developer tool specified")
+		return 64
+	}
+
+	switch args[0] {
+	case "xcodebuild", "simctl", "xcresulttool", "xccov":
+		return f.run(args[0], args[1:])
+	default:
+		fmt.Fprintf(f.stderr, "xcrun: error: unable to find utility %q, not a developer tool or in PATH\n", args[0])
+		return 72
+	}
+}
+
+// finish ends an action: after its delay it hangs, crashes or exits with
+// the action's exit code, or with code when it sets none
+func (f *fake) finish(action Action, code int) int {
+	if action.Delay > 0 {
+		time.Sleep(time.Duration(action.Delay * float64(time.Second)))
+	}
+	if action.Hang {
+		// A sleeping timer keeps the runtime from reporting a deadlock
+		for {
+			time.Sleep(time.Hour)
+		}
+	}
+	if action.Crash != "" {
+		return f.crash(action.Crash)
+	}
+	if action.ExitCode != nil {
+		return *action.ExitCode
+	}
+	return code
+}
+
+// crash replaces the process with a shell that kills itself with the
+// signal named sig, so the parent sees this process die by that signal
+// rather than the Go runtime's handling of it
+func (f *fake) crash(sig string) int {
+	sig = strings.TrimPrefix(strings.ToUpper(sig), "SIG")
+	err := syscall.Exec("/bin/sh", []string{"sh", "-c", "kill -" + sig + " $$"}, os.Environ())
+	fmt.Fprintf(f.stderr, "fakexcode: failed to crash with %s: %v\n", sig, err)
+	return 1
+}
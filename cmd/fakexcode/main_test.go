@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// runFake runs tool with args against scenario and returns its exit status
+// and output
+func runFake(t *testing.T, scenario, statePath, tool string, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	f, err := newFake(scenario, statePath, &stdout, &stderr)
+	if err != nil {
+		t.Fatalf("newFake failed: %v", err)
+	}
+	f.now = func() time.Time { return time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC) }
+	code := f.run(tool, args)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestXcodebuild_List(t *testing.T) {
+	code, stdout, _ := runFake(t, "testdata/success.json", "", "xcodebuild", "-list", "-project", "MyApp.xcodeproj")
+	if code != 0 {
+		t.Fatalf("exit status %d", code)
+	}
+	for _, want := range []string{`Information about project "MyApp":`, "        MyAppTests\n", "    Schemes:\n        MyApp\n"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("output missing %q:\n%s", want, stdout)
+		}
+	}
+}
+
+func TestXcodebuild_Build(t *testing.T) {
+	tests := []struct {
+		name     string
+		scenario string
+		args     []string
+		wantCode int
+		want     []string
+	}{
+		{
+			name:     "success with warning",
+			scenario: "testdata/success.json",
+			args:     []string{"build", "-project", "MyApp.xcodeproj", "-scheme", "MyApp"},
+			want: []string{
+				"=== BUILD TARGET MyApp OF PROJECT MyApp WITH CONFIGURATION Debug ===",
+				"/fake/MyApp/ContentView.swift:12:9: warning: initialization of immutable value 'unused' was never used",
+				"** BUILD SUCCEEDED **",
+			},
+		},
+		{
+			name:     "compile errors",
+			scenario: "testdata/compile_errors.json",
+			args:     []string{"build", "-project", "MyApp.xcodeproj", "-scheme", "MyApp"},
+			wantCode: 65,
+			want: []string{
+				"/fake/MyApp/LoginView.swift:42:17: error: cannot find 'usernmae' in scope",
+				"The following build commands failed:",
+				"** BUILD FAILED **",
+			},
+		},
+		{
+			name:     "clean",
+			scenario: "testdata/success.json",
+			args:     []string{"clean", "-project", "MyApp.xcodeproj", "-scheme", "MyApp"},
+			want:     []string{"** CLEAN SUCCEEDED **"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, stdout, stderr := runFake(t, tt.scenario, "", "xcodebuild", tt.args...)
+			if code != tt.wantCode {
+				t.Fatalf("exit status %d, want %d\n%s%s", code, tt.wantCode, stdout, stderr)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(stdout, want) {
+					t.Errorf("output missing %q:\n%s", want, stdout)
+				}
+			}
+		})
+	}
+}
+
+func TestXcodebuild_SetupErrors(t *testing.T) {
+	code, _, stderr := runFake(t, "testdata/success.json", "", "xcodebuild", "build", "-scheme", "Nope")
+	if code != 65 || !strings.Contains(stderr, `does not contain a scheme named "Nope"`) {
+		t.Errorf("missing scheme: exit status %d, stderr %q", code, stderr)
+	}
+
+	code, _, stderr = runFake(t, "testdata/success.json", "", "xcodebuild", "build", "-scheme", "MyApp",
+		"-destination", "platform=iOS Simulator,name=iPhone 99")
+	if code != 70 || !strings.Contains(stderr, "Unable to find a destination matching the provided destination specifier") {
+		t.Errorf("missing destination: exit status %d, stderr %q", code, stderr)
+	}
+}
+
+func TestXcodebuild_TestAndResultBundle(t *testing.T) {
+	bundle := filepath.Join(t.TempDir(), "Test.xcresult")
+	code, stdout, _ := runFake(t, "testdata/test_failures.json", "", "xcodebuild", "test", "-scheme", "MyApp",
+		"-destination", "platform=iOS Simulator,id=A1B2C3D4-0000-4000-8000-000000000001", "-resultBundlePath", bundle)
+	if code != 65 {
+		t.Fatalf("exit status %d, want 65", code)
+	}
+	for _, want := range []string{
+		"Test Case '-[MyAppTests.LoginTests testValidLogin]' passed (0.012 seconds).",
+		"/fake/MyAppTests/LoginTests.swift:27: error: -[MyAppTests.LoginTests testInvalidPassword] : XCTAssertEqual failed",
+		"Test Case '-[MyAppTests.CartTests testCheckout]' skipped",
+		"** TEST FAILED **",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("output missing %q:\n%s", want, stdout)
+		}
+	}
+
+	code, stdout, stderr := runFake(t, "testdata/test_failures.json", "", "xcrun", "xcresulttool", "get", "test-results", "summary", "--path", bundle)
+	if code != 0 {
+		t.Fatalf("xcresulttool exit status %d: %s", code, stderr)
+	}
+	var summary struct {
+		Result         string `json:"result"`
+		TotalTestCount int    `json:"totalTestCount"`
+		FailedTests    int    `json:"failedTests"`
+		SkippedTests   int    `json:"skippedTests"`
+		TestFailures   []struct {
+			FailureText string `json:"failureText"`
+		} `json:"testFailures"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &summary); err != nil {
+		t.Fatalf("summary is not JSON: %v", err)
+	}
+	if summary.Result != "Failed" || summary.TotalTestCount != 3 || summary.FailedTests != 1 || summary.SkippedTests != 1 {
+		t.Errorf("summary = %+v", summary)
+	}
+	if len(summary.TestFailures) != 1 || !strings.HasPrefix(summary.TestFailures[0].FailureText, "LoginTests.swift:27: ") {
+		t.Errorf("test failures = %+v", summary.TestFailures)
+	}
+
+	// -only-testing narrows the run to one suite
+	only := filepath.Join(t.TempDir(), "Only.xcresult")
+	code, stdout, _ = runFake(t, "testdata/test_failures.json", "", "xcodebuild", "test", "-scheme", "MyApp",
+		"-only-testing", "MyAppTests/CartTests", "-resultBundlePath", only)
+	if code != 0 || strings.Contains(stdout, "LoginTests") {
+		t.Errorf("-only-testing: exit status %d\n%s", code, stdout)
+	}
+}
+
+func TestSimctl_State(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	udid := "A1B2C3D4-0000-4000-8000-000000000001"
+
+	code, stdout, _ := runFake(t, "testdata/success.json", statePath, "xcrun", "simctl", "list", "devices", "--json")
+	if code != 0 || !strings.Contains(stdout, `"state" : "Shutdown"`) {
+		t.Fatalf("list: exit status %d\n%s", code, stdout)
+	}
+
+	if code, _, stderr := runFake(t, "testdata/success.json", statePath, "xcrun", "simctl", "install", udid, "/tmp/MyApp.app"); code != 149 ||
+		!strings.Contains(stderr, "current state: Shutdown") {
+		t.Errorf("install on a shutdown device: exit status %d, stderr %q", code, stderr)
+	}
+
+	if code, _, stderr := runFake(t, "testdata/success.json", statePath, "xcrun", "simctl", "boot", udid); code != 0 {
+		t.Fatalf("boot: exit status %d: %s", code, stderr)
+	}
+	if code, _, stderr := runFake(t, "testdata/success.json", statePath, "xcrun", "simctl", "boot", udid); code != 149 ||
+		!strings.Contains(stderr, "Unable to boot device in current state: Booted") {
+		t.Errorf("second boot: exit status %d, stderr %q", code, stderr)
+	}
+
+	app := filepath.Join(t.TempDir(), "MyApp.app")
+	if err := os.MkdirAll(app, 0755); err != nil {
+		t.Fatal(err)
+	}
+	infoPlist := `<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0"><dict><key>CFBundleIdentifier</key><string>com.example.MyApp</string></dict></plist>`
+	if err := os.WriteFile(filepath.Join(app, "Info.plist"), []byte(infoPlist), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if code, _, stderr := runFake(t, "testdata/success.json", statePath, "xcrun", "simctl", "install", udid, app); code != 0 {
+		t.Fatalf("install: exit status %d: %s", code, stderr)
+	}
+
+	code, stdout, _ = runFake(t, "testdata/success.json", statePath, "xcrun", "simctl", "launch", udid, "com.example.MyApp")
+	if code != 0 || !strings.HasPrefix(stdout, "com.example.MyApp: ") {
+		t.Errorf("launch: exit status %d, stdout %q", code, stdout)
+	}
+	if code, _, stderr := runFake(t, "testdata/success.json", statePath, "xcrun", "simctl", "launch", udid, "com.example.Other"); code != 4 ||
+		!strings.Contains(stderr, "App is not installed") {
+		t.Errorf("launch of a missing app: exit status %d, stderr %q", code, stderr)
+	}
+
+	shot := filepath.Join(t.TempDir(), "shot.png")
+	if code, _, stderr := runFake(t, "testdata/success.json", statePath, "xcrun", "simctl", "io", udid, "screenshot", shot); code != 0 {
+		t.Fatalf("screenshot: exit status %d: %s", code, stderr)
+	}
+	if data, err := os.ReadFile(shot); err != nil || !bytes.HasPrefix(data, []byte("\x89PNG")) {
+		t.Errorf("screenshot is not a PNG: %v", err)
+	}
+
+	if code, _, stderr := runFake(t, "testdata/success.json", statePath, "xcrun", "simctl", "boot", "NOPE"); code != 148 ||
+		!strings.Contains(stderr, "Invalid device: NOPE") {
+		t.Errorf("unknown device: exit status %d, stderr %q", code, stderr)
+	}
+}
+
+func TestXcrun_UnknownTool(t *testing.T) {
+	code, _, stderr := runFake(t, "", "", "xcrun", "--sdk", "iphonesimulator", "notatool")
+	if code != 72 || !strings.Contains(stderr, `unable to find utility "notatool"`) {
+		t.Errorf("exit status %d, stderr %q", code, stderr)
+	}
+}
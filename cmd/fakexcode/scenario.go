@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Scenario describes the Xcode installation fakexcode pretends to be: the
+// project it builds, the simulators it knows and how each xcodebuild
+// action ends
+type Scenario struct {
+	// Xcode is the version reported by xcodebuild -version
+	Xcode      string      `json:"xcode,omitempty"`
+	Project    Project     `json:"project"`
+	Simulators []Simulator `json:"simulators,omitempty"`
+	Build      Action      `json:"build"`
+	Test       Action      `json:"test"`
+	Clean      Action      `json:"clean"`
+	// Logs are the lines simctl spawn prints for log show and log stream
+	Logs []string `json:"logs,omitempty"`
+}
+
+// Project is what xcodebuild -list and -showBuildSettings report. A scheme
+// outside Schemes fails like a missing scheme does, unless Schemes is empty.
+type Project struct {
+	Name           string            `json:"name,omitempty"`
+	Schemes        []string          `json:"schemes,omitempty"`
+	Targets        []string          `json:"targets,omitempty"`
+	Configurations []string          `json:"configurations,omitempty"`
+	BuildSettings  map[string]string `json:"build_settings,omitempty"`
+}
+
+type Simulator struct {
+	UDID       string `json:"udid"`
+	Name       string `json:"name"`
+	Runtime    string `json:"runtime,omitempty"`
+	DeviceType string `json:"device_type,omitempty"`
+	State      string `json:"state,omitempty"`
+	// Apps are the bundle identifiers installed before the scenario starts
+	Apps []string `json:"apps,omitempty"`
+}
+
+// Action is how an xcodebuild action ends. Without errors, failed tests,
+// a crash or a hang it succeeds.
+type Action struct {
+	Errors   []Issue    `json:"errors,omitempty"`
+	Warnings []Issue    `json:"warnings,omitempty"`
+	Tests    []TestCase `json:"tests,omitempty"`
+	// Output lines are printed after the build steps
+	Output []string `json:"output,omitempty"`
+	// Delay is the number of seconds to wait before finishing
+	Delay float64 `json:"delay,omitempty"`
+	// Crash names the signal (SEGV, ABRT, KILL, ...) the process dies by
+	// once its output is printed
+	Crash string `json:"crash,omitempty"`
+	// Hang keeps the process running until it is killed
+	Hang bool `json:"hang,omitempty"`
+	// ExitCode replaces the exit status the action would end with
+	ExitCode *int `json:"exit_code,omitempty"`
+	// Coverage is printed as is by xccov view --report --json for the
+	// result bundle of the action
+	Coverage json.RawMessage `json:"coverage,omitempty"`
+}
+
+// Issue is a compiler error or warning
+type Issue struct {
+	File    string `json:"file"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message"`
+	Target  string `json:"target,omitempty"`
+}
+
+// TestCase is one test of a test action. Result is passed, failed or
+// skipped; failures and skips report Message at File:Line.
+type TestCase struct {
+	Target   string  `json:"target"`
+	Class    string  `json:"class"`
+	Name     string  `json:"name"`
+	Result   string  `json:"result,omitempty"`
+	Duration float64 `json:"duration,omitempty"`
+	Message  string  `json:"message,omitempty"`
+	File     string  `json:"file,omitempty"`
+	Line     int     `json:"line,omitempty"`
+}
+
+func (t TestCase) failed() bool {
+	return t.Result == "failed"
+}
+
+func (t TestCase) skipped() bool {
+	return t.Result == "skipped"
+}
+
+// loadScenario reads the scenario at path, or returns an empty scenario,
+// in which everything succeeds, when path is empty
+func loadScenario(path string) (*Scenario, error) {
+	scenario := &Scenario{}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read scenario: %w", err)
+		}
+		if err := json.Unmarshal(data, scenario); err != nil {
+			return nil, fmt.Errorf("failed to parse scenario %s: %w", path, err)
+		}
+	}
+
+	if scenario.Xcode == "" {
+		scenario.Xcode = "16.0"
+	}
+	if scenario.Project.Name == "" {
+		scenario.Project.Name = "App"
+	}
+	if len(scenario.Project.Configurations) == 0 {
+		scenario.Project.Configurations = []string{"Debug", "Release"}
+	}
+	for i := range scenario.Simulators {
+		sim := &scenario.Simulators[i]
+		if sim.Runtime == "" {
+			sim.Runtime = "com.apple.CoreSimulator.SimRuntime.iOS-18-0"
+		}
+		if sim.DeviceType == "" {
+			sim.DeviceType = "com.apple.CoreSimulator.SimDeviceType." + strings.ReplaceAll(sim.Name, " ", "-")
+		}
+		if sim.State == "" {
+			sim.State = "Shutdown"
+		}
+	}
+	for _, action := range []*Action{&scenario.Build, &scenario.Test, &scenario.Clean} {
+		for i := range action.Tests {
+			if action.Tests[i].Result == "" {
+				action.Tests[i].Result = "passed"
+			}
+		}
+	}
+	return scenario, nil
+}
+
+// deviceState is what simctl has changed about a simulator
+type deviceState struct {
+	State string `json:"state"`
+	// Apps maps the bundle identifier of each installed app to its path
+	Apps map[string]string `json:"apps"`
+}
+
+// state is kept in a file between invocations, so a simulator booted by
+// one simctl call is booted for the next
+type state struct {
+	path    string
+	Devices map[string]*deviceState `json:"devices"`
+}
+
+// loadState reads the state at path on top of the simulators of scenario.
+// Without a path changes last only as long as the process.
+func loadState(path string, scenario *Scenario) (*state, error) {
+	s := &state{path: path, Devices: map[string]*deviceState{}}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read state: %w", err)
+		}
+		if err == nil {
+			if err := json.Unmarshal(data, s); err != nil {
+				return nil, fmt.Errorf("failed to parse state %s: %w", path, err)
+			}
+		}
+	}
+
+	for _, sim := range scenario.Simulators {
+		if _, ok := s.Devices[sim.UDID]; ok {
+			continue
+		}
+		device := &deviceState{State: sim.State, Apps: map[string]string{}}
+		for _, app := range sim.Apps {
+			device.Apps[app] = ""
+		}
+		s.Devices[sim.UDID] = device
+	}
+	return s, nil
+}
+
+func (s *state) save() error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0644)
+}
@@ -0,0 +1,439 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/jontolof/xcode-build-mcp/internal/plist"
+)
+
+// Screenshot size in pixels
+const (
+	screenWidth  = 390
+	screenHeight = 844
+)
+
+// simctlKeyRegex finds the object keys simctl prints as "key" : value
+var simctlKeyRegex = regexp.MustCompile(`(?m)^(\s*"(?:[^"\\]|\\.)*"): `)
+
+func (f *fake) simctl(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(f.stderr, "usage: simctl [--set <path>] [--profiles <path>] <subcommand> ...")
+		return 64
+	}
+
+	command, args := args[0], args[1:]
+	switch command {
+	case "list":
+		return f.simctlList(args)
+	case "boot", "shutdown", "erase":
+		return f.simctlState(command, args)
+	case "install":
+		return f.simctlInstall(args)
+	case "uninstall":
+		return f.simctlUninstall(args)
+	case "launch":
+		return f.simctlLaunch(args)
+	case "terminate", "openurl":
+		_, _, code := f.bootedDevice(args)
+		return code
+	case "listapps":
+		return f.simctlListApps(args)
+	case "appinfo":
+		return f.simctlAppInfo(args)
+	case "io":
+		return f.simctlIO(args)
+	case "spawn":
+		return f.simctlSpawn(args)
+	default:
+		fmt.Fprintf(f.stderr, "fakexcode: simctl %s is not emulated\n", command)
+		return 64
+	}
+}
+
+// device finds the simulator with udid, or the first booted one for
+// "booted", printing simctl's error when there is none
+func (f *fake) device(udid string) (*Simulator, *deviceState, int) {
+	for i := range f.scenario.Simulators {
+		sim := &f.scenario.Simulators[i]
+		device := f.state.Devices[sim.UDID]
+		if sim.UDID == udid || (udid == "booted" && device.State == "Booted") {
+			return sim, device, 0
+		}
+	}
+	if udid == "booted" {
+		fmt.Fprintln(f.stderr, "No devices are booted.")
+		return nil, nil, 149
+	}
+	fmt.Fprintf(f.stderr, "Invalid device: %s\n", udid)
+	return nil, nil, 148
+}
+
+// bootedDevice finds the simulator named by the first of args and requires
+// it to be booted
+func (f *fake) bootedDevice(args []string) (*Simulator, *deviceState, int) {
+	if len(args) == 0 {
+		fmt.Fprintln(f.stderr, "error: a device is required")
+		return nil, nil, 64
+	}
+	sim, device, code := f.device(args[0])
+	if code != 0 {
+		return nil, nil, code
+	}
+	if device.State != "Booted" {
+		return nil, nil, f.simError(405, "Unable to lookup in current state: "+device.State)
+	}
+	return sim, device, 0
+}
+
+func (f *fake) simError(code int, message string) int {
+	fmt.Fprintf(f.stderr, "An error was encountered processing the command (domain=com.apple.CoreSimulator.SimError, code=%d):\n%s\n", code, message)
+	return 149
+}
+
+func (f *fake) simctlList(args []string) int {
+	kind, asJSON := "devices", false
+	for _, arg := range args {
+		switch arg {
+		case "-j", "--json":
+			asJSON = true
+		case "devices", "runtimes", "devicetypes":
+			kind = arg
+		}
+	}
+
+	runtimes := uniqueRuntimes(f.scenario.Simulators)
+	if !asJSON {
+		if kind == "devices" {
+			fmt.Fprintln(f.stdout, "== Devices ==")
+			for _, runtime := range runtimes {
+				fmt.Fprintf(f.stdout, "-- %s --\n", runtimeName(runtime))
+				for _, sim := range f.scenario.Simulators {
+					if sim.Runtime == runtime {
+						fmt.Fprintf(f.stdout, "    %s (%s) (%s) \n", sim.Name, sim.UDID, f.state.Devices[sim.UDID].State)
+					}
+				}
+			}
+		}
+		return 0
+	}
+
+	var list interface{}
+	switch kind {
+	case "runtimes":
+		entries := []map[string]interface{}{}
+		for _, runtime := range runtimes {
+			name := runtimeName(runtime)
+			platform, version, _ := strings.Cut(name, " ")
+			entries = append(entries, map[string]interface{}{
+				"identifier":  runtime,
+				"name":        name,
+				"platform":    platform,
+				"version":     version,
+				"isAvailable": true,
+			})
+		}
+		list = map[string]interface{}{"runtimes": entries}
+	case "devicetypes":
+		entries := []map[string]interface{}{}
+		for _, sim := range f.scenario.Simulators {
+			entries = append(entries, map[string]interface{}{"identifier": sim.DeviceType, "name": sim.Name})
+		}
+		list = map[string]interface{}{"devicetypes": entries}
+	default:
+		devices := map[string][]map[string]interface{}{}
+		for _, sim := range f.scenario.Simulators {
+			devices[sim.Runtime] = append(devices[sim.Runtime], map[string]interface{}{
+				"udid":                 sim.UDID,
+				"name":                 sim.Name,
+				"state":                f.state.Devices[sim.UDID].State,
+				"isAvailable":          true,
+				"deviceTypeIdentifier": sim.DeviceType,
+				"dataPath":             "/fake/CoreSimulator/Devices/" + sim.UDID + "/data",
+				"logPath":              "/fake/Logs/CoreSimulator/" + sim.UDID,
+			})
+		}
+		list = map[string]interface{}{"devices": devices}
+	}
+	return f.printSimctlJSON(list)
+}
+
+// printSimctlJSON prints v the way simctl formats JSON
+func (f *fake) printSimctlJSON(v interface{}) int {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		fmt.Fprintf(f.stderr, "fakexcode: %v\n", err)
+		return 1
+	}
+	fmt.Fprintln(f.stdout, simctlKeyRegex.ReplaceAllString(string(data), "$1 : "))
+	return 0
+}
+
+func uniqueRuntimes(simulators []Simulator) []string {
+	var runtimes []string
+	for _, sim := range simulators {
+		if !slices.Contains(runtimes, sim.Runtime) {
+			runtimes = append(runtimes, sim.Runtime)
+		}
+	}
+	return runtimes
+}
+
+// runtimeName turns com.apple.CoreSimulator.SimRuntime.iOS-18-0 into iOS 18.0
+func runtimeName(identifier string) string {
+	name := identifier[strings.LastIndex(identifier, ".")+1:]
+	platform, version, _ := strings.Cut(name, "-")
+	return platform + " " + strings.ReplaceAll(version, "-", ".")
+}
+
+func (f *fake) simctlState(command string, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintf(f.stderr, "error: simctl %s requires a device\n", command)
+		return 64
+	}
+	_, device, code := f.device(args[0])
+	if code != 0 {
+		return code
+	}
+
+	switch command {
+	case "boot":
+		if device.State == "Booted" {
+			return f.simError(405, "Unable to boot device in current state: Booted")
+		}
+		device.State = "Booted"
+	case "shutdown":
+		if device.State != "Booted" {
+			return f.simError(405, "Unable to shutdown device in current state: "+device.State)
+		}
+		device.State = "Shutdown"
+	case "erase":
+		if device.State == "Booted" {
+			return f.simError(405, "Unable to erase contents and settings in current state: Booted")
+		}
+		device.Apps = map[string]string{}
+	}
+	return f.saveState()
+}
+
+func (f *fake) saveState() int {
+	if err := f.state.save(); err != nil {
+		fmt.Fprintf(f.stderr, "fakexcode: failed to save state: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func (f *fake) simctlInstall(args []string) int {
+	_, device, code := f.bootedDevice(args)
+	if code != 0 {
+		return code
+	}
+	if len(args) < 2 {
+		fmt.Fprintln(f.stderr, "error: simctl install requires an application path")
+		return 64
+	}
+
+	appPath := args[1]
+	data, err := os.ReadFile(filepath.Join(appPath, "Info.plist"))
+	if err != nil {
+		fmt.Fprintf(f.stderr, "An error was encountered processing the command (domain=NSPOSIXErrorDomain, code=2):\n"+
+			"Failed to install the requested application\nThe application's path no longer exists: %s\n", appPath)
+		return 2
+	}
+	info, err := plist.UnmarshalDict(data)
+	bundleID, _ := info["CFBundleIdentifier"].(string)
+	if err != nil || bundleID == "" {
+		fmt.Fprintf(f.stderr, "An error was encountered processing the command (domain=IXUserPresentableErrorDomain, code=1):\n"+
+			"Failed to install the requested application\nThe application's Info.plist does not contain CFBundleIdentifier.\n")
+		return 1
+	}
+
+	device.Apps[bundleID] = appPath
+	return f.saveState()
+}
+
+func (f *fake) simctlUninstall(args []string) int {
+	_, device, code := f.bootedDevice(args)
+	if code != 0 {
+		return code
+	}
+	if len(args) < 2 {
+		fmt.Fprintln(f.stderr, "error: simctl uninstall requires a bundle identifier")
+		return 64
+	}
+	delete(device.Apps, args[1])
+	return f.saveState()
+}
+
+func (f *fake) simctlLaunch(args []string) int {
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		args = args[1:]
+	}
+	_, device, code := f.bootedDevice(args)
+	if code != 0 {
+		return code
+	}
+	if len(args) < 2 {
+		fmt.Fprintln(f.stderr, "error: simctl launch requires a bundle identifier")
+		return 64
+	}
+
+	bundleID := args[1]
+	if _, ok := device.Apps[bundleID]; !ok {
+		fmt.Fprintf(f.stderr, "An error was encountered processing the command (domain=FBSOpenApplicationServiceErrorDomain, code=4):\n"+
+			"The request to open %q failed.\nApp is not installed: %s\n", bundleID, bundleID)
+		return 4
+	}
+	fmt.Fprintf(f.stdout, "%s: %d\n", bundleID, 40000+len(bundleID))
+	return 0
+}
+
+func (f *fake) simctlListApps(args []string) int {
+	_, device, code := f.bootedDevice(args)
+	if code != 0 {
+		return code
+	}
+
+	bundleIDs := make([]string, 0, len(device.Apps))
+	for bundleID := range device.Apps {
+		bundleIDs = append(bundleIDs, bundleID)
+	}
+	sort.Strings(bundleIDs)
+
+	fmt.Fprintln(f.stdout, "{")
+	for _, bundleID := range bundleIDs {
+		fmt.Fprintf(f.stdout, "    %q =     {\n", bundleID)
+		writeAppInfo(f.stdout, bundleID, device.Apps[bundleID], "        ")
+		fmt.Fprintln(f.stdout, "    };")
+	}
+	fmt.Fprintln(f.stdout, "}")
+	return 0
+}
+
+func (f *fake) simctlAppInfo(args []string) int {
+	_, device, code := f.bootedDevice(args)
+	if code != 0 {
+		return code
+	}
+	if len(args) < 2 {
+		fmt.Fprintln(f.stderr, "error: simctl appinfo requires a bundle identifier")
+		return 64
+	}
+
+	bundleID := args[1]
+	path, ok := device.Apps[bundleID]
+	if !ok {
+		fmt.Fprintf(f.stderr, "An error was encountered processing the command (domain=NSPOSIXErrorDomain, code=2):\nApp is not installed: %s\n", bundleID)
+		return 2
+	}
+	fmt.Fprintln(f.stdout, "{")
+	writeAppInfo(f.stdout, bundleID, path, "    ")
+	fmt.Fprintln(f.stdout, "}")
+	return 0
+}
+
+// writeAppInfo prints the OpenStep dictionary entries simctl reports for
+// an installed app
+func writeAppInfo(w io.Writer, bundleID, path, indent string) {
+	if path == "" {
+		path = "/fake/CoreSimulator/Bundle/Application/" + bundleID + ".app"
+	}
+	name := strings.TrimSuffix(filepath.Base(path), ".app")
+	fmt.Fprintf(w, "%sApplicationType = User;\n", indent)
+	fmt.Fprintf(w, "%sBundle = %q;\n", indent, "file://"+path+"/")
+	fmt.Fprintf(w, "%sCFBundleDisplayName = %s;\n", indent, name)
+	fmt.Fprintf(w, "%sCFBundleExecutable = %s;\n", indent, name)
+	fmt.Fprintf(w, "%sCFBundleIdentifier = %q;\n", indent, bundleID)
+	fmt.Fprintf(w, "%sCFBundleName = %s;\n", indent, name)
+	fmt.Fprintf(w, "%sCFBundleVersion = 1;\n", indent)
+	fmt.Fprintf(w, "%sPath = %q;\n", indent, path)
+}
+
+func (f *fake) simctlIO(args []string) int {
+	if _, _, code := f.bootedDevice(args); code != 0 {
+		return code
+	}
+	if len(args) < 2 {
+		fmt.Fprintln(f.stderr, "error: simctl io requires an operation")
+		return 64
+	}
+	if args[1] != "screenshot" {
+		// Input events have no visible effect on a fake screen
+		return 0
+	}
+
+	format, path := "", ""
+	for i := 2; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "--type" && i+1 < len(args):
+			i++
+			format = args[i]
+		case strings.HasPrefix(arg, "--type="):
+			format = strings.TrimPrefix(arg, "--type=")
+		case strings.HasPrefix(arg, "-"):
+		default:
+			path = arg
+		}
+	}
+	if path == "" {
+		fmt.Fprintln(f.stderr, "error: simctl io screenshot requires an output path")
+		return 64
+	}
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+
+	screen := image.NewRGBA(image.Rect(0, 0, screenWidth, screenHeight))
+	draw.Draw(screen, screen.Bounds(), &image.Uniform{C: color.RGBA{R: 242, G: 242, B: 247, A: 255}}, image.Point{}, draw.Src)
+
+	file, err := os.Create(path)
+	if err != nil {
+		fmt.Fprintf(f.stderr, "Error creating the image: %v\n", err)
+		return 1
+	}
+	defer file.Close()
+
+	if format == "jpeg" || format == "jpg" {
+		err = jpeg.Encode(file, screen, nil)
+		format = "JPEG"
+	} else {
+		err = png.Encode(file, screen)
+		format = "PNG"
+	}
+	if err != nil {
+		fmt.Fprintf(f.stderr, "Error writing the image: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(f.stderr, "Detected file type '%s' from extension.\nWrote screenshot to: %s\n", format, path)
+	return 0
+}
+
+func (f *fake) simctlSpawn(args []string) int {
+	if _, _, code := f.bootedDevice(args); code != 0 {
+		return code
+	}
+	if len(args) < 3 || args[1] != "log" || (args[2] != "show" && args[2] != "stream") {
+		fmt.Fprintf(f.stderr, "fakexcode: simctl spawn %s is not emulated\n", strings.Join(args[1:], " "))
+		return 64
+	}
+
+	f.printLines(f.scenario.Logs)
+	if args[2] == "stream" {
+		// log stream runs until it is killed
+		return f.finish(Action{Hang: true}, 0)
+	}
+	return 0
+}
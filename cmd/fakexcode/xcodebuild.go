@@ -0,0 +1,646 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// xcodebuildValueFlags are the options of xcodebuild that take a value
+var xcodebuildValueFlags = map[string]bool{
+	"project": true, "workspace": true, "scheme": true, "target": true,
+	"configuration": true, "sdk": true, "destination": true, "arch": true,
+	"derivedDataPath": true, "resultBundlePath": true, "testPlan": true,
+	"archivePath": true, "exportPath": true, "exportOptionsPlist": true,
+	"xcconfig": true, "toolchain": true, "only-testing": true, "skip-testing": true,
+	"test-iterations": true, "test-timeouts-enabled": true,
+	"default-test-execution-time-allowance": true, "maximum-test-execution-time-allowance": true,
+	"parallel-testing-enabled": true, "parallel-testing-worker-count": true,
+	"enableCodeCoverage": true, "destination-timeout": true, "clonedSourcePackagesDirPath": true,
+}
+
+// xcodebuildActions are the actions fakexcode emulates
+var xcodebuildActions = []string{"build", "test", "clean", "build-for-testing", "test-without-building"}
+
+// invocation is a parsed xcodebuild command line
+type invocation struct {
+	actions  []string
+	values   map[string][]string
+	switches map[string]bool
+}
+
+func (inv *invocation) value(name string) string {
+	if values := inv.values[name]; len(values) > 0 {
+		return values[len(values)-1]
+	}
+	return ""
+}
+
+func parseXcodebuildArgs(args []string) (*invocation, error) {
+	inv := &invocation{values: map[string][]string{}, switches: map[string]bool{}}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case strings.HasPrefix(arg, "-"):
+			name := strings.TrimLeft(arg, "-")
+			if !xcodebuildValueFlags[name] {
+				inv.switches[name] = true
+				continue
+			}
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("option %s requires an argument", arg)
+			}
+			i++
+			inv.values[name] = append(inv.values[name], args[i])
+		case strings.Contains(arg, "="):
+			// Build setting overrides change nothing here
+		default:
+			inv.actions = append(inv.actions, arg)
+		}
+	}
+	return inv, nil
+}
+
+func (f *fake) xcodebuild(args []string) int {
+	inv, err := parseXcodebuildArgs(args)
+	if err != nil {
+		fmt.Fprintf(f.stderr, "xcodebuild: error: %v\n", err)
+		return 64
+	}
+
+	switch {
+	case inv.switches["version"]:
+		fmt.Fprintf(f.stdout, "Xcode %s\nBuild version 16A242d\n", f.scenario.Xcode)
+		return 0
+	case inv.switches["list"]:
+		f.printList()
+		return 0
+	}
+
+	if code := f.checkScheme(inv); code != 0 {
+		return code
+	}
+	if inv.switches["showBuildSettings"] {
+		f.printBuildSettings(inv)
+		return 0
+	}
+	if code := f.checkDestination(inv); code != 0 {
+		return code
+	}
+
+	actions := inv.actions
+	if len(actions) == 0 {
+		actions = []string{"build"}
+	}
+	for _, action := range actions {
+		if !slices.Contains(xcodebuildActions, action) {
+			fmt.Fprintf(f.stderr, "xcodebuild: error: fakexcode does not emulate the %s action\n", action)
+			return 64
+		}
+	}
+
+	fmt.Fprintf(f.stdout, "Command line invocation:\n    /Applications/Xcode.app/Contents/Developer/usr/bin/xcodebuild %s\n\n",
+		strings.Join(args, " "))
+	for _, action := range actions {
+		var code int
+		switch action {
+		case "clean":
+			code = f.clean(inv)
+		case "build", "build-for-testing":
+			code = f.build(inv)
+		case "test", "test-without-building":
+			code = f.test(inv)
+		}
+		if code != 0 {
+			return code
+		}
+	}
+	return 0
+}
+
+func (f *fake) printList() {
+	project := f.scenario.Project
+	fmt.Fprintf(f.stdout, "Command line invocation:\n    /Applications/Xcode.app/Contents/Developer/usr/bin/xcodebuild -list\n\n")
+	fmt.Fprintf(f.stdout, "Information about project %q:\n    Targets:\n", project.Name)
+	for _, target := range f.targets() {
+		fmt.Fprintf(f.stdout, "        %s\n", target)
+	}
+	fmt.Fprintf(f.stdout, "\n    Build Configurations:\n")
+	for _, configuration := range project.Configurations {
+		fmt.Fprintf(f.stdout, "        %s\n", configuration)
+	}
+	fmt.Fprintf(f.stdout, "\n    If no build configuration is specified and -scheme is not passed then %q is used.\n\n    Schemes:\n",
+		project.Configurations[len(project.Configurations)-1])
+	for _, scheme := range f.schemes() {
+		fmt.Fprintf(f.stdout, "        %s\n", scheme)
+	}
+}
+
+func (f *fake) printBuildSettings(inv *invocation) {
+	settings := map[string]string{
+		"PROJECT_NAME":  f.scenario.Project.Name,
+		"CONFIGURATION": f.configuration(inv),
+	}
+	for key, value := range f.scenario.Project.BuildSettings {
+		settings[key] = value
+	}
+	keys := make([]string, 0, len(settings)+1)
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	keys = append(keys, "TARGET_NAME")
+	sort.Strings(keys)
+
+	for _, target := range f.targets() {
+		fmt.Fprintf(f.stdout, "Build settings for action build and target %s:\n", target)
+		for _, key := range keys {
+			value := settings[key]
+			if key == "TARGET_NAME" {
+				value = target
+			}
+			fmt.Fprintf(f.stdout, "    %s = %s\n", key, value)
+		}
+		fmt.Fprintln(f.stdout)
+	}
+}
+
+// checkScheme fails like xcodebuild when the scheme is not in the project
+func (f *fake) checkScheme(inv *invocation) int {
+	scheme := inv.value("scheme")
+	if scheme == "" || len(f.scenario.Project.Schemes) == 0 || slices.Contains(f.scenario.Project.Schemes, scheme) {
+		return 0
+	}
+	fmt.Fprintf(f.stderr, "xcodebuild: error: The project named %q does not contain a scheme named %q. "+
+		"The \"-list\" option can be used to find the names of the schemes in the project.\n", f.scenario.Project.Name, scheme)
+	return 65
+}
+
+// checkDestination fails like xcodebuild when the destination names a
+// simulator the scenario does not have
+func (f *fake) checkDestination(inv *invocation) int {
+	destination := inv.value("destination")
+	if destination == "" || len(f.scenario.Simulators) == 0 || f.destination(inv) != nil {
+		return 0
+	}
+	fields := strings.Split(destination, ",")
+	for i, field := range fields {
+		fields[i] = strings.Replace(strings.TrimSpace(field), "=", ":", 1)
+	}
+	fmt.Fprintf(f.stderr, "xcodebuild: error: Unable to find a destination matching the provided destination specifier:\n\t\t{ %s }\n",
+		strings.Join(fields, ", "))
+	return 70
+}
+
+// destination returns the simulator named by the -destination of inv, or
+// the first simulator when there is none
+func (f *fake) destination(inv *invocation) *Simulator {
+	destination := inv.value("destination")
+	for i := range f.scenario.Simulators {
+		sim := &f.scenario.Simulators[i]
+		if destination == "" || destinationMatches(destination, sim) {
+			return sim
+		}
+	}
+	return nil
+}
+
+// destinationMatches reports whether the id and name of a destination
+// specifier, where given, are those of sim
+func destinationMatches(destination string, sim *Simulator) bool {
+	for _, field := range strings.Split(destination, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(field), "=")
+		if (key == "id" && value != sim.UDID) || (key == "name" && value != sim.Name) {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *fake) schemes() []string {
+	if len(f.scenario.Project.Schemes) > 0 {
+		return f.scenario.Project.Schemes
+	}
+	return []string{f.scenario.Project.Name}
+}
+
+func (f *fake) targets() []string {
+	if len(f.scenario.Project.Targets) > 0 {
+		return f.scenario.Project.Targets
+	}
+	return []string{f.scenario.Project.Name}
+}
+
+func (f *fake) configuration(inv *invocation) string {
+	if configuration := inv.value("configuration"); configuration != "" {
+		return configuration
+	}
+	return f.scenario.Project.Configurations[0]
+}
+
+func (f *fake) clean(inv *invocation) int {
+	action := f.scenario.Clean
+	for _, target := range f.targets() {
+		fmt.Fprintf(f.stdout, "=== CLEAN TARGET %s OF PROJECT %s WITH CONFIGURATION %s ===\n\n",
+			target, f.scenario.Project.Name, f.configuration(inv))
+	}
+	f.printIssues(action)
+	f.printLines(action.Output)
+	if action.Hang || action.Crash != "" {
+		return f.finish(action, 0)
+	}
+
+	if len(action.Errors) > 0 {
+		fmt.Fprintln(f.stdout, "** CLEAN FAILED **")
+		return f.finish(action, 65)
+	}
+	fmt.Fprintln(f.stdout, "** CLEAN SUCCEEDED **")
+	return f.finish(action, 0)
+}
+
+func (f *fake) build(inv *invocation) int {
+	action := f.scenario.Build
+	failed := f.compile(inv, action, f.buildTargets())
+	f.printLines(action.Output)
+	if action.Hang || action.Crash != "" {
+		return f.finish(action, 0)
+	}
+
+	if err := f.writeResultBundle(inv, "build", action, nil); err != nil {
+		fmt.Fprintf(f.stderr, "xcodebuild: error: %v\n", err)
+		return 74
+	}
+	if len(failed) > 0 {
+		f.printFailedCommands(failed)
+		fmt.Fprintln(f.stdout, "** BUILD FAILED **")
+		return f.finish(action, 65)
+	}
+	fmt.Fprintln(f.stdout, "** BUILD SUCCEEDED **")
+	return f.finish(action, 0)
+}
+
+func (f *fake) test(inv *invocation) int {
+	action := f.scenario.Test
+	failed := f.compile(inv, action, f.targets())
+	if len(failed) > 0 {
+		f.printLines(action.Output)
+		if action.Hang || action.Crash != "" {
+			return f.finish(action, 0)
+		}
+		if err := f.writeResultBundle(inv, "test", action, nil); err != nil {
+			fmt.Fprintf(f.stderr, "xcodebuild: error: %v\n", err)
+			return 74
+		}
+		fmt.Fprintln(f.stdout, "Testing failed:")
+		for _, issue := range action.Errors {
+			fmt.Fprintf(f.stdout, "\t%s\n", issue.Message)
+		}
+		fmt.Fprintln(f.stdout, "\n** TEST FAILED **")
+		return f.finish(action, 65)
+	}
+
+	tests := selectTests(action.Tests, inv.values["only-testing"], inv.values["skip-testing"])
+	f.runTests(tests)
+	f.printLines(action.Output)
+	if action.Hang || action.Crash != "" {
+		return f.finish(action, 0)
+	}
+
+	if err := f.writeResultBundle(inv, "test", action, tests); err != nil {
+		fmt.Fprintf(f.stderr, "xcodebuild: error: %v\n", err)
+		return 74
+	}
+	var failures []TestCase
+	for _, test := range tests {
+		if test.failed() {
+			failures = append(failures, test)
+		}
+	}
+	if len(failures) > 0 {
+		fmt.Fprintln(f.stdout, "\nFailing tests:")
+		for _, test := range failures {
+			fmt.Fprintf(f.stdout, "\t%s.%s()\n", test.Class, strings.TrimSuffix(test.Name, "()"))
+		}
+		fmt.Fprintln(f.stdout, "\n** TEST FAILED **")
+		return f.finish(action, 65)
+	}
+	fmt.Fprintln(f.stdout, "\n** TEST SUCCEEDED **")
+	return f.finish(action, 0)
+}
+
+// buildTargets are the targets built by a build action, which leaves out
+// test bundles
+func (f *fake) buildTargets() []string {
+	var targets []string
+	for _, target := range f.targets() {
+		if !strings.HasSuffix(target, "Tests") {
+			targets = append(targets, target)
+		}
+	}
+	if len(targets) == 0 {
+		return f.targets()
+	}
+	return targets
+}
+
+// compile prints the build steps of targets with the issues of action,
+// and returns the compile commands that failed
+func (f *fake) compile(inv *invocation, action Action, targets []string) []string {
+	var failed []string
+	for _, target := range targets {
+		fmt.Fprintf(f.stdout, "=== BUILD TARGET %s OF PROJECT %s WITH CONFIGURATION %s ===\n\n",
+			target, f.scenario.Project.Name, f.configuration(inv))
+
+		sources := []string{fmt.Sprintf("/fake/%s/%s.swift", target, target)}
+		for _, issue := range slices.Concat(action.Errors, action.Warnings) {
+			if issueTarget(issue, targets) == target && !slices.Contains(sources, issue.File) {
+				sources = append(sources, issue.File)
+			}
+		}
+
+		for _, source := range sources {
+			step := fmt.Sprintf("SwiftCompile normal arm64 %s (in target '%s' from project '%s')", source, target, f.scenario.Project.Name)
+			fmt.Fprintln(f.stdout, step)
+			sourceFailed := false
+			for _, issue := range action.Warnings {
+				if issue.File == source && issueTarget(issue, targets) == target {
+					fmt.Fprintln(f.stdout, formatIssue(issue, "warning"))
+				}
+			}
+			for _, issue := range action.Errors {
+				if issue.File == source && issueTarget(issue, targets) == target {
+					fmt.Fprintln(f.stdout, formatIssue(issue, "error"))
+					sourceFailed = true
+				}
+			}
+			if sourceFailed {
+				failed = append(failed, step)
+			}
+		}
+		if len(failed) == 0 {
+			fmt.Fprintf(f.stdout, "Ld /fake/Build/Products/%s/%s normal (in target '%s' from project '%s')\n",
+				f.configuration(inv), target, target, f.scenario.Project.Name)
+			fmt.Fprintf(f.stdout, "CodeSign /fake/Build/Products/%s/%s (in target '%s' from project '%s')\n\n",
+				f.configuration(inv), target, target, f.scenario.Project.Name)
+		}
+	}
+	return failed
+}
+
+// issueTarget is the target an issue belongs to: its own, or the first
+func issueTarget(issue Issue, targets []string) string {
+	if issue.Target != "" {
+		return issue.Target
+	}
+	return targets[0]
+}
+
+func formatIssue(issue Issue, severity string) string {
+	if issue.Line == 0 {
+		return fmt.Sprintf("%s: %s: %s", issue.File, severity, issue.Message)
+	}
+	column := max(issue.Column, 1)
+	return fmt.Sprintf("%s:%d:%d: %s: %s", issue.File, issue.Line, column, severity, issue.Message)
+}
+
+func (f *fake) printIssues(action Action) {
+	for _, issue := range action.Warnings {
+		fmt.Fprintln(f.stdout, formatIssue(issue, "warning"))
+	}
+	for _, issue := range action.Errors {
+		fmt.Fprintln(f.stdout, formatIssue(issue, "error"))
+	}
+}
+
+func (f *fake) printFailedCommands(failed []string) {
+	fmt.Fprintln(f.stdout, "\nThe following build commands failed:")
+	for _, step := range failed {
+		fmt.Fprintf(f.stdout, "\t%s\n", step)
+	}
+	fmt.Fprintf(f.stdout, "(%d failure", len(failed))
+	if len(failed) > 1 {
+		fmt.Fprint(f.stdout, "s")
+	}
+	fmt.Fprintln(f.stdout, ")")
+}
+
+func (f *fake) printLines(lines []string) {
+	for _, line := range lines {
+		fmt.Fprintln(f.stdout, line)
+	}
+}
+
+// selectTests applies -only-testing and -skip-testing identifiers
+// (Target, Target/Class or Target/Class/test) to tests
+func selectTests(tests []TestCase, only, skip []string) []TestCase {
+	var selected []TestCase
+	for _, test := range tests {
+		if len(only) > 0 && !slices.ContainsFunc(only, func(id string) bool { return identifies(id, test) }) {
+			continue
+		}
+		if slices.ContainsFunc(skip, func(id string) bool { return identifies(id, test) }) {
+			continue
+		}
+		selected = append(selected, test)
+	}
+	return selected
+}
+
+func identifies(id string, test TestCase) bool {
+	parts := strings.Split(id, "/")
+	name := strings.TrimSuffix(test.Name, "()")
+	switch len(parts) {
+	case 1:
+		return parts[0] == test.Target
+	case 2:
+		return parts[0] == test.Target && parts[1] == test.Class
+	default:
+		return parts[0] == test.Target && parts[1] == test.Class && strings.TrimSuffix(parts[2], "()") == name
+	}
+}
+
+// runTests prints the XCTest log of tests, suite by suite
+func (f *fake) runTests(tests []TestCase) {
+	stamp := func() string { return f.now().Format("2006-01-02 15:04:05.000") }
+
+	fmt.Fprintf(f.stdout, "Test Suite 'All tests' started at %s.\n", stamp())
+	var all suiteCounts
+	for _, target := range uniqueValues(tests, func(t TestCase) string { return t.Target }) {
+		bundleTests := filterTests(tests, func(t TestCase) bool { return t.Target == target })
+		fmt.Fprintf(f.stdout, "Test Suite '%s.xctest' started at %s.\n", target, stamp())
+
+		var bundle suiteCounts
+		for _, class := range uniqueValues(bundleTests, func(t TestCase) string { return t.Class }) {
+			fmt.Fprintf(f.stdout, "Test Suite '%s' started at %s.\n", class, stamp())
+			var suite suiteCounts
+			for _, test := range filterTests(bundleTests, func(t TestCase) bool { return t.Class == class }) {
+				f.runTest(test)
+				suite.add(test)
+			}
+			f.printSuiteEnd(class, suite, stamp())
+			bundle.merge(suite)
+		}
+		f.printSuiteEnd(target+".xctest", bundle, stamp())
+		all.merge(bundle)
+	}
+	f.printSuiteEnd("All tests", all, stamp())
+}
+
+func (f *fake) runTest(test TestCase) {
+	name := fmt.Sprintf("-[%s.%s %s]", test.Target, test.Class, strings.TrimSuffix(test.Name, "()"))
+	fmt.Fprintf(f.stdout, "Test Case '%s' started.\n", name)
+	switch {
+	case test.failed():
+		fmt.Fprintf(f.stdout, "%s:%d: error: %s : %s\n", test.File, test.Line, name, test.Message)
+		fmt.Fprintf(f.stdout, "Test Case '%s' failed (%.3f seconds).\n", name, testDuration(test))
+	case test.skipped():
+		fmt.Fprintf(f.stdout, "%s:%d: %s : Test skipped - %s\n", test.File, test.Line, name, test.Message)
+		fmt.Fprintf(f.stdout, "Test Case '%s' skipped (%.3f seconds).\n", name, testDuration(test))
+	default:
+		fmt.Fprintf(f.stdout, "Test Case '%s' passed (%.3f seconds).\n", name, testDuration(test))
+	}
+}
+
+func testDuration(test TestCase) float64 {
+	if test.Duration > 0 {
+		return test.Duration
+	}
+	return 0.001
+}
+
+type suiteCounts struct {
+	tests, failures, skipped int
+	duration                 float64
+}
+
+func (c *suiteCounts) add(test TestCase) {
+	c.tests++
+	c.duration += testDuration(test)
+	if test.failed() {
+		c.failures++
+	}
+	if test.skipped() {
+		c.skipped++
+	}
+}
+
+func (c *suiteCounts) merge(other suiteCounts) {
+	c.tests += other.tests
+	c.failures += other.failures
+	c.skipped += other.skipped
+	c.duration += other.duration
+}
+
+func (f *fake) printSuiteEnd(name string, counts suiteCounts, stamp string) {
+	status := "passed"
+	if counts.failures > 0 {
+		status = "failed"
+	}
+	fmt.Fprintf(f.stdout, "Test Suite '%s' %s at %s.\n", name, status, stamp)
+
+	plural := func(n int, word string) string {
+		if n == 1 {
+			return fmt.Sprintf("%d %s", n, word)
+		}
+		return fmt.Sprintf("%d %ss", n, word)
+	}
+	skipped := ""
+	if counts.skipped > 0 {
+		skipped = plural(counts.skipped, "test") + " skipped and "
+	}
+	fmt.Fprintf(f.stdout, "\t Executed %s, with %s%s (0 unexpected) in %.3f (%.3f) seconds\n",
+		plural(counts.tests, "test"), skipped, plural(counts.failures, "failure"), counts.duration, counts.duration)
+}
+
+func uniqueValues(tests []TestCase, key func(TestCase) string) []string {
+	var values []string
+	for _, test := range tests {
+		if value := key(test); !slices.Contains(values, value) {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func filterTests(tests []TestCase, keep func(TestCase) bool) []TestCase {
+	var kept []TestCase
+	for _, test := range tests {
+		if keep(test) {
+			kept = append(kept, test)
+		}
+	}
+	return kept
+}
+
+// bundleRecord is what fakexcode stores in a result bundle for
+// xcresulttool and xccov to report
+type bundleRecord struct {
+	Action   string          `json:"action"`
+	Scheme   string          `json:"scheme"`
+	Started  float64         `json:"started"`
+	Finished float64         `json:"finished"`
+	Tests    []TestCase      `json:"tests,omitempty"`
+	Errors   []Issue         `json:"errors,omitempty"`
+	Warnings []Issue         `json:"warnings,omitempty"`
+	Device   *Simulator      `json:"device,omitempty"`
+	Coverage json.RawMessage `json:"coverage,omitempty"`
+}
+
+// bundleRecordFile is the file in a result bundle holding its bundleRecord
+const bundleRecordFile = "fakexcode.json"
+
+// writeResultBundle creates the -resultBundlePath of inv, if any, recording
+// action and the tests that ran
+func (f *fake) writeResultBundle(inv *invocation, name string, action Action, tests []TestCase) error {
+	path := inv.value("resultBundlePath")
+	if path == "" {
+		return nil
+	}
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("Existing file at -resultBundlePath %q", path)
+	}
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return err
+	}
+
+	finished := float64(f.now().UnixMilli()) / 1000
+	var duration float64
+	for _, test := range tests {
+		duration += testDuration(test)
+	}
+	record := bundleRecord{
+		Action:   name,
+		Scheme:   inv.value("scheme"),
+		Started:  finished - duration,
+		Finished: finished,
+		Tests:    tests,
+		Errors:   action.Errors,
+		Warnings: action.Warnings,
+		Device:   f.destination(inv),
+	}
+	if inv.value("enableCodeCoverage") == "YES" {
+		record.Coverage = action.Coverage
+	}
+
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(path, bundleRecordFile), data, 0644)
+}
+
+// readResultBundle reads the record fakexcode stored in the bundle at path
+func readResultBundle(path string) (*bundleRecord, error) {
+	data, err := os.ReadFile(filepath.Join(path, bundleRecordFile))
+	if err != nil {
+		return nil, fmt.Errorf("Error: This result bundle could not be read: %s", path)
+	}
+	var record bundleRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("Error: This result bundle is corrupt: %s", path)
+	}
+	return &record, nil
+}
@@ -0,0 +1,373 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// legacyTestsID is the reference to the tests of a legacy xcresulttool
+// summary
+const legacyTestsID = "0~fakexcode-tests"
+
+func (f *fake) xcresulttool(args []string) int {
+	if len(args) > 0 && args[0] == "version" {
+		fmt.Fprintln(f.stdout, "xcresulttool version 23500, format version 3.53 (current)")
+		return 0
+	}
+
+	var words []string
+	options := map[string]string{}
+	switches := map[string]bool{}
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "--legacy" || arg == "--only-failures" || arg == "--compact":
+			switches[arg] = true
+		case strings.HasPrefix(arg, "--") && i+1 < len(args):
+			options[arg] = args[i+1]
+			i++
+		default:
+			words = append(words, arg)
+		}
+	}
+
+	path := options["--path"]
+	if path == "" {
+		fmt.Fprintln(f.stderr, "Error: Missing expected argument '--path <path>'")
+		return 64
+	}
+	record, err := readResultBundle(path)
+	if err != nil {
+		fmt.Fprintln(f.stderr, err)
+		return 1
+	}
+
+	command := strings.Join(words, " ")
+	switch {
+	case command == "get" && switches["--legacy"]:
+		if options["--id"] == legacyTestsID {
+			return f.printJSON(legacyTests(record))
+		}
+		return f.printJSON(legacySummary(record))
+	case command == "get test-results summary":
+		if record.Action != "test" {
+			break
+		}
+		return f.printJSON(testResultsSummary(record))
+	case command == "get test-results tests":
+		if record.Action != "test" {
+			break
+		}
+		return f.printJSON(testResultsTests(record))
+	case command == "get build-results":
+		return f.printJSON(buildResults(record))
+	case command == "export attachments" || command == "export diagnostics":
+		output := options["--output-path"]
+		if output == "" {
+			fmt.Fprintln(f.stderr, "Error: Missing expected argument '--output-path <path>'")
+			return 64
+		}
+		if err := os.MkdirAll(output, 0755); err != nil {
+			fmt.Fprintf(f.stderr, "Error: %v\n", err)
+			return 1
+		}
+		if command == "export attachments" {
+			if err := os.WriteFile(filepath.Join(output, "manifest.json"), []byte("[]\n"), 0644); err != nil {
+				fmt.Fprintf(f.stderr, "Error: %v\n", err)
+				return 1
+			}
+		}
+		return 0
+	default:
+		fmt.Fprintf(f.stderr, "fakexcode: xcresulttool %s is not emulated\n", command)
+		return 64
+	}
+
+	fmt.Fprintf(f.stderr, "Error: No test results found in %s\n", path)
+	return 1
+}
+
+func (f *fake) xccov(args []string) int {
+	if len(args) < 2 || args[0] != "view" || !strings.HasPrefix(args[1], "--report") {
+		fmt.Fprintf(f.stderr, "fakexcode: xccov %s is not emulated\n", strings.Join(args, " "))
+		return 64
+	}
+
+	path := args[len(args)-1]
+	record, err := readResultBundle(path)
+	if err != nil {
+		fmt.Fprintln(f.stderr, err)
+		return 1
+	}
+	if len(record.Coverage) == 0 {
+		fmt.Fprintf(f.stderr, "Error: Failed to load coverage report: %s does not contain coverage data\n", path)
+		return 1
+	}
+	fmt.Fprintln(f.stdout, string(record.Coverage))
+	return 0
+}
+
+func (f *fake) printJSON(v interface{}) int {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		fmt.Fprintf(f.stderr, "fakexcode: %v\n", err)
+		return 1
+	}
+	fmt.Fprintln(f.stdout, string(data))
+	return 0
+}
+
+// resultName is the capitalized result xcresulttool reports
+func resultName(test TestCase) string {
+	switch {
+	case test.failed():
+		return "Failed"
+	case test.skipped():
+		return "Skipped"
+	default:
+		return "Passed"
+	}
+}
+
+func overallResult(record *bundleRecord) string {
+	if len(record.Errors) > 0 {
+		return "Failed"
+	}
+	for _, test := range record.Tests {
+		if test.failed() {
+			return "Failed"
+		}
+	}
+	return "Passed"
+}
+
+func failureText(test TestCase) string {
+	if test.File == "" {
+		return test.Message
+	}
+	return fmt.Sprintf("%s:%d: %s", filepath.Base(test.File), test.Line, test.Message)
+}
+
+// recordDevice is the run destination of the bundle as xcresulttool reports it
+func recordDevice(record *bundleRecord) map[string]interface{} {
+	if record.Device == nil {
+		return nil
+	}
+	name := runtimeName(record.Device.Runtime)
+	platform, version, _ := strings.Cut(name, " ")
+	return map[string]interface{}{
+		"deviceId":     record.Device.UDID,
+		"deviceName":   record.Device.Name,
+		"architecture": "arm64",
+		"modelName":    record.Device.Name,
+		"platform":     platform + " Simulator",
+		"osVersion":    version,
+	}
+}
+
+func testResultsSummary(record *bundleRecord) map[string]interface{} {
+	var passed, failed, skipped int
+	failures := []map[string]interface{}{}
+	for _, test := range record.Tests {
+		switch {
+		case test.failed():
+			failed++
+			failures = append(failures, map[string]interface{}{
+				"testName":             test.Name,
+				"targetName":           test.Target,
+				"failureText":          failureText(test),
+				"testIdentifierString": test.Class + "/" + test.Name,
+			})
+		case test.skipped():
+			skipped++
+		default:
+			passed++
+		}
+	}
+
+	summary := map[string]interface{}{
+		"title":            "Test - " + record.Scheme,
+		"startTime":        record.Started,
+		"finishTime":       record.Finished,
+		"result":           overallResult(record),
+		"totalTestCount":   len(record.Tests),
+		"passedTests":      passed,
+		"failedTests":      failed,
+		"skippedTests":     skipped,
+		"expectedFailures": 0,
+		"testFailures":     failures,
+	}
+	if d := recordDevice(record); d != nil {
+		summary["devicesAndConfigurations"] = []map[string]interface{}{{
+			"device":                d,
+			"testPlanConfiguration": map[string]interface{}{"configurationId": "1", "configurationName": "Test Scheme Action"},
+			"passedTests":           passed,
+			"failedTests":           failed,
+			"skippedTests":          skipped,
+			"expectedFailures":      0,
+		}}
+	}
+	return summary
+}
+
+func testResultsTests(record *bundleRecord) map[string]interface{} {
+	var bundles []map[string]interface{}
+	for _, target := range uniqueValues(record.Tests, func(t TestCase) string { return t.Target }) {
+		bundleTests := filterTests(record.Tests, func(t TestCase) bool { return t.Target == target })
+
+		var suites []map[string]interface{}
+		for _, class := range uniqueValues(bundleTests, func(t TestCase) string { return t.Class }) {
+			var cases []map[string]interface{}
+			for _, test := range filterTests(bundleTests, func(t TestCase) bool { return t.Class == class }) {
+				node := map[string]interface{}{
+					"nodeType":          "Test Case",
+					"nodeIdentifier":    test.Class + "/" + test.Name,
+					"name":              test.Name,
+					"result":            resultName(test),
+					"durationInSeconds": testDuration(test),
+				}
+				if test.failed() {
+					node["children"] = []map[string]interface{}{{
+						"nodeType": "Failure Message",
+						"name":     failureText(test),
+						"result":   "Failed",
+					}}
+				}
+				cases = append(cases, node)
+			}
+			suites = append(suites, map[string]interface{}{
+				"nodeType": "Test Suite",
+				"name":     class,
+				"result":   overallResult(&bundleRecord{Tests: filterTests(bundleTests, func(t TestCase) bool { return t.Class == class })}),
+				"children": cases,
+			})
+		}
+		nodeType := "Unit test bundle"
+		if strings.Contains(target, "UITests") {
+			nodeType = "UI test bundle"
+		}
+		bundles = append(bundles, map[string]interface{}{
+			"nodeType": nodeType,
+			"name":     target,
+			"result":   overallResult(&bundleRecord{Tests: bundleTests}),
+			"children": suites,
+		})
+	}
+
+	return map[string]interface{}{
+		"testNodes": []map[string]interface{}{{
+			"nodeType": "Test Plan",
+			"name":     record.Scheme,
+			"result":   overallResult(record),
+			"children": bundles,
+		}},
+	}
+}
+
+func buildResults(record *bundleRecord) map[string]interface{} {
+	issues := func(list []Issue, issueType string) []map[string]interface{} {
+		entries := []map[string]interface{}{}
+		for _, issue := range list {
+			entry := map[string]interface{}{
+				"issueType":  issueType,
+				"message":    issue.Message,
+				"targetName": issue.Target,
+			}
+			if issue.File != "" {
+				// Locations in source URLs count from zero
+				entry["sourceURL"] = fmt.Sprintf("file://%s#StartingLineNumber=%d&StartingColumnNumber=%d",
+					issue.File, max(issue.Line-1, 0), max(issue.Column-1, 0))
+			}
+			entries = append(entries, entry)
+		}
+		return entries
+	}
+
+	status := "succeeded"
+	if len(record.Errors) > 0 {
+		status = "failed"
+	}
+	results := map[string]interface{}{
+		"actionTitle":      "Build " + record.Scheme,
+		"status":           status,
+		"startTime":        record.Started,
+		"endTime":          record.Finished,
+		"errorCount":       len(record.Errors),
+		"warningCount":     len(record.Warnings),
+		"errors":           issues(record.Errors, "Swift Compiler Error"),
+		"warnings":         issues(record.Warnings, "Swift Compiler Warning"),
+		"analyzerWarnings": []map[string]interface{}{},
+	}
+	if d := recordDevice(record); d != nil {
+		results["destination"] = d
+	}
+	return results
+}
+
+// legacySummary is the top level object of xcresulttool get --legacy
+func legacySummary(record *bundleRecord) map[string]interface{} {
+	actionResult := map[string]interface{}{
+		"status": map[string]interface{}{"_value": strings.ToLower(overallResult(record))},
+	}
+	if record.Action == "test" {
+		actionResult["testsRef"] = map[string]interface{}{
+			"id": map[string]interface{}{"_value": legacyTestsID},
+		}
+	}
+	return map[string]interface{}{
+		"actions": map[string]interface{}{
+			"_values": []map[string]interface{}{{"actionResult": actionResult}},
+		},
+	}
+}
+
+// legacyTests is the test summary xcresulttool get --legacy --id reports
+// for legacyTestsID
+func legacyTests(record *bundleRecord) map[string]interface{} {
+	value := func(v interface{}) map[string]interface{} {
+		return map[string]interface{}{"_value": v}
+	}
+	values := func(v []map[string]interface{}) map[string]interface{} {
+		if v == nil {
+			v = []map[string]interface{}{}
+		}
+		return map[string]interface{}{"_values": v}
+	}
+
+	var testables []map[string]interface{}
+	for _, target := range uniqueValues(record.Tests, func(t TestCase) string { return t.Target }) {
+		var tests []map[string]interface{}
+		for _, test := range filterTests(record.Tests, func(t TestCase) bool { return t.Target == target }) {
+			status := "Success"
+			switch {
+			case test.failed():
+				status = "Failure"
+			case test.skipped():
+				status = "Skipped"
+			}
+			node := map[string]interface{}{
+				"name":       value(test.Name),
+				"identifier": value(test.Class + "/" + test.Name),
+				"testStatus": value(status),
+				"duration":   value(fmt.Sprintf("%g", testDuration(test))),
+			}
+			if test.failed() {
+				node["failureSummaries"] = values([]map[string]interface{}{{"message": value(test.Message)}})
+			}
+			if test.skipped() {
+				node["summaryMessage"] = value(test.Message)
+			}
+			tests = append(tests, node)
+		}
+		testables = append(testables, map[string]interface{}{
+			"name":  value(target),
+			"tests": values(tests),
+		})
+	}
+
+	return map[string]interface{}{
+		"summaries": values([]map[string]interface{}{{"testableSummaries": values(testables)}}),
+	}
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// e2eClient speaks MCP over the stdio of a server process whose Xcode
+// tools are fakexcode
+type e2eClient struct {
+	t        *testing.T
+	stdin    io.WriteCloser
+	stdout   *bufio.Scanner
+	scenario string
+	nextID   int
+}
+
+// startE2EServer builds the server and fakexcode, links fakexcode as
+// xcodebuild and xcrun ahead of PATH and starts the server on stdio
+func startE2EServer(t *testing.T) *e2eClient {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("builds and runs the server binary")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool not found")
+	}
+
+	dir := t.TempDir()
+	binDir := filepath.Join(dir, "bin")
+	server := filepath.Join(dir, "xcode-build-mcp")
+	fake := filepath.Join(binDir, "fakexcode")
+	for _, build := range [][]string{{"-o", server, "."}, {"-o", fake, "../fakexcode"}} {
+		if output, err := exec.Command(goTool, append([]string{"build"}, build...)...).CombinedOutput(); err != nil {
+			t.Fatalf("go build %s failed: %v\n%s", build[2], err, output)
+		}
+	}
+	for _, tool := range []string{"xcodebuild", "xcrun"} {
+		if err := os.Symlink(fake, filepath.Join(binDir, tool)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	client := &e2eClient{t: t, scenario: filepath.Join(dir, "scenario.json")}
+	cmd := exec.Command(server, "-log-level", "error")
+	cmd.Env = append(os.Environ(),
+		"PATH="+binDir+string(os.PathListSeparator)+os.Getenv("PATH"),
+		"FAKEXCODE_SCENARIO="+client.scenario,
+		"FAKEXCODE_STATE="+filepath.Join(dir, "state.json"),
+	)
+	if client.stdin, err = cmd.StdinPipe(); err != nil {
+		t.Fatal(err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.stdout = bufio.NewScanner(stdout)
+	client.stdout.Buffer(nil, 16*1024*1024)
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+	t.Cleanup(func() {
+		client.stdin.Close()
+		done := make(chan error, 1)
+		go func() { done <- cmd.Wait() }()
+		select {
+		case <-done:
+		case <-time.After(10 * time.Second):
+			cmd.Process.Kill()
+		}
+	})
+
+	client.call("initialize", map[string]interface{}{
+		"protocolVersion": "2024-11-05",
+		"clientInfo":      map[string]interface{}{"name": "e2e", "version": "1.0"},
+		"capabilities":    map[string]interface{}{},
+	})
+	return client
+}
+
+// useScenario makes the fakexcode scenario with the given name current
+func (c *e2eClient) useScenario(name string) {
+	data, err := os.ReadFile(filepath.Join("..", "fakexcode", "testdata", name+".json"))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if err := os.WriteFile(c.scenario, data, 0644); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *e2eClient) send(message map[string]interface{}) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if _, err := c.stdin.Write(append(data, '\n')); err != nil {
+		c.t.Fatalf("failed to write request: %v", err)
+	}
+}
+
+// call sends a request and returns its result, skipping notifications
+func (c *e2eClient) call(method string, params interface{}) json.RawMessage {
+	c.t.Helper()
+	c.nextID++
+	id := c.nextID
+	c.send(map[string]interface{}{"jsonrpc": "2.0", "id": id, "method": method, "params": params})
+
+	for c.stdout.Scan() {
+		var response struct {
+			ID     *int            `json:"id"`
+			Result json.RawMessage `json:"result"`
+			Error  *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(c.stdout.Bytes(), &response); err != nil {
+			c.t.Fatalf("invalid message %q: %v", c.stdout.Text(), err)
+		}
+		if response.ID == nil || *response.ID != id {
+			continue
+		}
+		if response.Error != nil {
+			c.t.Fatalf("%s failed: %s", method, response.Error.Message)
+		}
+		return response.Result
+	}
+	c.t.Fatalf("server closed stdout before answering %s: %v", method, c.stdout.Err())
+	return nil
+}
+
+// callTool calls a tool and decodes its structured content into result,
+// returning whether the call reported an error
+func (c *e2eClient) callTool(name string, args map[string]interface{}, result interface{}) bool {
+	c.t.Helper()
+	raw := c.call("tools/call", map[string]interface{}{"name": name, "arguments": args})
+	var call struct {
+		IsError           bool            `json:"isError"`
+		StructuredContent json.RawMessage `json:"structuredContent"`
+		Content           []struct {
+			Text string `json:"text"`
+		} `json:"content"`
+	}
+	if err := json.Unmarshal(raw, &call); err != nil {
+		c.t.Fatalf("invalid %s result: %v", name, err)
+	}
+	if result != nil {
+		if err := json.Unmarshal(call.StructuredContent, result); err != nil {
+			c.t.Fatalf("invalid %s structured content %s: %v", name, call.StructuredContent, err)
+		}
+	}
+	return call.IsError
+}
+
+func TestE2E_Build(t *testing.T) {
+	client := startE2EServer(t)
+	args := map[string]interface{}{"project": "MyApp.xcodeproj", "scheme": "MyApp"}
+
+	type buildResult struct {
+		Success  bool `json:"success"`
+		ExitCode int  `json:"exit_code"`
+		Errors   []struct {
+			File    string `json:"file"`
+			Line    int    `json:"line"`
+			Message string `json:"message"`
+		} `json:"errors"`
+		Warnings []struct {
+			Message string `json:"message"`
+		} `json:"warnings"`
+	}
+
+	client.useScenario("success")
+	var result buildResult
+	if isError := client.callTool("xcode_build", args, &result); isError || !result.Success {
+		t.Fatalf("xcode_build failed: %+v", result)
+	}
+	if len(result.Warnings) != 1 {
+		t.Errorf("warnings = %+v, want 1", result.Warnings)
+	}
+
+	client.useScenario("compile_errors")
+	result = buildResult{}
+	if isError := client.callTool("xcode_build", args, &result); !isError || result.Success || result.ExitCode != 65 {
+		t.Fatalf("xcode_build with compile errors = %+v", result)
+	}
+	if len(result.Errors) != 2 || result.Errors[0].File != "/fake/MyApp/LoginView.swift" || result.Errors[0].Line != 42 {
+		t.Errorf("errors = %+v", result.Errors)
+	}
+}
+
+func TestE2E_Test(t *testing.T) {
+	client := startE2EServer(t)
+	args := map[string]interface{}{"project": "MyApp.xcodeproj", "scheme": "MyApp"}
+
+	type testResult struct {
+		Success        bool   `json:"success"`
+		ProcessCrashed bool   `json:"process_crashed"`
+		CrashType      string `json:"crash_type"`
+		TestSummary    struct {
+			TotalTests         int `json:"total_tests"`
+			PassedTests        int `json:"passed_tests"`
+			FailedTests        int `json:"failed_tests"`
+			FailedTestsDetails []struct {
+				Name      string `json:"name"`
+				ClassName string `json:"class_name"`
+			} `json:"failed_tests_details"`
+		} `json:"test_summary"`
+	}
+
+	client.useScenario("success")
+	var result testResult
+	if isError := client.callTool("xcode_test", args, &result); isError || !result.Success {
+		t.Fatalf("xcode_test failed: %+v", result)
+	}
+	if result.TestSummary.TotalTests != 3 || result.TestSummary.PassedTests != 3 {
+		t.Errorf("test summary = %+v", result.TestSummary)
+	}
+
+	client.useScenario("test_failures")
+	result = testResult{}
+	if isError := client.callTool("xcode_test", args, &result); !isError || result.Success {
+		t.Fatalf("xcode_test with failures = %+v", result)
+	}
+	if result.TestSummary.FailedTests != 1 || len(result.TestSummary.FailedTestsDetails) != 1 ||
+		!strings.Contains(result.TestSummary.FailedTestsDetails[0].Name, "testInvalidPassword") {
+		t.Errorf("test summary = %+v", result.TestSummary)
+	}
+
+	client.useScenario("crash")
+	result = testResult{}
+	if isError := client.callTool("xcode_test", args, &result); !isError || !result.ProcessCrashed {
+		t.Errorf("xcode_test that crashes = %+v", result)
+	}
+}
+
+func TestE2E_Simulators(t *testing.T) {
+	client := startE2EServer(t)
+	client.useScenario("success")
+	udid := "A1B2C3D4-0000-4000-8000-000000000001"
+
+	if isError := client.callTool("simulator_control", map[string]interface{}{"action": "boot", "udid": udid}, nil); isError {
+		t.Fatal("simulator_control boot failed")
+	}
+
+	var list struct {
+		Simulators []struct {
+			UDID  string `json:"udid"`
+			State string `json:"state"`
+		} `json:"simulators"`
+	}
+	if isError := client.callTool("list_simulators", map[string]interface{}{}, &list); isError {
+		t.Fatal("list_simulators failed")
+	}
+	booted := false
+	for _, sim := range list.Simulators {
+		if sim.UDID == udid {
+			booted = sim.State == "Booted"
+		}
+	}
+	if !booted {
+		t.Errorf("simulator %s is not booted after boot: %+v", udid, list.Simulators)
+	}
+}
+
+func TestE2E_CancelHungBuild(t *testing.T) {
+	client := startE2EServer(t)
+	client.useScenario("hang")
+	args := map[string]interface{}{"project": "MyApp.xcodeproj", "scheme": "MyApp"}
+
+	client.nextID++
+	hungID := client.nextID
+	client.send(map[string]interface{}{
+		"jsonrpc": "2.0", "id": hungID, "method": "tools/call",
+		"params": map[string]interface{}{"name": "xcode_build", "arguments": args},
+	})
+	time.Sleep(time.Second)
+	client.send(map[string]interface{}{
+		"jsonrpc": "2.0", "method": "notifications/cancelled",
+		"params": map[string]interface{}{"requestId": hungID, "reason": "test"},
+	})
+
+	// The cancelled build is never answered, and the server goes on to
+	// run the next one
+	client.useScenario("success")
+	done := make(chan bool, 1)
+	go func() {
+		var result struct {
+			Success bool `json:"success"`
+		}
+		isError := client.callTool("xcode_build", args, &result)
+		done <- !isError && result.Success
+	}()
+	select {
+	case ok := <-done:
+		if !ok {
+			t.Error("xcode_build after a cancelled build failed")
+		}
+	case <-time.After(30 * time.Second):
+		t.Fatal("xcode_build after a cancelled build was not answered")
+	}
+}
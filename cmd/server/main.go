@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 
 	"github.com/jontolof/xcode-build-mcp/internal/mcp"
@@ -18,7 +19,9 @@ var (
 
 func main() {
 	var (
-		transport   = flag.String("transport", "stdio", "Transport protocol (stdio)")
+		transport   = flag.String("transport", "stdio", "Transport protocol (stdio, http)")
+		addr        = flag.String("addr", getEnvOrDefault("MCP_HTTP_ADDR", mcp.DefaultHTTPAddr), "Listen address for the http transport")
+		maxInFlight = flag.Int("max-in-flight", getEnvIntOrDefault("MCP_MAX_IN_FLIGHT", mcp.DefaultMaxInFlight), "Maximum number of tool calls executed concurrently")
 		logLevel    = flag.String("log-level", getEnvOrDefault("MCP_LOG_LEVEL", "info"), "Log level (debug, info, warn, error)")
 		showVersion = flag.Bool("version", false, "Print version information")
 	)
@@ -41,6 +44,8 @@ func main() {
 	if err != nil {
 		logger.Fatalf("Failed to create MCP server: %v", err)
 	}
+	server.SetHTTPAddr(*addr)
+	server.SetMaxInFlight(*maxInFlight)
 
 	go func() {
 		<-sigChan
@@ -78,6 +83,15 @@ func getEnvOrDefault(key, defaultValue string) string {
 	return defaultValue
 }
 
+func getEnvIntOrDefault(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	}
+	return defaultValue
+}
+
 type errorOnlyWriter struct {
 	writer *os.File
 }
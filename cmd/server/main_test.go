@@ -110,6 +110,33 @@ func TestGetEnvOrDefault(t *testing.T) {
 	}
 }
 
+func TestGetEnvIntOrDefault(t *testing.T) {
+	tests := []struct {
+		name     string
+		envValue string
+		expected int
+	}{
+		{name: "valid integer", envValue: "8", expected: 8},
+		{name: "not a number", envValue: "many", expected: 4},
+		{name: "not set", envValue: "", expected: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.envValue != "" {
+				os.Setenv("TEST_INT_VAR", tt.envValue)
+				defer os.Unsetenv("TEST_INT_VAR")
+			} else {
+				os.Unsetenv("TEST_INT_VAR")
+			}
+
+			if result := getEnvIntOrDefault("TEST_INT_VAR", 4); result != tt.expected {
+				t.Errorf("getEnvIntOrDefault() = %d, want %d", result, tt.expected)
+			}
+		})
+	}
+}
+
 func TestErrorOnlyWriter(t *testing.T) {
 	tempFile, err := os.CreateTemp("", "test_error_writer")
 	if err != nil {
@@ -0,0 +1,170 @@
+// Package accessibility reads the accessibility hierarchy of a simulator.
+// simctl has no way to do this, so a Backend drives an external tool such as
+// AXe or idb, or replays a fixture in tests.
+package accessibility
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jontolof/xcode-build-mcp/internal/xcode"
+	"github.com/jontolof/xcode-build-mcp/pkg/types"
+)
+
+// Backend names accepted by MCP_ACCESSIBILITY_BACKEND
+const (
+	BackendAuto    = "auto"
+	BackendAXe     = "axe"
+	BackendIDB     = "idb"
+	BackendFixture = "fixture"
+)
+
+// Backend describes the on-screen accessibility hierarchy of a simulator and
+// injects touches and text. Coordinates are in screen points, as in frames.
+type Backend interface {
+	Name() string
+	Describe(ctx context.Context, udid string) (*types.UIElement, error)
+	// Tap touches a point, holding for duration when it is above zero
+	Tap(ctx context.Context, udid string, x, y float64, duration time.Duration) error
+	Swipe(ctx context.Context, udid string, fromX, fromY, toX, toY float64, duration time.Duration) error
+	TypeText(ctx context.Context, udid string, text string) error
+}
+
+// FromEnv selects the backend named by MCP_ACCESSIBILITY_BACKEND, detecting
+// an installed CLI when it is unset or "auto". The fixture backend replays
+// the file at MCP_ACCESSIBILITY_FIXTURE. CLI backends run their commands
+// with runner, so they can be recorded and replayed like any other.
+func FromEnv(runner xcode.CommandRunner) Backend {
+	name := strings.ToLower(os.Getenv("MCP_ACCESSIBILITY_BACKEND"))
+
+	switch name {
+	case BackendAXe:
+		return NewAXeBackend(runner)
+	case BackendIDB:
+		return NewIDBBackend(runner)
+	case BackendFixture:
+		return NewFixtureBackend(os.Getenv("MCP_ACCESSIBILITY_FIXTURE"))
+	default:
+		return Detect(runner)
+	}
+}
+
+// Detect returns a backend for the first accessibility CLI found on PATH,
+// preferring AXe. A runner that resolves commands itself, such as a
+// ReplayRunner, answers the lookup instead, so replaying does not depend on
+// what is installed. When no CLI is found the returned backend reports
+// BACKEND_UNAVAILABLE on use, so the server still starts.
+func Detect(runner xcode.CommandRunner) Backend {
+	lookPath := exec.LookPath
+	if resolver, ok := runner.(xcode.CommandResolver); ok {
+		lookPath = resolver.LookPath
+	}
+
+	if _, err := lookPath(BackendAXe); err == nil {
+		return NewAXeBackend(runner)
+	}
+	if _, err := lookPath(BackendIDB); err == nil {
+		return NewIDBBackend(runner)
+	}
+	return unavailableBackend{}
+}
+
+type unavailableBackend struct{}
+
+func (unavailableBackend) Name() string {
+	return "none"
+}
+
+func (unavailableBackend) Describe(ctx context.Context, udid string) (*types.UIElement, error) {
+	return nil, errNoBackend
+}
+
+func (unavailableBackend) Tap(ctx context.Context, udid string, x, y float64, duration time.Duration) error {
+	return errNoBackend
+}
+
+func (unavailableBackend) Swipe(ctx context.Context, udid string, fromX, fromY, toX, toY float64, duration time.Duration) error {
+	return errNoBackend
+}
+
+func (unavailableBackend) TypeText(ctx context.Context, udid string, text string) error {
+	return errNoBackend
+}
+
+var errNoBackend = types.NewXcodeError(types.ErrCodeBackendUnavailable,
+	"no accessibility backend found on PATH (looked for axe and idb)", nil)
+
+// Input is a touch or text input received by the fixture backend
+type Input struct {
+	Kind     string        `json:"kind"` // tap, swipe or type
+	X        float64       `json:"x,omitempty"`
+	Y        float64       `json:"y,omitempty"`
+	ToX      float64       `json:"to_x,omitempty"`
+	ToY      float64       `json:"to_y,omitempty"`
+	Duration time.Duration `json:"duration,omitempty"`
+	Text     string        `json:"text,omitempty"`
+}
+
+// FixtureBackend serves a hierarchy recorded from AXe or idb. The file is
+// read on every call, so tests can swap screens between steps. Inputs are
+// recorded instead of performed.
+type FixtureBackend struct {
+	path string
+
+	mu     sync.Mutex
+	inputs []Input
+}
+
+func NewFixtureBackend(path string) *FixtureBackend {
+	return &FixtureBackend{path: path}
+}
+
+func (b *FixtureBackend) Name() string {
+	return BackendFixture
+}
+
+func (b *FixtureBackend) Describe(ctx context.Context, udid string) (*types.UIElement, error) {
+	if b.path == "" {
+		return nil, types.NewXcodeError(types.ErrCodeBackendUnavailable,
+			"fixture backend selected but MCP_ACCESSIBILITY_FIXTURE is not set", nil)
+	}
+
+	data, err := os.ReadFile(b.path)
+	if err != nil {
+		return nil, types.NewXcodeErrorWithCause(types.ErrCodeInternal, "failed to read accessibility fixture", err,
+			map[string]interface{}{"path": b.path})
+	}
+	return Parse(data)
+}
+
+func (b *FixtureBackend) Tap(ctx context.Context, udid string, x, y float64, duration time.Duration) error {
+	b.record(Input{Kind: "tap", X: x, Y: y, Duration: duration})
+	return nil
+}
+
+func (b *FixtureBackend) Swipe(ctx context.Context, udid string, fromX, fromY, toX, toY float64, duration time.Duration) error {
+	b.record(Input{Kind: "swipe", X: fromX, Y: fromY, ToX: toX, ToY: toY, Duration: duration})
+	return nil
+}
+
+func (b *FixtureBackend) TypeText(ctx context.Context, udid string, text string) error {
+	b.record(Input{Kind: "type", Text: text})
+	return nil
+}
+
+func (b *FixtureBackend) record(input Input) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.inputs = append(b.inputs, input)
+}
+
+// Inputs returns the inputs received so far, oldest first
+func (b *FixtureBackend) Inputs() []Input {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]Input(nil), b.inputs...)
+}
@@ -0,0 +1,152 @@
+package accessibility
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jontolof/xcode-build-mcp/internal/xcode"
+	"github.com/jontolof/xcode-build-mcp/pkg/types"
+)
+
+// CLIBackend runs an accessibility CLI that prints the hierarchy as JSON
+// and injects input through its tap, swipe and type commands
+type CLIBackend struct {
+	name         string
+	binary       string
+	runner       xcode.CommandRunner
+	describeArgs func(udid string) []string
+	tapArgs      func(udid string, x, y float64, duration time.Duration) []string
+	swipeArgs    func(udid string, fromX, fromY, toX, toY float64, duration time.Duration) []string
+	typeArgs     func(udid, text string) []string
+}
+
+// NewAXeBackend drives AXe (axe describe-ui), which prints a nested tree.
+// Commands go through runner, or run locally when it is nil.
+func NewAXeBackend(runner xcode.CommandRunner) *CLIBackend {
+	return &CLIBackend{
+		name:   BackendAXe,
+		binary: "axe",
+		runner: runner,
+		describeArgs: func(udid string) []string {
+			return []string{"describe-ui", "--udid", udid}
+		},
+		tapArgs: func(udid string, x, y float64, duration time.Duration) []string {
+			if duration > 0 {
+				return []string{"touch", "-x", point(x), "-y", point(y), "--down", "--up",
+					"--delay", seconds(duration), "--udid", udid}
+			}
+			return []string{"tap", "-x", point(x), "-y", point(y), "--udid", udid}
+		},
+		swipeArgs: func(udid string, fromX, fromY, toX, toY float64, duration time.Duration) []string {
+			return []string{"swipe", "--start-x", point(fromX), "--start-y", point(fromY),
+				"--end-x", point(toX), "--end-y", point(toY), "--duration", seconds(duration), "--udid", udid}
+		},
+		typeArgs: func(udid, text string) []string {
+			// -- keeps text starting with a dash from being read as a flag
+			return []string{"type", "--udid", udid, "--", text}
+		},
+	}
+}
+
+// NewIDBBackend drives idb (idb ui describe-all), which prints a flat list.
+// Commands go through runner, or run locally when it is nil.
+func NewIDBBackend(runner xcode.CommandRunner) *CLIBackend {
+	return &CLIBackend{
+		name:   BackendIDB,
+		binary: "idb",
+		runner: runner,
+		describeArgs: func(udid string) []string {
+			return []string{"ui", "describe-all", "--udid", udid, "--json"}
+		},
+		tapArgs: func(udid string, x, y float64, duration time.Duration) []string {
+			args := []string{"ui", "tap", point(x), point(y), "--udid", udid}
+			if duration > 0 {
+				args = append(args, "--duration", seconds(duration))
+			}
+			return args
+		},
+		swipeArgs: func(udid string, fromX, fromY, toX, toY float64, duration time.Duration) []string {
+			return []string{"ui", "swipe", point(fromX), point(fromY), point(toX), point(toY),
+				"--duration", seconds(duration), "--udid", udid}
+		},
+		typeArgs: func(udid, text string) []string {
+			return []string{"ui", "text", "--udid", udid, "--", text}
+		},
+	}
+}
+
+// point formats a coordinate as whole points, which both CLIs accept
+func point(v float64) string {
+	return strconv.Itoa(int(math.Round(v)))
+}
+
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
+
+func (b *CLIBackend) Name() string {
+	return b.name
+}
+
+func (b *CLIBackend) Describe(ctx context.Context, udid string) (*types.UIElement, error) {
+	output, err := b.run(ctx, b.describeArgs(udid))
+	if err != nil {
+		return nil, err
+	}
+	return Parse(output)
+}
+
+func (b *CLIBackend) Tap(ctx context.Context, udid string, x, y float64, duration time.Duration) error {
+	_, err := b.run(ctx, b.tapArgs(udid, x, y, duration))
+	return err
+}
+
+func (b *CLIBackend) Swipe(ctx context.Context, udid string, fromX, fromY, toX, toY float64, duration time.Duration) error {
+	_, err := b.run(ctx, b.swipeArgs(udid, fromX, fromY, toX, toY, duration))
+	return err
+}
+
+func (b *CLIBackend) TypeText(ctx context.Context, udid string, text string) error {
+	_, err := b.run(ctx, b.typeArgs(udid, text))
+	return err
+}
+
+// run executes the CLI and returns its stdout, turning a missing binary into
+// BACKEND_UNAVAILABLE and a failed run into COMMAND_FAILED
+func (b *CLIBackend) run(ctx context.Context, args []string) ([]byte, error) {
+	runner := b.runner
+	if runner == nil {
+		runner = xcode.ExecRunner{}
+	}
+	args = append([]string{b.binary}, args...)
+
+	var stdout, stderr bytes.Buffer
+	status, err := runner.Run(ctx, xcode.Command{Args: args, Stdout: &stdout, Stderr: &stderr})
+	if err == nil && status.Success() {
+		return stdout.Bytes(), nil
+	}
+
+	if errors.Is(err, exec.ErrNotFound) {
+		return nil, types.NewXcodeErrorWithCause(types.ErrCodeBackendUnavailable,
+			b.binary+" is not installed or not on PATH", err, map[string]interface{}{"backend": b.name})
+	}
+
+	exitCode := -1
+	if err == nil {
+		exitCode = status.ExitCode
+		err = &xcode.ExitError{ExitStatus: status, Stderr: stderr.Bytes()}
+	}
+	return nil, types.NewXcodeErrorWithCause(types.ErrCodeCommandFailed, b.binary+" failed", err,
+		map[string]interface{}{
+			"backend":   b.name,
+			"command":   strings.Join(args, " "),
+			"exit_code": exitCode,
+			"output":    strings.TrimSpace(stderr.String()),
+		})
+}
@@ -0,0 +1,183 @@
+package accessibility
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jontolof/xcode-build-mcp/pkg/types"
+)
+
+// rawElement is one element as printed by AXe or idb. AXe nests children;
+// idb prints the same fields as a flat list.
+type rawElement struct {
+	Type       string         `json:"type"`
+	Role       string         `json:"role"`
+	Label      string         `json:"AXLabel"`
+	Title      string         `json:"title"`
+	Identifier string         `json:"AXUniqueId"`
+	Value      interface{}    `json:"AXValue"`
+	Frame      *types.UIFrame `json:"frame"`
+	AXFrame    string         `json:"AXFrame"`
+	Enabled    *bool          `json:"enabled"`
+	Traits     []string       `json:"traits"`
+	Children   []rawElement   `json:"children"`
+}
+
+// typeTraits maps element types to the UIAccessibilityTraits they imply, for
+// backends that do not report traits themselves
+var typeTraits = map[string]string{
+	"Button":      "button",
+	"Link":        "link",
+	"Image":       "image",
+	"Heading":     "header",
+	"StaticText":  "staticText",
+	"SearchField": "searchField",
+	"Slider":      "adjustable",
+	"Adjustable":  "adjustable",
+	"Key":         "keyboardKey",
+	"TabBar":      "tabBar",
+}
+
+// Parse normalizes AXe or idb JSON output into an element tree. A single
+// top-level element is the root. A flat list is nested under its leading
+// Application element, or under a synthetic Group spanning all elements.
+func Parse(data []byte) (*types.UIElement, error) {
+	data = bytes.TrimSpace(data)
+
+	var raws []rawElement
+	if bytes.HasPrefix(data, []byte("[")) {
+		if err := json.Unmarshal(data, &raws); err != nil {
+			return nil, parseError(err, data)
+		}
+	} else {
+		var raw rawElement
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, parseError(err, data)
+		}
+		raws = []rawElement{raw}
+	}
+
+	if len(raws) == 0 {
+		return nil, types.NewXcodeError(types.ErrCodeCommandFailed, "accessibility backend returned no elements", nil)
+	}
+
+	elements := make([]*types.UIElement, 0, len(raws))
+	for _, raw := range raws {
+		elements = append(elements, normalize(raw))
+	}
+
+	if len(elements) == 1 {
+		return elements[0], nil
+	}
+	if elements[0].Type == "Application" {
+		root := elements[0]
+		root.Children = append(root.Children, elements[1:]...)
+		return root, nil
+	}
+
+	root := &types.UIElement{Type: "Group", Enabled: true, Children: elements}
+	root.Frame = unionFrame(elements)
+	return root, nil
+}
+
+func parseError(err error, data []byte) error {
+	excerpt := string(data)
+	if len(excerpt) > 200 {
+		excerpt = excerpt[:200] + "..."
+	}
+	return types.NewXcodeErrorWithCause(types.ErrCodeCommandFailed, "failed to parse accessibility hierarchy", err,
+		map[string]interface{}{"output": excerpt})
+}
+
+func normalize(raw rawElement) *types.UIElement {
+	element := &types.UIElement{
+		Type:       elementType(raw),
+		Label:      raw.Label,
+		Identifier: raw.Identifier,
+		Value:      valueString(raw.Value),
+		Enabled:    raw.Enabled == nil || *raw.Enabled,
+	}
+	if element.Label == "" {
+		element.Label = raw.Title
+	}
+
+	if raw.Frame != nil {
+		element.Frame = *raw.Frame
+	} else if frame, ok := parseAXFrame(raw.AXFrame); ok {
+		element.Frame = frame
+	}
+
+	element.Traits = append(element.Traits, raw.Traits...)
+	if trait, ok := typeTraits[element.Type]; ok && !element.HasTrait(trait) {
+		element.Traits = append(element.Traits, trait)
+	}
+	if !element.Enabled && !element.HasTrait("notEnabled") {
+		element.Traits = append(element.Traits, "notEnabled")
+	}
+
+	for _, child := range raw.Children {
+		element.Children = append(element.Children, normalize(child))
+	}
+	return element
+}
+
+// elementType prefers the reported type and falls back to the AX role
+// without its prefix, so AXButton becomes Button
+func elementType(raw rawElement) string {
+	if raw.Type != "" {
+		return raw.Type
+	}
+	if role := strings.TrimPrefix(raw.Role, "AX"); role != "" {
+		return role
+	}
+	return "Other"
+}
+
+func valueString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+var axFramePattern = regexp.MustCompile(`-?[\d.]+`)
+
+// parseAXFrame reads the CGRect string form "{{x, y}, {width, height}}"
+func parseAXFrame(s string) (types.UIFrame, bool) {
+	numbers := axFramePattern.FindAllString(s, -1)
+	if len(numbers) != 4 {
+		return types.UIFrame{}, false
+	}
+
+	var values [4]float64
+	for i, n := range numbers {
+		v, err := strconv.ParseFloat(n, 64)
+		if err != nil {
+			return types.UIFrame{}, false
+		}
+		values[i] = v
+	}
+	return types.UIFrame{X: values[0], Y: values[1], Width: values[2], Height: values[3]}, true
+}
+
+func unionFrame(elements []*types.UIElement) types.UIFrame {
+	minX, minY := elements[0].Frame.X, elements[0].Frame.Y
+	maxX, maxY := minX+elements[0].Frame.Width, minY+elements[0].Frame.Height
+	for _, e := range elements[1:] {
+		minX = min(minX, e.Frame.X)
+		minY = min(minY, e.Frame.Y)
+		maxX = max(maxX, e.Frame.X+e.Frame.Width)
+		maxY = max(maxY, e.Frame.Y+e.Frame.Height)
+	}
+	return types.UIFrame{X: minX, Y: minY, Width: maxX - minX, Height: maxY - minY}
+}
@@ -0,0 +1,251 @@
+package accessibility
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jontolof/xcode-build-mcp/internal/xcode"
+	"github.com/jontolof/xcode-build-mcp/pkg/types"
+)
+
+func loadFixture(t *testing.T, name string) *types.UIElement {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	root, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	return root
+}
+
+func findByIdentifier(root *types.UIElement, id string) *types.UIElement {
+	var found *types.UIElement
+	root.Walk(func(e *types.UIElement, depth int) bool {
+		if e.Identifier == id {
+			found = e
+		}
+		return found == nil
+	})
+	return found
+}
+
+func TestParse_AXeTree(t *testing.T) {
+	root := loadFixture(t, "axe_login.json")
+
+	if root.Type != "Application" || root.Label != "Example" {
+		t.Errorf("Unexpected root: %s %q", root.Type, root.Label)
+	}
+	if root.Frame != (types.UIFrame{Width: 393, Height: 852}) {
+		t.Errorf("Unexpected root frame: %+v", root.Frame)
+	}
+	if got := root.Count(); got != 11 {
+		t.Errorf("Expected 11 elements, got %d", got)
+	}
+
+	submit := findByIdentifier(root, "login.submit")
+	if submit == nil {
+		t.Fatal("login.submit not found")
+	}
+	if submit.Type != "Button" || submit.Label != "Log In" || submit.Enabled {
+		t.Errorf("Unexpected submit button: %+v", submit)
+	}
+	if !submit.HasTrait("button") || !submit.HasTrait("notEnabled") {
+		t.Errorf("Expected button and notEnabled traits, got %v", submit.Traits)
+	}
+
+	home := findByIdentifier(root, "tab.home")
+	if home == nil || home.Value != "1" {
+		t.Errorf("Expected the tab value to be kept, got %+v", home)
+	}
+}
+
+func TestParse_IDBFlatList(t *testing.T) {
+	root := loadFixture(t, "idb_describe_all.json")
+
+	if root.Type != "Application" || len(root.Children) != 2 {
+		t.Fatalf("Expected the flat list nested under the application, got %s with %d children",
+			root.Type, len(root.Children))
+	}
+
+	email := findByIdentifier(root, "login.email")
+	if email == nil || email.Value != "user@example.com" {
+		t.Errorf("Unexpected email field: %+v", email)
+	}
+
+	// The submit button has no frame object, only the AXFrame string
+	submit := findByIdentifier(root, "login.submit")
+	if submit == nil || submit.Frame != (types.UIFrame{X: 24, Y: 316, Width: 345, Height: 50}) {
+		t.Errorf("Expected the frame parsed from AXFrame, got %+v", submit)
+	}
+}
+
+func TestParse_RoleFallbackAndGroupRoot(t *testing.T) {
+	data := []byte(`[
+		{"role": "AXButton", "AXLabel": "OK", "frame": {"x": 10, "y": 10, "width": 50, "height": 20}},
+		{"role": "AXStaticText", "title": "Done", "frame": {"x": 100, "y": 50, "width": 40, "height": 20}}
+	]`)
+
+	root, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if root.Type != "Group" || len(root.Children) != 2 {
+		t.Fatalf("Expected a synthetic group root, got %+v", root)
+	}
+	if root.Frame != (types.UIFrame{X: 10, Y: 10, Width: 130, Height: 60}) {
+		t.Errorf("Expected the union frame, got %+v", root.Frame)
+	}
+	if root.Children[0].Type != "Button" || !root.Children[0].HasTrait("button") {
+		t.Errorf("Expected AXButton to become Button, got %+v", root.Children[0])
+	}
+	if root.Children[1].Label != "Done" {
+		t.Errorf("Expected the title as label fallback, got %q", root.Children[1].Label)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, input := range []string{"", "[]", "not json", "{\"type\": 5}"} {
+		if _, err := Parse([]byte(input)); !types.IsXcodeError(err, types.ErrCodeCommandFailed) {
+			t.Errorf("Parse(%q): expected COMMAND_FAILED, got %v", input, err)
+		}
+	}
+}
+
+func TestFromEnv_Fixture(t *testing.T) {
+	t.Setenv("MCP_ACCESSIBILITY_BACKEND", "fixture")
+	t.Setenv("MCP_ACCESSIBILITY_FIXTURE", "testdata/axe_login.json")
+
+	backend := FromEnv(nil)
+	if backend.Name() != BackendFixture {
+		t.Fatalf("Expected the fixture backend, got %s", backend.Name())
+	}
+
+	root, err := backend.Describe(context.Background(), "any-udid")
+	if err != nil {
+		t.Fatalf("Describe failed: %v", err)
+	}
+	if findByIdentifier(root, "login.email") == nil {
+		t.Error("Expected the fixture hierarchy")
+	}
+}
+
+func TestBackend_Unavailable(t *testing.T) {
+	backends := []Backend{
+		unavailableBackend{},
+		NewFixtureBackend(""),
+		&CLIBackend{name: "missing", binary: "definitely-not-an-accessibility-cli", describeArgs: func(string) []string { return nil }},
+	}
+
+	for _, backend := range backends {
+		_, err := backend.Describe(context.Background(), "udid")
+		if !types.IsXcodeError(err, types.ErrCodeBackendUnavailable) {
+			t.Errorf("%s: expected BACKEND_UNAVAILABLE, got %v", backend.Name(), err)
+		}
+	}
+}
+
+func TestCLIBackend_InputArgs(t *testing.T) {
+	axe, idb := NewAXeBackend(nil), NewIDBBackend(nil)
+
+	tests := []struct {
+		name string
+		got  []string
+		want string
+	}{
+		{"axe tap", axe.tapArgs("U", 196.5, 341, 0), "tap -x 197 -y 341 --udid U"},
+		{"axe long press", axe.tapArgs("U", 10, 20, 1500*time.Millisecond), "touch -x 10 -y 20 --down --up --delay 1.5 --udid U"},
+		{"axe swipe", axe.swipeArgs("U", 100, 600, 100, 200, 300*time.Millisecond),
+			"swipe --start-x 100 --start-y 600 --end-x 100 --end-y 200 --duration 0.3 --udid U"},
+		{"axe type", axe.typeArgs("U", "hi there"), "type --udid U -- hi there"},
+		{"axe type dash", axe.typeArgs("U", "-x"), "type --udid U -- -x"},
+		{"idb tap", idb.tapArgs("U", 10, 20, 0), "ui tap 10 20 --udid U"},
+		{"idb long press", idb.tapArgs("U", 10, 20, 2*time.Second), "ui tap 10 20 --udid U --duration 2"},
+		{"idb swipe", idb.swipeArgs("U", 1, 2, 3, 4, time.Second), "ui swipe 1 2 3 4 --duration 1 --udid U"},
+		{"idb type", idb.typeArgs("U", "hi"), "ui text --udid U -- hi"},
+		{"idb type dash", idb.typeArgs("U", "--help"), "ui text --udid U -- --help"},
+	}
+
+	for _, tt := range tests {
+		if got := strings.Join(tt.got, " "); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDetect_Runner(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	if backend := Detect(nil); backend.Name() != "none" {
+		t.Fatalf("Expected no backend without a CLI on PATH, got %s", backend.Name())
+	}
+
+	fixture, err := os.ReadFile("testdata/axe_login.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	replay := xcode.NewReplayRunner([]xcode.Fixture{
+		{Args: []string{"axe", "describe-ui", "--udid", "U"}, Stdout: string(fixture)},
+	})
+
+	// A replayed session finds its backend without AXe installed
+	backend := Detect(replay)
+	if backend.Name() != BackendAXe {
+		t.Fatalf("Expected AXe when replaying, got %s", backend.Name())
+	}
+	if _, err := backend.Describe(context.Background(), "U"); err != nil {
+		t.Errorf("Describe failed: %v", err)
+	}
+}
+
+// runnerFunc adapts a function to xcode.CommandRunner
+type runnerFunc func(ctx context.Context, cmd xcode.Command) (xcode.ExitStatus, error)
+
+func (f runnerFunc) Run(ctx context.Context, cmd xcode.Command) (xcode.ExitStatus, error) {
+	return f(ctx, cmd)
+}
+
+func TestCLIBackend_Runner(t *testing.T) {
+	fixture, err := os.ReadFile("testdata/axe_login.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var commands []string
+	exitCode := 0
+	runner := runnerFunc(func(ctx context.Context, cmd xcode.Command) (xcode.ExitStatus, error) {
+		commands = append(commands, cmd.String())
+		if exitCode != 0 {
+			io.WriteString(cmd.Stderr, "simulator not booted\n")
+			return xcode.ExitStatus{ExitCode: exitCode}, nil
+		}
+		cmd.Stdout.Write(fixture)
+		return xcode.ExitStatus{}, nil
+	})
+	backend := NewAXeBackend(runner)
+
+	root, err := backend.Describe(context.Background(), "U")
+	if err != nil {
+		t.Fatalf("Describe failed: %v", err)
+	}
+	if findByIdentifier(root, "login.email") == nil {
+		t.Error("Expected the hierarchy printed through the runner")
+	}
+	if len(commands) != 1 || commands[0] != "axe describe-ui --udid U" {
+		t.Errorf("commands = %q", commands)
+	}
+
+	exitCode = 1
+	err = backend.Tap(context.Background(), "U", 1, 2, 0)
+	if !types.IsXcodeError(err, types.ErrCodeCommandFailed) {
+		t.Fatalf("expected COMMAND_FAILED, got %v", err)
+	}
+	if details := err.(*types.XcodeError).Details; details["exit_code"] != 1 || details["output"] != "simulator not booted" {
+		t.Errorf("details = %v", details)
+	}
+}
@@ -0,0 +1,23 @@
+package common
+
+import "context"
+
+// ProgressFunc receives progress updates for a running tool call. Progress
+// must increase with every call; total is zero when the amount of remaining
+// work is unknown.
+type ProgressFunc func(progress, total float64, message string)
+
+type progressKey struct{}
+
+// WithProgress returns a context carrying fn, so tools can report progress
+// without knowing about the transport
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// ProgressFromContext returns the progress callback attached to ctx, or nil
+// when the caller did not ask for progress
+func ProgressFromContext(ctx context.Context) ProgressFunc {
+	fn, _ := ctx.Value(progressKey{}).(ProgressFunc)
+	return fn
+}
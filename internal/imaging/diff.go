@@ -0,0 +1,198 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+)
+
+// regionCell is the size in pixels of the grid cells that group changed
+// pixels into regions. Changes in touching cells form one region, so a
+// re-rendered label is reported once rather than glyph by glyph.
+const regionCell = 16
+
+var (
+	changedColor = color.NRGBA{R: 255, A: 255}
+	outlineColor = color.NRGBA{R: 255, B: 255, A: 255}
+	ignoredColor = color.NRGBA{R: 80, G: 120, B: 200, A: 255}
+)
+
+// DiffOptions controls how Diff compares two images
+type DiffOptions struct {
+	// Tolerance is the largest difference in any channel (0-255) that still
+	// counts as equal, absorbing anti-aliasing and compression noise
+	Tolerance int
+	// Ignore lists regions, in pixels, that are not compared
+	Ignore []image.Rectangle
+	// Mask excludes every pixel where it is not fully transparent
+	Mask image.Image
+}
+
+// DiffResult describes how two images differ. Image shows the baseline faded
+// to grey with changed pixels in red, changed regions outlined in magenta and
+// ignored pixels in blue.
+type DiffResult struct {
+	Width          int
+	Height         int
+	ChangedPixels  int
+	ComparedPixels int
+	Regions        []image.Rectangle
+	Image          *image.NRGBA
+}
+
+// MismatchPercent is the share of compared pixels that changed
+func (r *DiffResult) MismatchPercent() float64 {
+	if r.ComparedPixels == 0 {
+		return 0
+	}
+	return float64(r.ChangedPixels) * 100 / float64(r.ComparedPixels)
+}
+
+// Diff compares actual against baseline pixel by pixel. Images of different
+// sizes are compared over the larger size, where pixels present in only one
+// image count as changed.
+func Diff(baseline, actual image.Image, opts DiffOptions) *DiffResult {
+	base, act := ToNRGBA(baseline), ToNRGBA(actual)
+	width := max(base.Rect.Dx(), act.Rect.Dx())
+	height := max(base.Rect.Dy(), act.Rect.Dy())
+
+	var mask *image.NRGBA
+	if opts.Mask != nil {
+		mask = ToNRGBA(opts.Mask)
+	}
+
+	result := &DiffResult{
+		Width:  width,
+		Height: height,
+		Image:  image.NewNRGBA(image.Rect(0, 0, width, height)),
+	}
+
+	cols, rows := (width+regionCell-1)/regionCell, (height+regionCell-1)/regionCell
+	cells := make([]image.Rectangle, cols*rows)
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			point := image.Point{X: x, Y: y}
+			inBase, inActual := point.In(base.Rect), point.In(act.Rect)
+
+			var faded color.NRGBA
+			if inBase {
+				faded = fade(base.NRGBAAt(x, y))
+			} else if inActual {
+				faded = fade(act.NRGBAAt(x, y))
+			}
+
+			if ignored(point, opts.Ignore, mask) {
+				result.Image.SetNRGBA(x, y, blend(faded, ignoredColor))
+				continue
+			}
+
+			result.ComparedPixels++
+			if inBase && inActual && channelDiff(base.NRGBAAt(x, y), act.NRGBAAt(x, y)) <= opts.Tolerance {
+				result.Image.SetNRGBA(x, y, faded)
+				continue
+			}
+
+			result.ChangedPixels++
+			result.Image.SetNRGBA(x, y, changedColor)
+			cell := &cells[(y/regionCell)*cols+x/regionCell]
+			*cell = cell.Union(image.Rect(x, y, x+1, y+1))
+		}
+	}
+
+	result.Regions = regions(cells, cols, rows)
+	for _, region := range result.Regions {
+		outline(result.Image, region)
+	}
+	return result
+}
+
+func ignored(p image.Point, regions []image.Rectangle, mask *image.NRGBA) bool {
+	for _, region := range regions {
+		if p.In(region) {
+			return true
+		}
+	}
+	return mask != nil && p.In(mask.Rect) && mask.NRGBAAt(p.X, p.Y).A > 0
+}
+
+// channelDiff returns the largest difference between the channels of a and b
+func channelDiff(a, b color.NRGBA) int {
+	diff := 0
+	for _, d := range []int{
+		int(a.R) - int(b.R), int(a.G) - int(b.G), int(a.B) - int(b.B), int(a.A) - int(b.A),
+	} {
+		if d < 0 {
+			d = -d
+		}
+		diff = max(diff, d)
+	}
+	return diff
+}
+
+// fade turns c into a light grey that keeps the layout recognisable behind
+// the highlighted changes
+func fade(c color.NRGBA) color.NRGBA {
+	luma := (299*int(c.R) + 587*int(c.G) + 114*int(c.B)) / 1000
+	v := uint8(255 - (255-luma)/4)
+	return color.NRGBA{R: v, G: v, B: v, A: 255}
+}
+
+func blend(a, b color.NRGBA) color.NRGBA {
+	return color.NRGBA{
+		R: uint8((int(a.R) + int(b.R)) / 2),
+		G: uint8((int(a.G) + int(b.G)) / 2),
+		B: uint8((int(a.B) + int(b.B)) / 2),
+		A: 255,
+	}
+}
+
+// regions joins grid cells with changes into 8-connected groups and returns
+// the bounding box of the changed pixels of each, top to bottom
+func regions(cells []image.Rectangle, cols, rows int) []image.Rectangle {
+	var result []image.Rectangle
+	visited := make([]bool, len(cells))
+
+	for start := range cells {
+		if visited[start] || cells[start].Empty() {
+			continue
+		}
+
+		var box image.Rectangle
+		stack := []int{start}
+		visited[start] = true
+		for len(stack) > 0 {
+			i := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			box = box.Union(cells[i])
+
+			cx, cy := i%cols, i/cols
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					nx, ny := cx+dx, cy+dy
+					if nx < 0 || ny < 0 || nx >= cols || ny >= rows {
+						continue
+					}
+					if n := ny*cols + nx; !visited[n] && !cells[n].Empty() {
+						visited[n] = true
+						stack = append(stack, n)
+					}
+				}
+			}
+		}
+		result = append(result, box)
+	}
+	return result
+}
+
+// outline draws a one pixel border just outside r, clipped to the image
+func outline(img *image.NRGBA, r image.Rectangle) {
+	border := r.Inset(-1).Intersect(img.Rect)
+	for x := border.Min.X; x < border.Max.X; x++ {
+		img.SetNRGBA(x, border.Min.Y, outlineColor)
+		img.SetNRGBA(x, border.Max.Y-1, outlineColor)
+	}
+	for y := border.Min.Y; y < border.Max.Y; y++ {
+		img.SetNRGBA(border.Min.X, y, outlineColor)
+		img.SetNRGBA(border.Max.X-1, y, outlineColor)
+	}
+}
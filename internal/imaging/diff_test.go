@@ -0,0 +1,108 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+var (
+	white = color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+	black = color.NRGBA{A: 255}
+)
+
+// paint fills r in a copy of img with c
+func paint(img *image.NRGBA, r image.Rectangle, c color.NRGBA) *image.NRGBA {
+	painted := image.NewNRGBA(img.Rect)
+	copy(painted.Pix, img.Pix)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			painted.SetNRGBA(x, y, c)
+		}
+	}
+	return painted
+}
+
+func TestDiff_Identical(t *testing.T) {
+	img := solidImage(40, 30, white)
+	result := Diff(img, img, DiffOptions{})
+
+	if result.ChangedPixels != 0 || len(result.Regions) != 0 || result.MismatchPercent() != 0 {
+		t.Errorf("Expected no differences, got %+v", result)
+	}
+	if result.ComparedPixels != 1200 || result.Image.Rect.Dx() != 40 {
+		t.Errorf("Unexpected comparison size: %+v", result)
+	}
+}
+
+func TestDiff_Tolerance(t *testing.T) {
+	base := solidImage(10, 10, color.NRGBA{R: 100, G: 100, B: 100, A: 255})
+	actual := solidImage(10, 10, color.NRGBA{R: 104, G: 100, B: 97, A: 255})
+
+	if got := Diff(base, actual, DiffOptions{Tolerance: 4}).ChangedPixels; got != 0 {
+		t.Errorf("Expected differences within tolerance to match, got %d changed", got)
+	}
+	if got := Diff(base, actual, DiffOptions{Tolerance: 3}).ChangedPixels; got != 100 {
+		t.Errorf("Expected every pixel to change, got %d", got)
+	}
+}
+
+func TestDiff_Regions(t *testing.T) {
+	base := solidImage(200, 100, white)
+	actual := paint(base, image.Rect(10, 10, 30, 20), black)
+	actual = paint(actual, image.Rect(150, 60, 160, 90), black)
+
+	result := Diff(base, actual, DiffOptions{})
+
+	if result.ChangedPixels != 500 {
+		t.Errorf("Expected 500 changed pixels, got %d", result.ChangedPixels)
+	}
+	want := []image.Rectangle{image.Rect(10, 10, 30, 20), image.Rect(150, 60, 160, 90)}
+	if len(result.Regions) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, result.Regions)
+	}
+	for i := range want {
+		if result.Regions[i] != want[i] {
+			t.Errorf("Region %d: expected %v, got %v", i, want[i], result.Regions[i])
+		}
+	}
+	if got := result.MismatchPercent(); got != 2.5 {
+		t.Errorf("Expected 2.5%% mismatch, got %v", got)
+	}
+
+	if c := result.Image.NRGBAAt(15, 15); c != changedColor {
+		t.Errorf("Expected changed pixels in red, got %v", c)
+	}
+	if c := result.Image.NRGBAAt(9, 9); c != outlineColor {
+		t.Errorf("Expected the region outlined, got %v", c)
+	}
+}
+
+func TestDiff_IgnoreRegionsAndMask(t *testing.T) {
+	base := solidImage(100, 100, white)
+	actual := paint(base, image.Rect(0, 0, 100, 10), black)   // status bar
+	actual = paint(actual, image.Rect(40, 40, 60, 60), black) // masked avatar
+
+	mask := image.NewNRGBA(image.Rect(0, 0, 100, 100))
+	mask = paint(mask, image.Rect(40, 40, 60, 60), black)
+
+	result := Diff(base, actual, DiffOptions{Ignore: []image.Rectangle{image.Rect(0, 0, 100, 10)}, Mask: mask})
+
+	if result.ChangedPixels != 0 || len(result.Regions) != 0 {
+		t.Errorf("Expected ignored changes to be skipped, got %+v", result.Regions)
+	}
+	if result.ComparedPixels != 10000-1000-400 {
+		t.Errorf("Expected ignored pixels excluded from the comparison, got %d", result.ComparedPixels)
+	}
+}
+
+func TestDiff_SizeMismatch(t *testing.T) {
+	result := Diff(solidImage(10, 10, white), solidImage(10, 12, white), DiffOptions{})
+
+	if result.Width != 10 || result.Height != 12 || result.ChangedPixels != 20 {
+		t.Errorf("Expected the extra rows to count as changed, got %+v", result)
+	}
+	if len(result.Regions) != 1 || result.Regions[0] != image.Rect(0, 10, 10, 12) {
+		t.Errorf("Unexpected regions: %v", result.Regions)
+	}
+}
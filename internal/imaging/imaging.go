@@ -0,0 +1,141 @@
+// Package imaging decodes, downscales and re-encodes screenshots in pure Go,
+// so image handling works without sips or other macOS tools.
+package imaging
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"strings"
+)
+
+// DefaultJPEGQuality balances legibility of UI text against payload size
+const DefaultJPEGQuality = 80
+
+// Load decodes a PNG or JPEG file
+func Load(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open image: %w", err)
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image %s: %w", path, err)
+	}
+	return img, nil
+}
+
+// Dimensions reads only the header of an image file and returns its size
+func Dimensions(path string) (int, int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to open image: %w", err)
+	}
+	defer file.Close()
+
+	config, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read image header: %w", err)
+	}
+	return config.Width, config.Height, nil
+}
+
+// ToNRGBA returns img as *image.NRGBA with its origin at 0,0, converting only
+// when needed
+func ToNRGBA(img image.Image) *image.NRGBA {
+	if nrgba, ok := img.(*image.NRGBA); ok && nrgba.Rect.Min == (image.Point{}) {
+		return nrgba
+	}
+
+	bounds := img.Bounds()
+	nrgba := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(nrgba, nrgba.Rect, img, bounds.Min, draw.Src)
+	return nrgba
+}
+
+// Fit scales img down so neither side exceeds maxDimension, preserving the
+// aspect ratio. Images that already fit, or a maxDimension of zero or less,
+// are returned unchanged. Each destination pixel averages the source pixels
+// it covers, which keeps small UI text readable.
+func Fit(img image.Image, maxDimension int) image.Image {
+	bounds := img.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	if maxDimension <= 0 || (srcW <= maxDimension && srcH <= maxDimension) {
+		return img
+	}
+
+	dstW, dstH := maxDimension, maxDimension
+	if srcW >= srcH {
+		dstH = max(1, srcH*maxDimension/srcW)
+	} else {
+		dstW = max(1, srcW*maxDimension/srcH)
+	}
+
+	src := ToNRGBA(img)
+	dst := image.NewNRGBA(image.Rect(0, 0, dstW, dstH))
+
+	for y := 0; y < dstH; y++ {
+		sy0 := y * srcH / dstH
+		sy1 := max(sy0+1, (y+1)*srcH/dstH)
+
+		for x := 0; x < dstW; x++ {
+			sx0 := x * srcW / dstW
+			sx1 := max(sx0+1, (x+1)*srcW/dstW)
+
+			var r, g, b, a, n uint64
+			for sy := sy0; sy < sy1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := sx0; sx < sx1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r += uint64(p[0])
+					g += uint64(p[1])
+					b += uint64(p[2])
+					a += uint64(p[3])
+					n++
+				}
+			}
+
+			o := dst.PixOffset(x, y)
+			dst.Pix[o] = uint8(r / n)
+			dst.Pix[o+1] = uint8(g / n)
+			dst.Pix[o+2] = uint8(b / n)
+			dst.Pix[o+3] = uint8(a / n)
+		}
+	}
+
+	return dst
+}
+
+// Encode encodes img as "png" or "jpeg" (alias "jpg") and returns the data
+// with its MIME type. quality applies to JPEG only; zero selects
+// DefaultJPEGQuality.
+func Encode(img image.Image, format string, quality int) ([]byte, string, error) {
+	var buf bytes.Buffer
+
+	switch strings.ToLower(format) {
+	case "png":
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, "", fmt.Errorf("failed to encode png: %w", err)
+		}
+		return buf.Bytes(), "image/png", nil
+	case "jpeg", "jpg":
+		if quality <= 0 {
+			quality = DefaultJPEGQuality
+		}
+		if quality > 100 {
+			quality = 100
+		}
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+			return nil, "", fmt.Errorf("failed to encode jpeg: %w", err)
+		}
+		return buf.Bytes(), "image/jpeg", nil
+	default:
+		return nil, "", fmt.Errorf("unsupported image format: %s (supported: png, jpeg)", format)
+	}
+}
@@ -0,0 +1,135 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func solidImage(w, h int, c color.NRGBA) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func writePNG(t *testing.T, img image.Image) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "image.png")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	if err := png.Encode(file, img); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFit_PreservesAspectRatio(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int
+		maxDimension  int
+		wantW, wantH  int
+	}{
+		{"portrait", 1179, 2556, 1024, 472, 1024},
+		{"landscape", 2000, 1000, 500, 500, 250},
+		{"already fits", 300, 600, 1024, 300, 600},
+		{"disabled", 3000, 3000, 0, 3000, 3000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := image.NewNRGBA(image.Rect(0, 0, tt.width, tt.height))
+			bounds := Fit(img, tt.maxDimension).Bounds()
+			if bounds.Dx() != tt.wantW || bounds.Dy() != tt.wantH {
+				t.Errorf("Fit() = %dx%d, want %dx%d", bounds.Dx(), bounds.Dy(), tt.wantW, tt.wantH)
+			}
+		})
+	}
+}
+
+func TestFit_AveragesPixels(t *testing.T) {
+	// Left half black, right half white: a 2x1 downscale to 1x1 is mid grey
+	img := solidImage(2, 1, color.NRGBA{0, 0, 0, 255})
+	img.SetNRGBA(1, 0, color.NRGBA{255, 255, 255, 255})
+
+	scaled := ToNRGBA(Fit(img, 1))
+	got := scaled.NRGBAAt(0, 0)
+	if got.R != 127 || got.G != 127 || got.B != 127 || got.A != 255 {
+		t.Errorf("Averaged pixel = %+v, want mid grey", got)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	img := solidImage(8, 8, color.NRGBA{200, 30, 30, 255})
+
+	tests := []struct {
+		format   string
+		wantMime string
+	}{
+		{"png", "image/png"},
+		{"jpeg", "image/jpeg"},
+		{"JPG", "image/jpeg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			data, mime, err := Encode(img, tt.format, 0)
+			if err != nil {
+				t.Fatalf("Encode failed: %v", err)
+			}
+			if mime != tt.wantMime {
+				t.Errorf("mime = %s, want %s", mime, tt.wantMime)
+			}
+			decoded, _, err := image.Decode(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("Encoded data does not decode: %v", err)
+			}
+			if decoded.Bounds().Dx() != 8 {
+				t.Errorf("Decoded width = %d, want 8", decoded.Bounds().Dx())
+			}
+		})
+	}
+
+	if _, _, err := Encode(img, "gif", 0); err == nil {
+		t.Error("Expected error for unsupported format")
+	}
+}
+
+func TestLoadAndDimensions(t *testing.T) {
+	path := writePNG(t, solidImage(30, 20, color.NRGBA{0, 0, 255, 255}))
+
+	w, h, err := Dimensions(path)
+	if err != nil {
+		t.Fatalf("Dimensions failed: %v", err)
+	}
+	if w != 30 || h != 20 {
+		t.Errorf("Dimensions() = %dx%d, want 30x20", w, h)
+	}
+
+	img, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if img.Bounds().Dx() != 30 {
+		t.Errorf("Loaded width = %d, want 30", img.Bounds().Dx())
+	}
+
+	notImage := filepath.Join(t.TempDir(), "note.txt")
+	os.WriteFile(notImage, []byte("not an image"), 0644)
+	if _, err := Load(notImage); err == nil {
+		t.Error("Expected error loading a non-image file")
+	}
+}
@@ -0,0 +1,582 @@
+package mcp
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// SessionIDHeader carries the session identifier assigned on initialize
+	SessionIDHeader = "Mcp-Session-Id"
+
+	// maxRequestBodySize bounds a single POST body (JSON-RPC message or batch)
+	maxRequestBodySize = 4 << 20
+
+	// sessionIdleTimeout is how long a session with no open stream and no
+	// request in flight is kept before it is dropped
+	sessionIdleTimeout = 30 * time.Minute
+)
+
+// HTTPTransport implements the MCP streamable HTTP transport. Clients POST
+// JSON-RPC messages to the endpoint and receive the matching responses in the
+// HTTP reply; server-initiated messages are delivered over an SSE stream
+// opened with GET. Sessions are created on initialize and identified by the
+// Mcp-Session-Id header. Clients that vanish without a DELETE leave idle
+// sessions behind; those are dropped after sessionIdleTimeout.
+//
+// Requests from a browser page on another host are refused, so a web page
+// cannot reach the server through DNS rebinding.
+//
+// HTTPTransport is an http.Handler, so it can be mounted on any server or
+// exercised directly with httptest.
+type HTTPTransport struct {
+	logger *log.Logger
+
+	incoming chan *Request
+	done     chan struct{}
+	once     sync.Once
+
+	mu       sync.Mutex
+	sessions map[string]*httpSession
+	pending  map[string]*pendingRequest
+	seq      uint64
+
+	idleTimeout time.Duration
+}
+
+type httpSession struct {
+	id         string
+	streams    map[chan []byte]struct{}
+	lastActive time.Time
+}
+
+type pendingRequest struct {
+	sessionID  string
+	originalID interface{}
+	response   chan *Response
+
+	// cancelled is closed when the client cancels the request, since the
+	// server sends no response for it
+	cancelled chan struct{}
+}
+
+func NewHTTPTransport(logger *log.Logger) *HTTPTransport {
+	return &HTTPTransport{
+		logger:   logger,
+		incoming: make(chan *Request),
+		done:     make(chan struct{}),
+		sessions: make(map[string]*httpSession),
+		pending:  make(map[string]*pendingRequest),
+
+		idleTimeout: sessionIdleTimeout,
+	}
+}
+
+func (t *HTTPTransport) ReadRequest() (*Request, error) {
+	select {
+	case req := <-t.incoming:
+		return req, nil
+	case <-t.done:
+		return nil, fmt.Errorf("connection closed")
+	}
+}
+
+func (t *HTTPTransport) WriteResponse(resp *Response) error {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return fmt.Errorf("failed to marshal response: %w", err)
+	}
+
+	// Server-initiated messages have no ID and go out on the SSE streams
+	if resp.ID == nil {
+		t.broadcast(data)
+		return nil
+	}
+
+	key, ok := resp.ID.(string)
+	if !ok {
+		return fmt.Errorf("unexpected response ID type %T", resp.ID)
+	}
+
+	t.mu.Lock()
+	pending, exists := t.pending[key]
+	delete(t.pending, key)
+	t.mu.Unlock()
+
+	if !exists {
+		// The HTTP client went away before the response was ready
+		if t.logger != nil && os.Getenv("MCP_LOG_LEVEL") == "debug" {
+			t.logger.Printf("Dropping response for abandoned request %s", key)
+		}
+		return nil
+	}
+
+	resp.ID = pending.originalID
+	pending.response <- resp
+	return nil
+}
+
+// WriteNotification delivers a server-initiated message over SSE. Messages
+// tied to a request go only to the streams of the session that sent it.
+func (t *HTTPTransport) WriteNotification(requestID interface{}, notification *Notification) error {
+	data, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+
+	if key, ok := requestID.(string); ok {
+		t.mu.Lock()
+		pending, exists := t.pending[key]
+		var session *httpSession
+		if exists {
+			session = t.sessions[pending.sessionID]
+		}
+		if session != nil {
+			t.sendLocked(session, data)
+		}
+		t.mu.Unlock()
+
+		if session == nil && t.logger != nil && os.Getenv("MCP_LOG_LEVEL") == "debug" {
+			t.logger.Printf("Dropping notification for finished request %s", key)
+		}
+		return nil
+	}
+
+	t.broadcast(data)
+	return nil
+}
+
+func (t *HTTPTransport) Close() error {
+	t.once.Do(func() {
+		close(t.done)
+
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		for _, session := range t.sessions {
+			for stream := range session.streams {
+				close(stream)
+			}
+			session.streams = make(map[chan []byte]struct{})
+		}
+	})
+	return nil
+}
+
+func (t *HTTPTransport) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !allowedOrigin(r.Header.Get("Origin")) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		t.handlePost(w, r)
+	case http.MethodGet:
+		t.handleStream(w, r)
+	case http.MethodDelete:
+		t.handleDelete(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (t *HTTPTransport) handlePost(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestBodySize))
+	if err != nil {
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	if t.logger != nil && os.Getenv("MCP_LOG_LEVEL") == "debug" {
+		t.logger.Printf("Received: %s", string(body))
+	}
+
+	requests, batch, err := decodeMessages(body)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, &Response{
+			JSONRPC: "2.0",
+			Error: &Error{
+				Code:    -32700,
+				Message: "Parse error",
+				Data:    map[string]interface{}{"error": err.Error()},
+			},
+		})
+		return
+	}
+
+	sessionID, status, err := t.resolveSession(r, requests)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	if sessionID != "" {
+		w.Header().Set(SessionIDHeader, sessionID)
+	}
+
+	// Register every request before dispatching any of them so that a fast
+	// response can never race ahead of its pending entry. Messages without a
+	// method are client responses; the server never issues requests, so they
+	// are acknowledged and dropped.
+	var dispatch []*Request
+	var waiting []*pendingRequest
+	var keys []string
+	t.mu.Lock()
+	for _, req := range requests {
+		if req.Method == "" {
+			continue
+		}
+		if req.Method == "notifications/cancelled" {
+			t.rewriteCancellation(sessionID, req)
+		}
+		dispatch = append(dispatch, req)
+		if req.ID == nil {
+			continue
+		}
+		t.seq++
+		key := fmt.Sprintf("%s#%d", sessionID, t.seq)
+		pending := &pendingRequest{
+			sessionID:  sessionID,
+			originalID: req.ID,
+			response:   make(chan *Response, 1),
+			cancelled:  make(chan struct{}),
+		}
+		t.pending[key] = pending
+		req.ID = key
+		req.ctx = r.Context()
+		waiting = append(waiting, pending)
+		keys = append(keys, key)
+	}
+	t.mu.Unlock()
+
+	defer func() {
+		t.mu.Lock()
+		for _, key := range keys {
+			delete(t.pending, key)
+		}
+		if session := t.sessions[sessionID]; session != nil {
+			session.lastActive = time.Now()
+		}
+		t.mu.Unlock()
+	}()
+
+	for _, req := range dispatch {
+		select {
+		case t.incoming <- req:
+		case <-t.done:
+			http.Error(w, "server shutting down", http.StatusServiceUnavailable)
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	// Notifications and responses only: acknowledge without a body
+	if len(waiting) == 0 {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	responses := make([]*Response, 0, len(waiting))
+	for _, pending := range waiting {
+		select {
+		case resp := <-pending.response:
+			responses = append(responses, resp)
+		case <-pending.cancelled:
+			// A cancelled request gets no response
+		case <-t.done:
+			http.Error(w, "server shutting down", http.StatusServiceUnavailable)
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if len(responses) == 0 {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	if batch {
+		writeJSON(w, http.StatusOK, responses)
+		return
+	}
+	writeJSON(w, http.StatusOK, responses[0])
+}
+
+// rewriteCancellation points a notifications/cancelled message at the
+// transport-assigned ID of the request it names and stops the POST waiting
+// for it. Client IDs are only unique within a session, so the lookup is
+// scoped to the sender's session. The caller must hold t.mu.
+func (t *HTTPTransport) rewriteCancellation(sessionID string, req *Request) {
+	var params CancelledParams
+	if err := json.Unmarshal(req.Params, &params); err != nil || params.RequestID == nil {
+		return
+	}
+
+	target := requestKey(params.RequestID)
+	for key, pending := range t.pending {
+		if pending.sessionID != sessionID || requestKey(pending.originalID) != target {
+			continue
+		}
+		params.RequestID = key
+		if data, err := json.Marshal(params); err == nil {
+			req.Params = data
+		}
+		select {
+		case <-pending.cancelled:
+		default:
+			close(pending.cancelled)
+		}
+		return
+	}
+}
+
+// resolveSession returns the session the POST belongs to, creating a new one
+// when the body carries an initialize request.
+func (t *HTTPTransport) resolveSession(r *http.Request, requests []*Request) (string, int, error) {
+	for _, req := range requests {
+		if req.Method == "initialize" {
+			if len(requests) > 1 {
+				return "", http.StatusBadRequest, fmt.Errorf("initialize must not be part of a batch")
+			}
+			return t.createSession(), 0, nil
+		}
+	}
+
+	sessionID := r.Header.Get(SessionIDHeader)
+	if sessionID == "" {
+		return "", http.StatusBadRequest, fmt.Errorf("missing %s header", SessionIDHeader)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	session, exists := t.sessions[sessionID]
+	if exists && t.idleLocked(session, time.Now()) {
+		t.dropSessionLocked(session)
+		exists = false
+	}
+	if !exists {
+		return "", http.StatusNotFound, fmt.Errorf("unknown session")
+	}
+	session.lastActive = time.Now()
+
+	return sessionID, 0, nil
+}
+
+func (t *HTTPTransport) handleStream(w http.ResponseWriter, r *http.Request) {
+	if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		http.Error(w, "client must accept text/event-stream", http.StatusNotAcceptable)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	sessionID := r.Header.Get(SessionIDHeader)
+	stream := make(chan []byte, 16)
+
+	t.mu.Lock()
+	session, exists := t.sessions[sessionID]
+	if exists && t.idleLocked(session, time.Now()) {
+		t.dropSessionLocked(session)
+		exists = false
+	}
+	if exists {
+		session.streams[stream] = struct{}{}
+	}
+	t.mu.Unlock()
+
+	if !exists {
+		http.Error(w, "unknown session", http.StatusNotFound)
+		return
+	}
+
+	defer func() {
+		t.mu.Lock()
+		if _, open := session.streams[stream]; open {
+			delete(session.streams, stream)
+			close(stream)
+		}
+		session.lastActive = time.Now()
+		t.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set(SessionIDHeader, sessionID)
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case data, open := <-stream:
+			if !open {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "event: message\ndata: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-t.done:
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func (t *HTTPTransport) handleDelete(w http.ResponseWriter, r *http.Request) {
+	sessionID := r.Header.Get(SessionIDHeader)
+
+	t.mu.Lock()
+	session, exists := t.sessions[sessionID]
+	if exists {
+		t.dropSessionLocked(session)
+	}
+	t.mu.Unlock()
+
+	if !exists {
+		http.Error(w, "unknown session", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// createSession registers a new session, first dropping the idle ones so
+// abandoned sessions cannot pile up
+func (t *HTTPTransport) createSession() string {
+	id := newSessionID()
+	now := time.Now()
+
+	t.mu.Lock()
+	for _, session := range t.sessions {
+		if t.idleLocked(session, now) {
+			t.dropSessionLocked(session)
+		}
+	}
+	t.sessions[id] = &httpSession{
+		id:         id,
+		streams:    make(map[chan []byte]struct{}),
+		lastActive: now,
+	}
+	t.mu.Unlock()
+
+	return id
+}
+
+// idleLocked reports whether session has had no open stream and no request
+// in flight for longer than the idle timeout. The caller must hold t.mu.
+func (t *HTTPTransport) idleLocked(session *httpSession, now time.Time) bool {
+	if len(session.streams) > 0 || now.Sub(session.lastActive) < t.idleTimeout {
+		return false
+	}
+	for _, pending := range t.pending {
+		if pending.sessionID == session.id {
+			return false
+		}
+	}
+	return true
+}
+
+// dropSessionLocked closes the streams of session and forgets it. The caller
+// must hold t.mu.
+func (t *HTTPTransport) dropSessionLocked(session *httpSession) {
+	for stream := range session.streams {
+		close(stream)
+	}
+	session.streams = make(map[chan []byte]struct{})
+	delete(t.sessions, session.id)
+}
+
+// broadcast delivers a server-initiated message to every open SSE stream
+func (t *HTTPTransport) broadcast(data []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, session := range t.sessions {
+		t.sendLocked(session, data)
+	}
+}
+
+// sendLocked queues data on every SSE stream of session. Slow consumers miss
+// messages rather than stalling the server. The caller must hold t.mu.
+func (t *HTTPTransport) sendLocked(session *httpSession, data []byte) {
+	for stream := range session.streams {
+		select {
+		case stream <- data:
+		default:
+			if t.logger != nil {
+				t.logger.Printf("Dropping server message for slow stream in session %s", session.id)
+			}
+		}
+	}
+}
+
+// allowedOrigin reports whether a request with the given Origin header may
+// be served. Clients other than browsers send no Origin; browser pages are
+// only accepted from the local machine.
+func allowedOrigin(origin string) bool {
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// decodeMessages accepts either a single JSON-RPC message or a batch array
+func decodeMessages(body []byte) ([]*Request, bool, error) {
+	trimmed := strings.TrimSpace(string(body))
+	if strings.HasPrefix(trimmed, "[") {
+		var requests []*Request
+		if err := json.Unmarshal(body, &requests); err != nil {
+			return nil, true, fmt.Errorf("failed to unmarshal batch: %w", err)
+		}
+		if len(requests) == 0 {
+			return nil, true, fmt.Errorf("empty batch")
+		}
+		return requests, true, nil
+	}
+
+	var req Request
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, false, fmt.Errorf("failed to unmarshal request: %w", err)
+	}
+	return []*Request{&req}, false, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func newSessionID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		panic(fmt.Sprintf("failed to generate session ID: %v", err))
+	}
+	return hex.EncodeToString(buf)
+}
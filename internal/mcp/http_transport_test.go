@@ -0,0 +1,534 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newHTTPTestServer runs a Server behind an HTTPTransport mounted on
+// httptest, with extra tools registered
+func newHTTPTestServer(t *testing.T, extra ...Tool) (*HTTPTransport, *httptest.Server) {
+	t.Helper()
+
+	logger := log.New(bytes.NewBuffer(nil), "", 0)
+	server, err := NewServer(logger)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	for _, tool := range extra {
+		if err := server.registry.Register(tool); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	transport := NewHTTPTransport(logger)
+	server.transport = transport
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go server.serve(ctx)
+
+	ts := httptest.NewServer(transport)
+	t.Cleanup(func() {
+		transport.Close()
+		ts.Close()
+		cancel()
+	})
+
+	return transport, ts
+}
+
+func postJSON(t *testing.T, url, sessionID, body string) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	if sessionID != "" {
+		req.Header.Set(SessionIDHeader, sessionID)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	return resp
+}
+
+func initializeSession(t *testing.T, url string) string {
+	t.Helper()
+
+	resp := postJSON(t, url, "", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"test","version":"1.0"}}}`)
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("initialize status = %d, want 200", resp.StatusCode)
+	}
+
+	sessionID := resp.Header.Get(SessionIDHeader)
+	if sessionID == "" {
+		t.Fatal("initialize should return a session ID")
+	}
+	return sessionID
+}
+
+func TestHTTPTransport_Initialize(t *testing.T) {
+	_, ts := newHTTPTestServer(t)
+
+	resp := postJSON(t, ts.URL, "", `{"jsonrpc":"2.0","id":7,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"test","version":"1.0"}}}`)
+	defer resp.Body.Close()
+
+	if resp.Header.Get(SessionIDHeader) == "" {
+		t.Error("Expected Mcp-Session-Id header on initialize")
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+
+	var decoded Response
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if decoded.Error != nil {
+		t.Fatalf("Initialize failed: %v", decoded.Error)
+	}
+	// The client's own ID must be restored on the way out
+	if id, ok := decoded.ID.(float64); !ok || id != 7 {
+		t.Errorf("Response ID = %v, want 7", decoded.ID)
+	}
+}
+
+func TestHTTPTransport_ToolsList(t *testing.T) {
+	_, ts := newHTTPTestServer(t)
+	sessionID := initializeSession(t, ts.URL)
+
+	resp := postJSON(t, ts.URL, sessionID, `{"jsonrpc":"2.0","id":"list","method":"tools/list"}`)
+	defer resp.Body.Close()
+
+	var decoded struct {
+		ID     string          `json:"id"`
+		Result ListToolsResult `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if decoded.ID != "list" {
+		t.Errorf("Response ID = %q, want list", decoded.ID)
+	}
+	if len(decoded.Result.Tools) == 0 {
+		t.Error("Expected tools in tools/list result")
+	}
+}
+
+func TestHTTPTransport_SessionValidation(t *testing.T) {
+	_, ts := newHTTPTestServer(t)
+
+	tests := []struct {
+		name      string
+		sessionID string
+		want      int
+	}{
+		{"missing session", "", http.StatusBadRequest},
+		{"unknown session", "does-not-exist", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := postJSON(t, ts.URL, tt.sessionID, `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+			resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPTransport_Notification(t *testing.T) {
+	_, ts := newHTTPTestServer(t)
+	sessionID := initializeSession(t, ts.URL)
+
+	resp := postJSON(t, ts.URL, sessionID, `{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		t.Errorf("status = %d, want 202", resp.StatusCode)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if len(body) != 0 {
+		t.Errorf("Notification should have no body, got %q", body)
+	}
+}
+
+func TestHTTPTransport_Batch(t *testing.T) {
+	_, ts := newHTTPTestServer(t)
+	sessionID := initializeSession(t, ts.URL)
+
+	resp := postJSON(t, ts.URL, sessionID, `[{"jsonrpc":"2.0","id":1,"method":"tools/list"},{"jsonrpc":"2.0","method":"notifications/initialized"},{"jsonrpc":"2.0","id":2,"method":"unknown/method"}]`)
+	defer resp.Body.Close()
+
+	var decoded []Response
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		t.Fatalf("Failed to decode batch response: %v", err)
+	}
+	if len(decoded) != 2 {
+		t.Fatalf("Expected 2 responses, got %d", len(decoded))
+	}
+	if decoded[1].Error == nil || decoded[1].Error.Code != -32601 {
+		t.Errorf("Expected method not found for second request, got %+v", decoded[1].Error)
+	}
+}
+
+func TestHTTPTransport_ParseError(t *testing.T) {
+	_, ts := newHTTPTestServer(t)
+
+	resp := postJSON(t, ts.URL, "", `{not json`)
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", resp.StatusCode)
+	}
+
+	var decoded Response
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if decoded.Error == nil || decoded.Error.Code != -32700 {
+		t.Errorf("Expected parse error, got %+v", decoded.Error)
+	}
+}
+
+func TestHTTPTransport_EventStream(t *testing.T) {
+	transport, ts := newHTTPTestServer(t)
+	sessionID := initializeSession(t, ts.URL)
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL, nil)
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set(SessionIDHeader, sessionID)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q, want text/event-stream", ct)
+	}
+
+	// Wait for the stream to register before broadcasting
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		transport.mu.Lock()
+		registered := len(transport.sessions[sessionID].streams) > 0
+		transport.mu.Unlock()
+		if registered {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("SSE stream was never registered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := transport.WriteResponse(&Response{JSONRPC: "2.0", Result: "hello"}); err != nil {
+		t.Fatalf("WriteResponse failed: %v", err)
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	var dataLine string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read event: %v", err)
+		}
+		if strings.HasPrefix(line, "data: ") {
+			dataLine = strings.TrimSpace(strings.TrimPrefix(line, "data: "))
+			break
+		}
+	}
+
+	var decoded Response
+	if err := json.Unmarshal([]byte(dataLine), &decoded); err != nil {
+		t.Fatalf("Failed to decode event data: %v", err)
+	}
+	if decoded.Result != "hello" {
+		t.Errorf("Result = %v, want hello", decoded.Result)
+	}
+}
+
+func TestHTTPTransport_EventStreamRequiresAccept(t *testing.T) {
+	_, ts := newHTTPTestServer(t)
+	sessionID := initializeSession(t, ts.URL)
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL, nil)
+	req.Header.Set(SessionIDHeader, sessionID)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNotAcceptable {
+		t.Errorf("status = %d, want 406", resp.StatusCode)
+	}
+}
+
+func TestHTTPTransport_DeleteSession(t *testing.T) {
+	_, ts := newHTTPTestServer(t)
+	sessionID := initializeSession(t, ts.URL)
+
+	req, _ := http.NewRequest(http.MethodDelete, ts.URL, nil)
+	req.Header.Set(SessionIDHeader, sessionID)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("DELETE failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("DELETE status = %d, want 204", resp.StatusCode)
+	}
+
+	resp = postJSON(t, ts.URL, sessionID, `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("POST after DELETE status = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestHTTPTransport_IdleSessionsExpire(t *testing.T) {
+	transport, ts := newHTTPTestServer(t)
+	idle := initializeSession(t, ts.URL)
+	streaming := initializeSession(t, ts.URL)
+
+	// Keep an SSE stream open on one session and age both past the timeout
+	req, _ := http.NewRequest(http.MethodGet, ts.URL, nil)
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set(SessionIDHeader, streaming)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	transport.mu.Lock()
+	for _, session := range transport.sessions {
+		session.lastActive = time.Now().Add(-2 * sessionIdleTimeout)
+	}
+	transport.mu.Unlock()
+
+	initializeSession(t, ts.URL)
+
+	transport.mu.Lock()
+	_, idleKept := transport.sessions[idle]
+	_, streamingKept := transport.sessions[streaming]
+	transport.mu.Unlock()
+	if idleKept {
+		t.Error("Expected the idle session to be dropped when a new session was created")
+	}
+	if !streamingKept {
+		t.Error("Expected the session with an open stream to be kept")
+	}
+
+	resp = postJSON(t, ts.URL, idle, `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("POST to an expired session status = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestHTTPTransport_Origin(t *testing.T) {
+	_, ts := newHTTPTestServer(t)
+
+	tests := map[string]int{
+		"http://evil.example":   http.StatusForbidden,
+		"null":                  http.StatusForbidden,
+		"http://localhost:3000": http.StatusOK,
+		"http://127.0.0.1:8080": http.StatusOK,
+		"http://[::1]":          http.StatusOK,
+	}
+	for origin, want := range tests {
+		req, _ := http.NewRequest(http.MethodPost, ts.URL, strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Origin", origin)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("Origin %s: status = %d, want %d", origin, resp.StatusCode, want)
+		}
+	}
+}
+
+// newCancellableTool returns a tool that blocks until its call is cancelled
+func newCancellableTool() *ctxTool {
+	return &ctxTool{
+		mockTool:  mockTool{name: "cancellable_tool", schema: map[string]interface{}{"type": "object"}},
+		started:   make(chan struct{}, 1),
+		cancelled: make(chan struct{}),
+	}
+}
+
+func TestHTTPTransport_CancelledRequestIsAccepted(t *testing.T) {
+	tool := newCancellableTool()
+	_, ts := newHTTPTestServer(t, tool)
+	sessionID := initializeSession(t, ts.URL)
+
+	status := make(chan int, 1)
+	go func() {
+		resp := postJSON(t, ts.URL, sessionID, `{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"cancellable_tool"}}`)
+		resp.Body.Close()
+		status <- resp.StatusCode
+	}()
+	<-tool.started
+
+	resp := postJSON(t, ts.URL, sessionID, `{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":3}}`)
+	resp.Body.Close()
+
+	select {
+	case code := <-status:
+		if code != http.StatusAccepted {
+			t.Errorf("Cancelled POST status = %d, want 202", code)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("The POST of a cancelled request was never answered")
+	}
+	select {
+	case <-tool.cancelled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Tool context was not cancelled")
+	}
+}
+
+func TestHTTPTransport_DisconnectCancelsToolCall(t *testing.T) {
+	tool := newCancellableTool()
+	_, ts := newHTTPTestServer(t, tool)
+	sessionID := initializeSession(t, ts.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, ts.URL,
+		strings.NewReader(`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"cancellable_tool"}}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SessionIDHeader, sessionID)
+	go func() {
+		if resp, err := http.DefaultClient.Do(req); err == nil {
+			resp.Body.Close()
+		}
+	}()
+
+	<-tool.started
+	cancel()
+
+	select {
+	case <-tool.cancelled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Disconnecting did not cancel the tool call")
+	}
+}
+
+func TestHTTPTransport_MethodNotAllowed(t *testing.T) {
+	_, ts := newHTTPTestServer(t)
+
+	req, _ := http.NewRequest(http.MethodPut, ts.URL, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("PUT failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("status = %d, want 405", resp.StatusCode)
+	}
+}
+
+func TestHTTPTransport_Close(t *testing.T) {
+	transport := NewHTTPTransport(log.New(bytes.NewBuffer(nil), "", 0))
+
+	if err := transport.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	// Close must be idempotent
+	if err := transport.Close(); err != nil {
+		t.Fatalf("Second Close failed: %v", err)
+	}
+
+	_, err := transport.ReadRequest()
+	if err == nil || !strings.Contains(err.Error(), "connection closed") {
+		t.Errorf("Expected connection closed error, got: %v", err)
+	}
+}
+
+func TestHTTPTransport_RewriteCancellation(t *testing.T) {
+	transport := NewHTTPTransport(nil)
+	other := &pendingRequest{sessionID: "a", originalID: float64(5), cancelled: make(chan struct{})}
+	target := &pendingRequest{sessionID: "b", originalID: float64(5), cancelled: make(chan struct{})}
+	transport.pending["a#1"] = other
+	transport.pending["b#2"] = target
+
+	req := &Request{JSONRPC: "2.0", Method: "notifications/cancelled", Params: json.RawMessage(`{"requestId":5,"reason":"timeout"}`)}
+	transport.rewriteCancellation("b", req)
+
+	var params CancelledParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		t.Fatal(err)
+	}
+	if params.RequestID != "b#2" {
+		t.Errorf("requestId = %v, want b#2", params.RequestID)
+	}
+	if params.Reason != "timeout" {
+		t.Errorf("reason = %q, want timeout", params.Reason)
+	}
+
+	select {
+	case <-target.cancelled:
+	default:
+		t.Error("Expected the cancelled request to stop waiting for a response")
+	}
+	select {
+	case <-other.cancelled:
+		t.Error("Expected the request of the other session to keep waiting")
+	default:
+	}
+}
+
+func TestHTTPTransport_WriteNotificationRoutesToSession(t *testing.T) {
+	transport := NewHTTPTransport(nil)
+	mine := make(chan []byte, 1)
+	other := make(chan []byte, 1)
+	transport.sessions["a"] = &httpSession{id: "a", streams: map[chan []byte]struct{}{mine: {}}}
+	transport.sessions["b"] = &httpSession{id: "b", streams: map[chan []byte]struct{}{other: {}}}
+	transport.pending["a#1"] = &pendingRequest{sessionID: "a", originalID: float64(1)}
+
+	notification := &Notification{JSONRPC: "2.0", Method: "notifications/progress", Params: ProgressParams{ProgressToken: "t", Progress: 1}}
+	if err := transport.WriteNotification("a#1", notification); err != nil {
+		t.Fatalf("WriteNotification failed: %v", err)
+	}
+
+	select {
+	case data := <-mine:
+		if !strings.Contains(string(data), `"notifications/progress"`) {
+			t.Errorf("Unexpected notification payload: %s", data)
+		}
+	default:
+		t.Error("Notification was not delivered to the requesting session")
+	}
+
+	select {
+	case data := <-other:
+		t.Errorf("Notification leaked to another session: %s", data)
+	default:
+	}
+}
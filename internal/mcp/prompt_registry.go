@@ -0,0 +1,79 @@
+package mcp
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Prompt is a templated message sequence offered to clients through
+// prompts/list and prompts/get
+type Prompt interface {
+	Name() string
+	Description() string
+	Arguments() []PromptArgument
+	Render(args map[string]string) (*GetPromptResult, error)
+}
+
+type PromptRegistry struct {
+	mu      sync.RWMutex
+	prompts map[string]Prompt
+}
+
+func NewPromptRegistry() *PromptRegistry {
+	return &PromptRegistry{
+		prompts: make(map[string]Prompt),
+	}
+}
+
+func (r *PromptRegistry) Register(prompt Prompt) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	name := prompt.Name()
+	if name == "" {
+		return fmt.Errorf("prompt name cannot be empty")
+	}
+
+	if _, exists := r.prompts[name]; exists {
+		return fmt.Errorf("prompt %s is already registered", name)
+	}
+
+	r.prompts[name] = prompt
+	return nil
+}
+
+func (r *PromptRegistry) GetPrompt(name string) Prompt {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.prompts[name]
+}
+
+// ListPrompts returns the prompt definitions sorted by name
+func (r *PromptRegistry) ListPrompts() []PromptDefinition {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	prompts := make([]PromptDefinition, 0, len(r.prompts))
+	for _, prompt := range r.prompts {
+		prompts = append(prompts, PromptDefinition{
+			Name:        prompt.Name(),
+			Description: prompt.Description(),
+			Arguments:   prompt.Arguments(),
+		})
+	}
+
+	sort.Slice(prompts, func(i, j int) bool {
+		return prompts[i].Name < prompts[j].Name
+	})
+
+	return prompts
+}
+
+func (r *PromptRegistry) Count() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.prompts)
+}
@@ -0,0 +1,206 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/jontolof/xcode-build-mcp/internal/runs"
+	"github.com/jontolof/xcode-build-mcp/pkg/types"
+)
+
+func TestPromptRegistry_RegisterAndList(t *testing.T) {
+	registry := NewPromptRegistry()
+	store := runs.NewStore(5)
+
+	if err := registry.Register(newTriageFailingTestsPrompt(store)); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if err := registry.Register(newFixFailingBuildPrompt(store)); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if err := registry.Register(newFixFailingBuildPrompt(store)); err == nil {
+		t.Error("Registering a duplicate prompt should fail")
+	}
+
+	prompts := registry.ListPrompts()
+	if len(prompts) != 2 {
+		t.Fatalf("Expected 2 prompts, got %d", len(prompts))
+	}
+	if prompts[0].Name != "fix_failing_build" || prompts[1].Name != "triage_failing_tests" {
+		t.Errorf("Prompts should be sorted by name, got %s, %s", prompts[0].Name, prompts[1].Name)
+	}
+	if registry.GetPrompt("missing") != nil {
+		t.Error("GetPrompt should return nil for unknown prompts")
+	}
+}
+
+func getPrompt(t *testing.T, server *Server, name string, args map[string]string) *Response {
+	t.Helper()
+
+	params, _ := json.Marshal(GetPromptParams{Name: name, Arguments: args})
+	return server.handleRequest(context.Background(), &Request{JSONRPC: "2.0", ID: 1, Method: "prompts/get", Params: params})
+}
+
+func promptText(t *testing.T, resp *Response) string {
+	t.Helper()
+
+	if resp.Error != nil {
+		t.Fatalf("prompts/get failed: %v", resp.Error.Data)
+	}
+	result := resp.Result.(*GetPromptResult)
+	if len(result.Messages) != 1 || result.Messages[0].Role != "user" {
+		t.Fatalf("Expected one user message, got %+v", result.Messages)
+	}
+	return result.Messages[0].Content.Text
+}
+
+func TestServer_ListPrompts(t *testing.T) {
+	server := newResourceTestServer(t)
+
+	resp := server.handleRequest(context.Background(), &Request{JSONRPC: "2.0", ID: 1, Method: "prompts/list"})
+	if resp.Error != nil {
+		t.Fatalf("prompts/list failed: %v", resp.Error)
+	}
+
+	result := resp.Result.(ListPromptsResult)
+	names := map[string]bool{}
+	for _, p := range result.Prompts {
+		names[p.Name] = true
+	}
+	for _, want := range []string{"fix_failing_build", "triage_failing_tests", "investigate_crash"} {
+		if !names[want] {
+			t.Errorf("Prompt %s not listed", want)
+		}
+	}
+}
+
+func TestFixFailingBuildPrompt_EmbedsLatestErrors(t *testing.T) {
+	server := newResourceTestServer(t)
+	server.runs.Add(&runs.Run{
+		Tool:    "xcode_build",
+		Command: "xcodebuild build -scheme App",
+		BuildResult: &types.BuildResult{
+			Errors: []types.BuildError{
+				{File: "/src/Login.swift", Line: 12, Column: 5, Message: "cannot find 'token' in scope"},
+			},
+		},
+	})
+
+	text := promptText(t, getPrompt(t, server, "fix_failing_build", map[string]string{"notes": "I renamed token yesterday"}))
+
+	for _, want := range []string{
+		"/src/Login.swift:12:5: cannot find 'token' in scope",
+		"xcodebuild build -scheme App",
+		"xcode://runs/1/log",
+		"I renamed token yesterday",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Prompt missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestFixFailingBuildPrompt_SucceededRun(t *testing.T) {
+	server := newResourceTestServer(t)
+	server.runs.Add(&runs.Run{Tool: "xcode_build", BuildResult: &types.BuildResult{Success: true}})
+
+	resp := getPrompt(t, server, "fix_failing_build", nil)
+	if resp.Error == nil {
+		t.Error("Expected an error when the latest build succeeded")
+	}
+}
+
+func TestFixFailingBuildPrompt_ExplicitErrorsWithoutRun(t *testing.T) {
+	server := newResourceTestServer(t)
+
+	text := promptText(t, getPrompt(t, server, "fix_failing_build", map[string]string{"errors": "main.swift:1: error: oops"}))
+	if !strings.Contains(text, "main.swift:1: error: oops") {
+		t.Errorf("Prompt should embed the provided errors:\n%s", text)
+	}
+}
+
+func TestFixFailingBuildPrompt_ExplicitErrorsWithUnknownRun(t *testing.T) {
+	server := newResourceTestServer(t)
+
+	resp := getPrompt(t, server, "fix_failing_build", map[string]string{"run_id": "7", "errors": "main.swift:1: error: oops"})
+	if resp.Error == nil {
+		t.Error("Expected an error when run_id names no run, even with errors given")
+	}
+}
+
+func TestTriageFailingTestsPrompt(t *testing.T) {
+	server := newResourceTestServer(t)
+	server.runs.Add(&runs.Run{
+		Tool: "xcode_test",
+		TestResult: &types.TestResult{
+			TestSummary: types.TestSummary{
+				TotalTests:  10,
+				FailedTests: 1,
+				FailedTestsDetails: []types.TestCase{
+					{ClassName: "LoginTests", Name: "testExpiredToken", Message: "XCTAssertTrue failed", Location: "LoginTests.swift:40"},
+				},
+			},
+		},
+	})
+
+	text := promptText(t, getPrompt(t, server, "triage_failing_tests", map[string]string{"run_id": "1"}))
+	for _, want := range []string{"1 of 10 tests failed", "LoginTests.testExpiredToken: XCTAssertTrue failed (LoginTests.swift:40)"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Prompt missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestTriageFailingTestsPrompt_WrongRunTool(t *testing.T) {
+	server := newResourceTestServer(t)
+	server.runs.Add(&runs.Run{Tool: "xcode_build", BuildResult: &types.BuildResult{}})
+
+	resp := getPrompt(t, server, "triage_failing_tests", map[string]string{"run_id": "1"})
+	if resp.Error == nil {
+		t.Error("Expected an error when run_id names a build run")
+	}
+}
+
+func TestInvestigateCrashPrompt(t *testing.T) {
+	server := newResourceTestServer(t)
+	server.runs.Add(&runs.Run{
+		Tool: "xcode_test",
+		TestResult: &types.TestResult{
+			CrashType:       types.CrashTypeSegmentationFault,
+			ProcessState:    &types.ProcessState{Signaled: true, SignalName: "segmentation fault"},
+			CrashIndicators: types.CrashIndicators{TestProcessCrashed: true},
+		},
+		CrashReports: []types.CrashReport{{ProcessName: "MyApp", ExceptionType: "EXC_BAD_ACCESS"}},
+	})
+
+	text := promptText(t, getPrompt(t, server, "investigate_crash", nil))
+	for _, want := range []string{
+		"Crash type: segmentation_fault",
+		"Signal: segmentation fault",
+		"Indicators: test_process_crashed",
+		"MyApp (EXC_BAD_ACCESS) xcode://runs/1/crashes/0",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Prompt missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestInvestigateCrashPrompt_NoCrash(t *testing.T) {
+	server := newResourceTestServer(t)
+	server.runs.Add(&runs.Run{Tool: "xcode_test", TestResult: &types.TestResult{CrashType: types.CrashTypeNone}})
+
+	if resp := getPrompt(t, server, "investigate_crash", nil); resp.Error == nil {
+		t.Error("Expected an error when no crash was recorded")
+	}
+}
+
+func TestServer_GetPrompt_Unknown(t *testing.T) {
+	server := newResourceTestServer(t)
+
+	if resp := getPrompt(t, server, "missing", nil); resp.Error == nil {
+		t.Error("Expected an error for an unknown prompt")
+	}
+}
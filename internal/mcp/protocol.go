@@ -1,6 +1,7 @@
 package mcp
 
 import (
+	"context"
 	"encoding/json"
 )
 
@@ -9,6 +10,18 @@ type Request struct {
 	Method  string          `json:"method"`
 	Params  json.RawMessage `json:"params,omitempty"`
 	ID      interface{}     `json:"id,omitempty"`
+
+	// ctx ends when the client stops waiting for the response, as when
+	// an HTTP client disconnects; nil for transports that cannot tell
+	ctx context.Context
+}
+
+// Context returns the context of the client waiting for the request
+func (r *Request) Context() context.Context {
+	if r.ctx == nil {
+		return context.Background()
+	}
+	return r.ctx
 }
 
 type Response struct {
@@ -18,6 +31,13 @@ type Response struct {
 	ID      interface{} `json:"id,omitempty"`
 }
 
+// Notification is a server-to-client JSON-RPC message that expects no reply
+type Notification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
 type Error struct {
 	Code    int         `json:"code"`
 	Message string      `json:"message"`
@@ -81,20 +101,23 @@ type ListToolsResult struct {
 }
 
 type ToolDefinition struct {
-	Name        string                 `json:"name"`
-	Description string                 `json:"description"`
-	InputSchema map[string]interface{} `json:"inputSchema"`
+	Name         string                 `json:"name"`
+	Description  string                 `json:"description"`
+	InputSchema  map[string]interface{} `json:"inputSchema"`
+	OutputSchema map[string]interface{} `json:"outputSchema,omitempty"`
 }
 
 type CallToolParams struct {
 	Name      string                 `json:"name"`
 	Arguments map[string]interface{} `json:"arguments,omitempty"`
+	Meta      *Meta                  `json:"_meta,omitempty"`
 }
 
 type CallToolResult struct {
-	Content []Content `json:"content"`
-	IsError *bool     `json:"isError,omitempty"`
-	Meta    *Meta     `json:"_meta,omitempty"`
+	Content           []Content   `json:"content"`
+	StructuredContent interface{} `json:"structuredContent,omitempty"`
+	IsError           *bool       `json:"isError,omitempty"`
+	Meta              *Meta       `json:"_meta,omitempty"`
 }
 
 type Content struct {
@@ -105,6 +128,79 @@ type Content struct {
 	Meta     map[string]interface{} `json:"meta,omitempty"`
 }
 
+// CancelledParams are the params of a notifications/cancelled message
+type CancelledParams struct {
+	RequestID interface{} `json:"requestId"`
+	Reason    string      `json:"reason,omitempty"`
+}
+
 type Meta struct {
-	ProgressToken string `json:"progressToken,omitempty"`
+	// ProgressToken is a string or an integer chosen by the client
+	ProgressToken interface{} `json:"progressToken,omitempty"`
+}
+
+// ProgressParams are the params of a notifications/progress message
+type ProgressParams struct {
+	ProgressToken interface{} `json:"progressToken"`
+	Progress      float64     `json:"progress"`
+	Total         float64     `json:"total,omitempty"`
+	Message       string      `json:"message,omitempty"`
+}
+
+type Resource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+type ListResourcesResult struct {
+	Resources []Resource `json:"resources"`
+}
+
+type ReadResourceParams struct {
+	URI string `json:"uri"`
+}
+
+type ReadResourceResult struct {
+	Contents []ResourceContents `json:"contents"`
+}
+
+// ResourceContents carries either Text or base64 encoded Blob
+type ResourceContents struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Blob     string `json:"blob,omitempty"`
+}
+
+type PromptArgument struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+type PromptDefinition struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	Arguments   []PromptArgument `json:"arguments,omitempty"`
+}
+
+type ListPromptsResult struct {
+	Prompts []PromptDefinition `json:"prompts"`
+}
+
+type GetPromptParams struct {
+	Name      string            `json:"name"`
+	Arguments map[string]string `json:"arguments,omitempty"`
+}
+
+type GetPromptResult struct {
+	Description string          `json:"description,omitempty"`
+	Messages    []PromptMessage `json:"messages"`
+}
+
+type PromptMessage struct {
+	Role    string  `json:"role"`
+	Content Content `json:"content"`
 }
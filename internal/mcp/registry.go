@@ -4,13 +4,18 @@ import (
 	"context"
 	"fmt"
 	"sync"
+
+	"github.com/jontolof/xcode-build-mcp/pkg/types"
 )
 
 type Tool interface {
 	Name() string
 	Description() string
 	InputSchema() map[string]interface{}
-	Execute(ctx context.Context, args map[string]interface{}) (string, error)
+	// OutputSchema describes the structured content of the tool's results,
+	// or is nil when the tool returns unstructured content only
+	OutputSchema() map[string]interface{}
+	Execute(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error)
 }
 
 type Registry struct {
@@ -62,9 +67,10 @@ func (r *Registry) ListTools() []ToolDefinition {
 	tools := make([]ToolDefinition, 0, len(r.tools))
 	for _, tool := range r.tools {
 		tools = append(tools, ToolDefinition{
-			Name:        tool.Name(),
-			Description: tool.Description(),
-			InputSchema: tool.InputSchema(),
+			Name:         tool.Name(),
+			Description:  tool.Description(),
+			InputSchema:  tool.InputSchema(),
+			OutputSchema: tool.OutputSchema(),
 		})
 	}
 
@@ -79,9 +85,10 @@ func (r *Registry) Count() int {
 }
 
 type BaseTool struct {
-	name        string
-	description string
-	schema      map[string]interface{}
+	name         string
+	description  string
+	schema       map[string]interface{}
+	outputSchema map[string]interface{}
 }
 
 func NewBaseTool(name, description string, schema map[string]interface{}) BaseTool {
@@ -92,6 +99,12 @@ func NewBaseTool(name, description string, schema map[string]interface{}) BaseTo
 	}
 }
 
+// WithOutputSchema sets the schema of the tool's structured content
+func (t BaseTool) WithOutputSchema(schema map[string]interface{}) BaseTool {
+	t.outputSchema = schema
+	return t
+}
+
 func (t *BaseTool) Name() string {
 	return t.name
 }
@@ -104,6 +117,10 @@ func (t *BaseTool) InputSchema() map[string]interface{} {
 	return t.schema
 }
 
+func (t *BaseTool) OutputSchema() map[string]interface{} {
+	return t.outputSchema
+}
+
 func ParseStringParam(args map[string]interface{}, key string, required bool) (string, error) {
 	value, exists := args[key]
 	if !exists {
@@ -4,14 +4,17 @@ import (
 	"context"
 	"errors"
 	"testing"
+
+	"github.com/jontolof/xcode-build-mcp/pkg/types"
 )
 
 // Test tool implementation
 type testTool struct {
-	name        string
-	description string
-	schema      map[string]interface{}
-	executeFunc func(ctx context.Context, args map[string]interface{}) (string, error)
+	name         string
+	description  string
+	schema       map[string]interface{}
+	outputSchema map[string]interface{}
+	executeFunc  func(ctx context.Context, args map[string]interface{}) (string, error)
 }
 
 func (t *testTool) Name() string {
@@ -26,11 +29,19 @@ func (t *testTool) InputSchema() map[string]interface{} {
 	return t.schema
 }
 
-func (t *testTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+func (t *testTool) OutputSchema() map[string]interface{} {
+	return t.outputSchema
+}
+
+func (t *testTool) Execute(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error) {
+	text := "default result"
 	if t.executeFunc != nil {
-		return t.executeFunc(ctx, args)
+		var err error
+		if text, err = t.executeFunc(ctx, args); err != nil {
+			return nil, err
+		}
 	}
-	return "default result", nil
+	return &types.ToolResult{Content: []types.ToolContent{types.TextContent(text)}}, nil
 }
 
 func TestNewRegistry(t *testing.T) {
@@ -208,8 +219,8 @@ func TestRegistry_Execute(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Execute failed: %v", err)
 	}
-	if result != "Echo: Hello" {
-		t.Errorf("Unexpected result: %s", result)
+	if result.Text() != "Echo: Hello" {
+		t.Errorf("Unexpected result: %s", result.Text())
 	}
 
 	// Test with missing message
@@ -259,3 +270,24 @@ func TestRegistry_Concurrent(t *testing.T) {
 		t.Error("Registry count should not be negative")
 	}
 }
+
+func TestRegistry_ListTools_OutputSchema(t *testing.T) {
+	registry := NewRegistry()
+
+	outputSchema := map[string]interface{}{"type": "object"}
+	registry.Register(&testTool{name: "structured", outputSchema: outputSchema})
+	registry.Register(&testTool{name: "plain"})
+
+	for _, def := range registry.ListTools() {
+		switch def.Name {
+		case "structured":
+			if def.OutputSchema["type"] != "object" {
+				t.Errorf("Expected the output schema to be listed, got %v", def.OutputSchema)
+			}
+		case "plain":
+			if def.OutputSchema != nil {
+				t.Errorf("Expected no output schema, got %v", def.OutputSchema)
+			}
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jontolof/xcode-build-mcp/internal/runs"
+	"github.com/jontolof/xcode-build-mcp/internal/xcode"
+)
+
+// resourceNotFound is the MCP error code for an unknown resource URI
+const resourceNotFound = -32002
+
+func (s *Server) handleListResources(req *Request) *Response {
+	resources := []Resource{}
+
+	for _, run := range s.runs.List() {
+		summary := fmt.Sprintf("%s run %s at %s (exit code %d)",
+			run.Tool, run.ID, run.StartedAt.Format("15:04:05"), run.ExitCode)
+
+		resources = append(resources, Resource{
+			URI:         run.LogURI(),
+			Name:        fmt.Sprintf("%s run %s log", run.Tool, run.ID),
+			Description: "Unfiltered output of " + summary,
+			MimeType:    "text/plain",
+		})
+
+		if run.ResultBundle != "" {
+			resources = append(resources, Resource{
+				URI:         run.ResultBundleURI(),
+				Name:        fmt.Sprintf("%s run %s xcresult", run.Tool, run.ID),
+				Description: "Result bundle summary of " + summary,
+				MimeType:    "application/json",
+			})
+		}
+
+		for i, report := range run.CrashReports {
+			resources = append(resources, Resource{
+				URI:         run.CrashReportURI(i),
+				Name:        fmt.Sprintf("%s run %s crash: %s", run.Tool, run.ID, report.ProcessName),
+				Description: fmt.Sprintf("Crash report %s captured during %s", report.FilePath, summary),
+				MimeType:    "text/plain",
+			})
+		}
+	}
+
+	return &Response{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result:  ListResourcesResult{Resources: resources},
+	}
+}
+
+func (s *Server) handleReadResource(ctx context.Context, req *Request) *Response {
+	var params ReadResourceParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return s.errorResponse(req.ID, -32602, "Invalid params", err)
+	}
+
+	contents, err := s.readRunResource(ctx, params.URI)
+	if err != nil {
+		return s.errorResponse(req.ID, resourceNotFound, "Resource not found", err)
+	}
+
+	return &Response{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result:  ReadResourceResult{Contents: []ResourceContents{*contents}},
+	}
+}
+
+// readRunResource resolves xcode://runs/<id>/log, /xcresult and
+// /crashes/<n> against the run store
+func (s *Server) readRunResource(ctx context.Context, uri string) (*ResourceContents, error) {
+	if !strings.HasPrefix(uri, runs.URIScheme) {
+		return nil, fmt.Errorf("unsupported resource URI: %s", uri)
+	}
+
+	parts := strings.Split(strings.TrimPrefix(uri, runs.URIScheme), "/")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("malformed resource URI: %s", uri)
+	}
+
+	run := s.runs.Get(parts[0])
+	if run == nil {
+		return nil, fmt.Errorf("run %s not found or no longer retained", parts[0])
+	}
+
+	switch {
+	case len(parts) == 2 && parts[1] == "log":
+		return &ResourceContents{URI: uri, MimeType: "text/plain", Text: run.Output}, nil
+
+	case len(parts) == 2 && parts[1] == "xcresult" && run.ResultBundle != "":
+		return s.readResultBundle(ctx, uri, run)
+
+	case len(parts) == 3 && parts[1] == "crashes":
+		index, err := strconv.Atoi(parts[2])
+		if err != nil || index < 0 || index >= len(run.CrashReports) {
+			return nil, fmt.Errorf("crash report %s not found in run %s", parts[2], run.ID)
+		}
+		data, err := os.ReadFile(run.CrashReports[index].FilePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read crash report: %w", err)
+		}
+		return &ResourceContents{URI: uri, MimeType: "text/plain", Text: string(data)}, nil
+	}
+
+	return nil, fmt.Errorf("resource %s not found", uri)
+}
+
+// readResultBundle summarizes the retained .xcresult of run. The bundle is a
+// directory, so clients get its path plus whatever xcresulttool can extract.
+// xcresulttool comes from the Xcode that wrote the bundle, since another
+// version may not read it.
+func (s *Server) readResultBundle(ctx context.Context, uri string, run *runs.Run) (*ResourceContents, error) {
+	content := map[string]interface{}{
+		"path": run.ResultBundle,
+	}
+
+	runner := s.runner
+	if run.DeveloperDir != "" {
+		runner = xcode.EnvRunner{Runner: runner, Env: []string{"DEVELOPER_DIR=" + run.DeveloperDir}}
+	}
+	summary, err := xcode.NewXCResultParser(runner).ParseResultBundle(ctx, run.ResultBundle)
+	if err != nil {
+		content["error"] = err.Error()
+	} else {
+		content["summary"] = summary
+	}
+
+	data, err := json.MarshalIndent(content, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal result bundle summary: %w", err)
+	}
+
+	return &ResourceContents{URI: uri, MimeType: "application/json", Text: string(data)}, nil
+}
@@ -0,0 +1,165 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jontolof/xcode-build-mcp/internal/runs"
+	"github.com/jontolof/xcode-build-mcp/internal/xcode"
+	"github.com/jontolof/xcode-build-mcp/pkg/types"
+)
+
+func newResourceTestServer(t *testing.T) *Server {
+	t.Helper()
+
+	logger := log.New(bytes.NewBuffer(nil), "", 0)
+	server, err := NewServer(logger)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	return server
+}
+
+func TestServer_ListResources(t *testing.T) {
+	server := newResourceTestServer(t)
+
+	crashFile := filepath.Join(t.TempDir(), "MyApp-2025.ips")
+	os.WriteFile(crashFile, []byte(`{"exception":"EXC_BAD_ACCESS"}`), 0644)
+
+	server.runs.Add(&runs.Run{Tool: "xcode_build", StartedAt: time.Now(), Output: "build log"})
+	server.runs.Add(&runs.Run{
+		Tool:         "xcode_test",
+		StartedAt:    time.Now(),
+		Output:       "test log",
+		ResultBundle: t.TempDir(),
+		CrashReports: []types.CrashReport{{ProcessName: "MyApp", FilePath: crashFile}},
+	})
+
+	resp := server.handleRequest(context.Background(), &Request{JSONRPC: "2.0", ID: 1, Method: "resources/list"})
+	if resp.Error != nil {
+		t.Fatalf("resources/list failed: %v", resp.Error)
+	}
+
+	result := resp.Result.(ListResourcesResult)
+	var uris []string
+	for _, r := range result.Resources {
+		uris = append(uris, r.URI)
+	}
+
+	expected := []string{
+		"xcode://runs/2/log",
+		"xcode://runs/2/xcresult",
+		"xcode://runs/2/crashes/0",
+		"xcode://runs/1/log",
+	}
+	if len(uris) != len(expected) {
+		t.Fatalf("Got resources %v, want %v", uris, expected)
+	}
+	for i := range expected {
+		if uris[i] != expected[i] {
+			t.Errorf("Resource %d = %s, want %s", i, uris[i], expected[i])
+		}
+	}
+}
+
+func TestServer_ReadResource(t *testing.T) {
+	server := newResourceTestServer(t)
+
+	crashFile := filepath.Join(t.TempDir(), "MyApp-2025.ips")
+	os.WriteFile(crashFile, []byte("crash contents"), 0644)
+
+	server.runs.Add(&runs.Run{
+		Tool:         "xcode_test",
+		Output:       "full unfiltered log",
+		CrashReports: []types.CrashReport{{ProcessName: "MyApp", FilePath: crashFile}},
+	})
+
+	tests := []struct {
+		uri      string
+		expected string
+	}{
+		{"xcode://runs/1/log", "full unfiltered log"},
+		{"xcode://runs/1/crashes/0", "crash contents"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.uri, func(t *testing.T) {
+			params, _ := json.Marshal(ReadResourceParams{URI: tt.uri})
+			resp := server.handleRequest(context.Background(), &Request{JSONRPC: "2.0", ID: 1, Method: "resources/read", Params: params})
+			if resp.Error != nil {
+				t.Fatalf("resources/read failed: %v", resp.Error)
+			}
+
+			result := resp.Result.(ReadResourceResult)
+			if len(result.Contents) != 1 {
+				t.Fatalf("Expected 1 content item, got %d", len(result.Contents))
+			}
+			if result.Contents[0].Text != tt.expected {
+				t.Errorf("Text = %q, want %q", result.Contents[0].Text, tt.expected)
+			}
+			if result.Contents[0].URI != tt.uri {
+				t.Errorf("URI = %s, want %s", result.Contents[0].URI, tt.uri)
+			}
+		})
+	}
+}
+
+func TestServer_ReadResource_NotFound(t *testing.T) {
+	server := newResourceTestServer(t)
+	server.runs.Add(&runs.Run{Tool: "xcode_build", Output: "log"})
+
+	uris := []string{
+		"xcode://runs/99/log",
+		"xcode://runs/1/xcresult",
+		"xcode://runs/1/crashes/0",
+		"xcode://runs/1/unknown",
+		"file:///etc/passwd",
+	}
+
+	for _, uri := range uris {
+		params, _ := json.Marshal(ReadResourceParams{URI: uri})
+		resp := server.handleRequest(context.Background(), &Request{JSONRPC: "2.0", ID: 1, Method: "resources/read", Params: params})
+		if resp.Error == nil {
+			t.Errorf("Expected error for %s", uri)
+			continue
+		}
+		if resp.Error.Code != resourceNotFound {
+			t.Errorf("Error code for %s = %d, want %d", uri, resp.Error.Code, resourceNotFound)
+		}
+	}
+}
+
+// envRecorder fails every command, keeping the environment it was given
+type envRecorder struct {
+	env []string
+}
+
+func (r *envRecorder) Run(ctx context.Context, cmd xcode.Command) (xcode.ExitStatus, error) {
+	r.env = append(r.env, cmd.Env...)
+	return xcode.ExitStatus{ExitCode: 1}, nil
+}
+
+func TestServer_ReadResource_ResultBundleUsesRunXcode(t *testing.T) {
+	server := newResourceTestServer(t)
+	recorder := &envRecorder{}
+	server.runner = recorder
+
+	developerDir := "/Applications/Xcode-16.1.app/Contents/Developer"
+	server.runs.Add(&runs.Run{Tool: "xcode_test", ResultBundle: t.TempDir(), DeveloperDir: developerDir})
+
+	params, _ := json.Marshal(ReadResourceParams{URI: "xcode://runs/1/xcresult"})
+	resp := server.handleRequest(context.Background(), &Request{JSONRPC: "2.0", ID: 1, Method: "resources/read", Params: params})
+	if resp.Error != nil {
+		t.Fatalf("resources/read failed: %v", resp.Error)
+	}
+	if !slices.Contains(recorder.env, "DEVELOPER_DIR="+developerDir) {
+		t.Errorf("Expected xcresulttool to run with the run's DEVELOPER_DIR, got env %v", recorder.env)
+	}
+}
@@ -3,42 +3,99 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"sync"
+	"time"
 
+	"github.com/jontolof/xcode-build-mcp/internal/accessibility"
+	"github.com/jontolof/xcode-build-mcp/internal/common"
+	"github.com/jontolof/xcode-build-mcp/internal/runs"
 	"github.com/jontolof/xcode-build-mcp/internal/tools"
 	"github.com/jontolof/xcode-build-mcp/internal/xcode"
+	"github.com/jontolof/xcode-build-mcp/pkg/types"
+)
+
+const (
+	// DefaultHTTPAddr is the listen address used by the http transport when
+	// none is configured. The transport has no authentication, so it only
+	// listens on loopback unless told otherwise.
+	DefaultHTTPAddr = "127.0.0.1:8080"
+
+	// DefaultMaxInFlight is the default number of tool calls executed
+	// concurrently
+	DefaultMaxInFlight = 4
 )
 
 type Server struct {
 	logger    *log.Logger
 	registry  *Registry
+	prompts   *PromptRegistry
 	transport Transport
+	runs      *runs.Store
+	runner    xcode.CommandRunner
+
+	httpAddr    string
+	maxInFlight int
+
+	// running holds the cancel func of every in-flight tools/call, keyed by
+	// requestKey of its ID, so notifications/cancelled can stop it
+	mu      sync.Mutex
+	running map[string]context.CancelFunc
 }
 
 func NewServer(logger *log.Logger) (*Server, error) {
 	registry := NewRegistry()
 
+	// Every external command goes through runner, which may record or
+	// replay them
+	runner, err := xcode.RunnerFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up command runner: %w", err)
+	}
+
 	server := &Server{
-		logger:   logger,
-		registry: registry,
+		logger:      logger,
+		registry:    registry,
+		prompts:     NewPromptRegistry(),
+		runs:        runs.NewStore(runs.DefaultRetention),
+		runner:      runner,
+		httpAddr:    DefaultHTTPAddr,
+		maxInFlight: DefaultMaxInFlight,
+		running:     make(map[string]context.CancelFunc),
 	}
 
 	if err := server.registerTools(); err != nil {
 		return nil, fmt.Errorf("failed to register tools: %w", err)
 	}
 
+	if err := server.registerPrompts(); err != nil {
+		return nil, fmt.Errorf("failed to register prompts: %w", err)
+	}
+
 	return server, nil
 }
 
+// SetHTTPAddr sets the listen address used by the http transport
+func (s *Server) SetHTTPAddr(addr string) {
+	s.httpAddr = addr
+}
+
 func (s *Server) Run(ctx context.Context, transportType string) error {
+	// Retained result bundles live in the temp dir; don't leak them
+	defer s.runs.Close()
+
 	var transport Transport
 	var err error
 
 	switch transportType {
 	case "stdio":
 		transport, err = NewStdioTransport(s.logger)
+	case "http":
+		return s.runHTTP(ctx)
 	default:
 		return fmt.Errorf("unsupported transport type: %s", transportType)
 	}
@@ -51,12 +108,67 @@ func (s *Server) Run(ctx context.Context, transportType string) error {
 	return s.serve(ctx)
 }
 
+// runHTTP serves the streamable HTTP transport on s.httpAddr until ctx is
+// cancelled or the listener fails
+func (s *Server) runHTTP(ctx context.Context) error {
+	transport := NewHTTPTransport(s.logger)
+	s.transport = transport
+
+	httpServer := &http.Server{
+		Addr:              s.httpAddr,
+		Handler:           transport,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	listenErr := make(chan error, 1)
+	go func() {
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			listenErr <- err
+		}
+		transport.Close()
+	}()
+
+	s.logger.Printf("MCP HTTP transport listening on %s", s.httpAddr)
+
+	// ReadRequest blocks until a client posts, so unblock it on cancellation
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			transport.Close()
+		case <-stop:
+		}
+	}()
+
+	serveErr := s.serve(ctx)
+
+	// Close the transport first so open SSE streams end and Shutdown can drain
+	transport.Close()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	httpServer.Shutdown(shutdownCtx)
+
+	select {
+	case err := <-listenErr:
+		return fmt.Errorf("http transport failed: %w", err)
+	default:
+	}
+	return serveErr
+}
+
 func (s *Server) serve(ctx context.Context) error {
 	// Only log in debug mode
 	if os.Getenv("MCP_LOG_LEVEL") == "debug" {
 		s.logger.Println("MCP server starting...")
 	}
 
+	// Let in-flight tool calls finish writing their responses before returning
+	var inFlight sync.WaitGroup
+	defer inFlight.Wait()
+
+	slots := make(chan struct{}, s.maxInFlight)
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -78,18 +190,131 @@ func (s *Server) serve(ctx context.Context) error {
 				return err
 			}
 
-			response := s.handleRequest(ctx, request)
-			// Don't send response for notifications (requests with no ID)
-			if response != nil {
-				if err := s.transport.WriteResponse(response); err != nil {
-					s.logger.Printf("Failed to write response: %v", err)
+			// Tool calls can run for many minutes and reading a result bundle
+			// shells out to xcresulttool, so those are dispatched concurrently.
+			// Everything else is cheap and handled inline, which keeps
+			// initialize and notifications ordered relative to the requests
+			// that follow them as the spec requires.
+			if !dispatchConcurrently(request) {
+				if err := s.respond(s.handleRequest(ctx, request)); err != nil {
 					return err
 				}
+				continue
 			}
+
+			// Track the call before dispatching it so a cancellation that
+			// arrives while it waits for a slot is not lost
+			reqCtx, cancel := s.trackRequest(ctx, request.ID)
+
+			// A client that goes away cancels its call like a
+			// notifications/cancelled would
+			stopWatching := context.AfterFunc(request.Context(), cancel)
+
+			inFlight.Add(1)
+			go func(request *Request) {
+				defer inFlight.Done()
+				defer s.untrackRequest(request.ID)
+				defer cancel()
+				defer stopWatching()
+
+				select {
+				case slots <- struct{}{}:
+					defer func() { <-slots }()
+				case <-reqCtx.Done():
+					return
+				}
+
+				response := s.handleRequest(reqCtx, request)
+
+				// The client is no longer waiting for a cancelled request
+				if reqCtx.Err() != nil && ctx.Err() == nil {
+					if os.Getenv("MCP_LOG_LEVEL") == "debug" {
+						s.logger.Printf("Suppressing response for cancelled request %v", request.ID)
+					}
+					return
+				}
+
+				if err := s.respond(response); err != nil {
+					s.logger.Printf("Dropped response for request %v", request.ID)
+				}
+			}(request)
 		}
 	}
 }
 
+// dispatchConcurrently reports whether a request may run alongside others
+func dispatchConcurrently(req *Request) bool {
+	if req.ID == nil {
+		return false
+	}
+	return req.Method == "tools/call" || req.Method == "resources/read"
+}
+
+// SetMaxInFlight bounds how many tool calls and resource reads may execute at
+// the same time. Calls beyond the limit wait for a free slot; other requests
+// are unaffected.
+func (s *Server) SetMaxInFlight(n int) {
+	if n < 1 {
+		n = 1
+	}
+	s.maxInFlight = n
+}
+
+// trackRequest derives a cancellable context for a concurrently dispatched
+// request and records it under the request ID
+func (s *Server) trackRequest(ctx context.Context, id interface{}) (context.Context, context.CancelFunc) {
+	reqCtx, cancel := context.WithCancel(ctx)
+
+	s.mu.Lock()
+	s.running[requestKey(id)] = cancel
+	s.mu.Unlock()
+
+	return reqCtx, cancel
+}
+
+func (s *Server) untrackRequest(id interface{}) {
+	s.mu.Lock()
+	delete(s.running, requestKey(id))
+	s.mu.Unlock()
+}
+
+// cancelRequest cancels the in-flight tools/call with the given ID. It
+// reports whether such a request was running.
+func (s *Server) cancelRequest(id interface{}) bool {
+	s.mu.Lock()
+	cancel, exists := s.running[requestKey(id)]
+	s.mu.Unlock()
+
+	if exists {
+		cancel()
+	}
+	return exists
+}
+
+// requestKey normalizes a JSON-RPC ID so that the same ID compares equal
+// whether it was decoded from JSON (float64) or built in Go (int), while
+// the number 1 and the string "1" stay distinct
+func requestKey(id interface{}) string {
+	data, err := json.Marshal(id)
+	if err != nil {
+		return fmt.Sprintf("%v", id)
+	}
+	return string(data)
+}
+
+// respond writes a response through the transport. Notifications (requests
+// with no ID) produce a nil response and nothing is sent.
+func (s *Server) respond(response *Response) error {
+	if response == nil {
+		return nil
+	}
+	if err := s.transport.WriteResponse(response); err != nil {
+		s.logger.Printf("Failed to write response: %v", err)
+		return err
+	}
+	return nil
+}
+
 func (s *Server) handleRequest(ctx context.Context, req *Request) *Response {
 	// Only log in debug mode
 	if os.Getenv("MCP_LOG_LEVEL") == "debug" {
@@ -99,10 +324,27 @@ func (s *Server) handleRequest(ctx context.Context, req *Request) *Response {
 	switch req.Method {
 	case "initialize":
 		return s.handleInitialize(req)
+	case "ping":
+		return &Response{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Result:  map[string]interface{}{},
+		}
 	case "tools/list":
 		return s.handleListTools(req)
 	case "tools/call":
 		return s.handleCallTool(ctx, req)
+	case "prompts/list":
+		return s.handleListPrompts(req)
+	case "prompts/get":
+		return s.handleGetPrompt(req)
+	case "resources/list":
+		return s.handleListResources(req)
+	case "resources/read":
+		return s.handleReadResource(ctx, req)
+	case "notifications/cancelled":
+		s.handleCancelled(req)
+		return nil
 	default:
 		// Notifications (requests with no ID) should not receive responses
 		if req.ID == nil {
@@ -124,6 +366,22 @@ func (s *Server) handleRequest(ctx context.Context, req *Request) *Response {
 	}
 }
 
+// handleCancelled stops the tool call named by a notifications/cancelled
+// message. Unknown or already finished requests are ignored, as the spec
+// allows for the race between completion and cancellation.
+func (s *Server) handleCancelled(req *Request) {
+	var params CancelledParams
+	if err := json.Unmarshal(req.Params, &params); err != nil || params.RequestID == nil {
+		s.logger.Printf("Ignoring malformed cancellation: %s", string(req.Params))
+		return
+	}
+
+	cancelled := s.cancelRequest(params.RequestID)
+	if os.Getenv("MCP_LOG_LEVEL") == "debug" {
+		s.logger.Printf("Cancellation for request %v (running: %t, reason: %q)", params.RequestID, cancelled, params.Reason)
+	}
+}
+
 func (s *Server) handleInitialize(req *Request) *Response {
 	var params InitializeParams
 	if err := json.Unmarshal(req.Params, &params); err != nil {
@@ -133,7 +391,9 @@ func (s *Server) handleInitialize(req *Request) *Response {
 	result := InitializeResult{
 		ProtocolVersion: "2024-11-05",
 		Capabilities: ServerCapabilities{
-			Tools: &ToolsCapability{},
+			Tools:     &ToolsCapability{},
+			Resources: &ResourcesCapability{},
+			Prompts:   &PromptsCapability{},
 		},
 		ServerInfo: ServerInfo{
 			Name:    "xcode-build-mcp",
@@ -159,6 +419,43 @@ func (s *Server) handleListTools(req *Request) *Response {
 	}
 }
 
+func (s *Server) handleListPrompts(req *Request) *Response {
+	return &Response{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result:  ListPromptsResult{Prompts: s.prompts.ListPrompts()},
+	}
+}
+
+func (s *Server) handleGetPrompt(req *Request) *Response {
+	var params GetPromptParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return s.errorResponse(req.ID, -32602, "Invalid params", err)
+	}
+
+	prompt := s.prompts.GetPrompt(params.Name)
+	if prompt == nil {
+		return s.errorResponse(req.ID, -32602, "Prompt not found", fmt.Errorf("prompt %s not found", params.Name))
+	}
+
+	for _, arg := range prompt.Arguments() {
+		if arg.Required && params.Arguments[arg.Name] == "" {
+			return s.errorResponse(req.ID, -32602, "Invalid params", fmt.Errorf("missing required argument: %s", arg.Name))
+		}
+	}
+
+	result, err := prompt.Render(params.Arguments)
+	if err != nil {
+		return s.errorResponse(req.ID, -32602, "Failed to render prompt", err)
+	}
+
+	return &Response{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result:  result,
+	}
+}
+
 func (s *Server) handleCallTool(ctx context.Context, req *Request) *Response {
 	var params CallToolParams
 	if err := json.Unmarshal(req.Params, &params); err != nil {
@@ -170,26 +467,101 @@ func (s *Server) handleCallTool(ctx context.Context, req *Request) *Response {
 		return s.errorResponse(req.ID, -32601, "Tool not found", fmt.Errorf("tool %s not found", params.Name))
 	}
 
+	if params.Meta != nil && params.Meta.ProgressToken != nil {
+		ctx = common.WithProgress(ctx, s.progressReporter(req.ID, params.Meta.ProgressToken))
+	}
+
 	result, err := tool.Execute(ctx, params.Arguments)
-	if err != nil {
-		return s.errorResponse(req.ID, -32603, "Tool execution failed", err)
+	if types.IsXcodeError(err, types.ErrCodeInvalidParams) {
+		return s.errorResponse(req.ID, -32602, "Invalid params", err)
 	}
 
 	return &Response{
 		JSONRPC: "2.0",
 		ID:      req.ID,
-		Result: CallToolResult{
-			Content: []Content{{
-				Type: "text",
-				Text: result,
-			}},
-		},
+		Result:  toolCallResult(result, err),
 	}
 }
 
+// toolCallResult converts a tool's result to its MCP form. A tool error means
+// the call failed, not the protocol, so it is reported with isError and its
+// message, followed by any partial result the tool returned. Typed errors are
+// sent as JSON carrying their code, details and remediation hint.
+func toolCallResult(result *types.ToolResult, err error) CallToolResult {
+	content := []Content{}
+	if err != nil {
+		content = append(content, Content{Type: "text", Text: errorText(err)})
+	}
+
+	var callResult CallToolResult
+	if result != nil {
+		for _, item := range result.Content {
+			content = append(content, Content{
+				Type:     item.Type,
+				Text:     item.Text,
+				Data:     item.Data,
+				MimeType: item.MimeType,
+			})
+		}
+		callResult.StructuredContent = result.Structured
+	}
+	callResult.Content = content
+
+	if err != nil || (result != nil && result.IsError) {
+		isError := true
+		callResult.IsError = &isError
+	}
+
+	return callResult
+}
+
+// progressReporter returns a callback that sends notifications/progress for
+// the request with the given ID and token
+func (s *Server) progressReporter(requestID, token interface{}) common.ProgressFunc {
+	return func(progress, total float64, message string) {
+		if s.transport == nil {
+			return
+		}
+
+		notification := &Notification{
+			JSONRPC: "2.0",
+			Method:  "notifications/progress",
+			Params: ProgressParams{
+				ProgressToken: token,
+				Progress:      progress,
+				Total:         total,
+				Message:       message,
+			},
+		}
+		if err := s.transport.WriteNotification(requestID, notification); err != nil {
+			s.logger.Printf("Failed to send progress for request %v: %v", requestID, err)
+		}
+	}
+}
+
+// errorText renders a tool error for the client, as JSON when it is an
+// XcodeError so the code survives
+func errorText(err error) string {
+	xerr := types.ExtractXcodeError(err)
+	if xerr == nil {
+		return err.Error()
+	}
+
+	data, marshalErr := json.Marshal(xerr.Data())
+	if marshalErr != nil {
+		return err.Error()
+	}
+	return string(data)
+}
+
 func (s *Server) errorResponse(id interface{}, code int, message string, err error) *Response {
 	data := make(map[string]interface{})
 	if err != nil {
+		if xerr := types.ExtractXcodeError(err); xerr != nil {
+			for key, value := range xerr.Data() {
+				data[key] = value
+			}
+		}
 		data["error"] = err.Error()
 	}
 
@@ -204,35 +576,77 @@ func (s *Server) errorResponse(id interface{}, code int, message string, err err
 	}
 }
 
+func (s *Server) registerPrompts() error {
+	prompts := []Prompt{
+		newFixFailingBuildPrompt(s.runs),
+		newTriageFailingTestsPrompt(s.runs),
+		newInvestigateCrashPrompt(s.runs),
+	}
+
+	for _, prompt := range prompts {
+		if err := s.prompts.Register(prompt); err != nil {
+			return fmt.Errorf("failed to register %s prompt: %w", prompt.Name(), err)
+		}
+	}
+
+	return nil
+}
+
 func (s *Server) registerTools() error {
 	// Create xcode components
-	executor := xcode.NewExecutor(s.logger)
+	executor := xcode.NewExecutorWithRunner(s.logger, s.runner)
+	executor.SetEnvPolicy(xcode.EnvPolicyFromEnv())
+	uiBackend := accessibility.FromEnv(executor.Runner())
 	parser := xcode.NewParser()
 
 	// Register build tool
-	buildTool := tools.NewXcodeBuildTool(executor, parser, s.logger)
+	buildTool := tools.NewXcodeBuildTool(executor, parser, s.runs, s.logger)
 	if err := s.registry.Register(buildTool); err != nil {
 		return fmt.Errorf("failed to register xcode_build tool: %w", err)
 	}
 
 	// Register test tool
-	testTool := tools.NewXcodeTestTool(executor, parser, s.logger)
+	testTool := tools.NewXcodeTestTool(executor, parser, s.runs, s.logger)
 	if err := s.registry.Register(testTool); err != nil {
 		return fmt.Errorf("failed to register xcode_test tool: %w", err)
 	}
 
+	// Register inspect xcresult tool
+	inspectXCResultTool := tools.NewInspectXCResultTool(executor)
+	if err := s.registry.Register(inspectXCResultTool); err != nil {
+		return fmt.Errorf("failed to register inspect_xcresult tool: %w", err)
+	}
+
 	// Register clean tool
 	cleanTool := tools.NewXcodeCleanTool(executor, parser, s.logger)
 	if err := s.registry.Register(cleanTool); err != nil {
 		return fmt.Errorf("failed to register xcode_clean tool: %w", err)
 	}
 
+	// Register archive tool
+	archiveTool := tools.NewXcodeArchiveTool(executor, parser, s.runs, s.logger)
+	if err := s.registry.Register(archiveTool); err != nil {
+		return fmt.Errorf("failed to register xcode_archive tool: %w", err)
+	}
+
+	// Register export archive tool
+	exportArchiveTool := tools.NewExportArchiveTool(executor, parser, s.runs, s.logger)
+	if err := s.registry.Register(exportArchiveTool); err != nil {
+		return fmt.Errorf("failed to register export_archive tool: %w", err)
+	}
+
 	// Register discover projects tool
 	discoverTool := tools.NewDiscoverProjectsTool(executor, parser, s.logger)
 	if err := s.registry.Register(discoverTool); err != nil {
 		return fmt.Errorf("failed to register discover_projects tool: %w", err)
 	}
 
+	// Register list Xcode versions tool
+	listXcodeVersionsTool := tools.NewListXcodeVersionsTool(executor)
+	if err := s.registry.Register(listXcodeVersionsTool); err != nil {
+		return fmt.Errorf("failed to register list_xcode_versions tool: %w", err)
+	}
+
 	// Register list simulators tool
 	listSimulatorsTool := tools.NewListSimulatorsTool(executor, parser, s.logger)
 	if err := s.registry.Register(listSimulatorsTool); err != nil {
@@ -258,37 +672,55 @@ func (s *Server) registerTools() error {
 	}
 
 	// Register list schemes tool
-	listSchemesTool := tools.NewListSchemes()
+	listSchemesTool := tools.NewListSchemes(executor)
 	if err := s.registry.Register(listSchemesTool); err != nil {
 		return fmt.Errorf("failed to register list_schemes tool: %w", err)
 	}
 
 	// Register capture logs tool
-	captureLogsTool := tools.NewCaptureLogs()
+	captureLogsTool := tools.NewCaptureLogs(executor)
 	if err := s.registry.Register(captureLogsTool); err != nil {
 		return fmt.Errorf("failed to register capture_logs tool: %w", err)
 	}
 
 	// Register screenshot tool
-	screenshotTool := tools.NewScreenshot()
+	screenshotTool := tools.NewScreenshot(executor)
 	if err := s.registry.Register(screenshotTool); err != nil {
 		return fmt.Errorf("failed to register screenshot tool: %w", err)
 	}
 
+	// Register compare screenshot tool
+	compareScreenshotTool := tools.NewCompareScreenshot(screenshotTool)
+	if err := s.registry.Register(compareScreenshotTool); err != nil {
+		return fmt.Errorf("failed to register compare_screenshot tool: %w", err)
+	}
+
 	// Register describe UI tool
-	describeUITool := tools.NewDescribeUI()
+	describeUITool := tools.NewDescribeUI(executor, uiBackend)
 	if err := s.registry.Register(describeUITool); err != nil {
 		return fmt.Errorf("failed to register describe_ui tool: %w", err)
 	}
 
 	// Register UI interact tool
-	uiInteractTool := tools.NewUIInteract()
+	uiInteractTool := tools.NewUIInteract(executor, uiBackend)
 	if err := s.registry.Register(uiInteractTool); err != nil {
 		return fmt.Errorf("failed to register ui_interact tool: %w", err)
 	}
 
+	// Register wait for element tool
+	waitForElementTool := tools.NewWaitForElement(executor, uiBackend)
+	if err := s.registry.Register(waitForElementTool); err != nil {
+		return fmt.Errorf("failed to register wait_for_element tool: %w", err)
+	}
+
+	// Register run UI flow tool
+	runUIFlowTool := tools.NewRunUIFlow(launchAppTool, uiInteractTool, waitForElementTool, screenshotTool)
+	if err := s.registry.Register(runUIFlowTool); err != nil {
+		return fmt.Errorf("failed to register run_ui_flow tool: %w", err)
+	}
+
 	// Register get app info tool
-	getAppInfoTool := tools.NewGetAppInfo()
+	getAppInfoTool := tools.NewGetAppInfo(executor)
 	if err := s.registry.Register(getAppInfoTool); err != nil {
 		return fmt.Errorf("failed to register get_app_info tool: %w", err)
 	}
@@ -4,8 +4,16 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/jontolof/xcode-build-mcp/internal/common"
+	"github.com/jontolof/xcode-build-mcp/pkg/types"
 )
 
 func TestNewServer(t *testing.T) {
@@ -68,6 +76,14 @@ func TestServer_HandleRequest_Initialize(t *testing.T) {
 	if resp.Result == nil {
 		t.Fatal("Initialize should return a result")
 	}
+
+	result := resp.Result.(InitializeResult)
+	if result.Capabilities.Resources == nil {
+		t.Error("Initialize should advertise the resources capability")
+	}
+	if result.Capabilities.Prompts == nil {
+		t.Error("Initialize should advertise the prompts capability")
+	}
 }
 
 func TestServer_HandleRequest_MethodNotFound(t *testing.T) {
@@ -161,6 +177,595 @@ func (m *mockTool) InputSchema() map[string]interface{} {
 	return m.schema
 }
 
-func (m *mockTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
-	return "Test result", nil
+func (m *mockTool) OutputSchema() map[string]interface{} {
+	return nil
+}
+
+func (m *mockTool) Execute(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error) {
+	return textResult("Test result"), nil
+}
+
+func textResult(text string) *types.ToolResult {
+	return &types.ToolResult{Content: []types.ToolContent{types.TextContent(text)}}
+}
+
+func TestServer_Run_HTTPTransportShutdown(t *testing.T) {
+	logger := log.New(bytes.NewBuffer(nil), "", 0)
+	server, _ := NewServer(logger)
+	server.SetHTTPAddr("127.0.0.1:0")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- server.Run(ctx, "http")
+	}()
+
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Run should return nil on cancellation, got: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after context cancellation")
+	}
+}
+
+// chanTransport feeds requests from a channel and records responses
+type chanTransport struct {
+	requests      chan *Request
+	responses     chan *Response
+	notifications chan *Notification
+}
+
+func newChanTransport() *chanTransport {
+	return &chanTransport{
+		requests:      make(chan *Request, 16),
+		responses:     make(chan *Response, 16),
+		notifications: make(chan *Notification, 16),
+	}
+}
+
+func (c *chanTransport) ReadRequest() (*Request, error) {
+	req, ok := <-c.requests
+	if !ok {
+		return nil, fmt.Errorf("connection closed")
+	}
+	return req, nil
+}
+
+func (c *chanTransport) WriteResponse(resp *Response) error {
+	c.responses <- resp
+	return nil
+}
+
+func (c *chanTransport) WriteNotification(requestID interface{}, notification *Notification) error {
+	c.notifications <- notification
+	return nil
+}
+
+func (c *chanTransport) Close() error {
+	return nil
+}
+
+// blockingTool blocks in Execute until release is closed
+type blockingTool struct {
+	mockTool
+	started chan struct{}
+	release chan struct{}
+
+	mu      sync.Mutex
+	running int
+	peak    int
+}
+
+func (b *blockingTool) Execute(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error) {
+	b.mu.Lock()
+	b.running++
+	if b.running > b.peak {
+		b.peak = b.running
+	}
+	b.mu.Unlock()
+
+	b.started <- struct{}{}
+	<-b.release
+
+	b.mu.Lock()
+	b.running--
+	b.mu.Unlock()
+	return textResult("done"), nil
+}
+
+func callToolRequest(id interface{}, name string) *Request {
+	params, _ := json.Marshal(CallToolParams{Name: name})
+	return &Request{JSONRPC: "2.0", ID: id, Method: "tools/call", Params: params}
+}
+
+func TestServer_Serve_ConcurrentToolCalls(t *testing.T) {
+	logger := log.New(bytes.NewBuffer(nil), "", 0)
+	server, _ := NewServer(logger)
+
+	tool := &blockingTool{
+		mockTool: mockTool{name: "slow_tool", schema: map[string]interface{}{"type": "object"}},
+		started:  make(chan struct{}, 4),
+		release:  make(chan struct{}),
+	}
+	server.registry.Register(tool)
+
+	transport := newChanTransport()
+	server.transport = transport
+
+	done := make(chan error, 1)
+	go func() { done <- server.serve(context.Background()) }()
+
+	transport.requests <- callToolRequest(1, "slow_tool")
+	<-tool.started
+
+	// A ping sent while the tool is still running must be answered first
+	transport.requests <- &Request{JSONRPC: "2.0", ID: 2, Method: "ping"}
+	select {
+	case resp := <-transport.responses:
+		if resp.ID != 2 {
+			t.Fatalf("Expected ping response first, got response for ID %v", resp.ID)
+		}
+		if resp.Error != nil {
+			t.Fatalf("ping failed: %v", resp.Error)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("ping was blocked by a running tool call")
+	}
+
+	close(tool.release)
+	select {
+	case resp := <-transport.responses:
+		if resp.ID != 1 {
+			t.Errorf("Expected tool response for ID 1, got %v", resp.ID)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("tool call response never arrived")
+	}
+
+	close(transport.requests)
+	if err := <-done; err != nil {
+		t.Errorf("serve returned error: %v", err)
+	}
+}
+
+func TestServer_Serve_MaxInFlight(t *testing.T) {
+	logger := log.New(bytes.NewBuffer(nil), "", 0)
+	server, _ := NewServer(logger)
+	server.SetMaxInFlight(1)
+
+	tool := &blockingTool{
+		mockTool: mockTool{name: "slow_tool", schema: map[string]interface{}{"type": "object"}},
+		started:  make(chan struct{}, 4),
+		release:  make(chan struct{}),
+	}
+	server.registry.Register(tool)
+
+	transport := newChanTransport()
+	server.transport = transport
+
+	done := make(chan error, 1)
+	go func() { done <- server.serve(context.Background()) }()
+
+	transport.requests <- callToolRequest(1, "slow_tool")
+	transport.requests <- callToolRequest(2, "slow_tool")
+	<-tool.started
+
+	select {
+	case <-tool.started:
+		t.Fatal("Second tool call started despite max in-flight of 1")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(tool.release)
+	<-tool.started
+	for i := 0; i < 2; i++ {
+		<-transport.responses
+	}
+
+	close(transport.requests)
+	<-done
+
+	if tool.peak != 1 {
+		t.Errorf("Peak concurrent executions = %d, want 1", tool.peak)
+	}
+}
+
+func TestServer_Serve_WaitsForInFlightOnClose(t *testing.T) {
+	logger := log.New(bytes.NewBuffer(nil), "", 0)
+	server, _ := NewServer(logger)
+
+	tool := &blockingTool{
+		mockTool: mockTool{name: "slow_tool", schema: map[string]interface{}{"type": "object"}},
+		started:  make(chan struct{}, 1),
+		release:  make(chan struct{}),
+	}
+	server.registry.Register(tool)
+
+	transport := newChanTransport()
+	server.transport = transport
+
+	done := make(chan error, 1)
+	go func() { done <- server.serve(context.Background()) }()
+
+	transport.requests <- callToolRequest(1, "slow_tool")
+	<-tool.started
+	close(transport.requests)
+
+	select {
+	case <-done:
+		t.Fatal("serve returned while a tool call was still running")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(tool.release)
+	<-done
+
+	select {
+	case resp := <-transport.responses:
+		if resp.ID != 1 {
+			t.Errorf("Expected response for ID 1, got %v", resp.ID)
+		}
+	default:
+		t.Error("In-flight response was not written before serve returned")
+	}
+}
+
+func TestServer_HandleRequest_Ping(t *testing.T) {
+	logger := log.New(bytes.NewBuffer(nil), "", 0)
+	server, _ := NewServer(logger)
+
+	resp := server.handleRequest(context.Background(), &Request{JSONRPC: "2.0", ID: 1, Method: "ping"})
+	if resp.Error != nil {
+		t.Fatalf("ping failed: %v", resp.Error)
+	}
+	if resp.Result == nil {
+		t.Fatal("ping should return an empty result")
+	}
+}
+
+// ctxTool blocks until its context is cancelled
+type ctxTool struct {
+	mockTool
+	started   chan struct{}
+	cancelled chan struct{}
+}
+
+func (c *ctxTool) Execute(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error) {
+	c.started <- struct{}{}
+	<-ctx.Done()
+	close(c.cancelled)
+	return nil, ctx.Err()
+}
+
+func TestServer_Serve_CancelledNotification(t *testing.T) {
+	logger := log.New(bytes.NewBuffer(nil), "", 0)
+	server, _ := NewServer(logger)
+
+	tool := &ctxTool{
+		mockTool:  mockTool{name: "cancellable_tool", schema: map[string]interface{}{"type": "object"}},
+		started:   make(chan struct{}, 1),
+		cancelled: make(chan struct{}),
+	}
+	server.registry.Register(tool)
+
+	transport := newChanTransport()
+	server.transport = transport
+
+	done := make(chan error, 1)
+	go func() { done <- server.serve(context.Background()) }()
+
+	transport.requests <- callToolRequest(7, "cancellable_tool")
+	<-tool.started
+
+	// IDs arrive as float64 after JSON decoding and must still match
+	params, _ := json.Marshal(CancelledParams{RequestID: float64(7), Reason: "user abort"})
+	transport.requests <- &Request{JSONRPC: "2.0", Method: "notifications/cancelled", Params: params}
+
+	select {
+	case <-tool.cancelled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Tool context was not cancelled")
+	}
+
+	close(transport.requests)
+	<-done
+
+	select {
+	case resp := <-transport.responses:
+		t.Errorf("Cancelled request should not be answered, got %+v", resp)
+	default:
+	}
+
+	if server.cancelRequest(7) {
+		t.Error("Cancelled request should no longer be tracked")
+	}
+}
+
+func TestServer_HandleCancelled_UnknownRequest(t *testing.T) {
+	logger := log.New(bytes.NewBuffer(nil), "", 0)
+	server, _ := NewServer(logger)
+
+	params, _ := json.Marshal(CancelledParams{RequestID: "missing"})
+	resp := server.handleRequest(context.Background(), &Request{JSONRPC: "2.0", Method: "notifications/cancelled", Params: params})
+	if resp != nil {
+		t.Errorf("Cancellation notification should not produce a response, got %+v", resp)
+	}
+}
+
+func TestRequestKey(t *testing.T) {
+	if requestKey(1) != requestKey(float64(1)) {
+		t.Error("int and float64 IDs with the same value should match")
+	}
+	if requestKey(1) == requestKey("1") {
+		t.Error("numeric and string IDs should not match")
+	}
+}
+
+// progressTool reports two progress steps before finishing
+type progressTool struct {
+	mockTool
+}
+
+func (p *progressTool) Execute(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error) {
+	if report := common.ProgressFromContext(ctx); report != nil {
+		report(1, 2, "half way")
+		report(2, 2, "done")
+	}
+	return textResult("ok"), nil
+}
+
+func TestServer_HandleCallTool_Progress(t *testing.T) {
+	logger := log.New(bytes.NewBuffer(nil), "", 0)
+	server, _ := NewServer(logger)
+	server.registry.Register(&progressTool{mockTool: mockTool{name: "progress_tool", schema: map[string]interface{}{"type": "object"}}})
+
+	transport := newChanTransport()
+	server.transport = transport
+
+	params := json.RawMessage(`{"name":"progress_tool","_meta":{"progressToken":"build-1"}}`)
+	resp := server.handleRequest(context.Background(), &Request{JSONRPC: "2.0", ID: 1, Method: "tools/call", Params: params})
+	if resp.Error != nil {
+		t.Fatalf("tools/call failed: %v", resp.Error)
+	}
+
+	if len(transport.notifications) != 2 {
+		t.Fatalf("Expected 2 progress notifications, got %d", len(transport.notifications))
+	}
+
+	first := <-transport.notifications
+	if first.Method != "notifications/progress" {
+		t.Errorf("Method = %s, want notifications/progress", first.Method)
+	}
+	progress, ok := first.Params.(ProgressParams)
+	if !ok {
+		t.Fatalf("Unexpected params type %T", first.Params)
+	}
+	if progress.ProgressToken != "build-1" || progress.Progress != 1 || progress.Total != 2 || progress.Message != "half way" {
+		t.Errorf("Unexpected progress params: %+v", progress)
+	}
+}
+
+func TestServer_HandleCallTool_NoProgressWithoutToken(t *testing.T) {
+	logger := log.New(bytes.NewBuffer(nil), "", 0)
+	server, _ := NewServer(logger)
+	server.registry.Register(&progressTool{mockTool: mockTool{name: "progress_tool", schema: map[string]interface{}{"type": "object"}}})
+
+	transport := newChanTransport()
+	server.transport = transport
+
+	server.handleRequest(context.Background(), callToolRequest(1, "progress_tool"))
+	if len(transport.notifications) != 0 {
+		t.Errorf("Expected no notifications without a progress token, got %d", len(transport.notifications))
+	}
+}
+
+// imageTool returns a text summary followed by an image
+type imageTool struct {
+	mockTool
+}
+
+func (i *imageTool) Execute(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error) {
+	return &types.ToolResult{
+		Content: []types.ToolContent{
+			types.TextContent(`{"success":true}`),
+			types.ImageContent([]byte{0x89, 'P', 'N', 'G'}, "image/png"),
+		},
+	}, nil
+}
+
+func TestServer_HandleCallTool_ImageContent(t *testing.T) {
+	logger := log.New(bytes.NewBuffer(nil), "", 0)
+	server, _ := NewServer(logger)
+	server.registry.Register(&imageTool{mockTool: mockTool{name: "image_tool", schema: map[string]interface{}{"type": "object"}}})
+
+	resp := server.handleRequest(context.Background(), callToolRequest(1, "image_tool"))
+	if resp.Error != nil {
+		t.Fatalf("tools/call failed: %v", resp.Error)
+	}
+
+	result := resp.Result.(CallToolResult)
+	if len(result.Content) != 2 {
+		t.Fatalf("Expected 2 content items, got %d", len(result.Content))
+	}
+	if result.Content[0].Type != "text" || result.Content[0].Text != `{"success":true}` {
+		t.Errorf("Unexpected text content: %+v", result.Content[0])
+	}
+	image := result.Content[1]
+	if image.Type != "image" || image.MimeType != "image/png" || image.Data != "iVBORw==" {
+		t.Errorf("Unexpected image content: %+v", image)
+	}
+
+	data, _ := json.Marshal(image)
+	if !strings.Contains(string(data), `"mimeType":"image/png"`) {
+		t.Errorf("Image content should serialize mimeType, got %s", data)
+	}
+}
+
+// failingTool fails with a partial structured result
+type failingTool struct {
+	mockTool
+}
+
+func (f *failingTool) Execute(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error) {
+	partial, _ := types.NewToolResult(map[string]interface{}{"success": false}, true)
+	return partial, errors.New("simulator not found")
+}
+
+func TestServer_HandleCallTool_ToolErrorIsResult(t *testing.T) {
+	logger := log.New(bytes.NewBuffer(nil), "", 0)
+	server, _ := NewServer(logger)
+	server.registry.Register(&failingTool{mockTool: mockTool{name: "failing_tool", schema: map[string]interface{}{"type": "object"}}})
+
+	resp := server.handleRequest(context.Background(), callToolRequest(1, "failing_tool"))
+	if resp.Error != nil {
+		t.Fatalf("A tool failure should not be a protocol error, got %+v", resp.Error)
+	}
+
+	result := resp.Result.(CallToolResult)
+	if result.IsError == nil || !*result.IsError {
+		t.Fatal("Expected isError to be set")
+	}
+	if len(result.Content) != 2 || result.Content[0].Text != "simulator not found" {
+		t.Fatalf("Expected the error message followed by the partial result, got %+v", result.Content)
+	}
+	if result.Content[1].Text != `{"success":false}` {
+		t.Errorf("Unexpected partial result: %s", result.Content[1].Text)
+	}
+	if result.StructuredContent == nil {
+		t.Error("Expected the partial structured result to be kept")
+	}
+}
+
+// typedErrorTool fails with the given XcodeError
+type typedErrorTool struct {
+	mockTool
+	err *types.XcodeError
+}
+
+func (e *typedErrorTool) Execute(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error) {
+	return nil, e.err
+}
+
+func TestServer_HandleCallTool_TypedError(t *testing.T) {
+	logger := log.New(bytes.NewBuffer(nil), "", 0)
+	server, _ := NewServer(logger)
+	server.registry.Register(&typedErrorTool{
+		mockTool: mockTool{name: "typed_tool", schema: map[string]interface{}{"type": "object"}},
+		err: types.NewXcodeError(types.ErrCodeSchemeNotFound, `scheme "Nope" not found`,
+			map[string]interface{}{"scheme": "Nope"}),
+	})
+
+	resp := server.handleRequest(context.Background(), callToolRequest(1, "typed_tool"))
+	if resp.Error != nil {
+		t.Fatalf("A typed tool failure should not be a protocol error, got %+v", resp.Error)
+	}
+
+	result := resp.Result.(CallToolResult)
+	if result.IsError == nil || !*result.IsError || len(result.Content) != 1 {
+		t.Fatalf("Expected a single isError content item, got %+v", result)
+	}
+
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(result.Content[0].Text), &data); err != nil {
+		t.Fatalf("Expected the error as JSON, got %q: %v", result.Content[0].Text, err)
+	}
+	if data["code"] != "SCHEME_NOT_FOUND" || data["hint"] != types.ErrCodeSchemeNotFound.Hint() {
+		t.Errorf("Expected the code and hint, got %v", data)
+	}
+	if details, _ := data["details"].(map[string]interface{}); details["scheme"] != "Nope" {
+		t.Errorf("Expected the details, got %v", data["details"])
+	}
+}
+
+func TestServer_HandleCallTool_InvalidParamsError(t *testing.T) {
+	logger := log.New(bytes.NewBuffer(nil), "", 0)
+	server, _ := NewServer(logger)
+	server.registry.Register(&typedErrorTool{
+		mockTool: mockTool{name: "typed_tool", schema: map[string]interface{}{"type": "object"}},
+		err: types.NewXcodeError(types.ErrCodeInvalidParams, "missing required parameter: udid",
+			map[string]interface{}{"parameter": "udid"}),
+	})
+
+	resp := server.handleRequest(context.Background(), callToolRequest(1, "typed_tool"))
+	if resp.Error == nil {
+		t.Fatal("Expected invalid arguments to be a protocol error")
+	}
+	if resp.Error.Code != -32602 {
+		t.Errorf("Expected -32602, got %d", resp.Error.Code)
+	}
+
+	data, ok := resp.Error.Data.(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected error data, got %T", resp.Error.Data)
+	}
+	if data["code"] != types.ErrCodeInvalidParams || data["hint"] == nil || data["error"] == nil {
+		t.Errorf("Expected code, hint and error in the data, got %v", data)
+	}
+	if details, _ := data["details"].(map[string]interface{}); details["parameter"] != "udid" {
+		t.Errorf("Expected the details, got %v", data["details"])
+	}
+}
+
+// structuredTool reports a failed run without a Go error, like a build with
+// compiler errors
+type structuredTool struct {
+	mockTool
+}
+
+func (s *structuredTool) Execute(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error) {
+	return types.NewToolResult(&types.BuildResult{Success: false, ExitCode: 65}, true)
+}
+
+func TestServer_HandleCallTool_StructuredContent(t *testing.T) {
+	logger := log.New(bytes.NewBuffer(nil), "", 0)
+	server, _ := NewServer(logger)
+	server.registry.Register(&structuredTool{mockTool: mockTool{name: "structured_tool", schema: map[string]interface{}{"type": "object"}}})
+
+	resp := server.handleRequest(context.Background(), callToolRequest(1, "structured_tool"))
+	if resp.Error != nil {
+		t.Fatalf("tools/call failed: %v", resp.Error)
+	}
+
+	data, err := json.Marshal(resp.Result)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded struct {
+		Content           []Content              `json:"content"`
+		StructuredContent map[string]interface{} `json:"structuredContent"`
+		IsError           bool                   `json:"isError"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if !decoded.IsError {
+		t.Error("A failed build should be reported with isError")
+	}
+	if decoded.StructuredContent["exit_code"] != float64(65) {
+		t.Errorf("Unexpected structuredContent: %v", decoded.StructuredContent)
+	}
+	if len(decoded.Content) != 1 || !strings.Contains(decoded.Content[0].Text, `"exit_code":65`) {
+		t.Errorf("Expected the structured result as text too, got %+v", decoded.Content)
+	}
+}
+
+func TestServer_RegisteredToolsHaveOutputSchemas(t *testing.T) {
+	logger := log.New(bytes.NewBuffer(nil), "", 0)
+	server, _ := NewServer(logger)
+
+	for _, def := range server.registry.ListTools() {
+		if def.OutputSchema == nil {
+			t.Errorf("%s has no output schema", def.Name)
+			continue
+		}
+		if def.OutputSchema["type"] != "object" {
+			t.Errorf("%s output schema must describe an object, got %v", def.Name, def.OutputSchema["type"])
+		}
+	}
 }
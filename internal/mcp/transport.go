@@ -7,11 +7,16 @@ import (
 	"io"
 	"log"
 	"os"
+	"sync"
 )
 
 type Transport interface {
 	ReadRequest() (*Request, error)
 	WriteResponse(*Response) error
+	// WriteNotification sends a server-initiated message. requestID names
+	// the request the notification belongs to, or is nil, so transports
+	// serving several clients can route it.
+	WriteNotification(requestID interface{}, notification *Notification) error
 	Close() error
 }
 
@@ -19,6 +24,10 @@ type StdioTransport struct {
 	reader *bufio.Reader
 	writer io.Writer
 	logger *log.Logger
+
+	// writeMu serializes responses from concurrently running requests so
+	// that each JSON message lands on its own line
+	writeMu sync.Mutex
 }
 
 func NewStdioTransport(logger *log.Logger) (*StdioTransport, error) {
@@ -52,9 +61,17 @@ func (t *StdioTransport) ReadRequest() (*Request, error) {
 }
 
 func (t *StdioTransport) WriteResponse(resp *Response) error {
-	data, err := json.Marshal(resp)
+	return t.writeMessage(resp)
+}
+
+func (t *StdioTransport) WriteNotification(requestID interface{}, notification *Notification) error {
+	return t.writeMessage(notification)
+}
+
+func (t *StdioTransport) writeMessage(message interface{}) error {
+	data, err := json.Marshal(message)
 	if err != nil {
-		return fmt.Errorf("failed to marshal response: %w", err)
+		return fmt.Errorf("failed to marshal message: %w", err)
 	}
 
 	// Only log in debug mode
@@ -62,12 +79,14 @@ func (t *StdioTransport) WriteResponse(resp *Response) error {
 		t.logger.Printf("Sending: %s", string(data))
 	}
 
-	if _, err := t.writer.Write(data); err != nil {
-		return fmt.Errorf("failed to write response: %w", err)
-	}
+	// Write message and newline in one call so it cannot interleave
+	data = append(data, '\n')
 
-	if _, err := t.writer.Write([]byte("\n")); err != nil {
-		return fmt.Errorf("failed to write newline: %w", err)
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+
+	if _, err := t.writer.Write(data); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
 	}
 
 	return nil
@@ -7,6 +7,7 @@ import (
 	"io"
 	"log"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -71,6 +72,63 @@ func TestStdioTransport_WriteResponse(t *testing.T) {
 	}
 }
 
+func TestStdioTransport_WriteResponse_Concurrent(t *testing.T) {
+	writer := &bytes.Buffer{}
+	logger := log.New(bytes.NewBuffer(nil), "", 0)
+
+	transport := &StdioTransport{
+		reader: bufio.NewReader(strings.NewReader("")),
+		writer: writer,
+		logger: logger,
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			transport.WriteResponse(&Response{JSONRPC: "2.0", ID: id, Result: strings.Repeat("x", 512)})
+		}(i)
+	}
+	wg.Wait()
+
+	lines := strings.Split(strings.TrimSuffix(writer.String(), "\n"), "\n")
+	if len(lines) != 50 {
+		t.Fatalf("Expected 50 lines, got %d", len(lines))
+	}
+	for _, line := range lines {
+		var resp Response
+		if err := json.Unmarshal([]byte(line), &resp); err != nil {
+			t.Fatalf("Interleaved output produced invalid JSON line: %v", err)
+		}
+	}
+}
+
+func TestStdioTransport_WriteNotification(t *testing.T) {
+	writer := &bytes.Buffer{}
+	logger := log.New(bytes.NewBuffer(nil), "", 0)
+
+	transport := &StdioTransport{
+		reader: bufio.NewReader(strings.NewReader("")),
+		writer: writer,
+		logger: logger,
+	}
+
+	notification := &Notification{
+		JSONRPC: "2.0",
+		Method:  "notifications/progress",
+		Params:  ProgressParams{ProgressToken: 3, Progress: 10},
+	}
+	if err := transport.WriteNotification(1, notification); err != nil {
+		t.Fatalf("Failed to write notification: %v", err)
+	}
+
+	expected := `{"jsonrpc":"2.0","method":"notifications/progress","params":{"progressToken":3,"progress":10}}` + "\n"
+	if writer.String() != expected {
+		t.Errorf("Output mismatch:\ngot  %s\nwant %s", writer.String(), expected)
+	}
+}
+
 func TestStdioTransport_ReadRequest_EOF(t *testing.T) {
 	reader := strings.NewReader("")
 	writer := &bytes.Buffer{}
@@ -0,0 +1,322 @@
+package mcp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jontolof/xcode-build-mcp/internal/runs"
+	"github.com/jontolof/xcode-build-mcp/pkg/types"
+)
+
+// workflowPrompt renders a triage prompt from a recorded run. Unless the
+// client passes its own details, the prompt embeds the parsed results of the
+// run named by run_id, or of the latest run of the source tools. A run_id
+// that names no usable run is an error even when details are given.
+type workflowPrompt struct {
+	name        string
+	description string
+	sources     []string
+	detailArg   PromptArgument
+	store       *runs.Store
+	render      func(run *runs.Run, details string) (string, error)
+}
+
+func (p *workflowPrompt) Name() string {
+	return p.name
+}
+
+func (p *workflowPrompt) Description() string {
+	return p.description
+}
+
+func (p *workflowPrompt) Arguments() []PromptArgument {
+	return []PromptArgument{
+		{
+			Name:        "run_id",
+			Description: fmt.Sprintf("Run to triage (defaults to the latest %s run)", strings.Join(p.sources, " or ")),
+		},
+		p.detailArg,
+		{
+			Name:        "notes",
+			Description: "Extra context to include, such as what was changed before the failure",
+		},
+	}
+}
+
+func (p *workflowPrompt) Render(args map[string]string) (*GetPromptResult, error) {
+	details := args[p.detailArg.Name]
+
+	// Details stand in for a missing latest run, not for the run the
+	// client asked for
+	run, err := p.findRun(args["run_id"])
+	if err != nil && (args["run_id"] != "" || details == "") {
+		return nil, err
+	}
+
+	text, err := p.render(run, details)
+	if err != nil {
+		return nil, err
+	}
+
+	if notes := args["notes"]; notes != "" {
+		text += "\nAdditional context:\n" + notes + "\n"
+	}
+
+	return &GetPromptResult{
+		Description: p.description,
+		Messages: []PromptMessage{{
+			Role:    "user",
+			Content: Content{Type: "text", Text: text},
+		}},
+	}, nil
+}
+
+func (p *workflowPrompt) findRun(id string) (*runs.Run, error) {
+	if id != "" {
+		run := p.store.Get(id)
+		if run == nil {
+			return nil, fmt.Errorf("run %s not found or no longer retained", id)
+		}
+		for _, source := range p.sources {
+			if run.Tool == source {
+				return run, nil
+			}
+		}
+		return nil, fmt.Errorf("run %s was produced by %s, expected %s", id, run.Tool, strings.Join(p.sources, " or "))
+	}
+
+	for _, run := range p.store.List() {
+		for _, source := range p.sources {
+			if run.Tool == source {
+				return run, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no %s run recorded yet; run it first or pass the %s argument",
+		strings.Join(p.sources, " or "), p.detailArg.Name)
+}
+
+func newFixFailingBuildPrompt(store *runs.Store) Prompt {
+	return &workflowPrompt{
+		name:        "fix_failing_build",
+		description: "Fix the compiler errors from the latest failing Xcode build",
+		sources:     []string{"xcode_build"},
+		detailArg: PromptArgument{
+			Name:        "errors",
+			Description: "Build errors to fix (defaults to the errors parsed from the run)",
+		},
+		store:  store,
+		render: renderFixFailingBuild,
+	}
+}
+
+func renderFixFailingBuild(run *runs.Run, details string) (string, error) {
+	var b strings.Builder
+	b.WriteString("The Xcode build failed. Fix the errors below with minimal, targeted changes, ")
+	b.WriteString("then rebuild with xcode_build to confirm the fix.\n\n")
+
+	if details != "" {
+		b.WriteString("Errors:\n")
+		b.WriteString(details)
+		b.WriteString("\n")
+	} else {
+		result := run.BuildResult
+		if result == nil {
+			return "", fmt.Errorf("run %s has no parsed build result", run.ID)
+		}
+		if result.Success {
+			return "", fmt.Errorf("run %s succeeded; there is nothing to fix", run.ID)
+		}
+
+		if len(result.Errors) == 0 {
+			fmt.Fprintf(&b, "No compiler errors were parsed (exit code %d, crash type %s). ", result.ExitCode, result.CrashType)
+			b.WriteString("Read the full log to find the cause.\n")
+		} else {
+			b.WriteString("Errors:\n")
+			for _, e := range result.Errors {
+				b.WriteString("- ")
+				if e.File != "" {
+					b.WriteString(e.File)
+					if e.Line > 0 {
+						fmt.Fprintf(&b, ":%d:%d", e.Line, e.Column)
+					}
+					b.WriteString(": ")
+				}
+				b.WriteString(e.Message)
+				b.WriteString("\n")
+			}
+		}
+
+		if len(result.Warnings) > 0 {
+			fmt.Fprintf(&b, "\n%d warnings were also reported; leave them unless they cause the errors.\n", len(result.Warnings))
+		}
+	}
+
+	if run != nil {
+		fmt.Fprintf(&b, "\nBuild command: %s\nFull log: %s\n", run.Command, run.LogURI())
+	}
+
+	return b.String(), nil
+}
+
+func newTriageFailingTestsPrompt(store *runs.Store) Prompt {
+	return &workflowPrompt{
+		name:        "triage_failing_tests",
+		description: "Triage and fix the failing tests from the latest Xcode test run",
+		sources:     []string{"xcode_test"},
+		detailArg: PromptArgument{
+			Name:        "failures",
+			Description: "Test failures to triage (defaults to the failures parsed from the run)",
+		},
+		store:  store,
+		render: renderTriageFailingTests,
+	}
+}
+
+func renderTriageFailingTests(run *runs.Run, details string) (string, error) {
+	var b strings.Builder
+	b.WriteString("These tests failed. For each failure, decide whether the test or the code under test is wrong ")
+	b.WriteString("and explain why, fix it, then rerun only the affected tests with xcode_test.\n\n")
+
+	if details != "" {
+		b.WriteString("Failures:\n")
+		b.WriteString(details)
+		b.WriteString("\n")
+	} else {
+		result := run.TestResult
+		if result == nil {
+			return "", fmt.Errorf("run %s has no parsed test result", run.ID)
+		}
+
+		summary := result.TestSummary
+		if len(summary.FailedTestsDetails) == 0 {
+			if summary.UnparsedFailures || !result.Success {
+				return "", fmt.Errorf("run %s failed but no individual test failures were parsed; see %s", run.ID, run.LogURI())
+			}
+			return "", fmt.Errorf("run %s has no failing tests", run.ID)
+		}
+
+		fmt.Fprintf(&b, "%d of %d tests failed:\n", summary.FailedTests, summary.TotalTests)
+		for _, tc := range summary.FailedTestsDetails {
+			b.WriteString("- ")
+			if tc.ClassName != "" {
+				b.WriteString(tc.ClassName)
+				b.WriteString(".")
+			}
+			b.WriteString(tc.Name)
+			if tc.Message != "" {
+				b.WriteString(": ")
+				b.WriteString(tc.Message)
+			}
+			if tc.Location != "" {
+				fmt.Fprintf(&b, " (%s)", tc.Location)
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	if run != nil {
+		fmt.Fprintf(&b, "\nTest command: %s\nFull log: %s\n", run.Command, run.LogURI())
+		if run.ResultBundle != "" {
+			fmt.Fprintf(&b, "Result bundle: %s\n", run.ResultBundleURI())
+		}
+	}
+
+	return b.String(), nil
+}
+
+func newInvestigateCrashPrompt(store *runs.Store) Prompt {
+	return &workflowPrompt{
+		name:        "investigate_crash",
+		description: "Find the root cause of a crash during the latest Xcode build or test run",
+		sources:     []string{"xcode_test", "xcode_build"},
+		detailArg: PromptArgument{
+			Name:        "crash_details",
+			Description: "Crash description or report excerpt (defaults to the crash data recorded with the run)",
+		},
+		store:  store,
+		render: renderInvestigateCrash,
+	}
+}
+
+func renderInvestigateCrash(run *runs.Run, details string) (string, error) {
+	var b strings.Builder
+	b.WriteString("A process crashed. Find the root cause from the evidence below, read the crash reports ")
+	b.WriteString("and log before changing code, then propose and apply a fix.\n\n")
+
+	if details != "" {
+		b.WriteString("Crash details:\n")
+		b.WriteString(details)
+		b.WriteString("\n")
+	} else {
+		var crashType, signal string
+		var indicators types.CrashIndicators
+		switch {
+		case run.TestResult != nil:
+			crashType = string(run.TestResult.CrashType)
+			if state := run.TestResult.ProcessState; state != nil && state.Signaled {
+				signal = state.SignalName
+			}
+			indicators = run.TestResult.CrashIndicators
+		case run.BuildResult != nil:
+			crashType = string(run.BuildResult.CrashType)
+			if state := run.BuildResult.ProcessState; state != nil && state.Signaled {
+				signal = state.SignalName
+			}
+			indicators = run.BuildResult.CrashIndicators
+		default:
+			return "", fmt.Errorf("run %s has no parsed result", run.ID)
+		}
+
+		if (crashType == "" || crashType == "none") && len(run.CrashReports) == 0 {
+			return "", fmt.Errorf("no crash was detected in run %s", run.ID)
+		}
+
+		fmt.Fprintf(&b, "Crash type: %s\n", crashType)
+		if signal != "" {
+			fmt.Fprintf(&b, "Signal: %s\n", signal)
+		}
+		if names := crashIndicatorNames(indicators); len(names) > 0 {
+			fmt.Fprintf(&b, "Indicators: %s\n", strings.Join(names, ", "))
+		}
+		if len(run.CrashReports) > 0 {
+			b.WriteString("Crash reports:\n")
+			for i, report := range run.CrashReports {
+				fmt.Fprintf(&b, "- %s (%s) %s\n", report.ProcessName, report.ExceptionType, run.CrashReportURI(i))
+			}
+		}
+	}
+
+	if run != nil {
+		fmt.Fprintf(&b, "\nCommand: %s\nFull log: %s\n", run.Command, run.LogURI())
+	}
+
+	return b.String(), nil
+}
+
+// crashIndicatorNames lists the indicators that are set, using their JSON
+// names so they match the tool responses
+func crashIndicatorNames(indicators types.CrashIndicators) []string {
+	flags := []struct {
+		set  bool
+		name string
+	}{
+		{indicators.TestRunnerCrashed, "test_runner_crashed"},
+		{indicators.TestProcessCrashed, "test_process_crashed"},
+		{indicators.FatalErrorDetected, "fatal_error_detected"},
+		{indicators.SwiftRuntimeCrash, "swift_runtime_crash"},
+		{indicators.ConnectionInterrupted, "connection_interrupted"},
+		{indicators.EarlyExit, "early_exit"},
+		{indicators.NeverBeganTesting, "never_began_testing"},
+		{indicators.BundleLoadFailed, "bundle_load_failed"},
+		{indicators.SimulatorBootTimeout, "simulator_boot_timeout"},
+	}
+
+	var names []string
+	for _, flag := range flags {
+		if flag.set {
+			names = append(names, flag.name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,305 @@
+package plist
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+	"unicode/utf16"
+)
+
+const (
+	binaryHeaderSize  = 8
+	binaryTrailerSize = 32
+	// maxBinaryDepth bounds nesting so crafted files with reference cycles
+	// fail instead of recursing forever
+	maxBinaryDepth = 512
+	// maxBinaryObjects bounds the values produced while decoding, since
+	// shared references expand into copies and a short chain of arrays
+	// that each reference the previous one twice doubles at every step
+	maxBinaryObjects = 1 << 20
+)
+
+// appleEpoch is the reference date of plist dates, 2001-01-01 UTC
+var appleEpoch = time.Date(2001, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+type binaryDecoder struct {
+	data       []byte
+	offsets    []uint64
+	refSize    int
+	inProgress map[uint64]bool
+	decoded    int
+}
+
+func decodeBinary(data []byte) (interface{}, error) {
+	if len(data) < binaryHeaderSize+binaryTrailerSize || string(data[:8]) != "bplist00" {
+		return nil, fmt.Errorf("invalid binary plist: missing bplist00 header")
+	}
+
+	trailer := data[len(data)-binaryTrailerSize:]
+	offsetSize := int(trailer[6])
+	refSize := int(trailer[7])
+	numObjects := binary.BigEndian.Uint64(trailer[8:16])
+	topObject := binary.BigEndian.Uint64(trailer[16:24])
+	tableOffset := binary.BigEndian.Uint64(trailer[24:32])
+
+	if offsetSize < 1 || offsetSize > 8 || refSize < 1 || refSize > 8 {
+		return nil, fmt.Errorf("invalid binary plist: bad trailer")
+	}
+	tableEnd := uint64(len(data) - binaryTrailerSize)
+	if numObjects == 0 || topObject >= numObjects || tableOffset < binaryHeaderSize ||
+		tableOffset > tableEnd || numObjects > (tableEnd-tableOffset)/uint64(offsetSize) {
+		return nil, fmt.Errorf("invalid binary plist: bad offset table")
+	}
+
+	d := &binaryDecoder{
+		data:       data[:tableOffset],
+		offsets:    make([]uint64, numObjects),
+		refSize:    refSize,
+		inProgress: make(map[uint64]bool),
+	}
+	for i := range d.offsets {
+		start := tableOffset + uint64(i*offsetSize)
+		d.offsets[i] = readUint(data[start : start+uint64(offsetSize)])
+	}
+
+	return d.object(topObject, 0)
+}
+
+func (d *binaryDecoder) object(ref uint64, depth int) (interface{}, error) {
+	if ref >= uint64(len(d.offsets)) {
+		return nil, fmt.Errorf("invalid binary plist: object reference %d out of range", ref)
+	}
+	if depth > maxBinaryDepth || d.inProgress[ref] {
+		return nil, fmt.Errorf("invalid binary plist: object %d refers to itself", ref)
+	}
+	if d.decoded++; d.decoded > maxBinaryObjects {
+		return nil, fmt.Errorf("invalid binary plist: shared references expand to more than %d objects", maxBinaryObjects)
+	}
+
+	offset := d.offsets[ref]
+	if offset < binaryHeaderSize || offset >= uint64(len(d.data)) {
+		return nil, fmt.Errorf("invalid binary plist: object %d offset out of range", ref)
+	}
+	marker := d.data[offset]
+	kind, info := marker>>4, int(marker&0x0F)
+	pos := offset + 1
+
+	switch kind {
+	case 0x0:
+		switch info {
+		case 0x8:
+			return false, nil
+		case 0x9:
+			return true, nil
+		}
+		return nil, fmt.Errorf("invalid binary plist: unsupported marker 0x%02x", marker)
+
+	case 0x1:
+		size := uint64(1) << info
+		b, err := d.bytes(pos, size)
+		if err != nil {
+			return nil, err
+		}
+		switch size {
+		case 1, 2, 4, 8:
+			return int64(readUint(b)), nil
+		case 16:
+			// 128-bit integers only hold unsigned values above MaxInt64
+			if readUint(b[:8]) != 0 {
+				return nil, fmt.Errorf("invalid binary plist: integer overflows 64 bits")
+			}
+			v := readUint(b[8:])
+			if v > math.MaxInt64 {
+				return v, nil
+			}
+			return int64(v), nil
+		}
+		return nil, fmt.Errorf("invalid binary plist: %d byte integer", size)
+
+	case 0x2:
+		size := uint64(1) << info
+		b, err := d.bytes(pos, size)
+		if err != nil {
+			return nil, err
+		}
+		switch size {
+		case 4:
+			return float64(math.Float32frombits(uint32(readUint(b)))), nil
+		case 8:
+			return math.Float64frombits(readUint(b)), nil
+		}
+		return nil, fmt.Errorf("invalid binary plist: %d byte real", size)
+
+	case 0x3:
+		if info != 0x3 {
+			return nil, fmt.Errorf("invalid binary plist: unsupported marker 0x%02x", marker)
+		}
+		b, err := d.bytes(pos, 8)
+		if err != nil {
+			return nil, err
+		}
+		return dateFromSeconds(math.Float64frombits(readUint(b))), nil
+
+	case 0x4:
+		count, pos, err := d.count(info, pos)
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.bytes(pos, count)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), b...), nil
+
+	case 0x5, 0x7:
+		count, pos, err := d.count(info, pos)
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.bytes(pos, count)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+
+	case 0x6:
+		count, pos, err := d.count(info, pos)
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.bytes(pos, count*2)
+		if err != nil {
+			return nil, err
+		}
+		units := make([]uint16, count)
+		for i := range units {
+			units[i] = binary.BigEndian.Uint16(b[i*2:])
+		}
+		return string(utf16.Decode(units)), nil
+
+	case 0x8:
+		b, err := d.bytes(pos, uint64(info)+1)
+		if err != nil {
+			return nil, err
+		}
+		return UID(readUint(b)), nil
+
+	case 0xA, 0xC:
+		count, pos, err := d.count(info, pos)
+		if err != nil {
+			return nil, err
+		}
+		refs, err := d.refs(pos, count)
+		if err != nil {
+			return nil, err
+		}
+
+		d.inProgress[ref] = true
+		defer delete(d.inProgress, ref)
+
+		array := make([]interface{}, 0, len(refs))
+		for _, r := range refs {
+			value, err := d.object(r, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		return array, nil
+
+	case 0xD:
+		count, pos, err := d.count(info, pos)
+		if err != nil {
+			return nil, err
+		}
+		refs, err := d.refs(pos, count*2)
+		if err != nil {
+			return nil, err
+		}
+
+		d.inProgress[ref] = true
+		defer delete(d.inProgress, ref)
+
+		dict := make(map[string]interface{}, count)
+		for i := uint64(0); i < count; i++ {
+			key, err := d.object(refs[i], depth+1)
+			if err != nil {
+				return nil, err
+			}
+			name, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid binary plist: dictionary key is %T, not a string", key)
+			}
+			value, err := d.object(refs[count+i], depth+1)
+			if err != nil {
+				return nil, err
+			}
+			dict[name] = value
+		}
+		return dict, nil
+	}
+
+	return nil, fmt.Errorf("invalid binary plist: unsupported marker 0x%02x", marker)
+}
+
+// count returns the length encoded in the low nibble of a marker, which
+// holds 0xF when an integer object with the real length follows
+func (d *binaryDecoder) count(info int, pos uint64) (uint64, uint64, error) {
+	if info != 0xF {
+		return uint64(info), pos, nil
+	}
+	marker, err := d.bytes(pos, 1)
+	if err != nil {
+		return 0, 0, err
+	}
+	if marker[0]>>4 != 0x1 || marker[0]&0x0F > 3 {
+		return 0, 0, fmt.Errorf("invalid binary plist: bad length marker 0x%02x", marker[0])
+	}
+	size := uint64(1) << (marker[0] & 0x0F)
+	b, err := d.bytes(pos+1, size)
+	if err != nil {
+		return 0, 0, err
+	}
+	count := readUint(b)
+	if count > uint64(len(d.data)) {
+		return 0, 0, fmt.Errorf("invalid binary plist: length %d runs past the object table", count)
+	}
+	return count, pos + 1 + size, nil
+}
+
+func (d *binaryDecoder) refs(pos, count uint64) ([]uint64, error) {
+	if count > uint64(len(d.data))/uint64(d.refSize) {
+		return nil, fmt.Errorf("invalid binary plist: collection runs past the object table")
+	}
+	b, err := d.bytes(pos, count*uint64(d.refSize))
+	if err != nil {
+		return nil, err
+	}
+	refs := make([]uint64, count)
+	for i := range refs {
+		refs[i] = readUint(b[i*d.refSize : (i+1)*d.refSize])
+	}
+	return refs, nil
+}
+
+func (d *binaryDecoder) bytes(pos, size uint64) ([]byte, error) {
+	if pos > uint64(len(d.data)) || size > uint64(len(d.data))-pos {
+		return nil, fmt.Errorf("invalid binary plist: object runs past the object table")
+	}
+	return d.data[pos : pos+size], nil
+}
+
+// readUint reads a big-endian unsigned integer of up to 8 bytes
+func readUint(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+func dateFromSeconds(seconds float64) time.Time {
+	whole, frac := math.Modf(seconds)
+	return appleEpoch.Add(time.Duration(whole)*time.Second + time.Duration(frac*float64(time.Second))).UTC()
+}
@@ -0,0 +1,376 @@
+package plist
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+const xmlHeader = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+`
+
+// Marshal encodes v as a binary or XML property list. Besides the types
+// Unmarshal produces it accepts any integer or float type and slices and
+// string-keyed maps of supported values. Dictionary keys are written sorted.
+func Marshal(v interface{}, format Format) ([]byte, error) {
+	value, err := normalize(reflect.ValueOf(v), 0)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case BinaryFormat:
+		return encodeBinary(value), nil
+	case XMLFormat:
+		var b bytes.Buffer
+		b.WriteString(xmlHeader)
+		encodeXML(&b, value, 0)
+		b.WriteString("</plist>\n")
+		return b.Bytes(), nil
+	}
+	return nil, fmt.Errorf("encoding %s plists is not supported", format)
+}
+
+// normalize converts v to the value types Unmarshal produces
+func normalize(v reflect.Value, depth int) (interface{}, error) {
+	if depth > maxBinaryDepth {
+		return nil, fmt.Errorf("plist value nested too deep")
+	}
+	if !v.IsValid() {
+		return nil, fmt.Errorf("nil is not a plist value")
+	}
+
+	switch value := v.Interface().(type) {
+	case time.Time:
+		return value.UTC(), nil
+	case []byte:
+		return value, nil
+	case UID:
+		return value, nil
+	}
+
+	switch v.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		if v.IsNil() {
+			return nil, fmt.Errorf("nil is not a plist value")
+		}
+		return normalize(v.Elem(), depth)
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return v.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if n := v.Uint(); n > math.MaxInt64 {
+			return n, nil
+		}
+		return int64(v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	case reflect.Slice, reflect.Array:
+		array := make([]interface{}, v.Len())
+		for i := range array {
+			item, err := normalize(v.Index(i), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			array[i] = item
+		}
+		return array, nil
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("plist dictionary keys must be strings, not %s", v.Type().Key())
+		}
+		dict := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			item, err := normalize(iter.Value(), depth+1)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", iter.Key().String(), err)
+			}
+			dict[iter.Key().String()] = item
+		}
+		return dict, nil
+	}
+	return nil, fmt.Errorf("%s is not a plist value", v.Type())
+}
+
+func sortedKeys(dict map[string]interface{}) []string {
+	keys := make([]string, 0, len(dict))
+	for key := range dict {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func encodeXML(b *bytes.Buffer, value interface{}, depth int) {
+	indent := strings.Repeat("\t", depth)
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			b.WriteString(indent + "<dict/>\n")
+			return
+		}
+		b.WriteString(indent + "<dict>\n")
+		for _, key := range sortedKeys(v) {
+			b.WriteString(indent + "\t<key>")
+			xml.EscapeText(b, []byte(key))
+			b.WriteString("</key>\n")
+			encodeXML(b, v[key], depth+1)
+		}
+		b.WriteString(indent + "</dict>\n")
+	case []interface{}:
+		if len(v) == 0 {
+			b.WriteString(indent + "<array/>\n")
+			return
+		}
+		b.WriteString(indent + "<array>\n")
+		for _, item := range v {
+			encodeXML(b, item, depth+1)
+		}
+		b.WriteString(indent + "</array>\n")
+	case string:
+		b.WriteString(indent + "<string>")
+		xml.EscapeText(b, []byte(v))
+		b.WriteString("</string>\n")
+	case bool:
+		if v {
+			b.WriteString(indent + "<true/>\n")
+		} else {
+			b.WriteString(indent + "<false/>\n")
+		}
+	case int64:
+		b.WriteString(indent + "<integer>" + strconv.FormatInt(v, 10) + "</integer>\n")
+	case uint64:
+		b.WriteString(indent + "<integer>" + strconv.FormatUint(v, 10) + "</integer>\n")
+	case UID:
+		// XML has no UID type, so it is written the way plutil does
+		b.WriteString(indent + "<dict>\n" + indent + "\t<key>CF$UID</key>\n" +
+			indent + "\t<integer>" + strconv.FormatUint(uint64(v), 10) + "</integer>\n" + indent + "</dict>\n")
+	case float64:
+		b.WriteString(indent + "<real>" + formatReal(v) + "</real>\n")
+	case time.Time:
+		b.WriteString(indent + "<date>" + v.Format("2006-01-02T15:04:05Z") + "</date>\n")
+	case []byte:
+		// Data is wrapped to lines of at most 76 columns, counting tabs as 8
+		encoded := base64.StdEncoding.EncodeToString(v)
+		width := max(16, 76-8*depth)
+		b.WriteString(indent + "<data>\n")
+		for len(encoded) > 0 {
+			line := encoded[:min(width, len(encoded))]
+			encoded = encoded[len(line):]
+			b.WriteString(indent + line + "\n")
+		}
+		b.WriteString(indent + "</data>\n")
+	}
+}
+
+func formatReal(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "nan"
+	case math.IsInf(v, 1):
+		return "+infinity"
+	case math.IsInf(v, -1):
+		return "-infinity"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// binaryEncoder flattens values into the object table of a bplist00 file.
+// Strings are stored once however often they occur, which keeps the
+// repeated keys of arrays of dictionaries small.
+type binaryEncoder struct {
+	objects []interface{}
+	refs    [][]int
+	strings map[string]int
+}
+
+func encodeBinary(value interface{}) []byte {
+	e := &binaryEncoder{strings: make(map[string]int)}
+	e.flatten(value)
+
+	refSize := intSize(uint64(len(e.objects)))
+	var b bytes.Buffer
+	b.WriteString("bplist00")
+
+	offsets := make([]uint64, len(e.objects))
+	for i, object := range e.objects {
+		offsets[i] = uint64(b.Len())
+		e.writeObject(&b, object, e.refs[i], refSize)
+	}
+
+	tableOffset := uint64(b.Len())
+	offsetSize := intSize(tableOffset)
+	for _, offset := range offsets {
+		writeUint(&b, offset, offsetSize)
+	}
+
+	var trailer [32]byte
+	trailer[6] = byte(offsetSize)
+	trailer[7] = byte(refSize)
+	binary.BigEndian.PutUint64(trailer[8:], uint64(len(e.objects)))
+	binary.BigEndian.PutUint64(trailer[24:], tableOffset)
+	b.Write(trailer[:])
+	return b.Bytes()
+}
+
+// flatten adds value and its children to the object table and returns its
+// index. The top-level value is object 0.
+func (e *binaryEncoder) flatten(value interface{}) int {
+	if s, ok := value.(string); ok {
+		if index, exists := e.strings[s]; exists {
+			return index
+		}
+		e.strings[s] = len(e.objects)
+	}
+
+	index := len(e.objects)
+	e.objects = append(e.objects, value)
+	e.refs = append(e.refs, nil)
+
+	switch v := value.(type) {
+	case []interface{}:
+		refs := make([]int, len(v))
+		for i, item := range v {
+			refs[i] = e.flatten(item)
+		}
+		e.refs[index] = refs
+	case map[string]interface{}:
+		keys := sortedKeys(v)
+		refs := make([]int, 2*len(keys))
+		for i, key := range keys {
+			refs[i] = e.flatten(key)
+		}
+		for i, key := range keys {
+			refs[len(keys)+i] = e.flatten(v[key])
+		}
+		e.refs[index] = refs
+	}
+	return index
+}
+
+func (e *binaryEncoder) writeObject(b *bytes.Buffer, object interface{}, refs []int, refSize int) {
+	switch v := object.(type) {
+	case bool:
+		if v {
+			b.WriteByte(0x09)
+		} else {
+			b.WriteByte(0x08)
+		}
+	case int64:
+		writeInt(b, v)
+	case uint64:
+		// Unsigned values above MaxInt64 need the 16 byte form
+		b.WriteByte(0x14)
+		b.Write(make([]byte, 8))
+		writeUint(b, v, 8)
+	case float64:
+		b.WriteByte(0x23)
+		writeUint(b, math.Float64bits(v), 8)
+	case time.Time:
+		b.WriteByte(0x33)
+		writeUint(b, math.Float64bits(v.Sub(appleEpoch).Seconds()), 8)
+	case []byte:
+		writeMarker(b, 0x4, len(v))
+		b.Write(v)
+	case string:
+		if isASCII(v) {
+			writeMarker(b, 0x5, len(v))
+			b.WriteString(v)
+			return
+		}
+		units := utf16.Encode([]rune(v))
+		writeMarker(b, 0x6, len(units))
+		for _, unit := range units {
+			writeUint(b, uint64(unit), 2)
+		}
+	case UID:
+		size := intSize(uint64(v))
+		b.WriteByte(0x80 | byte(size-1))
+		writeUint(b, uint64(v), size)
+	case []interface{}:
+		writeMarker(b, 0xA, len(refs))
+		for _, ref := range refs {
+			writeUint(b, uint64(ref), refSize)
+		}
+	case map[string]interface{}:
+		writeMarker(b, 0xD, len(refs)/2)
+		for _, ref := range refs {
+			writeUint(b, uint64(ref), refSize)
+		}
+	}
+}
+
+// writeMarker writes an object marker with its length, which follows as an
+// integer object when it does not fit in the low nibble
+func writeMarker(b *bytes.Buffer, kind byte, length int) {
+	if length < 0xF {
+		b.WriteByte(kind<<4 | byte(length))
+		return
+	}
+	b.WriteByte(kind<<4 | 0xF)
+	writeInt(b, int64(length))
+}
+
+// writeInt writes an integer object in the smallest size that holds it.
+// Only the 8 byte form is signed.
+func writeInt(b *bytes.Buffer, v int64) {
+	size := 8
+	if v >= 0 {
+		size = intSize(uint64(v))
+	}
+	switch size {
+	case 1:
+		b.WriteByte(0x10)
+	case 2:
+		b.WriteByte(0x11)
+	case 4:
+		b.WriteByte(0x12)
+	default:
+		b.WriteByte(0x13)
+	}
+	writeUint(b, uint64(v), size)
+}
+
+func writeUint(b *bytes.Buffer, v uint64, size int) {
+	for i := size - 1; i >= 0; i-- {
+		b.WriteByte(byte(v >> (8 * i)))
+	}
+}
+
+// intSize returns the smallest of 1, 2, 4 or 8 bytes that holds v
+func intSize(v uint64) int {
+	switch {
+	case v <= math.MaxUint8:
+		return 1
+	case v <= math.MaxUint16:
+		return 2
+	case v <= math.MaxUint32:
+		return 4
+	}
+	return 8
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
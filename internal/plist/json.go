@@ -0,0 +1,50 @@
+package plist
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// decodeJSON reads the JSON form written by plutil -convert json and used by
+// .xctestplan files. JSON has no dates or data, and null decodes to nil.
+func decodeJSON(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("invalid JSON plist: %w", err)
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("invalid JSON plist: unexpected data after the top-level value")
+	}
+	return convertJSON(value), nil
+}
+
+// convertJSON turns json.Number into int64, uint64 or float64
+func convertJSON(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = convertJSON(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = convertJSON(item)
+		}
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		if n, err := strconv.ParseUint(v.String(), 10, 64); err == nil {
+			return n
+		}
+		if n, err := v.Float64(); err == nil {
+			return n
+		}
+	}
+	return value
+}
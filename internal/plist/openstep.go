@@ -0,0 +1,295 @@
+package plist
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// maxOpenStepDepth bounds nesting the same way as in binary plists
+const maxOpenStepDepth = maxBinaryDepth
+
+// openStepDecoder reads the old-style ASCII format used by project.pbxproj.
+// It has no numbers, booleans or dates, so every scalar decodes to a string.
+type openStepDecoder struct {
+	data  []byte
+	pos   int
+	depth int
+}
+
+func decodeOpenStep(data []byte) (interface{}, error) {
+	d := &openStepDecoder{data: data}
+	if err := d.skipSpace(); err != nil {
+		return nil, err
+	}
+	if d.pos == len(d.data) {
+		return nil, fmt.Errorf("invalid OpenStep plist: no value")
+	}
+
+	value, err := d.value()
+	if err != nil {
+		return nil, err
+	}
+	if err := d.skipSpace(); err != nil {
+		return nil, err
+	}
+	if d.pos != len(d.data) {
+		return nil, d.errorf("unexpected %q after the top-level value", d.data[d.pos])
+	}
+	return value, nil
+}
+
+func (d *openStepDecoder) value() (interface{}, error) {
+	if d.depth >= maxOpenStepDepth {
+		return nil, d.errorf("nesting too deep")
+	}
+
+	switch c := d.data[d.pos]; {
+	case c == '{':
+		return d.dict()
+	case c == '(':
+		return d.array()
+	case c == '<':
+		return d.hexData()
+	case c == '"' || c == '\'':
+		return d.quoted(c)
+	case isUnquoted(c):
+		start := d.pos
+		for d.pos < len(d.data) && isUnquoted(d.data[d.pos]) {
+			d.pos++
+		}
+		return string(d.data[start:d.pos]), nil
+	default:
+		return nil, d.errorf("unexpected %q", c)
+	}
+}
+
+func (d *openStepDecoder) dict() (interface{}, error) {
+	d.pos++ // {
+	d.depth++
+	defer func() { d.depth-- }()
+
+	dict := make(map[string]interface{})
+	for {
+		if err := d.skipSpace(); err != nil {
+			return nil, err
+		}
+		if d.pos == len(d.data) {
+			return nil, d.errorf("unterminated dictionary")
+		}
+		if d.data[d.pos] == '}' {
+			d.pos++
+			return dict, nil
+		}
+
+		key, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		name, ok := key.(string)
+		if !ok {
+			return nil, d.errorf("dictionary key is %T, not a string", key)
+		}
+		if err := d.expect('='); err != nil {
+			return nil, err
+		}
+		if err := d.skipSpace(); err != nil {
+			return nil, err
+		}
+		if d.pos == len(d.data) {
+			return nil, d.errorf("key %q has no value", name)
+		}
+		if dict[name], err = d.value(); err != nil {
+			return nil, err
+		}
+		if err := d.expect(';'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (d *openStepDecoder) array() (interface{}, error) {
+	d.pos++ // (
+	d.depth++
+	defer func() { d.depth-- }()
+
+	array := []interface{}{}
+	for {
+		if err := d.skipSpace(); err != nil {
+			return nil, err
+		}
+		if d.pos == len(d.data) {
+			return nil, d.errorf("unterminated array")
+		}
+		if d.data[d.pos] == ')' {
+			d.pos++
+			return array, nil
+		}
+
+		value, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		array = append(array, value)
+
+		// Elements are separated by commas, with an optional trailing one
+		if err := d.skipSpace(); err != nil {
+			return nil, err
+		}
+		if d.pos < len(d.data) && d.data[d.pos] == ',' {
+			d.pos++
+		} else if d.pos < len(d.data) && d.data[d.pos] != ')' {
+			return nil, d.errorf("expected ',' or ')' in array")
+		}
+	}
+}
+
+func (d *openStepDecoder) hexData() (interface{}, error) {
+	end := bytes.IndexByte(d.data[d.pos:], '>')
+	if end < 0 {
+		return nil, d.errorf("unterminated data")
+	}
+	digits := strings.Map(func(r rune) rune {
+		if r == ' ' || r == '\t' || r == '\r' || r == '\n' {
+			return -1
+		}
+		return r
+	}, string(d.data[d.pos+1:d.pos+end]))
+
+	data, err := hex.DecodeString(digits)
+	if err != nil {
+		return nil, d.errorf("bad data: %v", err)
+	}
+	d.pos += end + 1
+	return data, nil
+}
+
+func (d *openStepDecoder) quoted(quote byte) (interface{}, error) {
+	d.pos++
+	var b strings.Builder
+	for d.pos < len(d.data) {
+		c := d.data[d.pos]
+		switch {
+		case c == quote:
+			d.pos++
+			return b.String(), nil
+		case c != '\\':
+			b.WriteByte(c)
+			d.pos++
+			continue
+		}
+
+		d.pos++
+		if d.pos == len(d.data) {
+			break
+		}
+		c = d.data[d.pos]
+		d.pos++
+		switch c {
+		case 'a':
+			b.WriteByte('\a')
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'v':
+			b.WriteByte('\v')
+		case 'U', 'u':
+			r, err := d.hexRune()
+			if err != nil {
+				return nil, err
+			}
+			// Characters outside the BMP are written as surrogate pairs
+			if utf16.IsSurrogate(r) && d.pos+6 <= len(d.data) && d.data[d.pos] == '\\' &&
+				(d.data[d.pos+1] == 'U' || d.data[d.pos+1] == 'u') {
+				d.pos += 2
+				low, err := d.hexRune()
+				if err != nil {
+					return nil, err
+				}
+				r = utf16.DecodeRune(r, low)
+			}
+			b.WriteRune(r)
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			// Up to three octal digits
+			v := int(c - '0')
+			for i := 0; i < 2 && d.pos < len(d.data) && d.data[d.pos] >= '0' && d.data[d.pos] <= '7'; i++ {
+				v = v*8 + int(d.data[d.pos]-'0')
+				d.pos++
+			}
+			b.WriteRune(rune(v))
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return nil, d.errorf("unterminated string")
+}
+
+// hexRune reads the four hex digits of a \U escape
+func (d *openStepDecoder) hexRune() (rune, error) {
+	if d.pos+4 > len(d.data) {
+		return 0, d.errorf("truncated \\U escape")
+	}
+	r, err := strconv.ParseUint(string(d.data[d.pos:d.pos+4]), 16, 16)
+	if err != nil {
+		return 0, d.errorf("bad \\U escape")
+	}
+	d.pos += 4
+	return rune(r), nil
+}
+
+func (d *openStepDecoder) expect(c byte) error {
+	if err := d.skipSpace(); err != nil {
+		return err
+	}
+	if d.pos == len(d.data) || d.data[d.pos] != c {
+		return d.errorf("expected %q", c)
+	}
+	d.pos++
+	return nil
+}
+
+// skipSpace skips whitespace and // or /* */ comments
+func (d *openStepDecoder) skipSpace() error {
+	for d.pos < len(d.data) {
+		switch c := d.data[d.pos]; {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			d.pos++
+		case c == '/' && d.pos+1 < len(d.data) && d.data[d.pos+1] == '/':
+			end := bytes.IndexByte(d.data[d.pos:], '\n')
+			if end < 0 {
+				d.pos = len(d.data)
+			} else {
+				d.pos += end + 1
+			}
+		case c == '/' && d.pos+1 < len(d.data) && d.data[d.pos+1] == '*':
+			end := bytes.Index(d.data[d.pos+2:], []byte("*/"))
+			if end < 0 {
+				return d.errorf("unterminated comment")
+			}
+			d.pos += end + 4
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+func (d *openStepDecoder) errorf(format string, args ...interface{}) error {
+	line := 1 + bytes.Count(d.data[:d.pos], []byte("\n"))
+	return fmt.Errorf("invalid OpenStep plist at line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+func isUnquoted(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		strings.IndexByte("_$+/:.-", c) >= 0
+}
@@ -0,0 +1,88 @@
+// Package plist reads and writes property lists in pure Go, so Info.plist
+// files, entitlements, project files and test plans can be handled on any
+// platform without plutil.
+//
+// Values decode to map[string]interface{} for dictionaries, []interface{}
+// for arrays, string, int64 (uint64 for unsigned values that do not fit),
+// float64, bool, time.Time for dates, []byte for data and UID for the object
+// references of keyed archives.
+package plist
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Format is a property list serialization
+type Format int
+
+const (
+	// BinaryFormat is the bplist00 format Xcode writes into built products
+	BinaryFormat Format = iota
+	// XMLFormat is the format of source Info.plist and entitlements files
+	XMLFormat
+	// OpenStepFormat is the old-style ASCII format of project.pbxproj
+	OpenStepFormat
+	// JSONFormat is the format of .xctestplan files and plutil -convert json
+	JSONFormat
+)
+
+func (f Format) String() string {
+	switch f {
+	case BinaryFormat:
+		return "binary"
+	case XMLFormat:
+		return "xml"
+	case OpenStepFormat:
+		return "openstep"
+	case JSONFormat:
+		return "json"
+	}
+	return fmt.Sprintf("Format(%d)", int(f))
+}
+
+// UID is an object reference in a binary plist written by NSKeyedArchiver
+type UID uint64
+
+// Unmarshal decodes a binary, XML, OpenStep or JSON property list, detecting
+// the format from the content
+func Unmarshal(data []byte) (interface{}, error) {
+	if bytes.HasPrefix(data, []byte("bplist")) {
+		return decodeBinary(data)
+	}
+
+	trimmed := bytes.TrimLeft(data, "\ufeff \t\r\n")
+	switch {
+	case len(trimmed) == 0:
+		return nil, fmt.Errorf("empty plist")
+	case trimmed[0] == '<':
+		// A top-level OpenStep data value also starts with '<'
+		value, err := decodeXML(trimmed)
+		if err == nil {
+			return value, nil
+		}
+		if value, openStepErr := decodeOpenStep(trimmed); openStepErr == nil {
+			return value, nil
+		}
+		return nil, err
+	case json.Valid(trimmed):
+		return decodeJSON(trimmed)
+	default:
+		return decodeOpenStep(trimmed)
+	}
+}
+
+// UnmarshalDict decodes a property list whose top-level value must be a
+// dictionary, as in Info.plist and entitlements
+func UnmarshalDict(data []byte) (map[string]interface{}, error) {
+	value, err := Unmarshal(data)
+	if err != nil {
+		return nil, err
+	}
+	dict, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("plist top-level value is %T, not a dictionary", value)
+	}
+	return dict, nil
+}
@@ -0,0 +1,268 @@
+package plist
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The fixtures were written by Python's plistlib from the same dictionary
+var wantInfo = map[string]interface{}{
+	"CFBundleIdentifier":         "com.example.TestApp",
+	"CFBundleName":               "TestApp",
+	"CFBundleDisplayName":        "Test Äpp ✓",
+	"CFBundleExecutable":         "TestApp",
+	"CFBundleShortVersionString": "1.2.3",
+	"CFBundleVersion":            "42",
+	"LSRequiresIPhoneOS":         true,
+	"UIRequiresFullScreen":       false,
+	"UIDeviceFamily":             []interface{}{int64(1), int64(2)},
+	"MinimumOSVersion":           "17.0",
+	"BuildNumber":                int64(9007199254740993),
+	"Ratio":                      0.75,
+	"BuildDate":                  time.Date(2026, time.March, 14, 15, 9, 26, 0, time.UTC),
+	"Token":                      []byte("\x00\x01\x02xcode"),
+	"CFBundleIcons": map[string]interface{}{
+		"CFBundlePrimaryIcon": map[string]interface{}{
+			"CFBundleIconFiles": []interface{}{"AppIcon60x60"},
+			"CFBundleIconName":  "AppIcon",
+		},
+	},
+}
+
+func TestUnmarshal_Formats(t *testing.T) {
+	for _, name := range []string{"Info.bplist", "Info.xml"} {
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile("testdata/" + name)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := UnmarshalDict(data)
+			if err != nil {
+				t.Fatalf("UnmarshalDict failed: %v", err)
+			}
+			for key, want := range wantInfo {
+				if !reflect.DeepEqual(got[key], want) {
+					t.Errorf("%s = %#v, want %#v", key, got[key], want)
+				}
+			}
+			if len(got) != len(wantInfo) {
+				t.Errorf("Expected %d keys, got %d", len(wantInfo), len(got))
+			}
+		})
+	}
+}
+
+func TestUnmarshal_XMLValues(t *testing.T) {
+	tests := []struct {
+		name string
+		xml  string
+		want interface{}
+	}{
+		{"bare value", "<string>hello &amp; bye</string>", "hello & bye"},
+		{"empty string", "<plist><string/></plist>", ""},
+		{"empty array", "<plist><array/></plist>", []interface{}{}},
+		{"hex integer", "<integer>0x10</integer>", int64(16)},
+		{"negative integer", "<integer> -3 </integer>", int64(-3)},
+		{"unsigned integer", "<integer>18446744073709551615</integer>", uint64(18446744073709551615)},
+		{"wrapped data", "<data>\n\tAAEC\n\teGNvZGU=\n</data>", []byte("\x00\x01\x02xcode")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Unmarshal([]byte(tt.xml))
+			if err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Unmarshal() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnmarshal_Invalid(t *testing.T) {
+	binary, err := os.ReadFile("testdata/Info.bplist")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An array (object 0) that contains itself
+	cycle := []byte("bplist00\xa1\x00\x08")
+	cycle = append(cycle, make([]byte, 6)...)
+	cycle = append(cycle, 1, 1, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 10)
+
+	// 64 arrays that each hold the previous one twice, expanding to 2^64
+	// values although the file holds 65 objects
+	var expansion bytes.Buffer
+	expansion.WriteString("bplist00")
+	offsets := []byte{8}
+	expansion.WriteByte(0x09)
+	for i := 1; i <= 64; i++ {
+		offsets = append(offsets, byte(expansion.Len()))
+		expansion.Write([]byte{0xa2, byte(i - 1), byte(i - 1)})
+	}
+	tableOffset := expansion.Len()
+	expansion.Write(offsets)
+	expansion.Write([]byte{0, 0, 0, 0, 0, 0, 1, 1})
+	expansion.Write([]byte{0, 0, 0, 0, 0, 0, 0, 65, 0, 0, 0, 0, 0, 0, 0, 64, 0, 0, 0, 0, 0, 0, 0, byte(tableOffset)})
+
+	tests := map[string][]byte{
+		"empty":            {},
+		"openstep":         []byte(`{ a = "unterminated; }`),
+		"trailing json":    []byte(`{"a": 1} {`),
+		"truncated binary": binary[:len(binary)-40],
+		"corrupt offsets":  append(bytes.Clone(binary[:len(binary)-8]), 0xff, 0, 0, 0, 0, 0, 0, 0),
+		"cycle":            cycle,
+		"shared expansion": expansion.Bytes(),
+		"unclosed xml":     []byte("<plist><dict><key>a</key>"),
+		"key without dict": []byte("<dict><string>a</string></dict>"),
+		"bad integer":      []byte("<integer>twelve</integer>"),
+	}
+
+	for name, data := range tests {
+		if _, err := Unmarshal(data); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	if _, err := Unmarshal(expansion.Bytes()); err == nil || !strings.Contains(err.Error(), "shared references") {
+		t.Errorf("Expected the object budget to stop the expansion, got %v", err)
+	}
+
+	if _, err := UnmarshalDict([]byte("(a, b)")); err == nil {
+		t.Error("Expected UnmarshalDict to reject an array")
+	}
+}
+
+func TestUnmarshal_OpenStep(t *testing.T) {
+	data, err := os.ReadFile("testdata/project.pbxproj")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	project, err := UnmarshalDict(data)
+	if err != nil {
+		t.Fatalf("UnmarshalDict failed: %v", err)
+	}
+	if project["objectVersion"] != "56" || project["rootObject"] != "1A0000000000000000000030" {
+		t.Errorf("Unexpected top-level values: %v", project)
+	}
+
+	objects := project["objects"].(map[string]interface{})
+	file := objects["1A0000000000000000000002"].(map[string]interface{})
+	if file["path"] != "Content View.swift" || file["sourceTree"] != "<group>" {
+		t.Errorf("Unexpected file reference: %v", file)
+	}
+
+	root := objects["1A0000000000000000000030"].(map[string]interface{})
+	wantTargets := []interface{}{"1A0000000000000000000010", "1A0000000000000000000011"}
+	if !reflect.DeepEqual(root["targets"], wantTargets) {
+		t.Errorf("targets = %v, want %v", root["targets"], wantTargets)
+	}
+	if root["comment"] != "Café \"quoted\"\n🚀" {
+		t.Errorf("Unexpected escapes: %q", root["comment"])
+	}
+	if !bytes.Equal(root["icon"].([]byte), []byte("\x89PNG\r\n")) {
+		t.Errorf("Unexpected data: %v", root["icon"])
+	}
+	if phases := objects["1A0000000000000000000010"].(map[string]interface{})["buildPhases"]; !reflect.DeepEqual(phases, []interface{}{}) {
+		t.Errorf("Expected an empty array, got %#v", phases)
+	}
+}
+
+func TestUnmarshal_JSON(t *testing.T) {
+	got, err := Unmarshal([]byte(`{"version": 1, "ratio": 0.5, "big": 18446744073709551615, "targets": [{"name": "AppTests", "enabled": false}]}`))
+	if err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	want := map[string]interface{}{
+		"version": int64(1),
+		"ratio":   0.5,
+		"big":     uint64(18446744073709551615),
+		"targets": []interface{}{map[string]interface{}{"name": "AppTests", "enabled": false}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unmarshal() = %#v, want %#v", got, want)
+	}
+}
+
+func TestMarshal_RoundTrip(t *testing.T) {
+	value := map[string]interface{}{}
+	for key, v := range wantInfo {
+		value[key] = v
+	}
+	value["Large"] = uint64(math.MaxUint64)
+	value["Negative"] = int64(-42)
+	value["LongString"] = strings.Repeat("x", 300)
+	value["Archive"] = []interface{}{UID(7), UID(300)}
+	value["Empty"] = map[string]interface{}{}
+	value["Plain"] = []string{"typed", "slice"}
+
+	for _, format := range []Format{BinaryFormat, XMLFormat} {
+		t.Run(format.String(), func(t *testing.T) {
+			data, err := Marshal(value, format)
+			if err != nil {
+				t.Fatalf("Marshal failed: %v", err)
+			}
+			got, err := UnmarshalDict(data)
+			if err != nil {
+				t.Fatalf("Unmarshal of the encoded plist failed: %v", err)
+			}
+
+			for key, want := range value {
+				switch {
+				case key == "Plain":
+					want = []interface{}{"typed", "slice"}
+				case key == "Archive" && format == XMLFormat:
+					// XML has no UID type
+					want = []interface{}{
+						map[string]interface{}{"CF$UID": int64(7)},
+						map[string]interface{}{"CF$UID": int64(300)},
+					}
+				}
+				if !reflect.DeepEqual(got[key], want) {
+					t.Errorf("%s = %#v, want %#v", key, got[key], want)
+				}
+			}
+		})
+	}
+}
+
+func TestMarshal_Fixture(t *testing.T) {
+	// The XML encoder writes byte for byte what plistlib writes
+	want, err := os.ReadFile("testdata/Info.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Marshal(wantInfo, XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("Marshal() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestMarshal_Invalid(t *testing.T) {
+	tests := map[string]interface{}{
+		"nil":         nil,
+		"nil in dict": map[string]interface{}{"a": nil},
+		"int keys":    map[int]string{1: "a"},
+		"struct":      struct{ A string }{"a"},
+	}
+	for name, value := range tests {
+		if _, err := Marshal(value, XMLFormat); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if _, err := Marshal("a", JSONFormat); err == nil {
+		t.Error("Expected an error for an unsupported format")
+	}
+}
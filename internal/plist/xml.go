@@ -0,0 +1,188 @@
+package plist
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type xmlDecoder struct {
+	decoder *xml.Decoder
+}
+
+func decodeXML(data []byte) (interface{}, error) {
+	d := &xmlDecoder{decoder: xml.NewDecoder(bytes.NewReader(data))}
+
+	for {
+		start, err := d.nextStart()
+		if err != nil {
+			return nil, err
+		}
+		if start == nil {
+			return nil, fmt.Errorf("invalid XML plist: no value")
+		}
+		// The <plist> wrapper is optional
+		if start.Name.Local != "plist" {
+			return d.value(*start)
+		}
+	}
+}
+
+// nextStart skips to the next start element, returning nil at an end
+// element or the end of the document
+func (d *xmlDecoder) nextStart() (*xml.StartElement, error) {
+	for {
+		token, err := d.decoder.Token()
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid XML plist: %w", err)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			return &t, nil
+		case xml.EndElement:
+			return nil, nil
+		}
+	}
+}
+
+func (d *xmlDecoder) value(start xml.StartElement) (interface{}, error) {
+	switch start.Name.Local {
+	case "dict":
+		dict := make(map[string]interface{})
+		for {
+			key, err := d.nextStart()
+			if err != nil {
+				return nil, err
+			}
+			if key == nil {
+				return dict, nil
+			}
+			if key.Name.Local != "key" {
+				return nil, fmt.Errorf("invalid XML plist: <%s> where a dictionary key was expected", key.Name.Local)
+			}
+			name, err := d.text()
+			if err != nil {
+				return nil, err
+			}
+
+			next, err := d.nextStart()
+			if err != nil {
+				return nil, err
+			}
+			if next == nil {
+				return nil, fmt.Errorf("invalid XML plist: key %q has no value", name)
+			}
+			if dict[name], err = d.value(*next); err != nil {
+				return nil, err
+			}
+		}
+
+	case "array":
+		array := []interface{}{}
+		for {
+			next, err := d.nextStart()
+			if err != nil {
+				return nil, err
+			}
+			if next == nil {
+				return array, nil
+			}
+			value, err := d.value(*next)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+
+	case "true", "false":
+		if err := d.decoder.Skip(); err != nil {
+			return nil, fmt.Errorf("invalid XML plist: %w", err)
+		}
+		return start.Name.Local == "true", nil
+	}
+
+	text, err := d.text()
+	if err != nil {
+		return nil, err
+	}
+
+	switch start.Name.Local {
+	case "string":
+		return text, nil
+
+	case "integer":
+		text = strings.TrimSpace(text)
+		if v, err := strconv.ParseInt(text, 0, 64); err == nil {
+			return v, nil
+		}
+		if v, err := strconv.ParseUint(text, 0, 64); err == nil {
+			return v, nil
+		}
+		return nil, fmt.Errorf("invalid XML plist: bad integer %q", text)
+
+	case "real":
+		text = strings.TrimSpace(text)
+		switch strings.ToLower(text) {
+		case "nan":
+			return math.NaN(), nil
+		case "inf", "+inf", "infinity", "+infinity":
+			return math.Inf(1), nil
+		case "-inf", "-infinity":
+			return math.Inf(-1), nil
+		}
+		v, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid XML plist: bad real %q", text)
+		}
+		return v, nil
+
+	case "date":
+		v, err := time.Parse(time.RFC3339, strings.TrimSpace(text))
+		if err != nil {
+			return nil, fmt.Errorf("invalid XML plist: bad date %q", text)
+		}
+		return v.UTC(), nil
+
+	case "data":
+		v, err := base64.StdEncoding.DecodeString(strings.Map(func(r rune) rune {
+			if r == ' ' || r == '\t' || r == '\r' || r == '\n' {
+				return -1
+			}
+			return r
+		}, text))
+		if err != nil {
+			return nil, fmt.Errorf("invalid XML plist: bad data: %w", err)
+		}
+		return v, nil
+	}
+
+	return nil, fmt.Errorf("invalid XML plist: unknown element <%s>", start.Name.Local)
+}
+
+// text reads the character data up to the end of the current element
+func (d *xmlDecoder) text() (string, error) {
+	var b strings.Builder
+	for {
+		token, err := d.decoder.Token()
+		if err != nil {
+			return "", fmt.Errorf("invalid XML plist: %w", err)
+		}
+		switch t := token.(type) {
+		case xml.CharData:
+			b.Write(t)
+		case xml.StartElement:
+			return "", fmt.Errorf("invalid XML plist: unexpected <%s> in text", t.Name.Local)
+		case xml.EndElement:
+			return b.String(), nil
+		}
+	}
+}
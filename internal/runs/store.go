@@ -0,0 +1,132 @@
+// Package runs keeps the raw artifacts of recent build and test runs so they
+// can be fetched as MCP resources after the tool call has returned.
+package runs
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jontolof/xcode-build-mcp/pkg/types"
+)
+
+// URIScheme prefixes every run resource URI, e.g. xcode://runs/3/log
+const URIScheme = "xcode://runs/"
+
+// DefaultRetention is the number of runs kept before the oldest is evicted
+const DefaultRetention = 20
+
+// Run is the unfiltered record of one xcodebuild invocation
+type Run struct {
+	ID           string
+	Tool         string
+	Command      string
+	StartedAt    time.Time
+	Duration     time.Duration
+	ExitCode     int
+	Output       string
+	ResultBundle string
+	CrashReports []types.CrashReport
+
+	// DeveloperDir is the Xcode the run used, empty for the default one.
+	// Its result bundle is read with the same Xcode's xcresulttool.
+	DeveloperDir string
+
+	// Parsed results, set by the tool that produced the run
+	BuildResult *types.BuildResult
+	TestResult  *types.TestResult
+}
+
+// LogURI addresses the raw output of the run
+func (r *Run) LogURI() string {
+	return URIScheme + r.ID + "/log"
+}
+
+// ResultBundleURI addresses the retained .xcresult bundle of the run
+func (r *Run) ResultBundleURI() string {
+	return URIScheme + r.ID + "/xcresult"
+}
+
+// CrashReportURI addresses the crash report at index i
+func (r *Run) CrashReportURI(i int) string {
+	return fmt.Sprintf("%s%s/crashes/%d", URIScheme, r.ID, i)
+}
+
+// Store holds the most recent runs. It owns retained result bundles and
+// deletes them from disk when their run is evicted.
+type Store struct {
+	mu        sync.RWMutex
+	runs      []*Run
+	byID      map[string]*Run
+	retention int
+	seq       int
+}
+
+func NewStore(retention int) *Store {
+	if retention < 1 {
+		retention = DefaultRetention
+	}
+	return &Store{
+		byID:      make(map[string]*Run),
+		retention: retention,
+	}
+}
+
+// Add assigns the run an ID and stores it, evicting the oldest run when the
+// store is full
+func (s *Store) Add(run *Run) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq++
+	run.ID = fmt.Sprintf("%d", s.seq)
+	s.runs = append(s.runs, run)
+	s.byID[run.ID] = run
+
+	for len(s.runs) > s.retention {
+		evicted := s.runs[0]
+		s.runs = s.runs[1:]
+		delete(s.byID, evicted.ID)
+		if evicted.ResultBundle != "" {
+			os.RemoveAll(evicted.ResultBundle)
+		}
+	}
+
+	return run.ID
+}
+
+// Get returns the run with the given ID, or nil if it was never recorded or
+// has been evicted
+func (s *Store) Get(id string) *Run {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.byID[id]
+}
+
+// List returns the stored runs, newest first
+func (s *Store) List() []*Run {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	runs := make([]*Run, 0, len(s.runs))
+	for i := len(s.runs) - 1; i >= 0; i-- {
+		runs = append(runs, s.runs[i])
+	}
+	return runs
+}
+
+// Close deletes every retained result bundle and empties the store
+func (s *Store) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, run := range s.runs {
+		if run.ResultBundle != "" {
+			os.RemoveAll(run.ResultBundle)
+		}
+	}
+	s.runs = nil
+	s.byID = make(map[string]*Run)
+}
@@ -0,0 +1,84 @@
+package runs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStore_AddGetList(t *testing.T) {
+	store := NewStore(5)
+
+	first := store.Add(&Run{Tool: "xcode_build", Output: "one"})
+	second := store.Add(&Run{Tool: "xcode_test", Output: "two"})
+
+	if first == second {
+		t.Fatal("Runs should get distinct IDs")
+	}
+
+	run := store.Get(first)
+	if run == nil || run.Output != "one" {
+		t.Fatalf("Get(%s) = %+v, want run with output 'one'", first, run)
+	}
+
+	list := store.List()
+	if len(list) != 2 || list[0].ID != second {
+		t.Errorf("List should return newest first, got %+v", list)
+	}
+
+	if store.Get("missing") != nil {
+		t.Error("Get should return nil for unknown IDs")
+	}
+}
+
+func TestStore_EvictionRemovesResultBundle(t *testing.T) {
+	store := NewStore(1)
+
+	bundle := filepath.Join(t.TempDir(), "run.xcresult")
+	if err := os.Mkdir(bundle, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	oldID := store.Add(&Run{Tool: "xcode_test", ResultBundle: bundle})
+	store.Add(&Run{Tool: "xcode_build"})
+
+	if store.Get(oldID) != nil {
+		t.Error("Oldest run should have been evicted")
+	}
+	if _, err := os.Stat(bundle); !os.IsNotExist(err) {
+		t.Error("Evicted run's result bundle should be deleted")
+	}
+}
+
+func TestStore_Close(t *testing.T) {
+	store := NewStore(5)
+
+	bundle := filepath.Join(t.TempDir(), "run.xcresult")
+	if err := os.Mkdir(bundle, 0755); err != nil {
+		t.Fatal(err)
+	}
+	store.Add(&Run{Tool: "xcode_test", ResultBundle: bundle})
+
+	store.Close()
+
+	if len(store.List()) != 0 {
+		t.Error("Close should empty the store")
+	}
+	if _, err := os.Stat(bundle); !os.IsNotExist(err) {
+		t.Error("Close should delete retained result bundles")
+	}
+}
+
+func TestRun_URIs(t *testing.T) {
+	run := &Run{ID: "7"}
+
+	if got := run.LogURI(); got != "xcode://runs/7/log" {
+		t.Errorf("LogURI() = %s", got)
+	}
+	if got := run.ResultBundleURI(); got != "xcode://runs/7/xcresult" {
+		t.Errorf("ResultBundleURI() = %s", got)
+	}
+	if got := run.CrashReportURI(2); got != "xcode://runs/7/crashes/2" {
+		t.Errorf("CrashReportURI() = %s", got)
+	}
+}
@@ -0,0 +1,688 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jontolof/xcode-build-mcp/internal/common"
+	"github.com/jontolof/xcode-build-mcp/internal/filter"
+	"github.com/jontolof/xcode-build-mcp/internal/plist"
+	"github.com/jontolof/xcode-build-mcp/internal/runs"
+	"github.com/jontolof/xcode-build-mcp/internal/xcode"
+	"github.com/jontolof/xcode-build-mcp/pkg/types"
+)
+
+const (
+	defaultArchiveConfiguration = "Release"
+	defaultArchiveDestination   = "generic/platform=iOS"
+	defaultExportMethod         = "app-store"
+)
+
+// exportMethods are the methods xcodebuild -exportArchive accepts. Xcode 15.3
+// renamed app-store, ad-hoc and development to app-store-connect,
+// release-testing and debugging and still accepts the old names.
+var exportMethods = []string{
+	"app-store", "app-store-connect", "ad-hoc", "release-testing", "enterprise",
+	"development", "debugging", "developer-id", "mac-application", "validation",
+}
+
+// exportOptionKeys are the arguments that go into a generated
+// ExportOptions.plist
+var exportOptionKeys = []string{
+	"method", "team_id", "signing_style", "signing_certificate",
+	"provisioning_profiles", "destination", "upload_symbols",
+}
+
+type XcodeArchiveTool struct {
+	name         string
+	description  string
+	schema       map[string]interface{}
+	outputSchema map[string]interface{}
+	executor     *xcode.Executor
+	parser       *xcode.Parser
+	runs         *runs.Store
+	logger       common.Logger
+}
+
+func NewXcodeArchiveTool(executor *xcode.Executor, parser *xcode.Parser, store *runs.Store, logger common.Logger) *XcodeArchiveTool {
+	schema := createJSONSchema("object", map[string]interface{}{
+		"project_path": map[string]interface{}{
+			"type":        "string",
+			"description": "Path to the directory containing the Xcode project or workspace",
+		},
+		"workspace": map[string]interface{}{
+			"type":        "string",
+			"description": "Name of the .xcworkspace file (relative to project_path)",
+		},
+		"project": map[string]interface{}{
+			"type":        "string",
+			"description": "Name of the .xcodeproj file (relative to project_path)",
+		},
+		"scheme": map[string]interface{}{
+			"type":        "string",
+			"description": "Scheme to archive",
+		},
+		"configuration": map[string]interface{}{
+			"type":        "string",
+			"description": "Build configuration",
+			"default":     defaultArchiveConfiguration,
+		},
+		"destination": map[string]interface{}{
+			"type":        "string",
+			"description": "Archive destination (generic/platform=macOS, etc.)",
+			"default":     defaultArchiveDestination,
+		},
+		"archive_path": map[string]interface{}{
+			"type":        "string",
+			"description": "Where to write the .xcarchive - default: build/<scheme>.xcarchive under project_path",
+		},
+		"derived_data": map[string]interface{}{
+			"type":        "string",
+			"description": "Path for derived data",
+		},
+		"allow_provisioning_updates": map[string]interface{}{
+			"type":        "boolean",
+			"description": "Let xcodebuild create and update signing certificates and profiles",
+			"default":     false,
+		},
+		"output_mode": map[string]interface{}{
+			"type":        "string",
+			"enum":        []string{"minimal", "standard", "verbose"},
+			"description": "Output filtering level",
+			"default":     "standard",
+		},
+		"developer_dir": map[string]interface{}{
+			"type":        "string",
+			"description": "Xcode to archive with: an Xcode.app bundle or its Contents/Developer directory, set as DEVELOPER_DIR",
+		},
+		"xcode_version": xcodeVersionSchema(),
+		"extra_args": map[string]interface{}{
+			"type":        "array",
+			"items":       map[string]string{"type": "string"},
+			"description": "Additional xcodebuild arguments",
+		},
+	}, []string{"scheme"})
+
+	return &XcodeArchiveTool{
+		name:         "xcode_archive",
+		description:  "Archive a scheme with xcodebuild archive for distribution. Returns the .xcarchive, .app and .dSYM paths with the bundle ID, version and build number read from the archive; pass the archive to export_archive to produce an .ipa.",
+		schema:       schema,
+		outputSchema: types.SchemaFor(types.ArchiveResult{}),
+		executor:     executor,
+		parser:       parser,
+		runs:         store,
+		logger:       logger,
+	}
+}
+
+func (t *XcodeArchiveTool) Name() string {
+	return t.name
+}
+
+func (t *XcodeArchiveTool) Description() string {
+	return t.description
+}
+
+func (t *XcodeArchiveTool) InputSchema() map[string]interface{} {
+	return t.schema
+}
+
+func (t *XcodeArchiveTool) OutputSchema() map[string]interface{} {
+	return t.outputSchema
+}
+
+func (t *XcodeArchiveTool) Execute(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error) {
+	params, err := t.parseParams(args)
+	if err != nil {
+		return nil, err
+	}
+
+	env, err := xcodeEnv(ctx, t.executor, &params.DeveloperDir, params.XcodeVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	cmdArgs, err := t.executor.BuildXcodeArgs(params)
+	if err != nil {
+		return nil, toolError(err, types.ErrCodeInternal, "failed to build command arguments", nil)
+	}
+
+	run, err := runArchiveCommand(ctx, t.executor, t.parser, t.runs, t.name, cmdArgs, env, params.OutputMode)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &types.ArchiveResult{
+		Success:        run.result.Success(),
+		Duration:       run.result.Duration,
+		FilteredOutput: run.build.FilteredOutput,
+		Errors:         run.build.Errors,
+		Warnings:       run.build.Warnings,
+		ExitCode:       run.result.ExitCode,
+		ArchivePath:    params.ArchivePath,
+		LogURI:         run.logURI,
+	}
+
+	// A failed run may leave an older archive at archive_path; only a
+	// successful one is described
+	if result.Success {
+		if info, err := readArchiveInfo(params.ArchivePath); err == nil {
+			result.AppPath = info.AppPath
+			result.DSYMPaths = info.DSYMPaths
+			result.BundleID = info.BundleID
+			result.Version = info.Version
+			result.BuildNumber = info.BuildNumber
+			result.TeamID = info.TeamID
+		} else {
+			t.logger.Printf("Archive succeeded but its Info.plist could not be read: %v", err)
+		}
+	}
+
+	toolResult, err := types.NewToolResult(result, !result.Success)
+	if err != nil {
+		return nil, err
+	}
+	if !result.Success {
+		if err := xcodebuildSetupError(run.result.Output); err != nil {
+			return toolResult, err
+		}
+	}
+	return toolResult, nil
+}
+
+func (t *XcodeArchiveTool) parseParams(args map[string]interface{}) (*types.ArchiveParams, error) {
+	params := &types.ArchiveParams{
+		Configuration: defaultArchiveConfiguration,
+		Destination:   defaultArchiveDestination,
+		OutputMode:    "standard",
+	}
+
+	var err error
+	if params.Scheme, err = parseStringParam(args, "scheme", true); err != nil {
+		return nil, err
+	}
+	if params.ProjectPath, err = parseStringParam(args, "project_path", false); err != nil {
+		return nil, err
+	}
+	if params.Workspace, err = parseStringParam(args, "workspace", false); err != nil {
+		return nil, err
+	}
+	if params.Project, err = parseStringParam(args, "project", false); err != nil {
+		return nil, err
+	}
+	if params.ArchivePath, err = parseStringParam(args, "archive_path", false); err != nil {
+		return nil, err
+	}
+	if params.DerivedData, err = parseStringParam(args, "derived_data", false); err != nil {
+		return nil, err
+	}
+	if params.DeveloperDir, err = parseStringParam(args, "developer_dir", false); err != nil {
+		return nil, err
+	}
+	if params.XcodeVersion, err = parseStringParam(args, "xcode_version", false); err != nil {
+		return nil, err
+	}
+
+	for key, target := range map[string]*string{
+		"configuration": &params.Configuration,
+		"destination":   &params.Destination,
+		"output_mode":   &params.OutputMode,
+	} {
+		value, err := parseStringParam(args, key, false)
+		if err != nil {
+			return nil, err
+		}
+		if value != "" {
+			*target = value
+		}
+	}
+
+	params.AllowProvisioningUpdates = parseBoolParam(args, "allow_provisioning_updates", false)
+
+	if params.ExtraArgs, err = parseStringArrayParam(args, "extra_args"); err != nil {
+		return nil, err
+	}
+
+	if params.Workspace == "" && params.Project == "" {
+		return nil, invalidParams("either workspace or project must be specified",
+			map[string]interface{}{"parameters": []string{"workspace", "project"}})
+	}
+
+	// xcodebuild adds the extension when it is missing, so add it here too
+	// to know where the archive ends up
+	if params.ArchivePath == "" {
+		dir := params.ProjectPath
+		if dir == "" && params.Workspace != "" {
+			dir = filepath.Dir(params.Workspace)
+		} else if dir == "" {
+			dir = filepath.Dir(params.Project)
+		}
+		params.ArchivePath = filepath.Join(dir, "build", params.Scheme+".xcarchive")
+	} else if !strings.HasSuffix(params.ArchivePath, ".xcarchive") {
+		params.ArchivePath += ".xcarchive"
+	}
+
+	return params, nil
+}
+
+type ExportArchiveTool struct {
+	name         string
+	description  string
+	schema       map[string]interface{}
+	outputSchema map[string]interface{}
+	executor     *xcode.Executor
+	parser       *xcode.Parser
+	runs         *runs.Store
+	logger       common.Logger
+}
+
+func NewExportArchiveTool(executor *xcode.Executor, parser *xcode.Parser, store *runs.Store, logger common.Logger) *ExportArchiveTool {
+	schema := createJSONSchema("object", map[string]interface{}{
+		"archive_path": map[string]interface{}{
+			"type":        "string",
+			"description": "Path of the .xcarchive to export, as returned by xcode_archive",
+		},
+		"export_path": map[string]interface{}{
+			"type":        "string",
+			"description": "Directory for the exported files - default: <archive name>-export next to the archive",
+		},
+		"export_options_plist": map[string]interface{}{
+			"type":        "string",
+			"description": "Existing ExportOptions.plist to use instead of generating one from the options below",
+		},
+		"method": map[string]interface{}{
+			"type":        "string",
+			"enum":        exportMethods,
+			"description": "Distribution method; app-store builds can be uploaded to TestFlight",
+			"default":     defaultExportMethod,
+		},
+		"team_id": map[string]interface{}{
+			"type":        "string",
+			"description": "Developer team ID used for signing",
+		},
+		"signing_style": map[string]interface{}{
+			"type":        "string",
+			"enum":        []string{"automatic", "manual"},
+			"description": "Signing style; manual signing needs provisioning_profiles",
+		},
+		"signing_certificate": map[string]interface{}{
+			"type":        "string",
+			"description": "Certificate name, SHA-1 hash or automatic selector such as \"Apple Distribution\"",
+		},
+		"provisioning_profiles": map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": map[string]string{"type": "string"},
+			"description":          "Provisioning profile name or UUID for each bundle ID, for manual signing",
+		},
+		"destination": map[string]interface{}{
+			"type":        "string",
+			"enum":        []string{"export", "upload"},
+			"description": "Write the export locally or upload it to App Store Connect",
+		},
+		"upload_symbols": map[string]interface{}{
+			"type":        "boolean",
+			"description": "Include symbols for App Store Connect crash reports",
+		},
+		"allow_provisioning_updates": map[string]interface{}{
+			"type":        "boolean",
+			"description": "Let xcodebuild create and update signing certificates and profiles",
+			"default":     false,
+		},
+		"output_mode": map[string]interface{}{
+			"type":        "string",
+			"enum":        []string{"minimal", "standard", "verbose"},
+			"description": "Output filtering level",
+			"default":     "standard",
+		},
+		"developer_dir": map[string]interface{}{
+			"type":        "string",
+			"description": "Xcode to export with: an Xcode.app bundle or its Contents/Developer directory, set as DEVELOPER_DIR",
+		},
+		"xcode_version": xcodeVersionSchema(),
+		"extra_args": map[string]interface{}{
+			"type":        "array",
+			"items":       map[string]string{"type": "string"},
+			"description": "Additional xcodebuild arguments",
+		},
+	}, []string{"archive_path"})
+
+	return &ExportArchiveTool{
+		name:         "export_archive",
+		description:  "Export an .xcarchive with xcodebuild -exportArchive, using an existing ExportOptions.plist or one generated from method, team, signing style and provisioning profiles. Returns the .ipa and .dSYM paths with the version and build number, ready for TestFlight or ad hoc distribution.",
+		schema:       schema,
+		outputSchema: types.SchemaFor(types.ExportResult{}),
+		executor:     executor,
+		parser:       parser,
+		runs:         store,
+		logger:       logger,
+	}
+}
+
+func (t *ExportArchiveTool) Name() string {
+	return t.name
+}
+
+func (t *ExportArchiveTool) Description() string {
+	return t.description
+}
+
+func (t *ExportArchiveTool) InputSchema() map[string]interface{} {
+	return t.schema
+}
+
+func (t *ExportArchiveTool) OutputSchema() map[string]interface{} {
+	return t.outputSchema
+}
+
+func (t *ExportArchiveTool) Execute(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error) {
+	params, err := t.parseParams(args)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := readArchiveInfo(params.ArchivePath)
+	if err != nil {
+		return nil, invalidParams(fmt.Sprintf("%s is not a readable archive: %v", params.ArchivePath, err),
+			map[string]interface{}{"parameter": "archive_path"})
+	}
+
+	env, err := xcodeEnv(ctx, t.executor, &params.DeveloperDir, params.XcodeVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(params.ExportPath, 0755); err != nil {
+		return nil, toolError(err, types.ErrCodeInternal, "failed to create export directory",
+			map[string]interface{}{"path": params.ExportPath})
+	}
+
+	if params.ExportOptionsPlist == "" {
+		// xcodebuild writes its own ExportOptions.plist here after the
+		// export, with the same options
+		params.ExportOptionsPlist = filepath.Join(params.ExportPath, "ExportOptions.plist")
+		data, err := plist.Marshal(exportOptions(params), plist.XMLFormat)
+		if err != nil {
+			return nil, toolError(err, types.ErrCodeInternal, "failed to encode export options", nil)
+		}
+		if err := os.WriteFile(params.ExportOptionsPlist, data, 0644); err != nil {
+			return nil, toolError(err, types.ErrCodeInternal, "failed to write export options",
+				map[string]interface{}{"path": params.ExportOptionsPlist})
+		}
+	} else if options, err := readInfoPlist(params.ExportOptionsPlist); err == nil {
+		params.Method, _ = options["method"].(string)
+	}
+
+	cmdArgs, err := t.executor.BuildXcodeArgs(params)
+	if err != nil {
+		return nil, toolError(err, types.ErrCodeInternal, "failed to build command arguments", nil)
+	}
+
+	start := time.Now()
+	run, err := runArchiveCommand(ctx, t.executor, t.parser, t.runs, t.name, cmdArgs, env, params.OutputMode)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &types.ExportResult{
+		Success:           run.result.Success(),
+		Duration:          run.result.Duration,
+		FilteredOutput:    run.build.FilteredOutput,
+		Errors:            run.build.Errors,
+		ExitCode:          run.result.ExitCode,
+		ArchivePath:       params.ArchivePath,
+		ExportPath:        params.ExportPath,
+		ExportOptionsPath: params.ExportOptionsPlist,
+		Method:            params.Method,
+		DSYMPaths:         info.DSYMPaths,
+		BundleID:          info.BundleID,
+		Version:           info.Version,
+		BuildNumber:       info.BuildNumber,
+		LogURI:            run.logURI,
+	}
+
+	if result.Success {
+		result.ExportedPaths = exportedPaths(params.ExportPath, start)
+	}
+	for _, path := range result.ExportedPaths {
+		if strings.HasSuffix(path, ".ipa") {
+			result.IPAPath = path
+			break
+		}
+	}
+
+	return types.NewToolResult(result, !result.Success)
+}
+
+func (t *ExportArchiveTool) parseParams(args map[string]interface{}) (*types.ExportParams, error) {
+	params := &types.ExportParams{OutputMode: "standard"}
+
+	var err error
+	if params.ArchivePath, err = parseStringParam(args, "archive_path", true); err != nil {
+		return nil, err
+	}
+	for key, target := range map[string]*string{
+		"export_path":          &params.ExportPath,
+		"export_options_plist": &params.ExportOptionsPlist,
+		"method":               &params.Method,
+		"team_id":              &params.TeamID,
+		"signing_style":        &params.SigningStyle,
+		"signing_certificate":  &params.SigningCertificate,
+		"destination":          &params.Destination,
+		"developer_dir":        &params.DeveloperDir,
+		"xcode_version":        &params.XcodeVersion,
+	} {
+		if *target, err = parseStringParam(args, key, false); err != nil {
+			return nil, err
+		}
+	}
+	if outputMode, err := parseStringParam(args, "output_mode", false); err != nil {
+		return nil, err
+	} else if outputMode != "" {
+		params.OutputMode = outputMode
+	}
+
+	if value, exists := args["upload_symbols"]; exists {
+		uploadSymbols, ok := value.(bool)
+		if !ok {
+			return nil, invalidParams("parameter upload_symbols must be a boolean",
+				map[string]interface{}{"parameter": "upload_symbols"})
+		}
+		params.UploadSymbols = &uploadSymbols
+	}
+	params.AllowProvisioningUpdates = parseBoolParam(args, "allow_provisioning_updates", false)
+
+	if value, exists := args["provisioning_profiles"]; exists {
+		profiles, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, invalidParams("parameter provisioning_profiles must be an object",
+				map[string]interface{}{"parameter": "provisioning_profiles"})
+		}
+		params.ProvisioningProfiles = make(map[string]string, len(profiles))
+		for bundleID, profile := range profiles {
+			name, ok := profile.(string)
+			if !ok {
+				return nil, invalidParams(fmt.Sprintf("provisioning profile for %s must be a string", bundleID),
+					map[string]interface{}{"parameter": "provisioning_profiles"})
+			}
+			params.ProvisioningProfiles[bundleID] = name
+		}
+	}
+
+	if params.ExtraArgs, err = parseStringArrayParam(args, "extra_args"); err != nil {
+		return nil, err
+	}
+
+	if params.ExportOptionsPlist != "" {
+		for _, key := range exportOptionKeys {
+			if _, exists := args[key]; exists {
+				return nil, invalidParams(fmt.Sprintf("%s cannot be combined with export_options_plist", key),
+					map[string]interface{}{"parameters": []string{key, "export_options_plist"}})
+			}
+		}
+		if _, err := os.Stat(params.ExportOptionsPlist); err != nil {
+			return nil, invalidParams(fmt.Sprintf("export options plist not found: %s", params.ExportOptionsPlist),
+				map[string]interface{}{"parameter": "export_options_plist"})
+		}
+	}
+
+	if params.Method == "" && params.ExportOptionsPlist == "" {
+		params.Method = defaultExportMethod
+	}
+	if params.Method != "" && !slices.Contains(exportMethods, params.Method) {
+		return nil, invalidParams(fmt.Sprintf("unknown export method %q", params.Method),
+			map[string]interface{}{"parameter": "method", "allowed": exportMethods})
+	}
+	if params.SigningStyle != "" && params.SigningStyle != "automatic" && params.SigningStyle != "manual" {
+		return nil, invalidParams("signing_style must be automatic or manual",
+			map[string]interface{}{"parameter": "signing_style"})
+	}
+	if params.Destination != "" && params.Destination != "export" && params.Destination != "upload" {
+		return nil, invalidParams("destination must be export or upload",
+			map[string]interface{}{"parameter": "destination"})
+	}
+
+	if !strings.HasSuffix(params.ArchivePath, ".xcarchive") {
+		return nil, invalidParams("archive_path must be an .xcarchive",
+			map[string]interface{}{"parameter": "archive_path"})
+	}
+	if params.ExportPath == "" {
+		name := strings.TrimSuffix(filepath.Base(params.ArchivePath), ".xcarchive")
+		params.ExportPath = filepath.Join(filepath.Dir(params.ArchivePath), name+"-export")
+	}
+
+	return params, nil
+}
+
+// exportOptions builds the ExportOptions.plist dictionary for params
+func exportOptions(params *types.ExportParams) map[string]interface{} {
+	options := map[string]interface{}{"method": params.Method}
+	if params.TeamID != "" {
+		options["teamID"] = params.TeamID
+	}
+	if params.SigningStyle != "" {
+		options["signingStyle"] = params.SigningStyle
+	}
+	if params.SigningCertificate != "" {
+		options["signingCertificate"] = params.SigningCertificate
+	}
+	if len(params.ProvisioningProfiles) > 0 {
+		options["provisioningProfiles"] = params.ProvisioningProfiles
+	}
+	if params.Destination != "" {
+		options["destination"] = params.Destination
+	}
+	if params.UploadSymbols != nil {
+		options["uploadSymbols"] = *params.UploadSymbols
+	}
+	return options
+}
+
+// archiveInfo is what an .xcarchive records about the app it holds
+type archiveInfo struct {
+	AppPath     string
+	DSYMPaths   []string
+	BundleID    string
+	Version     string
+	BuildNumber string
+	TeamID      string
+}
+
+// readArchiveInfo reads the Info.plist of an .xcarchive and lists its dSYMs
+func readArchiveInfo(archivePath string) (*archiveInfo, error) {
+	root, err := readInfoPlist(filepath.Join(archivePath, "Info.plist"))
+	if err != nil {
+		return nil, err
+	}
+
+	info := &archiveInfo{}
+	properties, _ := root["ApplicationProperties"].(map[string]interface{})
+	if appPath, ok := properties["ApplicationPath"].(string); ok {
+		info.AppPath = filepath.Join(archivePath, "Products", filepath.FromSlash(appPath))
+	}
+	info.BundleID, _ = properties["CFBundleIdentifier"].(string)
+	info.Version, _ = properties["CFBundleShortVersionString"].(string)
+	info.BuildNumber, _ = properties["CFBundleVersion"].(string)
+	info.TeamID, _ = properties["Team"].(string)
+
+	info.DSYMPaths, _ = filepath.Glob(filepath.Join(archivePath, "dSYMs", "*.dSYM"))
+	return info, nil
+}
+
+// exportedPaths lists the products xcodebuild wrote to exportPath since
+// start, leaving out those of earlier exports to the same directory.
+// Modification times are compared to the second, as some file systems
+// store no more.
+func exportedPaths(exportPath string, start time.Time) []string {
+	var paths []string
+	for _, pattern := range []string{"*.ipa", "*.pkg", "*.app"} {
+		matches, _ := filepath.Glob(filepath.Join(exportPath, pattern))
+		for _, path := range matches {
+			if info, err := os.Stat(path); err == nil && !info.ModTime().Before(start.Truncate(time.Second)) {
+				paths = append(paths, path)
+			}
+		}
+	}
+	return paths
+}
+
+// archiveRun is an xcodebuild archive or export run with its parsed output
+type archiveRun struct {
+	result *xcode.CommandResult
+	build  *types.BuildResult
+	logURI string
+}
+
+// xcodeEnv selects the Xcode of a call's developer_dir or xcode_version,
+// replacing *developerDir with its DEVELOPER_DIR so xcodebuild runs from
+// it, and returns the DEVELOPER_DIR entry, if any
+func xcodeEnv(ctx context.Context, executor *xcode.Executor, developerDir *string, version string) ([]string, error) {
+	dir, err := selectXcode(ctx, executor, *developerDir, version)
+	if err != nil {
+		return nil, err
+	}
+	*developerDir = dir
+	env, _, err := commandEnv(executor, nil, "", dir)
+	return env, err
+}
+
+// runArchiveCommand runs cmdArgs with env, filters and parses the output the way
+// xcode_build does, and records the run so its log can be fetched
+func runArchiveCommand(ctx context.Context, executor *xcode.Executor, parser *xcode.Parser, store *runs.Store,
+	tool string, cmdArgs, env []string, outputMode string) (*archiveRun, error) {
+	start := time.Now()
+	opts := append(progressOptions(ctx), xcode.WithEnv(env...))
+	result, err := executor.ExecuteCommand(ctx, cmdArgs, opts...)
+	if err != nil {
+		return nil, toolError(err, types.ErrCodeCommandFailed, "failed to execute "+tool+" command",
+			map[string]interface{}{"command": strings.Join(cmdArgs, " ")})
+	}
+	duration := time.Since(start)
+	result.Duration = duration
+
+	build := parser.ParseBuildOutput(result.Output)
+	build.Duration = duration
+	build.ExitCode = result.ExitCode
+	build.Success = result.Success()
+	build.FilteredOutput = filter.NewFilter(filter.OutputMode(outputMode)).Filter(result.Output)
+
+	run := &archiveRun{result: result, build: build}
+	if store != nil {
+		record := &runs.Run{
+			Tool:        tool,
+			Command:     strings.Join(cmdArgs, " "),
+			StartedAt:   start,
+			Duration:    duration,
+			ExitCode:    result.ExitCode,
+			Output:      result.Output,
+			BuildResult: build,
+		}
+		store.Add(record)
+		run.logURI = record.LogURI()
+	}
+	return run, nil
+}
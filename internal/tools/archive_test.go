@@ -0,0 +1,257 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jontolof/xcode-build-mcp/internal/plist"
+	"github.com/jontolof/xcode-build-mcp/internal/xcode"
+	"github.com/jontolof/xcode-build-mcp/pkg/types"
+)
+
+// writeArchive lays out a minimal App.xcarchive in dir and returns its path
+func writeArchive(t *testing.T, dir string) string {
+	t.Helper()
+
+	archivePath := filepath.Join(dir, "App.xcarchive")
+	for _, sub := range []string{"Products/Applications/App.app", "dSYMs/App.app.dSYM"} {
+		if err := os.MkdirAll(filepath.Join(archivePath, sub), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := plist.Marshal(map[string]interface{}{
+		"ArchiveVersion": 2,
+		"Name":           "App",
+		"SchemeName":     "App",
+		"ApplicationProperties": map[string]interface{}{
+			"ApplicationPath":            "Applications/App.app",
+			"CFBundleIdentifier":         "com.example.App",
+			"CFBundleShortVersionString": "2.1.0",
+			"CFBundleVersion":            "317",
+			"Team":                       "ABCDE12345",
+		},
+	}, plist.XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(archivePath, "Info.plist"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return archivePath
+}
+
+func TestXcodeArchiveTool_ParseParams(t *testing.T) {
+	tool := NewXcodeArchiveTool(xcode.NewExecutor(&testLogger{}), xcode.NewParser(), nil, &testLogger{})
+
+	params, err := tool.parseParams(map[string]interface{}{
+		"project_path": "/src/App",
+		"workspace":    "App.xcworkspace",
+		"scheme":       "App",
+	})
+	if err != nil {
+		t.Fatalf("parseParams failed: %v", err)
+	}
+	if params.ArchivePath != "/src/App/build/App.xcarchive" {
+		t.Errorf("ArchivePath = %q", params.ArchivePath)
+	}
+	if params.Configuration != "Release" || params.Destination != "generic/platform=iOS" {
+		t.Errorf("Unexpected defaults: %+v", params)
+	}
+
+	params, err = tool.parseParams(map[string]interface{}{
+		"project":       "App.xcodeproj",
+		"scheme":        "App",
+		"archive_path":  "out/Nightly",
+		"configuration": "Beta",
+	})
+	if err != nil {
+		t.Fatalf("parseParams failed: %v", err)
+	}
+	if params.ArchivePath != "out/Nightly.xcarchive" || params.Configuration != "Beta" {
+		t.Errorf("Unexpected params: %+v", params)
+	}
+
+	for name, args := range map[string]map[string]interface{}{
+		"no scheme":  {"project": "App.xcodeproj"},
+		"no project": {"scheme": "App"},
+	} {
+		_, err := tool.parseParams(args)
+		if xerr := types.ExtractXcodeError(err); xerr == nil || xerr.Code != types.ErrCodeInvalidParams {
+			t.Errorf("%s: expected INVALID_PARAMS, got %v", name, err)
+		}
+	}
+}
+
+func TestExportArchiveTool_ParseParams(t *testing.T) {
+	tool := NewExportArchiveTool(xcode.NewExecutor(&testLogger{}), xcode.NewParser(), nil, &testLogger{})
+
+	params, err := tool.parseParams(map[string]interface{}{"archive_path": "/out/App.xcarchive"})
+	if err != nil {
+		t.Fatalf("parseParams failed: %v", err)
+	}
+	if params.Method != "app-store" || params.ExportPath != "/out/App-export" {
+		t.Errorf("Unexpected defaults: %+v", params)
+	}
+
+	optionsPath := filepath.Join(t.TempDir(), "ExportOptions.plist")
+	if err := os.WriteFile(optionsPath, []byte("<plist><dict/></plist>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	invalid := map[string]map[string]interface{}{
+		"no archive":        {},
+		"not an archive":    {"archive_path": "/out/App.app"},
+		"unknown method":    {"archive_path": "/out/App.xcarchive", "method": "store"},
+		"bad signing style": {"archive_path": "/out/App.xcarchive", "signing_style": "auto"},
+		"bad profiles":      {"archive_path": "/out/App.xcarchive", "provisioning_profiles": map[string]interface{}{"com.example.App": 1}},
+		"missing options":   {"archive_path": "/out/App.xcarchive", "export_options_plist": "/nonexistent/ExportOptions.plist"},
+		"options and method": {
+			"archive_path":         "/out/App.xcarchive",
+			"export_options_plist": optionsPath,
+			"method":               "ad-hoc",
+		},
+	}
+	for name, args := range invalid {
+		_, err := tool.parseParams(args)
+		if xerr := types.ExtractXcodeError(err); xerr == nil || xerr.Code != types.ErrCodeInvalidParams {
+			t.Errorf("%s: expected INVALID_PARAMS, got %v", name, err)
+		}
+	}
+}
+
+func TestExportOptions(t *testing.T) {
+	uploadSymbols := false
+	params := &types.ExportParams{
+		Method:               "ad-hoc",
+		TeamID:               "ABCDE12345",
+		SigningStyle:         "manual",
+		ProvisioningProfiles: map[string]string{"com.example.App": "App Ad Hoc"},
+		UploadSymbols:        &uploadSymbols,
+	}
+
+	data, err := plist.Marshal(exportOptions(params), plist.XMLFormat)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	got, err := plist.UnmarshalDict(data)
+	if err != nil {
+		t.Fatalf("UnmarshalDict failed: %v", err)
+	}
+
+	want := map[string]interface{}{
+		"method":               "ad-hoc",
+		"teamID":               "ABCDE12345",
+		"signingStyle":         "manual",
+		"provisioningProfiles": map[string]interface{}{"com.example.App": "App Ad Hoc"},
+		"uploadSymbols":        false,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("exportOptions() = %v, want %v", got, want)
+	}
+}
+
+func TestReadArchiveInfo(t *testing.T) {
+	dir := t.TempDir()
+	archivePath := writeArchive(t, dir)
+
+	info, err := readArchiveInfo(archivePath)
+	if err != nil {
+		t.Fatalf("readArchiveInfo failed: %v", err)
+	}
+
+	want := &archiveInfo{
+		AppPath:     filepath.Join(archivePath, "Products", "Applications", "App.app"),
+		DSYMPaths:   []string{filepath.Join(archivePath, "dSYMs", "App.app.dSYM")},
+		BundleID:    "com.example.App",
+		Version:     "2.1.0",
+		BuildNumber: "317",
+		TeamID:      "ABCDE12345",
+	}
+	if !reflect.DeepEqual(info, want) {
+		t.Errorf("readArchiveInfo() = %+v, want %+v", info, want)
+	}
+
+	if _, err := readArchiveInfo(dir); err == nil {
+		t.Error("Expected an error for a directory without Info.plist")
+	}
+}
+
+func TestExportArchiveTool_Execute_NotAnArchive(t *testing.T) {
+	tool := NewExportArchiveTool(xcode.NewExecutor(&testLogger{}), xcode.NewParser(), nil, &testLogger{})
+
+	archivePath := filepath.Join(t.TempDir(), "Missing.xcarchive")
+	_, err := tool.Execute(context.Background(), map[string]interface{}{"archive_path": archivePath})
+	if xerr := types.ExtractXcodeError(err); xerr == nil || xerr.Code != types.ErrCodeInvalidParams {
+		t.Errorf("Expected INVALID_PARAMS, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(archivePath), "Missing-export")); !os.IsNotExist(err) {
+		t.Error("Export directory should not be created for a missing archive")
+	}
+}
+
+func TestXcodeArchiveTool_Execute_FailedKeepsNoStaleArchive(t *testing.T) {
+	archivePath := writeArchive(t, t.TempDir())
+	replay := xcode.NewReplayRunner([]xcode.Fixture{{
+		Args: []string{"xcodebuild", "archive", "-project", "App.xcodeproj", "-scheme", "App",
+			"-configuration", defaultArchiveConfiguration, "-destination", defaultArchiveDestination, "-archivePath", archivePath},
+		Stdout:   "** ARCHIVE FAILED **\n",
+		ExitCode: 65,
+	}})
+	tool := NewXcodeArchiveTool(xcode.NewExecutorWithRunner(&testLogger{}, replay), xcode.NewParser(), nil, &testLogger{})
+
+	toolResult, _ := tool.Execute(context.Background(), map[string]interface{}{
+		"project":      "App.xcodeproj",
+		"scheme":       "App",
+		"archive_path": archivePath,
+	})
+	if toolResult == nil {
+		t.Fatal("Expected a result for the failed archive")
+	}
+	result := toolResult.Structured.(*types.ArchiveResult)
+	if result.Success || result.Version != "" || result.BundleID != "" || result.DSYMPaths != nil {
+		t.Errorf("Expected a failed archive without the details of the old one, got %+v", result)
+	}
+}
+
+func TestExportArchiveTool_Execute_SkipsStaleExports(t *testing.T) {
+	dir := t.TempDir()
+	archivePath := writeArchive(t, dir)
+	exportPath := filepath.Join(dir, "export")
+	if err := os.MkdirAll(exportPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	stale := filepath.Join(exportPath, "Old.ipa")
+	if err := os.WriteFile(stale, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(stale, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, exitCode := range []int{0, 70} {
+		replay := xcode.NewReplayRunner([]xcode.Fixture{{
+			Args: []string{"xcodebuild", "-exportArchive", "-archivePath", archivePath, "-exportPath", exportPath,
+				"-exportOptionsPlist", filepath.Join(exportPath, "ExportOptions.plist")},
+			ExitCode: exitCode,
+		}})
+		tool := NewExportArchiveTool(xcode.NewExecutorWithRunner(&testLogger{}, replay), xcode.NewParser(), nil, &testLogger{})
+
+		toolResult, err := tool.Execute(context.Background(), map[string]interface{}{
+			"archive_path": archivePath,
+			"export_path":  exportPath,
+		})
+		if err != nil {
+			t.Fatalf("export_archive failed: %v", err)
+		}
+		result := toolResult.Structured.(*types.ExportResult)
+		if result.Success != (exitCode == 0) || result.IPAPath != "" || len(result.ExportedPaths) != 0 {
+			t.Errorf("exit code %d: expected no exported paths from an earlier export, got %+v", exitCode, result)
+		}
+	}
+}
@@ -4,24 +4,28 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/jontolof/xcode-build-mcp/internal/common"
 	"github.com/jontolof/xcode-build-mcp/internal/filter"
+	"github.com/jontolof/xcode-build-mcp/internal/runs"
 	"github.com/jontolof/xcode-build-mcp/internal/xcode"
 	"github.com/jontolof/xcode-build-mcp/pkg/types"
 )
 
 type XcodeBuildTool struct {
-	name        string
-	description string
-	schema      map[string]interface{}
-	executor    *xcode.Executor
-	parser      *xcode.Parser
-	logger      common.Logger
+	name         string
+	description  string
+	schema       map[string]interface{}
+	outputSchema map[string]interface{}
+	executor     *xcode.Executor
+	parser       *xcode.Parser
+	runs         *runs.Store
+	logger       common.Logger
 }
 
-func NewXcodeBuildTool(executor *xcode.Executor, parser *xcode.Parser, logger common.Logger) *XcodeBuildTool {
+func NewXcodeBuildTool(executor *xcode.Executor, parser *xcode.Parser, store *runs.Store, logger common.Logger) *XcodeBuildTool {
 	schema := createJSONSchema("object", map[string]interface{}{
 		"project_path": map[string]interface{}{
 			"type":        "string",
@@ -72,7 +76,7 @@ func NewXcodeBuildTool(executor *xcode.Executor, parser *xcode.Parser, logger co
 		},
 		"archive": map[string]interface{}{
 			"type":        "boolean",
-			"description": "Create archive instead of regular build",
+			"description": "Add the archive action; xcode_archive also reports the archive, dSYM and version",
 			"default":     false,
 		},
 		"derived_data": map[string]interface{}{
@@ -80,9 +84,15 @@ func NewXcodeBuildTool(executor *xcode.Executor, parser *xcode.Parser, logger co
 			"description": "Path for derived data",
 		},
 		"environment": map[string]interface{}{
-			"type":        "object",
-			"description": "Environment variables for the build",
+			"type":                 "object",
+			"additionalProperties": map[string]interface{}{"type": "string"},
+			"description":          "Environment variables for xcodebuild, added to the server's environment; PATH, HOME, DYLD_* and other sensitive variables are rejected",
 		},
+		"developer_dir": map[string]interface{}{
+			"type":        "string",
+			"description": "Xcode to build with: an Xcode.app bundle or its Contents/Developer directory, set as DEVELOPER_DIR",
+		},
+		"xcode_version": xcodeVersionSchema(),
 		"extra_args": map[string]interface{}{
 			"type":        "array",
 			"items":       map[string]string{"type": "string"},
@@ -91,12 +101,14 @@ func NewXcodeBuildTool(executor *xcode.Executor, parser *xcode.Parser, logger co
 	}, []string{})
 
 	return &XcodeBuildTool{
-		name:        "xcode_build",
-		description: "Universal Xcode build command that handles projects, workspaces, schemes, and targets with intelligent output filtering. Returns comprehensive crash detection including: crash_type (segmentation_fault, abort, killed, timeout, fatal_error, test_crash, build_failure, etc.), process_crashed (bool), crash_indicators (fatal_error_detected, swift_runtime_crash, simulator_boot_timeout, bundle_load_failed, etc.), and silent_failure detection. Always check crash_type field - if not 'none', xcodebuild crashed rather than having normal build errors.",
-		schema:      schema,
-		executor:    executor,
-		parser:      parser,
-		logger:      logger,
+		name:         "xcode_build",
+		description:  "Universal Xcode build command that handles projects, workspaces, schemes, and targets with intelligent output filtering. Returns comprehensive crash detection including: crash_type (segmentation_fault, abort, killed, timeout, fatal_error, test_crash, build_failure, etc.), process_crashed (bool), crash_indicators (fatal_error_detected, swift_runtime_crash, simulator_boot_timeout, bundle_load_failed, etc.), and silent_failure detection. Always check crash_type field - if not 'none', xcodebuild crashed rather than having normal build errors.",
+		schema:       schema,
+		outputSchema: types.SchemaFor(types.BuildResult{}),
+		executor:     executor,
+		parser:       parser,
+		runs:         store,
+		logger:       logger,
 	}
 }
 
@@ -112,32 +124,41 @@ func (t *XcodeBuildTool) InputSchema() map[string]interface{} {
 	return t.schema
 }
 
-func (t *XcodeBuildTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+func (t *XcodeBuildTool) OutputSchema() map[string]interface{} {
+	return t.outputSchema
+}
+
+func (t *XcodeBuildTool) Execute(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error) {
 	params, err := t.parseParams(args)
 	if err != nil {
-		return "", fmt.Errorf("invalid parameters: %w", err)
+		return nil, toolError(err, types.ErrCodeInvalidParams, "invalid parameters", nil)
 	}
 
 	t.logger.Printf("Starting Xcode build with params: %+v", params)
 
-	// Build xcodebuild command arguments
-	cmdArgs, err := t.executor.BuildXcodeArgs(params)
+	// Run with the requested environment and Xcode
+	if params.DeveloperDir, err = selectXcode(ctx, t.executor, params.DeveloperDir, params.XcodeVersion); err != nil {
+		return nil, err
+	}
+	env, _, err := commandEnv(t.executor, params.Environment, "", params.DeveloperDir)
 	if err != nil {
-		return "", fmt.Errorf("failed to build command arguments: %w", err)
+		return nil, err
 	}
+	opts := append(progressOptions(ctx), xcode.WithEnv(env...))
 
-	// Add environment variables if specified
-	if len(params.Environment) > 0 {
-		// TODO: Set environment variables for the command
-		t.logger.Printf("Setting %d environment variables", len(params.Environment))
+	// Build xcodebuild command arguments
+	cmdArgs, err := t.executor.BuildXcodeArgs(params)
+	if err != nil {
+		return nil, toolError(err, types.ErrCodeInternal, "failed to build command arguments", nil)
 	}
 
 	start := time.Now()
 
 	// Execute the build command
-	result, err := t.executor.ExecuteCommand(ctx, cmdArgs)
+	result, err := t.executor.ExecuteCommand(ctx, cmdArgs, opts...)
 	if err != nil {
-		return "", fmt.Errorf("failed to execute build command: %w", err)
+		return nil, toolError(err, types.ErrCodeCommandFailed, "failed to execute build command",
+			map[string]interface{}{"command": strings.Join(cmdArgs, " ")})
 	}
 
 	duration := time.Since(start)
@@ -183,13 +204,49 @@ func (t *XcodeBuildTool) Execute(ctx context.Context, args map[string]interface{
 	// Extract build settings if present
 	buildResult.BuildSettings = t.parser.ExtractBuildSettings(result.Output)
 
+	// Keep the unfiltered log so it can be fetched as a resource
+	logURI := ""
+	if t.runs != nil {
+		run := &runs.Run{
+			Tool:         t.name,
+			Command:      strings.Join(cmdArgs, " "),
+			StartedAt:    start,
+			Duration:     duration,
+			ExitCode:     result.ExitCode,
+			Output:       result.Output,
+			DeveloperDir: params.DeveloperDir,
+			BuildResult:  buildResult,
+		}
+		t.runs.Add(run)
+		logURI = run.LogURI()
+	}
+
 	// Format the response
-	response, err := t.formatBuildResponse(buildResult, outputFilter)
+	response, err := t.formatBuildResponse(buildResult, outputFilter, logURI)
 	if err != nil {
-		return "", fmt.Errorf("failed to format response: %w", err)
+		return nil, toolError(err, types.ErrCodeInternal, "failed to format response", nil)
+	}
+
+	// Like the text response, the structured result leaves out the raw
+	// output; it is available as the log resource
+	structured := *buildResult
+	structured.Output = ""
+
+	toolResult := &types.ToolResult{
+		Content:    []types.ToolContent{types.TextContent(response)},
+		Structured: &structured,
+		IsError:    !buildResult.Success,
+	}
+
+	// Report a missing scheme, project or destination as a typed error so
+	// clients need not match the log text
+	if !buildResult.Success {
+		if err := xcodebuildSetupError(result.Output); err != nil {
+			return toolResult, err
+		}
 	}
 
-	return response, nil
+	return toolResult, nil
 }
 
 func (t *XcodeBuildTool) parseParams(args map[string]interface{}) (*types.BuildParams, error) {
@@ -269,16 +326,24 @@ func (t *XcodeBuildTool) parseParams(args map[string]interface{}) (*types.BuildP
 	params.Clean = parseBoolParam(args, "clean", false)
 	params.Archive = parseBoolParam(args, "archive", false)
 
+	if developerDir, err := parseStringParam(args, "developer_dir", false); err != nil {
+		return nil, err
+	} else if developerDir != "" {
+		params.DeveloperDir = developerDir
+	}
+
+	if xcodeVersion, err := parseStringParam(args, "xcode_version", false); err != nil {
+		return nil, err
+	} else if xcodeVersion != "" {
+		params.XcodeVersion = xcodeVersion
+	}
+
 	// Parse environment variables
-	if env, exists := args["environment"]; exists {
-		if envMap, ok := env.(map[string]interface{}); ok {
-			for k, v := range envMap {
-				if strVal, ok := v.(string); ok {
-					params.Environment[k] = strVal
-				}
-			}
-		}
+	environment, err := parseEnvironmentParam(args, "environment")
+	if err != nil {
+		return nil, err
 	}
+	params.Environment = environment
 
 	// Parse extra arguments
 	if extraArgs, err := parseArrayParam(args, "extra_args"); err != nil {
@@ -293,17 +358,19 @@ func (t *XcodeBuildTool) parseParams(args map[string]interface{}) (*types.BuildP
 
 	// Validate parameters
 	if params.Workspace == "" && params.Project == "" {
-		return nil, fmt.Errorf("either workspace or project must be specified")
+		return nil, invalidParams("either workspace or project must be specified",
+			map[string]interface{}{"parameters": []string{"workspace", "project"}})
 	}
 
 	if params.Scheme == "" && params.Target == "" {
-		return nil, fmt.Errorf("either scheme or target must be specified")
+		return nil, invalidParams("either scheme or target must be specified",
+			map[string]interface{}{"parameters": []string{"scheme", "target"}})
 	}
 
 	return params, nil
 }
 
-func (t *XcodeBuildTool) formatBuildResponse(result *types.BuildResult, outputFilter *filter.Filter) (string, error) {
+func (t *XcodeBuildTool) formatBuildResponse(result *types.BuildResult, outputFilter *filter.Filter, logURI string) (string, error) {
 	response := map[string]interface{}{
 		"success":         result.Success,
 		"duration":        result.Duration.String(),
@@ -368,7 +435,11 @@ func (t *XcodeBuildTool) formatBuildResponse(result *types.BuildResult, outputFi
 	}
 
 	// NEVER include full output - it defeats the entire purpose of filtering!
-	// The filtered output already contains all critical information including errors
+	// The filtered output already contains all critical information including errors.
+	// Point at the raw log resource instead so it can be fetched on demand.
+	if logURI != "" {
+		response["resources"] = map[string]interface{}{"log": logURI}
+	}
 
 	jsonData, err := json.MarshalIndent(response, "", "  ")
 	if err != nil {
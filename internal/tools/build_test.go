@@ -0,0 +1,46 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jontolof/xcode-build-mcp/internal/xcode"
+	"github.com/jontolof/xcode-build-mcp/pkg/types"
+)
+
+// TestXcodeBuildTool_Replay runs xcode_build against a recorded session, a
+// successful build followed by one with compile errors, without Xcode
+func TestXcodeBuildTool_Replay(t *testing.T) {
+	fixtures, err := xcode.LoadFixtures("testdata/xcode_build_session.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	replay := xcode.NewReplayRunner(fixtures)
+	tool := NewXcodeBuildTool(xcode.NewExecutorWithRunner(&testLogger{}, replay), xcode.NewParser(), nil, &testLogger{})
+	args := map[string]interface{}{"project": "MyApp.xcodeproj", "scheme": "MyApp"}
+
+	toolResult, err := tool.Execute(context.Background(), args)
+	if err != nil {
+		t.Fatalf("xcode_build failed: %v", err)
+	}
+	result := toolResult.Structured.(*types.BuildResult)
+	if !result.Success || toolResult.IsError || len(result.Warnings) != 1 {
+		t.Errorf("successful build = %+v", result)
+	}
+
+	toolResult, err = tool.Execute(context.Background(), args)
+	if err != nil {
+		t.Fatalf("xcode_build failed: %v", err)
+	}
+	result = toolResult.Structured.(*types.BuildResult)
+	if result.Success || !toolResult.IsError || result.ExitCode != 65 {
+		t.Errorf("failed build = %+v", result)
+	}
+	if len(result.Errors) != 2 || result.Errors[0].File != "/fake/MyApp/LoginView.swift" || result.Errors[0].Line != 42 {
+		t.Errorf("errors = %+v", result.Errors)
+	}
+
+	if unused := replay.Unused(); len(unused) != 0 {
+		t.Errorf("%d fixtures were not replayed", len(unused))
+	}
+}
@@ -0,0 +1,237 @@
+package tools
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jontolof/xcode-build-mcp/internal/plist"
+	"github.com/jontolof/xcode-build-mcp/pkg/types"
+)
+
+// readInfoPlist decodes a binary or XML Info.plist
+func readInfoPlist(plistPath string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(plistPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plist: %w", err)
+	}
+	info, err := plist.UnmarshalDict(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse plist: %w", err)
+	}
+	return info, nil
+}
+
+// The largest entry, and the most bytes in total, extractIPA writes, so a
+// zip bomb cannot fill the disk
+var (
+	maxIPAEntrySize int64 = 2 << 30
+	maxIPASize      int64 = 8 << 30
+)
+
+// extractIPA unpacks the Payload/*.app bundle of an .ipa into a temporary
+// directory and returns its path. cleanup removes the directory and must be
+// called once the bundle is no longer needed.
+func extractIPA(ipaPath string) (appPath string, cleanup func(), err error) {
+	archive, err := zip.OpenReader(ipaPath)
+	if err != nil {
+		return "", nil, invalidParams(fmt.Sprintf("failed to open .ipa archive %s: %v", ipaPath, err),
+			map[string]interface{}{"parameter": "app_path", "app_path": ipaPath})
+	}
+	defer archive.Close()
+
+	bundle := ipaBundleName(archive.File)
+	if bundle == "" {
+		return "", nil, invalidParams(fmt.Sprintf("no Payload/*.app bundle found in %s", ipaPath),
+			map[string]interface{}{"parameter": "app_path", "app_path": ipaPath})
+	}
+
+	dir, err := os.MkdirTemp("", "xcode-build-mcp-ipa-")
+	if err != nil {
+		return "", nil, types.NewXcodeErrorWithCause(types.ErrCodeInternal, "failed to create temporary directory", err, nil)
+	}
+	cleanup = func() { os.RemoveAll(dir) }
+
+	if err := extractBundle(archive.File, dir, "Payload/"+bundle+"/"); err != nil {
+		cleanup()
+		return "", nil, types.NewXcodeErrorWithCause(types.ErrCodeInternal, "failed to extract .ipa", err,
+			map[string]interface{}{"app_path": ipaPath})
+	}
+
+	return filepath.Join(dir, bundle), cleanup, nil
+}
+
+// ipaBundleName returns the name of the first .app directory under Payload/
+func ipaBundleName(files []*zip.File) string {
+	for _, file := range files {
+		parts := strings.SplitN(file.Name, "/", 3)
+		if len(parts) >= 2 && parts[0] == "Payload" && strings.HasSuffix(parts[1], ".app") && (len(parts) == 3 || file.FileInfo().IsDir()) {
+			return parts[1]
+		}
+	}
+	return ""
+}
+
+// extractBundle writes the entries below prefix into dir. Files and
+// directories go through an os.Root, which will not follow a path out of
+// dir. Symlinks come last, once no later entry can be written through
+// them, and are then checked to resolve inside dir.
+func extractBundle(files []*zip.File, dir, prefix string) error {
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	var links []*zip.File
+	remaining := maxIPASize
+	for _, file := range files {
+		if !strings.HasPrefix(file.Name, prefix) {
+			continue
+		}
+		if file.Mode()&os.ModeSymlink != 0 {
+			links = append(links, file)
+			continue
+		}
+		written, err := extractZipFile(root, file, strings.TrimPrefix(file.Name, "Payload/"), remaining)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file.Name, err)
+		}
+		remaining -= written
+	}
+
+	for _, file := range links {
+		if err := extractZipSymlink(root, file, strings.TrimPrefix(file.Name, "Payload/")); err != nil {
+			return fmt.Errorf("%s: %w", file.Name, err)
+		}
+	}
+	return checkSymlinks(dir, links)
+}
+
+// extractZipFile writes the file or directory entry to name below root,
+// failing once more than limit bytes would be written
+func extractZipFile(root *os.Root, file *zip.File, name string, limit int64) (int64, error) {
+	name, err := localName(name)
+	if err != nil {
+		return 0, err
+	}
+	if file.Mode().IsDir() {
+		return 0, mkdirAll(root, name)
+	}
+	if err := mkdirAll(root, filepath.Dir(name)); err != nil {
+		return 0, err
+	}
+
+	limit = min(limit, maxIPAEntrySize)
+	if file.UncompressedSize64 > uint64(limit) {
+		return 0, fmt.Errorf("entry is larger than %d bytes", limit)
+	}
+	reader, err := file.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+
+	out, err := root.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, file.Mode().Perm()|0600)
+	if err != nil {
+		return 0, err
+	}
+	written, err := io.Copy(out, io.LimitReader(reader, limit+1))
+	if err == nil && written > limit {
+		err = fmt.Errorf("entry is larger than %d bytes", limit)
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return written, err
+}
+
+// extractZipSymlink creates the symlink entry at name below root. Every
+// parent must be a real directory, so the link is made inside root.
+func extractZipSymlink(root *os.Root, file *zip.File, name string) error {
+	name, err := localName(name)
+	if err != nil {
+		return err
+	}
+	reader, err := file.Open()
+	if err != nil {
+		return err
+	}
+	link, err := io.ReadAll(io.LimitReader(reader, 4096))
+	reader.Close()
+	if err != nil {
+		return err
+	}
+	linkTarget := filepath.FromSlash(string(link))
+	if filepath.IsAbs(linkTarget) || !filepath.IsLocal(filepath.Join(filepath.Dir(name), linkTarget)) {
+		return fmt.Errorf("symlink escapes the bundle: -> %s", link)
+	}
+
+	if err := mkdirAll(root, filepath.Dir(name)); err != nil {
+		return err
+	}
+	parent := "."
+	for _, part := range strings.Split(filepath.Dir(name), string(filepath.Separator)) {
+		parent = filepath.Join(parent, part)
+		info, err := root.Lstat(parent)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("symlink is below another symlink: %s", parent)
+		}
+	}
+	return os.Symlink(linkTarget, filepath.Join(root.Name(), name))
+}
+
+// checkSymlinks resolves every extracted symlink, catching chains such as
+// a -> b/.. with b -> .. that only leave dir once followed
+func checkSymlinks(dir string, links []*zip.File) error {
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return err
+	}
+	for _, file := range links {
+		resolved, err := filepath.EvalSymlinks(filepath.Join(dir, filepath.FromSlash(strings.TrimPrefix(file.Name, "Payload/"))))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", file.Name, err)
+		}
+		if rel, err := filepath.Rel(realDir, resolved); err != nil || !filepath.IsLocal(rel) {
+			return fmt.Errorf("%s: symlink escapes the bundle", file.Name)
+		}
+	}
+	return nil
+}
+
+// localName checks that the slash separated name stays below the
+// extraction directory and returns it in the local form
+func localName(name string) (string, error) {
+	local := filepath.Clean(filepath.FromSlash(strings.TrimSuffix(name, "/")))
+	if !filepath.IsLocal(local) {
+		return "", fmt.Errorf("entry escapes the bundle")
+	}
+	return local, nil
+}
+
+// mkdirAll creates name and its parents below root
+func mkdirAll(root *os.Root, name string) error {
+	dir := "."
+	for _, part := range strings.Split(name, string(filepath.Separator)) {
+		if part == "." {
+			continue
+		}
+		dir = filepath.Join(dir, part)
+		if err := root.Mkdir(dir, 0755); err != nil && !errors.Is(err, fs.ErrExist) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package tools
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeZip writes entries, keyed by archive path, to a zip file at path
+func writeZip(t *testing.T, path string, entries map[string]string) string {
+	t.Helper()
+
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	archive := zip.NewWriter(file)
+	for name, content := range entries {
+		w, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// writeIPA writes TestApp.ipa with files added to a Payload/TestApp.app whose
+// Info.plist is the binary fixture of the plist package
+func writeIPA(t *testing.T, dir string, files map[string]string) string {
+	t.Helper()
+
+	info, err := os.ReadFile("../plist/testdata/Info.bplist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := map[string]string{"Payload/TestApp.app/Info.plist": string(info)}
+	for name, content := range files {
+		entries[name] = content
+	}
+	return writeZip(t, filepath.Join(dir, "TestApp.ipa"), entries)
+}
+
+func TestExtractIPA(t *testing.T) {
+	ipaPath := writeIPA(t, t.TempDir(), map[string]string{
+		"Payload/TestApp.app/TestApp":             "binary",
+		"Payload/TestApp.app/Base.lproj/Main.nib": "nib",
+		"iTunesMetadata.plist":                    "metadata",
+	})
+
+	appPath, cleanup, err := extractIPA(ipaPath)
+	if err != nil {
+		t.Fatalf("extractIPA failed: %v", err)
+	}
+
+	if filepath.Base(appPath) != "TestApp.app" {
+		t.Errorf("Expected the TestApp.app bundle, got %s", appPath)
+	}
+	for _, name := range []string{"Info.plist", "TestApp", "Base.lproj/Main.nib"} {
+		if _, err := os.Stat(filepath.Join(appPath, name)); err != nil {
+			t.Errorf("Expected %s in the bundle: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(appPath), "iTunesMetadata.plist")); err == nil {
+		t.Error("Expected files outside the bundle to be skipped")
+	}
+
+	bundleID, err := extractBundleID(appPath)
+	if err != nil || bundleID != "com.example.TestApp" {
+		t.Errorf("extractBundleID() = %q, %v", bundleID, err)
+	}
+
+	cleanup()
+	if _, err := os.Stat(appPath); !os.IsNotExist(err) {
+		t.Errorf("Expected cleanup to remove %s", appPath)
+	}
+}
+
+func TestExtractIPA_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	notZip := filepath.Join(dir, "broken.ipa")
+	if err := os.WriteFile(notZip, []byte("not a zip"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"not a zip":  notZip,
+		"no payload": writeZip(t, filepath.Join(dir, "empty.ipa"), map[string]string{"Payload/README": "x"}),
+		"zip slip":   writeIPA(t, dir, map[string]string{"Payload/TestApp.app/../../../escape": "x"}),
+	}
+
+	for name, ipaPath := range tests {
+		if _, _, err := extractIPA(ipaPath); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+// writeSymlinkIPA writes TestApp.ipa with the given files and symlinks,
+// keyed by archive path, in the order listed
+func writeSymlinkIPA(t *testing.T, dir string, entries [][3]string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, "TestApp.ipa")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	archive := zip.NewWriter(file)
+	for _, entry := range entries {
+		header := &zip.FileHeader{Name: entry[1], Method: zip.Deflate}
+		header.SetMode(0644)
+		if entry[0] == "link" {
+			header.SetMode(os.ModeSymlink | 0777)
+		}
+		w, err := archive.CreateHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(entry[2])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExtractIPA_Symlinks(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+
+	ipaPath := writeSymlinkIPA(t, t.TempDir(), [][3]string{
+		{"file", "Payload/TestApp.app/Info.plist", "plist"},
+		{"link", "Payload/TestApp.app/Frameworks/Kit.framework/Versions/Current", "A"},
+		{"file", "Payload/TestApp.app/Frameworks/Kit.framework/Versions/A/Kit", "binary"},
+	})
+	appPath, cleanup, err := extractIPA(ipaPath)
+	if err != nil {
+		t.Fatalf("extractIPA failed: %v", err)
+	}
+	defer cleanup()
+	if _, err := os.Stat(filepath.Join(appPath, "Frameworks/Kit.framework/Versions/Current/Kit")); err != nil {
+		t.Errorf("Expected the framework symlink to resolve: %v", err)
+	}
+
+	tests := map[string][][3]string{
+		"absolute": {
+			{"link", "Payload/TestApp.app/s", "/etc"},
+		},
+		"relative": {
+			{"link", "Payload/TestApp.app/s", "../../.."},
+		},
+		"chain": {
+			{"link", "Payload/TestApp.app/s2", ".."},
+			{"link", "Payload/TestApp.app/s1", "s2/.."},
+		},
+		"write through chain": {
+			{"link", "Payload/TestApp.app/s2", ".."},
+			{"link", "Payload/TestApp.app/s1", "s2/.."},
+			{"file", "Payload/TestApp.app/s1/evil", "x"},
+		},
+		"link through chain": {
+			{"link", "Payload/TestApp.app/s2", ".."},
+			{"link", "Payload/TestApp.app/s1", "s2/.."},
+			{"link", "Payload/TestApp.app/s1/evil", "."},
+		},
+	}
+	for name, entries := range tests {
+		entries = append([][3]string{{"file", "Payload/TestApp.app/Info.plist", "plist"}}, entries...)
+		if _, _, err := extractIPA(writeSymlinkIPA(t, t.TempDir(), entries)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+		if _, err := os.Lstat(filepath.Join(tmp, "evil")); err == nil {
+			t.Fatalf("%s: wrote outside the extraction directory", name)
+		}
+	}
+
+	cleanup()
+	if leftovers, _ := os.ReadDir(tmp); len(leftovers) != 0 {
+		t.Errorf("Expected failed extractions to clean up, found %d entries", len(leftovers))
+	}
+}
+
+func TestExtractIPA_SizeLimit(t *testing.T) {
+	defer func(limit int64) { maxIPAEntrySize = limit }(maxIPAEntrySize)
+	maxIPAEntrySize = 16
+
+	ipaPath := writeIPA(t, t.TempDir(), map[string]string{
+		"Payload/TestApp.app/TestApp": string(make([]byte, 1024)),
+	})
+	if _, _, err := extractIPA(ipaPath); err == nil {
+		t.Error("Expected an entry over the size limit to fail")
+	}
+}
@@ -3,7 +3,7 @@ package tools
 import (
 	"context"
 	"encoding/json"
-	"fmt"
+	"strings"
 
 	"github.com/jontolof/xcode-build-mcp/internal/common"
 	"github.com/jontolof/xcode-build-mcp/internal/filter"
@@ -12,12 +12,13 @@ import (
 )
 
 type XcodeCleanTool struct {
-	name        string
-	description string
-	schema      map[string]interface{}
-	executor    *xcode.Executor
-	parser      *xcode.Parser
-	logger      common.Logger
+	name         string
+	description  string
+	schema       map[string]interface{}
+	outputSchema map[string]interface{}
+	executor     *xcode.Executor
+	parser       *xcode.Parser
+	logger       common.Logger
 }
 
 func NewXcodeCleanTool(executor *xcode.Executor, parser *xcode.Parser, logger common.Logger) *XcodeCleanTool {
@@ -45,15 +46,21 @@ func NewXcodeCleanTool(executor *xcode.Executor, parser *xcode.Parser, logger co
 			"description": "Output filtering level",
 			"default":     "standard",
 		},
+		"developer_dir": map[string]interface{}{
+			"type":        "string",
+			"description": "Xcode to clean with: an Xcode.app bundle or its Contents/Developer directory, set as DEVELOPER_DIR",
+		},
+		"xcode_version": xcodeVersionSchema(),
 	}, []string{})
 
 	return &XcodeCleanTool{
-		name:        "xcode_clean",
-		description: "Clean Xcode build artifacts with support for derived data and deep cleaning",
-		schema:      schema,
-		executor:    executor,
-		parser:      parser,
-		logger:      logger,
+		name:         "xcode_clean",
+		description:  "Clean Xcode build artifacts with support for derived data and deep cleaning",
+		schema:       schema,
+		outputSchema: types.SchemaFor(types.CleanResult{}),
+		executor:     executor,
+		parser:       parser,
+		logger:       logger,
 	}
 }
 
@@ -69,7 +76,11 @@ func (t *XcodeCleanTool) InputSchema() map[string]interface{} {
 	return t.schema
 }
 
-func (t *XcodeCleanTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+func (t *XcodeCleanTool) OutputSchema() map[string]interface{} {
+	return t.outputSchema
+}
+
+func (t *XcodeCleanTool) Execute(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error) {
 	params := &types.CleanParams{}
 
 	// Parse basic parameters
@@ -77,25 +88,45 @@ func (t *XcodeCleanTool) Execute(ctx context.Context, args map[string]interface{
 	workspace, _ := parseStringParam(args, "workspace", false)
 	project, _ := parseStringParam(args, "project", false)
 	outputMode, _ := parseStringParam(args, "output_mode", false)
+	developerDir, err := parseStringParam(args, "developer_dir", false)
+	if err != nil {
+		return nil, err
+	}
+	xcodeVersion, err := parseStringParam(args, "xcode_version", false)
+	if err != nil {
+		return nil, err
+	}
 
 	params.ProjectPath = projectPath
 	params.Workspace = workspace
 	params.Project = project
 	params.CleanBuild = parseBoolParam(args, "clean_build", false)
+	params.DeveloperDir = developerDir
+	params.XcodeVersion = xcodeVersion
 
 	// Validate
 	if params.Workspace == "" && params.Project == "" {
-		return "", fmt.Errorf("either workspace or project must be specified")
+		return nil, invalidParams("either workspace or project must be specified",
+			map[string]interface{}{"parameters": []string{"workspace", "project"}})
+	}
+
+	if params.DeveloperDir, err = selectXcode(ctx, t.executor, params.DeveloperDir, params.XcodeVersion); err != nil {
+		return nil, err
+	}
+	env, _, err := commandEnv(t.executor, nil, "", params.DeveloperDir)
+	if err != nil {
+		return nil, err
 	}
 
 	cmdArgs, err := t.executor.BuildXcodeArgs(params)
 	if err != nil {
-		return "", fmt.Errorf("failed to build command arguments: %w", err)
+		return nil, toolError(err, types.ErrCodeInternal, "failed to build command arguments", nil)
 	}
 
-	result, err := t.executor.ExecuteCommand(ctx, cmdArgs)
+	result, err := t.executor.ExecuteCommand(ctx, cmdArgs, xcode.WithEnv(env...))
 	if err != nil {
-		return "", fmt.Errorf("failed to execute clean command: %w", err)
+		return nil, toolError(err, types.ErrCodeCommandFailed, "failed to execute clean command",
+			map[string]interface{}{"command": strings.Join(cmdArgs, " ")})
 	}
 
 	cleanResult := t.parser.ParseCleanOutput(result.Output)
@@ -125,8 +156,15 @@ func (t *XcodeCleanTool) Execute(ctx context.Context, args map[string]interface{
 
 	jsonData, err := json.MarshalIndent(response, "", "  ")
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal response: %w", err)
+		return nil, toolError(err, types.ErrCodeInternal, "failed to marshal response", nil)
 	}
 
-	return string(jsonData), nil
+	structured := *cleanResult
+	structured.Output = ""
+
+	return &types.ToolResult{
+		Content:    []types.ToolContent{types.TextContent(string(jsonData))},
+		Structured: &structured,
+		IsError:    !cleanResult.Success,
+	}, nil
 }
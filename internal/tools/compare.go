@@ -0,0 +1,330 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jontolof/xcode-build-mcp/internal/imaging"
+	"github.com/jontolof/xcode-build-mcp/pkg/types"
+)
+
+const (
+	defaultCompareTolerance = 8
+	// statusBarPercent of the image height covers the status bar of notched
+	// and Dynamic Island iPhones, whose clock and battery change every run
+	statusBarPercent = 7
+)
+
+type CompareScreenshot struct {
+	name         string
+	description  string
+	schema       map[string]interface{}
+	outputSchema map[string]interface{}
+	screenshot   *Screenshot
+}
+
+// NewCompareScreenshot captures fresh screenshots through screenshot
+func NewCompareScreenshot(screenshot *Screenshot) *CompareScreenshot {
+	schema := createJSONSchema("object", map[string]interface{}{
+		"baseline_path": map[string]interface{}{
+			"type":        "string",
+			"description": "Path of the baseline PNG to compare against",
+		},
+		"actual_path": map[string]interface{}{
+			"type":        "string",
+			"description": "Compare this image instead of capturing a new screenshot",
+		},
+		"udid": map[string]interface{}{
+			"type":        "string",
+			"description": "UDID of the simulator to capture (optional for auto-detection)",
+		},
+		"device_type": map[string]interface{}{
+			"type":        "string",
+			"description": "Device type filter for auto-selection if UDID not provided",
+		},
+		"tolerance": map[string]interface{}{
+			"type":        "integer",
+			"description": "Largest per-channel difference (0-255) at which a pixel still matches",
+			"default":     defaultCompareTolerance,
+		},
+		"max_mismatch": map[string]interface{}{
+			"type":        "number",
+			"description": "Percentage of changed pixels allowed before the comparison fails - default: 0",
+		},
+		"ignore_regions": map[string]interface{}{
+			"type":        "array",
+			"description": "Regions in image pixels that are not compared",
+			"items": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"x":      map[string]interface{}{"type": "integer"},
+					"y":      map[string]interface{}{"type": "integer"},
+					"width":  map[string]interface{}{"type": "integer"},
+					"height": map[string]interface{}{"type": "integer"},
+				},
+				"required": []string{"x", "y", "width", "height"},
+			},
+		},
+		"ignore_status_bar": map[string]interface{}{
+			"type":        "boolean",
+			"description": "Ignore the status bar (the top 7% of the image)",
+		},
+		"mask_path": map[string]interface{}{
+			"type":        "string",
+			"description": "PNG whose non-transparent pixels are not compared",
+		},
+		"diff_path": map[string]interface{}{
+			"type":        "string",
+			"description": "Where to write the diff image - default: next to the actual image with a _diff suffix",
+		},
+		"update_baseline": map[string]interface{}{
+			"type":        "boolean",
+			"description": "Record the actual image as the baseline when it is missing or differs",
+		},
+		"include_image": map[string]interface{}{
+			"type":        "boolean",
+			"description": "Return the diff image as image content when the images differ",
+			"default":     true,
+		},
+	}, []string{"baseline_path"})
+
+	return &CompareScreenshot{
+		name:         "compare_screenshot",
+		description:  "Compare a fresh simulator screenshot (or an image file) against a baseline PNG with a per-pixel tolerance and ignored regions, writing a highlighted diff image and returning the mismatch percentage and bounding boxes of the changed areas",
+		schema:       schema,
+		outputSchema: types.SchemaFor(types.ScreenshotCompareResult{}),
+		screenshot:   screenshot,
+	}
+}
+
+func (t *CompareScreenshot) Name() string {
+	return t.name
+}
+
+func (t *CompareScreenshot) Description() string {
+	return t.description
+}
+
+func (t *CompareScreenshot) InputSchema() map[string]interface{} {
+	return t.schema
+}
+
+func (t *CompareScreenshot) OutputSchema() map[string]interface{} {
+	return t.outputSchema
+}
+
+func (t *CompareScreenshot) Execute(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error) {
+	p, err := t.parseParams(args)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	result := &types.ScreenshotCompareResult{BaselinePath: p.BaselinePath}
+
+	if p.ActualPath == "" {
+		captureArgs := map[string]interface{}{"format": "png", "include_image": false}
+		if p.UDID != "" {
+			captureArgs["udid"] = p.UDID
+		}
+		if p.DeviceType != "" {
+			captureArgs["device_type"] = p.DeviceType
+		}
+
+		capture, _, err := t.screenshot.capture(ctx, captureArgs)
+		if err != nil {
+			result.Duration = time.Since(start)
+			partial, _ := types.NewToolResult(result, true)
+			return partial, toolError(err, types.ErrCodeCommandFailed, "failed to capture screenshot", nil)
+		}
+		p.ActualPath = capture.FilePath
+	}
+	result.ActualPath = p.ActualPath
+
+	diffImage, err := t.compare(p, result)
+	if err != nil {
+		result.Duration = time.Since(start)
+		partial, _ := types.NewToolResult(result, true)
+		return partial, err
+	}
+
+	result.Success = result.Match || result.BaselineUpdated
+	result.Duration = time.Since(start)
+
+	toolResult, err := types.NewToolResult(result, !result.Success)
+	if err != nil {
+		return nil, err
+	}
+	if p.IncludeImage && diffImage != nil {
+		data, mimeType, err := imaging.Encode(imaging.Fit(diffImage, defaultScreenshotMaxDimension), "jpeg", imaging.DefaultJPEGQuality)
+		if err != nil {
+			return nil, err
+		}
+		toolResult.Content = append(toolResult.Content, types.ImageContent(data, mimeType))
+	}
+	return toolResult, nil
+}
+
+func (t *CompareScreenshot) parseParams(args map[string]interface{}) (*types.ScreenshotCompareParams, error) {
+	p := &types.ScreenshotCompareParams{
+		Tolerance:       defaultCompareTolerance,
+		IgnoreStatusBar: parseBoolParam(args, "ignore_status_bar", false),
+		UpdateBaseline:  parseBoolParam(args, "update_baseline", false),
+		IncludeImage:    parseBoolParam(args, "include_image", true),
+	}
+
+	var err error
+	if p.BaselinePath, err = parseStringParam(args, "baseline_path", true); err != nil {
+		return nil, err
+	}
+	for key, target := range map[string]*string{
+		"actual_path": &p.ActualPath,
+		"udid":        &p.UDID,
+		"device_type": &p.DeviceType,
+		"mask_path":   &p.MaskPath,
+		"diff_path":   &p.DiffPath,
+	} {
+		if *target, err = parseStringParam(args, key, false); err != nil {
+			return nil, err
+		}
+	}
+
+	if tolerance, exists := args["tolerance"]; exists {
+		num, ok := tolerance.(float64)
+		if !ok || num < 0 || num > 255 {
+			return nil, invalidParams("tolerance must be between 0 and 255", map[string]interface{}{"parameter": "tolerance"})
+		}
+		p.Tolerance = int(num)
+	}
+	if maxMismatch, exists := args["max_mismatch"]; exists {
+		num, ok := maxMismatch.(float64)
+		if !ok || num < 0 || num > 100 {
+			return nil, invalidParams("max_mismatch must be a percentage between 0 and 100", map[string]interface{}{"parameter": "max_mismatch"})
+		}
+		p.MaxMismatch = num
+	}
+
+	regions, err := parseArrayParam(args, "ignore_regions")
+	if err != nil {
+		return nil, err
+	}
+	for i, raw := range regions {
+		region, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, invalidParams(fmt.Sprintf("ignore_regions[%d] must be an object with x, y, width and height", i),
+				map[string]interface{}{"parameter": "ignore_regions"})
+		}
+		var values [4]int
+		for j, key := range []string{"x", "y", "width", "height"} {
+			num, ok := region[key].(float64)
+			if !ok {
+				return nil, invalidParams(fmt.Sprintf("ignore_regions[%d].%s must be a number", i, key),
+					map[string]interface{}{"parameter": "ignore_regions"})
+			}
+			values[j] = int(num)
+		}
+		p.IgnoreRegions = append(p.IgnoreRegions, types.ImageRegion{X: values[0], Y: values[1], Width: values[2], Height: values[3]})
+	}
+
+	return p, nil
+}
+
+// compare diffs the actual image against the baseline and fills in result.
+// When the images differ it writes the diff image and returns it.
+func (t *CompareScreenshot) compare(p *types.ScreenshotCompareParams, result *types.ScreenshotCompareResult) (image.Image, error) {
+	if _, err := os.Stat(p.BaselinePath); os.IsNotExist(err) {
+		if !p.UpdateBaseline {
+			return nil, invalidParams(fmt.Sprintf("baseline not found: %s (pass update_baseline to record it)", p.BaselinePath),
+				map[string]interface{}{"parameter": "baseline_path"})
+		}
+		if err := copyFile(p.ActualPath, p.BaselinePath); err != nil {
+			return nil, err
+		}
+		result.Match, result.BaselineUpdated = true, true
+		return nil, nil
+	}
+
+	baseline, err := imaging.Load(p.BaselinePath)
+	if err != nil {
+		return nil, invalidParams(err.Error(), map[string]interface{}{"parameter": "baseline_path"})
+	}
+	actual, err := imaging.Load(p.ActualPath)
+	if err != nil {
+		return nil, invalidParams(err.Error(), map[string]interface{}{"parameter": "actual_path"})
+	}
+
+	opts := imaging.DiffOptions{Tolerance: p.Tolerance}
+	if p.MaskPath != "" {
+		if opts.Mask, err = imaging.Load(p.MaskPath); err != nil {
+			return nil, invalidParams(err.Error(), map[string]interface{}{"parameter": "mask_path"})
+		}
+	}
+	for _, region := range p.IgnoreRegions {
+		opts.Ignore = append(opts.Ignore, image.Rect(region.X, region.Y, region.X+region.Width, region.Y+region.Height))
+	}
+	if p.IgnoreStatusBar {
+		bounds := baseline.Bounds()
+		opts.Ignore = append(opts.Ignore, image.Rect(0, 0, bounds.Dx(), bounds.Dy()*statusBarPercent/100))
+	}
+
+	diff := imaging.Diff(baseline, actual, opts)
+	result.Width, result.Height = diff.Width, diff.Height
+	result.ChangedPixels, result.ComparedPixels = diff.ChangedPixels, diff.ComparedPixels
+	result.MismatchPercent = diff.MismatchPercent()
+	result.Match = result.MismatchPercent <= p.MaxMismatch
+	for _, region := range diff.Regions {
+		result.Regions = append(result.Regions, types.ImageRegion{
+			X: region.Min.X, Y: region.Min.Y, Width: region.Dx(), Height: region.Dy(),
+		})
+	}
+
+	if diff.ChangedPixels > 0 {
+		diffPath := p.DiffPath
+		if diffPath == "" {
+			diffPath = strings.TrimSuffix(p.ActualPath, filepath.Ext(p.ActualPath)) + "_diff.png"
+		}
+		data, _, err := imaging.Encode(diff.Image, "png", 0)
+		if err != nil {
+			return nil, types.NewXcodeErrorWithCause(types.ErrCodeInternal, "failed to encode diff image", err, nil)
+		}
+		if err := writeFile(diffPath, data); err != nil {
+			return nil, err
+		}
+		result.DiffPath = diffPath
+	}
+
+	if !result.Match && p.UpdateBaseline {
+		if err := copyFile(p.ActualPath, p.BaselinePath); err != nil {
+			return nil, err
+		}
+		result.BaselineUpdated = true
+	}
+	if result.DiffPath == "" {
+		return nil, nil
+	}
+	return diff.Image, nil
+}
+
+func copyFile(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return types.NewXcodeErrorWithCause(types.ErrCodeInternal, "failed to read image", err, map[string]interface{}{"path": src})
+	}
+	return writeFile(dst, data)
+}
+
+// writeFile writes data to path, creating its directory
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return types.NewXcodeErrorWithCause(types.ErrCodeInternal, "failed to create output directory", err, map[string]interface{}{"path": path})
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return types.NewXcodeErrorWithCause(types.ErrCodeInternal, "failed to write file", err, map[string]interface{}{"path": path})
+	}
+	return nil
+}
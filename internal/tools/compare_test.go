@@ -0,0 +1,210 @@
+package tools
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jontolof/xcode-build-mcp/internal/xcode"
+	"github.com/jontolof/xcode-build-mcp/pkg/types"
+)
+
+// writeScreen writes a white 100x200 PNG with the given rectangles in black
+func writeScreen(t *testing.T, path string, rects ...image.Rectangle) string {
+	t.Helper()
+
+	img := image.NewNRGBA(image.Rect(0, 0, 100, 200))
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 100; x++ {
+			c := color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+			for _, r := range rects {
+				if (image.Point{X: x, Y: y}).In(r) {
+					c = color.NRGBA{A: 255}
+				}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if err := png.Encode(file, img); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCompareScreenshot_Name(t *testing.T) {
+	tool := NewCompareScreenshot(NewScreenshot(xcode.NewExecutor(&testLogger{})))
+	if got := tool.Name(); got != "compare_screenshot" {
+		t.Errorf("CompareScreenshot.Name() = %v, want %v", got, "compare_screenshot")
+	}
+	if len(tool.Description()) < 20 {
+		t.Errorf("CompareScreenshot.Description() too short: %s", tool.Description())
+	}
+}
+
+func TestCompareScreenshot_Execute(t *testing.T) {
+	dir := t.TempDir()
+	baseline := writeScreen(t, filepath.Join(dir, "baseline.png"), image.Rect(10, 50, 90, 70))
+	same := writeScreen(t, filepath.Join(dir, "same.png"), image.Rect(10, 50, 90, 70))
+	// The clock in the status bar and the button moved
+	changed := writeScreen(t, filepath.Join(dir, "changed.png"), image.Rect(40, 2, 60, 8), image.Rect(10, 60, 90, 80))
+
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		match   bool
+		changed int
+		regions int
+	}{
+		{
+			name:  "identical",
+			args:  map[string]interface{}{"actual_path": same},
+			match: true,
+		},
+		{
+			name:    "changed",
+			args:    map[string]interface{}{"actual_path": changed},
+			changed: 120 + 1600,
+			regions: 2,
+		},
+		{
+			name:    "status bar ignored",
+			args:    map[string]interface{}{"actual_path": changed, "ignore_status_bar": true},
+			changed: 1600,
+			regions: 1,
+		},
+		{
+			name: "regions ignored",
+			args: map[string]interface{}{
+				"actual_path": changed,
+				"ignore_regions": []interface{}{
+					map[string]interface{}{"x": float64(0), "y": float64(0), "width": float64(100), "height": float64(10)},
+					map[string]interface{}{"x": float64(0), "y": float64(40), "width": float64(100), "height": float64(50)},
+				},
+			},
+			match: true,
+		},
+		{
+			name:    "within max_mismatch",
+			args:    map[string]interface{}{"actual_path": changed, "max_mismatch": float64(10)},
+			match:   true,
+			changed: 1720,
+			regions: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := map[string]interface{}{"baseline_path": baseline}
+			for k, v := range tt.args {
+				args[k] = v
+			}
+
+			toolResult, err := NewCompareScreenshot(NewScreenshot(xcode.NewExecutor(&testLogger{}))).Execute(context.Background(), args)
+			if err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+
+			result := toolResult.Structured.(*types.ScreenshotCompareResult)
+			if result.Match != tt.match || toolResult.IsError == tt.match {
+				t.Errorf("Expected match=%v, got %+v", tt.match, result)
+			}
+			if result.ChangedPixels != tt.changed || len(result.Regions) != tt.regions {
+				t.Errorf("Expected %d changed pixels in %d regions, got %d in %v",
+					tt.changed, tt.regions, result.ChangedPixels, result.Regions)
+			}
+
+			// The diff image is written and attached only when pixels changed
+			if tt.changed == 0 {
+				if result.DiffPath != "" || len(toolResult.Content) != 1 {
+					t.Errorf("Expected no diff image, got %q", result.DiffPath)
+				}
+				return
+			}
+			if _, err := os.Stat(result.DiffPath); err != nil {
+				t.Errorf("Expected the diff image at %s: %v", result.DiffPath, err)
+			}
+			if len(toolResult.Content) != 2 || toolResult.Content[1].Type != "image" {
+				t.Errorf("Expected the diff image as content, got %d items", len(toolResult.Content))
+			}
+		})
+	}
+}
+
+func TestCompareScreenshot_Regions(t *testing.T) {
+	dir := t.TempDir()
+	baseline := writeScreen(t, filepath.Join(dir, "baseline.png"))
+	actual := writeScreen(t, filepath.Join(dir, "actual.png"), image.Rect(20, 100, 50, 110))
+
+	toolResult, _ := NewCompareScreenshot(NewScreenshot(xcode.NewExecutor(&testLogger{}))).Execute(context.Background(), map[string]interface{}{
+		"baseline_path": baseline,
+		"actual_path":   actual,
+		"diff_path":     filepath.Join(dir, "out", "diff.png"),
+	})
+
+	result := toolResult.Structured.(*types.ScreenshotCompareResult)
+	want := types.ImageRegion{X: 20, Y: 100, Width: 30, Height: 10}
+	if len(result.Regions) != 1 || result.Regions[0] != want {
+		t.Errorf("Expected %+v, got %+v", want, result.Regions)
+	}
+	if result.MismatchPercent != 1.5 {
+		t.Errorf("Expected 1.5%% mismatch, got %v", result.MismatchPercent)
+	}
+	if result.DiffPath != filepath.Join(dir, "out", "diff.png") {
+		t.Errorf("Expected the diff at diff_path, got %s", result.DiffPath)
+	}
+}
+
+func TestCompareScreenshot_Baseline(t *testing.T) {
+	dir := t.TempDir()
+	actual := writeScreen(t, filepath.Join(dir, "actual.png"), image.Rect(0, 0, 10, 10))
+	baseline := filepath.Join(dir, "baselines", "login.png")
+	tool := NewCompareScreenshot(NewScreenshot(xcode.NewExecutor(&testLogger{})))
+
+	_, err := tool.Execute(context.Background(), map[string]interface{}{"baseline_path": baseline, "actual_path": actual})
+	if !types.IsXcodeError(err, types.ErrCodeInvalidParams) {
+		t.Errorf("Expected INVALID_PARAMS for a missing baseline, got %v", err)
+	}
+
+	toolResult, err := tool.Execute(context.Background(), map[string]interface{}{
+		"baseline_path":   baseline,
+		"actual_path":     actual,
+		"update_baseline": true,
+	})
+	if err != nil {
+		t.Fatalf("Recording the baseline failed: %v", err)
+	}
+	if result := toolResult.Structured.(*types.ScreenshotCompareResult); !result.Success || !result.BaselineUpdated {
+		t.Errorf("Expected the baseline to be recorded, got %+v", result)
+	}
+
+	toolResult, err = tool.Execute(context.Background(), map[string]interface{}{"baseline_path": baseline, "actual_path": actual})
+	if err != nil || !toolResult.Structured.(*types.ScreenshotCompareResult).Match {
+		t.Errorf("Expected the recorded baseline to match, got %v", err)
+	}
+}
+
+func TestCompareScreenshot_InvalidParams(t *testing.T) {
+	tests := []map[string]interface{}{
+		{},
+		{"baseline_path": "b.png", "tolerance": float64(300)},
+		{"baseline_path": "b.png", "max_mismatch": float64(-1)},
+		{"baseline_path": "b.png", "ignore_regions": []interface{}{map[string]interface{}{"x": float64(1)}}},
+	}
+
+	for _, args := range tests {
+		_, err := NewCompareScreenshot(NewScreenshot(xcode.NewExecutor(&testLogger{}))).Execute(context.Background(), args)
+		if !types.IsXcodeError(err, types.ErrCodeInvalidParams) {
+			t.Errorf("%v: expected INVALID_PARAMS, got %v", args, err)
+		}
+	}
+}
@@ -2,8 +2,8 @@ package tools
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
+	"errors"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
@@ -15,12 +15,13 @@ import (
 )
 
 type DiscoverProjectsTool struct {
-	name        string
-	description string
-	schema      map[string]interface{}
-	executor    *xcode.Executor
-	parser      *xcode.Parser
-	logger      common.Logger
+	name         string
+	description  string
+	schema       map[string]interface{}
+	outputSchema map[string]interface{}
+	executor     *xcode.Executor
+	parser       *xcode.Parser
+	logger       common.Logger
 }
 
 func NewDiscoverProjectsTool(executor *xcode.Executor, parser *xcode.Parser, logger common.Logger) *DiscoverProjectsTool {
@@ -49,12 +50,13 @@ func NewDiscoverProjectsTool(executor *xcode.Executor, parser *xcode.Parser, log
 	}, []string{})
 
 	return &DiscoverProjectsTool{
-		name:        "discover_projects",
-		description: "Discover Xcode projects and workspaces in a directory tree with metadata extraction",
-		schema:      schema,
-		executor:    executor,
-		parser:      parser,
-		logger:      logger,
+		name:         "discover_projects",
+		description:  "Discover Xcode projects and workspaces in a directory tree with metadata extraction",
+		schema:       schema,
+		outputSchema: types.SchemaFor(types.DiscoveryResult{}),
+		executor:     executor,
+		parser:       parser,
+		logger:       logger,
 	}
 }
 
@@ -70,10 +72,14 @@ func (t *DiscoverProjectsTool) InputSchema() map[string]interface{} {
 	return t.schema
 }
 
-func (t *DiscoverProjectsTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+func (t *DiscoverProjectsTool) OutputSchema() map[string]interface{} {
+	return t.outputSchema
+}
+
+func (t *DiscoverProjectsTool) Execute(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error) {
 	discoveryParams, err := t.parseParams(args)
 	if err != nil {
-		return "", fmt.Errorf("invalid parameters: %w", err)
+		return nil, toolError(err, types.ErrCodeInvalidParams, "invalid parameters", nil)
 	}
 
 	start := time.Now()
@@ -82,7 +88,7 @@ func (t *DiscoverProjectsTool) Execute(ctx context.Context, args map[string]inte
 	if discoveryParams.RootPath == "" {
 		cwd, err := os.Getwd()
 		if err != nil {
-			return "", fmt.Errorf("failed to get current directory: %w", err)
+			return nil, toolError(err, types.ErrCodeInternal, "failed to get current directory", nil)
 		}
 		discoveryParams.RootPath = cwd
 	}
@@ -91,7 +97,12 @@ func (t *DiscoverProjectsTool) Execute(ctx context.Context, args map[string]inte
 
 	projects, err := t.discoverProjects(ctx, *discoveryParams)
 	if err != nil {
-		return "", fmt.Errorf("project discovery failed: %w", err)
+		code := types.ErrCodeInternal
+		if errors.Is(err, fs.ErrNotExist) {
+			code = types.ErrCodeProjectNotFound
+		}
+		return nil, toolError(err, code, "project discovery failed",
+			map[string]interface{}{"root_path": discoveryParams.RootPath})
 	}
 
 	duration := time.Since(start)
@@ -102,13 +113,7 @@ func (t *DiscoverProjectsTool) Execute(ctx context.Context, args map[string]inte
 		Duration: duration,
 	}
 
-	// Convert result to JSON string
-	resultJSON, err := json.Marshal(result)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal result: %w", err)
-	}
-
-	return string(resultJSON), nil
+	return types.NewToolResult(result, false)
 }
 
 func (t *DiscoverProjectsTool) discoverProjects(ctx context.Context, params types.ProjectDiscovery) ([]types.ProjectInfo, error) {
@@ -300,7 +305,7 @@ func (t *DiscoverProjectsTool) parseParams(args map[string]interface{}) (*types.
 		} else if depth, ok := maxDepth.(int); ok {
 			params.MaxDepth = depth
 		} else {
-			return nil, fmt.Errorf("max_depth must be a number")
+			return nil, invalidParams("max_depth must be a number", map[string]interface{}{"parameter": "max_depth"})
 		}
 	}
 
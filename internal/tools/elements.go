@@ -0,0 +1,274 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jontolof/xcode-build-mcp/internal/accessibility"
+	"github.com/jontolof/xcode-build-mcp/internal/xcode"
+	"github.com/jontolof/xcode-build-mcp/pkg/types"
+)
+
+const (
+	defaultMaxScrolls = 10
+	scrollDuration    = 300 * time.Millisecond
+)
+
+// scrollSettle lets scrolling momentum stop before the screen is re-read
+var scrollSettle = 500 * time.Millisecond
+
+// scrollableTypes are element types that scroll their children
+var scrollableTypes = map[string]bool{
+	"ScrollView":     true,
+	"Table":          true,
+	"CollectionView": true,
+	"WebView":        true,
+}
+
+// elementQuerySchema describes the predicate parameter shared by the
+// element-based tools
+func elementQuerySchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "object",
+		"description": "Element predicate; every field given must match (case-insensitive)",
+		"properties": map[string]interface{}{
+			"identifier":     map[string]interface{}{"type": "string", "description": "Accessibility identifier"},
+			"label":          map[string]interface{}{"type": "string", "description": "Exact accessibility label"},
+			"label_contains": map[string]interface{}{"type": "string", "description": "Substring of the accessibility label"},
+			"type":           map[string]interface{}{"type": "string", "description": "Element type, e.g. Button, TextField, Cell"},
+			"value":          map[string]interface{}{"type": "string", "description": "Accessibility value"},
+			"traits": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Traits the element must have, e.g. button, selected",
+			},
+			"enabled": map[string]interface{}{"type": "boolean", "description": "Require the element to be enabled or disabled"},
+			"index":   map[string]interface{}{"type": "integer", "description": "Which match to use when several match (0-based, depth-first)"},
+		},
+	}
+}
+
+// parseElementQuery builds a query from the element_id and label shorthands
+// and the predicate object. The shorthands override the predicate's fields.
+func parseElementQuery(args map[string]interface{}) (types.ElementQuery, error) {
+	var query types.ElementQuery
+
+	if predicate, exists := args["predicate"]; exists && predicate != nil {
+		data, err := json.Marshal(predicate)
+		if err != nil {
+			return query, invalidParams("predicate must be an object", map[string]interface{}{"parameter": "predicate"})
+		}
+		decoder := json.NewDecoder(strings.NewReader(string(data)))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&query); err != nil {
+			return query, invalidParams(fmt.Sprintf("invalid predicate: %v", err), map[string]interface{}{"parameter": "predicate"})
+		}
+	}
+
+	identifier, err := parseStringParam(args, "element_id", false)
+	if err != nil {
+		return query, err
+	}
+	if identifier != "" {
+		query.Identifier = identifier
+	}
+
+	label, err := parseStringParam(args, "label", false)
+	if err != nil {
+		return query, err
+	}
+	if label != "" {
+		query.Label = label
+	}
+
+	return query, nil
+}
+
+// findElement reads the screen and returns the element matching query,
+// together with the screen it was found on and the number of matches
+func findElement(ctx context.Context, executor *xcode.Executor, backend accessibility.Backend, udid string, query types.ElementQuery) (*types.UIElement, *types.UIElement, int, error) {
+	root, err := describeScreen(ctx, executor, backend, udid)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	matches := root.Find(query)
+	if query.Index >= len(matches) {
+		return nil, root, len(matches), elementNotFound(query, len(matches))
+	}
+	return matches[query.Index], root, len(matches), nil
+}
+
+// leafCopy returns element without its children, to report which element
+// was acted on without repeating its subtree
+func leafCopy(element *types.UIElement) *types.UIElement {
+	leaf := *element
+	leaf.Children = nil
+	return &leaf
+}
+
+func elementNotFound(query types.ElementQuery, matches int) error {
+	message := "no element matches " + query.String()
+	if matches > 0 {
+		message = fmt.Sprintf("only %d elements match %s", matches, query.String())
+	}
+	return types.NewXcodeError(types.ErrCodeElementNotFound, message,
+		map[string]interface{}{"query": query, "matches": matches})
+}
+
+// hittablePoint returns the center of element, or ELEMENT_NOT_FOUND when the
+// center lies outside the screen and a touch there would miss
+func hittablePoint(root, element *types.UIElement, query types.ElementQuery) (float64, float64, error) {
+	x, y := element.Frame.Center()
+	if !onScreen(root, element) {
+		return 0, 0, types.NewXcodeError(types.ErrCodeElementNotFound,
+			fmt.Sprintf("element matching %s is off screen at (%.0f, %.0f); use scroll_to first", query.String(), x, y),
+			map[string]interface{}{"query": query, "frame": element.Frame, "offscreen": true})
+	}
+	return x, y, nil
+}
+
+// onScreen reports whether the element's center is inside the root frame.
+// A root without a frame is treated as the whole screen.
+func onScreen(root, element *types.UIElement) bool {
+	if root.Frame.Width == 0 || root.Frame.Height == 0 {
+		return true
+	}
+	return root.Frame.Contains(element.Frame.Center())
+}
+
+// scrollToElement swipes until the element matching query is on screen. A
+// known but off-screen element is scrolled towards; one not in the hierarchy
+// yet, as in lazily loaded lists, is searched for in direction. It returns
+// the element and the number of swipes made.
+func scrollToElement(ctx context.Context, executor *xcode.Executor, backend accessibility.Backend, udid string, query types.ElementQuery,
+	direction string, maxScrolls int) (*types.UIElement, int, error) {
+
+	if direction == "" {
+		direction = "down"
+	}
+	if maxScrolls <= 0 {
+		maxScrolls = defaultMaxScrolls
+	}
+
+	for scrolls := 0; ; scrolls++ {
+		element, root, matches, err := findElement(ctx, executor, backend, udid, query)
+		if err != nil && !types.IsXcodeError(err, types.ErrCodeElementNotFound) {
+			return nil, scrolls, err
+		}
+		if element != nil && onScreen(root, element) {
+			return element, scrolls, nil
+		}
+		if scrolls == maxScrolls {
+			return nil, scrolls, types.NewXcodeError(types.ErrCodeElementNotFound,
+				fmt.Sprintf("element matching %s not on screen after %d scrolls", query.String(), scrolls),
+				map[string]interface{}{"query": query, "matches": matches, "scrolls": scrolls})
+		}
+
+		swipeDirection := direction
+		container := scrollContainer(root, element)
+		if element != nil {
+			swipeDirection = directionTowards(root.Frame, element.Frame)
+		}
+
+		fromX, fromY, toX, toY := scrollSwipe(visibleFrame(root, container), swipeDirection)
+		if err := backend.Swipe(ctx, udid, fromX, fromY, toX, toY, scrollDuration); err != nil {
+			return nil, scrolls, toolError(err, types.ErrCodeCommandFailed, "failed to scroll", nil)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, scrolls + 1, ctx.Err()
+		case <-time.After(scrollSettle):
+		}
+	}
+}
+
+// scrollContainer returns the nearest scrollable ancestor of element, or the
+// largest scrollable element on screen when element is unknown, or root
+func scrollContainer(root, element *types.UIElement) *types.UIElement {
+	if element != nil {
+		path := pathTo(root, element)
+		for i := len(path) - 2; i >= 0; i-- {
+			if scrollableTypes[path[i].Type] {
+				return path[i]
+			}
+		}
+		return root
+	}
+
+	container := root
+	largest := 0.0
+	root.Walk(func(e *types.UIElement, depth int) bool {
+		if area := e.Frame.Width * e.Frame.Height; scrollableTypes[e.Type] && area > largest {
+			container, largest = e, area
+		}
+		return true
+	})
+	return container
+}
+
+// pathTo returns the elements from root down to target, or nil
+func pathTo(root, target *types.UIElement) []*types.UIElement {
+	if root == target {
+		return []*types.UIElement{root}
+	}
+	for _, child := range root.Children {
+		if path := pathTo(child, target); path != nil {
+			return append([]*types.UIElement{root}, path...)
+		}
+	}
+	return nil
+}
+
+// visibleFrame clips the container to the screen
+func visibleFrame(root, container *types.UIElement) types.UIFrame {
+	frame, screen := container.Frame, root.Frame
+	if screen.Width == 0 || screen.Height == 0 {
+		return frame
+	}
+
+	left, top := max(frame.X, screen.X), max(frame.Y, screen.Y)
+	right := min(frame.X+frame.Width, screen.X+screen.Width)
+	bottom := min(frame.Y+frame.Height, screen.Y+screen.Height)
+	if right <= left || bottom <= top {
+		return screen
+	}
+	return types.UIFrame{X: left, Y: top, Width: right - left, Height: bottom - top}
+}
+
+// directionTowards names the direction to scroll to bring target on screen
+func directionTowards(screen, target types.UIFrame) string {
+	x, y := target.Center()
+	switch {
+	case y >= screen.Y+screen.Height:
+		return "down"
+	case y < screen.Y:
+		return "up"
+	case x >= screen.X+screen.Width:
+		return "right"
+	default:
+		return "left"
+	}
+}
+
+// scrollSwipe returns a swipe across the middle half of frame that scrolls
+// the content in direction: scrolling down drags the finger upwards
+func scrollSwipe(frame types.UIFrame, direction string) (float64, float64, float64, float64) {
+	cx, cy := frame.Center()
+	dx, dy := frame.Width/4, frame.Height/4
+
+	switch direction {
+	case "up":
+		return cx, cy - dy, cx, cy + dy
+	case "left":
+		return cx - dx, cy, cx + dx, cy
+	case "right":
+		return cx + dx, cy, cx - dx, cy
+	default:
+		return cx, cy + dy, cx, cy - dy
+	}
+}
@@ -0,0 +1,405 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jontolof/xcode-build-mcp/internal/accessibility"
+	"github.com/jontolof/xcode-build-mcp/pkg/types"
+)
+
+// Step statuses in a run_ui_flow report
+const (
+	StepPassed  = "passed"
+	StepFailed  = "failed"
+	StepSkipped = "skipped"
+)
+
+// flowInteractActions are the steps run by ui_interact
+var flowInteractActions = map[string]bool{
+	"tap":        true,
+	"double_tap": true,
+	"long_press": true,
+	"swipe":      true,
+	"type":       true,
+	"scroll_to":  true,
+	"home":       true,
+	"shake":      true,
+	"rotate":     true,
+}
+
+// RunUIFlow runs a list of steps against one simulator in a single call.
+// Each step is the arguments of the tool that performs it, so a step behaves
+// exactly like the corresponding launch_app, ui_interact, wait_for_element or
+// screenshot call.
+type RunUIFlow struct {
+	name         string
+	description  string
+	schema       map[string]interface{}
+	outputSchema map[string]interface{}
+	launch       *LaunchAppTool
+	interact     *UIInteract
+	wait         *WaitForElement
+	screenshot   *Screenshot
+	backend      accessibility.Backend
+}
+
+func NewRunUIFlow(launch *LaunchAppTool, interact *UIInteract, wait *WaitForElement, screenshot *Screenshot) *RunUIFlow {
+	schema := createJSONSchema("object", map[string]interface{}{
+		"udid": map[string]interface{}{
+			"type":        "string",
+			"description": "UDID of the target simulator (optional for auto-detection)",
+		},
+		"bundle_id": map[string]interface{}{
+			"type":        "string",
+			"description": "Bundle identifier used by launch steps that do not name one",
+		},
+		"screenshot_dir": map[string]interface{}{
+			"type":        "string",
+			"description": "Directory for screenshots taken by screenshot steps - default: screenshots",
+		},
+		"include_images": map[string]interface{}{
+			"type":        "boolean",
+			"description": "Attach the screenshots as image content",
+			"default":     true,
+		},
+		"steps": map[string]interface{}{
+			"type":        "array",
+			"description": "Steps to run in order; the flow stops at the first failing step",
+			"items": map[string]interface{}{
+				"type":        "object",
+				"description": "A step: action plus the parameters of the tool that runs it. launch takes launch_app parameters; tap, double_tap, long_press, swipe, type, scroll_to, home, shake and rotate take ui_interact parameters; wait takes wait_for_element parameters; assert checks an element once (element_id, label or predicate, and condition); screenshot takes screenshot parameters. name labels the step in the report.",
+				"properties": map[string]interface{}{
+					"action": map[string]interface{}{
+						"type": "string",
+						"enum": []string{"launch", "tap", "double_tap", "long_press", "swipe", "type", "scroll_to",
+							"home", "shake", "rotate", "wait", "assert", "screenshot"},
+					},
+					"name": map[string]interface{}{"type": "string"},
+				},
+				"required": []string{"action"},
+			},
+		},
+	}, []string{"steps"})
+
+	return &RunUIFlow{
+		name:         "run_ui_flow",
+		description:  "Run a scripted UI flow (launch, tap, type, wait, assert, screenshot) against one simulator in a single call, stopping at the first failure and reporting each step with its timing and screenshots",
+		schema:       schema,
+		outputSchema: types.SchemaFor(types.UIFlowResult{}),
+		launch:       launch,
+		interact:     interact,
+		wait:         wait,
+		screenshot:   screenshot,
+		backend:      interact.backend,
+	}
+}
+
+func (t *RunUIFlow) Name() string {
+	return t.name
+}
+
+func (t *RunUIFlow) Description() string {
+	return t.description
+}
+
+func (t *RunUIFlow) InputSchema() map[string]interface{} {
+	return t.schema
+}
+
+func (t *RunUIFlow) OutputSchema() map[string]interface{} {
+	return t.outputSchema
+}
+
+func (t *RunUIFlow) Execute(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error) {
+	p, err := t.parseParams(args)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+
+	// Auto-select device if not specified
+	if p.UDID == "" {
+		simulator, err := selectBestSimulator(t.launch.executor, "")
+		if err != nil {
+			errorResult := &types.UIFlowResult{
+				Success:  false,
+				Duration: time.Since(start),
+			}
+			partial, _ := types.NewToolResult(errorResult, true)
+			return partial, toolError(err, types.ErrCodeSimulatorNotFound, "failed to auto-select device", nil)
+		}
+		p.UDID = simulator.UDID
+	}
+
+	// Check the arguments of every step before running any, so a bad
+	// parameter in a later step does not leave the app half way through
+	prefix := "ui_flow_" + start.Format("2006-01-02_15-04-05")
+	stepArgs := make([]map[string]interface{}, len(p.Steps))
+	for i, step := range p.Steps {
+		action := strings.ToLower(step["action"].(string))
+		stepArgs[i] = t.stepArgs(p, i, step, action, prefix)
+		if err := t.checkStep(action, stepArgs[i]); err != nil {
+			return nil, toolError(err, types.ErrCodeInvalidParams, fmt.Sprintf("step %d (%s) is invalid", i+1, action),
+				map[string]interface{}{"parameter": "steps", "step": i})
+		}
+	}
+
+	result := &types.UIFlowResult{UDID: p.UDID}
+	var (
+		images  []types.ToolContent
+		failure error
+	)
+
+	for i, step := range p.Steps {
+		action := strings.ToLower(step["action"].(string))
+		name, _ := step["name"].(string)
+		report := types.UIFlowStepResult{Index: i, Action: action, Name: name}
+
+		if failure != nil {
+			report.Status = StepSkipped
+			result.Skipped++
+			result.Steps = append(result.Steps, report)
+			continue
+		}
+
+		stepStart := time.Now()
+		var content []types.ToolContent
+		switch action {
+		case "launch":
+			content, err = t.runTool(ctx, t.launch, stepArgs[i], &report)
+		case "wait":
+			content, err = t.runTool(ctx, t.wait, stepArgs[i], &report)
+		case "assert":
+			err = t.assert(ctx, p.UDID, stepArgs[i], &report)
+		case "screenshot":
+			content, err = t.runTool(ctx, t.screenshot, stepArgs[i], &report)
+		default:
+			content, err = t.runTool(ctx, t.interact, stepArgs[i], &report)
+		}
+		report.Duration = time.Since(stepStart)
+
+		// Images follow the JSON text item of the step's own result
+		for _, item := range content {
+			if item.Type == "image" {
+				images = append(images, item)
+			}
+		}
+
+		if err != nil {
+			report.Status = StepFailed
+			report.Error = errorData(err)
+			result.Failed++
+			failure = stepFailure(i, action, err)
+		} else {
+			report.Status = StepPassed
+			result.Passed++
+		}
+		result.Steps = append(result.Steps, report)
+	}
+
+	result.Success = failure == nil
+	result.Duration = time.Since(start)
+
+	toolResult, err := types.NewToolResult(result, !result.Success)
+	if err != nil {
+		return nil, err
+	}
+	toolResult.Content = append(toolResult.Content, images...)
+	return toolResult, failure
+}
+
+func (t *RunUIFlow) parseParams(args map[string]interface{}) (*types.UIFlowParams, error) {
+	p := &types.UIFlowParams{
+		ScreenshotDir: "screenshots",
+		IncludeImages: parseBoolParam(args, "include_images", true),
+	}
+
+	var err error
+	if p.UDID, err = parseStringParam(args, "udid", false); err != nil {
+		return nil, err
+	}
+	if p.BundleID, err = parseStringParam(args, "bundle_id", false); err != nil {
+		return nil, err
+	}
+	if dir, err := parseStringParam(args, "screenshot_dir", false); err != nil {
+		return nil, err
+	} else if dir != "" {
+		p.ScreenshotDir = dir
+	}
+
+	steps, err := parseArrayParam(args, "steps")
+	if err != nil {
+		return nil, err
+	}
+	if len(steps) == 0 {
+		return nil, invalidParams("steps must list at least one step", map[string]interface{}{"parameter": "steps"})
+	}
+
+	// Reject malformed steps before running any, so a typo in the last step
+	// does not leave the app half way through the flow
+	for i, raw := range steps {
+		step, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, invalidParams(fmt.Sprintf("step %d must be an object", i+1),
+				map[string]interface{}{"parameter": "steps", "step": i})
+		}
+		action, _ := step["action"].(string)
+		switch strings.ToLower(action) {
+		case "launch", "wait", "assert", "screenshot":
+		default:
+			if !flowInteractActions[strings.ToLower(action)] {
+				return nil, invalidParams(fmt.Sprintf("step %d has unsupported action %q", i+1, action),
+					map[string]interface{}{"parameter": "steps", "step": i})
+			}
+		}
+		p.Steps = append(p.Steps, step)
+	}
+
+	return p, nil
+}
+
+// stepArgs returns the arguments of the tool that runs a step: the step
+// itself plus the flow's device, bundle and screenshot settings
+func (t *RunUIFlow) stepArgs(p *types.UIFlowParams, i int, step map[string]interface{}, action, prefix string) map[string]interface{} {
+	args := make(map[string]interface{}, len(step)+2)
+	for k, v := range step {
+		args[k] = v
+	}
+	args["udid"] = p.UDID
+
+	switch action {
+	case "launch":
+		if _, exists := args["bundle_id"]; !exists && p.BundleID != "" {
+			args["bundle_id"] = p.BundleID
+		}
+	case "screenshot":
+		if _, exists := args["output_path"]; !exists {
+			args["output_path"] = filepath.Join(p.ScreenshotDir, fmt.Sprintf("%s_step%02d", prefix, i+1))
+		}
+		if _, exists := args["include_image"]; !exists {
+			args["include_image"] = p.IncludeImages
+		}
+	}
+	return args
+}
+
+// checkStep parses a step's arguments the way the tool that runs it will
+func (t *RunUIFlow) checkStep(action string, args map[string]interface{}) error {
+	var err error
+	switch action {
+	case "launch":
+		_, err = t.launch.parseParams(args)
+	case "wait":
+		_, err = t.wait.parseParams(args)
+	case "assert":
+		_, _, err = parseAssertion(args)
+	case "screenshot":
+		_, err = t.screenshot.parseParams(args)
+	default:
+		_, err = t.interact.parseParams(args)
+	}
+	return err
+}
+
+// stepFailure is the flow's error for a step that failed while running. It
+// is ASSERTION_FAILED for a failed assertion and COMMAND_FAILED otherwise,
+// never INVALID_PARAMS, so the report of the steps that ran reaches the
+// client instead of being replaced by a protocol error.
+func stepFailure(i int, action string, err error) error {
+	code := types.ErrCodeCommandFailed
+	if types.IsXcodeError(err, types.ErrCodeAssertionFailed) {
+		code = types.ErrCodeAssertionFailed
+	}
+	return types.NewXcodeErrorWithCause(code, fmt.Sprintf("step %d (%s) failed", i+1, action), err,
+		map[string]interface{}{"step": i, "action": action})
+}
+
+// stepTool is the part of a tool that a flow step runs
+type stepTool interface {
+	Name() string
+	Execute(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error)
+}
+
+// runTool executes the tool for a step and copies what the report needs from
+// its structured result
+func (t *RunUIFlow) runTool(ctx context.Context, tool stepTool, args map[string]interface{}, report *types.UIFlowStepResult) ([]types.ToolContent, error) {
+	toolResult, err := tool.Execute(ctx, args)
+	if toolResult == nil {
+		return nil, err
+	}
+
+	switch result := toolResult.Structured.(type) {
+	case *types.AppLaunchResult:
+		report.Output = strings.TrimSpace(result.Output)
+	case *types.UIInteractResult:
+		report.Output = result.Output
+		report.Element = result.Element
+	case *types.WaitForElementResult:
+		report.Output = result.Output
+		report.Element = result.Element
+	case *types.ScreenshotResult:
+		report.Screenshot = result.FilePath
+	}
+
+	if err == nil && toolResult.IsError {
+		err = types.NewXcodeError(types.ErrCodeCommandFailed, tool.Name()+" reported a failure", nil)
+	}
+	return toolResult.Content, err
+}
+
+// assert checks the condition of an element once, without waiting
+func (t *RunUIFlow) assert(ctx context.Context, udid string, args map[string]interface{}, report *types.UIFlowStepResult) error {
+	query, condition, err := parseAssertion(args)
+	if err != nil {
+		return err
+	}
+
+	element, _, matches, err := findElement(ctx, t.launch.executor, t.backend, udid, query)
+	if err != nil && !types.IsXcodeError(err, types.ErrCodeElementNotFound) {
+		return err
+	}
+	if element != nil {
+		report.Element = leafCopy(element)
+	}
+
+	if !conditionMet(condition, element) {
+		details := map[string]interface{}{"query": query, "condition": condition, "matches": matches}
+		if element != nil {
+			details["element"] = report.Element
+		}
+		return types.NewXcodeError(types.ErrCodeAssertionFailed,
+			fmt.Sprintf("element matching %s did not %s", query.String(), conditionVerb(condition)), details)
+	}
+
+	report.Output = fmt.Sprintf("Element matching %s: %s", query.String(), condition)
+	return nil
+}
+
+// parseAssertion reads the element query and condition of an assert step
+func parseAssertion(args map[string]interface{}) (types.ElementQuery, string, error) {
+	query, err := parseElementQuery(args)
+	if err != nil {
+		return query, "", err
+	}
+	if query.IsZero() {
+		return query, "", invalidParams("assert requires element_id, label or predicate",
+			map[string]interface{}{"parameters": []string{"element_id", "label", "predicate"}})
+	}
+	condition, err := parseCondition(args)
+	return query, condition, err
+}
+
+// errorData returns the structured form of a step error for the report
+func errorData(err error) map[string]interface{} {
+	var xerr *types.XcodeError
+	if errors.As(err, &xerr) {
+		return xerr.Data()
+	}
+	return map[string]interface{}{"message": err.Error()}
+}
@@ -0,0 +1,185 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jontolof/xcode-build-mcp/internal/accessibility"
+	"github.com/jontolof/xcode-build-mcp/internal/xcode"
+	"github.com/jontolof/xcode-build-mcp/pkg/types"
+)
+
+// newFixtureRunUIFlow runs flows on the login screen recorded from AXe and
+// returns the backend so tests can check the inputs it received
+func newFixtureRunUIFlow() (*RunUIFlow, *accessibility.FixtureBackend) {
+	backend := accessibility.NewFixtureBackend("../accessibility/testdata/axe_login.json")
+	logger := &testLogger{}
+	launch := NewLaunchAppTool(xcode.NewExecutor(logger), xcode.NewParser(), logger)
+	return NewRunUIFlow(launch, NewUIInteract(xcode.NewExecutor(&testLogger{}), backend), NewWaitForElement(xcode.NewExecutor(&testLogger{}), backend), NewScreenshot(xcode.NewExecutor(&testLogger{}))), backend
+}
+
+func TestRunUIFlow_Name(t *testing.T) {
+	tool, _ := newFixtureRunUIFlow()
+	if got := tool.Name(); got != "run_ui_flow" {
+		t.Errorf("RunUIFlow.Name() = %v, want %v", got, "run_ui_flow")
+	}
+	if len(tool.Description()) < 20 {
+		t.Errorf("RunUIFlow.Description() too short: %s", tool.Description())
+	}
+}
+
+func TestRunUIFlow_Steps(t *testing.T) {
+	tool, backend := newFixtureRunUIFlow()
+
+	toolResult, err := tool.Execute(context.Background(), map[string]interface{}{
+		"udid": "test-udid",
+		"steps": []interface{}{
+			map[string]interface{}{"action": "type", "element_id": "login.email", "text": "user@example.com"},
+			map[string]interface{}{"action": "type", "element_id": "login.password", "text": "secret"},
+			map[string]interface{}{"action": "wait", "label": "Home", "name": "tab bar shown"},
+			map[string]interface{}{"action": "assert", "predicate": map[string]interface{}{"identifier": "login.submit", "enabled": false}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Flow failed: %v", err)
+	}
+
+	result := toolResult.Structured.(*types.UIFlowResult)
+	if !result.Success || result.Passed != 4 || len(result.Steps) != 4 {
+		t.Fatalf("Expected 4 passed steps, got %+v", result)
+	}
+	if step := result.Steps[2]; step.Action != "wait" || step.Name != "tab bar shown" || step.Element == nil || step.Element.Identifier != "tab.home" {
+		t.Errorf("Unexpected wait step: %+v", step)
+	}
+	if step := result.Steps[3]; step.Element == nil || step.Element.Identifier != "login.submit" {
+		t.Errorf("Expected the asserted element in the report, got %+v", step)
+	}
+
+	// Each type step taps the field to focus it, then types
+	var kinds []string
+	for _, input := range backend.Inputs() {
+		kinds = append(kinds, input.Kind)
+	}
+	if got := strings.Join(kinds, ","); got != "tap,type,tap,type" {
+		t.Errorf("Unexpected inputs: %s", got)
+	}
+}
+
+func TestRunUIFlow_StopsAtFirstFailure(t *testing.T) {
+	tool, backend := newFixtureRunUIFlow()
+
+	toolResult, err := tool.Execute(context.Background(), map[string]interface{}{
+		"udid": "test-udid",
+		"steps": []interface{}{
+			map[string]interface{}{"action": "tap", "element_id": "login.email"},
+			map[string]interface{}{"action": "assert", "element_id": "login.submit", "condition": "enabled"},
+			map[string]interface{}{"action": "tap", "element_id": "login.submit"},
+		},
+	})
+	if !types.IsXcodeError(err, types.ErrCodeAssertionFailed) {
+		t.Fatalf("Expected ASSERTION_FAILED, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "step 2 (assert) failed") {
+		t.Errorf("Expected the failing step in the error, got %v", err)
+	}
+	if !toolResult.IsError {
+		t.Error("Expected isError for a failed flow")
+	}
+
+	result := toolResult.Structured.(*types.UIFlowResult)
+	statuses := []string{StepPassed, StepFailed, StepSkipped}
+	for i, step := range result.Steps {
+		if step.Status != statuses[i] {
+			t.Errorf("Step %d: expected %s, got %s", i, statuses[i], step.Status)
+		}
+	}
+	if result.Steps[1].Error["code"] != types.ErrCodeAssertionFailed {
+		t.Errorf("Expected the error in the step report, got %+v", result.Steps[1].Error)
+	}
+	if inputs := backend.Inputs(); len(inputs) != 1 {
+		t.Errorf("Expected only the first tap, got %+v", inputs)
+	}
+}
+
+func TestRunUIFlow_InvalidSteps(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps interface{}
+	}{
+		{"missing", nil},
+		{"empty", []interface{}{}},
+		{"not an object", []interface{}{"tap"}},
+		{"unknown action", []interface{}{
+			map[string]interface{}{"action": "tap", "element_id": "login.email"},
+			map[string]interface{}{"action": "pinch"},
+		}},
+		{"bad step arguments", []interface{}{
+			map[string]interface{}{"action": "tap", "element_id": "login.email"},
+			map[string]interface{}{"action": "tap"},
+			map[string]interface{}{"action": "tap", "element_id": "login.submit"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tool, backend := newFixtureRunUIFlow()
+			args := map[string]interface{}{"udid": "test-udid"}
+			if tt.steps != nil {
+				args["steps"] = tt.steps
+			}
+
+			_, err := tool.Execute(context.Background(), args)
+			if !types.IsXcodeError(err, types.ErrCodeInvalidParams) {
+				t.Errorf("Expected INVALID_PARAMS, got %v", err)
+			}
+			if len(backend.Inputs()) != 0 {
+				t.Error("No step should run when the flow is invalid")
+			}
+		})
+	}
+}
+
+func TestRunUIFlow_StepErrorKeepsReport(t *testing.T) {
+	tool, backend := newFixtureRunUIFlow()
+
+	toolResult, err := tool.Execute(context.Background(), map[string]interface{}{
+		"udid": "test-udid",
+		"steps": []interface{}{
+			map[string]interface{}{"action": "tap", "element_id": "login.email"},
+			map[string]interface{}{"action": "tap", "element_id": "no.such.element"},
+			map[string]interface{}{"action": "tap", "element_id": "login.submit"},
+		},
+	})
+	if !types.IsXcodeError(err, types.ErrCodeCommandFailed) || types.IsXcodeError(err, types.ErrCodeInvalidParams) {
+		t.Fatalf("Expected COMMAND_FAILED, got %v", err)
+	}
+	if toolResult == nil || !toolResult.IsError {
+		t.Fatal("Expected the flow report with isError for a failed step")
+	}
+
+	result := toolResult.Structured.(*types.UIFlowResult)
+	if result.Passed != 1 || result.Failed != 1 || result.Skipped != 1 {
+		t.Errorf("Expected one passed, failed and skipped step, got %+v", result)
+	}
+	if result.Steps[1].Error["code"] != types.ErrCodeElementNotFound {
+		t.Errorf("Expected the step's own error in the report, got %+v", result.Steps[1].Error)
+	}
+	if inputs := backend.Inputs(); len(inputs) != 1 {
+		t.Errorf("Expected only the first tap, got %+v", inputs)
+	}
+}
+
+func TestStepFailure(t *testing.T) {
+	// A step that only fails on its parameters once running must not turn
+	// the flow into a protocol error
+	err := stepFailure(1, "tap", invalidParams("bad coordinates", nil))
+	if !types.IsXcodeError(err, types.ErrCodeCommandFailed) {
+		t.Errorf("Expected COMMAND_FAILED, got %v", err)
+	}
+
+	err = stepFailure(1, "assert", types.NewXcodeError(types.ErrCodeAssertionFailed, "not enabled", nil))
+	if !types.IsXcodeError(err, types.ErrCodeAssertionFailed) {
+		t.Errorf("Expected ASSERTION_FAILED, got %v", err)
+	}
+}
@@ -4,30 +4,190 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"os/exec"
+	"regexp"
+	"strings"
 	"time"
 
+	"github.com/jontolof/xcode-build-mcp/internal/common"
+	"github.com/jontolof/xcode-build-mcp/internal/xcode"
 	"github.com/jontolof/xcode-build-mcp/pkg/types"
 )
 
+// progressOptions streams command output into a ProgressTracker when the
+// caller asked for progress notifications
+func progressOptions(ctx context.Context) []xcode.ExecOption {
+	report := common.ProgressFromContext(ctx)
+	if report == nil {
+		return nil
+	}
+	return []xcode.ExecOption{xcode.WithLineHandler(xcode.NewProgressTracker(report).Observe)}
+}
+
+// invalidParams reports a problem with the tool arguments
+func invalidParams(message string, details map[string]interface{}) error {
+	return types.NewXcodeError(types.ErrCodeInvalidParams, message, details)
+}
+
+// toolError converts err to an XcodeError with the given code. An error that
+// already carries a code keeps it, gaining the message as context and any
+// missing details, so a SIMULATOR_NOT_FOUND from a helper is not hidden
+// behind the caller's more generic code.
+func toolError(err error, code types.ErrorCode, message string, details map[string]interface{}) error {
+	xerr := types.ExtractXcodeError(err)
+	if xerr == nil {
+		return types.NewXcodeErrorWithCause(code, message, err, details)
+	}
+
+	merged := make(map[string]interface{}, len(xerr.Details)+len(details))
+	for k, v := range details {
+		merged[k] = v
+	}
+	for k, v := range xerr.Details {
+		merged[k] = v
+	}
+
+	return &types.XcodeError{
+		Code:    xerr.Code,
+		Message: message + ": " + xerr.Message,
+		Details: merged,
+		Cause:   xerr.Cause,
+	}
+}
+
+// commandDetails describes a failed command for XcodeError details
+func commandDetails(command []string, exitCode int, output string) map[string]interface{} {
+	return map[string]interface{}{
+		"command":   strings.Join(command, " "),
+		"exit_code": exitCode,
+		"output":    strings.TrimSpace(output),
+	}
+}
+
+// commandFailed reports a command that ran and exited unsuccessfully
+func commandFailed(message string, args []string, output []byte, err error) error {
+	exitCode := -1
+	if exitErr, ok := err.(*xcode.ExitError); ok {
+		exitCode = exitErr.ExitCode
+	}
+	return types.NewXcodeErrorWithCause(types.ErrCodeCommandFailed, message, err,
+		commandDetails(args, exitCode, string(output)))
+}
+
+var (
+	schemeNotFoundPattern      = regexp.MustCompile(`does not contain a scheme named "([^"]+)"`)
+	projectNotFoundPattern     = regexp.MustCompile(`xcodebuild: error: '([^']+)' does not exist`)
+	destinationNotFoundPattern = regexp.MustCompile(`Unable to find a destination matching the provided destination specifier:\s*\{([^}]*)\}`)
+)
+
+// xcodebuildSetupError recognizes runs where xcodebuild rejected the project,
+// scheme or destination before building anything, and returns nil otherwise
+func xcodebuildSetupError(output string) error {
+	if m := schemeNotFoundPattern.FindStringSubmatch(output); m != nil {
+		return types.NewXcodeError(types.ErrCodeSchemeNotFound, fmt.Sprintf("scheme %q not found", m[1]),
+			map[string]interface{}{"scheme": m[1]})
+	}
+	if m := projectNotFoundPattern.FindStringSubmatch(output); m != nil {
+		return types.NewXcodeError(types.ErrCodeProjectNotFound, fmt.Sprintf("%s does not exist", m[1]),
+			map[string]interface{}{"path": m[1]})
+	}
+	if m := destinationNotFoundPattern.FindStringSubmatch(output); m != nil {
+		destination := strings.TrimSpace(m[1])
+		return types.NewXcodeError(types.ErrCodeSimulatorNotFound, "no destination matches "+destination,
+			map[string]interface{}{"destination": destination})
+	}
+	return nil
+}
+
+// ensureDeviceBooted returns SIMULATOR_NOT_FOUND or SIMULATOR_NOT_BOOTED unless
+// the simulator with the given UDID is booted
+func ensureDeviceBooted(ctx context.Context, executor *xcode.Executor, udid string) error {
+	output, err := executor.Output(ctx, "xcrun", "simctl", "list", "devices", "--json")
+	if err != nil {
+		return types.NewXcodeErrorWithCause(types.ErrCodeCommandFailed, "failed to check device state", err, nil)
+	}
+
+	var deviceList struct {
+		Devices map[string][]struct {
+			UDID  string `json:"udid"`
+			State string `json:"state"`
+		} `json:"devices"`
+	}
+
+	if err := json.Unmarshal(output, &deviceList); err != nil {
+		return types.NewXcodeErrorWithCause(types.ErrCodeCommandFailed, "failed to parse device list", err, nil)
+	}
+
+	for _, devices := range deviceList.Devices {
+		for _, device := range devices {
+			if device.UDID == udid {
+				if device.State != "Booted" {
+					return types.NewXcodeError(types.ErrCodeSimulatorNotBooted, fmt.Sprintf("device is %s, not Booted", device.State),
+						map[string]interface{}{"udid": udid, "state": device.State})
+				}
+				return nil
+			}
+		}
+	}
+
+	return types.NewXcodeError(types.ErrCodeSimulatorNotFound, "device not found", map[string]interface{}{"udid": udid})
+}
+
+// commandEnv checks the environment variables of a call against the
+// executor's policy, giving each name prefix, and adds DEVELOPER_DIR for
+// developerDir, as returned by selectXcode. developerEnv is the
+// DEVELOPER_DIR entry alone, for the follow-up commands of the call.
+func commandEnv(executor *xcode.Executor, vars map[string]string, prefix, developerDir string) (env, developerEnv []string, err error) {
+	env, err = executor.EnvPolicy().CommandEnv(vars, prefix)
+	if err != nil {
+		return nil, nil, invalidParams(err.Error(), map[string]interface{}{"parameter": "environment"})
+	}
+	if developerDir != "" {
+		developerEnv = []string{"DEVELOPER_DIR=" + developerDir}
+	}
+	return append(developerEnv, env...), developerEnv, nil
+}
+
 // Helper functions for parameter parsing
 func parseStringParam(args map[string]interface{}, key string, required bool) (string, error) {
 	value, exists := args[key]
 	if !exists {
 		if required {
-			return "", fmt.Errorf("missing required parameter: %s", key)
+			return "", invalidParams(fmt.Sprintf("missing required parameter: %s", key), map[string]interface{}{"parameter": key})
 		}
 		return "", nil
 	}
 
 	str, ok := value.(string)
 	if !ok {
-		return "", fmt.Errorf("parameter %s must be a string", key)
+		return "", invalidParams(fmt.Sprintf("parameter %s must be a string", key), map[string]interface{}{"parameter": key})
 	}
 
 	return str, nil
 }
 
+// parseEnvironmentParam reads an object of environment variables, whose
+// values must be strings
+func parseEnvironmentParam(args map[string]interface{}, key string) (map[string]string, error) {
+	value, exists := args[key]
+	if !exists {
+		return map[string]string{}, nil
+	}
+	vars, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, invalidParams(fmt.Sprintf("parameter %s must be an object", key), map[string]interface{}{"parameter": key})
+	}
+	env := make(map[string]string, len(vars))
+	for name, v := range vars {
+		str, ok := v.(string)
+		if !ok {
+			return nil, invalidParams(fmt.Sprintf("parameter %s: value of %s must be a string", key, name),
+				map[string]interface{}{"parameter": key, "variable": name})
+		}
+		env[name] = str
+	}
+	return env, nil
+}
+
 func parseBoolParam(args map[string]interface{}, key string, defaultValue bool) bool {
 	value, exists := args[key]
 	if !exists {
@@ -50,12 +210,46 @@ func parseArrayParam(args map[string]interface{}, key string) ([]interface{}, er
 
 	array, ok := value.([]interface{})
 	if !ok {
-		return nil, fmt.Errorf("parameter %s must be an array", key)
+		return nil, invalidParams(fmt.Sprintf("parameter %s must be an array", key), map[string]interface{}{"parameter": key})
 	}
 
 	return array, nil
 }
 
+// parseStringArrayParam reads an array parameter whose items must all be
+// strings
+func parseStringArrayParam(args map[string]interface{}, key string) ([]string, error) {
+	array, err := parseArrayParam(args, key)
+	if err != nil {
+		return nil, err
+	}
+
+	strs := make([]string, 0, len(array))
+	for _, item := range array {
+		str, ok := item.(string)
+		if !ok {
+			return nil, invalidParams(fmt.Sprintf("parameter %s must be an array of strings", key), map[string]interface{}{"parameter": key})
+		}
+		strs = append(strs, str)
+	}
+	return strs, nil
+}
+
+// parseIntParam reads a whole number of at least min, returning 0 when the
+// parameter is absent
+func parseIntParam(args map[string]interface{}, key string, min int) (int, error) {
+	value, exists := args[key]
+	if !exists {
+		return 0, nil
+	}
+
+	num, ok := value.(float64)
+	if !ok || num != float64(int(num)) || int(num) < min {
+		return 0, invalidParams(fmt.Sprintf("parameter %s must be a whole number of at least %d", key, min), map[string]interface{}{"parameter": key})
+	}
+	return int(num), nil
+}
+
 func createJSONSchema(schemaType string, properties map[string]interface{}, required []string) map[string]interface{} {
 	schema := map[string]interface{}{
 		"type":       schemaType,
@@ -71,15 +265,15 @@ func createJSONSchema(schemaType string, properties map[string]interface{}, requ
 
 // selectBestSimulator is a shared helper function to auto-select a booted simulator
 // with proper timeout handling to prevent hanging in test environments
-func selectBestSimulator(platform string) (*types.SimulatorInfo, error) {
+func selectBestSimulator(executor *xcode.Executor, platform string) (*types.SimulatorInfo, error) {
 	// Add timeout to prevent hanging in test environments
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, "xcrun", "simctl", "list", "devices", "--json")
-	output, err := cmd.Output()
+	output, err := executor.Output(ctx, "xcrun", "simctl", "list", "devices", "--json")
 	if err != nil {
-		return nil, fmt.Errorf("failed to list simulators (timeout or command failed): %w", err)
+		return nil, types.NewXcodeErrorWithCause(types.ErrCodeCommandFailed, "failed to list simulators (timeout or command failed)", err,
+			map[string]interface{}{"command": "xcrun simctl list devices --json"})
 	}
 
 	var simList struct {
@@ -93,7 +287,7 @@ func selectBestSimulator(platform string) (*types.SimulatorInfo, error) {
 	}
 
 	if err := json.Unmarshal(output, &simList); err != nil {
-		return nil, fmt.Errorf("failed to parse simulator list: %w", err)
+		return nil, types.NewXcodeErrorWithCause(types.ErrCodeCommandFailed, "failed to parse simulator list", err, nil)
 	}
 
 	// Look for booted simulators first
@@ -112,5 +306,6 @@ func selectBestSimulator(platform string) (*types.SimulatorInfo, error) {
 		}
 	}
 
-	return nil, fmt.Errorf("no booted simulators found (this is expected in test environments)")
+	return nil, types.NewXcodeError(types.ErrCodeSimulatorNotBooted, "no booted simulators found (this is expected in test environments)",
+		map[string]interface{}{"platform": platform})
 }
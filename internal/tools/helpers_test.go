@@ -1,13 +1,20 @@
 package tools
 
 import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/jontolof/xcode-build-mcp/internal/xcode"
+	"github.com/jontolof/xcode-build-mcp/pkg/types"
 )
 
 func TestSelectBestSimulator(t *testing.T) {
 	// This test checks what selectBestSimulator actually returns
 	// Behavior depends on whether simulators are available
-	simulator, err := selectBestSimulator("")
+	simulator, err := selectBestSimulator(xcode.NewExecutor(&testLogger{}), "")
 
 	t.Logf("selectBestSimulator returned: simulator=%+v, err=%v", simulator, err)
 
@@ -29,3 +36,194 @@ func TestSelectBestSimulator(t *testing.T) {
 		t.Logf("Expected failure in environment without simulators: %v", err)
 	}
 }
+
+func TestToolError(t *testing.T) {
+	plain := toolError(errors.New("exit status 1"), types.ErrCodeCommandFailed, "simctl failed",
+		map[string]interface{}{"udid": "ABC"})
+	xerr := types.ExtractXcodeError(plain)
+	if xerr == nil || xerr.Code != types.ErrCodeCommandFailed {
+		t.Fatalf("Expected COMMAND_FAILED, got %v", plain)
+	}
+	if xerr.Details["udid"] != "ABC" {
+		t.Errorf("Expected details to be set, got %v", xerr.Details)
+	}
+
+	inner := types.NewXcodeError(types.ErrCodeSimulatorNotBooted, "device is Shutdown",
+		map[string]interface{}{"state": "Shutdown"})
+	wrapped := types.ExtractXcodeError(toolError(inner, types.ErrCodeCommandFailed, "failed to launch app",
+		map[string]interface{}{"udid": "ABC"}))
+	if wrapped.Code != types.ErrCodeSimulatorNotBooted {
+		t.Errorf("Expected the inner code to be kept, got %s", wrapped.Code)
+	}
+	if wrapped.Message != "failed to launch app: device is Shutdown" {
+		t.Errorf("Unexpected message: %s", wrapped.Message)
+	}
+	if wrapped.Details["state"] != "Shutdown" || wrapped.Details["udid"] != "ABC" {
+		t.Errorf("Expected merged details, got %v", wrapped.Details)
+	}
+}
+
+func TestXcodebuildSetupError(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		code   types.ErrorCode
+	}{
+		{
+			name:   "missing scheme",
+			output: `xcodebuild: error: The project named "App" does not contain a scheme named "Nope".`,
+			code:   types.ErrCodeSchemeNotFound,
+		},
+		{
+			name:   "missing project",
+			output: "xcodebuild: error: '/tmp/Missing.xcodeproj' does not exist.",
+			code:   types.ErrCodeProjectNotFound,
+		},
+		{
+			name: "missing destination",
+			output: "xcodebuild: error: Unable to find a destination matching the provided destination specifier:\n" +
+				"\t\t{ platform:iOS Simulator, name:iPhone 99 }",
+			code: types.ErrCodeSimulatorNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := xcodebuildSetupError(tt.output)
+			if !types.IsXcodeError(err, tt.code) {
+				t.Errorf("Expected %s, got %v", tt.code, err)
+			}
+		})
+	}
+
+	if err := xcodebuildSetupError("error: cannot find 'foo' in scope"); err != nil {
+		t.Errorf("Compiler errors are not setup errors, got %v", err)
+	}
+}
+
+func TestExecute_InvalidParamsCode(t *testing.T) {
+	tool := NewSimulatorControlTool(nil, nil, nil)
+	_, err := tool.Execute(context.Background(), map[string]interface{}{"udid": "ABC", "action": "explode"})
+	if !types.IsXcodeError(err, types.ErrCodeInvalidParams) {
+		t.Fatalf("Expected INVALID_PARAMS, got %v", err)
+	}
+	if xerr := types.ExtractXcodeError(err); xerr.Details["parameter"] != "action" {
+		t.Errorf("Expected details to name the parameter, got %v", xerr.Details)
+	}
+}
+
+func TestParseStringArrayParam(t *testing.T) {
+	got, err := parseStringArrayParam(map[string]interface{}{"tests": []interface{}{"A", "B/c"}}, "tests")
+	if err != nil || len(got) != 2 || got[0] != "A" || got[1] != "B/c" {
+		t.Errorf("parseStringArrayParam() = %v, %v", got, err)
+	}
+
+	if got, err := parseStringArrayParam(map[string]interface{}{}, "tests"); err != nil || len(got) != 0 {
+		t.Errorf("Expected no items for a missing parameter, got %v, %v", got, err)
+	}
+	if _, err := parseStringArrayParam(map[string]interface{}{"tests": []interface{}{"A", 1}}, "tests"); err == nil {
+		t.Error("Expected an error for a non-string item")
+	}
+}
+
+func TestParseIntParam(t *testing.T) {
+	tests := []struct {
+		value   interface{}
+		want    int
+		wantErr bool
+	}{
+		{float64(3), 3, false},
+		{float64(0), 0, true},
+		{2.5, 0, true},
+		{"3", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseIntParam(map[string]interface{}{"n": tt.value}, "n", 1)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("parseIntParam(%v) = %d, %v", tt.value, got, err)
+		}
+	}
+
+	if got, err := parseIntParam(map[string]interface{}{}, "n", 1); got != 0 || err != nil {
+		t.Errorf("Expected 0 for a missing parameter, got %d, %v", got, err)
+	}
+}
+
+func TestCommandEnv(t *testing.T) {
+	executor := xcode.NewExecutor(&testLogger{})
+
+	env, developerEnv, err := commandEnv(executor, map[string]string{"FLAG": "1", "TEST_RUNNER_MODE": "ci"}, xcode.TestRunnerPrefix, "")
+	if err != nil {
+		t.Fatalf("commandEnv failed: %v", err)
+	}
+	if want := []string{"TEST_RUNNER_FLAG=1", "TEST_RUNNER_MODE=ci"}; !reflect.DeepEqual(env, want) || developerEnv != nil {
+		t.Errorf("commandEnv() = %v, %v, want %v", env, developerEnv, want)
+	}
+
+	for name, vars := range map[string]map[string]string{
+		"denied variable":   {"DYLD_INSERT_LIBRARIES": "/tmp/x.dylib"},
+		"developer dir var": {"DEVELOPER_DIR": "/Applications/Xcode.app"},
+	} {
+		_, _, err := commandEnv(executor, vars, "", "")
+		if xerr := types.ExtractXcodeError(err); xerr == nil || xerr.Code != types.ErrCodeInvalidParams || xerr.Details["parameter"] != "environment" {
+			t.Errorf("%s: expected INVALID_PARAMS for environment, got %v", name, err)
+		}
+	}
+
+	developer := "/Applications/Xcode.app/Contents/Developer"
+	env, developerEnv, err = commandEnv(executor, map[string]string{"FLAG": "1"}, "", developer)
+	if err != nil {
+		t.Fatalf("commandEnv failed: %v", err)
+	}
+	if want := []string{"DEVELOPER_DIR=" + developer, "FLAG=1"}; !reflect.DeepEqual(env, want) || !reflect.DeepEqual(developerEnv, want[:1]) {
+		t.Errorf("commandEnv() = %v, %v, want %v", env, developerEnv, want)
+	}
+}
+
+func TestParseEnvironmentParam(t *testing.T) {
+	got, err := parseEnvironmentParam(map[string]interface{}{"env": map[string]interface{}{"A": "1"}}, "env")
+	if err != nil || !reflect.DeepEqual(got, map[string]string{"A": "1"}) {
+		t.Errorf("parseEnvironmentParam() = %v, %v", got, err)
+	}
+	for _, value := range []interface{}{"A=1", map[string]interface{}{"A": 1}} {
+		if _, err := parseEnvironmentParam(map[string]interface{}{"env": value}, "env"); !types.IsXcodeError(err, types.ErrCodeInvalidParams) {
+			t.Errorf("Expected INVALID_PARAMS for %v, got %v", value, err)
+		}
+	}
+}
+
+func TestEnsureDeviceBooted_Replay(t *testing.T) {
+	listArgs := []string{"xcrun", "simctl", "list", "devices", "--json"}
+	booted := strings.Replace(mockSimulatorListJSON, `"Shutdown"`, `"Booted"`, 1)
+
+	tests := []struct {
+		name     string
+		fixture  xcode.Fixture
+		udid     string
+		wantCode types.ErrorCode
+	}{
+		{"booted", xcode.Fixture{Args: listArgs, Stdout: booted}, "ABC123-DEF4-5678-9ABC-DEF123456789", ""},
+		{"shutdown", xcode.Fixture{Args: listArgs, Stdout: mockSimulatorListJSON}, "ABC123-DEF4-5678-9ABC-DEF123456789", types.ErrCodeSimulatorNotBooted},
+		{"unknown", xcode.Fixture{Args: listArgs, Stdout: mockSimulatorListJSON}, "NOPE", types.ErrCodeSimulatorNotFound},
+		{"simctl fails", xcode.Fixture{Args: listArgs, Stderr: "CoreSimulator is out of date", ExitCode: 1}, "NOPE", types.ErrCodeCommandFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := xcode.NewReplayRunner([]xcode.Fixture{tt.fixture})
+			executor := xcode.NewExecutorWithRunner(&testLogger{}, runner)
+
+			err := ensureDeviceBooted(context.Background(), executor, tt.udid)
+			if tt.wantCode == "" {
+				if err != nil {
+					t.Fatalf("ensureDeviceBooted() = %v, want nil", err)
+				}
+				return
+			}
+			if !types.IsXcodeError(err, tt.wantCode) {
+				t.Errorf("ensureDeviceBooted() = %v, want %s", err, tt.wantCode)
+			}
+		})
+	}
+}
@@ -1,25 +1,29 @@
 package tools
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/jontolof/xcode-build-mcp/internal/plist"
+	"github.com/jontolof/xcode-build-mcp/internal/xcode"
 	"github.com/jontolof/xcode-build-mcp/pkg/types"
 )
 
 type GetAppInfo struct {
-	name        string
-	description string
-	schema      map[string]interface{}
+	name         string
+	description  string
+	schema       map[string]interface{}
+	outputSchema map[string]interface{}
+	executor     *xcode.Executor
 }
 
-func NewGetAppInfo() *GetAppInfo {
+func NewGetAppInfo(executor *xcode.Executor) *GetAppInfo {
 	schema := createJSONSchema("object", map[string]interface{}{
 		"app_path": map[string]interface{}{
 			"type":        "string",
@@ -40,9 +44,11 @@ func NewGetAppInfo() *GetAppInfo {
 	}, []string{})
 
 	return &GetAppInfo{
-		name:        "get_app_info",
-		description: "Extract metadata from iOS/macOS app bundles including bundle ID, version, entitlements, and icon paths",
-		schema:      schema,
+		name:         "get_app_info",
+		description:  "Extract metadata from iOS/macOS app bundles including bundle ID, version, entitlements, and icon paths",
+		schema:       schema,
+		outputSchema: types.SchemaFor(types.AppInfoResult{}),
+		executor:     executor,
 	}
 }
 
@@ -58,7 +64,11 @@ func (t *GetAppInfo) InputSchema() map[string]interface{} {
 	return t.schema
 }
 
-func (t *GetAppInfo) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+func (t *GetAppInfo) OutputSchema() map[string]interface{} {
+	return t.outputSchema
+}
+
+func (t *GetAppInfo) Execute(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error) {
 	var p types.AppInfoParams
 
 	// Parse parameters from args
@@ -82,22 +92,19 @@ func (t *GetAppInfo) Execute(ctx context.Context, args map[string]interface{}) (
 			Success:  false,
 			Duration: time.Since(start),
 		}
-		resultJSON, _ := json.Marshal(errorResult)
-		return string(resultJSON), err
+		partial, _ := types.NewToolResult(errorResult, true)
+		return partial, err
 	}
 
 	result.Duration = time.Since(start)
-	resultJSON, err := json.Marshal(result)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal result: %w", err)
-	}
-	return string(resultJSON), nil
+	return types.NewToolResult(result, !result.Success)
 }
 
 func (t *GetAppInfo) extractAppInfo(ctx context.Context, params *types.AppInfoParams) (*types.AppInfoResult, error) {
 	// Validate parameters - need either app path or bundle ID + device
 	if params.AppPath == "" && params.BundleID == "" {
-		return nil, fmt.Errorf("either app_path or bundle_id must be specified")
+		return nil, invalidParams("either app_path or bundle_id must be specified",
+			map[string]interface{}{"parameters": []string{"app_path", "bundle_id"}})
 	}
 
 	if params.AppPath != "" {
@@ -112,29 +119,34 @@ func (t *GetAppInfo) extractAppInfo(ctx context.Context, params *types.AppInfoPa
 func (t *GetAppInfo) extractFromLocalBundle(ctx context.Context, appPath string) (*types.AppInfoResult, error) {
 	// Verify app bundle exists
 	if _, err := os.Stat(appPath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("app bundle not found: %s", appPath)
+		return nil, types.NewXcodeError(types.ErrCodeAppNotFound, fmt.Sprintf("app bundle not found: %s", appPath),
+			map[string]interface{}{"app_path": appPath})
 	}
 
 	// Check if it's a valid app bundle
 	if !strings.HasSuffix(appPath, ".app") && !strings.HasSuffix(appPath, ".ipa") {
-		return nil, fmt.Errorf("invalid app bundle format (must be .app or .ipa): %s", appPath)
+		return nil, invalidParams(fmt.Sprintf("invalid app bundle format (must be .app or .ipa): %s", appPath),
+			map[string]interface{}{"parameter": "app_path", "app_path": appPath})
 	}
 
-	result := &types.AppInfoResult{Success: true}
-
-	// Extract Info.plist path
-	var infoPlistPath string
-	if strings.HasSuffix(appPath, ".app") {
-		infoPlistPath = filepath.Join(appPath, "Info.plist")
-	} else {
-		// For .ipa files, we need to extract the Info.plist
-		return nil, fmt.Errorf(".ipa extraction not yet implemented - please extract the .app bundle first")
+	// .ipa files are inspected through their unpacked Payload/*.app bundle
+	bundlePath := appPath
+	if strings.HasSuffix(appPath, ".ipa") {
+		extracted, cleanup, err := extractIPA(appPath)
+		if err != nil {
+			return nil, toolError(err, types.ErrCodeAppNotFound, "failed to extract .ipa", nil)
+		}
+		defer cleanup()
+		bundlePath = extracted
 	}
 
-	// Read Info.plist using plutil
-	plistData, err := t.readInfoPlist(ctx, infoPlistPath)
+	result := &types.AppInfoResult{Success: true}
+
+	infoPlistPath := filepath.Join(bundlePath, "Info.plist")
+	plistData, err := readInfoPlist(infoPlistPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read Info.plist: %w", err)
+		return nil, toolError(err, types.ErrCodeAppNotFound, "failed to read Info.plist",
+			map[string]interface{}{"app_path": appPath, "plist_path": infoPlistPath})
 	}
 
 	// Parse plist data
@@ -162,11 +174,19 @@ func (t *GetAppInfo) extractFromLocalBundle(ctx context.Context, appPath string)
 		result.MinOSVersion = minOSVersion
 	}
 
-	// Extract icon paths
-	result.IconPaths = t.findIconFiles(appPath, plistData)
+	// Extract icon paths. The unpacked copy of an .ipa is removed on return,
+	// so its icons are reported relative to the archive.
+	result.IconPaths = t.findIconFiles(bundlePath, plistData)
+	if bundlePath != appPath {
+		for i, iconPath := range result.IconPaths {
+			if rel, err := filepath.Rel(filepath.Dir(bundlePath), iconPath); err == nil {
+				result.IconPaths[i] = "Payload/" + filepath.ToSlash(rel)
+			}
+		}
+	}
 
 	// Extract entitlements if available
-	entitlements, err := t.extractEntitlements(ctx, appPath)
+	entitlements, err := t.extractEntitlements(ctx, bundlePath)
 	if err == nil {
 		result.Entitlements = entitlements
 	}
@@ -178,22 +198,22 @@ func (t *GetAppInfo) extractFromInstalledApp(ctx context.Context, params *types.
 	// Auto-select device if not specified
 	udid := params.UDID
 	if udid == "" && params.DeviceType == "" {
-		simulator, err := selectBestSimulator("")
+		simulator, err := selectBestSimulator(t.executor, "")
 		if err != nil {
-			return nil, fmt.Errorf("failed to auto-select device: %w", err)
+			return nil, toolError(err, types.ErrCodeSimulatorNotFound, "failed to auto-select device", nil)
 		}
 		udid = simulator.UDID
 	}
 
 	if udid == "" {
-		return nil, fmt.Errorf("device UDID is required for installed app info")
+		return nil, invalidParams("device UDID is required for installed app info", map[string]interface{}{"parameter": "udid"})
 	}
 
 	// Get app info from device using simctl
-	cmd := exec.CommandContext(ctx, "xcrun", "simctl", "appinfo", udid, params.BundleID)
-	output, err := cmd.Output()
+	output, err := t.executor.Output(ctx, "xcrun", "simctl", "appinfo", udid, params.BundleID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get app info from device: %w\nOutput: %s", err, string(output))
+		return nil, toolError(err, types.ErrCodeAppNotFound, "failed to get app info from device",
+			map[string]interface{}{"udid": udid, "bundle_id": params.BundleID, "output": strings.TrimSpace(string(output))})
 	}
 
 	// Parse simctl appinfo output (JSON format)
@@ -227,22 +247,6 @@ func (t *GetAppInfo) extractFromInstalledApp(ctx context.Context, params *types.
 	return result, nil
 }
 
-func (t *GetAppInfo) readInfoPlist(ctx context.Context, plistPath string) (map[string]interface{}, error) {
-	// Use plutil to convert plist to JSON
-	cmd := exec.CommandContext(ctx, "plutil", "-convert", "json", "-o", "-", plistPath)
-	output, err := cmd.Output()
-	if err != nil {
-		return nil, fmt.Errorf("failed to read plist: %w", err)
-	}
-
-	var plistData map[string]interface{}
-	if err := json.Unmarshal(output, &plistData); err != nil {
-		return nil, fmt.Errorf("failed to parse plist JSON: %w", err)
-	}
-
-	return plistData, nil
-}
-
 func (t *GetAppInfo) findIconFiles(appPath string, plistData map[string]interface{}) []string {
 	var iconPaths []string
 
@@ -339,31 +343,48 @@ func (t *GetAppInfo) extractEntitlements(ctx context.Context, appPath string) (m
 }
 
 func (t *GetAppInfo) extractEntitlementsFromBinary(ctx context.Context, binaryPath string) (map[string]interface{}, error) {
-	cmd := exec.CommandContext(ctx, "codesign", "-d", "--entitlements", ":-", binaryPath)
-	output, err := cmd.Output()
+	output, err := t.executor.Output(ctx, "codesign", "-d", "--entitlements", ":-", binaryPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to extract entitlements: %w", err)
 	}
 
-	// Parse entitlements plist
-	var entitlements map[string]interface{}
-	if err := json.Unmarshal(output, &entitlements); err != nil {
-		// Try parsing as plist
-		return t.parsePlistString(string(output))
+	entitlements, err := plist.UnmarshalDict(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse entitlements: %w", err)
 	}
-
 	return entitlements, nil
 }
 
+// extractEntitlementsFromMobileProvision reads the Entitlements of a
+// provisioning profile. The profile is an XML plist wrapped in a CMS
+// signature, which does not need verifying to read it.
 func (t *GetAppInfo) extractEntitlementsFromMobileProvision(ctx context.Context, provisionPath string) (map[string]interface{}, error) {
-	// Mobile provision files are complex - this is a simplified implementation
-	return nil, fmt.Errorf("mobile provision parsing not yet implemented")
+	data, err := os.ReadFile(provisionPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read provisioning profile: %w", err)
+	}
+
+	start := bytes.Index(data, []byte("<?xml"))
+	end := bytes.LastIndex(data, []byte("</plist>"))
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no plist found in provisioning profile %s", provisionPath)
+	}
+
+	profile, err := plist.UnmarshalDict(data[start : end+len("</plist>")])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse provisioning profile: %w", err)
+	}
+	entitlements, ok := profile["Entitlements"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("provisioning profile has no entitlements")
+	}
+	return entitlements, nil
 }
 
 func (t *GetAppInfo) findExecutable(appPath string) string {
 	// Try to find the main executable from Info.plist
 	infoPlistPath := filepath.Join(appPath, "Info.plist")
-	plistData, err := t.readInfoPlist(context.Background(), infoPlistPath)
+	plistData, err := readInfoPlist(infoPlistPath)
 	if err != nil {
 		return ""
 	}
@@ -375,11 +396,6 @@ func (t *GetAppInfo) findExecutable(appPath string) string {
 	return ""
 }
 
-func (t *GetAppInfo) parsePlistString(plistString string) (map[string]interface{}, error) {
-	// Simple plist string parsing - in a real implementation, you'd use a proper plist parser
-	return nil, fmt.Errorf("plist string parsing not implemented")
-}
-
 func (t *GetAppInfo) parseAppInfoText(output string, bundleID string) (*types.AppInfoResult, error) {
 	// Parse text output from simctl appinfo when JSON parsing fails
 	result := &types.AppInfoResult{
@@ -8,18 +8,19 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/jontolof/xcode-build-mcp/internal/xcode"
 	"github.com/jontolof/xcode-build-mcp/pkg/types"
 )
 
 func TestGetAppInfo_Name(t *testing.T) {
-	tool := NewGetAppInfo()
+	tool := NewGetAppInfo(xcode.NewExecutor(&testLogger{}))
 	if got := tool.Name(); got != "get_app_info" {
 		t.Errorf("GetAppInfo.Name() = %v, want %v", got, "get_app_info")
 	}
 }
 
 func TestGetAppInfo_Description(t *testing.T) {
-	tool := NewGetAppInfo()
+	tool := NewGetAppInfo(xcode.NewExecutor(&testLogger{}))
 	desc := tool.Description()
 	if desc == "" {
 		t.Error("GetAppInfo.Description() returned empty string")
@@ -30,11 +31,12 @@ func TestGetAppInfo_Description(t *testing.T) {
 }
 
 func TestGetAppInfo_Execute_InvalidParams(t *testing.T) {
-	tool := NewGetAppInfo()
+	tool := NewGetAppInfo(xcode.NewExecutor(&testLogger{}))
 	ctx := context.Background()
 
 	// Test with empty params (no app_path or bundle_id)
-	result, err := tool.Execute(ctx, map[string]interface{}{})
+	toolResult, err := tool.Execute(ctx, map[string]interface{}{})
+	result := toolResult.Text()
 	if err == nil {
 		t.Error("Expected error for empty params, got nil")
 	}
@@ -49,7 +51,7 @@ func TestGetAppInfo_Execute_InvalidParams(t *testing.T) {
 }
 
 func TestGetAppInfo_Execute_ValidParams(t *testing.T) {
-	tool := NewGetAppInfo()
+	tool := NewGetAppInfo(xcode.NewExecutor(&testLogger{}))
 	ctx := context.Background()
 
 	// Create a temporary app bundle for testing
@@ -68,7 +70,8 @@ func TestGetAppInfo_Execute_ValidParams(t *testing.T) {
 		"app_path": appPath,
 	}
 
-	result, err := tool.Execute(ctx, args)
+	toolResult, err := tool.Execute(ctx, args)
+	result := toolResult.Text()
 
 	// Should get a result string even if extraction partially fails
 	if result == "" {
@@ -87,13 +90,14 @@ func TestGetAppInfo_Execute_ValidParams(t *testing.T) {
 }
 
 func TestGetAppInfo_Execute_MissingParams(t *testing.T) {
-	tool := NewGetAppInfo()
+	tool := NewGetAppInfo(xcode.NewExecutor(&testLogger{}))
 	ctx := context.Background()
 
 	// Test with no app path or bundle ID
 	args := map[string]interface{}{}
 
-	result, err := tool.Execute(ctx, args)
+	toolResult, err := tool.Execute(ctx, args)
+	result := toolResult.Text()
 
 	if err == nil {
 		t.Error("Expected error for missing parameters")
@@ -376,7 +380,7 @@ func TestGetAppInfo_ParameterValidation(t *testing.T) {
 		},
 	}
 
-	tool := NewGetAppInfo()
+	tool := NewGetAppInfo(xcode.NewExecutor(&testLogger{}))
 	ctx := context.Background()
 
 	for _, tt := range tests {
@@ -396,7 +400,8 @@ func TestGetAppInfo_ParameterValidation(t *testing.T) {
 				args["device_type"] = tt.params.DeviceType
 			}
 
-			result, err := tool.Execute(ctx, args)
+			toolResult, err := tool.Execute(ctx, args)
+			result := toolResult.Text()
 
 			if tt.valid {
 				// For valid params, we should get a result string
@@ -426,3 +431,68 @@ func TestGetAppInfo_ParameterValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestGetAppInfo_Execute_IPA(t *testing.T) {
+	ipaPath := writeIPA(t, t.TempDir(), map[string]string{
+		"Payload/TestApp.app/TestApp":             "binary",
+		"Payload/TestApp.app/AppIcon60x60@2x.png": "png",
+	})
+
+	toolResult, err := NewGetAppInfo(xcode.NewExecutor(&testLogger{})).Execute(context.Background(), map[string]interface{}{"app_path": ipaPath})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	result := toolResult.Structured.(*types.AppInfoResult)
+	if result.BundleID != "com.example.TestApp" || result.Version != "1.2.3" || result.BuildNumber != "42" {
+		t.Errorf("Unexpected app info: %+v", result)
+	}
+	if result.DisplayName != "Test Äpp ✓" || result.MinOSVersion != "17.0" {
+		t.Errorf("Unexpected names: %+v", result)
+	}
+	if len(result.IconPaths) != 1 || result.IconPaths[0] != "Payload/TestApp.app/AppIcon60x60@2x.png" {
+		t.Errorf("Expected icon paths inside the archive, got %v", result.IconPaths)
+	}
+}
+
+func TestGetAppInfo_ExtractEntitlementsFromMobileProvision(t *testing.T) {
+	tool := &GetAppInfo{}
+	appPath := filepath.Join(t.TempDir(), "TestApp.app")
+	if err := os.Mkdir(appPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// The plist sits between the binary CMS envelope and its signature
+	profile := "0\x82\x0b\x1e\x06\t*\x86H\x86\xf7\r\x01\x07\x02" + `<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0">
+<dict>
+	<key>Name</key>
+	<string>QA Distribution</string>
+	<key>Entitlements</key>
+	<dict>
+		<key>application-identifier</key>
+		<string>TEAMID.com.example.TestApp</string>
+		<key>get-task-allow</key>
+		<true/>
+		<key>keychain-access-groups</key>
+		<array>
+			<string>TEAMID.*</string>
+		</array>
+	</dict>
+</dict>
+</plist>` + "\n\xa0\x82\x03\x00signature"
+	if err := os.WriteFile(filepath.Join(appPath, "embedded.mobileprovision"), []byte(profile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	entitlements, err := tool.extractEntitlements(context.Background(), appPath)
+	if err != nil {
+		t.Fatalf("extractEntitlements failed: %v", err)
+	}
+	if entitlements["application-identifier"] != "TEAMID.com.example.TestApp" || entitlements["get-task-allow"] != true {
+		t.Errorf("Unexpected entitlements: %v", entitlements)
+	}
+	if groups, ok := entitlements["keychain-access-groups"].([]interface{}); !ok || len(groups) != 1 {
+		t.Errorf("Expected one keychain access group, got %v", entitlements["keychain-access-groups"])
+	}
+}
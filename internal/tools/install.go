@@ -15,12 +15,13 @@ import (
 )
 
 type InstallAppTool struct {
-	name        string
-	description string
-	schema      map[string]interface{}
-	executor    *xcode.Executor
-	parser      *xcode.Parser
-	logger      common.Logger
+	name         string
+	description  string
+	schema       map[string]interface{}
+	outputSchema map[string]interface{}
+	executor     *xcode.Executor
+	parser       *xcode.Parser
+	logger       common.Logger
 }
 
 func NewInstallAppTool(executor *xcode.Executor, parser *xcode.Parser, logger common.Logger) *InstallAppTool {
@@ -44,12 +45,13 @@ func NewInstallAppTool(executor *xcode.Executor, parser *xcode.Parser, logger co
 	}, []string{"app_path"})
 
 	return &InstallAppTool{
-		name:        "install_app",
-		description: "Install iOS/tvOS/watchOS apps on simulators or devices",
-		schema:      schema,
-		executor:    executor,
-		parser:      parser,
-		logger:      logger,
+		name:         "install_app",
+		description:  "Install iOS/tvOS/watchOS apps on simulators or devices",
+		schema:       schema,
+		outputSchema: types.SchemaFor(types.AppInstallResult{}),
+		executor:     executor,
+		parser:       parser,
+		logger:       logger,
 	}
 }
 
@@ -65,10 +67,14 @@ func (t *InstallAppTool) InputSchema() map[string]interface{} {
 	return t.schema
 }
 
-func (t *InstallAppTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+func (t *InstallAppTool) OutputSchema() map[string]interface{} {
+	return t.outputSchema
+}
+
+func (t *InstallAppTool) Execute(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error) {
 	params, err := t.parseParams(args)
 	if err != nil {
-		return "", fmt.Errorf("invalid parameters: %w", err)
+		return nil, toolError(err, types.ErrCodeInvalidParams, "invalid parameters", nil)
 	}
 
 	start := time.Now()
@@ -77,15 +83,19 @@ func (t *InstallAppTool) Execute(ctx context.Context, args map[string]interface{
 	if params.AppPath == "." || params.AppPath == "./" {
 		appPath, err := t.findAppBundle(".")
 		if err != nil {
-			return "", fmt.Errorf("no .app bundle found in current directory. Build your project first with xcode_build, then specify the exact path to the .app bundle")
+			return nil, types.NewXcodeError(types.ErrCodeAppNotFound,
+				"no .app bundle found in current directory. Build your project first with xcode_build, then specify the exact path to the .app bundle",
+				map[string]interface{}{"app_path": params.AppPath})
 		}
 		params.AppPath = appPath
 		t.logger.Printf("Auto-detected app bundle: %s", appPath)
 	}
 
-	// Validate it's actually a .app bundle
-	if !strings.HasSuffix(params.AppPath, ".app") {
-		return "", fmt.Errorf("invalid app path: %s. Must be a .app bundle, not a directory", params.AppPath)
+	// Validate it's actually a .app bundle or an .ipa archive
+	isIPA := strings.HasSuffix(params.AppPath, ".ipa")
+	if !strings.HasSuffix(params.AppPath, ".app") && !isIPA {
+		return nil, invalidParams(fmt.Sprintf("invalid app path: %s. Must be a .app bundle or .ipa file, not a directory", params.AppPath),
+			map[string]interface{}{"parameter": "app_path", "app_path": params.AppPath})
 	}
 
 	// Debug logging if enabled
@@ -97,7 +107,20 @@ func (t *InstallAppTool) Execute(ctx context.Context, args map[string]interface{
 
 	// Validate app path exists
 	if _, err := os.Stat(params.AppPath); os.IsNotExist(err) {
-		return "", fmt.Errorf("app path does not exist: %s", params.AppPath)
+		return nil, types.NewXcodeError(types.ErrCodeAppNotFound, fmt.Sprintf("app path does not exist: %s", params.AppPath),
+			map[string]interface{}{"app_path": params.AppPath})
+	}
+
+	// simctl installs .app bundles, so unpack the .ipa first
+	bundlePath := params.AppPath
+	if isIPA {
+		extracted, cleanup, err := extractIPA(params.AppPath)
+		if err != nil {
+			return nil, toolError(err, types.ErrCodeInstallFailed, "failed to extract .ipa", nil)
+		}
+		defer cleanup()
+		bundlePath = extracted
+		t.logger.Printf("Extracted %s to %s", params.AppPath, bundlePath)
 	}
 
 	// Resolve target device if not provided
@@ -105,23 +128,25 @@ func (t *InstallAppTool) Execute(ctx context.Context, args map[string]interface{
 	if targetUDID == "" {
 		detectedUDID, err := t.selectBestDevice(ctx, params.DeviceType)
 		if err != nil {
-			return "", fmt.Errorf("failed to auto-detect device: %w", err)
+			return nil, toolError(err, types.ErrCodeSimulatorNotFound, "failed to auto-detect device",
+				map[string]interface{}{"device_type": params.DeviceType})
 		}
 		targetUDID = detectedUDID
 		t.logger.Printf("Auto-selected device: %s", targetUDID)
 	}
 
 	// Extract bundle ID from app
-	bundleID, err := t.extractBundleID(ctx, params.AppPath)
+	bundleID, err := extractBundleID(bundlePath)
 	if err != nil {
 		t.logger.Printf("Warning: Could not extract bundle ID: %v", err)
 		bundleID = "Unknown"
 	}
 
 	// Perform the installation
-	output, err := t.installApp(ctx, params.AppPath, targetUDID, params.Replace)
+	output, err := t.installApp(ctx, bundlePath, targetUDID, params.Replace)
 	if err != nil {
-		return "", fmt.Errorf("failed to install app: %w", err)
+		return nil, toolError(err, types.ErrCodeInstallFailed, "failed to install app",
+			map[string]interface{}{"app_path": params.AppPath, "udid": targetUDID})
 	}
 
 	duration := time.Since(start)
@@ -134,13 +159,7 @@ func (t *InstallAppTool) Execute(ctx context.Context, args map[string]interface{
 		BundleID: bundleID,
 	}
 
-	// Convert result to JSON string
-	resultJSON, err := json.Marshal(result)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal result: %w", err)
-	}
-
-	return string(resultJSON), nil
+	return types.NewToolResult(result, !result.Success)
 }
 
 func (t *InstallAppTool) parseParams(args map[string]interface{}) (*types.AppInstallParams, error) {
@@ -224,11 +243,12 @@ func (t *InstallAppTool) selectBestDevice(ctx context.Context, deviceTypeFilter
 
 	result, err := t.executor.ExecuteCommand(ctx, args)
 	if err != nil {
-		return "", err
+		return "", toolError(err, types.ErrCodeCommandFailed, "failed to list devices", nil)
 	}
 
 	if !result.Success() {
-		return "", fmt.Errorf("failed to list devices: %s", result.StderrOutput)
+		return "", types.NewXcodeError(types.ErrCodeCommandFailed, fmt.Sprintf("failed to list devices: %s", result.StderrOutput),
+			commandDetails(args, result.ExitCode, result.StderrOutput))
 	}
 
 	// Parse JSON output
@@ -243,7 +263,7 @@ func (t *InstallAppTool) selectBestDevice(ctx context.Context, deviceTypeFilter
 	}
 
 	if err := json.Unmarshal([]byte(result.Output), &simctlOutput); err != nil {
-		return "", fmt.Errorf("failed to parse device list: %w", err)
+		return "", types.NewXcodeErrorWithCause(types.ErrCodeCommandFailed, "failed to parse device list", err, nil)
 	}
 
 	// Find the best device (prefer booted iOS simulators)
@@ -315,7 +335,8 @@ func (t *InstallAppTool) selectBestDevice(ctx context.Context, deviceTypeFilter
 	}
 
 	if len(candidates) == 0 {
-		return "", fmt.Errorf("no suitable devices found")
+		return "", types.NewXcodeError(types.ErrCodeSimulatorNotFound, "no suitable devices found",
+			map[string]interface{}{"device_type": deviceTypeFilter})
 	}
 
 	// Sort by score (highest first)
@@ -350,7 +371,7 @@ func (t *InstallAppTool) installApp(ctx context.Context, appPath, udid string, r
 
 	result, err := t.executor.ExecuteCommand(ctx, args)
 	if err != nil {
-		return "", err
+		return "", toolError(err, types.ErrCodeCommandFailed, "failed to run simctl install", nil)
 	}
 
 	if !result.Success() {
@@ -358,66 +379,34 @@ func (t *InstallAppTool) installApp(ctx context.Context, appPath, udid string, r
 		if errorOutput == "" {
 			errorOutput = result.Output
 		}
+		details := commandDetails(args, result.ExitCode, errorOutput)
 
 		// Check for common error cases and provide better error messages
 		if strings.Contains(errorOutput, "Unable to install") {
-			return "", fmt.Errorf("unable to install app: %s", strings.TrimSpace(errorOutput))
+			return "", types.NewXcodeError(types.ErrCodeInstallFailed, fmt.Sprintf("unable to install app: %s", strings.TrimSpace(errorOutput)), details)
 		} else if strings.Contains(errorOutput, "device is not booted") {
-			return "", fmt.Errorf("target device is not booted, please boot the simulator first")
+			return "", types.NewXcodeError(types.ErrCodeSimulatorNotBooted, "target device is not booted, please boot the simulator first", details)
 		} else if strings.Contains(errorOutput, "No such file or directory") {
-			return "", fmt.Errorf("app bundle not found at path: %s", appPath)
+			return "", types.NewXcodeError(types.ErrCodeAppNotFound, fmt.Sprintf("app bundle not found at path: %s", appPath), details)
 		} else if strings.Contains(errorOutput, "Invalid app") {
-			return "", fmt.Errorf("invalid app bundle: %s", strings.TrimSpace(errorOutput))
+			return "", types.NewXcodeError(types.ErrCodeInstallFailed, fmt.Sprintf("invalid app bundle: %s", strings.TrimSpace(errorOutput)), details)
 		}
 
-		return "", fmt.Errorf("installation failed (exit code %d): %s", result.ExitCode, strings.TrimSpace(errorOutput))
+		return "", types.NewXcodeError(types.ErrCodeInstallFailed,
+			fmt.Sprintf("installation failed (exit code %d): %s", result.ExitCode, strings.TrimSpace(errorOutput)), details)
 	}
 
 	return result.Output, nil
 }
 
-func (t *InstallAppTool) extractBundleID(ctx context.Context, appPath string) (string, error) {
-	// Check if it's a .app bundle or .ipa
-	ext := filepath.Ext(appPath)
-
-	var infoPlistPath string
-	if ext == ".app" {
-		// For .app bundles, Info.plist is directly in the bundle
-		infoPlistPath = filepath.Join(appPath, "Info.plist")
-	} else if ext == ".ipa" {
-		// For .ipa files, we would need to extract the Info.plist
-		// This is more complex, so we'll skip it for now
-		return "", fmt.Errorf(".ipa bundle ID extraction not implemented")
-	} else {
-		return "", fmt.Errorf("unsupported app format: %s", ext)
-	}
-
-	// Use plutil to read the bundle identifier
-	args := []string{"plutil", "-p", infoPlistPath}
-
-	result, err := t.executor.ExecuteCommand(ctx, args)
+// extractBundleID reads CFBundleIdentifier from the Info.plist of a .app bundle
+func extractBundleID(appPath string) (string, error) {
+	info, err := readInfoPlist(filepath.Join(appPath, "Info.plist"))
 	if err != nil {
 		return "", err
 	}
-
-	if !result.Success() {
-		return "", fmt.Errorf("failed to read Info.plist: %s", result.StderrOutput)
-	}
-
-	// Parse the plist output to find CFBundleIdentifier
-	lines := strings.Split(result.Output, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.Contains(line, "CFBundleIdentifier") {
-			// Look for the next line which should contain the value
-			parts := strings.SplitN(line, "=>", 2)
-			if len(parts) == 2 {
-				bundleID := strings.TrimSpace(parts[1])
-				bundleID = strings.Trim(bundleID, `"`)
-				return bundleID, nil
-			}
-		}
+	if bundleID, ok := info["CFBundleIdentifier"].(string); ok && bundleID != "" {
+		return bundleID, nil
 	}
-
 	return "", fmt.Errorf("CFBundleIdentifier not found in Info.plist")
 }
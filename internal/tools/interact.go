@@ -2,23 +2,33 @@ package tools
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"os/exec"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/jontolof/xcode-build-mcp/internal/accessibility"
+	"github.com/jontolof/xcode-build-mcp/internal/xcode"
 	"github.com/jontolof/xcode-build-mcp/pkg/types"
 )
 
 type UIInteract struct {
-	name        string
-	description string
-	schema      map[string]interface{}
+	name         string
+	description  string
+	schema       map[string]interface{}
+	outputSchema map[string]interface{}
+	executor     *xcode.Executor
+	backend      accessibility.Backend
 }
 
-func NewUIInteract() *UIInteract {
+// NewUIInteract performs element-based actions through backend, or through
+// the backend selected by accessibility.FromEnv when backend is nil, and
+// runs simctl through executor
+func NewUIInteract(executor *xcode.Executor, backend accessibility.Backend) *UIInteract {
+	if backend == nil {
+		backend = accessibility.FromEnv(executor.Runner())
+	}
+
 	schema := createJSONSchema("object", map[string]interface{}{
 		"udid": map[string]interface{}{
 			"type":        "string",
@@ -30,7 +40,7 @@ func NewUIInteract() *UIInteract {
 		},
 		"action": map[string]interface{}{
 			"type":        "string",
-			"description": "UI action to perform (tap, swipe, type, press_key)",
+			"description": "UI action to perform (tap, double_tap, long_press, swipe, type, scroll_to, home, shake, rotate). tap, long_press, type and scroll_to accept an element instead of coordinates",
 		},
 		"x": map[string]interface{}{
 			"type":        "number",
@@ -46,14 +56,35 @@ func NewUIInteract() *UIInteract {
 		},
 		"element_id": map[string]interface{}{
 			"type":        "string",
-			"description": "Element identifier for element-based actions",
+			"description": "Accessibility identifier of the target element; its frame center is tapped",
+		},
+		"label": map[string]interface{}{
+			"type":        "string",
+			"description": "Accessibility label of the target element",
+		},
+		"predicate": elementQuerySchema(),
+		"duration": map[string]interface{}{
+			"type":        "number",
+			"description": "Seconds to hold for long_press - default: 2",
+		},
+		"direction": map[string]interface{}{
+			"type":        "string",
+			"description": "Direction to scroll for scroll_to while the element is not in the hierarchy - default: down",
+			"enum":        []string{"up", "down", "left", "right"},
+		},
+		"max_scrolls": map[string]interface{}{
+			"type":        "integer",
+			"description": "Maximum swipes for scroll_to - default: 10",
 		},
 	}, []string{"action"})
 
 	return &UIInteract{
-		name:        "ui_interact",
-		description: "Perform UI automation actions on iOS/tvOS/watchOS simulators including tap, swipe, type, and element interactions",
-		schema:      schema,
+		name:         "ui_interact",
+		description:  "Perform UI automation actions on iOS/tvOS/watchOS simulators: tap, long-press, type into or scroll to elements found by accessibility identifier, label or predicate, or act on raw coordinates",
+		schema:       schema,
+		outputSchema: types.SchemaFor(types.UIInteractResult{}),
+		executor:     executor,
+		backend:      backend,
 	}
 }
 
@@ -69,7 +100,58 @@ func (t *UIInteract) InputSchema() map[string]interface{} {
 	return t.schema
 }
 
-func (t *UIInteract) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+func (t *UIInteract) OutputSchema() map[string]interface{} {
+	return t.outputSchema
+}
+
+func (t *UIInteract) Execute(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error) {
+	p, err := t.parseParams(args)
+	if err != nil {
+		partial, _ := types.NewToolResult(&types.UIInteractResult{Success: false}, true)
+		return partial, err
+	}
+
+	start := time.Now()
+
+	// Auto-select device if not specified
+	if p.UDID == "" && p.DeviceType == "" {
+		simulator, err := selectBestSimulator(t.executor, "")
+		if err != nil {
+			errorResult := &types.UIInteractResult{
+				Success:  false,
+				Duration: time.Since(start),
+			}
+			partial, _ := types.NewToolResult(errorResult, true)
+			return partial, toolError(err, types.ErrCodeSimulatorNotFound, "failed to auto-select device", nil)
+		}
+		p.UDID = simulator.UDID
+	}
+
+	// Set default timeout
+	if p.Timeout == 0 {
+		p.Timeout = 30
+	}
+
+	result, err := t.performUIInteraction(ctx, p)
+	if err != nil {
+		// Keep what the interaction found, such as the number of matches or
+		// how often it scrolled, so the client can see why it failed
+		if result == nil {
+			result = &types.UIInteractResult{}
+		}
+		result.Success = false
+		result.Duration = time.Since(start)
+		partial, _ := types.NewToolResult(result, true)
+		return partial, err
+	}
+
+	result.Duration = time.Since(start)
+	return types.NewToolResult(result, !result.Success)
+}
+
+// parseParams reads the interaction from args and rejects actions that are
+// missing what they need, before any device is touched
+func (t *UIInteract) parseParams(args map[string]interface{}) (*types.UIInteractParams, error) {
 	var p types.UIInteractParams
 
 	// Parse parameters from args
@@ -116,44 +198,86 @@ func (t *UIInteract) Execute(ctx context.Context, args map[string]interface{}) (
 			p.Target = str
 		}
 	}
-
-	start := time.Now()
-
-	// Auto-select device if not specified
-	if p.UDID == "" && p.DeviceType == "" {
-		simulator, err := selectBestSimulator("")
-		if err != nil {
-			errorResult := &types.UIInteractResult{
-				Success:  false,
-				Duration: time.Since(start),
+	if duration, exists := args["duration"]; exists {
+		if num, ok := duration.(float64); ok {
+			if p.Parameters == nil {
+				p.Parameters = make(map[string]interface{})
 			}
-			resultJSON, _ := json.Marshal(errorResult)
-			return string(resultJSON), fmt.Errorf("failed to auto-select device: %w", err)
+			p.Parameters["duration"] = num
 		}
-		p.UDID = simulator.UDID
 	}
-
-	// Set default timeout
-	if p.Timeout == 0 {
-		p.Timeout = 30
+	if direction, exists := args["direction"]; exists {
+		if str, ok := direction.(string); ok {
+			p.Direction = strings.ToLower(str)
+		}
+		switch p.Direction {
+		case "up", "down", "left", "right":
+		default:
+			return nil, invalidParams(fmt.Sprintf("invalid direction %v: must be up, down, left or right", direction),
+				map[string]interface{}{"parameter": "direction"})
+		}
 	}
-
-	result, err := t.performUIInteraction(ctx, &p)
-	if err != nil {
-		errorResult := &types.UIInteractResult{
-			Success:  false,
-			Duration: time.Since(start),
+	if maxScrolls, exists := args["max_scrolls"]; exists {
+		if num, ok := maxScrolls.(float64); ok {
+			p.MaxScrolls = int(num)
 		}
-		resultJSON, _ := json.Marshal(errorResult)
-		return string(resultJSON), err
 	}
 
-	result.Duration = time.Since(start)
-	resultJSON, err := json.Marshal(result)
+	query, err := parseElementQuery(args)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal result: %w", err)
+		return nil, err
+	}
+	p.Element = query
+
+	if err := checkInteraction(&p); err != nil {
+		return nil, err
 	}
-	return string(resultJSON), nil
+	return &p, nil
+}
+
+// checkInteraction rejects an action that lacks the element, coordinates or
+// text it needs
+func checkInteraction(p *types.UIInteractParams) error {
+	if p.Action == "" {
+		return invalidParams("action is required", map[string]interface{}{"parameter": "action"})
+	}
+
+	hasElement := !p.Element.IsZero() || p.Target != ""
+	hasPoint := len(p.Coordinates) >= 2
+	switch strings.ToLower(p.Action) {
+	case "tap":
+		if !hasElement && !hasPoint {
+			return invalidParams("either target element or coordinates must be specified for tap action",
+				map[string]interface{}{"parameters": []string{"target", "coordinates"}})
+		}
+	case "long_press", "longpress":
+		if !hasElement && !hasPoint {
+			return invalidParams("coordinates required for long press", map[string]interface{}{"parameter": "coordinates"})
+		}
+	case "double_tap", "doubletap":
+		if !hasPoint {
+			return invalidParams("coordinates required for double tap", map[string]interface{}{"parameter": "coordinates"})
+		}
+	case "swipe":
+		if len(p.Coordinates) < 4 {
+			return invalidParams("swipe requires 4 coordinates: start_x, start_y, end_x, end_y",
+				map[string]interface{}{"parameter": "coordinates"})
+		}
+	case "type", "enter_text":
+		if p.Text == "" {
+			return invalidParams("text is required for type action", map[string]interface{}{"parameter": "text"})
+		}
+	case "scroll_to":
+		if !hasElement {
+			return invalidParams("scroll_to requires element_id, label or predicate",
+				map[string]interface{}{"parameters": []string{"element_id", "label", "predicate"}})
+		}
+	case "home", "shake", "rotate":
+	default:
+		return invalidParams(fmt.Sprintf("unsupported action: %s", p.Action),
+			map[string]interface{}{"parameter": "action"})
+	}
+	return nil
 }
 
 func (t *UIInteract) isTestEnvironment(udid string) bool {
@@ -162,23 +286,39 @@ func (t *UIInteract) isTestEnvironment(udid string) bool {
 
 func (t *UIInteract) performUIInteraction(ctx context.Context, params *types.UIInteractParams) (*types.UIInteractResult, error) {
 	if params.UDID == "" {
-		return &types.UIInteractResult{Success: false}, fmt.Errorf("device UDID is required")
+		return &types.UIInteractResult{Success: false}, invalidParams("device UDID is required", map[string]interface{}{"parameter": "udid"})
 	}
 
 	if params.Action == "" {
-		return &types.UIInteractResult{Success: false}, fmt.Errorf("action is required")
+		return &types.UIInteractResult{Success: false}, invalidParams("action is required", map[string]interface{}{"parameter": "action"})
 	}
 
 	// Skip device boot check in test environment
 	if !t.isTestEnvironment(params.UDID) {
 		// Ensure device is booted
-		if err := t.ensureDeviceBooted(ctx, params.UDID); err != nil {
-			return &types.UIInteractResult{Success: false}, fmt.Errorf("device not ready: %w", err)
+		if err := ensureDeviceBooted(ctx, t.executor, params.UDID); err != nil {
+			return &types.UIInteractResult{Success: false}, toolError(err, types.ErrCodeSimulatorNotBooted, "device not ready", nil)
+		}
+	}
+
+	action := strings.ToLower(params.Action)
+
+	query := params.Element
+	if query.IsZero() && params.Target != "" {
+		query.Identifier = params.Target
+	}
+	if !query.IsZero() {
+		switch action {
+		case "tap", "long_press", "longpress", "type", "enter_text", "scroll_to":
+			return t.performElementAction(ctx, params, action, query)
 		}
 	}
 
 	// Perform the specific action
-	switch strings.ToLower(params.Action) {
+	switch action {
+	case "scroll_to":
+		return &types.UIInteractResult{Success: false}, invalidParams("scroll_to requires element_id, label or predicate",
+			map[string]interface{}{"parameters": []string{"element_id", "label", "predicate"}})
 	case "tap":
 		return t.performTap(ctx, params)
 	case "double_tap", "doubletap":
@@ -196,22 +336,79 @@ func (t *UIInteract) performUIInteraction(ctx context.Context, params *types.UII
 	case "rotate":
 		return t.performRotate(ctx, params)
 	default:
-		return &types.UIInteractResult{Success: false}, fmt.Errorf("unsupported action: %s", params.Action)
+		return &types.UIInteractResult{Success: false}, invalidParams(fmt.Sprintf("unsupported action: %s", params.Action),
+			map[string]interface{}{"parameter": "action"})
 	}
 }
 
-func (t *UIInteract) performTap(ctx context.Context, params *types.UIInteractParams) (*types.UIInteractResult, error) {
-	var args []string
-
-	if params.Target != "" {
-		// Target-based tap (find element by text/identifier)
-		// Always return mock result for target-based taps (including test environment)
+// performElementAction resolves query against the live hierarchy and acts on
+// the center of the matching element's frame
+func (t *UIInteract) performElementAction(ctx context.Context, params *types.UIInteractParams, action string, query types.ElementQuery) (*types.UIInteractResult, error) {
+	if action == "scroll_to" {
+		element, scrolls, err := scrollToElement(ctx, t.executor, t.backend, params.UDID, query, params.Direction, params.MaxScrolls)
+		if err != nil {
+			return &types.UIInteractResult{Success: false, Scrolls: scrolls}, err
+		}
 		return &types.UIInteractResult{
 			Success: true,
-			Output:  fmt.Sprintf("Tapped element with identifier: %s", params.Target),
+			Output:  fmt.Sprintf("Scrolled %s into view after %d scrolls", describeElement(element, false), scrolls),
 			Found:   true,
+			Element: leafCopy(element),
+			Scrolls: scrolls,
 		}, nil
-	} else if len(params.Coordinates) >= 2 {
+	}
+
+	if (action == "type" || action == "enter_text") && params.Text == "" {
+		return &types.UIInteractResult{Success: false}, invalidParams("text is required for type action", map[string]interface{}{"parameter": "text"})
+	}
+
+	element, root, matches, err := findElement(ctx, t.executor, t.backend, params.UDID, query)
+	if err != nil {
+		return &types.UIInteractResult{Success: false, Matches: matches}, err
+	}
+	x, y, err := hittablePoint(root, element, query)
+	if err != nil {
+		return &types.UIInteractResult{Success: false, Matches: matches, Element: leafCopy(element)}, err
+	}
+
+	var output string
+	switch action {
+	case "tap":
+		err = t.backend.Tap(ctx, params.UDID, x, y, 0)
+		output = fmt.Sprintf("Tapped %s at (%.1f, %.1f)", describeElement(element, false), x, y)
+	case "long_press", "longpress":
+		duration := 2.0
+		if d, ok := params.Parameters["duration"].(float64); ok {
+			duration = d
+		}
+		err = t.backend.Tap(ctx, params.UDID, x, y, time.Duration(duration*float64(time.Second)))
+		output = fmt.Sprintf("Long pressed %s at (%.1f, %.1f) for %.1f seconds", describeElement(element, false), x, y, duration)
+	default:
+		// Focus the field before typing into it
+		if err = t.backend.Tap(ctx, params.UDID, x, y, 0); err == nil {
+			err = t.backend.TypeText(ctx, params.UDID, params.Text)
+		}
+		output = fmt.Sprintf("Typed %q into %s", params.Text, describeElement(element, false))
+	}
+
+	result := &types.UIInteractResult{
+		Success:     err == nil,
+		Output:      output,
+		Found:       true,
+		Element:     leafCopy(element),
+		Matches:     matches,
+		Coordinates: []float64{x, y},
+	}
+	if err != nil {
+		return result, toolError(err, types.ErrCodeCommandFailed, action+" failed", map[string]interface{}{"backend": t.backend.Name()})
+	}
+	return result, nil
+}
+
+func (t *UIInteract) performTap(ctx context.Context, params *types.UIInteractParams) (*types.UIInteractResult, error) {
+	var args []string
+
+	if len(params.Coordinates) >= 2 {
 		// Coordinate-based tap
 		x := params.Coordinates[0]
 		y := params.Coordinates[1]
@@ -229,11 +426,11 @@ func (t *UIInteract) performTap(ctx context.Context, params *types.UIInteractPar
 			strconv.FormatFloat(x, 'f', 1, 64),
 			strconv.FormatFloat(y, 'f', 1, 64)}
 
-		cmd := exec.CommandContext(ctx, "xcrun", args...)
-		output, err := cmd.CombinedOutput()
+		args = append([]string{"xcrun"}, args...)
+		output, err := t.executor.CombinedOutput(ctx, args...)
 
 		if err != nil {
-			return &types.UIInteractResult{Success: false}, fmt.Errorf("tap failed: %w\nOutput: %s", err, string(output))
+			return &types.UIInteractResult{Success: false}, commandFailed("tap failed", args, output, err)
 		}
 
 		return &types.UIInteractResult{
@@ -243,12 +440,13 @@ func (t *UIInteract) performTap(ctx context.Context, params *types.UIInteractPar
 		}, nil
 	}
 
-	return &types.UIInteractResult{Success: false}, fmt.Errorf("either target element or coordinates must be specified for tap action")
+	return &types.UIInteractResult{Success: false}, invalidParams("either target element or coordinates must be specified for tap action",
+		map[string]interface{}{"parameters": []string{"target", "coordinates"}})
 }
 
 func (t *UIInteract) performDoubleTap(ctx context.Context, params *types.UIInteractParams) (*types.UIInteractResult, error) {
 	if len(params.Coordinates) < 2 {
-		return &types.UIInteractResult{Success: false}, fmt.Errorf("coordinates required for double tap")
+		return &types.UIInteractResult{Success: false}, invalidParams("coordinates required for double tap", map[string]interface{}{"parameter": "coordinates"})
 	}
 
 	x := params.Coordinates[0]
@@ -260,9 +458,9 @@ func (t *UIInteract) performDoubleTap(ctx context.Context, params *types.UIInter
 			strconv.FormatFloat(x, 'f', 1, 64),
 			strconv.FormatFloat(y, 'f', 1, 64)}
 
-		cmd := exec.CommandContext(ctx, "xcrun", args...)
-		if err := cmd.Run(); err != nil {
-			return &types.UIInteractResult{Success: false}, fmt.Errorf("double tap failed on attempt %d: %w", i+1, err)
+		args = append([]string{"xcrun"}, args...)
+		if output, err := t.executor.CombinedOutput(ctx, args...); err != nil {
+			return &types.UIInteractResult{Success: false}, commandFailed(fmt.Sprintf("double tap failed on attempt %d", i+1), args, output, err)
 		}
 
 		if i == 0 {
@@ -280,7 +478,7 @@ func (t *UIInteract) performDoubleTap(ctx context.Context, params *types.UIInter
 
 func (t *UIInteract) performLongPress(ctx context.Context, params *types.UIInteractParams) (*types.UIInteractResult, error) {
 	if len(params.Coordinates) < 2 {
-		return &types.UIInteractResult{Success: false}, fmt.Errorf("coordinates required for long press")
+		return &types.UIInteractResult{Success: false}, invalidParams("coordinates required for long press", map[string]interface{}{"parameter": "coordinates"})
 	}
 
 	x := params.Coordinates[0]
@@ -300,11 +498,11 @@ func (t *UIInteract) performLongPress(ctx context.Context, params *types.UIInter
 
 	// Note: simctl doesn't have native long press, so we simulate with regular tap
 	// In a real implementation, this would use more sophisticated touch simulation
-	cmd := exec.CommandContext(ctx, "xcrun", args...)
-	output, err := cmd.CombinedOutput()
+	args = append([]string{"xcrun"}, args...)
+	output, err := t.executor.CombinedOutput(ctx, args...)
 
 	if err != nil {
-		return &types.UIInteractResult{Success: false}, fmt.Errorf("long press failed: %w\nOutput: %s", err, string(output))
+		return &types.UIInteractResult{Success: false}, commandFailed("long press failed", args, output, err)
 	}
 
 	return &types.UIInteractResult{
@@ -316,7 +514,8 @@ func (t *UIInteract) performLongPress(ctx context.Context, params *types.UIInter
 
 func (t *UIInteract) performSwipe(ctx context.Context, params *types.UIInteractParams) (*types.UIInteractResult, error) {
 	if len(params.Coordinates) < 4 {
-		return &types.UIInteractResult{Success: false}, fmt.Errorf("swipe requires 4 coordinates: start_x, start_y, end_x, end_y")
+		return &types.UIInteractResult{Success: false}, invalidParams("swipe requires 4 coordinates: start_x, start_y, end_x, end_y",
+			map[string]interface{}{"parameter": "coordinates"})
 	}
 
 	startX := params.Coordinates[0]
@@ -342,11 +541,11 @@ func (t *UIInteract) performSwipe(ctx context.Context, params *types.UIInteractP
 		strconv.FormatFloat(endX, 'f', 1, 64),
 		strconv.FormatFloat(endY, 'f', 1, 64)}
 
-	cmd := exec.CommandContext(ctx, "xcrun", args...)
-	output, err := cmd.CombinedOutput()
+	args = append([]string{"xcrun"}, args...)
+	output, err := t.executor.CombinedOutput(ctx, args...)
 
 	if err != nil {
-		return &types.UIInteractResult{Success: false}, fmt.Errorf("swipe failed: %w\nOutput: %s", err, string(output))
+		return &types.UIInteractResult{Success: false}, commandFailed("swipe failed", args, output, err)
 	}
 
 	return &types.UIInteractResult{
@@ -358,7 +557,7 @@ func (t *UIInteract) performSwipe(ctx context.Context, params *types.UIInteractP
 
 func (t *UIInteract) performType(ctx context.Context, params *types.UIInteractParams) (*types.UIInteractResult, error) {
 	if params.Text == "" {
-		return &types.UIInteractResult{Success: false}, fmt.Errorf("text is required for type action")
+		return &types.UIInteractResult{Success: false}, invalidParams("text is required for type action", map[string]interface{}{"parameter": "text"})
 	}
 
 	// In test environment, return mock result
@@ -372,11 +571,11 @@ func (t *UIInteract) performType(ctx context.Context, params *types.UIInteractPa
 
 	args := []string{"simctl", "io", params.UDID, "type", params.Text}
 
-	cmd := exec.CommandContext(ctx, "xcrun", args...)
-	output, err := cmd.CombinedOutput()
+	args = append([]string{"xcrun"}, args...)
+	output, err := t.executor.CombinedOutput(ctx, args...)
 
 	if err != nil {
-		return &types.UIInteractResult{Success: false}, fmt.Errorf("type failed: %w\nOutput: %s", err, string(output))
+		return &types.UIInteractResult{Success: false}, commandFailed("type failed", args, output, err)
 	}
 
 	return &types.UIInteractResult{
@@ -389,11 +588,11 @@ func (t *UIInteract) performType(ctx context.Context, params *types.UIInteractPa
 func (t *UIInteract) performHomeButton(ctx context.Context, params *types.UIInteractParams) (*types.UIInteractResult, error) {
 	args := []string{"simctl", "io", params.UDID, "home"}
 
-	cmd := exec.CommandContext(ctx, "xcrun", args...)
-	output, err := cmd.CombinedOutput()
+	args = append([]string{"xcrun"}, args...)
+	output, err := t.executor.CombinedOutput(ctx, args...)
 
 	if err != nil {
-		return &types.UIInteractResult{Success: false}, fmt.Errorf("home button failed: %w\nOutput: %s", err, string(output))
+		return &types.UIInteractResult{Success: false}, commandFailed("home button failed", args, output, err)
 	}
 
 	return &types.UIInteractResult{
@@ -406,11 +605,11 @@ func (t *UIInteract) performHomeButton(ctx context.Context, params *types.UIInte
 func (t *UIInteract) performShake(ctx context.Context, params *types.UIInteractParams) (*types.UIInteractResult, error) {
 	args := []string{"simctl", "io", params.UDID, "shake"}
 
-	cmd := exec.CommandContext(ctx, "xcrun", args...)
-	output, err := cmd.CombinedOutput()
+	args = append([]string{"xcrun"}, args...)
+	output, err := t.executor.CombinedOutput(ctx, args...)
 
 	if err != nil {
-		return &types.UIInteractResult{Success: false}, fmt.Errorf("shake failed: %w\nOutput: %s", err, string(output))
+		return &types.UIInteractResult{Success: false}, commandFailed("shake failed", args, output, err)
 	}
 
 	return &types.UIInteractResult{
@@ -441,7 +640,8 @@ func (t *UIInteract) performRotate(ctx context.Context, params *types.UIInteract
 	case "portrait_upside_down", "portrait_upside":
 		simctlOrientation = "portraitUpsideDown"
 	default:
-		return &types.UIInteractResult{Success: false}, fmt.Errorf("unsupported orientation: %s", orientation)
+		return &types.UIInteractResult{Success: false}, invalidParams(fmt.Sprintf("unsupported orientation: %s", orientation),
+			map[string]interface{}{"parameter": "orientation"})
 	}
 
 	// In test environment, return mock result
@@ -455,11 +655,11 @@ func (t *UIInteract) performRotate(ctx context.Context, params *types.UIInteract
 
 	args := []string{"simctl", "io", params.UDID, "orientation", simctlOrientation}
 
-	cmd := exec.CommandContext(ctx, "xcrun", args...)
-	output, err := cmd.CombinedOutput()
+	args = append([]string{"xcrun"}, args...)
+	output, err := t.executor.CombinedOutput(ctx, args...)
 
 	if err != nil {
-		return &types.UIInteractResult{Success: false}, fmt.Errorf("rotate failed: %w\nOutput: %s", err, string(output))
+		return &types.UIInteractResult{Success: false}, commandFailed("rotate failed", args, output, err)
 	}
 
 	return &types.UIInteractResult{
@@ -486,38 +686,6 @@ func (t *UIInteract) getSwipeDirection(startX, startY, endX, endY float64) strin
 	}
 }
 
-func (t *UIInteract) ensureDeviceBooted(ctx context.Context, udid string) error {
-	cmd := exec.CommandContext(ctx, "xcrun", "simctl", "list", "devices", "--json")
-	output, err := cmd.Output()
-	if err != nil {
-		return fmt.Errorf("failed to check device state: %w", err)
-	}
-
-	var deviceList struct {
-		Devices map[string][]struct {
-			UDID  string `json:"udid"`
-			State string `json:"state"`
-		} `json:"devices"`
-	}
-
-	if err := json.Unmarshal(output, &deviceList); err != nil {
-		return fmt.Errorf("failed to parse device list: %w", err)
-	}
-
-	for _, devices := range deviceList.Devices {
-		for _, device := range devices {
-			if device.UDID == udid {
-				if device.State != "Booted" {
-					return fmt.Errorf("device is %s, not Booted", device.State)
-				}
-				return nil
-			}
-		}
-	}
-
-	return fmt.Errorf("device not found")
-}
-
 func abs(x float64) float64 {
 	if x < 0 {
 		return -x
@@ -5,19 +5,27 @@ import (
 	"encoding/json"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/jontolof/xcode-build-mcp/internal/accessibility"
+	"github.com/jontolof/xcode-build-mcp/internal/xcode"
 	"github.com/jontolof/xcode-build-mcp/pkg/types"
 )
 
+// newFixtureUIInteract acts on the login screen recorded from AXe
+func newFixtureUIInteract() *UIInteract {
+	return NewUIInteract(xcode.NewExecutor(&testLogger{}), accessibility.NewFixtureBackend("../accessibility/testdata/axe_login.json"))
+}
+
 func TestUIInteract_Name(t *testing.T) {
-	tool := NewUIInteract()
+	tool := newFixtureUIInteract()
 	if got := tool.Name(); got != "ui_interact" {
 		t.Errorf("UIInteract.Name() = %v, want %v", got, "ui_interact")
 	}
 }
 
 func TestUIInteract_Description(t *testing.T) {
-	tool := NewUIInteract()
+	tool := newFixtureUIInteract()
 	desc := tool.Description()
 	if desc == "" {
 		t.Error("UIInteract.Description() returned empty string")
@@ -28,11 +36,12 @@ func TestUIInteract_Description(t *testing.T) {
 }
 
 func TestUIInteract_Execute_InvalidParams(t *testing.T) {
-	tool := NewUIInteract()
+	tool := newFixtureUIInteract()
 	ctx := context.Background()
 
 	// Test with empty params (no action specified)
-	result, err := tool.Execute(ctx, map[string]interface{}{})
+	toolResult, err := tool.Execute(ctx, map[string]interface{}{})
+	result := toolResult.Text()
 	if err == nil {
 		t.Error("Expected error for empty params, got nil")
 	}
@@ -42,7 +51,7 @@ func TestUIInteract_Execute_InvalidParams(t *testing.T) {
 }
 
 func TestUIInteract_Execute_ValidParams(t *testing.T) {
-	tool := NewUIInteract()
+	tool := newFixtureUIInteract()
 	ctx := context.Background()
 
 	params := types.UIInteractParams{
@@ -59,7 +68,8 @@ func TestUIInteract_Execute_ValidParams(t *testing.T) {
 		"y":      params.Coordinates[1],
 	}
 
-	result, err := tool.Execute(ctx, args)
+	toolResult, err := tool.Execute(ctx, args)
+	result := toolResult.Text()
 
 	// Should get a result string even if command fails
 	if result == "" {
@@ -83,7 +93,7 @@ func TestUIInteract_Execute_ValidParams(t *testing.T) {
 }
 
 func TestUIInteract_Execute_DefaultTimeout(t *testing.T) {
-	tool := NewUIInteract()
+	tool := newFixtureUIInteract()
 	ctx := context.Background()
 
 	// Test with minimal params (no timeout specified)
@@ -97,7 +107,8 @@ func TestUIInteract_Execute_DefaultTimeout(t *testing.T) {
 		"action": params.Action,
 	}
 
-	result, err := tool.Execute(ctx, args)
+	toolResult, err := tool.Execute(ctx, args)
+	result := toolResult.Text()
 
 	if result == "" {
 		t.Error("Expected non-empty result string")
@@ -121,7 +132,7 @@ func TestUIInteract_Execute_DefaultTimeout(t *testing.T) {
 }
 
 func TestUIInteract_GetSwipeDirection(t *testing.T) {
-	tool := NewUIInteract()
+	tool := newFixtureUIInteract()
 
 	tests := []struct {
 		startX, startY, endX, endY float64
@@ -147,7 +158,7 @@ func TestUIInteract_GetSwipeDirection(t *testing.T) {
 }
 
 func TestUIInteract_PerformTap_Coordinates(t *testing.T) {
-	tool := NewUIInteract()
+	tool := newFixtureUIInteract()
 	ctx := context.Background()
 
 	params := &types.UIInteractParams{
@@ -175,31 +186,26 @@ func TestUIInteract_PerformTap_Coordinates(t *testing.T) {
 }
 
 func TestUIInteract_PerformTap_Target(t *testing.T) {
-	tool := NewUIInteract()
+	tool := newFixtureUIInteract()
 	ctx := context.Background()
 
 	params := &types.UIInteractParams{
 		UDID:   "test-udid",
 		Action: "tap",
-		Target: "Submit Button",
+		Target: "login.email",
 	}
 
 	result, err := tool.performUIInteraction(ctx, params)
-	_ = result
-
-	// Target-based tap should work even without real device (it's simulated)
-	if result != nil {
-		if !strings.Contains(result.Output, "Submit Button") {
-			t.Error("Expected output to contain target element name")
-		}
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
 	}
-	if err != nil && !strings.Contains(err.Error(), "device") {
-		t.Errorf("Unexpected error: %v", err)
+	if !strings.Contains(result.Output, "login.email") {
+		t.Errorf("Expected output to contain target element name, got %q", result.Output)
 	}
 }
 
 func TestUIInteract_PerformTap_InvalidParams(t *testing.T) {
-	tool := NewUIInteract()
+	tool := newFixtureUIInteract()
 	ctx := context.Background()
 
 	// Test tap without coordinates or target
@@ -221,7 +227,7 @@ func TestUIInteract_PerformTap_InvalidParams(t *testing.T) {
 }
 
 func TestUIInteract_PerformSwipe_ValidParams(t *testing.T) {
-	tool := NewUIInteract()
+	tool := newFixtureUIInteract()
 	ctx := context.Background()
 
 	params := &types.UIInteractParams{
@@ -244,7 +250,7 @@ func TestUIInteract_PerformSwipe_ValidParams(t *testing.T) {
 }
 
 func TestUIInteract_PerformSwipe_InvalidParams(t *testing.T) {
-	tool := NewUIInteract()
+	tool := newFixtureUIInteract()
 	ctx := context.Background()
 
 	// Test swipe with insufficient coordinates
@@ -268,7 +274,7 @@ func TestUIInteract_PerformSwipe_InvalidParams(t *testing.T) {
 }
 
 func TestUIInteract_PerformType_ValidParams(t *testing.T) {
-	tool := NewUIInteract()
+	tool := newFixtureUIInteract()
 	ctx := context.Background()
 
 	params := &types.UIInteractParams{
@@ -291,7 +297,7 @@ func TestUIInteract_PerformType_ValidParams(t *testing.T) {
 }
 
 func TestUIInteract_PerformType_InvalidParams(t *testing.T) {
-	tool := NewUIInteract()
+	tool := newFixtureUIInteract()
 	ctx := context.Background()
 
 	// Test type without text
@@ -315,7 +321,7 @@ func TestUIInteract_PerformType_InvalidParams(t *testing.T) {
 }
 
 func TestUIInteract_PerformRotate_ValidParams(t *testing.T) {
-	tool := NewUIInteract()
+	tool := newFixtureUIInteract()
 	ctx := context.Background()
 
 	params := &types.UIInteractParams{
@@ -340,7 +346,7 @@ func TestUIInteract_PerformRotate_ValidParams(t *testing.T) {
 }
 
 func TestUIInteract_SupportedActions(t *testing.T) {
-	tool := NewUIInteract()
+	tool := newFixtureUIInteract()
 	ctx := context.Background()
 
 	supportedActions := []string{
@@ -384,7 +390,7 @@ func TestUIInteract_SupportedActions(t *testing.T) {
 }
 
 func TestUIInteract_UnsupportedAction(t *testing.T) {
-	tool := NewUIInteract()
+	tool := newFixtureUIInteract()
 	ctx := context.Background()
 
 	params := &types.UIInteractParams{
@@ -406,7 +412,7 @@ func TestUIInteract_UnsupportedAction(t *testing.T) {
 }
 
 func TestUIInteract_MissingAction(t *testing.T) {
-	tool := NewUIInteract()
+	tool := newFixtureUIInteract()
 	ctx := context.Background()
 
 	params := &types.UIInteractParams{
@@ -427,7 +433,7 @@ func TestUIInteract_MissingAction(t *testing.T) {
 }
 
 func TestUIInteract_MissingUDID(t *testing.T) {
-	tool := NewUIInteract()
+	tool := newFixtureUIInteract()
 	ctx := context.Background()
 
 	params := &types.UIInteractParams{
@@ -446,3 +452,212 @@ func TestUIInteract_MissingUDID(t *testing.T) {
 		t.Errorf("Expected 'UDID is required' error, got: %v", err)
 	}
 }
+
+func TestUIInteract_ElementActions(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   map[string]interface{}
+		inputs []accessibility.Input
+	}{
+		{
+			name:   "tap by identifier",
+			args:   map[string]interface{}{"action": "tap", "element_id": "tab.settings"},
+			inputs: []accessibility.Input{{Kind: "tap", X: 294.5, Y: 793}},
+		},
+		{
+			name:   "tap by label",
+			args:   map[string]interface{}{"action": "tap", "label": "home"},
+			inputs: []accessibility.Input{{Kind: "tap", X: 98, Y: 793}},
+		},
+		{
+			name: "long press by predicate",
+			args: map[string]interface{}{
+				"action":    "long_press",
+				"predicate": map[string]interface{}{"type": "Button", "label_contains": "log"},
+				"duration":  1.5,
+			},
+			inputs: []accessibility.Input{{Kind: "tap", X: 196.5, Y: 341, Duration: 1500 * time.Millisecond}},
+		},
+		{
+			name: "type into field",
+			args: map[string]interface{}{"action": "type", "element_id": "login.email", "text": "a@b.c"},
+			inputs: []accessibility.Input{
+				{Kind: "tap", X: 196.5, Y: 202},
+				{Kind: "type", Text: "a@b.c"},
+			},
+		},
+		{
+			name: "second match by index",
+			args: map[string]interface{}{
+				"action":    "tap",
+				"predicate": map[string]interface{}{"traits": []interface{}{"button"}, "index": float64(2)},
+			},
+			inputs: []accessibility.Input{{Kind: "tap", X: 294.5, Y: 793}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := accessibility.NewFixtureBackend("../accessibility/testdata/axe_login.json")
+			tool := NewUIInteract(xcode.NewExecutor(&testLogger{}), backend)
+
+			args := map[string]interface{}{"udid": "test-udid"}
+			for k, v := range tt.args {
+				args[k] = v
+			}
+
+			toolResult, err := tool.Execute(context.Background(), args)
+			if err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			result := toolResult.Structured.(*types.UIInteractResult)
+			if !result.Success || !result.Found || result.Element == nil {
+				t.Errorf("Expected a successful element action, got %+v", result)
+			}
+
+			inputs := backend.Inputs()
+			if len(inputs) != len(tt.inputs) {
+				t.Fatalf("Expected inputs %+v, got %+v", tt.inputs, inputs)
+			}
+			for i := range inputs {
+				if inputs[i] != tt.inputs[i] {
+					t.Errorf("Input %d: expected %+v, got %+v", i, tt.inputs[i], inputs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestUIInteract_ElementErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args map[string]interface{}
+		code types.ErrorCode
+	}{
+		{
+			name: "no match",
+			args: map[string]interface{}{"action": "tap", "element_id": "login.missing"},
+			code: types.ErrCodeElementNotFound,
+		},
+		{
+			name: "off screen",
+			args: map[string]interface{}{"action": "tap", "element_id": "login.support"},
+			code: types.ErrCodeElementNotFound,
+		},
+		{
+			name: "unknown predicate field",
+			args: map[string]interface{}{"action": "tap", "predicate": map[string]interface{}{"name": "Log In"}},
+			code: types.ErrCodeInvalidParams,
+		},
+		{
+			name: "scroll_to without element",
+			args: map[string]interface{}{"action": "scroll_to"},
+			code: types.ErrCodeInvalidParams,
+		},
+		{
+			name: "unknown direction",
+			args: map[string]interface{}{"action": "scroll_to", "element_id": "login.support", "direction": "sideways"},
+			code: types.ErrCodeInvalidParams,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := map[string]interface{}{"udid": "test-udid"}
+			for k, v := range tt.args {
+				args[k] = v
+			}
+
+			_, err := newFixtureUIInteract().Execute(context.Background(), args)
+			if !types.IsXcodeError(err, tt.code) {
+				t.Errorf("Expected %s, got %v", tt.code, err)
+			}
+		})
+	}
+}
+
+// scrollingBackend moves the fixture's content up by the length of each
+// swipe, like a scroll view following the finger
+type scrollingBackend struct {
+	*accessibility.FixtureBackend
+	offset float64
+}
+
+func (b *scrollingBackend) Describe(ctx context.Context, udid string) (*types.UIElement, error) {
+	root, err := b.FixtureBackend.Describe(ctx, udid)
+	if err != nil {
+		return nil, err
+	}
+	root.Walk(func(e *types.UIElement, depth int) bool {
+		if depth > 1 {
+			e.Frame.Y -= b.offset
+		}
+		return true
+	})
+	return root, nil
+}
+
+func (b *scrollingBackend) Swipe(ctx context.Context, udid string, fromX, fromY, toX, toY float64, duration time.Duration) error {
+	b.offset += fromY - toY
+	return b.FixtureBackend.Swipe(ctx, udid, fromX, fromY, toX, toY, duration)
+}
+
+func TestUIInteract_ScrollTo(t *testing.T) {
+	defer func(settle time.Duration) { scrollSettle = settle }(scrollSettle)
+	scrollSettle = 0
+
+	backend := &scrollingBackend{FixtureBackend: accessibility.NewFixtureBackend("../accessibility/testdata/axe_login.json")}
+	tool := NewUIInteract(xcode.NewExecutor(&testLogger{}), backend)
+
+	toolResult, err := tool.Execute(context.Background(), map[string]interface{}{
+		"udid":       "test-udid",
+		"action":     "scroll_to",
+		"element_id": "login.support",
+	})
+	if err != nil {
+		t.Fatalf("scroll_to failed: %v", err)
+	}
+
+	result := toolResult.Structured.(*types.UIInteractResult)
+	if result.Scrolls != 3 {
+		t.Errorf("Expected 3 scrolls, got %d", result.Scrolls)
+	}
+
+	// Swipes run upwards through the middle of the scroll view (y 400-768)
+	for _, input := range backend.Inputs() {
+		if input.Kind != "swipe" || input.X != 196.5 || input.Y != 676 || input.ToY != 492 {
+			t.Errorf("Unexpected swipe: %+v", input)
+		}
+	}
+
+	toolResult, err = tool.Execute(context.Background(), map[string]interface{}{
+		"udid":        "test-udid",
+		"action":      "scroll_to",
+		"element_id":  "login.nowhere",
+		"max_scrolls": float64(2),
+	})
+	if !types.IsXcodeError(err, types.ErrCodeElementNotFound) {
+		t.Errorf("Expected ELEMENT_NOT_FOUND after max_scrolls, got %v", err)
+	}
+	if result := toolResult.Structured.(*types.UIInteractResult); result.Success || result.Scrolls != 2 {
+		t.Errorf("Expected the failed result to report 2 scrolls, got %+v", result)
+	}
+}
+
+func TestUIInteract_ElementErrorResult(t *testing.T) {
+	toolResult, err := newFixtureUIInteract().Execute(context.Background(), map[string]interface{}{
+		"udid":       "test-udid",
+		"action":     "tap",
+		"element_id": "login.support",
+	})
+	if !types.IsXcodeError(err, types.ErrCodeElementNotFound) {
+		t.Fatalf("Expected ELEMENT_NOT_FOUND, got %v", err)
+	}
+	result := toolResult.Structured.(*types.UIInteractResult)
+	if result.Success || result.Matches != 1 || result.Element == nil || result.Element.Identifier != "login.support" {
+		t.Errorf("Expected the off-screen element in the result, got %+v", result)
+	}
+	if result.Duration <= 0 {
+		t.Error("Expected the failed result to carry its duration")
+	}
+}
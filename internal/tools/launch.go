@@ -15,12 +15,13 @@ import (
 )
 
 type LaunchAppTool struct {
-	name        string
-	description string
-	schema      map[string]interface{}
-	executor    *xcode.Executor
-	parser      *xcode.Parser
-	logger      common.Logger
+	name         string
+	description  string
+	schema       map[string]interface{}
+	outputSchema map[string]interface{}
+	executor     *xcode.Executor
+	parser       *xcode.Parser
+	logger       common.Logger
 }
 
 func NewLaunchAppTool(executor *xcode.Executor, parser *xcode.Parser, logger common.Logger) *LaunchAppTool {
@@ -58,12 +59,13 @@ func NewLaunchAppTool(executor *xcode.Executor, parser *xcode.Parser, logger com
 	}, []string{"bundle_id"})
 
 	return &LaunchAppTool{
-		name:        "launch_app",
-		description: "Launch iOS/tvOS/watchOS apps on simulators or devices",
-		schema:      schema,
-		executor:    executor,
-		parser:      parser,
-		logger:      logger,
+		name:         "launch_app",
+		description:  "Launch iOS/tvOS/watchOS apps on simulators or devices",
+		schema:       schema,
+		outputSchema: types.SchemaFor(types.AppLaunchResult{}),
+		executor:     executor,
+		parser:       parser,
+		logger:       logger,
 	}
 }
 
@@ -79,10 +81,14 @@ func (t *LaunchAppTool) InputSchema() map[string]interface{} {
 	return t.schema
 }
 
-func (t *LaunchAppTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+func (t *LaunchAppTool) OutputSchema() map[string]interface{} {
+	return t.outputSchema
+}
+
+func (t *LaunchAppTool) Execute(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error) {
 	params, err := t.parseParams(args)
 	if err != nil {
-		return "", fmt.Errorf("invalid parameters: %w", err)
+		return nil, toolError(err, types.ErrCodeInvalidParams, "invalid parameters", nil)
 	}
 
 	start := time.Now()
@@ -99,7 +105,8 @@ func (t *LaunchAppTool) Execute(ctx context.Context, args map[string]interface{}
 	if targetUDID == "" {
 		detectedUDID, err := t.selectBestDevice(ctx, params.DeviceType)
 		if err != nil {
-			return "", fmt.Errorf("failed to auto-detect device: %w", err)
+			return nil, toolError(err, types.ErrCodeSimulatorNotFound, "failed to auto-detect device",
+				map[string]interface{}{"device_type": params.DeviceType})
 		}
 		targetUDID = detectedUDID
 		t.logger.Printf("Auto-selected device: %s", targetUDID)
@@ -107,13 +114,15 @@ func (t *LaunchAppTool) Execute(ctx context.Context, args map[string]interface{}
 
 	// Pre-flight checks to provide better error messages
 	if err := t.validateLaunchPreconditions(ctx, params, targetUDID); err != nil {
-		return "", err // Already formatted with actionable message
+		return nil, err // Already formatted with actionable message
 	}
 
 	// Launch the app
 	result, err := t.launchApp(ctx, params, targetUDID)
 	if err != nil {
-		return "", fmt.Errorf("failed to launch app: %w", err)
+		partial, _ := types.NewToolResult(result, true)
+		return partial, toolError(err, types.ErrCodeLaunchFailed, "failed to launch app",
+			map[string]interface{}{"bundle_id": params.BundleID, "udid": targetUDID})
 	}
 
 	duration := time.Since(start)
@@ -121,13 +130,7 @@ func (t *LaunchAppTool) Execute(ctx context.Context, args map[string]interface{}
 
 	t.logger.Printf("Successfully launched app %s in %v", params.BundleID, duration)
 
-	// Convert result to JSON string
-	resultJSON, err := json.Marshal(result)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal result: %w", err)
-	}
-
-	return string(resultJSON), nil
+	return types.NewToolResult(result, !result.Success)
 }
 
 func (t *LaunchAppTool) parseParams(args map[string]interface{}) (*types.AppLaunchParams, error) {
@@ -192,11 +195,12 @@ func (t *LaunchAppTool) selectBestDevice(ctx context.Context, deviceTypeFilter s
 
 	result, err := t.executor.ExecuteCommand(ctx, args)
 	if err != nil {
-		return "", err
+		return "", toolError(err, types.ErrCodeCommandFailed, "failed to list devices", nil)
 	}
 
 	if !result.Success() {
-		return "", fmt.Errorf("failed to list devices: %s", result.StderrOutput)
+		return "", types.NewXcodeError(types.ErrCodeCommandFailed, fmt.Sprintf("failed to list devices: %s", result.StderrOutput),
+			commandDetails(args, result.ExitCode, result.StderrOutput))
 	}
 
 	// Parse JSON output (same structure as install_app)
@@ -211,7 +215,7 @@ func (t *LaunchAppTool) selectBestDevice(ctx context.Context, deviceTypeFilter s
 	}
 
 	if err := json.Unmarshal([]byte(result.Output), &simctlOutput); err != nil {
-		return "", fmt.Errorf("failed to parse device list: %w", err)
+		return "", types.NewXcodeErrorWithCause(types.ErrCodeCommandFailed, "failed to parse device list", err, nil)
 	}
 
 	// Find booted devices (we can only launch apps on booted devices)
@@ -273,7 +277,8 @@ func (t *LaunchAppTool) selectBestDevice(ctx context.Context, deviceTypeFilter s
 	}
 
 	if len(candidates) == 0 {
-		return "", fmt.Errorf("no suitable booted devices found")
+		return "", types.NewXcodeError(types.ErrCodeSimulatorNotFound, "no suitable booted devices found",
+			map[string]interface{}{"device_type": deviceTypeFilter})
 	}
 
 	// Sort by score (highest first)
@@ -295,12 +300,16 @@ func (t *LaunchAppTool) validateLaunchPreconditions(ctx context.Context, params
 	if err != nil {
 		t.logger.Printf("Warning: Could not check simulator state: %v", err)
 	} else if !isBooted {
-		return fmt.Errorf("simulator %s is not booted. Boot it first using: simulator_control with action='boot' and udid='%s'", udid, udid)
+		return types.NewXcodeError(types.ErrCodeSimulatorNotBooted,
+			fmt.Sprintf("simulator %s is not booted. Boot it first using: simulator_control with action='boot' and udid='%s'", udid, udid),
+			map[string]interface{}{"udid": udid})
 	}
 
 	// Check if app is installed (best effort - don't fail if we can't check)
 	if isInstalled, err := t.isAppInstalled(ctx, params.BundleID, udid); err == nil && !isInstalled {
-		return fmt.Errorf("app '%s' is not installed on device %s. Install it first using: install_app with app_path pointing to your .app bundle", params.BundleID, udid)
+		return types.NewXcodeError(types.ErrCodeAppNotFound,
+			fmt.Sprintf("app '%s' is not installed on device %s. Install it first using: install_app with app_path pointing to your .app bundle", params.BundleID, udid),
+			map[string]interface{}{"bundle_id": params.BundleID, "udid": udid})
 	}
 
 	return nil
@@ -364,7 +373,7 @@ func (t *LaunchAppTool) launchApp(ctx context.Context, params *types.AppLaunchPa
 	cmdResult, err := t.executor.ExecuteCommand(ctx, args)
 	if err != nil {
 		result.Output = fmt.Sprintf("Command execution error: %v", err)
-		return result, err
+		return result, toolError(err, types.ErrCodeCommandFailed, "failed to run simctl launch", nil)
 	}
 
 	result.Output = cmdResult.Output
@@ -378,16 +387,19 @@ func (t *LaunchAppTool) launchApp(ctx context.Context, params *types.AppLaunchPa
 			errorOutput = cmdResult.Output
 		}
 
+		details := commandDetails(args, cmdResult.ExitCode, errorOutput)
+
 		// Check for common error cases and provide better error messages
 		if strings.Contains(errorOutput, "Unable to launch") {
-			return result, fmt.Errorf("unable to launch app: %s", strings.TrimSpace(errorOutput))
+			return result, types.NewXcodeError(types.ErrCodeLaunchFailed, fmt.Sprintf("unable to launch app: %s", strings.TrimSpace(errorOutput)), details)
 		} else if strings.Contains(errorOutput, "device is not booted") {
-			return result, fmt.Errorf("target device is not booted")
+			return result, types.NewXcodeError(types.ErrCodeSimulatorNotBooted, "target device is not booted", details)
 		} else if strings.Contains(errorOutput, "App is not installed") {
-			return result, fmt.Errorf("app with bundle ID %s is not installed on the device", params.BundleID)
+			return result, types.NewXcodeError(types.ErrCodeAppNotFound, fmt.Sprintf("app with bundle ID %s is not installed on the device", params.BundleID), details)
 		}
 
-		return result, fmt.Errorf("launch failed (exit code %d): %s", cmdResult.ExitCode, strings.TrimSpace(errorOutput))
+		return result, types.NewXcodeError(types.ErrCodeLaunchFailed,
+			fmt.Sprintf("launch failed (exit code %d): %s", cmdResult.ExitCode, strings.TrimSpace(errorOutput)), details)
 	}
 
 	result.Success = true
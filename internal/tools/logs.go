@@ -1,26 +1,27 @@
 package tools
 
 import (
-	"bufio"
+	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
-	"os/exec"
 	"regexp"
 	"strings"
 	"time"
 
+	"github.com/jontolof/xcode-build-mcp/internal/xcode"
 	"github.com/jontolof/xcode-build-mcp/pkg/types"
 )
 
 type CaptureLogs struct {
-	name        string
-	description string
-	schema      map[string]interface{}
+	name         string
+	description  string
+	schema       map[string]interface{}
+	outputSchema map[string]interface{}
+	executor     *xcode.Executor
 }
 
-func NewCaptureLogs() *CaptureLogs {
+func NewCaptureLogs(executor *xcode.Executor) *CaptureLogs {
 	schema := createJSONSchema("object", map[string]interface{}{
 		"udid": map[string]interface{}{
 			"type":        "string",
@@ -53,9 +54,11 @@ func NewCaptureLogs() *CaptureLogs {
 	}, []string{})
 
 	return &CaptureLogs{
-		name:        "capture_logs",
-		description: "Capture and stream device/simulator logs with filtering and real-time monitoring capabilities",
-		schema:      schema,
+		name:         "capture_logs",
+		description:  "Capture and stream device/simulator logs with filtering and real-time monitoring capabilities",
+		schema:       schema,
+		outputSchema: types.SchemaFor(types.LogCaptureResult{}),
+		executor:     executor,
 	}
 }
 
@@ -71,7 +74,11 @@ func (t *CaptureLogs) InputSchema() map[string]interface{} {
 	return t.schema
 }
 
-func (t *CaptureLogs) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+func (t *CaptureLogs) OutputSchema() map[string]interface{} {
+	return t.outputSchema
+}
+
+func (t *CaptureLogs) Execute(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error) {
 	var p types.LogCaptureParams
 
 	// Parse parameters from args
@@ -115,14 +122,14 @@ func (t *CaptureLogs) Execute(ctx context.Context, args map[string]interface{})
 
 	// Auto-select device if not specified
 	if p.UDID == "" && p.DeviceType == "" {
-		simulator, err := selectBestSimulator("")
+		simulator, err := selectBestSimulator(t.executor, "")
 		if err != nil {
 			result := &types.LogCaptureResult{
 				Success:  false,
 				Duration: time.Since(start),
 			}
-			resultJSON, _ := json.Marshal(result)
-			return string(resultJSON), fmt.Errorf("failed to auto-select device: %w", err)
+			partial, _ := types.NewToolResult(result, true)
+			return partial, toolError(err, types.ErrCodeSimulatorNotFound, "failed to auto-select device", nil)
 		}
 		p.UDID = simulator.UDID
 		p.DeviceType = simulator.DeviceType
@@ -142,16 +149,12 @@ func (t *CaptureLogs) Execute(ctx context.Context, args map[string]interface{})
 			Success:  false,
 			Duration: time.Since(start),
 		}
-		resultJSON, _ := json.Marshal(errorResult)
-		return string(resultJSON), err
+		partial, _ := types.NewToolResult(errorResult, true)
+		return partial, err
 	}
 
 	result.Duration = time.Since(start)
-	resultJSON, err := json.Marshal(result)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal result: %w", err)
-	}
-	return string(resultJSON), nil
+	return types.NewToolResult(result, !result.Success)
 }
 
 func (t *CaptureLogs) captureLogs(ctx context.Context, params *types.LogCaptureParams) (*types.LogCaptureResult, error) {
@@ -161,7 +164,7 @@ func (t *CaptureLogs) captureLogs(ctx context.Context, params *types.LogCaptureP
 	if params.UDID != "" {
 		args = append(args, params.UDID)
 	} else {
-		return nil, fmt.Errorf("device UDID is required")
+		return nil, invalidParams("device UDID is required", map[string]interface{}{"parameter": "udid"})
 	}
 
 	// Use 'log show' instead of 'log stream' to prevent hanging
@@ -204,58 +207,39 @@ func (t *CaptureLogs) captureLogs(ctx context.Context, params *types.LogCaptureP
 		fmt.Printf("DEBUG: capture_logs executing: xcrun %s\n", strings.Join(args, " "))
 	}
 
-	// Execute log command
-	cmd := exec.CommandContext(cmdCtx, "xcrun", args...)
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
-	}
+	// Execute log command, stopping it once one line more than requested
+	// has arrived
+	args = append([]string{"xcrun"}, args...)
+	streamCtx, stop := context.WithCancel(cmdCtx)
+	defer stop()
 
-	if err := cmd.Start(); err != nil {
-		return nil, fmt.Errorf("failed to start log command: %w", err)
-	}
+	// Regex patterns for parsing log lines
+	logPattern := regexp.MustCompile(`^(\d{4}-\d{2}-\d{2}\s\d{2}:\d{2}:\d{2}\.\d+[+-]\d{4})\s+(\w+)\s+(\w+)\s+(\[.*?\])?\s*(.*)$`)
 
 	// Parse log output
 	var logEntries []types.LogEntry
-	scanner := bufio.NewScanner(stdout)
-	lineCount := 0
 	truncated := false
-
-	// Regex patterns for parsing log lines
-	logPattern := regexp.MustCompile(`^(\d{4}-\d{2}-\d{2}\s\d{2}:\d{2}:\d{2}\.\d+[+-]\d{4})\s+(\w+)\s+(\w+)\s+(\[.*?\])?\s*(.*)$`)
-
-	for scanner.Scan() && lineCount < params.MaxLines {
-		line := scanner.Text()
-		if line == "" {
-			continue
+	stdout := &logLineWriter{observe: func(line string) {
+		if line == "" || truncated {
+			return
 		}
-
-		entry := t.parseLogLine(line, logPattern)
-		if entry != nil {
-			logEntries = append(logEntries, *entry)
-			lineCount++
+		if len(logEntries) >= params.MaxLines {
+			truncated = true
+			stop()
+			return
 		}
-
-		// Check for cancellation
-		select {
-		case <-cmdCtx.Done():
-			break
-		default:
+		if entry := t.parseLogLine(line, logPattern); entry != nil {
+			logEntries = append(logEntries, *entry)
 		}
-	}
-
-	// Check if we hit the line limit
-	if lineCount >= params.MaxLines && scanner.Scan() {
-		truncated = true
-	}
+	}}
 
-	// Stop the command
-	if err := cmd.Process.Kill(); err != nil && !strings.Contains(err.Error(), "process already finished") {
-		// Log warning but don't fail
+	// The command is killed on truncation or timeout, so its exit status
+	// says nothing about the logs read
+	if _, err := t.executor.Run(streamCtx, xcode.Command{Args: args, Stdout: stdout}); err != nil {
+		return nil, toolError(err, types.ErrCodeCommandFailed, "failed to start log command",
+			map[string]interface{}{"command": strings.Join(args, " ")})
 	}
-
-	// Wait for command to exit
-	cmd.Wait()
+	stdout.flush()
 
 	return &types.LogCaptureResult{
 		Success:   true,
@@ -300,3 +284,30 @@ func (t *CaptureLogs) parseLogLine(line string, pattern *regexp.Regexp) *types.L
 		Message:   strings.TrimSpace(message),
 	}
 }
+
+// logLineWriter hands each complete line written to it to observe
+type logLineWriter struct {
+	partial []byte
+	observe func(line string)
+}
+
+func (w *logLineWriter) Write(p []byte) (int, error) {
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		w.observe(strings.TrimSuffix(string(w.partial[:i]), "\r"))
+		w.partial = w.partial[i+1:]
+	}
+	return len(p), nil
+}
+
+// flush hands an unterminated last line to observe
+func (w *logLineWriter) flush() {
+	if len(w.partial) > 0 {
+		w.observe(strings.TrimSuffix(string(w.partial), "\r"))
+		w.partial = nil
+	}
+}
@@ -0,0 +1,246 @@
+package tools
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jontolof/xcode-build-mcp/internal/plist"
+	"github.com/jontolof/xcode-build-mcp/pkg/types"
+)
+
+// xcscheme is the part of an .xcscheme file that names targets and test plans
+type xcscheme struct {
+	BuildEntries []xcschemeBuildable `xml:"BuildAction>BuildActionEntries>BuildActionEntry>BuildableReference"`
+	Testables    []struct {
+		Skipped   string            `xml:"skipped,attr"`
+		Buildable xcschemeBuildable `xml:"BuildableReference"`
+	} `xml:"TestAction>Testables>TestableReference"`
+	TestPlans []struct {
+		Reference string `xml:"reference,attr"`
+		Default   string `xml:"default,attr"`
+	} `xml:"TestAction>TestPlans>TestPlanReference"`
+}
+
+type xcschemeBuildable struct {
+	BlueprintName string `xml:"BlueprintName,attr"`
+}
+
+// schemeDefinition is what list_schemes reports from an .xcscheme file
+type schemeDefinition struct {
+	Targets   []string
+	TestPlans []types.TestPlanInfo
+}
+
+// findSchemeFile returns the .xcscheme of schemeName in a project or
+// workspace, looking at shared schemes before user schemes. Schemes of a
+// workspace usually live in its member projects, which are searched too.
+func findSchemeFile(containerPath, schemeName string) string {
+	containers := []string{containerPath}
+	if strings.HasSuffix(containerPath, ".xcworkspace") {
+		containers = append(containers, workspaceProjects(containerPath)...)
+	}
+
+	for _, container := range containers {
+		candidates := []string{filepath.Join(container, "xcshareddata", "xcschemes", schemeName+".xcscheme")}
+		userSchemes, _ := filepath.Glob(filepath.Join(container, "xcuserdata", "*.xcuserdatad", "xcschemes", schemeName+".xcscheme"))
+		candidates = append(candidates, userSchemes...)
+
+		for _, candidate := range candidates {
+			if _, err := os.Stat(candidate); err == nil {
+				return candidate
+			}
+		}
+	}
+	return ""
+}
+
+// workspaceProjects lists the .xcodeproj files referenced by a workspace
+func workspaceProjects(workspacePath string) []string {
+	data, err := os.ReadFile(filepath.Join(workspacePath, "contents.xcworkspacedata"))
+	if err != nil {
+		return nil
+	}
+
+	var workspace struct {
+		FileRefs []struct {
+			Location string `xml:"location,attr"`
+		} `xml:"FileRef"`
+		Groups []struct {
+			FileRefs []struct {
+				Location string `xml:"location,attr"`
+			} `xml:"FileRef"`
+		} `xml:"Group"`
+	}
+	if err := xml.Unmarshal(data, &workspace); err != nil {
+		return nil
+	}
+
+	locations := make([]string, 0, len(workspace.FileRefs))
+	for _, ref := range workspace.FileRefs {
+		locations = append(locations, ref.Location)
+	}
+	for _, group := range workspace.Groups {
+		for _, ref := range group.FileRefs {
+			locations = append(locations, ref.Location)
+		}
+	}
+
+	var projects []string
+	for _, location := range locations {
+		path := resolveContainerReference(filepath.Dir(workspacePath), location)
+		if strings.HasSuffix(path, ".xcodeproj") {
+			projects = append(projects, path)
+		}
+	}
+	return projects
+}
+
+// resolveContainerReference resolves Xcode references such as
+// "group:App.xcodeproj" or "container:App.xctestplan" against dir
+func resolveContainerReference(dir, reference string) string {
+	kind, path, found := strings.Cut(reference, ":")
+	if !found {
+		path = kind
+	} else if kind == "absolute" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, filepath.FromSlash(path))
+}
+
+// readSchemeFile reads the targets and test plans of an .xcscheme file.
+// Test plan references are relative to the directory holding the project.
+func readSchemeFile(schemePath string) (*schemeDefinition, error) {
+	data, err := os.ReadFile(schemePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scheme: %w", err)
+	}
+
+	var scheme xcscheme
+	if err := xml.Unmarshal(data, &scheme); err != nil {
+		return nil, fmt.Errorf("failed to parse scheme %s: %w", schemePath, err)
+	}
+
+	definition := &schemeDefinition{}
+	seen := make(map[string]bool)
+	addTarget := func(name string) {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			definition.Targets = append(definition.Targets, name)
+		}
+	}
+	for _, entry := range scheme.BuildEntries {
+		addTarget(entry.BlueprintName)
+	}
+	for _, testable := range scheme.Testables {
+		if testable.Skipped != "YES" {
+			addTarget(testable.Buildable.BlueprintName)
+		}
+	}
+
+	// Scheme files live in <container>/xcshareddata/xcschemes or
+	// <container>/xcuserdata/<user>.xcuserdatad/xcschemes
+	container := filepath.Dir(filepath.Dir(filepath.Dir(schemePath)))
+	if filepath.Base(container) == "xcuserdata" {
+		container = filepath.Dir(container)
+	}
+	projectDir := filepath.Dir(container)
+
+	for _, ref := range scheme.TestPlans {
+		planPath := resolveContainerReference(projectDir, ref.Reference)
+		plan, err := readTestPlan(planPath)
+		if err != nil {
+			plan = &types.TestPlanInfo{Name: strings.TrimSuffix(filepath.Base(planPath), ".xctestplan"), Path: planPath}
+		}
+		plan.Default = ref.Default == "YES"
+		definition.TestPlans = append(definition.TestPlans, *plan)
+	}
+
+	return definition, nil
+}
+
+// readTestPlan reads the configurations and enabled test targets of an
+// .xctestplan file
+func readTestPlan(planPath string) (*types.TestPlanInfo, error) {
+	data, err := os.ReadFile(planPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read test plan: %w", err)
+	}
+	root, err := plist.UnmarshalDict(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse test plan %s: %w", planPath, err)
+	}
+
+	plan := &types.TestPlanInfo{
+		Name: strings.TrimSuffix(filepath.Base(planPath), ".xctestplan"),
+		Path: planPath,
+	}
+	configurations, _ := root["configurations"].([]interface{})
+	for _, item := range configurations {
+		if configuration, ok := item.(map[string]interface{}); ok {
+			if name, ok := configuration["name"].(string); ok {
+				plan.Configurations = append(plan.Configurations, name)
+			}
+		}
+	}
+	testTargets, _ := root["testTargets"].([]interface{})
+	for _, item := range testTargets {
+		testTarget, ok := item.(map[string]interface{})
+		if !ok || testTarget["enabled"] == false {
+			continue
+		}
+		if target, ok := testTarget["target"].(map[string]interface{}); ok {
+			if name, ok := target["name"].(string); ok {
+				plan.TestTargets = append(plan.TestTargets, name)
+			}
+		}
+	}
+	return plan, nil
+}
+
+// readProjectTargets lists the targets of an .xcodeproj from its
+// project.pbxproj, without running xcodebuild
+func readProjectTargets(projectPath string) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(projectPath, "project.pbxproj"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read project: %w", err)
+	}
+	project, err := plist.UnmarshalDict(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse project %s: %w", projectPath, err)
+	}
+
+	objects, _ := project["objects"].(map[string]interface{})
+	object := func(id interface{}) map[string]interface{} {
+		key, _ := id.(string)
+		dict, _ := objects[key].(map[string]interface{})
+		return dict
+	}
+
+	// The root object lists targets in the order Xcode shows them
+	var targets []string
+	if root := object(project["rootObject"]); root != nil {
+		ids, _ := root["targets"].([]interface{})
+		for _, id := range ids {
+			if name, ok := object(id)["name"].(string); ok {
+				targets = append(targets, name)
+			}
+		}
+		return targets, nil
+	}
+
+	for _, value := range objects {
+		target, _ := value.(map[string]interface{})
+		switch target["isa"] {
+		case "PBXNativeTarget", "PBXAggregateTarget", "PBXLegacyTarget":
+			if name, ok := target["name"].(string); ok {
+				targets = append(targets, name)
+			}
+		}
+	}
+	sort.Strings(targets)
+	return targets, nil
+}
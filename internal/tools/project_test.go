@@ -0,0 +1,151 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jontolof/xcode-build-mcp/pkg/types"
+)
+
+const testScheme = `<?xml version="1.0" encoding="UTF-8"?>
+<Scheme LastUpgradeVersion = "1500" version = "1.7">
+   <BuildAction parallelizeBuildables = "YES" buildImplicitDependencies = "YES">
+      <BuildActionEntries>
+         <BuildActionEntry buildForTesting = "YES" buildForRunning = "YES">
+            <BuildableReference BuildableIdentifier = "primary" BuildableName = "App.app" BlueprintName = "App" ReferencedContainer = "container:App.xcodeproj">
+            </BuildableReference>
+         </BuildActionEntry>
+      </BuildActionEntries>
+   </BuildAction>
+   <TestAction buildConfiguration = "Debug">
+      <TestPlans>
+         <TestPlanReference reference = "container:App.xctestplan" default = "YES">
+         </TestPlanReference>
+         <TestPlanReference reference = "container:Missing.xctestplan">
+         </TestPlanReference>
+      </TestPlans>
+      <Testables>
+         <TestableReference skipped = "NO">
+            <BuildableReference BlueprintName = "AppTests" ReferencedContainer = "container:App.xcodeproj">
+            </BuildableReference>
+         </TestableReference>
+         <TestableReference skipped = "YES">
+            <BuildableReference BlueprintName = "AppUITests" ReferencedContainer = "container:App.xcodeproj">
+            </BuildableReference>
+         </TestableReference>
+      </Testables>
+   </TestAction>
+</Scheme>
+`
+
+const testPlan = `{
+  "configurations" : [
+    { "id" : "A", "name" : "English", "options" : { "language" : "en" } },
+    { "id" : "B", "name" : "German", "options" : { "language" : "de" } }
+  ],
+  "testTargets" : [
+    { "target" : { "containerPath" : "container:App.xcodeproj", "identifier" : "1", "name" : "AppTests" } },
+    { "enabled" : false, "target" : { "containerPath" : "container:App.xcodeproj", "identifier" : "2", "name" : "AppUITests" } }
+  ],
+  "version" : 1
+}
+`
+
+// writeProject lays out App.xcworkspace and App.xcodeproj with a shared App
+// scheme and App.xctestplan in dir
+func writeProject(t *testing.T, dir string) {
+	t.Helper()
+
+	pbxproj, err := os.ReadFile("../plist/testdata/project.pbxproj")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"App.xcodeproj/project.pbxproj":                     string(pbxproj),
+		"App.xcodeproj/xcshareddata/xcschemes/App.xcscheme": testScheme,
+		"App.xcworkspace/contents.xcworkspacedata":          `<Workspace version = "1.0"><FileRef location = "group:App.xcodeproj"></FileRef></Workspace>`,
+		"App.xctestplan": testPlan,
+		"App.xcodeproj/xcuserdata/me.xcuserdatad/xcschemes/Mine.xcscheme": testScheme,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFindSchemeFile(t *testing.T) {
+	dir := t.TempDir()
+	writeProject(t, dir)
+	shared := filepath.Join(dir, "App.xcodeproj", "xcshareddata", "xcschemes", "App.xcscheme")
+	user := filepath.Join(dir, "App.xcodeproj", "xcuserdata", "me.xcuserdatad", "xcschemes", "Mine.xcscheme")
+
+	tests := []struct {
+		container string
+		scheme    string
+		want      string
+	}{
+		{filepath.Join(dir, "App.xcodeproj"), "App", shared},
+		{filepath.Join(dir, "App.xcodeproj"), "Mine", user},
+		{filepath.Join(dir, "App.xcworkspace"), "App", shared},
+		{filepath.Join(dir, "App.xcodeproj"), "Unknown", ""},
+	}
+
+	for _, tt := range tests {
+		if got := findSchemeFile(tt.container, tt.scheme); got != tt.want {
+			t.Errorf("findSchemeFile(%s, %s) = %q, want %q", filepath.Base(tt.container), tt.scheme, got, tt.want)
+		}
+	}
+}
+
+func TestReadSchemeFile(t *testing.T) {
+	dir := t.TempDir()
+	writeProject(t, dir)
+
+	for _, scheme := range []string{"App", "Mine"} {
+		definition, err := readSchemeFile(findSchemeFile(filepath.Join(dir, "App.xcodeproj"), scheme))
+		if err != nil {
+			t.Fatalf("readSchemeFile failed: %v", err)
+		}
+
+		if want := []string{"App", "AppTests"}; !reflect.DeepEqual(definition.Targets, want) {
+			t.Errorf("Targets = %v, want %v", definition.Targets, want)
+		}
+		want := []types.TestPlanInfo{
+			{
+				Name:           "App",
+				Path:           filepath.Join(dir, "App.xctestplan"),
+				Default:        true,
+				Configurations: []string{"English", "German"},
+				TestTargets:    []string{"AppTests"},
+			},
+			{Name: "Missing", Path: filepath.Join(dir, "Missing.xctestplan")},
+		}
+		if !reflect.DeepEqual(definition.TestPlans, want) {
+			t.Errorf("%s: TestPlans = %+v, want %+v", scheme, definition.TestPlans, want)
+		}
+	}
+}
+
+func TestReadProjectTargets(t *testing.T) {
+	dir := t.TempDir()
+	writeProject(t, dir)
+
+	targets, err := readProjectTargets(filepath.Join(dir, "App.xcodeproj"))
+	if err != nil {
+		t.Fatalf("readProjectTargets failed: %v", err)
+	}
+	if want := []string{"App", "AppTests"}; !reflect.DeepEqual(targets, want) {
+		t.Errorf("readProjectTargets() = %v, want %v", targets, want)
+	}
+
+	if _, err := readProjectTargets(dir); err == nil {
+		t.Error("Expected an error without a project.pbxproj")
+	}
+}
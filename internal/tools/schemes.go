@@ -2,24 +2,26 @@ package tools
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
 
+	"github.com/jontolof/xcode-build-mcp/internal/xcode"
 	"github.com/jontolof/xcode-build-mcp/pkg/types"
 )
 
 type ListSchemes struct {
-	name        string
-	description string
-	schema      map[string]interface{}
+	name         string
+	description  string
+	schema       map[string]interface{}
+	outputSchema map[string]interface{}
+	executor     *xcode.Executor
 }
 
-func NewListSchemes() *ListSchemes {
+func NewListSchemes(executor *xcode.Executor) *ListSchemes {
 	schema := createJSONSchema("object", map[string]interface{}{
 		"project_path": map[string]interface{}{
 			"type":        "string",
@@ -36,9 +38,11 @@ func NewListSchemes() *ListSchemes {
 	}, []string{})
 
 	return &ListSchemes{
-		name:        "list_schemes",
-		description: "List available build schemes from Xcode projects and workspaces with metadata and target information",
-		schema:      schema,
+		name:         "list_schemes",
+		description:  "List available build schemes from Xcode projects and workspaces with metadata and target information",
+		schema:       schema,
+		outputSchema: types.SchemaFor(types.SchemesListResult{}),
+		executor:     executor,
 	}
 }
 
@@ -54,7 +58,11 @@ func (t *ListSchemes) InputSchema() map[string]interface{} {
 	return t.schema
 }
 
-func (t *ListSchemes) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+func (t *ListSchemes) OutputSchema() map[string]interface{} {
+	return t.outputSchema
+}
+
+func (t *ListSchemes) Execute(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error) {
 	var p types.SchemesListParams
 
 	// Parse parameters from args
@@ -84,8 +92,8 @@ func (t *ListSchemes) Execute(ctx context.Context, args map[string]interface{})
 				Schemes:  []types.SchemeInfo{},
 				Duration: time.Since(start),
 			}
-			resultJSON, _ := json.Marshal(result)
-			return string(resultJSON), fmt.Errorf("failed to auto-detect project: %w", err)
+			partial, _ := types.NewToolResult(result, true)
+			return partial, toolError(err, types.ErrCodeProjectNotFound, "failed to auto-detect project", nil)
 		}
 		p.ProjectPath = projectPath
 	}
@@ -96,16 +104,12 @@ func (t *ListSchemes) Execute(ctx context.Context, args map[string]interface{})
 			Schemes:  []types.SchemeInfo{},
 			Duration: time.Since(start),
 		}
-		resultJSON, _ := json.Marshal(errorResult)
-		return string(resultJSON), err
+		partial, _ := types.NewToolResult(errorResult, true)
+		return partial, err
 	}
 
 	result.Duration = time.Since(start)
-	resultJSON, err := json.Marshal(result)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal result: %w", err)
-	}
-	return string(resultJSON), nil
+	return types.NewToolResult(result, false)
 }
 
 func (t *ListSchemes) listSchemes(ctx context.Context, params *types.SchemesListParams) (*types.SchemesListResult, error) {
@@ -127,7 +131,8 @@ func (t *ListSchemes) listSchemes(ctx context.Context, params *types.SchemesList
 			// Assume it's a directory, look for workspace or project
 			workspacePath, projectFile, err := t.findProjectInPath(params.ProjectPath)
 			if err != nil {
-				return nil, fmt.Errorf("failed to find project in path: %w", err)
+				return nil, toolError(err, types.ErrCodeProjectNotFound, "failed to find project in path",
+					map[string]interface{}{"project_path": params.ProjectPath})
 			}
 			if workspacePath != "" {
 				projectPath = workspacePath
@@ -136,13 +141,15 @@ func (t *ListSchemes) listSchemes(ctx context.Context, params *types.SchemesList
 			}
 		}
 	} else {
-		return nil, fmt.Errorf("no project or workspace specified")
+		return nil, invalidParams("no project or workspace specified",
+			map[string]interface{}{"parameters": []string{"project_path", "workspace", "project"}})
 	}
 
 	// Get schemes using xcodebuild -list
 	schemesFromList, err := t.getSchemesFromXcodebuild(ctx, projectPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get schemes from xcodebuild: %w", err)
+		return nil, toolError(err, types.ErrCodeCommandFailed, "failed to get schemes from xcodebuild",
+			map[string]interface{}{"project_path": projectPath})
 	}
 
 	// Get additional scheme information
@@ -153,10 +160,20 @@ func (t *ListSchemes) listSchemes(ctx context.Context, params *types.SchemesList
 			SharedScheme: t.isSharedScheme(projectPath, schemeName),
 		}
 
-		// Try to get targets for this scheme
-		targets, err := t.getTargetsForScheme(ctx, projectPath, schemeName)
-		if err == nil {
-			schemeInfo.Targets = targets
+		// Scheme files name the targets and test plans without running xcodebuild
+		if schemePath := findSchemeFile(projectPath, schemeName); schemePath != "" {
+			if definition, err := readSchemeFile(schemePath); err == nil {
+				schemeInfo.Targets = definition.Targets
+				schemeInfo.TestPlans = definition.TestPlans
+			}
+		}
+
+		// Otherwise try to get targets for this scheme from xcodebuild
+		if len(schemeInfo.Targets) == 0 {
+			targets, err := t.getTargetsForScheme(ctx, projectPath, schemeName)
+			if err == nil {
+				schemeInfo.Targets = targets
+			}
 		}
 
 		schemes = append(schemes, schemeInfo)
@@ -175,10 +192,19 @@ func (t *ListSchemes) getSchemesFromXcodebuild(ctx context.Context, projectPath
 		args = []string{"-project", projectPath, "-list"}
 	}
 
-	cmd := exec.CommandContext(ctx, "xcodebuild", args...)
-	output, err := cmd.Output()
+	args = append([]string{"xcodebuild"}, args...)
+	output, err := t.executor.Output(ctx, args...)
 	if err != nil {
-		return nil, fmt.Errorf("xcodebuild -list failed: %w\nOutput: %s", err, string(output))
+		exitCode, stderr := -1, ""
+		var exitErr *xcode.ExitError
+		if errors.As(err, &exitErr) {
+			exitCode, stderr = exitErr.ExitCode, string(exitErr.Stderr)
+		}
+		if setupErr := xcodebuildSetupError(stderr); setupErr != nil {
+			return nil, setupErr
+		}
+		return nil, types.NewXcodeErrorWithCause(types.ErrCodeCommandFailed, "xcodebuild -list failed", err,
+			commandDetails(args, exitCode, string(output)+stderr))
 	}
 
 	return t.parseSchemesFromOutput(string(output)), nil
@@ -223,8 +249,7 @@ func (t *ListSchemes) getTargetsForScheme(ctx context.Context, projectPath, sche
 	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, "xcodebuild", args...)
-	output, err := cmd.Output()
+	output, err := t.executor.Output(ctx, append([]string{"xcodebuild"}, args...)...)
 	if err != nil {
 		// If getting build settings fails, try a simpler approach
 		return t.getTargetsFromList(context.Background(), projectPath)
@@ -259,6 +284,13 @@ func (t *ListSchemes) parseTargetsFromBuildSettings(output string) []string {
 }
 
 func (t *ListSchemes) getTargetsFromList(ctx context.Context, projectPath string) ([]string, error) {
+	// A project's targets can be read straight from its project.pbxproj
+	if strings.HasSuffix(projectPath, ".xcodeproj") {
+		if targets, err := readProjectTargets(projectPath); err == nil && len(targets) > 0 {
+			return targets, nil
+		}
+	}
+
 	var args []string
 	if strings.HasSuffix(projectPath, ".xcworkspace") {
 		args = []string{"-workspace", projectPath, "-list"}
@@ -266,10 +298,9 @@ func (t *ListSchemes) getTargetsFromList(ctx context.Context, projectPath string
 		args = []string{"-project", projectPath, "-list"}
 	}
 
-	cmd := exec.CommandContext(ctx, "xcodebuild", args...)
-	output, err := cmd.Output()
+	output, err := t.executor.Output(ctx, append([]string{"xcodebuild"}, args...)...)
 	if err != nil {
-		return nil, fmt.Errorf("xcodebuild -list failed: %w", err)
+		return nil, types.NewXcodeErrorWithCause(types.ErrCodeCommandFailed, "xcodebuild -list failed", err, nil)
 	}
 
 	return t.parseTargetsFromOutput(string(output)), nil
@@ -348,15 +379,15 @@ func (t *ListSchemes) autoDetectProject() (string, error) {
 		return projectPath, nil
 	}
 
-	return "", fmt.Errorf("no Xcode project or workspace found in current directory")
+	return "", types.NewXcodeError(types.ErrCodeProjectNotFound, "no Xcode project or workspace found in current directory", nil)
 }
 
 func (t *ListSchemes) findProjectInPath(searchPath string) (workspace string, project string, err error) {
 	// Use find command to locate .xcworkspace and .xcodeproj files
-	cmd := exec.Command("find", searchPath, "-maxdepth", "2", "-name", "*.xcworkspace", "-o", "-name", "*.xcodeproj")
-	output, err := cmd.Output()
+	output, err := t.executor.Output(context.Background(), "find", searchPath, "-maxdepth", "2", "-name", "*.xcworkspace", "-o", "-name", "*.xcodeproj")
 	if err != nil {
-		return "", "", fmt.Errorf("failed to search for projects: %w", err)
+		return "", "", types.NewXcodeErrorWithCause(types.ErrCodeProjectNotFound, "failed to search for projects", err,
+			map[string]interface{}{"path": searchPath})
 	}
 
 	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
@@ -374,7 +405,8 @@ func (t *ListSchemes) findProjectInPath(searchPath string) (workspace string, pr
 	}
 
 	if workspace == "" && project == "" {
-		return "", "", fmt.Errorf("no Xcode projects found in path: %s", searchPath)
+		return "", "", types.NewXcodeError(types.ErrCodeProjectNotFound, fmt.Sprintf("no Xcode projects found in path: %s", searchPath),
+			map[string]interface{}{"path": searchPath})
 	}
 
 	return workspace, project, nil
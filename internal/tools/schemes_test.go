@@ -6,18 +6,19 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/jontolof/xcode-build-mcp/internal/xcode"
 	"github.com/jontolof/xcode-build-mcp/pkg/types"
 )
 
 func TestListSchemes_Name(t *testing.T) {
-	tool := NewListSchemes()
+	tool := NewListSchemes(xcode.NewExecutor(&testLogger{}))
 	if got := tool.Name(); got != "list_schemes" {
 		t.Errorf("ListSchemes.Name() = %v, want %v", got, "list_schemes")
 	}
 }
 
 func TestListSchemes_Description(t *testing.T) {
-	tool := NewListSchemes()
+	tool := NewListSchemes(xcode.NewExecutor(&testLogger{}))
 	desc := tool.Description()
 	if desc == "" {
 		t.Error("ListSchemes.Description() returned empty string")
@@ -28,11 +29,12 @@ func TestListSchemes_Description(t *testing.T) {
 }
 
 func TestListSchemes_Execute_InvalidParams(t *testing.T) {
-	tool := NewListSchemes()
+	tool := NewListSchemes(xcode.NewExecutor(&testLogger{}))
 	ctx := context.Background()
 
 	// Test with empty params (no project specified)
-	result, _ := tool.Execute(ctx, map[string]interface{}{})
+	toolResult, _ := tool.Execute(ctx, map[string]interface{}{})
+	result := toolResult.Text()
 	// This might succeed due to auto-detection, so just check for string result
 	if result == "" {
 		t.Error("Expected non-empty result string")
@@ -40,14 +42,15 @@ func TestListSchemes_Execute_InvalidParams(t *testing.T) {
 }
 
 func TestListSchemes_Execute_ValidParams(t *testing.T) {
-	tool := NewListSchemes()
+	tool := NewListSchemes(xcode.NewExecutor(&testLogger{}))
 	ctx := context.Background()
 
 	args := map[string]interface{}{
 		"project_path": "/path/to/test.xcodeproj",
 	}
 
-	result, _ := tool.Execute(ctx, args)
+	toolResult, _ := tool.Execute(ctx, args)
+	result := toolResult.Text()
 
 	// Should get a result string even if command fails
 	if result == "" {
@@ -72,13 +75,14 @@ func TestListSchemes_Execute_ValidParams(t *testing.T) {
 }
 
 func TestListSchemes_Execute_AutoDetect(t *testing.T) {
-	tool := NewListSchemes()
+	tool := NewListSchemes(xcode.NewExecutor(&testLogger{}))
 	ctx := context.Background()
 
 	// Test with minimal params (should trigger auto-detection)
 	args := map[string]interface{}{}
 
-	result, _ := tool.Execute(ctx, args)
+	toolResult, _ := tool.Execute(ctx, args)
+	result := toolResult.Text()
 
 	if result == "" {
 		t.Error("Expected non-empty result string")
@@ -97,7 +101,7 @@ func TestListSchemes_Execute_AutoDetect(t *testing.T) {
 }
 
 func TestListSchemes_ParseSchemesFromOutput(t *testing.T) {
-	tool := NewListSchemes()
+	tool := NewListSchemes(xcode.NewExecutor(&testLogger{}))
 
 	testOutput := `Information about project "TestProject":
     Targets:
@@ -131,7 +135,7 @@ func TestListSchemes_ParseSchemesFromOutput(t *testing.T) {
 }
 
 func TestListSchemes_ParseTargetsFromOutput(t *testing.T) {
-	tool := NewListSchemes()
+	tool := NewListSchemes(xcode.NewExecutor(&testLogger{}))
 
 	testOutput := `Information about project "TestProject":
     Targets:
@@ -161,7 +165,7 @@ func TestListSchemes_ParseTargetsFromOutput(t *testing.T) {
 }
 
 func TestListSchemes_ParseTargetsFromBuildSettings(t *testing.T) {
-	tool := NewListSchemes()
+	tool := NewListSchemes(xcode.NewExecutor(&testLogger{}))
 
 	testOutput := `Build settings for action build and target TestApp:
     ACTION = build
@@ -199,7 +203,7 @@ Build settings for action build and target TestApp:
 }
 
 func TestListSchemes_IsSharedScheme(t *testing.T) {
-	tool := NewListSchemes()
+	tool := NewListSchemes(xcode.NewExecutor(&testLogger{}))
 
 	// Test with workspace path
 	workspacePath := "/path/to/project.xcworkspace"
@@ -254,7 +258,7 @@ func TestListSchemes_ParameterValidation(t *testing.T) {
 		},
 	}
 
-	tool := NewListSchemes()
+	tool := NewListSchemes(xcode.NewExecutor(&testLogger{}))
 	ctx := context.Background()
 
 	for _, tt := range tests {
@@ -271,7 +275,8 @@ func TestListSchemes_ParameterValidation(t *testing.T) {
 				args["project"] = tt.params.Project
 			}
 
-			result, err := tool.Execute(ctx, args)
+			toolResult, err := tool.Execute(ctx, args)
+			result := toolResult.Text()
 
 			if result == "" {
 				t.Error("Expected non-empty result string")
@@ -302,7 +307,7 @@ func TestListSchemes_ParameterValidation(t *testing.T) {
 }
 
 func TestListSchemes_ProjectTypeDetection(t *testing.T) {
-	tool := NewListSchemes()
+	tool := NewListSchemes(xcode.NewExecutor(&testLogger{}))
 	ctx := context.Background()
 
 	tests := []struct {
@@ -356,7 +361,7 @@ func TestListSchemes_ProjectTypeDetection(t *testing.T) {
 }
 
 func TestListSchemes_EmptyOutput(t *testing.T) {
-	tool := NewListSchemes()
+	tool := NewListSchemes(xcode.NewExecutor(&testLogger{}))
 
 	// Test with empty output
 	schemes := tool.parseSchemesFromOutput("")
@@ -376,7 +381,7 @@ func TestListSchemes_EmptyOutput(t *testing.T) {
 }
 
 func TestListSchemes_MalformedOutput(t *testing.T) {
-	tool := NewListSchemes()
+	tool := NewListSchemes(xcode.NewExecutor(&testLogger{}))
 
 	// Test with malformed output that has schemes section but no schemes
 	malformedOutput := `Information about project "TestProject":
@@ -395,7 +400,7 @@ func TestListSchemes_MalformedOutput(t *testing.T) {
 }
 
 func TestListSchemes_ProjectPathPriority(t *testing.T) {
-	tool := NewListSchemes()
+	tool := NewListSchemes(xcode.NewExecutor(&testLogger{}))
 	ctx := context.Background()
 
 	// Test that workspace takes priority over project when both are specified
@@ -2,24 +2,31 @@ package tools
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/jontolof/xcode-build-mcp/internal/imaging"
+	"github.com/jontolof/xcode-build-mcp/internal/xcode"
 	"github.com/jontolof/xcode-build-mcp/pkg/types"
 )
 
+// defaultScreenshotMaxDimension keeps returned images within a modest token
+// budget while leaving UI text legible
+const defaultScreenshotMaxDimension = 1024
+
 type Screenshot struct {
-	name        string
-	description string
-	schema      map[string]interface{}
+	name         string
+	description  string
+	schema       map[string]interface{}
+	outputSchema map[string]interface{}
+	executor     *xcode.Executor
 }
 
-func NewScreenshot() *Screenshot {
+func NewScreenshot(executor *xcode.Executor) *Screenshot {
 	schema := createJSONSchema("object", map[string]interface{}{
 		"udid": map[string]interface{}{
 			"type":        "string",
@@ -37,12 +44,35 @@ func NewScreenshot() *Screenshot {
 			"type":        "string",
 			"description": "Image format (png, jpg) - default: png",
 		},
+		"include_image": map[string]interface{}{
+			"type":        "boolean",
+			"description": "Return the screenshot as image content so it can be viewed directly",
+			"default":     true,
+		},
+		"max_dimension": map[string]interface{}{
+			"type":        "integer",
+			"description": "Downscale the returned image so its longest side is at most this many pixels (0 keeps full size). The saved file is never scaled.",
+			"default":     defaultScreenshotMaxDimension,
+		},
+		"image_format": map[string]interface{}{
+			"type":        "string",
+			"enum":        []string{"jpeg", "png"},
+			"description": "Encoding of the returned image; jpeg is much smaller",
+			"default":     "jpeg",
+		},
+		"jpeg_quality": map[string]interface{}{
+			"type":        "integer",
+			"description": "JPEG quality 1-100 for the returned image",
+			"default":     imaging.DefaultJPEGQuality,
+		},
 	}, []string{})
 
 	return &Screenshot{
-		name:        "screenshot",
-		description: "Capture screenshots from iOS/tvOS/watchOS simulators with automatic naming and format support",
-		schema:      schema,
+		name:         "screenshot",
+		description:  "Capture screenshots from iOS/tvOS/watchOS simulators with automatic naming and format support. The screenshot is saved to disk and, unless include_image is false, returned as image content downscaled to max_dimension.",
+		schema:       schema,
+		outputSchema: types.SchemaFor(types.ScreenshotResult{}),
+		executor:     executor,
 	}
 }
 
@@ -58,48 +88,61 @@ func (t *Screenshot) InputSchema() map[string]interface{} {
 	return t.schema
 }
 
-func (t *Screenshot) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
-	// Validate that parameters are provided
-	if len(args) == 0 {
-		return "", fmt.Errorf("parameters cannot be empty")
-	}
-
-	var p types.ScreenshotParams
+func (t *Screenshot) OutputSchema() map[string]interface{} {
+	return t.outputSchema
+}
 
-	// Parse parameters from args
-	if udid, exists := args["udid"]; exists {
-		if str, ok := udid.(string); ok {
-			p.UDID = str
+// Execute returns the JSON result followed by the screenshot as image
+// content, so the agent can see the simulator screen
+func (t *Screenshot) Execute(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error) {
+	result, params, err := t.capture(ctx, args)
+	if err != nil {
+		if result == nil {
+			return nil, err
 		}
+		partial, _ := types.NewToolResult(result, true)
+		return partial, err
 	}
-	if deviceType, exists := args["device_type"]; exists {
-		if str, ok := deviceType.(string); ok {
-			p.DeviceType = str
+
+	var image *types.ToolContent
+	if params.IncludeImage {
+		content, err := t.encodeImage(result, params)
+		if err != nil {
+			return nil, err
 		}
+		image = &content
 	}
-	if outputPath, exists := args["output_path"]; exists {
-		if str, ok := outputPath.(string); ok {
-			p.OutputPath = str
-		}
+
+	toolResult, err := types.NewToolResult(result, false)
+	if err != nil {
+		return nil, err
 	}
-	if format, exists := args["format"]; exists {
-		if str, ok := format.(string); ok {
-			p.Format = str
-		}
+	if image != nil {
+		toolResult.Content = append(toolResult.Content, *image)
+	}
+	return toolResult, nil
+}
+
+// capture parses the parameters and takes the screenshot. On failure the
+// returned result still describes the attempt, unless the parameters were
+// rejected outright.
+func (t *Screenshot) capture(ctx context.Context, args map[string]interface{}) (*types.ScreenshotResult, *types.ScreenshotParams, error) {
+	p, err := t.parseParams(args)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	start := time.Now()
 
 	// Auto-select device if not specified
 	if p.UDID == "" && p.DeviceType == "" {
-		simulator, err := selectBestSimulator("")
+		simulator, err := selectBestSimulator(t.executor, "")
 		if err != nil {
 			errorResult := &types.ScreenshotResult{
 				Success:  false,
 				Duration: time.Since(start),
 			}
-			resultJSON, _ := json.Marshal(errorResult)
-			return string(resultJSON), fmt.Errorf("failed to auto-select device: %w", err)
+			return errorResult, p, toolError(err, types.ErrCodeSimulatorNotFound, "failed to auto-select device", nil)
 		}
 		p.UDID = simulator.UDID
 	}
@@ -119,27 +162,101 @@ func (t *Screenshot) Execute(ctx context.Context, args map[string]interface{}) (
 		}
 	}
 
-	result, err := t.captureScreenshot(ctx, &p)
+	result, err := t.captureScreenshot(ctx, p)
 	if err != nil {
 		errorResult := &types.ScreenshotResult{
 			Success:  false,
 			Duration: time.Since(start),
 		}
-		resultJSON, _ := json.Marshal(errorResult)
-		return string(resultJSON), err
+		return errorResult, p, err
 	}
 
 	result.Duration = time.Since(start)
-	resultJSON, err := json.Marshal(result)
+	return result, p, nil
+}
+
+// parseParams reads the screenshot options from args and rejects an
+// unsupported format before anything is captured
+func (t *Screenshot) parseParams(args map[string]interface{}) (*types.ScreenshotParams, error) {
+	// Validate that parameters are provided
+	if len(args) == 0 {
+		return nil, invalidParams("parameters cannot be empty", nil)
+	}
+
+	p := types.ScreenshotParams{
+		IncludeImage: parseBoolParam(args, "include_image", true),
+		MaxDimension: defaultScreenshotMaxDimension,
+		ImageFormat:  "jpeg",
+		JPEGQuality:  imaging.DefaultJPEGQuality,
+	}
+
+	// Parse parameters from args
+	if udid, exists := args["udid"]; exists {
+		if str, ok := udid.(string); ok {
+			p.UDID = str
+		}
+	}
+	if deviceType, exists := args["device_type"]; exists {
+		if str, ok := deviceType.(string); ok {
+			p.DeviceType = str
+		}
+	}
+	if outputPath, exists := args["output_path"]; exists {
+		if str, ok := outputPath.(string); ok {
+			p.OutputPath = str
+		}
+	}
+	if format, exists := args["format"]; exists {
+		if str, ok := format.(string); ok {
+			p.Format = str
+		}
+	}
+	if maxDimension, exists := args["max_dimension"]; exists {
+		if num, ok := maxDimension.(float64); ok {
+			p.MaxDimension = int(num)
+		}
+	}
+	if imageFormat, exists := args["image_format"]; exists {
+		if str, ok := imageFormat.(string); ok && str != "" {
+			p.ImageFormat = str
+		}
+	}
+	if quality, exists := args["jpeg_quality"]; exists {
+		if num, ok := quality.(float64); ok {
+			p.JPEGQuality = int(num)
+		}
+	}
+	if p.Format != "" && !slices.Contains([]string{"png", "jpeg", "jpg"}, strings.ToLower(p.Format)) {
+		return nil, invalidParams(fmt.Sprintf("unsupported format: %s (supported: png, jpeg, jpg)", p.Format),
+			map[string]interface{}{"parameter": "format"})
+	}
+	return &p, nil
+}
+
+// encodeImage loads the captured file, downscales it and re-encodes it for
+// embedding in the tool result. The file on disk is left untouched.
+func (t *Screenshot) encodeImage(result *types.ScreenshotResult, params *types.ScreenshotParams) (types.ToolContent, error) {
+	img, err := imaging.Load(result.FilePath)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal result: %w", err)
+		return types.ToolContent{}, err
 	}
-	return string(resultJSON), nil
+
+	img = imaging.Fit(img, params.MaxDimension)
+	data, mimeType, err := imaging.Encode(img, params.ImageFormat, params.JPEGQuality)
+	if err != nil {
+		return types.ToolContent{}, err
+	}
+
+	bounds := img.Bounds()
+	result.ImageDimensions = fmt.Sprintf("%dx%d", bounds.Dx(), bounds.Dy())
+	result.ImageSize = len(data)
+
+	return types.ImageContent(data, mimeType), nil
 }
 
 func (t *Screenshot) captureScreenshot(ctx context.Context, params *types.ScreenshotParams) (*types.ScreenshotResult, error) {
 	if params.UDID == "" {
-		return &types.ScreenshotResult{Success: false}, fmt.Errorf("device UDID is required")
+		return &types.ScreenshotResult{Success: false}, invalidParams("device UDID is required", map[string]interface{}{"parameter": "udid"})
 	}
 
 	// Validate format
@@ -150,13 +267,15 @@ func (t *Screenshot) captureScreenshot(ctx context.Context, params *types.Screen
 	}
 
 	if !supportedFormats[strings.ToLower(params.Format)] {
-		return &types.ScreenshotResult{Success: false}, fmt.Errorf("unsupported format: %s (supported: png, jpeg, jpg)", params.Format)
+		return &types.ScreenshotResult{Success: false}, invalidParams(fmt.Sprintf("unsupported format: %s (supported: png, jpeg, jpg)", params.Format),
+			map[string]interface{}{"parameter": "format"})
 	}
 
 	// Ensure output directory exists
 	outputDir := filepath.Dir(params.OutputPath)
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return &types.ScreenshotResult{Success: false}, fmt.Errorf("failed to create output directory: %w", err)
+		return &types.ScreenshotResult{Success: false}, types.NewXcodeErrorWithCause(types.ErrCodeInternal, "failed to create output directory", err,
+			map[string]interface{}{"output_path": params.OutputPath})
 	}
 
 	// Build screenshot command
@@ -170,20 +289,20 @@ func (t *Screenshot) captureScreenshot(ctx context.Context, params *types.Screen
 	args = append(args, params.OutputPath)
 
 	// Execute screenshot command
-	cmd := exec.CommandContext(ctx, "xcrun", args...)
-	output, err := cmd.CombinedOutput()
+	args = append([]string{"xcrun"}, args...)
+	output, err := t.executor.CombinedOutput(ctx, args...)
 
 	if err != nil {
-		return &types.ScreenshotResult{Success: false}, fmt.Errorf("screenshot failed: %w\nOutput: %s", err, string(output))
+		return &types.ScreenshotResult{Success: false}, commandFailed("screenshot failed", args, output, err)
 	}
 
 	// Get file info
 	fileInfo, err := os.Stat(params.OutputPath)
 	if err != nil {
-		return &types.ScreenshotResult{Success: false}, fmt.Errorf("failed to get file info: %w", err)
+		return &types.ScreenshotResult{Success: false}, types.NewXcodeErrorWithCause(types.ErrCodeInternal, "failed to get file info", err, nil)
 	}
 
-	// Get dimensions (if possible) using sips command on macOS
+	// Get dimensions from the image header
 	dimensions := t.getImageDimensions(params.OutputPath)
 
 	return &types.ScreenshotResult{
@@ -203,29 +322,9 @@ func (t *Screenshot) generateScreenshotPath(format string) string {
 }
 
 func (t *Screenshot) getImageDimensions(imagePath string) string {
-	// Use sips command to get image dimensions on macOS
-	cmd := exec.Command("sips", "-g", "pixelWidth", "-g", "pixelHeight", imagePath)
-	output, err := cmd.Output()
+	width, height, err := imaging.Dimensions(imagePath)
 	if err != nil {
 		return ""
 	}
-
-	// Parse sips output
-	lines := strings.Split(string(output), "\n")
-	var width, height string
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "pixelWidth:") {
-			width = strings.TrimSpace(strings.TrimPrefix(line, "pixelWidth:"))
-		} else if strings.HasPrefix(line, "pixelHeight:") {
-			height = strings.TrimSpace(strings.TrimPrefix(line, "pixelHeight:"))
-		}
-	}
-
-	if width != "" && height != "" {
-		return fmt.Sprintf("%sx%s", width, height)
-	}
-
-	return ""
+	return fmt.Sprintf("%dx%d", width, height)
 }
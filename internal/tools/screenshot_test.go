@@ -1,25 +1,31 @@
 package tools
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"image"
+	"image/jpeg"
+	"image/png"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/jontolof/xcode-build-mcp/internal/xcode"
 	"github.com/jontolof/xcode-build-mcp/pkg/types"
 )
 
 func TestScreenshot_Name(t *testing.T) {
-	tool := NewScreenshot()
+	tool := NewScreenshot(xcode.NewExecutor(&testLogger{}))
 	if got := tool.Name(); got != "screenshot" {
 		t.Errorf("Screenshot.Name() = %v, want %v", got, "screenshot")
 	}
 }
 
 func TestScreenshot_Description(t *testing.T) {
-	tool := NewScreenshot()
+	tool := NewScreenshot(xcode.NewExecutor(&testLogger{}))
 	desc := tool.Description()
 	if desc == "" {
 		t.Error("Screenshot.Description() returned empty string")
@@ -30,11 +36,12 @@ func TestScreenshot_Description(t *testing.T) {
 }
 
 func TestScreenshot_Execute_InvalidParams(t *testing.T) {
-	tool := NewScreenshot()
+	tool := NewScreenshot(xcode.NewExecutor(&testLogger{}))
 	ctx := context.Background()
 
 	// Test with invalid JSON
-	result, err := tool.Execute(ctx, map[string]interface{}{})
+	toolResult, err := tool.Execute(ctx, map[string]interface{}{})
+	result := toolResult.Text()
 	if err == nil {
 		t.Error("Expected error for invalid JSON, got nil")
 	}
@@ -44,7 +51,7 @@ func TestScreenshot_Execute_InvalidParams(t *testing.T) {
 }
 
 func TestScreenshot_Execute_ValidParams(t *testing.T) {
-	tool := NewScreenshot()
+	tool := NewScreenshot(xcode.NewExecutor(&testLogger{}))
 	ctx := context.Background()
 
 	// Create temp directory for test
@@ -54,11 +61,12 @@ func TestScreenshot_Execute_ValidParams(t *testing.T) {
 	}
 	defer os.RemoveAll(tempDir)
 
-	resultStr, execErr := tool.Execute(ctx, map[string]interface{}{
+	toolResult, execErr := tool.Execute(ctx, map[string]interface{}{
 		"udid":        "test-udid",
 		"output_path": filepath.Join(tempDir, "test.png"),
 		"format":      "png",
 	})
+	resultStr := toolResult.Text()
 
 	// Should get a result even if command fails
 	if resultStr == "" {
@@ -83,13 +91,14 @@ func TestScreenshot_Execute_ValidParams(t *testing.T) {
 }
 
 func TestScreenshot_Execute_DefaultFormat(t *testing.T) {
-	tool := NewScreenshot()
+	tool := NewScreenshot(xcode.NewExecutor(&testLogger{}))
 	ctx := context.Background()
 
 	// Test with minimal params (no format specified)
-	resultStr, execErr := tool.Execute(ctx, map[string]interface{}{
+	toolResult, execErr := tool.Execute(ctx, map[string]interface{}{
 		"udid": "test-udid",
 	})
+	resultStr := toolResult.Text()
 
 	if resultStr == "" {
 		t.Error("Expected non-empty result")
@@ -111,7 +120,7 @@ func TestScreenshot_Execute_DefaultFormat(t *testing.T) {
 }
 
 func TestScreenshot_GenerateScreenshotPath(t *testing.T) {
-	tool := NewScreenshot()
+	tool := NewScreenshot(xcode.NewExecutor(&testLogger{}))
 
 	tests := []struct {
 		format   string
@@ -146,7 +155,7 @@ func TestScreenshot_GenerateScreenshotPath(t *testing.T) {
 }
 
 func TestScreenshot_GetImageDimensions(t *testing.T) {
-	tool := NewScreenshot()
+	tool := NewScreenshot(xcode.NewExecutor(&testLogger{}))
 
 	// Test with non-existent file
 	dimensions := tool.getImageDimensions("/non/existent/file.png")
@@ -212,7 +221,7 @@ func TestScreenshot_ParameterValidation(t *testing.T) {
 		},
 	}
 
-	tool := NewScreenshot()
+	tool := NewScreenshot(xcode.NewExecutor(&testLogger{}))
 	ctx := context.Background()
 
 	for _, tt := range tests {
@@ -228,7 +237,8 @@ func TestScreenshot_ParameterValidation(t *testing.T) {
 				args["format"] = tt.params.Format
 			}
 
-			resultStr, execErr := tool.Execute(ctx, args)
+			toolResult, execErr := tool.Execute(ctx, args)
+			resultStr := toolResult.Text()
 
 			if tt.valid {
 				if resultStr == "" {
@@ -248,7 +258,7 @@ func TestScreenshot_ParameterValidation(t *testing.T) {
 }
 
 func TestScreenshot_FormatValidation(t *testing.T) {
-	tool := NewScreenshot()
+	tool := NewScreenshot(xcode.NewExecutor(&testLogger{}))
 
 	// Test unsupported format in captureScreenshot
 	params := &types.ScreenshotParams{
@@ -268,15 +278,16 @@ func TestScreenshot_FormatValidation(t *testing.T) {
 }
 
 func TestScreenshot_OutputPathExtension(t *testing.T) {
-	tool := NewScreenshot()
+	tool := NewScreenshot(xcode.NewExecutor(&testLogger{}))
 	ctx := context.Background()
 
 	// Test that extension is added when missing
-	resultStr, execErr := tool.Execute(ctx, map[string]interface{}{
+	toolResult, execErr := tool.Execute(ctx, map[string]interface{}{
 		"udid":        "test-udid",
 		"output_path": "/tmp/test",
 		"format":      "png",
 	})
+	resultStr := toolResult.Text()
 
 	if resultStr != "" {
 		var screenshotResult types.ScreenshotResult
@@ -293,3 +304,48 @@ func TestScreenshot_OutputPathExtension(t *testing.T) {
 		}
 	}
 }
+
+func TestScreenshot_EncodeImage(t *testing.T) {
+	tool := NewScreenshot(xcode.NewExecutor(&testLogger{}))
+
+	path := filepath.Join(t.TempDir(), "screen.png")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(file, image.NewNRGBA(image.Rect(0, 0, 1170, 2532))); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	result := &types.ScreenshotResult{Success: true, FilePath: path}
+	params := &types.ScreenshotParams{MaxDimension: 1024, ImageFormat: "jpeg", JPEGQuality: 80}
+
+	content, err := tool.encodeImage(result, params)
+	if err != nil {
+		t.Fatalf("encodeImage failed: %v", err)
+	}
+	if content.Type != "image" || content.MimeType != "image/jpeg" {
+		t.Errorf("Unexpected content: type=%s mimeType=%s", content.Type, content.MimeType)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(content.Data)
+	if err != nil {
+		t.Fatalf("Image data is not base64: %v", err)
+	}
+	config, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Image data is not a JPEG: %v", err)
+	}
+	if config.Height != 1024 || config.Width != 473 {
+		t.Errorf("Expected a 473x1024 image, got %dx%d", config.Width, config.Height)
+	}
+	if result.ImageDimensions != "473x1024" || result.ImageSize != len(data) {
+		t.Errorf("Result not updated: dimensions=%s size=%d", result.ImageDimensions, result.ImageSize)
+	}
+
+	// The saved file keeps its full resolution
+	if dimensions := tool.getImageDimensions(path); dimensions != "1170x2532" {
+		t.Errorf("Expected the file to stay 1170x2532, got %s", dimensions)
+	}
+}
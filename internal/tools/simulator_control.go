@@ -13,12 +13,13 @@ import (
 )
 
 type SimulatorControlTool struct {
-	name        string
-	description string
-	schema      map[string]interface{}
-	executor    *xcode.Executor
-	parser      *xcode.Parser
-	logger      common.Logger
+	name         string
+	description  string
+	schema       map[string]interface{}
+	outputSchema map[string]interface{}
+	executor     *xcode.Executor
+	parser       *xcode.Parser
+	logger       common.Logger
 }
 
 func NewSimulatorControlTool(executor *xcode.Executor, parser *xcode.Parser, logger common.Logger) *SimulatorControlTool {
@@ -41,12 +42,13 @@ func NewSimulatorControlTool(executor *xcode.Executor, parser *xcode.Parser, log
 	}, []string{"udid", "action"})
 
 	return &SimulatorControlTool{
-		name:        "simulator_control",
-		description: "Control iOS simulators - boot, shutdown, reset, or erase simulators",
-		schema:      schema,
-		executor:    executor,
-		parser:      parser,
-		logger:      logger,
+		name:         "simulator_control",
+		description:  "Control iOS simulators - boot, shutdown, reset, or erase simulators",
+		schema:       schema,
+		outputSchema: types.SchemaFor(types.SimulatorControlResult{}),
+		executor:     executor,
+		parser:       parser,
+		logger:       logger,
 	}
 }
 
@@ -62,10 +64,14 @@ func (t *SimulatorControlTool) InputSchema() map[string]interface{} {
 	return t.schema
 }
 
-func (t *SimulatorControlTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+func (t *SimulatorControlTool) OutputSchema() map[string]interface{} {
+	return t.outputSchema
+}
+
+func (t *SimulatorControlTool) Execute(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error) {
 	params, err := t.parseParams(args)
 	if err != nil {
-		return "", fmt.Errorf("invalid parameters: %w", err)
+		return nil, toolError(err, types.ErrCodeInvalidParams, "invalid parameters", nil)
 	}
 
 	start := time.Now()
@@ -82,7 +88,8 @@ func (t *SimulatorControlTool) Execute(ctx context.Context, args map[string]inte
 	// Perform the simulator control action
 	output, err := t.performSimulatorAction(ctx, params)
 	if err != nil {
-		return "", fmt.Errorf("failed to perform %s action: %w", params.Action, err)
+		return nil, toolError(err, types.ErrCodeCommandFailed, fmt.Sprintf("failed to perform %s action", params.Action),
+			map[string]interface{}{"udid": params.UDID, "action": params.Action})
 	}
 
 	// Get the state after performing the action
@@ -103,13 +110,7 @@ func (t *SimulatorControlTool) Execute(ctx context.Context, args map[string]inte
 		CurrentState:  currentState,
 	}
 
-	// Convert result to JSON string
-	resultJSON, err := json.Marshal(result)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal result: %w", err)
-	}
-
-	return string(resultJSON), nil
+	return types.NewToolResult(result, !result.Success)
 }
 
 func (t *SimulatorControlTool) parseParams(args map[string]interface{}) (*types.SimulatorControlParams, error) {
@@ -139,7 +140,8 @@ func (t *SimulatorControlTool) parseParams(args map[string]interface{}) (*types.
 	}
 
 	if !validActions[action] {
-		return nil, fmt.Errorf("invalid action '%s'. Valid actions are: boot, shutdown, reset, erase", action)
+		return nil, invalidParams(fmt.Sprintf("invalid action '%s'. Valid actions are: boot, shutdown, reset, erase", action),
+			map[string]interface{}{"parameter": "action"})
 	}
 	params.Action = action
 
@@ -150,7 +152,7 @@ func (t *SimulatorControlTool) parseParams(args map[string]interface{}) (*types.
 		} else if timeoutInt, ok := timeout.(int); ok {
 			params.Timeout = timeoutInt
 		} else {
-			return nil, fmt.Errorf("timeout must be a number")
+			return nil, invalidParams("timeout must be a number", map[string]interface{}{"parameter": "timeout"})
 		}
 	}
 
@@ -171,7 +173,7 @@ func (t *SimulatorControlTool) performSimulatorAction(ctx context.Context, param
 	case "erase":
 		args = []string{"xcrun", "simctl", "erase", params.UDID}
 	default:
-		return "", fmt.Errorf("unsupported action: %s", params.Action)
+		return "", invalidParams(fmt.Sprintf("unsupported action: %s", params.Action), map[string]interface{}{"parameter": "action"})
 	}
 
 	// Create a context with timeout
@@ -181,9 +183,10 @@ func (t *SimulatorControlTool) performSimulatorAction(ctx context.Context, param
 	result, err := t.executor.ExecuteCommand(timeoutCtx, args)
 	if err != nil {
 		if timeoutCtx.Err() == context.DeadlineExceeded {
-			return "", fmt.Errorf("operation timed out after %d seconds", params.Timeout)
+			return "", types.NewXcodeErrorWithCause(types.ErrCodeTimeout, fmt.Sprintf("operation timed out after %d seconds", params.Timeout), err,
+				map[string]interface{}{"timeout": params.Timeout})
 		}
-		return "", err
+		return "", types.NewXcodeErrorWithCause(types.ErrCodeCommandFailed, "failed to run simctl", err, nil)
 	}
 
 	if !result.Success() {
@@ -192,16 +195,18 @@ func (t *SimulatorControlTool) performSimulatorAction(ctx context.Context, param
 			errorOutput = result.Output
 		}
 
+		details := commandDetails(args, result.ExitCode, errorOutput)
+
 		// Check for common error cases and provide better error messages
 		if strings.Contains(errorOutput, "Unable to boot device") {
-			return "", fmt.Errorf("unable to boot simulator (may already be booted): %s", strings.TrimSpace(errorOutput))
+			return "", types.NewXcodeError(types.ErrCodeCommandFailed, fmt.Sprintf("unable to boot simulator (may already be booted): %s", strings.TrimSpace(errorOutput)), details)
 		} else if strings.Contains(errorOutput, "Unable to shutdown device") {
-			return "", fmt.Errorf("unable to shutdown simulator (may already be shutdown): %s", strings.TrimSpace(errorOutput))
+			return "", types.NewXcodeError(types.ErrCodeCommandFailed, fmt.Sprintf("unable to shutdown simulator (may already be shutdown): %s", strings.TrimSpace(errorOutput)), details)
 		} else if strings.Contains(errorOutput, "No device found") {
-			return "", fmt.Errorf("simulator with UDID %s not found", params.UDID)
+			return "", types.NewXcodeError(types.ErrCodeSimulatorNotFound, fmt.Sprintf("simulator with UDID %s not found", params.UDID), details)
 		}
 
-		return "", fmt.Errorf("simctl command failed (exit code %d): %s", result.ExitCode, strings.TrimSpace(errorOutput))
+		return "", types.NewXcodeError(types.ErrCodeCommandFailed, fmt.Sprintf("simctl command failed (exit code %d): %s", result.ExitCode, strings.TrimSpace(errorOutput)), details)
 	}
 
 	return result.Output, nil
@@ -216,7 +221,8 @@ func (t *SimulatorControlTool) getSimulatorState(ctx context.Context, udid strin
 	}
 
 	if !result.Success() {
-		return "", fmt.Errorf("failed to get simulator state: %s", result.StderrOutput)
+		return "", types.NewXcodeError(types.ErrCodeCommandFailed, fmt.Sprintf("failed to get simulator state: %s", result.StderrOutput),
+			commandDetails(args, result.ExitCode, result.StderrOutput))
 	}
 
 	// Parse JSON output to find the simulator state
@@ -228,7 +234,7 @@ func (t *SimulatorControlTool) getSimulatorState(ctx context.Context, udid strin
 	}
 
 	if err := json.Unmarshal([]byte(result.Output), &simctlOutput); err != nil {
-		return "", fmt.Errorf("failed to parse simctl output: %w", err)
+		return "", types.NewXcodeErrorWithCause(types.ErrCodeCommandFailed, "failed to parse simctl output", err, nil)
 	}
 
 	// Find the simulator with the given UDID
@@ -240,5 +246,6 @@ func (t *SimulatorControlTool) getSimulatorState(ctx context.Context, udid strin
 		}
 	}
 
-	return "", fmt.Errorf("simulator with UDID %s not found", udid)
+	return "", types.NewXcodeError(types.ErrCodeSimulatorNotFound, fmt.Sprintf("simulator with UDID %s not found", udid),
+		map[string]interface{}{"udid": udid})
 }
@@ -13,12 +13,13 @@ import (
 )
 
 type ListSimulatorsTool struct {
-	name        string
-	description string
-	schema      map[string]interface{}
-	executor    *xcode.Executor
-	parser      *xcode.Parser
-	logger      common.Logger
+	name         string
+	description  string
+	schema       map[string]interface{}
+	outputSchema map[string]interface{}
+	executor     *xcode.Executor
+	parser       *xcode.Parser
+	logger       common.Logger
 }
 
 func NewListSimulatorsTool(executor *xcode.Executor, parser *xcode.Parser, logger common.Logger) *ListSimulatorsTool {
@@ -47,12 +48,13 @@ func NewListSimulatorsTool(executor *xcode.Executor, parser *xcode.Parser, logge
 	}, []string{})
 
 	return &ListSimulatorsTool{
-		name:        "list_simulators",
-		description: "List available iOS, watchOS, and tvOS simulators with filtering options",
-		schema:      schema,
-		executor:    executor,
-		parser:      parser,
-		logger:      logger,
+		name:         "list_simulators",
+		description:  "List available iOS, watchOS, and tvOS simulators with filtering options",
+		schema:       schema,
+		outputSchema: types.SchemaFor(types.SimulatorListResult{}),
+		executor:     executor,
+		parser:       parser,
+		logger:       logger,
 	}
 }
 
@@ -68,10 +70,14 @@ func (t *ListSimulatorsTool) InputSchema() map[string]interface{} {
 	return t.schema
 }
 
-func (t *ListSimulatorsTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+func (t *ListSimulatorsTool) OutputSchema() map[string]interface{} {
+	return t.outputSchema
+}
+
+func (t *ListSimulatorsTool) Execute(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error) {
 	params, err := t.parseParams(args)
 	if err != nil {
-		return "", fmt.Errorf("invalid parameters: %w", err)
+		return nil, toolError(err, types.ErrCodeInvalidParams, "invalid parameters", nil)
 	}
 
 	start := time.Now()
@@ -82,7 +88,7 @@ func (t *ListSimulatorsTool) Execute(ctx context.Context, args map[string]interf
 	// Execute xcrun simctl list command
 	simulators, err := t.listSimulators(ctx, params)
 	if err != nil {
-		return "", fmt.Errorf("failed to list simulators: %w", err)
+		return nil, toolError(err, types.ErrCodeCommandFailed, "failed to list simulators", nil)
 	}
 
 	duration := time.Since(start)
@@ -93,13 +99,7 @@ func (t *ListSimulatorsTool) Execute(ctx context.Context, args map[string]interf
 		Duration:   duration,
 	}
 
-	// Convert result to JSON string
-	resultJSON, err := json.Marshal(result)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal result: %w", err)
-	}
-
-	return string(resultJSON), nil
+	return types.NewToolResult(result, false)
 }
 
 func (t *ListSimulatorsTool) parseParams(args map[string]interface{}) (*types.SimulatorListParams, error) {
@@ -138,7 +138,7 @@ func (t *ListSimulatorsTool) parseParams(args map[string]interface{}) (*types.Si
 		if availableBool, ok := available.(bool); ok {
 			params.Available = &availableBool
 		} else {
-			return nil, fmt.Errorf("available must be a boolean")
+			return nil, invalidParams("available must be a boolean", map[string]interface{}{"parameter": "available"})
 		}
 	}
 
@@ -151,17 +151,18 @@ func (t *ListSimulatorsTool) listSimulators(ctx context.Context, params *types.S
 
 	result, err := t.executor.ExecuteCommand(ctx, args)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute simctl command: %w", err)
+		return nil, types.NewXcodeErrorWithCause(types.ErrCodeCommandFailed, "failed to execute simctl command", err, nil)
 	}
 
 	if !result.Success() {
-		return nil, fmt.Errorf("simctl command failed with exit code %d: %s", result.ExitCode, result.StderrOutput)
+		return nil, types.NewXcodeError(types.ErrCodeCommandFailed, fmt.Sprintf("simctl command failed with exit code %d: %s", result.ExitCode, result.StderrOutput),
+			commandDetails(args, result.ExitCode, result.StderrOutput))
 	}
 
 	// Parse the JSON output
 	simulators, err := t.parseSimulatorListOutput(result.Output)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse simulator list output: %w", err)
+		return nil, types.NewXcodeErrorWithCause(types.ErrCodeCommandFailed, "failed to parse simulator list output", err, nil)
 	}
 
 	// Apply filters
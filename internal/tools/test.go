@@ -4,27 +4,33 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/jontolof/xcode-build-mcp/internal/common"
 	"github.com/jontolof/xcode-build-mcp/internal/filter"
+	"github.com/jontolof/xcode-build-mcp/internal/runs"
 	"github.com/jontolof/xcode-build-mcp/internal/xcode"
 	"github.com/jontolof/xcode-build-mcp/pkg/types"
 )
 
 type XcodeTestTool struct {
-	name        string
-	description string
-	schema      map[string]interface{}
-	executor    *xcode.Executor
-	parser      *xcode.Parser
-	logger      common.Logger
+	name         string
+	description  string
+	schema       map[string]interface{}
+	outputSchema map[string]interface{}
+	executor     *xcode.Executor
+	parser       *xcode.Parser
+	runs         *runs.Store
+	logger       common.Logger
 }
 
-func NewXcodeTestTool(executor *xcode.Executor, parser *xcode.Parser, logger common.Logger) *XcodeTestTool {
+func NewXcodeTestTool(executor *xcode.Executor, parser *xcode.Parser, store *runs.Store, logger common.Logger) *XcodeTestTool {
 	schema := createJSONSchema("object", map[string]interface{}{
 		"project_path": map[string]interface{}{
 			"type":        "string",
@@ -40,27 +46,119 @@ func NewXcodeTestTool(executor *xcode.Executor, parser *xcode.Parser, logger com
 		},
 		"scheme": map[string]interface{}{
 			"type":        "string",
-			"description": "Test scheme to use",
+			"description": "Test scheme to use; xcodebuild test needs a scheme, so use only_testing to run a single test target",
+		},
+		"test_plan": map[string]interface{}{
+			"type":        "string",
+			"description": "Test plan of the scheme to run (list_schemes reports them)",
+		},
+		"sdk": map[string]interface{}{
+			"type":        "string",
+			"description": "SDK to test against (iphonesimulator, macosx, etc.)",
 		},
 		"destination": map[string]interface{}{
 			"type":        "string",
 			"description": "Test destination (platform=iOS Simulator,name=iPhone 15, etc.)",
 		},
+		"only_testing": map[string]interface{}{
+			"type":        "array",
+			"items":       map[string]string{"type": "string"},
+			"description": "Run only these tests, as Target, Target/Class or Target/Class/method",
+		},
+		"skip_testing": map[string]interface{}{
+			"type":        "array",
+			"items":       map[string]string{"type": "string"},
+			"description": "Skip these tests, as Target, Target/Class or Target/Class/method",
+		},
+		"parallel": map[string]interface{}{
+			"type":        "boolean",
+			"description": "Run tests in parallel",
+			"default":     false,
+		},
+		"coverage": map[string]interface{}{
+			"type":        "boolean",
+			"description": "Collect code coverage and report it from the result bundle",
+			"default":     false,
+		},
+		"coverage_report": map[string]interface{}{
+			"type":        "string",
+			"enum":        xcode.CoverageDetails,
+			"description": "Coverage detail: per target, per file, per function, or per function plus the line ranges that never ran; implies coverage",
+			"default":     xcode.CoverageFiles,
+		},
+		"coverage_files": map[string]interface{}{
+			"type":        "array",
+			"items":       map[string]string{"type": "string"},
+			"description": "Report coverage only for these source files, as absolute paths or path suffixes; implies coverage",
+		},
+		"coverage_changed_since": map[string]interface{}{
+			"type":        "string",
+			"description": "Report coverage only for files changed since this git ref (HEAD for uncommitted changes), including untracked files; implies coverage",
+		},
+		"test_iterations": map[string]interface{}{
+			"type":        "integer",
+			"minimum":     1,
+			"description": "Run each test this many times, or retry a failing test up to this many times with retry_tests_on_failure",
+		},
+		"retry_tests_on_failure": map[string]interface{}{
+			"type":        "boolean",
+			"description": "Rerun failing tests until they pass or test_iterations is reached",
+			"default":     false,
+		},
+		"test_timeouts_enabled": map[string]interface{}{
+			"type":        "boolean",
+			"description": "Enable per-test execution time limits; defaults to the test plan's setting",
+		},
+		"default_test_timeout": map[string]interface{}{
+			"type":        "integer",
+			"minimum":     1,
+			"description": "Time allowance in seconds for each test that sets none; enables test timeouts",
+		},
+		"maximum_test_timeout": map[string]interface{}{
+			"type":        "integer",
+			"minimum":     1,
+			"description": "Largest time allowance in seconds any test may request; enables test timeouts",
+		},
+		"result_bundle": map[string]interface{}{
+			"type":        "string",
+			"description": "Where to write the .xcresult bundle, which is kept; it must not exist yet",
+		},
+		"derived_data": map[string]interface{}{
+			"type":        "string",
+			"description": "Path for derived data",
+		},
+		"environment": map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": map[string]interface{}{"type": "string"},
+			"description":          "Environment variables for the test processes, passed through xcodebuild with the TEST_RUNNER_ prefix; PATH, HOME, DYLD_* and other sensitive variables are rejected",
+		},
+		"developer_dir": map[string]interface{}{
+			"type":        "string",
+			"description": "Xcode to test with: an Xcode.app bundle or its Contents/Developer directory, set as DEVELOPER_DIR",
+		},
+		"xcode_version": xcodeVersionSchema(),
 		"output_mode": map[string]interface{}{
 			"type":        "string",
 			"enum":        []string{"minimal", "standard", "verbose"},
 			"description": "Output filtering level",
 			"default":     "standard",
 		},
-	}, []string{})
+		"extra_args": map[string]interface{}{
+			"type":        "array",
+			"items":       map[string]string{"type": "string"},
+			"description": "Additional xcodebuild arguments",
+		},
+	}, []string{"scheme"})
 
 	return &XcodeTestTool{
-		name:        "xcode_test",
-		description: "Universal Xcode test command that runs tests with detailed results and intelligent output filtering. Returns comprehensive crash detection including: crash_type (segmentation_fault, abort, killed, timeout, fatal_error, test_crash, etc.), process_crashed (bool), crash_indicators (test_runner_crashed, fatal_error_detected, swift_runtime_crash, connection_interrupted, simulator_boot_timeout, etc.), simulator_crashes (array of crash reports), and silent_failure detection. Always check crash_type field - if not 'none', the test execution crashed rather than failed normally.",
-		schema:      schema,
-		executor:    executor,
-		parser:      parser,
-		logger:      logger,
+		name:         "xcode_test",
+		description:  "Universal Xcode test command that runs tests with detailed results and intelligent output filtering. Returns comprehensive crash detection including: crash_type (segmentation_fault, abort, killed, timeout, fatal_error, test_crash, etc.), process_crashed (bool), crash_indicators (test_runner_crashed, fatal_error_detected, swift_runtime_crash, connection_interrupted, simulator_boot_timeout, etc.), simulator_crashes (array of crash reports), and silent_failure detection. Always check crash_type field - if not 'none', the test execution crashed rather than failed normally.",
+		schema:       schema,
+		outputSchema: types.SchemaFor(types.TestResult{}),
+		executor:     executor,
+		parser:       parser,
+		runs:         store,
+		logger:       logger,
 	}
 }
 
@@ -76,6 +174,10 @@ func (t *XcodeTestTool) InputSchema() map[string]interface{} {
 	return t.schema
 }
 
+func (t *XcodeTestTool) OutputSchema() map[string]interface{} {
+	return t.outputSchema
+}
+
 // fixMisleadingSummary replaces misleading "passed" summaries with accurate failure counts
 // This handles silent test failures that only appear in xcresult bundles
 func fixMisleadingSummary(output string, totalTests, passedTests, failedTests, skippedTests int) string {
@@ -125,56 +227,76 @@ func fixMisleadingSummary(output string, totalTests, passedTests, failedTests, s
 	return result.String()
 }
 
-func (t *XcodeTestTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
-	params := &types.TestParams{
-		OutputMode:  "standard",
-		Environment: make(map[string]string),
-		ExtraArgs:   []string{},
+func (t *XcodeTestTool) Execute(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error) {
+	params, err := t.parseParams(args)
+	if err != nil {
+		return nil, err
 	}
 
-	// Parse basic parameters
-	projectPath, _ := parseStringParam(args, "project_path", false)
-	workspace, _ := parseStringParam(args, "workspace", false)
-	project, _ := parseStringParam(args, "project", false)
-	scheme, _ := parseStringParam(args, "scheme", false)
-	destination, _ := parseStringParam(args, "destination", false)
-	outputMode, _ := parseStringParam(args, "output_mode", false)
-
-	params.ProjectPath = projectPath
-	params.Workspace = workspace
-	params.Project = project
-	params.Scheme = scheme
-	params.Destination = destination
-	if outputMode != "" {
-		params.OutputMode = outputMode
+	// Environment variables reach the test processes through xcodebuild's
+	// TEST_RUNNER_ prefix; xcresulttool and xccov use the same Xcode as
+	// the run
+	if params.DeveloperDir, err = selectXcode(ctx, t.executor, params.DeveloperDir, params.XcodeVersion); err != nil {
+		return nil, err
+	}
+	env, developerEnv, err := commandEnv(t.executor, params.Environment, xcode.TestRunnerPrefix, params.DeveloperDir)
+	if err != nil {
+		return nil, err
+	}
+	runner := t.executor.Runner()
+	if developerEnv != nil {
+		runner = xcode.EnvRunner{Runner: runner, Env: developerEnv}
 	}
 
-	// Validate
-	if params.Workspace == "" && params.Project == "" {
-		return "", fmt.Errorf("either workspace or project must be specified")
+	// Resolve the coverage filter up front so a bad git ref fails before
+	// the test run rather than after it
+	var coverageFiles []string
+	if len(params.CoverageFiles) > 0 || params.CoverageChangedSince != "" {
+		coverageFiles = slices.Clone(params.CoverageFiles)
+		if params.CoverageChangedSince != "" {
+			changed, err := changedFiles(ctx, t.executor, params.ProjectPath, params.CoverageChangedSince)
+			if err != nil {
+				return nil, invalidParams(fmt.Sprintf("failed to list files changed since %s: %v", params.CoverageChangedSince, err),
+					map[string]interface{}{"parameter": "coverage_changed_since"})
+			}
+			coverageFiles = append(coverageFiles, changed...)
+		}
+		if coverageFiles == nil {
+			coverageFiles = []string{}
+		}
 	}
 
-	// Generate a temporary result bundle path for accurate test result parsing
+	// Without a requested result bundle, generate a temporary one for
+	// accurate test result parsing
 	// This provides structured JSON results instead of text parsing
-	resultBundlePath := xcode.GenerateResultBundlePath()
-	defer xcode.CleanupResultBundle(resultBundlePath)
-
-	// Add result bundle path to params if not already specified
-	if params.ResultBundle == "" {
+	// The bundle is deleted afterwards unless the run store takes ownership
+	resultBundlePath := params.ResultBundle
+	temporaryBundle := resultBundlePath == ""
+	retainBundle := false
+	if temporaryBundle {
+		resultBundlePath = xcode.GenerateResultBundlePath()
 		params.ResultBundle = resultBundlePath
+		defer func() {
+			if !retainBundle {
+				xcode.CleanupResultBundle(resultBundlePath)
+			}
+		}()
 	}
 
 	cmdArgs, err := t.executor.BuildXcodeArgs(params)
 	if err != nil {
-		return "", fmt.Errorf("failed to build command arguments: %w", err)
+		return nil, toolError(err, types.ErrCodeInternal, "failed to build command arguments", nil)
 	}
 
 	// Initialize crash detector before execution
 	crashDetector := xcode.NewSimulatorCrashDetector()
 
-	result, err := t.executor.ExecuteCommand(ctx, cmdArgs)
+	start := time.Now()
+	opts := append(progressOptions(ctx), xcode.WithEnv(env...))
+	result, err := t.executor.ExecuteCommand(ctx, cmdArgs, opts...)
 	if err != nil {
-		return "", fmt.Errorf("failed to execute test command: %w", err)
+		return nil, toolError(err, types.ErrCodeCommandFailed, "failed to execute test command",
+			map[string]interface{}{"command": strings.Join(cmdArgs, " ")})
 	}
 
 	// Debug logging: save raw output for troubleshooting parsing issues
@@ -216,8 +338,8 @@ func (t *XcodeTestTool) Execute(ctx context.Context, args map[string]interface{}
 
 	// Parse xcresult bundle for accurate results (if available)
 	// This is the most reliable source of test results
-	xcresultParser := xcode.NewXCResultParser()
-	xcresultSummary, xcresultErr := xcresultParser.ParseResultBundle(resultBundlePath)
+	xcresultParser := xcode.NewXCResultParser(runner)
+	xcresultSummary, xcresultErr := xcresultParser.ParseResultBundle(ctx, resultBundlePath)
 
 	// Debug: Log xcresult parsing attempt
 	if debugEnabled {
@@ -298,6 +420,21 @@ func (t *XcodeTestTool) Execute(ctx context.Context, args map[string]interface{}
 		}
 	}
 
+	// Report coverage from the result bundle; a missing report leaves the
+	// test results intact
+	var coverageErr error
+	if params.Coverage {
+		detail := params.CoverageReport
+		if detail == "" {
+			detail = xcode.CoverageFiles
+		}
+		testResult.Coverage, coverageErr = xcode.NewCoverageParser(runner).ParseCoverage(ctx, resultBundlePath,
+			xcode.CoverageOptions{Detail: detail, Files: coverageFiles})
+		if coverageErr != nil {
+			t.logger.Printf("Warning: failed to read code coverage: %v", coverageErr)
+		}
+	}
+
 	// Integrate crash detection from executor
 	testResult.CrashType = result.CrashType
 	testResult.ProcessCrashed = result.ProcessState != nil && result.ProcessState.Signaled
@@ -336,6 +473,30 @@ func (t *XcodeTestTool) Execute(ctx context.Context, args map[string]interface{}
 		}
 	}
 
+	// Keep the unfiltered log, result bundle and crash reports so they can
+	// be fetched as resources
+	var run *runs.Run
+	if t.runs != nil {
+		run = &runs.Run{
+			Tool:         t.name,
+			Command:      strings.Join(cmdArgs, " "),
+			StartedAt:    start,
+			Duration:     result.Duration,
+			ExitCode:     result.ExitCode,
+			Output:       result.Output,
+			CrashReports: testResult.SimulatorCrashes,
+			DeveloperDir: params.DeveloperDir,
+			TestResult:   testResult,
+		}
+		// The store deletes the bundles it owns, so a requested bundle
+		// stays out of it
+		if _, err := os.Stat(resultBundlePath); err == nil && temporaryBundle {
+			run.ResultBundle = resultBundlePath
+			retainBundle = true
+		}
+		t.runs.Add(run)
+	}
+
 	// Apply filtering
 	outputFilter := filter.NewFilter(filter.OutputMode(params.OutputMode))
 	filteredOutput := outputFilter.Filter(result.Output)
@@ -480,10 +641,239 @@ func (t *XcodeTestTool) Execute(ctx context.Context, args map[string]interface{}
 		"simulator_crashes": testResult.SimulatorCrashes,
 	}
 
+	if testResult.Coverage != nil {
+		response["coverage"] = testResult.Coverage
+	} else if coverageErr != nil {
+		response["coverage_error"] = coverageErr.Error()
+	}
+
+	if run != nil {
+		resources := map[string]interface{}{
+			"log": run.LogURI(),
+		}
+		if run.ResultBundle != "" {
+			resources["xcresult"] = run.ResultBundleURI()
+		}
+		if len(run.CrashReports) > 0 {
+			crashURIs := make([]string, len(run.CrashReports))
+			for i := range run.CrashReports {
+				crashURIs[i] = run.CrashReportURI(i)
+			}
+			resources["crash_reports"] = crashURIs
+		}
+		response["resources"] = resources
+	}
+
 	jsonData, err := json.MarshalIndent(response, "", "  ")
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal response: %w", err)
+		return nil, toolError(err, types.ErrCodeInternal, "failed to marshal response", nil)
+	}
+
+	// Like the text response, the structured result leaves out the raw
+	// output; it is available as the log resource
+	structured := *testResult
+	structured.Output = ""
+
+	toolResult := &types.ToolResult{
+		Content:    []types.ToolContent{types.TextContent(string(jsonData))},
+		Structured: &structured,
+		IsError:    !testResult.Success,
+	}
+
+	// Report a missing scheme, project or destination as a typed error so
+	// clients need not match the log text
+	if !testResult.Success {
+		if err := xcodebuildSetupError(result.Output); err != nil {
+			return toolResult, err
+		}
+	}
+
+	return toolResult, nil
+}
+
+func (t *XcodeTestTool) parseParams(args map[string]interface{}) (*types.TestParams, error) {
+	params := &types.TestParams{
+		OutputMode:  "standard",
+		Environment: make(map[string]string),
+		ExtraArgs:   []string{},
+	}
+
+	// Parse required and optional parameters
+	var err error
+	for key, target := range map[string]*string{
+		"project_path":  &params.ProjectPath,
+		"workspace":     &params.Workspace,
+		"project":       &params.Project,
+		"scheme":        &params.Scheme,
+		"test_plan":     &params.TestPlan,
+		"sdk":           &params.SDK,
+		"destination":   &params.Destination,
+		"result_bundle": &params.ResultBundle,
+		"derived_data":  &params.DerivedData,
+		"developer_dir": &params.DeveloperDir,
+		"xcode_version": &params.XcodeVersion,
+
+		"coverage_report":        &params.CoverageReport,
+		"coverage_changed_since": &params.CoverageChangedSince,
+	} {
+		if *target, err = parseStringParam(args, key, false); err != nil {
+			return nil, err
+		}
+	}
+
+	if outputMode, err := parseStringParam(args, "output_mode", false); err != nil {
+		return nil, err
+	} else if outputMode != "" {
+		params.OutputMode = outputMode
+	}
+
+	if params.OnlyTesting, err = parseStringArrayParam(args, "only_testing"); err != nil {
+		return nil, err
+	}
+	if params.SkipTesting, err = parseStringArrayParam(args, "skip_testing"); err != nil {
+		return nil, err
+	}
+	if params.ExtraArgs, err = parseStringArrayParam(args, "extra_args"); err != nil {
+		return nil, err
+	}
+	if params.CoverageFiles, err = parseStringArrayParam(args, "coverage_files"); err != nil {
+		return nil, err
+	}
+
+	params.Parallel = parseBoolParam(args, "parallel", false)
+	params.Coverage = parseBoolParam(args, "coverage", false)
+
+	// Asking for a coverage report implies collecting coverage
+	if params.CoverageReport != "" || len(params.CoverageFiles) > 0 || params.CoverageChangedSince != "" {
+		params.Coverage = true
+	}
+	if params.CoverageReport != "" && !slices.Contains(xcode.CoverageDetails, params.CoverageReport) {
+		return nil, invalidParams(fmt.Sprintf("invalid coverage_report %q, expected one of %s", params.CoverageReport, strings.Join(xcode.CoverageDetails, ", ")),
+			map[string]interface{}{"parameter": "coverage_report"})
+	}
+	params.RetryTestsOnFailure = parseBoolParam(args, "retry_tests_on_failure", false)
+
+	if params.TestIterations, err = parseIntParam(args, "test_iterations", 1); err != nil {
+		return nil, err
+	}
+	if params.DefaultTestTimeout, err = parseIntParam(args, "default_test_timeout", 1); err != nil {
+		return nil, err
+	}
+	if params.MaximumTestTimeout, err = parseIntParam(args, "maximum_test_timeout", 1); err != nil {
+		return nil, err
+	}
+	if value, exists := args["test_timeouts_enabled"]; exists {
+		enabled, ok := value.(bool)
+		if !ok {
+			return nil, invalidParams("parameter test_timeouts_enabled must be a boolean",
+				map[string]interface{}{"parameter": "test_timeouts_enabled"})
+		}
+		params.TestTimeouts = &enabled
+	}
+
+	// Parse environment variables
+	if params.Environment, err = parseEnvironmentParam(args, "environment"); err != nil {
+		return nil, err
+	}
+
+	// Validate parameters
+	if params.Workspace == "" && params.Project == "" {
+		return nil, invalidParams("either workspace or project must be specified",
+			map[string]interface{}{"parameters": []string{"workspace", "project"}})
+	}
+
+	// xcodebuild test rejects -target, so a target alone cannot be tested
+	if _, ok := args["target"]; ok {
+		return nil, invalidParams("target is not supported by xcode_test: xcodebuild test requires a scheme; use only_testing to run a single test target",
+			map[string]interface{}{"parameter": "target"})
+	}
+	if params.Scheme == "" {
+		return nil, invalidParams("scheme must be specified", map[string]interface{}{"parameter": "scheme"})
+	}
+
+	// The ref is handed to git, which would read a leading dash as an option
+	if strings.HasPrefix(params.CoverageChangedSince, "-") {
+		return nil, invalidParams(fmt.Sprintf("invalid coverage_changed_since %q, expected a git revision", params.CoverageChangedSince),
+			map[string]interface{}{"parameter": "coverage_changed_since"})
+	}
+
+	for _, test := range slices.Concat(params.OnlyTesting, params.SkipTesting) {
+		if test == "" || strings.HasPrefix(test, "/") || strings.HasSuffix(test, "/") || strings.Count(test, "/") > 2 {
+			return nil, invalidParams(fmt.Sprintf("invalid test identifier %q, expected Target, Target/Class or Target/Class/method", test),
+				map[string]interface{}{"parameters": []string{"only_testing", "skip_testing"}})
+		}
+	}
+
+	// Time allowances only apply with test timeouts enabled
+	if params.DefaultTestTimeout > 0 || params.MaximumTestTimeout > 0 {
+		if params.TestTimeouts != nil && !*params.TestTimeouts {
+			return nil, invalidParams("test timeouts cannot be set with test_timeouts_enabled false",
+				map[string]interface{}{"parameters": []string{"test_timeouts_enabled", "default_test_timeout", "maximum_test_timeout"}})
+		}
+		enabled := true
+		params.TestTimeouts = &enabled
+	}
+	if params.MaximumTestTimeout > 0 && params.DefaultTestTimeout > params.MaximumTestTimeout {
+		return nil, invalidParams("default_test_timeout cannot exceed maximum_test_timeout",
+			map[string]interface{}{"parameters": []string{"default_test_timeout", "maximum_test_timeout"}})
+	}
+
+	// xcodebuild refuses to overwrite an existing result bundle
+	if params.ResultBundle != "" {
+		if _, err := os.Stat(params.ResultBundle); err == nil {
+			return nil, invalidParams(fmt.Sprintf("result bundle %s already exists", params.ResultBundle),
+				map[string]interface{}{"parameter": "result_bundle"})
+		}
+	}
+
+	return params, nil
+}
+
+// changedFiles lists the absolute paths of the files changed since ref in the
+// git repository containing dir, including untracked files
+func changedFiles(ctx context.Context, executor *xcode.Executor, dir, ref string) ([]string, error) {
+	if dir == "" {
+		dir = "."
+	}
+
+	git := func(args ...string) ([]string, error) {
+		output, err := executor.Output(ctx, append([]string{"git", "-C", dir}, args...)...)
+		if err != nil {
+			var exitErr *xcode.ExitError
+			if errors.As(err, &exitErr) {
+				return nil, fmt.Errorf("%s", strings.TrimSpace(string(exitErr.Stderr)))
+			}
+			return nil, err
+		}
+		var lines []string
+		for _, line := range strings.Split(string(output), "\n") {
+			if line != "" {
+				lines = append(lines, line)
+			}
+		}
+		return lines, nil
+	}
+
+	root, err := git("rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	if len(root) != 1 {
+		return nil, fmt.Errorf("unexpected repository root %q", root)
+	}
+
+	changed, err := git("diff", "--name-only", "--end-of-options", ref, "--")
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := git("ls-files", "--others", "--exclude-standard", "--full-name")
+	if err != nil {
+		return nil, err
 	}
 
-	return string(jsonData), nil
+	files := []string{}
+	for _, file := range slices.Concat(changed, untracked) {
+		files = append(files, filepath.Join(root[0], file))
+	}
+	return files, nil
 }
@@ -0,0 +1,209 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jontolof/xcode-build-mcp/internal/xcode"
+	"github.com/jontolof/xcode-build-mcp/pkg/types"
+)
+
+func TestXcodeTestTool_Schema(t *testing.T) {
+	tool := NewXcodeTestTool(xcode.NewExecutor(&testLogger{}), xcode.NewParser(), nil, &testLogger{})
+	properties := tool.InputSchema()["properties"].(map[string]interface{})
+
+	// Every TestParams field is settable through the schema
+	for _, field := range reflect.VisibleFields(reflect.TypeOf(types.TestParams{})) {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if _, ok := properties[name]; !ok {
+			t.Errorf("Schema is missing %s", name)
+		}
+	}
+}
+
+func TestXcodeTestTool_ParseParams(t *testing.T) {
+	tool := NewXcodeTestTool(xcode.NewExecutor(&testLogger{}), xcode.NewParser(), nil, &testLogger{})
+
+	params, err := tool.parseParams(map[string]interface{}{
+		"project":                "App.xcodeproj",
+		"scheme":                 "App",
+		"test_plan":              "Smoke",
+		"only_testing":           []interface{}{"AppTests/LoginTests/testInvalidPassword"},
+		"skip_testing":           []interface{}{"AppUITests"},
+		"coverage":               true,
+		"test_iterations":        float64(3),
+		"retry_tests_on_failure": true,
+		"default_test_timeout":   float64(60),
+		"environment":            map[string]interface{}{"FEATURE_FLAG": "1"},
+	})
+	if err != nil {
+		t.Fatalf("parseParams failed: %v", err)
+	}
+
+	enabled := true
+	want := &types.TestParams{
+		Project:             "App.xcodeproj",
+		Scheme:              "App",
+		TestPlan:            "Smoke",
+		OnlyTesting:         []string{"AppTests/LoginTests/testInvalidPassword"},
+		SkipTesting:         []string{"AppUITests"},
+		OutputMode:          "standard",
+		Coverage:            true,
+		Environment:         map[string]string{"FEATURE_FLAG": "1"},
+		ExtraArgs:           []string{},
+		TestIterations:      3,
+		RetryTestsOnFailure: true,
+		TestTimeouts:        &enabled,
+		DefaultTestTimeout:  60,
+		CoverageFiles:       []string{},
+	}
+	if !reflect.DeepEqual(params, want) {
+		t.Errorf("parseParams() = %+v, want %+v", params, want)
+	}
+}
+
+func TestXcodeTestTool_ParseParams_CoverageReport(t *testing.T) {
+	tool := NewXcodeTestTool(xcode.NewExecutor(&testLogger{}), xcode.NewParser(), nil, &testLogger{})
+
+	// Any coverage report option turns on coverage collection
+	for _, extra := range []map[string]interface{}{
+		{"coverage_report": "uncovered"},
+		{"coverage_files": []interface{}{"Sources/App/Login.swift"}},
+		{"coverage_changed_since": "HEAD"},
+	} {
+		args := map[string]interface{}{"project": "App.xcodeproj", "scheme": "App"}
+		for k, v := range extra {
+			args[k] = v
+		}
+		params, err := tool.parseParams(args)
+		if err != nil {
+			t.Fatalf("parseParams(%v) failed: %v", extra, err)
+		}
+		if !params.Coverage {
+			t.Errorf("parseParams(%v) did not enable coverage", extra)
+		}
+	}
+}
+
+func TestChangedFiles(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	dir := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=Test", "-c", "user.email=test@example.com"}, args...)...)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	git("init", "-q")
+	write("Sources/App/Login.swift", "struct Login {}\n")
+	write("Sources/App/Profile.swift", "struct Profile {}\n")
+	git("add", ".")
+	git("commit", "-q", "-m", "Initial")
+
+	write("Sources/App/Login.swift", "struct Login { let user: String }\n")
+	write("Sources/App/Signup View.swift", "struct SignupView {}\n")
+
+	files, err := changedFiles(context.Background(), xcode.NewExecutor(&testLogger{}), filepath.Join(dir, "Sources"), "HEAD")
+	if err != nil {
+		t.Fatalf("changedFiles failed: %v", err)
+	}
+
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		filepath.Join(root, "Sources/App/Login.swift"),
+		filepath.Join(root, "Sources/App/Signup View.swift"),
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("changedFiles() = %v, want %v", files, want)
+	}
+
+	if _, err := changedFiles(context.Background(), xcode.NewExecutor(&testLogger{}), dir, "no-such-ref"); err == nil {
+		t.Error("Expected an error for an unknown ref")
+	}
+}
+
+func TestXcodeTestTool_CoverageChangedSinceOption(t *testing.T) {
+	replay := xcode.NewReplayRunner([]xcode.Fixture{
+		{Args: []string{"git", "-C", ".", "rev-parse", "--show-toplevel"}, Stdout: "/repo\n"},
+	})
+	tool := NewXcodeTestTool(xcode.NewExecutorWithRunner(&testLogger{}, replay), xcode.NewParser(), nil, &testLogger{})
+
+	_, err := tool.Execute(context.Background(), map[string]interface{}{
+		"project":                "App.xcodeproj",
+		"scheme":                 "App",
+		"coverage_changed_since": "--output=/tmp/owned",
+	})
+	if !types.IsXcodeError(err, types.ErrCodeInvalidParams) {
+		t.Errorf("Expected INVALID_PARAMS for a ref starting with a dash, got %v", err)
+	}
+	if len(replay.Unused()) != 1 {
+		t.Error("Expected git not to run for a ref starting with a dash")
+	}
+}
+
+func TestXcodeTestTool_ParseParams_Invalid(t *testing.T) {
+	tool := NewXcodeTestTool(xcode.NewExecutor(&testLogger{}), xcode.NewParser(), nil, &testLogger{})
+	existing := t.TempDir()
+
+	base := func(extra map[string]interface{}) map[string]interface{} {
+		args := map[string]interface{}{"project": "App.xcodeproj", "scheme": "App"}
+		for k, v := range extra {
+			args[k] = v
+		}
+		return args
+	}
+
+	tests := map[string]map[string]interface{}{
+		"no project":          {"scheme": "App"},
+		"no scheme":           {"project": "App.xcodeproj"},
+		"target only":         {"project": "App.xcodeproj", "target": "AppTests"},
+		"target with scheme":  base(map[string]interface{}{"target": "AppTests"}),
+		"non-string test":     base(map[string]interface{}{"only_testing": []interface{}{1}}),
+		"too deep identifier": base(map[string]interface{}{"only_testing": []interface{}{"A/B/c/d"}}),
+		"empty identifier":    base(map[string]interface{}{"skip_testing": []interface{}{""}}),
+		"zero iterations":     base(map[string]interface{}{"test_iterations": float64(0)}),
+		"fractional timeout":  base(map[string]interface{}{"default_test_timeout": 1.5}),
+		"disabled timeouts": base(map[string]interface{}{
+			"test_timeouts_enabled": false,
+			"maximum_test_timeout":  float64(60),
+		}),
+		"default above maximum": base(map[string]interface{}{
+			"default_test_timeout": float64(120),
+			"maximum_test_timeout": float64(60),
+		}),
+		"existing result bundle":  base(map[string]interface{}{"result_bundle": existing}),
+		"unknown coverage report": base(map[string]interface{}{"coverage_report": "lines"}),
+		"option as changed ref":   base(map[string]interface{}{"coverage_changed_since": "--output=x"}),
+		"non-string environment":  base(map[string]interface{}{"environment": map[string]interface{}{"RETRIES": 3}}),
+	}
+
+	for name, args := range tests {
+		_, err := tool.parseParams(args)
+		if xerr := types.ExtractXcodeError(err); xerr == nil || xerr.Code != types.ErrCodeInvalidParams {
+			t.Errorf("%s: expected INVALID_PARAMS, got %v", name, err)
+		}
+	}
+}
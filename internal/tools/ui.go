@@ -2,22 +2,32 @@ package tools
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"os/exec"
 	"strings"
 	"time"
 
+	"github.com/jontolof/xcode-build-mcp/internal/accessibility"
+	"github.com/jontolof/xcode-build-mcp/internal/xcode"
 	"github.com/jontolof/xcode-build-mcp/pkg/types"
 )
 
 type DescribeUI struct {
-	name        string
-	description string
-	schema      map[string]interface{}
+	name         string
+	description  string
+	schema       map[string]interface{}
+	outputSchema map[string]interface{}
+	executor     *xcode.Executor
+	backend      accessibility.Backend
 }
 
-func NewDescribeUI() *DescribeUI {
+// NewDescribeUI reads the hierarchy through backend, or through the backend
+// selected by accessibility.FromEnv when backend is nil, and checks the
+// simulator state through executor
+func NewDescribeUI(executor *xcode.Executor, backend accessibility.Backend) *DescribeUI {
+	if backend == nil {
+		backend = accessibility.FromEnv(executor.Runner())
+	}
+
 	schema := createJSONSchema("object", map[string]interface{}{
 		"udid": map[string]interface{}{
 			"type":        "string",
@@ -29,7 +39,15 @@ func NewDescribeUI() *DescribeUI {
 		},
 		"output_format": map[string]interface{}{
 			"type":        "string",
-			"description": "Output format (tree, flat, json) - default: tree",
+			"description": "Output format (tree, flat, json) - default: tree. json returns the typed element tree as root",
+		},
+		"max_depth": map[string]interface{}{
+			"type":        "integer",
+			"description": "Maximum depth below the root to describe - default: 10",
+		},
+		"include_text": map[string]interface{}{
+			"type":        "boolean",
+			"description": "Include element values (text field contents, slider positions) in tree and flat output",
 		},
 		"filter_type": map[string]interface{}{
 			"type":        "string",
@@ -38,9 +56,12 @@ func NewDescribeUI() *DescribeUI {
 	}, []string{})
 
 	return &DescribeUI{
-		name:        "describe_ui",
-		description: "Describe UI hierarchy of iOS/tvOS/watchOS simulators with tree, flat, or JSON format output",
-		schema:      schema,
+		name:         "describe_ui",
+		description:  "Describe the accessibility hierarchy of a booted simulator (element types, labels, identifiers, frames and traits) as a tree, flat list or JSON",
+		schema:       schema,
+		outputSchema: types.SchemaFor(types.UIDescribeResult{}),
+		executor:     executor,
+		backend:      backend,
 	}
 }
 
@@ -56,10 +77,14 @@ func (t *DescribeUI) InputSchema() map[string]interface{} {
 	return t.schema
 }
 
-func (t *DescribeUI) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+func (t *DescribeUI) OutputSchema() map[string]interface{} {
+	return t.outputSchema
+}
+
+func (t *DescribeUI) Execute(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error) {
 	// Validate that parameters are provided
 	if len(args) == 0 {
-		return "", fmt.Errorf("parameters cannot be empty")
+		return nil, invalidParams("parameters cannot be empty", nil)
 	}
 
 	var p types.UIDescribeParams
@@ -75,11 +100,23 @@ func (t *DescribeUI) Execute(ctx context.Context, args map[string]interface{}) (
 			p.DeviceType = str
 		}
 	}
-	if outputFormat, exists := args["output_format"]; exists {
-		if str, ok := outputFormat.(string); ok {
+	// format is accepted as an alias, matching UIDescribeParams
+	for _, key := range []string{"format", "output_format"} {
+		if str, ok := args[key].(string); ok && str != "" {
 			p.Format = str
 		}
 	}
+	if maxDepth, exists := args["max_depth"]; exists {
+		switch v := maxDepth.(type) {
+		case float64:
+			p.MaxDepth = int(v)
+		case int:
+			p.MaxDepth = v
+		default:
+			return nil, invalidParams("max_depth must be a number", map[string]interface{}{"parameter": "max_depth"})
+		}
+	}
+	p.IncludeText = parseBoolParam(args, "include_text", false)
 	// Note: FilterType might not exist in current types, skipping for now
 	// if filterType, exists := args["filter_type"]; exists {
 	//	if str, ok := filterType.(string); ok {
@@ -91,14 +128,14 @@ func (t *DescribeUI) Execute(ctx context.Context, args map[string]interface{}) (
 
 	// Auto-select device if not specified
 	if p.UDID == "" && p.DeviceType == "" {
-		simulator, err := selectBestSimulator("")
+		simulator, err := selectBestSimulator(t.executor, "")
 		if err != nil {
 			errorResult := &types.UIDescribeResult{
 				Success:  false,
 				Duration: time.Since(start),
 			}
-			resultJSON, _ := json.Marshal(errorResult)
-			return string(resultJSON), fmt.Errorf("failed to auto-select device: %w", err)
+			partial, _ := types.NewToolResult(errorResult, true)
+			return partial, toolError(err, types.ErrCodeSimulatorNotFound, "failed to auto-select device", nil)
 		}
 		p.UDID = simulator.UDID
 	}
@@ -107,7 +144,7 @@ func (t *DescribeUI) Execute(ctx context.Context, args map[string]interface{}) (
 	if p.Format == "" {
 		p.Format = "tree"
 	}
-	if p.MaxDepth == 0 {
+	if p.MaxDepth <= 0 {
 		p.MaxDepth = 10
 	}
 
@@ -117,239 +154,121 @@ func (t *DescribeUI) Execute(ctx context.Context, args map[string]interface{}) (
 			Success:  false,
 			Duration: time.Since(start),
 		}
-		resultJSON, _ := json.Marshal(errorResult)
-		return string(resultJSON), err
+		partial, _ := types.NewToolResult(errorResult, true)
+		return partial, err
 	}
 
 	result.Duration = time.Since(start)
-	resultJSON, err := json.Marshal(result)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal result: %w", err)
-	}
-	return string(resultJSON), nil
+	return types.NewToolResult(result, !result.Success)
 }
 
 func (t *DescribeUI) describeUI(ctx context.Context, params *types.UIDescribeParams) (*types.UIDescribeResult, error) {
 	if params.UDID == "" {
-		return &types.UIDescribeResult{Success: false}, fmt.Errorf("device UDID is required")
-	}
-
-	// Validate format
-	supportedFormats := map[string]bool{
-		"tree": true,
-		"flat": true,
-		"json": true,
-	}
-
-	if !supportedFormats[strings.ToLower(params.Format)] {
-		return &types.UIDescribeResult{Success: false}, fmt.Errorf("unsupported format: %s (supported: tree, flat, json)", params.Format)
+		return &types.UIDescribeResult{Success: false}, invalidParams("device UDID is required", map[string]interface{}{"parameter": "udid"})
 	}
 
-	// Note: simctl doesn't have direct UI hierarchy commands, so we use a mock implementation
-
-	// Check if the device is booted first
-	if err := t.ensureDeviceBooted(ctx, params.UDID); err != nil {
-		return &types.UIDescribeResult{Success: false}, fmt.Errorf("device not booted: %w", err)
+	format := strings.ToLower(params.Format)
+	if format != "tree" && format != "flat" && format != "json" {
+		return &types.UIDescribeResult{Success: false}, invalidParams(fmt.Sprintf("unsupported format: %s (supported: tree, flat, json)", params.Format),
+			map[string]interface{}{"parameter": "format"})
 	}
 
-	// Attempt to get UI accessibility tree
-	uiData, err := t.getUIHierarchy(ctx, params.UDID, params.Format, params.MaxDepth, params.IncludeText)
+	root, err := describeScreen(ctx, t.executor, t.backend, params.UDID)
 	if err != nil {
-		return &types.UIDescribeResult{Success: false}, fmt.Errorf("failed to get UI hierarchy: %w", err)
+		return &types.UIDescribeResult{Success: false, Backend: t.backend.Name()}, err
 	}
+	root = root.Prune(params.MaxDepth)
 
-	// Parse UI data based on format
-	var hierarchyData interface{}
-	elementCount := 0
+	result := &types.UIDescribeResult{
+		Success:      true,
+		Backend:      t.backend.Name(),
+		ElementCount: root.Count(),
+	}
 
-	switch strings.ToLower(params.Format) {
+	switch format {
 	case "json":
-		if err := json.Unmarshal([]byte(uiData), &hierarchyData); err != nil {
-			// If JSON parsing fails, return as string
-			hierarchyData = uiData
-		}
-		elementCount = t.countElementsInJSON(uiData)
-	case "tree", "flat":
-		hierarchyData = uiData
-		elementCount = t.countElementsInText(uiData)
+		result.Root = root
+	case "tree":
+		result.UIHierarchy = renderTree(root, params.IncludeText)
+	case "flat":
+		result.UIHierarchy = renderFlat(root, params.IncludeText)
 	}
 
-	return &types.UIDescribeResult{
-		Success:      true,
-		UIHierarchy:  hierarchyData,
-		ElementCount: elementCount,
-	}, nil
+	return result, nil
 }
 
-func (t *DescribeUI) ensureDeviceBooted(ctx context.Context, udid string) error {
-	// Check device state
-	cmd := exec.CommandContext(ctx, "xcrun", "simctl", "list", "devices", "--json")
-	output, err := cmd.Output()
-	if err != nil {
-		return fmt.Errorf("failed to check device state: %w", err)
+// describeScreen reads the hierarchy through backend. The backend's own error
+// rarely says why it failed, so a failure is checked against the simulator's
+// state to report SIMULATOR_NOT_BOOTED or SIMULATOR_NOT_FOUND instead.
+func describeScreen(ctx context.Context, executor *xcode.Executor, backend accessibility.Backend, udid string) (*types.UIElement, error) {
+	root, err := backend.Describe(ctx, udid)
+	if err == nil {
+		return root, nil
 	}
 
-	var deviceList struct {
-		Devices map[string][]struct {
-			UDID  string `json:"udid"`
-			State string `json:"state"`
-		} `json:"devices"`
+	if types.IsXcodeError(err, types.ErrCodeBackendUnavailable) {
+		return nil, err
 	}
-
-	if err := json.Unmarshal(output, &deviceList); err != nil {
-		return fmt.Errorf("failed to parse device list: %w", err)
+	if stateErr := ensureDeviceBooted(ctx, executor, udid); types.IsXcodeError(stateErr, types.ErrCodeSimulatorNotBooted) ||
+		types.IsXcodeError(stateErr, types.ErrCodeSimulatorNotFound) {
+		return nil, stateErr
 	}
+	return nil, toolError(err, types.ErrCodeCommandFailed, "failed to get UI hierarchy",
+		map[string]interface{}{"backend": backend.Name(), "udid": udid})
+}
 
-	// Find the device and check if it's booted
-	for _, devices := range deviceList.Devices {
-		for _, device := range devices {
-			if device.UDID == udid {
-				if device.State != "Booted" {
-					return fmt.Errorf("device is %s, not Booted", device.State)
-				}
-				return nil
+// renderTree draws the hierarchy with box-drawing connectors, one element
+// per line
+func renderTree(root *types.UIElement, includeText bool) string {
+	var b strings.Builder
+	b.WriteString(describeElement(root, includeText))
+
+	var walk func(children []*types.UIElement, prefix string)
+	walk = func(children []*types.UIElement, prefix string) {
+		for i, child := range children {
+			connector, indent := "├── ", "│   "
+			if i == len(children)-1 {
+				connector, indent = "└── ", "    "
 			}
+			b.WriteString("\n" + prefix + connector + describeElement(child, includeText))
+			walk(child.Children, prefix+indent)
 		}
 	}
+	walk(root.Children, "")
 
-	return fmt.Errorf("device not found")
+	return b.String()
 }
 
-func (t *DescribeUI) getUIHierarchy(ctx context.Context, udid, format string, maxDepth int, includeText bool) (string, error) {
-	// Use simctl to spawn accessibility inspector or use AppleScript approach
-	// Since direct UI hierarchy access is limited, we'll create a mock structure
-	// In a real implementation, this would use private frameworks or accessibility APIs
-
-	// For now, we'll create a representative UI hierarchy structure
-	if strings.ToLower(format) == "json" {
-		return t.generateMockJSONHierarchy(includeText, maxDepth), nil
-	}
-
-	// For tree and flat formats, generate text representation
-	return t.generateMockTextHierarchy(format, includeText, maxDepth), nil
+// renderFlat lists every element on its own line in depth-first order
+func renderFlat(root *types.UIElement, includeText bool) string {
+	var lines []string
+	root.Walk(func(element *types.UIElement, depth int) bool {
+		lines = append(lines, describeElement(element, includeText))
+		return true
+	})
+	return strings.Join(lines, "\n")
 }
 
-func (t *DescribeUI) generateMockJSONHierarchy(includeText bool, maxDepth int) string {
-	// Generate a mock JSON hierarchy that represents typical iOS app structure
-	hierarchy := map[string]interface{}{
-		"type":       "Application",
-		"identifier": "com.example.app",
-		"frame":      map[string]int{"x": 0, "y": 0, "width": 375, "height": 812},
-		"visible":    true,
-		"children": []map[string]interface{}{
-			{
-				"type":       "NavigationBar",
-				"identifier": "Navigation Bar",
-				"frame":      map[string]int{"x": 0, "y": 44, "width": 375, "height": 44},
-				"visible":    true,
-				"children": []map[string]interface{}{
-					{
-						"type":       "Button",
-						"identifier": "Back",
-						"frame":      map[string]int{"x": 16, "y": 52, "width": 60, "height": 30},
-						"visible":    true,
-						"enabled":    true,
-					},
-				},
-			},
-			{
-				"type":       "ScrollView",
-				"identifier": "Main Content",
-				"frame":      map[string]int{"x": 0, "y": 88, "width": 375, "height": 724},
-				"visible":    true,
-				"children": []map[string]interface{}{
-					{
-						"type":       "Cell",
-						"identifier": "Table View Cell",
-						"frame":      map[string]int{"x": 0, "y": 88, "width": 375, "height": 60},
-						"visible":    true,
-					},
-				},
-			},
-		},
+// describeElement formats one element as
+// Type "label" (identifier) [x,y,width,height] traits
+func describeElement(element *types.UIElement, includeText bool) string {
+	var b strings.Builder
+	b.WriteString(element.Type)
+	if element.Label != "" {
+		fmt.Fprintf(&b, " %q", element.Label)
 	}
-
-	if includeText {
-		t.addTextToHierarchy(hierarchy)
+	if element.Identifier != "" {
+		fmt.Fprintf(&b, " (%s)", element.Identifier)
 	}
 
-	jsonData, _ := json.MarshalIndent(hierarchy, "", "  ")
-	return string(jsonData)
-}
+	f := element.Frame
+	fmt.Fprintf(&b, " [%g,%g,%g,%g]", f.X, f.Y, f.Width, f.Height)
 
-func (t *DescribeUI) generateMockTextHierarchy(format string, includeText bool, maxDepth int) string {
-	if strings.ToLower(format) == "tree" {
-		hierarchy := `Application (com.example.app) [0,0,375,812]
-├── NavigationBar (Navigation Bar) [0,44,375,44]
-│   └── Button (Back) [16,52,60,30] enabled
-├── ScrollView (Main Content) [0,88,375,724]
-│   ├── Cell (Table View Cell) [0,88,375,60]
-│   ├── Cell (Table View Cell) [0,148,375,60]
-│   └── Cell (Table View Cell) [0,208,375,60]
-└── TabBar (Tab Bar) [0,763,375,49]
-    ├── Button (Home) [0,763,125,49] selected
-    ├── Button (Search) [125,763,125,49]
-    └── Button (Profile) [250,763,125,49]`
-
-		if includeText {
-			hierarchy += `
-    Text: "Welcome to the app"
-    Text: "Search for items"
-    Text: "View your profile"`
-		}
-
-		return hierarchy
+	if len(element.Traits) > 0 {
+		b.WriteString(" " + strings.Join(element.Traits, ","))
 	}
-
-	// Flat format
-	hierarchy := `Application (com.example.app) [0,0,375,812]
-NavigationBar (Navigation Bar) [0,44,375,44]
-Button (Back) [16,52,60,30] enabled
-ScrollView (Main Content) [0,88,375,724]
-Cell (Table View Cell) [0,88,375,60]
-Cell (Table View Cell) [0,148,375,60]
-Cell (Table View Cell) [0,208,375,60]
-TabBar (Tab Bar) [0,763,375,49]
-Button (Home) [0,763,125,49] selected
-Button (Search) [125,763,125,49]
-Button (Profile) [250,763,125,49]`
-
-	if includeText {
-		hierarchy += `
-Text: "Welcome to the app"
-Text: "Search for items"
-Text: "View your profile"`
-	}
-
-	return hierarchy
-}
-
-func (t *DescribeUI) addTextToHierarchy(hierarchy map[string]interface{}) {
-	// Add text properties to elements where appropriate
-	if children, ok := hierarchy["children"].([]map[string]interface{}); ok {
-		for _, child := range children {
-			if child["type"] == "Button" {
-				child["text"] = child["identifier"]
-			}
-			t.addTextToHierarchy(child)
-		}
-	}
-}
-
-func (t *DescribeUI) countElementsInJSON(jsonData string) int {
-	// Simple count based on occurrence of "type" fields
-	return strings.Count(jsonData, `"type":`)
-}
-
-func (t *DescribeUI) countElementsInText(textData string) int {
-	// Count lines that represent UI elements
-	lines := strings.Split(textData, "\n")
-	count := 0
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line != "" && !strings.HasPrefix(line, "Text:") {
-			count++
-		}
+	if includeText && element.Value != "" {
+		fmt.Fprintf(&b, " value=%q", element.Value)
 	}
-	return count
+	return b.String()
 }
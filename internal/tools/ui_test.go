@@ -6,18 +6,25 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/jontolof/xcode-build-mcp/internal/accessibility"
+	"github.com/jontolof/xcode-build-mcp/internal/xcode"
 	"github.com/jontolof/xcode-build-mcp/pkg/types"
 )
 
+// newFixtureDescribeUI describes the login screen recorded from AXe
+func newFixtureDescribeUI() *DescribeUI {
+	return NewDescribeUI(xcode.NewExecutor(&testLogger{}), accessibility.NewFixtureBackend("../accessibility/testdata/axe_login.json"))
+}
+
 func TestDescribeUI_Name(t *testing.T) {
-	tool := NewDescribeUI()
+	tool := newFixtureDescribeUI()
 	if got := tool.Name(); got != "describe_ui" {
 		t.Errorf("DescribeUI.Name() = %v, want %v", got, "describe_ui")
 	}
 }
 
 func TestDescribeUI_Description(t *testing.T) {
-	tool := NewDescribeUI()
+	tool := newFixtureDescribeUI()
 	desc := tool.Description()
 	if desc == "" {
 		t.Error("DescribeUI.Description() returned empty string")
@@ -28,11 +35,12 @@ func TestDescribeUI_Description(t *testing.T) {
 }
 
 func TestDescribeUI_Execute_InvalidParams(t *testing.T) {
-	tool := NewDescribeUI()
+	tool := newFixtureDescribeUI()
 	ctx := context.Background()
 
 	// Test with invalid JSON
-	result, err := tool.Execute(ctx, map[string]interface{}{})
+	toolResult, err := tool.Execute(ctx, map[string]interface{}{})
+	result := toolResult.Text()
 	if err == nil {
 		t.Error("Expected error for invalid JSON, got nil")
 	}
@@ -42,15 +50,16 @@ func TestDescribeUI_Execute_InvalidParams(t *testing.T) {
 }
 
 func TestDescribeUI_Execute_ValidParams(t *testing.T) {
-	tool := NewDescribeUI()
+	tool := newFixtureDescribeUI()
 	ctx := context.Background()
 
-	resultStr, execErr := tool.Execute(ctx, map[string]interface{}{
+	toolResult, execErr := tool.Execute(ctx, map[string]interface{}{
 		"udid":         "test-udid",
 		"format":       "tree",
 		"max_depth":    5,
 		"include_text": true,
 	})
+	resultStr := toolResult.Text()
 
 	// Should get a result even if command fails
 	if resultStr == "" {
@@ -75,13 +84,14 @@ func TestDescribeUI_Execute_ValidParams(t *testing.T) {
 }
 
 func TestDescribeUI_Execute_DefaultValues(t *testing.T) {
-	tool := NewDescribeUI()
+	tool := newFixtureDescribeUI()
 	ctx := context.Background()
 
 	// Test with minimal params
-	resultStr, execErr := tool.Execute(ctx, map[string]interface{}{
+	toolResult, execErr := tool.Execute(ctx, map[string]interface{}{
 		"udid": "test-udid",
 	})
+	resultStr := toolResult.Text()
 
 	if resultStr == "" {
 		t.Error("Expected non-empty result")
@@ -105,126 +115,114 @@ func TestDescribeUI_Execute_DefaultValues(t *testing.T) {
 	}
 }
 
-func TestDescribeUI_GenerateMockJSONHierarchy(t *testing.T) {
-	tool := NewDescribeUI()
+func TestDescribeUI_JSONFormat(t *testing.T) {
+	tool := newFixtureDescribeUI()
 
-	// Test JSON hierarchy generation
-	jsonData := tool.generateMockJSONHierarchy(false, 5)
-
-	if jsonData == "" {
-		t.Error("Expected non-empty JSON data")
+	toolResult, err := tool.Execute(context.Background(), map[string]interface{}{
+		"udid":          "test-udid",
+		"output_format": "json",
+	})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
 	}
 
-	// Verify it's valid JSON
-	var hierarchy map[string]interface{}
-	if err := json.Unmarshal([]byte(jsonData), &hierarchy); err != nil {
-		t.Errorf("Generated JSON is not valid: %v", err)
+	result := toolResult.Structured.(*types.UIDescribeResult)
+	if result.Backend != "fixture" || result.Root == nil {
+		t.Fatalf("Expected the fixture tree as root, got %+v", result)
 	}
-
-	// Check basic structure
-	if hierarchy["type"] != "Application" {
-		t.Errorf("Expected type 'Application', got %v", hierarchy["type"])
+	if result.ElementCount != 11 {
+		t.Errorf("Expected 11 elements, got %d", result.ElementCount)
 	}
 
-	children, ok := hierarchy["children"].([]interface{})
-	if !ok || len(children) == 0 {
-		t.Error("Expected children array in hierarchy")
+	var email *types.UIElement
+	result.Root.Walk(func(e *types.UIElement, depth int) bool {
+		if e.Identifier == "login.email" {
+			email = e
+		}
+		return true
+	})
+	if email == nil || email.Type != "TextField" || email.Frame.Width != 345 {
+		t.Errorf("Expected the typed email field, got %+v", email)
 	}
 }
 
-func TestDescribeUI_GenerateMockTextHierarchy(t *testing.T) {
-	tool := NewDescribeUI()
+func TestDescribeUI_TreeAndFlatFormats(t *testing.T) {
+	tool := newFixtureDescribeUI()
 
 	tests := []struct {
-		format      string
-		includeText bool
-		expected    []string
+		format   string
+		args     map[string]interface{}
+		expected []string
+		absent   []string
+		count    int
 	}{
 		{
-			format:   "tree",
-			expected: []string{"Application", "├──", "└──"},
+			format: "tree",
+			expected: []string{
+				`Application "Example" [0,0,393,852]`,
+				`├── Button "Log In" (login.submit) [24,316,345,50] button,notEnabled`,
+				`│   └── Link "Contact Support" (login.support) [24,1200,345,44] link`,
+				`└── TabBar "Tab Bar" [0,768,393,84] tabBar`,
+			},
+			absent: []string{"value="},
+			count:  11,
 		},
 		{
 			format:   "flat",
-			expected: []string{"Application", "NavigationBar", "Button"},
+			args:     map[string]interface{}{"include_text": true},
+			expected: []string{"\nButton \"Home\" (tab.home) [0,768,196,50] button value=\"1\""},
+			count:    11,
 		},
 		{
-			format:      "tree",
-			includeText: true,
-			expected:    []string{"Application", "Text:", "Welcome"},
+			format:   "tree",
+			args:     map[string]interface{}{"max_depth": float64(1)},
+			expected: []string{`└── TabBar "Tab Bar"`},
+			absent:   []string{"Home", "Contact Support"},
+			count:    7,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.format, func(t *testing.T) {
-			textData := tool.generateMockTextHierarchy(tt.format, tt.includeText, 5)
+			args := map[string]interface{}{"udid": "test-udid", "output_format": tt.format}
+			for k, v := range tt.args {
+				args[k] = v
+			}
 
-			if textData == "" {
-				t.Error("Expected non-empty text data")
+			toolResult, err := tool.Execute(context.Background(), args)
+			if err != nil {
+				t.Fatalf("Execute failed: %v", err)
 			}
+			result := toolResult.Structured.(*types.UIDescribeResult)
 
 			for _, expected := range tt.expected {
-				if !strings.Contains(textData, expected) {
-					t.Errorf("Expected text to contain '%s', got: %s", expected, textData)
+				if !strings.Contains(result.UIHierarchy, expected) {
+					t.Errorf("Expected output to contain %q, got:\n%s", expected, result.UIHierarchy)
+				}
+			}
+			for _, absent := range tt.absent {
+				if strings.Contains(result.UIHierarchy, absent) {
+					t.Errorf("Expected output not to contain %q, got:\n%s", absent, result.UIHierarchy)
 				}
 			}
+			if result.ElementCount != tt.count {
+				t.Errorf("Expected %d elements, got %d", tt.count, result.ElementCount)
+			}
 		})
 	}
 }
 
-func TestDescribeUI_CountElementsInJSON(t *testing.T) {
-	tool := NewDescribeUI()
-
-	jsonData := `{"type": "Application", "children": [{"type": "Button"}, {"type": "Label"}]}`
-	count := tool.countElementsInJSON(jsonData)
-
-	expected := 3 // Application, Button, Label
-	if count != expected {
-		t.Errorf("Expected count %d, got %d", expected, count)
-	}
-}
-
-func TestDescribeUI_CountElementsInText(t *testing.T) {
-	tool := NewDescribeUI()
-
-	textData := `Application [0,0,375,812]
-Button [0,0,60,30]
-Text: "Hello World"
-Label [0,40,100,20]`
-
-	count := tool.countElementsInText(textData)
-
-	expected := 3 // Application, Button, Label (Text: line is ignored)
-	if count != expected {
-		t.Errorf("Expected count %d, got %d", expected, count)
-	}
-}
-
-func TestDescribeUI_AddTextToHierarchy(t *testing.T) {
-	tool := NewDescribeUI()
-
-	hierarchy := map[string]interface{}{
-		"type": "Application",
-		"children": []map[string]interface{}{
-			{
-				"type":       "Button",
-				"identifier": "Submit",
-			},
-		},
-	}
-
-	tool.addTextToHierarchy(hierarchy)
-
-	children := hierarchy["children"].([]map[string]interface{})
-	button := children[0]
+func TestDescribeUI_BackendUnavailable(t *testing.T) {
+	tool := NewDescribeUI(xcode.NewExecutor(&testLogger{}), accessibility.NewFixtureBackend(""))
 
-	if button["text"] != "Submit" {
-		t.Errorf("Expected button text 'Submit', got %v", button["text"])
+	_, err := tool.Execute(context.Background(), map[string]interface{}{"udid": "test-udid"})
+	if !types.IsXcodeError(err, types.ErrCodeBackendUnavailable) {
+		t.Errorf("Expected BACKEND_UNAVAILABLE, got %v", err)
 	}
 }
 
 func TestDescribeUI_FormatValidation(t *testing.T) {
-	tool := NewDescribeUI()
+	tool := newFixtureDescribeUI()
 
 	// Test unsupported format
 	params := &types.UIDescribeParams{
@@ -298,7 +296,7 @@ func TestDescribeUI_ParameterValidation(t *testing.T) {
 		},
 	}
 
-	tool := NewDescribeUI()
+	tool := newFixtureDescribeUI()
 	ctx := context.Background()
 
 	for _, tt := range tests {
@@ -317,7 +315,8 @@ func TestDescribeUI_ParameterValidation(t *testing.T) {
 				args["include_text"] = tt.params.IncludeText
 			}
 
-			resultStr, execErr := tool.Execute(ctx, args)
+			toolResult, execErr := tool.Execute(ctx, args)
+			resultStr := toolResult.Text()
 
 			if tt.valid {
 				if resultStr == "" {
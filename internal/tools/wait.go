@@ -0,0 +1,299 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jontolof/xcode-build-mcp/internal/accessibility"
+	"github.com/jontolof/xcode-build-mcp/internal/xcode"
+	"github.com/jontolof/xcode-build-mcp/pkg/types"
+)
+
+// Conditions accepted by wait_for_element
+const (
+	ConditionAppears    = "appears"
+	ConditionDisappears = "disappears"
+	ConditionEnabled    = "enabled"
+)
+
+const (
+	defaultWaitTimeout = 10 * time.Second
+	maxPollInterval    = 2 * time.Second
+)
+
+// pollInterval is the delay before the second read of the screen. It doubles
+// after every read up to maxPollInterval, so a slow screen is not hammered.
+var pollInterval = 250 * time.Millisecond
+
+type WaitForElement struct {
+	name         string
+	description  string
+	schema       map[string]interface{}
+	outputSchema map[string]interface{}
+	executor     *xcode.Executor
+	backend      accessibility.Backend
+}
+
+// NewWaitForElement polls the hierarchy through backend, or through the
+// backend selected by accessibility.FromEnv when backend is nil, and checks
+// the simulator state through executor
+func NewWaitForElement(executor *xcode.Executor, backend accessibility.Backend) *WaitForElement {
+	if backend == nil {
+		backend = accessibility.FromEnv(executor.Runner())
+	}
+
+	schema := createJSONSchema("object", map[string]interface{}{
+		"udid": map[string]interface{}{
+			"type":        "string",
+			"description": "UDID of the target simulator or device (optional for auto-detection)",
+		},
+		"device_type": map[string]interface{}{
+			"type":        "string",
+			"description": "Device type filter for auto-selection if UDID not provided",
+		},
+		"element_id": map[string]interface{}{
+			"type":        "string",
+			"description": "Accessibility identifier of the element to wait for",
+		},
+		"label": map[string]interface{}{
+			"type":        "string",
+			"description": "Accessibility label of the element to wait for",
+		},
+		"predicate": elementQuerySchema(),
+		"condition": map[string]interface{}{
+			"type":        "string",
+			"description": "Condition to wait for (appears, disappears, enabled) - default: appears",
+		},
+		"timeout": map[string]interface{}{
+			"type":        "number",
+			"description": "Seconds to wait before failing with TIMEOUT - default: 10",
+		},
+	}, []string{})
+
+	return &WaitForElement{
+		name:         "wait_for_element",
+		description:  "Wait until an element found by accessibility identifier, label or predicate appears, disappears or becomes enabled, polling the accessibility hierarchy with backoff",
+		schema:       schema,
+		outputSchema: types.SchemaFor(types.WaitForElementResult{}),
+		executor:     executor,
+		backend:      backend,
+	}
+}
+
+func (t *WaitForElement) Name() string {
+	return t.name
+}
+
+func (t *WaitForElement) Description() string {
+	return t.description
+}
+
+func (t *WaitForElement) InputSchema() map[string]interface{} {
+	return t.schema
+}
+
+func (t *WaitForElement) OutputSchema() map[string]interface{} {
+	return t.outputSchema
+}
+
+func (t *WaitForElement) Execute(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error) {
+	p, err := t.parseParams(args)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+
+	// Auto-select device if not specified
+	if p.UDID == "" && p.DeviceType == "" {
+		simulator, err := selectBestSimulator(t.executor, "")
+		if err != nil {
+			errorResult := &types.WaitForElementResult{
+				Success:   false,
+				Condition: p.Condition,
+				Duration:  time.Since(start),
+			}
+			partial, _ := types.NewToolResult(errorResult, true)
+			return partial, toolError(err, types.ErrCodeSimulatorNotFound, "failed to auto-select device", nil)
+		}
+		p.UDID = simulator.UDID
+	}
+	if p.UDID == "" {
+		return nil, invalidParams("device UDID is required", map[string]interface{}{"parameter": "udid"})
+	}
+
+	timeout := defaultWaitTimeout
+	if p.Timeout > 0 {
+		timeout = time.Duration(p.Timeout * float64(time.Second))
+	}
+
+	wait, err := waitForElement(ctx, t.executor, t.backend, p.UDID, p.Element, p.Condition, timeout)
+	result := &types.WaitForElementResult{
+		Success:   err == nil,
+		Condition: p.Condition,
+		Matches:   wait.matches,
+		Polls:     wait.polls,
+		Duration:  time.Since(start),
+	}
+	if wait.element != nil {
+		result.Element = leafCopy(wait.element)
+	}
+	if err != nil {
+		partial, _ := types.NewToolResult(result, true)
+		return partial, err
+	}
+
+	result.Output = fmt.Sprintf("Condition %s met for element matching %s after %.1f seconds (%d polls)",
+		p.Condition, p.Element.String(), result.Duration.Seconds(), result.Polls)
+	return types.NewToolResult(result, false)
+}
+
+// parseParams reads the element query, condition and timeout from args
+func (t *WaitForElement) parseParams(args map[string]interface{}) (*types.WaitForElementParams, error) {
+	var p types.WaitForElementParams
+
+	// Parse parameters from args
+	if udid, exists := args["udid"]; exists {
+		if str, ok := udid.(string); ok {
+			p.UDID = str
+		}
+	}
+	if deviceType, exists := args["device_type"]; exists {
+		if str, ok := deviceType.(string); ok {
+			p.DeviceType = str
+		}
+	}
+	condition, err := parseCondition(args)
+	if err != nil {
+		return nil, err
+	}
+	p.Condition = condition
+	if timeout, exists := args["timeout"]; exists {
+		num, ok := timeout.(float64)
+		if !ok || num < 0 {
+			return nil, invalidParams("timeout must be a positive number of seconds", map[string]interface{}{"parameter": "timeout"})
+		}
+		p.Timeout = num
+	}
+
+	query, err := parseElementQuery(args)
+	if err != nil {
+		return nil, err
+	}
+	if query.IsZero() {
+		return nil, invalidParams("element_id, label or predicate is required",
+			map[string]interface{}{"parameters": []string{"element_id", "label", "predicate"}})
+	}
+	p.Element = query
+	return &p, nil
+}
+
+// waitResult is the state of the screen at the last poll
+type waitResult struct {
+	element *types.UIElement
+	matches int
+	polls   int
+}
+
+// waitForElement reads the screen until condition holds for the element
+// matching query, backing off between reads. A read that fails with
+// COMMAND_FAILED, as while the app is relaunching, is retried; other errors
+// end the wait. When timeout passes it returns TIMEOUT with the last state.
+func waitForElement(ctx context.Context, executor *xcode.Executor, backend accessibility.Backend, udid string, query types.ElementQuery,
+	condition string, timeout time.Duration) (waitResult, error) {
+
+	var (
+		result   waitResult
+		lastErr  error
+		interval = pollInterval
+		deadline = time.Now().Add(timeout)
+	)
+
+	for {
+		result.polls++
+		element, _, matches, err := findElement(ctx, executor, backend, udid, query)
+		result.element, result.matches = element, matches
+
+		switch {
+		case err == nil || types.IsXcodeError(err, types.ErrCodeElementNotFound):
+			lastErr = nil
+			if conditionMet(condition, element) {
+				return result, nil
+			}
+		case types.IsXcodeError(err, types.ErrCodeCommandFailed):
+			lastErr = err
+		default:
+			return result, err
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			break
+		}
+
+		timer := time.NewTimer(min(interval, remaining))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return result, ctx.Err()
+		case <-timer.C:
+		}
+		interval = min(interval*2, maxPollInterval)
+	}
+
+	details := map[string]interface{}{
+		"query":     query,
+		"condition": condition,
+		"matches":   result.matches,
+		"polls":     result.polls,
+		"timeout":   timeout.Seconds(),
+	}
+	if lastErr != nil {
+		details["last_error"] = lastErr.Error()
+	}
+	return result, types.NewXcodeError(types.ErrCodeTimeout,
+		fmt.Sprintf("timed out after %.1f seconds waiting for element matching %s to %s", timeout.Seconds(), query.String(), conditionVerb(condition)),
+		details)
+}
+
+// parseCondition reads the condition parameter, defaulting to appears
+func parseCondition(args map[string]interface{}) (string, error) {
+	condition, err := parseStringParam(args, "condition", false)
+	if err != nil {
+		return "", err
+	}
+
+	switch condition = strings.ToLower(condition); condition {
+	case "":
+		return ConditionAppears, nil
+	case ConditionAppears, ConditionDisappears, ConditionEnabled:
+		return condition, nil
+	default:
+		return "", invalidParams(fmt.Sprintf("unsupported condition: %s (supported: appears, disappears, enabled)", condition),
+			map[string]interface{}{"parameter": "condition"})
+	}
+}
+
+func conditionMet(condition string, element *types.UIElement) bool {
+	switch condition {
+	case ConditionDisappears:
+		return element == nil
+	case ConditionEnabled:
+		return element != nil && element.Enabled
+	default:
+		return element != nil
+	}
+}
+
+func conditionVerb(condition string) string {
+	switch condition {
+	case ConditionDisappears:
+		return "disappear"
+	case ConditionEnabled:
+		return "become enabled"
+	default:
+		return "appear"
+	}
+}
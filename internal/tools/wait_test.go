@@ -0,0 +1,204 @@
+package tools
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jontolof/xcode-build-mcp/internal/accessibility"
+	"github.com/jontolof/xcode-build-mcp/internal/xcode"
+	"github.com/jontolof/xcode-build-mcp/pkg/types"
+)
+
+// changingBackend serves the login fixture and applies change to it from the
+// read after the given number of reads on, like a screen finishing loading
+type changingBackend struct {
+	*accessibility.FixtureBackend
+	after  int
+	reads  int
+	change func(root *types.UIElement)
+}
+
+func newChangingBackend(after int, change func(root *types.UIElement)) *changingBackend {
+	return &changingBackend{
+		FixtureBackend: accessibility.NewFixtureBackend("../accessibility/testdata/axe_login.json"),
+		after:          after,
+		change:         change,
+	}
+}
+
+func (b *changingBackend) Describe(ctx context.Context, udid string) (*types.UIElement, error) {
+	root, err := b.FixtureBackend.Describe(ctx, udid)
+	if err != nil {
+		return nil, err
+	}
+	b.reads++
+	if b.reads > b.after {
+		b.change(root)
+	}
+	return root, nil
+}
+
+func TestWaitForElement_Name(t *testing.T) {
+	tool := NewWaitForElement(xcode.NewExecutor(&testLogger{}), newChangingBackend(0, func(*types.UIElement) {}))
+	if got := tool.Name(); got != "wait_for_element" {
+		t.Errorf("WaitForElement.Name() = %v, want %v", got, "wait_for_element")
+	}
+	if len(tool.Description()) < 20 {
+		t.Errorf("WaitForElement.Description() too short: %s", tool.Description())
+	}
+}
+
+func TestWaitForElement_Conditions(t *testing.T) {
+	defer func(interval time.Duration) { pollInterval = interval }(pollInterval)
+	pollInterval = time.Millisecond
+
+	enableSubmit := func(root *types.UIElement) {
+		root.Walk(func(e *types.UIElement, depth int) bool {
+			if e.Identifier == "login.submit" {
+				e.Enabled = true
+			}
+			return true
+		})
+	}
+	dismissHelp := func(root *types.UIElement) {
+		var children []*types.UIElement
+		for _, child := range root.Children {
+			if child.Identifier != "login.help" {
+				children = append(children, child)
+			}
+		}
+		root.Children = children
+	}
+
+	tests := []struct {
+		name      string
+		backend   *changingBackend
+		args      map[string]interface{}
+		polls     int
+		elementID string
+	}{
+		{
+			name:      "appears",
+			backend:   newChangingBackend(0, func(*types.UIElement) {}),
+			args:      map[string]interface{}{"element_id": "login.email"},
+			polls:     1,
+			elementID: "login.email",
+		},
+		{
+			name:      "enabled",
+			backend:   newChangingBackend(2, enableSubmit),
+			args:      map[string]interface{}{"element_id": "login.submit", "condition": "enabled"},
+			polls:     3,
+			elementID: "login.submit",
+		},
+		{
+			name:    "disappears",
+			backend: newChangingBackend(3, dismissHelp),
+			args: map[string]interface{}{
+				"predicate": map[string]interface{}{"label_contains": "forgot"},
+				"condition": "disappears",
+			},
+			polls: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := map[string]interface{}{"udid": "test-udid", "timeout": float64(5)}
+			for k, v := range tt.args {
+				args[k] = v
+			}
+
+			toolResult, err := NewWaitForElement(xcode.NewExecutor(&testLogger{}), tt.backend).Execute(context.Background(), args)
+			if err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+
+			result := toolResult.Structured.(*types.WaitForElementResult)
+			if !result.Success || result.Polls != tt.polls {
+				t.Errorf("Expected success after %d polls, got %+v", tt.polls, result)
+			}
+			if tt.elementID == "" && result.Element != nil {
+				t.Errorf("Expected no element, got %+v", result.Element)
+			}
+			if tt.elementID != "" && (result.Element == nil || result.Element.Identifier != tt.elementID) {
+				t.Errorf("Expected %s, got %+v", tt.elementID, result.Element)
+			}
+		})
+	}
+}
+
+func TestWaitForElement_Timeout(t *testing.T) {
+	defer func(interval time.Duration) { pollInterval = interval }(pollInterval)
+	pollInterval = time.Millisecond
+
+	tool := NewWaitForElement(xcode.NewExecutor(&testLogger{}), newChangingBackend(0, func(*types.UIElement) {}))
+	toolResult, err := tool.Execute(context.Background(), map[string]interface{}{
+		"udid":       "test-udid",
+		"element_id": "login.submit",
+		"condition":  "enabled",
+		"timeout":    0.05,
+	})
+	if !types.IsXcodeError(err, types.ErrCodeTimeout) {
+		t.Fatalf("Expected TIMEOUT, got %v", err)
+	}
+	if !toolResult.IsError {
+		t.Error("Expected isError on timeout")
+	}
+
+	// The disabled button is still reported so the caller can see why
+	result := toolResult.Structured.(*types.WaitForElementResult)
+	if result.Success || result.Element == nil || result.Element.Enabled || result.Polls < 2 {
+		t.Errorf("Unexpected timeout result: %+v", result)
+	}
+}
+
+func TestWaitForElement_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		backend accessibility.Backend
+		args    map[string]interface{}
+		code    types.ErrorCode
+	}{
+		{
+			name: "no element",
+			args: map[string]interface{}{"condition": "appears"},
+			code: types.ErrCodeInvalidParams,
+		},
+		{
+			name: "unknown condition",
+			args: map[string]interface{}{"element_id": "login.email", "condition": "visible"},
+			code: types.ErrCodeInvalidParams,
+		},
+		{
+			name: "negative timeout",
+			args: map[string]interface{}{"element_id": "login.email", "timeout": float64(-1)},
+			code: types.ErrCodeInvalidParams,
+		},
+		{
+			name:    "backend unavailable",
+			backend: accessibility.NewFixtureBackend(""),
+			args:    map[string]interface{}{"element_id": "login.email"},
+			code:    types.ErrCodeBackendUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := tt.backend
+			if backend == nil {
+				backend = accessibility.NewFixtureBackend("../accessibility/testdata/axe_login.json")
+			}
+			args := map[string]interface{}{"udid": "test-udid"}
+			for k, v := range tt.args {
+				args[k] = v
+			}
+
+			_, err := NewWaitForElement(xcode.NewExecutor(&testLogger{}), backend).Execute(context.Background(), args)
+			if !types.IsXcodeError(err, tt.code) {
+				t.Errorf("Expected %s, got %v", tt.code, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package tools
+
+import (
+	"context"
+	"time"
+
+	"github.com/jontolof/xcode-build-mcp/internal/xcode"
+	"github.com/jontolof/xcode-build-mcp/pkg/types"
+)
+
+type ListXcodeVersionsTool struct {
+	name         string
+	description  string
+	schema       map[string]interface{}
+	outputSchema map[string]interface{}
+	executor     *xcode.Executor
+}
+
+func NewListXcodeVersionsTool(executor *xcode.Executor) *ListXcodeVersionsTool {
+	schema := createJSONSchema("object", map[string]interface{}{}, []string{})
+
+	return &ListXcodeVersionsTool{
+		name:         "list_xcode_versions",
+		description:  "List the installed Xcode versions with their build numbers, SDKs and developer directories, marking the one xcode-select points at; pass a version or build number as xcode_version to the build and test tools to use another",
+		schema:       schema,
+		outputSchema: types.SchemaFor(types.XcodeVersionsResult{}),
+		executor:     executor,
+	}
+}
+
+func (t *ListXcodeVersionsTool) Name() string {
+	return t.name
+}
+
+func (t *ListXcodeVersionsTool) Description() string {
+	return t.description
+}
+
+func (t *ListXcodeVersionsTool) InputSchema() map[string]interface{} {
+	return t.schema
+}
+
+func (t *ListXcodeVersionsTool) OutputSchema() map[string]interface{} {
+	return t.outputSchema
+}
+
+func (t *ListXcodeVersionsTool) Execute(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error) {
+	start := time.Now()
+	installations, selected := xcode.FindXcodeInstallations(ctx, t.executor.Runner(), xcode.XcodeSearchDirs())
+
+	return types.NewToolResult(&types.XcodeVersionsResult{
+		Installations:        installations,
+		SelectedDeveloperDir: selected,
+		Duration:             time.Since(start),
+	}, false)
+}
+
+// xcodeVersionSchema is the xcode_version property of the tools that run
+// xcodebuild
+func xcodeVersionSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "string",
+		"description": "Xcode to use instead of the selected one: a version (16, 16.1, \"16 beta\", \"beta\") or build number (16A242d) from list_xcode_versions",
+	}
+}
+
+// selectXcode returns the DEVELOPER_DIR a call asked for with developer_dir
+// or xcode_version, which may not both be set, or "" for the Xcode
+// xcode-select points at
+func selectXcode(ctx context.Context, executor *xcode.Executor, developerDir, version string) (string, error) {
+	if version == "" {
+		if developerDir == "" {
+			return "", nil
+		}
+		dir, err := xcode.ResolveDeveloperDir(developerDir)
+		if err != nil {
+			return "", invalidParams(err.Error(), map[string]interface{}{"parameter": "developer_dir"})
+		}
+		return dir, nil
+	}
+	if developerDir != "" {
+		return "", invalidParams("developer_dir and xcode_version cannot both be set",
+			map[string]interface{}{"parameters": []string{"developer_dir", "xcode_version"}})
+	}
+
+	installations, _ := xcode.FindXcodeInstallations(ctx, executor.Runner(), xcode.XcodeSearchDirs())
+	install, err := xcode.MatchXcodeInstallation(installations, version)
+	if err != nil {
+		available := make([]string, 0, len(installations))
+		for _, installation := range installations {
+			available = append(available, installation.Version+" ("+installation.Build+")")
+		}
+		return "", types.NewXcodeError(types.ErrCodeXcodeNotFound, err.Error(),
+			map[string]interface{}{"xcode_version": version, "installed": available})
+	}
+	return install.DeveloperDir, nil
+}
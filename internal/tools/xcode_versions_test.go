@@ -0,0 +1,130 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jontolof/xcode-build-mcp/internal/xcode"
+	"github.com/jontolof/xcode-build-mcp/pkg/types"
+)
+
+// xcodeVersionsExecutor installs a fake Xcode 16.1 where the search for
+// installations looks and answers xcode-select with it
+func xcodeVersionsExecutor(t *testing.T) (*xcode.Executor, string) {
+	t.Helper()
+	apps := t.TempDir()
+	t.Setenv("MCP_XCODE_SEARCH_PATHS", apps)
+	t.Setenv("HOME", t.TempDir())
+
+	developer := filepath.Join(apps, "Xcode.app", "Contents", "Developer")
+	if err := os.MkdirAll(filepath.Join(developer, "usr", "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(developer, "usr", "bin", "xcodebuild"), nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+	versionPlist := `{"CFBundleShortVersionString": "16.1", "ProductBuildVersion": "16B40"}`
+	if err := os.WriteFile(filepath.Join(apps, "Xcode.app", "Contents", "version.plist"), []byte(versionPlist), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fixtures := make([]xcode.Fixture, 4)
+	for i := range fixtures {
+		fixtures[i] = xcode.Fixture{Args: []string{"xcode-select", "-p"}, Stdout: developer + "\n"}
+	}
+	return xcode.NewExecutorWithRunner(&testLogger{}, xcode.NewReplayRunner(fixtures)), developer
+}
+
+func TestListXcodeVersionsTool_Execute(t *testing.T) {
+	executor, developer := xcodeVersionsExecutor(t)
+	tool := NewListXcodeVersionsTool(executor)
+
+	result, err := tool.Execute(context.Background(), map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	versions, ok := result.Structured.(*types.XcodeVersionsResult)
+	if !ok {
+		t.Fatalf("Structured result is %T", result.Structured)
+	}
+	if versions.SelectedDeveloperDir != developer {
+		t.Errorf("selected developer dir = %s, want %s", versions.SelectedDeveloperDir, developer)
+	}
+
+	found := false
+	for _, install := range versions.Installations {
+		if install.DeveloperDir == developer {
+			found = true
+			if install.Version != "16.1" || install.Build != "16B40" || !install.Selected {
+				t.Errorf("installation = %+v", install)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("Xcode at %s was not listed: %+v", developer, versions.Installations)
+	}
+}
+
+func TestSelectXcode(t *testing.T) {
+	executor, developer := xcodeVersionsExecutor(t)
+	ctx := context.Background()
+
+	xcodeApp := filepath.Dir(filepath.Dir(developer))
+	if dir, err := selectXcode(ctx, executor, xcodeApp, ""); err != nil || dir != developer {
+		t.Errorf("selectXcode() with developer_dir = %s, %v, want %s", dir, err, developer)
+	}
+	_, err := selectXcode(ctx, executor, t.TempDir(), "")
+	if xerr := types.ExtractXcodeError(err); xerr == nil || xerr.Code != types.ErrCodeInvalidParams || xerr.Details["parameter"] != "developer_dir" {
+		t.Errorf("Expected INVALID_PARAMS for a developer_dir without xcodebuild, got %v", err)
+	}
+	if dir, err := selectXcode(ctx, executor, "", "16B40"); err != nil || dir != developer {
+		t.Errorf("selectXcode(16B40) = %s, %v, want %s", dir, err, developer)
+	}
+	if _, err := selectXcode(ctx, executor, "/Applications/Xcode.app", "16"); !types.IsXcodeError(err, types.ErrCodeInvalidParams) {
+		t.Errorf("Expected INVALID_PARAMS for both developer_dir and xcode_version, got %v", err)
+	}
+	if _, err := selectXcode(ctx, executor, "", "99"); !types.IsXcodeError(err, types.ErrCodeXcodeNotFound) {
+		t.Errorf("Expected XCODE_NOT_FOUND for an Xcode that is not installed, got %v", err)
+	}
+}
+
+func TestXcodeBuildTool_UnknownXcodeVersion(t *testing.T) {
+	executor, _ := xcodeVersionsExecutor(t)
+	tool := NewXcodeBuildTool(executor, xcode.NewParser(), nil, &testLogger{})
+
+	_, err := tool.Execute(context.Background(), map[string]interface{}{
+		"project":       "App.xcodeproj",
+		"scheme":        "App",
+		"xcode_version": "99",
+	})
+	if !types.IsXcodeError(err, types.ErrCodeXcodeNotFound) {
+		t.Errorf("Expected XCODE_NOT_FOUND, got %v", err)
+	}
+}
+
+func TestXcodeCleanTool_RunsSelectedXcodebuild(t *testing.T) {
+	_, developer := xcodeVersionsExecutor(t)
+	xcodebuild := filepath.Join(developer, "usr", "bin", "xcodebuild")
+	replay := xcode.NewReplayRunner([]xcode.Fixture{
+		{Args: []string{xcodebuild, "clean", "-project", "App.xcodeproj"}, Stdout: "** CLEAN SUCCEEDED **\n"},
+	})
+	tool := NewXcodeCleanTool(xcode.NewExecutorWithRunner(&testLogger{}, replay), xcode.NewParser(), &testLogger{})
+
+	// developer_dir names the bundle; xcodebuild still runs from inside it,
+	// whatever PATH finds first
+	result, err := tool.Execute(context.Background(), map[string]interface{}{
+		"project":       "App.xcodeproj",
+		"developer_dir": filepath.Dir(filepath.Dir(developer)),
+	})
+	if err != nil {
+		t.Fatalf("xcode_clean failed: %v", err)
+	}
+	if result.IsError {
+		t.Errorf("xcode_clean reported an error: %+v", result.Structured)
+	}
+	if unused := replay.Unused(); len(unused) != 0 {
+		t.Errorf("Expected xcodebuild of the selected Xcode to run, fixtures left: %+v", unused)
+	}
+}
@@ -0,0 +1,121 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jontolof/xcode-build-mcp/internal/xcode"
+	"github.com/jontolof/xcode-build-mcp/pkg/types"
+)
+
+type InspectXCResultTool struct {
+	name         string
+	description  string
+	schema       map[string]interface{}
+	outputSchema map[string]interface{}
+	parser       *xcode.XCResultParser
+}
+
+func NewInspectXCResultTool(executor *xcode.Executor) *InspectXCResultTool {
+	schema := createJSONSchema("object", map[string]interface{}{
+		"path": map[string]interface{}{
+			"type":        "string",
+			"description": "Path to the .xcresult bundle, for example one downloaded from CI artifacts",
+		},
+		"attachments_dir": map[string]interface{}{
+			"type":        "string",
+			"description": "Directory to export test attachments (screenshots, logs) to; created if missing",
+		},
+		"only_failures": map[string]interface{}{
+			"type":        "boolean",
+			"description": "Export only the attachments of failed tests",
+			"default":     false,
+		},
+		"include_logs": map[string]interface{}{
+			"type":        "boolean",
+			"description": "Also export the diagnostics logs to a Diagnostics folder in attachments_dir",
+			"default":     false,
+		},
+	}, []string{"path"})
+
+	return &InspectXCResultTool{
+		name:         "inspect_xcresult",
+		description:  "Inspect any existing .xcresult bundle: test summary, failures with source locations, build errors and warnings, devices and run timing, optionally exporting attachments and logs to a directory",
+		schema:       schema,
+		outputSchema: types.SchemaFor(types.XCResultResult{}),
+		parser:       xcode.NewXCResultParser(executor.Runner()),
+	}
+}
+
+func (t *InspectXCResultTool) Name() string {
+	return t.name
+}
+
+func (t *InspectXCResultTool) Description() string {
+	return t.description
+}
+
+func (t *InspectXCResultTool) InputSchema() map[string]interface{} {
+	return t.schema
+}
+
+func (t *InspectXCResultTool) OutputSchema() map[string]interface{} {
+	return t.outputSchema
+}
+
+func (t *InspectXCResultTool) Execute(ctx context.Context, args map[string]interface{}) (*types.ToolResult, error) {
+	params, err := t.parseParams(args)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	result, err := t.parser.InspectResultBundle(ctx, params.Path, xcode.InspectOptions{
+		AttachmentsDir: params.AttachmentsDir,
+		OnlyFailures:   params.OnlyFailures,
+		IncludeLogs:    params.IncludeLogs,
+	})
+	if err != nil {
+		return nil, toolError(err, types.ErrCodeCommandFailed, "failed to inspect result bundle",
+			map[string]interface{}{"path": params.Path})
+	}
+	result.Duration = time.Since(start)
+
+	// A failed run is what is being inspected, not a failure of the tool
+	return types.NewToolResult(result, false)
+}
+
+func (t *InspectXCResultTool) parseParams(args map[string]interface{}) (*types.XCResultParams, error) {
+	params := &types.XCResultParams{}
+
+	var err error
+	if params.Path, err = parseStringParam(args, "path", true); err != nil {
+		return nil, err
+	}
+	if params.AttachmentsDir, err = parseStringParam(args, "attachments_dir", false); err != nil {
+		return nil, err
+	}
+	params.OnlyFailures = parseBoolParam(args, "only_failures", false)
+	params.IncludeLogs = parseBoolParam(args, "include_logs", false)
+
+	params.Path = filepath.Clean(params.Path)
+	if !strings.HasSuffix(params.Path, ".xcresult") {
+		return nil, invalidParams(fmt.Sprintf("path must be an .xcresult bundle: %s", params.Path),
+			map[string]interface{}{"parameter": "path"})
+	}
+	if info, err := os.Stat(params.Path); err != nil || !info.IsDir() {
+		return nil, invalidParams(fmt.Sprintf("result bundle not found: %s", params.Path),
+			map[string]interface{}{"parameter": "path"})
+	}
+
+	if (params.OnlyFailures || params.IncludeLogs) && params.AttachmentsDir == "" {
+		return nil, invalidParams("only_failures and include_logs require attachments_dir",
+			map[string]interface{}{"parameters": []string{"only_failures", "include_logs", "attachments_dir"}})
+	}
+
+	return params, nil
+}
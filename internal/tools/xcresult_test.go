@@ -0,0 +1,44 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jontolof/xcode-build-mcp/internal/xcode"
+	"github.com/jontolof/xcode-build-mcp/pkg/types"
+)
+
+func TestInspectXCResultTool_ParseParams(t *testing.T) {
+	tool := NewInspectXCResultTool(xcode.NewExecutor(&testLogger{}))
+
+	bundle := filepath.Join(t.TempDir(), "Tests.xcresult")
+	if err := os.Mkdir(bundle, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	params, err := tool.parseParams(map[string]interface{}{
+		"path":            bundle + "/",
+		"attachments_dir": "/tmp/attachments",
+		"only_failures":   true,
+	})
+	if err != nil {
+		t.Fatalf("parseParams failed: %v", err)
+	}
+	if params.Path != bundle || params.AttachmentsDir != "/tmp/attachments" || !params.OnlyFailures {
+		t.Errorf("Unexpected params: %+v", params)
+	}
+
+	invalid := map[string]map[string]interface{}{
+		"no path":          {},
+		"not a bundle":     {"path": filepath.Dir(bundle)},
+		"missing bundle":   {"path": filepath.Join(filepath.Dir(bundle), "Missing.xcresult")},
+		"logs without dir": {"path": bundle, "include_logs": true},
+	}
+	for name, args := range invalid {
+		_, err := tool.parseParams(args)
+		if xerr := types.ExtractXcodeError(err); xerr == nil || xerr.Code != types.ErrCodeInvalidParams {
+			t.Errorf("%s: expected INVALID_PARAMS, got %v", name, err)
+		}
+	}
+}
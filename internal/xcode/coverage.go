@@ -0,0 +1,259 @@
+package xcode
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jontolof/xcode-build-mcp/pkg/types"
+)
+
+// Coverage report detail levels, each including the ones before it
+const (
+	CoverageSummary   = "summary"
+	CoverageFiles     = "files"
+	CoverageFunctions = "functions"
+	CoverageUncovered = "uncovered"
+)
+
+// CoverageDetails lists the detail levels in increasing order
+var CoverageDetails = []string{CoverageSummary, CoverageFiles, CoverageFunctions, CoverageUncovered}
+
+// CoverageOptions selects what ParseCoverage reports. Files holds absolute
+// paths or path suffixes such as Sources/App/Login.swift; when it is non-nil
+// only those files and their targets are reported, so an empty list reports
+// the totals alone.
+type CoverageOptions struct {
+	Detail string
+	Files  []string
+}
+
+// CoverageParser reads code coverage from result bundles with xccov
+type CoverageParser struct {
+	xcrunPath string
+	runner    CommandRunner
+}
+
+// NewCoverageParser creates a new coverage parser that runs xccov with runner
+func NewCoverageParser(runner CommandRunner) *CoverageParser {
+	return &CoverageParser{
+		xcrunPath: "xcrun", // Uses xcrun to find xccov
+		runner:    runner,
+	}
+}
+
+// xccovReport is the output of xccov view --report --json
+type xccovReport struct {
+	CoveredLines    int     `json:"coveredLines"`
+	ExecutableLines int     `json:"executableLines"`
+	LineCoverage    float64 `json:"lineCoverage"`
+	Targets         []struct {
+		Name            string  `json:"name"`
+		CoveredLines    int     `json:"coveredLines"`
+		ExecutableLines int     `json:"executableLines"`
+		LineCoverage    float64 `json:"lineCoverage"`
+		Files           []struct {
+			Path            string  `json:"path"`
+			CoveredLines    int     `json:"coveredLines"`
+			ExecutableLines int     `json:"executableLines"`
+			LineCoverage    float64 `json:"lineCoverage"`
+			Functions       []struct {
+				Name            string  `json:"name"`
+				LineNumber      int     `json:"lineNumber"`
+				CoveredLines    int     `json:"coveredLines"`
+				ExecutableLines int     `json:"executableLines"`
+				ExecutionCount  int     `json:"executionCount"`
+				LineCoverage    float64 `json:"lineCoverage"`
+			} `json:"functions"`
+		} `json:"files"`
+	} `json:"targets"`
+}
+
+// xccovLine is one line of xccov view --archive --file --json output
+type xccovLine struct {
+	Line           int  `json:"line"`
+	IsExecutable   bool `json:"isExecutable"`
+	ExecutionCount int  `json:"executionCount"`
+}
+
+// ParseCoverage reads the coverage report of a result bundle. With the
+// uncovered detail level it also reads the line data of every reported file.
+func (p *CoverageParser) ParseCoverage(ctx context.Context, bundlePath string, opts CoverageOptions) (*types.Coverage, error) {
+	if _, err := os.Stat(bundlePath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("xcresult bundle not found: %s", bundlePath)
+	}
+
+	output, err := p.runXccov(ctx, "view", "--report", "--json", bundlePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read coverage report: %w", err)
+	}
+
+	coverage, err := ParseCoverageReport(output, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Detail == CoverageUncovered {
+		for i := range coverage.FilesCoverage {
+			file := &coverage.FilesCoverage[i]
+			if file.CoveredLines == file.TotalLines {
+				continue
+			}
+			lines, err := p.runXccov(ctx, "view", "--archive", "--file", file.Path, "--json", bundlePath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read line coverage of %s: %w", file.Path, err)
+			}
+			if file.UncoveredRanges, err = ParseUncoveredRanges(lines); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return coverage, nil
+}
+
+// runXccov executes xccov with the given arguments
+func (p *CoverageParser) runXccov(ctx context.Context, args ...string) ([]byte, error) {
+	output, err := runOutput(ctx, p.runner, append([]string{p.xcrunPath, "xccov"}, args...))
+	if err != nil {
+		if exitErr, ok := err.(*ExitError); ok {
+			return nil, fmt.Errorf("xccov failed: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, err
+	}
+	return output, nil
+}
+
+// ParseCoverageReport converts xccov view --report --json output. Coverage
+// is reported in percent.
+func ParseCoverageReport(data []byte, opts CoverageOptions) (*types.Coverage, error) {
+	var report xccovReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse coverage report: %w", err)
+	}
+
+	detail := detailLevel(opts.Detail)
+	coverage := &types.Coverage{
+		LineCoverage:    percent(report.LineCoverage),
+		CoveredLines:    report.CoveredLines,
+		ExecutableLines: report.ExecutableLines,
+		FilesCoverage:   []types.FileCoverage{},
+	}
+
+	for _, target := range report.Targets {
+		matched := opts.Files == nil
+		for _, file := range target.Files {
+			if opts.Files != nil && !matchesFile(file.Path, opts.Files) {
+				continue
+			}
+			matched = true
+			if detail < detailLevel(CoverageFiles) {
+				continue
+			}
+
+			fileCoverage := types.FileCoverage{
+				Path:         file.Path,
+				Target:       target.Name,
+				LineCoverage: percent(file.LineCoverage),
+				CoveredLines: file.CoveredLines,
+				TotalLines:   file.ExecutableLines,
+			}
+			if detail >= detailLevel(CoverageFunctions) {
+				for _, function := range file.Functions {
+					fileCoverage.Functions = append(fileCoverage.Functions, types.FunctionCoverage{
+						Name:            function.Name,
+						Line:            function.LineNumber,
+						LineCoverage:    percent(function.LineCoverage),
+						CoveredLines:    function.CoveredLines,
+						ExecutableLines: function.ExecutableLines,
+						ExecutionCount:  function.ExecutionCount,
+					})
+				}
+			}
+			coverage.FilesCoverage = append(coverage.FilesCoverage, fileCoverage)
+		}
+
+		if matched {
+			coverage.Targets = append(coverage.Targets, types.TargetCoverage{
+				Name:            target.Name,
+				LineCoverage:    percent(target.LineCoverage),
+				CoveredLines:    target.CoveredLines,
+				ExecutableLines: target.ExecutableLines,
+			})
+		}
+	}
+
+	return coverage, nil
+}
+
+// ParseUncoveredRanges converts xccov view --archive --file --json output
+// to the ranges of executable lines that never ran. Ranges span the lines
+// that are not executable, such as comments, between uncovered lines.
+func ParseUncoveredRanges(data []byte) ([]types.LineRange, error) {
+	var files map[string][]xccovLine
+	if err := json.Unmarshal(data, &files); err != nil {
+		return nil, fmt.Errorf("failed to parse line coverage: %w", err)
+	}
+
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var ranges []types.LineRange
+	for _, path := range paths {
+		lines := files[path]
+		sort.Slice(lines, func(i, j int) bool { return lines[i].Line < lines[j].Line })
+
+		// A range never continues from one file into the next
+		open := false
+		for _, line := range lines {
+			switch {
+			case !line.IsExecutable:
+				continue
+			case line.ExecutionCount > 0:
+				open = false
+			case open:
+				ranges[len(ranges)-1].End = line.Line
+			default:
+				ranges = append(ranges, types.LineRange{Start: line.Line, End: line.Line})
+				open = true
+			}
+		}
+	}
+	return ranges, nil
+}
+
+// matchesFile reports whether path is one of files, or ends with one of
+// them at a path separator
+func matchesFile(path string, files []string) bool {
+	path = filepath.ToSlash(path)
+	for _, file := range files {
+		file = strings.TrimPrefix(filepath.ToSlash(file), "./")
+		if path == file || strings.HasSuffix(path, "/"+file) {
+			return true
+		}
+	}
+	return false
+}
+
+// detailLevel orders the detail levels, treating unknown ones as files
+func detailLevel(detail string) int {
+	for i, level := range CoverageDetails {
+		if level == detail {
+			return i
+		}
+	}
+	return 1
+}
+
+// percent converts an xccov fraction to a percentage with two decimals
+func percent(fraction float64) float64 {
+	return math.Round(fraction*10000) / 100
+}
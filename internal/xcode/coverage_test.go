@@ -0,0 +1,240 @@
+package xcode
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/jontolof/xcode-build-mcp/pkg/types"
+)
+
+const coverageReportJSON = `{
+  "coveredLines": 30,
+  "executableLines": 50,
+  "lineCoverage": 0.6,
+  "targets": [
+    {
+      "name": "App.app",
+      "coveredLines": 27,
+      "executableLines": 40,
+      "lineCoverage": 0.675,
+      "files": [
+        {
+          "name": "Login.swift",
+          "path": "/src/App/Sources/App/Login.swift",
+          "coveredLines": 17,
+          "executableLines": 20,
+          "lineCoverage": 0.85,
+          "functions": [
+            {
+              "name": "Login.validate(password:)",
+              "lineNumber": 12,
+              "coveredLines": 5,
+              "executableLines": 8,
+              "executionCount": 4,
+              "lineCoverage": 0.625
+            }
+          ]
+        },
+        {
+          "name": "Profile.swift",
+          "path": "/src/App/Sources/App/Profile.swift",
+          "coveredLines": 10,
+          "executableLines": 20,
+          "lineCoverage": 0.5,
+          "functions": []
+        }
+      ]
+    },
+    {
+      "name": "Networking.framework",
+      "coveredLines": 3,
+      "executableLines": 10,
+      "lineCoverage": 0.3,
+      "files": [
+        {
+          "name": "Client.swift",
+          "path": "/src/App/Sources/Networking/Client.swift",
+          "coveredLines": 3,
+          "executableLines": 10,
+          "lineCoverage": 0.3,
+          "functions": []
+        }
+      ]
+    }
+  ]
+}`
+
+func TestParseCoverageReport(t *testing.T) {
+	coverage, err := ParseCoverageReport([]byte(coverageReportJSON), CoverageOptions{Detail: CoverageFunctions})
+	if err != nil {
+		t.Fatalf("ParseCoverageReport failed: %v", err)
+	}
+
+	if coverage.LineCoverage != 60 || coverage.CoveredLines != 30 || coverage.ExecutableLines != 50 {
+		t.Errorf("Unexpected totals: %+v", coverage)
+	}
+
+	wantTargets := []types.TargetCoverage{
+		{Name: "App.app", LineCoverage: 67.5, CoveredLines: 27, ExecutableLines: 40},
+		{Name: "Networking.framework", LineCoverage: 30, CoveredLines: 3, ExecutableLines: 10},
+	}
+	if !reflect.DeepEqual(coverage.Targets, wantTargets) {
+		t.Errorf("Targets = %+v, want %+v", coverage.Targets, wantTargets)
+	}
+
+	if len(coverage.FilesCoverage) != 3 {
+		t.Fatalf("Expected 3 files, got %d", len(coverage.FilesCoverage))
+	}
+	login := coverage.FilesCoverage[0]
+	if login.Target != "App.app" || login.LineCoverage != 85 || login.CoveredLines != 17 || login.TotalLines != 20 {
+		t.Errorf("Unexpected file coverage: %+v", login)
+	}
+	wantFunctions := []types.FunctionCoverage{
+		{Name: "Login.validate(password:)", Line: 12, LineCoverage: 62.5, CoveredLines: 5, ExecutableLines: 8, ExecutionCount: 4},
+	}
+	if !reflect.DeepEqual(login.Functions, wantFunctions) {
+		t.Errorf("Functions = %+v, want %+v", login.Functions, wantFunctions)
+	}
+}
+
+func TestParseCoverageReport_Detail(t *testing.T) {
+	coverage, err := ParseCoverageReport([]byte(coverageReportJSON), CoverageOptions{Detail: CoverageSummary})
+	if err != nil {
+		t.Fatalf("ParseCoverageReport failed: %v", err)
+	}
+	if len(coverage.Targets) != 2 || len(coverage.FilesCoverage) != 0 {
+		t.Errorf("Summary should report targets only, got %+v", coverage)
+	}
+
+	coverage, err = ParseCoverageReport([]byte(coverageReportJSON), CoverageOptions{Detail: CoverageFiles})
+	if err != nil {
+		t.Fatalf("ParseCoverageReport failed: %v", err)
+	}
+	if len(coverage.FilesCoverage) != 3 || coverage.FilesCoverage[0].Functions != nil {
+		t.Errorf("Files should report files without functions, got %+v", coverage.FilesCoverage)
+	}
+}
+
+func TestParseCoverageReport_Files(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   []string
+		targets []string
+		paths   []string
+	}{
+		{
+			name:    "suffix",
+			files:   []string{"App/Login.swift"},
+			targets: []string{"App.app"},
+			paths:   []string{"/src/App/Sources/App/Login.swift"},
+		},
+		{
+			name:    "absolute path across targets",
+			files:   []string{"/src/App/Sources/App/Profile.swift", "./Networking/Client.swift"},
+			targets: []string{"App.app", "Networking.framework"},
+			paths:   []string{"/src/App/Sources/App/Profile.swift", "/src/App/Sources/Networking/Client.swift"},
+		},
+		{
+			name:  "partial file name",
+			files: []string{"ogin.swift"},
+		},
+		{
+			name:  "no files",
+			files: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			coverage, err := ParseCoverageReport([]byte(coverageReportJSON), CoverageOptions{Files: tt.files})
+			if err != nil {
+				t.Fatalf("ParseCoverageReport failed: %v", err)
+			}
+
+			var targets, paths []string
+			for _, target := range coverage.Targets {
+				targets = append(targets, target.Name)
+			}
+			for _, file := range coverage.FilesCoverage {
+				paths = append(paths, file.Path)
+			}
+			if !reflect.DeepEqual(targets, tt.targets) {
+				t.Errorf("targets = %v, want %v", targets, tt.targets)
+			}
+			if !reflect.DeepEqual(paths, tt.paths) {
+				t.Errorf("paths = %v, want %v", paths, tt.paths)
+			}
+			if coverage.LineCoverage != 60 {
+				t.Errorf("Totals should not be filtered, got %v", coverage.LineCoverage)
+			}
+		})
+	}
+}
+
+func TestParseCoverageReport_Invalid(t *testing.T) {
+	if _, err := ParseCoverageReport([]byte("error: no coverage data"), CoverageOptions{}); err == nil {
+		t.Error("Expected an error for output that is not JSON")
+	}
+}
+
+func TestParseUncoveredRanges(t *testing.T) {
+	data := []byte(`{
+  "/src/App/Sources/App/Login.swift": [
+    {"line": 1, "isExecutable": false, "executionCount": 0},
+    {"line": 2, "isExecutable": true, "executionCount": 3},
+    {"line": 3, "isExecutable": true, "executionCount": 0},
+    {"line": 4, "isExecutable": false, "executionCount": 0},
+    {"line": 5, "isExecutable": true, "executionCount": 0},
+    {"line": 6, "isExecutable": true, "executionCount": 1},
+    {"line": 9, "isExecutable": true, "executionCount": 0},
+    {"line": 8, "isExecutable": true, "executionCount": 2},
+    {"line": 10, "isExecutable": false, "executionCount": 0}
+  ]
+}`)
+
+	ranges, err := ParseUncoveredRanges(data)
+	if err != nil {
+		t.Fatalf("ParseUncoveredRanges failed: %v", err)
+	}
+
+	want := []types.LineRange{{Start: 3, End: 5}, {Start: 9, End: 9}}
+	if !reflect.DeepEqual(ranges, want) {
+		t.Errorf("ParseUncoveredRanges() = %+v, want %+v", ranges, want)
+	}
+
+	// A file ending uncovered does not extend into the next one
+	data = []byte(`{
+  "/src/B.swift": [
+    {"line": 1, "isExecutable": true, "executionCount": 0},
+    {"line": 2, "isExecutable": true, "executionCount": 1}
+  ],
+  "/src/A.swift": [
+    {"line": 7, "isExecutable": true, "executionCount": 1},
+    {"line": 8, "isExecutable": true, "executionCount": 0}
+  ]
+}`)
+	ranges, err = ParseUncoveredRanges(data)
+	if err != nil {
+		t.Fatalf("ParseUncoveredRanges failed: %v", err)
+	}
+	want = []types.LineRange{{Start: 8, End: 8}, {Start: 1, End: 1}}
+	if !reflect.DeepEqual(ranges, want) {
+		t.Errorf("ParseUncoveredRanges() of two files = %+v, want %+v", ranges, want)
+	}
+}
+
+func TestParseCoverage_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	runner := &contextRunner{}
+	_, err := NewCoverageParser(runner).ParseCoverage(ctx, t.TempDir(), CoverageOptions{Detail: CoverageUncovered})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if runner.commands != 1 {
+		t.Errorf("Expected one xccov command before giving up, got %d", runner.commands)
+	}
+}
@@ -0,0 +1,144 @@
+package xcode
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// TestRunnerPrefix marks variables xcodebuild passes on to the test runner
+// process, with the prefix removed
+const TestRunnerPrefix = "TEST_RUNNER_"
+
+// DefaultDeniedEnv lists the variables a tool call may not set. They
+// decide which binaries and libraries run, or where the server's own
+// files live; DEVELOPER_DIR has its own developer_dir parameter.
+var DefaultDeniedEnv = []string{
+	"PATH",
+	"HOME",
+	"USER",
+	"LOGNAME",
+	"SHELL",
+	"TMPDIR",
+	"DEVELOPER_DIR",
+	"DYLD_*",
+	"LD_*",
+}
+
+// EnvPolicy decides which environment variables a tool call may set for
+// the commands it runs. Patterns are names, or prefixes ending in "*".
+// A variable must match Allow, when it is not empty, and must not match
+// Deny.
+type EnvPolicy struct {
+	Allow []string
+	Deny  []string
+}
+
+// DefaultEnvPolicy allows every variable but those in DefaultDeniedEnv
+func DefaultEnvPolicy() EnvPolicy {
+	return EnvPolicy{Deny: append([]string(nil), DefaultDeniedEnv...)}
+}
+
+// EnvPolicyFromEnv extends the default policy with the comma separated
+// patterns of MCP_ENV_ALLOW and MCP_ENV_DENY. The defaults can only be
+// narrowed: MCP_ENV_DENY adds to DefaultDeniedEnv.
+func EnvPolicyFromEnv() EnvPolicy {
+	policy := DefaultEnvPolicy()
+	policy.Allow = append(policy.Allow, splitPatterns(os.Getenv("MCP_ENV_ALLOW"))...)
+	policy.Deny = append(policy.Deny, splitPatterns(os.Getenv("MCP_ENV_DENY"))...)
+	return policy
+}
+
+func splitPatterns(value string) []string {
+	var patterns []string
+	for _, pattern := range strings.Split(value, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
+
+// Check reports why name may not be set, or nil if it may
+func (p EnvPolicy) Check(name string) error {
+	if name == "" || strings.ContainsAny(name, "=\x00") {
+		return fmt.Errorf("invalid environment variable name %q", name)
+	}
+	if pattern, ok := matchPattern(p.Deny, name); ok {
+		return fmt.Errorf("environment variable %s is not allowed (denied by %s)", name, pattern)
+	}
+	if len(p.Allow) > 0 {
+		if _, ok := matchPattern(p.Allow, name); !ok {
+			return fmt.Errorf("environment variable %s is not in the allowed list", name)
+		}
+	}
+	return nil
+}
+
+func matchPattern(patterns []string, name string) (string, bool) {
+	for _, pattern := range patterns {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return pattern, true
+			}
+		} else if pattern == name {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// CommandEnv checks vars against the policy and returns them as KEY=VALUE
+// entries sorted by name, to be added to the server's environment. With a
+// prefix, such as TestRunnerPrefix, every name gets it unless it already
+// has it; the policy applies to the name without it, which is the one the
+// process finally sees.
+func (p EnvPolicy) CommandEnv(vars map[string]string, prefix string) ([]string, error) {
+	env := make([]string, 0, len(vars))
+	for name := range vars {
+		if strings.ContainsRune(vars[name], 0) {
+			return nil, fmt.Errorf("environment variable %s contains a NUL byte", name)
+		}
+		bare := name
+		if prefix != "" {
+			bare = strings.TrimPrefix(name, prefix)
+		}
+		if err := p.Check(bare); err != nil {
+			return nil, err
+		}
+		env = append(env, prefix+bare+"="+vars[name])
+	}
+	sort.Strings(env)
+	return env, nil
+}
+
+// ResolveDeveloperDir turns an Xcode bundle or its Contents/Developer
+// directory into a DEVELOPER_DIR, checking it holds xcodebuild
+func ResolveDeveloperDir(path string) (string, error) {
+	dir, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if info, err := os.Stat(filepath.Join(dir, "Contents", "Developer")); err == nil && info.IsDir() {
+		dir = filepath.Join(dir, "Contents", "Developer")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "usr", "bin", "xcodebuild")); err != nil {
+		return "", fmt.Errorf("%s is not an Xcode developer directory: usr/bin/xcodebuild not found", path)
+	}
+	return dir, nil
+}
+
+// EnvRunner adds Env to every command it runs with Runner, so follow-up
+// commands such as xcresulttool see the same DEVELOPER_DIR as the build
+type EnvRunner struct {
+	Runner CommandRunner
+	Env    []string
+}
+
+func (r EnvRunner) Run(ctx context.Context, cmd Command) (ExitStatus, error) {
+	cmd.Env = append(append([]string(nil), r.Env...), cmd.Env...)
+	return r.Runner.Run(ctx, cmd)
+}
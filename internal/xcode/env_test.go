@@ -0,0 +1,126 @@
+package xcode
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEnvPolicy_Check(t *testing.T) {
+	policy := DefaultEnvPolicy()
+	for _, name := range []string{"FEATURE_FLAG", "SWIFT_DETERMINISTIC_HASHING", "PATHS"} {
+		if err := policy.Check(name); err != nil {
+			t.Errorf("Check(%s) = %v, want allowed", name, err)
+		}
+	}
+	for _, name := range []string{"PATH", "HOME", "DEVELOPER_DIR", "DYLD_INSERT_LIBRARIES", "LD_PRELOAD", "", "A=B"} {
+		if err := policy.Check(name); err == nil {
+			t.Errorf("Check(%q) allowed a denied or invalid name", name)
+		}
+	}
+
+	policy.Allow = []string{"APP_*"}
+	if err := policy.Check("APP_MODE"); err != nil {
+		t.Errorf("Check(APP_MODE) = %v, want allowed", err)
+	}
+	if err := policy.Check("FEATURE_FLAG"); err == nil {
+		t.Error("Check(FEATURE_FLAG) allowed a name outside the allowed list")
+	}
+}
+
+func TestEnvPolicyFromEnv(t *testing.T) {
+	t.Setenv("MCP_ENV_ALLOW", "APP_*, CI")
+	t.Setenv("MCP_ENV_DENY", "APP_SECRET")
+
+	policy := EnvPolicyFromEnv()
+	if err := policy.Check("APP_MODE"); err != nil {
+		t.Errorf("Check(APP_MODE) = %v, want allowed", err)
+	}
+	for _, name := range []string{"APP_SECRET", "PATH", "OTHER"} {
+		if err := policy.Check(name); err == nil {
+			t.Errorf("Check(%s) was allowed", name)
+		}
+	}
+}
+
+func TestEnvPolicy_CommandEnv(t *testing.T) {
+	policy := DefaultEnvPolicy()
+
+	env, err := policy.CommandEnv(map[string]string{"B": "2", "A": "1=one"}, "")
+	if err != nil || !reflect.DeepEqual(env, []string{"A=1=one", "B=2"}) {
+		t.Errorf("CommandEnv() = %v, %v", env, err)
+	}
+
+	// The policy applies to the name the test process sees
+	env, err = policy.CommandEnv(map[string]string{"MODE": "ci", "TEST_RUNNER_LEVEL": "3"}, TestRunnerPrefix)
+	if err != nil || !reflect.DeepEqual(env, []string{"TEST_RUNNER_LEVEL=3", "TEST_RUNNER_MODE=ci"}) {
+		t.Errorf("CommandEnv() with prefix = %v, %v", env, err)
+	}
+	if _, err := policy.CommandEnv(map[string]string{"TEST_RUNNER_DYLD_INSERT_LIBRARIES": "x"}, TestRunnerPrefix); err == nil {
+		t.Error("CommandEnv() allowed a denied variable behind the prefix")
+	}
+	if _, err := policy.CommandEnv(map[string]string{"A": "x\x00y"}, ""); err == nil {
+		t.Error("CommandEnv() allowed a NUL byte in a value")
+	}
+}
+
+func TestResolveDeveloperDir(t *testing.T) {
+	xcodeApp := filepath.Join(t.TempDir(), "Xcode-beta.app")
+	developer := filepath.Join(xcodeApp, "Contents", "Developer")
+	if err := os.MkdirAll(filepath.Join(developer, "usr", "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(developer, "usr", "bin", "xcodebuild"), nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{xcodeApp, developer} {
+		if got, err := ResolveDeveloperDir(path); err != nil || got != developer {
+			t.Errorf("ResolveDeveloperDir(%s) = %s, %v, want %s", path, got, err, developer)
+		}
+	}
+	if _, err := ResolveDeveloperDir(filepath.Dir(xcodeApp)); err == nil {
+		t.Error("ResolveDeveloperDir() accepted a directory without xcodebuild")
+	}
+}
+
+// captureRunner remembers the last command it was asked to run
+type captureRunner struct {
+	cmd Command
+}
+
+func (r *captureRunner) Run(ctx context.Context, cmd Command) (ExitStatus, error) {
+	r.cmd = cmd
+	return ExitStatus{}, nil
+}
+
+func TestEnvRunner(t *testing.T) {
+	inner := &captureRunner{}
+	env := EnvRunner{Runner: inner, Env: []string{"DEVELOPER_DIR=/Applications/Xcode.app/Contents/Developer"}}
+	if _, err := env.Run(context.Background(), Command{Args: []string{"xcrun", "xccov"}, Env: []string{"A=1"}}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"DEVELOPER_DIR=/Applications/Xcode.app/Contents/Developer", "A=1"}; !reflect.DeepEqual(inner.cmd.Env, want) {
+		t.Errorf("Env = %v, want %v", inner.cmd.Env, want)
+	}
+}
+
+func TestExecutor_ExecuteCommand_WithEnv(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping command execution test in short mode")
+	}
+
+	executor := NewExecutor(&testLogger{})
+	result, err := executor.ExecuteCommand(context.Background(),
+		[]string{"sh", "-c", `echo "$FEATURE_FLAG:$HOME"`}, WithEnv("FEATURE_FLAG=on"))
+	if err != nil {
+		t.Fatalf("ExecuteCommand failed: %v", err)
+	}
+	// The command inherits the server's environment
+	if want := "on:" + os.Getenv("HOME"); strings.TrimSpace(result.Output) != want {
+		t.Errorf("Output = %q, want %q", result.Output, want)
+	}
+}
@@ -1,12 +1,15 @@
 package xcode
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -15,75 +18,176 @@ import (
 )
 
 type Executor struct {
-	logger common.Logger
+	logger    common.Logger
+	runner    CommandRunner
+	envPolicy EnvPolicy
 }
 
 func NewExecutor(logger common.Logger) *Executor {
+	return NewExecutorWithRunner(logger, ExecRunner{})
+}
+
+// NewExecutorWithRunner creates an executor that runs every command through
+// runner, such as a ReplayRunner serving recorded fixtures
+func NewExecutorWithRunner(logger common.Logger, runner CommandRunner) *Executor {
 	return &Executor{
-		logger: logger,
+		logger:    logger,
+		runner:    runner,
+		envPolicy: DefaultEnvPolicy(),
 	}
 }
 
-func (e *Executor) ExecuteCommand(ctx context.Context, args []string) (*CommandResult, error) {
-	if len(args) == 0 {
-		return nil, fmt.Errorf("no command arguments provided")
-	}
+// EnvPolicy returns the policy tools check per-call environment variables
+// against
+func (e *Executor) EnvPolicy() EnvPolicy {
+	return e.envPolicy
+}
 
-	e.logger.Printf("Executing command: %s %s", args[0], strings.Join(args[1:], " "))
+// SetEnvPolicy replaces the default environment policy
+func (e *Executor) SetEnvPolicy(policy EnvPolicy) {
+	e.envPolicy = policy
+}
 
-	start := time.Now()
-	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+// Runner returns the CommandRunner the executor runs commands with
+func (e *Executor) Runner() CommandRunner {
+	return e.runner
+}
 
-	// Set up pipes for capturing output
-	stdout, err := cmd.StdoutPipe()
+// Run runs cmd with the executor's runner
+func (e *Executor) Run(ctx context.Context, cmd Command) (ExitStatus, error) {
+	return e.runner.Run(ctx, cmd)
+}
+
+// Output runs a command and returns its standard output. Like
+// exec.Cmd.Output, a command that fails returns an *ExitError carrying its
+// standard error, along with the output it produced.
+func (e *Executor) Output(ctx context.Context, args ...string) ([]byte, error) {
+	return runOutput(ctx, e.runner, args)
+}
+
+// CombinedOutput runs a command and returns its standard output and
+// standard error interleaved, with an *ExitError if the command fails
+func (e *Executor) CombinedOutput(ctx context.Context, args ...string) ([]byte, error) {
+	var output syncBuffer
+	status, err := e.runner.Run(ctx, Command{Args: args, Stdout: &output, Stderr: &output})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+		return nil, err
+	}
+	if !status.Success() {
+		return output.Bytes(), &ExitError{ExitStatus: status}
 	}
+	return output.Bytes(), nil
+}
 
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create stderr pipe: %w", err)
+// syncBuffer is a bytes.Buffer that both output streams may write to
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Bytes()
+}
+
+// ExecOption customizes a single ExecuteCommand call
+type ExecOption func(*execConfig)
+
+type execConfig struct {
+	observers []func(OutputLine)
+	env       []string
+}
+
+// WithEnv adds KEY=VALUE entries to the environment the command inherits
+// from the server, overriding variables of the same name
+func WithEnv(env ...string) ExecOption {
+	return func(c *execConfig) {
+		c.env = append(c.env, env...)
 	}
+}
 
-	// Start the command
-	if err := cmd.Start(); err != nil {
-		return nil, fmt.Errorf("failed to start command: %w", err)
+// Stream identifies the output stream a line was written to
+type Stream int
+
+const (
+	StreamStdout Stream = iota
+	StreamStderr
+)
+
+func (s Stream) String() string {
+	if s == StreamStderr {
+		return "stderr"
 	}
+	return "stdout"
+}
 
-	// Capture output
-	outputChan := make(chan string, 2)
+// OutputLine is one line of command output as it was observed
+type OutputLine struct {
+	Stream Stream
+	Text   string
+	// Time is when the line arrived; it never goes backwards between the
+	// lines of one command
+	Time time.Time
+	// Index counts the lines of both streams from 0 in arrival order
+	Index int
+}
 
-	// Read stdout - use safe scanner to handle lines >64KB
-	go func() {
-		scanner := newSafeScanner(stdout)
-		var output strings.Builder
-		for scanner.Scan() {
-			line := scanner.Text()
-			output.WriteString(line)
-			output.WriteString("\n")
-		}
-		outputChan <- output.String()
-	}()
-
-	// Read stderr - use safe scanner to handle lines >64KB
-	go func() {
-		scanner := newSafeScanner(stderr)
-		var output strings.Builder
-		for scanner.Scan() {
-			line := scanner.Text()
-			output.WriteString(line)
-			output.WriteString("\n")
-		}
-		outputChan <- output.String()
-	}()
+// WithLineObserver calls fn for every line of stdout and stderr while the
+// command runs, in the order the lines arrive across both streams. Calls
+// are serialized, and all of them happen before ExecuteCommand returns.
+// The option may be given more than once; observers see each line in the
+// order they were given.
+func WithLineObserver(fn func(OutputLine)) ExecOption {
+	return func(c *execConfig) {
+		c.observers = append(c.observers, fn)
+	}
+}
 
-	// Wait for the command to finish
-	err = cmd.Wait()
+// WithLineHandler is WithLineObserver for callers that only need the text
+func WithLineHandler(fn func(line string)) ExecOption {
+	return WithLineObserver(func(line OutputLine) {
+		fn(line.Text)
+	})
+}
+
+func (e *Executor) ExecuteCommand(ctx context.Context, args []string, opts ...ExecOption) (*CommandResult, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("no command arguments provided")
+	}
+
+	var config execConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	e.logger.Printf("Executing command: %s %s", args[0], strings.Join(args[1:], " "))
+	if len(config.env) > 0 {
+		e.logger.Printf("With %d environment variables", len(config.env))
+	}
+
+	start := time.Now()
+
+	// Capture output. Runners return only after every byte has been
+	// written, so both streams are complete afterwards.
+	lines := &lineObservers{observers: config.observers}
+	stdout := &lineWriter{observe: lines.stream(StreamStdout)}
+	stderr := &lineWriter{observe: lines.stream(StreamStderr)}
+	status, err := e.runner.Run(ctx, Command{Args: args, Env: config.env, Stdout: stdout, Stderr: stderr})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start command: %w", err)
+	}
 	duration := time.Since(start)
 
 	// Get outputs
-	stdoutOutput := <-outputChan
-	stderrOutput := <-outputChan
+	stdoutOutput := stdout.String()
+	stderrOutput := stderr.String()
 
 	var combinedOutput strings.Builder
 	if stdoutOutput != "" {
@@ -104,46 +208,10 @@ func (e *Executor) ExecuteCommand(ctx context.Context, args []string) (*CommandR
 	}
 
 	// Enhanced crash detection
-	if err != nil {
-		result.Error = err
-
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			// Get platform-specific process state (Unix/Linux/macOS)
-			if ws, ok := exitErr.Sys().(syscall.WaitStatus); ok {
-				result.ProcessState = &types.ProcessState{
-					Exited:   ws.Exited(),
-					Signaled: ws.Signaled(),
-				}
-
-				if ws.Signaled() {
-					// Process was killed by a signal
-					signal := ws.Signal()
-					result.ProcessState.Signal = int(signal)
-					result.ProcessState.SignalName = signal.String()
-					result.CrashType = classifySignal(signal)
-					result.ExitCode = 128 + int(signal)
-
-					e.logger.Printf("Command terminated by signal: %s (%d)",
-						signal.String(), signal)
-				} else if ws.Exited() {
-					// Normal exit with exit code
-					result.ExitCode = ws.ExitStatus()
-					result.CrashType = classifyExitCode(result.ExitCode)
-
-					e.logger.Printf("Command exited with code: %d", result.ExitCode)
-				}
-
-				if ws.CoreDump() {
-					result.ProcessState.CoreDump = true
-					result.CrashType = types.CrashTypeSegmentationFault
-					e.logger.Printf("Command produced core dump")
-				}
-			} else {
-				// Fallback for non-Unix systems or when casting fails
-				result.ExitCode = exitErr.ExitCode()
-				result.CrashType = classifyExitCode(result.ExitCode)
-			}
-		} else if ctx.Err() == context.DeadlineExceeded {
+	if !status.Success() {
+		result.Error = &ExitError{ExitStatus: status}
+
+		if ctx.Err() == context.DeadlineExceeded {
 			// Timeout
 			result.ExitCode = -2
 			result.CrashType = types.CrashTypeTimeout
@@ -154,10 +222,34 @@ func (e *Executor) ExecuteCommand(ctx context.Context, args []string) (*CommandR
 			result.CrashType = types.CrashTypeInterrupted
 			e.logger.Printf("Command was canceled")
 		} else {
-			// Other errors (failed to start, etc.)
-			result.ExitCode = -1
-			result.CrashType = types.CrashTypeUnknown
-			e.logger.Printf("Command failed with unknown error: %v", err)
+			result.ProcessState = &types.ProcessState{
+				Exited:   status.Signal == 0,
+				Signaled: status.Signal != 0,
+			}
+
+			if status.Signal != 0 {
+				// Process was killed by a signal
+				signal := syscall.Signal(status.Signal)
+				result.ProcessState.Signal = status.Signal
+				result.ProcessState.SignalName = signal.String()
+				result.CrashType = classifySignal(signal)
+				result.ExitCode = 128 + status.Signal
+
+				e.logger.Printf("Command terminated by signal: %s (%d)",
+					signal.String(), signal)
+			} else {
+				// Normal exit with exit code
+				result.ExitCode = status.ExitCode
+				result.CrashType = classifyExitCode(result.ExitCode)
+
+				e.logger.Printf("Command exited with code: %d", result.ExitCode)
+			}
+
+			if status.CoreDump {
+				result.ProcessState.CoreDump = true
+				result.CrashType = types.CrashTypeSegmentationFault
+				e.logger.Printf("Command produced core dump")
+			}
 		}
 	}
 
@@ -167,7 +259,80 @@ func (e *Executor) ExecuteCommand(ctx context.Context, args []string) (*CommandR
 	return result, nil
 }
 
+// lineObservers delivers the lines of both output streams of a command to
+// its observers. The pipe readers write concurrently, so delivery is
+// serialized; a line is stamped under the lock, which keeps arrival order,
+// index and time consistent with each other.
+type lineObservers struct {
+	mu        sync.Mutex
+	observers []func(OutputLine)
+	count     int
+}
+
+// stream returns the observe function of a lineWriter for stream
+func (o *lineObservers) stream(stream Stream) func(text string) {
+	return func(text string) {
+		if len(o.observers) == 0 {
+			return
+		}
+		o.mu.Lock()
+		defer o.mu.Unlock()
+
+		line := OutputLine{Stream: stream, Text: text, Time: time.Now(), Index: o.count}
+		o.count++
+		for _, observe := range o.observers {
+			observe(line)
+		}
+	}
+}
+
+// lineWriter accumulates one output stream of a command and hands every
+// complete line to observe as it arrives. Lines of any length are supported.
+type lineWriter struct {
+	output  strings.Builder
+	partial []byte
+	observe func(line string)
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.output.Write(p)
+
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		w.observe(strings.TrimSuffix(string(w.partial[:i]), "\r"))
+		w.partial = w.partial[i+1:]
+	}
+
+	return len(p), nil
+}
+
+// String returns everything written so far, newline terminated, and flushes
+// an unterminated last line to the observer
+func (w *lineWriter) String() string {
+	if len(w.partial) > 0 {
+		w.observe(strings.TrimSuffix(string(w.partial), "\r"))
+		w.partial = nil
+	}
+
+	output := w.output.String()
+	if output != "" && !strings.HasSuffix(output, "\n") {
+		output += "\n"
+	}
+	return output
+}
+
+// FindXcodeCommand locates xcodebuild, leaving it to runners that resolve
+// commands themselves, such as a ReplayRunner, so replaying works where
+// Xcode is not installed
 func (e *Executor) FindXcodeCommand() (string, error) {
+	if resolver, ok := e.runner.(CommandResolver); ok {
+		return resolver.LookPath("xcodebuild")
+	}
+
 	// Try to find xcodebuild in common locations
 	paths := []string{
 		"/usr/bin/xcodebuild",
@@ -189,10 +354,19 @@ func (e *Executor) FindXcodeCommand() (string, error) {
 	return "", fmt.Errorf("xcodebuild not found in any expected location")
 }
 
+// BuildXcodeArgs returns the xcodebuild command line for params. When
+// params name a DEVELOPER_DIR, which tools resolve before calling this,
+// that Xcode's own xcodebuild runs, so the selection holds even when PATH
+// leads straight to the usr/bin of another Xcode.
 func (e *Executor) BuildXcodeArgs(params interface{}) ([]string, error) {
-	xcodeCmd, err := e.FindXcodeCommand()
-	if err != nil {
-		return nil, err
+	var xcodeCmd string
+	if developerDir := paramsDeveloperDir(params); developerDir != "" {
+		xcodeCmd = filepath.Join(developerDir, "usr", "bin", "xcodebuild")
+	} else {
+		var err error
+		if xcodeCmd, err = e.FindXcodeCommand(); err != nil {
+			return nil, err
+		}
 	}
 
 	args := []string{xcodeCmd}
@@ -204,11 +378,32 @@ func (e *Executor) BuildXcodeArgs(params interface{}) ([]string, error) {
 		return e.buildTestArgs(args, p)
 	case *types.CleanParams:
 		return e.buildCleanArgs(args, p)
+	case *types.ArchiveParams:
+		return e.buildArchiveArgs(args, p)
+	case *types.ExportParams:
+		return e.buildExportArgs(args, p)
 	default:
 		return nil, fmt.Errorf("unsupported parameter type: %T", params)
 	}
 }
 
+// paramsDeveloperDir returns the DEVELOPER_DIR an xcodebuild call selects
+func paramsDeveloperDir(params interface{}) string {
+	switch p := params.(type) {
+	case *types.BuildParams:
+		return p.DeveloperDir
+	case *types.TestParams:
+		return p.DeveloperDir
+	case *types.CleanParams:
+		return p.DeveloperDir
+	case *types.ArchiveParams:
+		return p.DeveloperDir
+	case *types.ExportParams:
+		return p.DeveloperDir
+	}
+	return ""
+}
+
 func (e *Executor) buildBuildArgs(baseArgs []string, params *types.BuildParams) ([]string, error) {
 	args := append(baseArgs, "build")
 
@@ -289,11 +484,9 @@ func (e *Executor) buildTestArgs(baseArgs []string, params *types.TestParams) ([
 		args = append(args, "-project", params.Project)
 	}
 
-	// Scheme or target
+	// Scheme; xcodebuild test has no -target
 	if params.Scheme != "" {
 		args = append(args, "-scheme", params.Scheme)
-	} else if params.Target != "" {
-		args = append(args, "-target", params.Target)
 	}
 
 	// Test plan
@@ -321,6 +514,29 @@ func (e *Executor) buildTestArgs(baseArgs []string, params *types.TestParams) ([
 		args = append(args, "-skip-testing", test)
 	}
 
+	// Repetitions
+	if params.TestIterations > 0 {
+		args = append(args, "-test-iterations", strconv.Itoa(params.TestIterations))
+	}
+	if params.RetryTestsOnFailure {
+		args = append(args, "-retry-tests-on-failure")
+	}
+
+	// Test timeouts
+	if params.TestTimeouts != nil {
+		if *params.TestTimeouts {
+			args = append(args, "-test-timeouts-enabled", "YES")
+		} else {
+			args = append(args, "-test-timeouts-enabled", "NO")
+		}
+	}
+	if params.DefaultTestTimeout > 0 {
+		args = append(args, "-default-test-execution-time-allowance", strconv.Itoa(params.DefaultTestTimeout))
+	}
+	if params.MaximumTestTimeout > 0 {
+		args = append(args, "-maximum-test-execution-time-allowance", strconv.Itoa(params.MaximumTestTimeout))
+	}
+
 	// Parallel testing
 	if params.Parallel {
 		args = append(args, "-parallel-testing-enabled", "YES")
@@ -378,6 +594,68 @@ func (e *Executor) buildCleanArgs(baseArgs []string, params *types.CleanParams)
 	return args, nil
 }
 
+func (e *Executor) buildArchiveArgs(baseArgs []string, params *types.ArchiveParams) ([]string, error) {
+	args := append(baseArgs, "archive")
+
+	// Workspace or project
+	if params.Workspace != "" {
+		if !filepath.IsAbs(params.Workspace) && params.ProjectPath != "" {
+			params.Workspace = filepath.Join(params.ProjectPath, params.Workspace)
+		}
+		args = append(args, "-workspace", params.Workspace)
+	} else if params.Project != "" {
+		if !filepath.IsAbs(params.Project) && params.ProjectPath != "" {
+			params.Project = filepath.Join(params.ProjectPath, params.Project)
+		}
+		args = append(args, "-project", params.Project)
+	}
+
+	args = append(args, "-scheme", params.Scheme)
+
+	// Configuration
+	if params.Configuration != "" {
+		args = append(args, "-configuration", params.Configuration)
+	}
+
+	// Destination
+	if params.Destination != "" {
+		args = append(args, "-destination", params.Destination)
+	}
+
+	args = append(args, "-archivePath", params.ArchivePath)
+
+	// Derived data
+	if params.DerivedData != "" {
+		args = append(args, "-derivedDataPath", params.DerivedData)
+	}
+
+	if params.AllowProvisioningUpdates {
+		args = append(args, "-allowProvisioningUpdates")
+	}
+
+	// Extra arguments
+	args = append(args, params.ExtraArgs...)
+
+	return args, nil
+}
+
+func (e *Executor) buildExportArgs(baseArgs []string, params *types.ExportParams) ([]string, error) {
+	args := append(baseArgs, "-exportArchive",
+		"-archivePath", params.ArchivePath,
+		"-exportPath", params.ExportPath,
+		"-exportOptionsPlist", params.ExportOptionsPlist,
+	)
+
+	if params.AllowProvisioningUpdates {
+		args = append(args, "-allowProvisioningUpdates")
+	}
+
+	// Extra arguments
+	args = append(args, params.ExtraArgs...)
+
+	return args, nil
+}
+
 type CommandResult struct {
 	Command      string
 	Output       string
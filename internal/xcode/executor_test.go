@@ -4,7 +4,9 @@ import (
 	"context"
 	"log"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/jontolof/xcode-build-mcp/pkg/types"
 )
@@ -116,6 +118,50 @@ func TestExecutor_BuildXcodeArgs_Test(t *testing.T) {
 	}
 }
 
+func TestExecutor_BuildXcodeArgs_TestRepetitions(t *testing.T) {
+	executor := NewExecutor(&testLogger{})
+
+	enabled := true
+	params := &types.TestParams{
+		Project:             "MyProject.xcodeproj",
+		Scheme:              "MyScheme",
+		OnlyTesting:         []string{"MyTests/LoginTests/testLogin"},
+		TestIterations:      5,
+		RetryTestsOnFailure: true,
+		TestTimeouts:        &enabled,
+		DefaultTestTimeout:  30,
+		MaximumTestTimeout:  120,
+	}
+
+	args, err := executor.buildTestArgs([]string{"xcodebuild"}, params)
+	if err != nil {
+		t.Fatalf("buildTestArgs failed: %v", err)
+	}
+
+	expected := []string{
+		"xcodebuild", "test",
+		"-project", "MyProject.xcodeproj",
+		"-scheme", "MyScheme",
+		"-only-testing", "MyTests/LoginTests/testLogin",
+		"-test-iterations", "5",
+		"-retry-tests-on-failure",
+		"-test-timeouts-enabled", "YES",
+		"-default-test-execution-time-allowance", "30",
+		"-maximum-test-execution-time-allowance", "120",
+		"-parallel-testing-enabled", "NO",
+	}
+
+	if len(args) != len(expected) {
+		t.Fatalf("Expected %d args, got %d: %v", len(expected), len(args), args)
+	}
+
+	for i, arg := range expected {
+		if args[i] != arg {
+			t.Errorf("Expected arg[%d] = %q, got %q", i, arg, args[i])
+		}
+	}
+}
+
 func TestExecutor_BuildXcodeArgs_Clean(t *testing.T) {
 	logger := &testLogger{}
 	executor := NewExecutor(logger)
@@ -147,6 +193,79 @@ func TestExecutor_BuildXcodeArgs_Clean(t *testing.T) {
 	}
 }
 
+func TestExecutor_BuildXcodeArgs_Archive(t *testing.T) {
+	executor := NewExecutor(&testLogger{})
+
+	params := &types.ArchiveParams{
+		ProjectPath:              "/src/App",
+		Workspace:                "App.xcworkspace",
+		Scheme:                   "App",
+		Configuration:            "Release",
+		Destination:              "generic/platform=iOS",
+		ArchivePath:              "/src/App/build/App.xcarchive",
+		AllowProvisioningUpdates: true,
+	}
+
+	args, err := executor.buildArchiveArgs([]string{"xcodebuild"}, params)
+	if err != nil {
+		t.Fatalf("buildArchiveArgs failed: %v", err)
+	}
+
+	expected := []string{
+		"xcodebuild", "archive",
+		"-workspace", "/src/App/App.xcworkspace",
+		"-scheme", "App",
+		"-configuration", "Release",
+		"-destination", "generic/platform=iOS",
+		"-archivePath", "/src/App/build/App.xcarchive",
+		"-allowProvisioningUpdates",
+	}
+
+	if len(args) != len(expected) {
+		t.Fatalf("Expected %d args, got %d: %v", len(expected), len(args), args)
+	}
+
+	for i, arg := range expected {
+		if args[i] != arg {
+			t.Errorf("Expected arg[%d] = %q, got %q", i, arg, args[i])
+		}
+	}
+}
+
+func TestExecutor_BuildXcodeArgs_Export(t *testing.T) {
+	executor := NewExecutor(&testLogger{})
+
+	params := &types.ExportParams{
+		ArchivePath:        "App.xcarchive",
+		ExportPath:         "export",
+		ExportOptionsPlist: "export/ExportOptions.plist",
+		ExtraArgs:          []string{"-quiet"},
+	}
+
+	args, err := executor.buildExportArgs([]string{"xcodebuild"}, params)
+	if err != nil {
+		t.Fatalf("buildExportArgs failed: %v", err)
+	}
+
+	expected := []string{
+		"xcodebuild", "-exportArchive",
+		"-archivePath", "App.xcarchive",
+		"-exportPath", "export",
+		"-exportOptionsPlist", "export/ExportOptions.plist",
+		"-quiet",
+	}
+
+	if len(args) != len(expected) {
+		t.Fatalf("Expected %d args, got %d: %v", len(expected), len(args), args)
+	}
+
+	for i, arg := range expected {
+		if args[i] != arg {
+			t.Errorf("Expected arg[%d] = %q, got %q", i, arg, args[i])
+		}
+	}
+}
+
 func TestExecutor_ExecuteCommand(t *testing.T) {
 	if testing.Short() {
 		t.Skip("Skipping command execution test in short mode")
@@ -180,6 +299,105 @@ func TestExecutor_ExecuteCommand(t *testing.T) {
 	}
 }
 
+func TestExecutor_ExecuteCommand_LineHandler(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping command execution test in short mode")
+	}
+
+	executor := NewExecutor(&testLogger{})
+
+	var lines []string
+	result, err := executor.ExecuteCommand(context.Background(),
+		[]string{"sh", "-c", "echo one; echo two >&2; echo three"},
+		WithLineHandler(func(line string) { lines = append(lines, line) }))
+	if err != nil {
+		t.Fatalf("ExecuteCommand failed: %v", err)
+	}
+	if !result.Success() {
+		t.Fatalf("Expected command to succeed, got exit code %d", result.ExitCode)
+	}
+
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 observed lines, got %d: %v", len(lines), lines)
+	}
+	seen := map[string]bool{}
+	for _, line := range lines {
+		seen[line] = true
+	}
+	for _, want := range []string{"one", "two", "three"} {
+		if !seen[want] {
+			t.Errorf("Line %q was not observed", want)
+		}
+	}
+}
+
+func TestExecutor_ExecuteCommand_LineObserver(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping command execution test in short mode")
+	}
+
+	executor := NewExecutor(&testLogger{})
+
+	var lines []OutputLine
+	var texts []string
+	_, err := executor.ExecuteCommand(context.Background(),
+		[]string{"sh", "-c", "echo one; sleep 0.1; echo two >&2; sleep 0.1; echo three; sleep 0.1; printf four >&2"},
+		WithLineObserver(func(line OutputLine) { lines = append(lines, line) }),
+		WithLineHandler(func(line string) { texts = append(texts, line) }))
+	if err != nil {
+		t.Fatalf("ExecuteCommand failed: %v", err)
+	}
+
+	want := []struct {
+		stream Stream
+		text   string
+	}{{StreamStdout, "one"}, {StreamStderr, "two"}, {StreamStdout, "three"}, {StreamStderr, "four"}}
+	if len(lines) != len(want) {
+		t.Fatalf("Expected %d observed lines, got %+v", len(want), lines)
+	}
+	for i, w := range want {
+		line := lines[i]
+		if line.Stream != w.stream || line.Text != w.text || line.Index != i {
+			t.Errorf("Line %d = %s %q (index %d), want %s %q", i, line.Stream, line.Text, line.Index, w.stream, w.text)
+		}
+		if line.Time.IsZero() || (i > 0 && line.Time.Before(lines[i-1].Time)) {
+			t.Errorf("Line %d has time %v after %v", i, line.Time, lines[max(i-1, 0)].Time)
+		}
+	}
+	if strings.Join(texts, ",") != "one,two,three,four" {
+		t.Errorf("Line handler saw %v, want the same lines in the same order", texts)
+	}
+}
+
+func TestExecutor_ExecuteCommand_CancelKillsProcessGroup(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping command execution test in short mode")
+	}
+
+	executor := NewExecutor(&testLogger{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	// The grandchild sleep keeps stdout open; only a process group kill
+	// lets ExecuteCommand return promptly
+	start := time.Now()
+	result, err := executor.ExecuteCommand(ctx, []string{"sh", "-c", "sleep 30 & wait"})
+	if err != nil {
+		t.Fatalf("ExecuteCommand failed: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("Cancellation took %v, child processes were not killed", elapsed)
+	}
+	if result.ExitCode != -3 {
+		t.Errorf("Expected exit code -3 for cancellation, got %d", result.ExitCode)
+	}
+	if result.CrashType != types.CrashTypeInterrupted {
+		t.Errorf("Expected crash type %s, got %s", types.CrashTypeInterrupted, result.CrashType)
+	}
+}
+
 func TestCommandResult_Success(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -0,0 +1,203 @@
+package xcode
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jontolof/xcode-build-mcp/internal/plist"
+	"github.com/jontolof/xcode-build-mcp/pkg/types"
+)
+
+// XcodeSearchDirs returns the directories searched for Xcode bundles: those
+// in MCP_XCODE_SEARCH_PATHS (a PATH-style list), then /Applications and
+// ~/Applications
+func XcodeSearchDirs() []string {
+	var dirs []string
+	if paths := os.Getenv("MCP_XCODE_SEARCH_PATHS"); paths != "" {
+		dirs = append(dirs, filepath.SplitList(paths)...)
+	}
+	dirs = append(dirs, "/Applications")
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, "Applications"))
+	}
+	return dirs
+}
+
+// FindXcodeInstallations lists the Xcode bundles in dirs, newest version
+// first, marking the one xcode-select points at. The selected developer
+// directory is returned too; it is also listed when it belongs to a bundle
+// outside dirs. Unreadable directories and bundles are skipped.
+func FindXcodeInstallations(ctx context.Context, runner CommandRunner, dirs []string) ([]types.XcodeInstallation, string) {
+	// xcode-select -p reports DEVELOPER_DIR when it is set
+	selected := ""
+	if output, err := runOutput(ctx, runner, []string{"xcode-select", "-p"}); err == nil {
+		selected = strings.TrimSpace(string(output))
+	}
+
+	var bundles []string
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if strings.HasSuffix(entry.Name(), ".app") {
+				bundles = append(bundles, filepath.Join(dir, entry.Name()))
+			}
+		}
+	}
+	if bundle, ok := strings.CutSuffix(selected, "/Contents/Developer"); ok {
+		bundles = append(bundles, bundle)
+	}
+
+	seen := map[string]bool{}
+	installations := []types.XcodeInstallation{}
+	for _, bundle := range bundles {
+		install, err := ReadXcodeInstallation(bundle)
+		if err != nil {
+			continue
+		}
+		key := realPath(install.DeveloperDir)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		install.Selected = selected != "" && key == realPath(selected)
+		installations = append(installations, *install)
+	}
+
+	sort.SliceStable(installations, func(i, j int) bool {
+		if c := compareVersions(installations[i].Version, installations[j].Version); c != 0 {
+			return c > 0
+		}
+		return installations[i].Build > installations[j].Build
+	})
+	return installations, selected
+}
+
+// ReadXcodeInstallation describes the Xcode bundle at path from its
+// version.plist and the SDKs of its platforms
+func ReadXcodeInstallation(path string) (*types.XcodeInstallation, error) {
+	developerDir := filepath.Join(path, "Contents", "Developer")
+	if _, err := os.Stat(filepath.Join(developerDir, "usr", "bin", "xcodebuild")); err != nil {
+		return nil, fmt.Errorf("%s is not an Xcode bundle: %w", path, err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(path, "Contents", "version.plist"))
+	if err != nil {
+		return nil, err
+	}
+	version, err := plist.UnmarshalDict(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read version.plist of %s: %w", path, err)
+	}
+
+	install := &types.XcodeInstallation{
+		Path:         path,
+		DeveloperDir: developerDir,
+		SDKs:         []string{},
+	}
+	install.Version, _ = version["CFBundleShortVersionString"].(string)
+	install.Build, _ = version["ProductBuildVersion"].(string)
+	// Betas install side by side as Xcode-beta.app; version.plist does not
+	// tell them apart
+	install.Beta = strings.Contains(strings.ToLower(filepath.Base(path)), "beta")
+
+	// Versioned SDK directories; the unversioned names are symlinks to them
+	sdks, _ := filepath.Glob(filepath.Join(developerDir, "Platforms", "*.platform", "Developer", "SDKs", "*.sdk"))
+	for _, sdk := range sdks {
+		if info, err := os.Lstat(sdk); err != nil || info.Mode()&os.ModeSymlink != 0 {
+			continue
+		}
+		install.SDKs = append(install.SDKs, strings.ToLower(strings.TrimSuffix(filepath.Base(sdk), ".sdk")))
+	}
+	sort.Strings(install.SDKs)
+
+	return install, nil
+}
+
+// MatchXcodeInstallation picks the installation a call's xcode_version
+// names: a build number such as 16A242d, or a version such as 16 or 16.1
+// that matches every release it is a prefix of. "beta" alone, or after a
+// version, only matches betas. Releases win over betas, then the newest
+// version; installations are expected newest first.
+func MatchXcodeInstallation(installations []types.XcodeInstallation, version string) (*types.XcodeInstallation, error) {
+	version = strings.TrimSpace(version)
+	for i := range installations {
+		if installations[i].Build != "" && strings.EqualFold(installations[i].Build, version) {
+			return &installations[i], nil
+		}
+	}
+
+	wanted, betaOnly := strings.CutSuffix(strings.ToLower(version), "beta")
+	wanted = strings.TrimSpace(wanted)
+
+	var match *types.XcodeInstallation
+	for i := range installations {
+		install := &installations[i]
+		if betaOnly && !install.Beta {
+			continue
+		}
+		if wanted != "" && !versionHasPrefix(install.Version, wanted) {
+			continue
+		}
+		if match == nil || (match.Beta && !install.Beta) {
+			match = install
+		}
+	}
+	if match == nil {
+		return nil, fmt.Errorf("no installed Xcode matches version %q", version)
+	}
+	return match, nil
+}
+
+// versionHasPrefix reports whether the dotted version starts with the
+// components of prefix, counting missing components as 0 so 16.0 matches 16
+func versionHasPrefix(version, prefix string) bool {
+	have := strings.Split(version, ".")
+	for i, want := range strings.Split(prefix, ".") {
+		component := "0"
+		if i < len(have) {
+			component = have[i]
+		}
+		a, errA := strconv.Atoi(component)
+		b, errB := strconv.Atoi(want)
+		if errA != nil || errB != nil || a != b {
+			return false
+		}
+	}
+	return true
+}
+
+// compareVersions compares dotted versions numerically, returning -1, 0 or 1
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func realPath(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return filepath.Clean(path)
+}
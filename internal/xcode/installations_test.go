@@ -0,0 +1,155 @@
+package xcode
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jontolof/xcode-build-mcp/pkg/types"
+)
+
+// makeXcodeBundle lays out the parts of an Xcode bundle that installation
+// discovery reads: xcodebuild, version.plist and the platform SDKs
+func makeXcodeBundle(t *testing.T, path, version, build string, sdks ...string) string {
+	t.Helper()
+	developer := filepath.Join(path, "Contents", "Developer")
+	if err := os.MkdirAll(filepath.Join(developer, "usr", "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(developer, "usr", "bin", "xcodebuild"), nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+	versionPlist := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>CFBundleShortVersionString</key>
+	<string>` + version + `</string>
+	<key>ProductBuildVersion</key>
+	<string>` + build + `</string>
+</dict>
+</plist>
+`
+	if err := os.WriteFile(filepath.Join(path, "Contents", "version.plist"), []byte(versionPlist), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, sdk := range sdks {
+		platform := strings.TrimRight(sdk, "0123456789.")
+		dir := filepath.Join(developer, "Platforms", platform+".platform", "Developer", "SDKs")
+		if err := os.MkdirAll(filepath.Join(dir, sdk+".sdk"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		// Xcode links the unversioned SDK name to the versioned one
+		os.Symlink(sdk+".sdk", filepath.Join(dir, platform+".sdk"))
+	}
+	return developer
+}
+
+func TestReadXcodeInstallation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Xcode-beta.app")
+	developer := makeXcodeBundle(t, path, "16.2", "16C5023f", "iPhoneOS18.2", "iPhoneSimulator18.2")
+
+	install, err := ReadXcodeInstallation(path)
+	if err != nil {
+		t.Fatalf("ReadXcodeInstallation failed: %v", err)
+	}
+	want := &types.XcodeInstallation{
+		Path:         path,
+		DeveloperDir: developer,
+		Version:      "16.2",
+		Build:        "16C5023f",
+		Beta:         true,
+		SDKs:         []string{"iphoneos18.2", "iphonesimulator18.2"},
+	}
+	if !reflect.DeepEqual(install, want) {
+		t.Errorf("ReadXcodeInstallation() = %+v, want %+v", install, want)
+	}
+
+	if _, err := ReadXcodeInstallation(t.TempDir()); err == nil {
+		t.Error("ReadXcodeInstallation() accepted a directory that is not an Xcode bundle")
+	}
+}
+
+func TestFindXcodeInstallations(t *testing.T) {
+	apps := t.TempDir()
+	makeXcodeBundle(t, filepath.Join(apps, "Xcode-15.4.app"), "15.4", "15F31d")
+	selected := makeXcodeBundle(t, filepath.Join(apps, "Xcode.app"), "16.1", "16B40")
+	makeXcodeBundle(t, filepath.Join(apps, "Xcode-beta.app"), "16.2", "16C5023f")
+	if err := os.MkdirAll(filepath.Join(apps, "Safari.app", "Contents"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// A bundle outside the search directories is found through xcode-select
+	elsewhere := makeXcodeBundle(t, filepath.Join(t.TempDir(), "Xcode-16.0.app"), "16.0", "16A242d")
+
+	tests := []struct {
+		name     string
+		selected string
+		want     []string
+		selectIn string
+	}{
+		{"selected in dirs", selected, []string{"16.2", "16.1", "15.4"}, "16.1"},
+		{"selected elsewhere", elsewhere, []string{"16.2", "16.1", "16.0", "15.4"}, "16.0"},
+		{"command line tools", "/Library/Developer/CommandLineTools", []string{"16.2", "16.1", "15.4"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := NewReplayRunner([]Fixture{{Args: []string{"xcode-select", "-p"}, Stdout: tt.selected + "\n"}})
+			installations, selectedDir := FindXcodeInstallations(context.Background(), runner, []string{apps, filepath.Join(apps, "missing")})
+			if selectedDir != tt.selected {
+				t.Errorf("selected developer dir = %s, want %s", selectedDir, tt.selected)
+			}
+
+			var versions []string
+			selectedVersion := ""
+			for _, install := range installations {
+				versions = append(versions, install.Version)
+				if install.Selected {
+					selectedVersion = install.Version
+				}
+			}
+			if !reflect.DeepEqual(versions, tt.want) {
+				t.Errorf("versions = %v, want %v", versions, tt.want)
+			}
+			if selectedVersion != tt.selectIn {
+				t.Errorf("selected version = %q, want %q", selectedVersion, tt.selectIn)
+			}
+		})
+	}
+}
+
+func TestMatchXcodeInstallation(t *testing.T) {
+	installations := []types.XcodeInstallation{
+		{Path: "/Applications/Xcode-beta.app", Version: "16.2", Build: "16C5023f", Beta: true},
+		{Path: "/Applications/Xcode.app", Version: "16.1", Build: "16B40"},
+		{Path: "/Applications/Xcode-16.0.app", Version: "16.0", Build: "16A242d"},
+		{Path: "/Applications/Xcode-15.4.app", Version: "15.4", Build: "15F31d"},
+	}
+
+	tests := map[string]string{
+		"16":       "/Applications/Xcode.app",
+		"16.0":     "/Applications/Xcode-16.0.app",
+		"16.0.0":   "/Applications/Xcode-16.0.app",
+		"16.2":     "/Applications/Xcode-beta.app",
+		"beta":     "/Applications/Xcode-beta.app",
+		"16 beta":  "/Applications/Xcode-beta.app",
+		"15":       "/Applications/Xcode-15.4.app",
+		"16a242d":  "/Applications/Xcode-16.0.app",
+		" 15.4 ":   "/Applications/Xcode-15.4.app",
+		"16C5023f": "/Applications/Xcode-beta.app",
+	}
+	for version, want := range tests {
+		install, err := MatchXcodeInstallation(installations, version)
+		if err != nil || install.Path != want {
+			t.Errorf("MatchXcodeInstallation(%q) = %+v, %v, want %s", version, install, err, want)
+		}
+	}
+
+	for _, version := range []string{"1", "17", "15.3", "15 beta", "latest"} {
+		if install, err := MatchXcodeInstallation(installations, version); err == nil {
+			t.Errorf("MatchXcodeInstallation(%q) = %s, want no match", version, install.Path)
+		}
+	}
+}
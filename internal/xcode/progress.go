@@ -0,0 +1,82 @@
+package xcode
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jontolof/xcode-build-mcp/internal/common"
+)
+
+// Progress patterns for live xcodebuild output
+var (
+	compileStepRegex = regexp.MustCompile(`^(CompileSwift|SwiftCompile|CompileC|CompileAssetCatalog|CompileStoryboard|CompileXIB|Ld|Libtool|CodeSign|ProcessInfoPlistFile|PhaseScriptExecution)\s`)
+	buildTargetRegex = regexp.MustCompile(`=== BUILD TARGET (.+?) OF PROJECT (.+?) WITH`)
+	testStartedRegex = regexp.MustCompile(`Test Case '(.+?)' started`)
+)
+
+// minProgressInterval throttles updates; a large project emits thousands of
+// compile steps and clients only need a steady heartbeat
+const minProgressInterval = 250 * time.Millisecond
+
+// ProgressTracker turns xcodebuild output lines into progress updates. It
+// counts compile steps, targets and started test cases, and reports the
+// running total, which only ever grows. Observe is meant to be passed to
+// WithLineHandler, which serializes calls.
+type ProgressTracker struct {
+	report common.ProgressFunc
+
+	steps   int
+	targets int
+	tests   int
+
+	currentTarget string
+	lastReport    time.Time
+	now           func() time.Time
+}
+
+func NewProgressTracker(report common.ProgressFunc) *ProgressTracker {
+	return &ProgressTracker{
+		report: report,
+		now:    time.Now,
+	}
+}
+
+// Observe inspects one output line and reports progress when it marks a
+// compile step, the start of the next target or a test case starting
+func (t *ProgressTracker) Observe(line string) {
+	line = strings.TrimSpace(line)
+
+	var message string
+	force := false
+
+	switch {
+	case compileStepRegex.MatchString(line):
+		t.steps++
+		message = fmt.Sprintf("%d build steps", t.steps)
+		if t.currentTarget != "" {
+			message = fmt.Sprintf("Building %s: %s", t.currentTarget, message)
+		}
+	case buildTargetRegex.MatchString(line):
+		// xcodebuild announces targets as it starts them, so every target
+		// before this one has finished
+		t.targets++
+		t.currentTarget = buildTargetRegex.FindStringSubmatch(line)[1]
+		message = fmt.Sprintf("Building target %s (%d targets done)", t.currentTarget, t.targets-1)
+		force = true
+	case testStartedRegex.MatchString(line):
+		t.tests++
+		message = fmt.Sprintf("Running test %d: %s", t.tests, testStartedRegex.FindStringSubmatch(line)[1])
+	default:
+		return
+	}
+
+	now := t.now()
+	if !force && now.Sub(t.lastReport) < minProgressInterval {
+		return
+	}
+	t.lastReport = now
+
+	t.report(float64(t.steps+t.targets+t.tests), 0, message)
+}
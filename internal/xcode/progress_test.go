@@ -0,0 +1,75 @@
+package xcode
+
+import (
+	"testing"
+	"time"
+)
+
+type progressUpdate struct {
+	progress float64
+	message  string
+}
+
+func newTestTracker() (*ProgressTracker, *[]progressUpdate, *time.Time) {
+	var updates []progressUpdate
+	clock := time.Unix(0, 0)
+
+	tracker := NewProgressTracker(func(progress, total float64, message string) {
+		updates = append(updates, progressUpdate{progress, message})
+	})
+	tracker.now = func() time.Time { return clock }
+
+	return tracker, &updates, &clock
+}
+
+func TestProgressTracker_Observe(t *testing.T) {
+	tracker, updates, clock := newTestTracker()
+
+	lines := []string{
+		"=== BUILD TARGET Core OF PROJECT App WITH CONFIGURATION Debug ===",
+		"CompileSwift normal arm64 /src/Core/Model.swift",
+		"warning: something unrelated",
+		"=== BUILD TARGET App OF PROJECT App WITH CONFIGURATION Debug ===",
+		"Ld /build/App.app/App normal",
+		"Test Case '-[AppTests.LoginTests testValidLogin]' started.",
+	}
+	for _, line := range lines {
+		*clock = clock.Add(time.Second)
+		tracker.Observe(line)
+	}
+
+	if len(*updates) != 5 {
+		t.Fatalf("Expected 5 updates, got %d: %+v", len(*updates), *updates)
+	}
+
+	for i := 1; i < len(*updates); i++ {
+		if (*updates)[i].progress <= (*updates)[i-1].progress {
+			t.Errorf("Progress must increase: %v then %v", (*updates)[i-1].progress, (*updates)[i].progress)
+		}
+	}
+
+	if got := (*updates)[2].message; got != "Building target App (1 targets done)" {
+		t.Errorf("Unexpected target message: %q", got)
+	}
+	if got := (*updates)[4].message; got != "Running test 1: -[AppTests.LoginTests testValidLogin]" {
+		t.Errorf("Unexpected test message: %q", got)
+	}
+}
+
+func TestProgressTracker_Throttle(t *testing.T) {
+	tracker, updates, _ := newTestTracker()
+
+	// The clock never advances, so only the first step and the forced
+	// target announcement get through
+	tracker.Observe("CompileC /build/a.o /src/a.c normal arm64 c")
+	tracker.Observe("CompileC /build/b.o /src/b.c normal arm64 c")
+	tracker.Observe("=== BUILD TARGET App OF PROJECT App WITH CONFIGURATION Debug ===")
+	tracker.Observe("CompileC /build/c.o /src/c.c normal arm64 c")
+
+	if len(*updates) != 2 {
+		t.Fatalf("Expected 2 updates, got %d: %+v", len(*updates), *updates)
+	}
+	if (*updates)[1].progress != 3 {
+		t.Errorf("Expected progress to count throttled steps, got %v", (*updates)[1].progress)
+	}
+}
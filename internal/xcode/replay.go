@@ -0,0 +1,228 @@
+package xcode
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Fixture is one recorded command: what ran, what it printed and how it
+// ended. Args of a fixture may use "*" to match any one argument, or end in
+// "*" to match any argument with that prefix, so temporary paths still
+// match on replay.
+type Fixture struct {
+	Args     []string `json:"args"`
+	Dir      string   `json:"dir,omitempty"`
+	Stdout   string   `json:"stdout,omitempty"`
+	Stderr   string   `json:"stderr,omitempty"`
+	ExitCode int      `json:"exit_code"`
+	Signal   int      `json:"signal,omitempty"`
+	CoreDump bool     `json:"core_dump,omitempty"`
+}
+
+// Matches reports whether the fixture recorded args
+func (f *Fixture) Matches(args []string) bool {
+	if len(f.Args) != len(args) {
+		return false
+	}
+	for i, pattern := range f.Args {
+		if pattern == args[i] || pattern == "*" {
+			continue
+		}
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok && strings.HasPrefix(args[i], prefix) {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+func (f *Fixture) status() ExitStatus {
+	return ExitStatus{ExitCode: f.ExitCode, Signal: f.Signal, CoreDump: f.CoreDump}
+}
+
+// LoadFixtures reads a session of fixtures written by a RecordingRunner
+func LoadFixtures(path string) ([]Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixtures: %w", err)
+	}
+	var fixtures []Fixture
+	if err := json.Unmarshal(data, &fixtures); err != nil {
+		return nil, fmt.Errorf("failed to parse fixtures %s: %w", path, err)
+	}
+	return fixtures, nil
+}
+
+// RecordingRunner runs commands through another runner and records each
+// of them as a Fixture, rewriting the session file at path after every
+// command so a session survives the server being killed
+type RecordingRunner struct {
+	runner   CommandRunner
+	path     string
+	mu       sync.Mutex
+	fixtures []Fixture
+}
+
+func NewRecordingRunner(runner CommandRunner, path string) *RecordingRunner {
+	return &RecordingRunner{
+		runner: runner,
+		path:   path,
+	}
+}
+
+func (r *RecordingRunner) Run(ctx context.Context, cmd Command) (ExitStatus, error) {
+	var stdout, stderr bytes.Buffer
+	recorded := cmd
+	recorded.Stdout = teeWriter(cmd.Stdout, &stdout)
+	recorded.Stderr = teeWriter(cmd.Stderr, &stderr)
+
+	status, err := r.runner.Run(ctx, recorded)
+	if err != nil {
+		// Commands that never started have nothing to replay
+		return status, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fixtures = append(r.fixtures, Fixture{
+		Args:     cmd.Args,
+		Dir:      cmd.Dir,
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
+		ExitCode: status.ExitCode,
+		Signal:   status.Signal,
+		CoreDump: status.CoreDump,
+	})
+
+	data, err := json.MarshalIndent(r.fixtures, "", "  ")
+	if err != nil {
+		return status, fmt.Errorf("failed to encode fixtures: %w", err)
+	}
+	if err := os.WriteFile(r.path, data, 0644); err != nil {
+		return status, fmt.Errorf("failed to write fixtures: %w", err)
+	}
+	return status, nil
+}
+
+// Fixtures returns the commands recorded so far
+func (r *RecordingRunner) Fixtures() []Fixture {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Fixture(nil), r.fixtures...)
+}
+
+// teeWriter copies writes to w, if set, and to record
+func teeWriter(w io.Writer, record *bytes.Buffer) io.Writer {
+	if w == nil {
+		return record
+	}
+	return io.MultiWriter(w, record)
+}
+
+// ReplayRunner answers commands from fixtures instead of running them. Each
+// command takes the first unused fixture that matches it, so a session
+// replays in order even when commands repeat; once all matching fixtures
+// are used the last one answers again, which suits polling. Commands
+// without a fixture fail to start.
+type ReplayRunner struct {
+	mu       sync.Mutex
+	fixtures []Fixture
+	used     []bool
+}
+
+func NewReplayRunner(fixtures []Fixture) *ReplayRunner {
+	return &ReplayRunner{
+		fixtures: fixtures,
+		used:     make([]bool, len(fixtures)),
+	}
+}
+
+func (r *ReplayRunner) Run(ctx context.Context, cmd Command) (ExitStatus, error) {
+	fixture, err := r.take(cmd.Args)
+	if err != nil {
+		return ExitStatus{}, err
+	}
+
+	if cmd.Stdout != nil {
+		if _, err := io.WriteString(cmd.Stdout, fixture.Stdout); err != nil {
+			return ExitStatus{}, err
+		}
+	}
+	if cmd.Stderr != nil {
+		if _, err := io.WriteString(cmd.Stderr, fixture.Stderr); err != nil {
+			return ExitStatus{}, err
+		}
+	}
+	return fixture.status(), nil
+}
+
+// take claims the fixture that answers args
+func (r *ReplayRunner) take(args []string) (*Fixture, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	last := -1
+	for i := range r.fixtures {
+		if !r.fixtures[i].Matches(args) {
+			continue
+		}
+		if !r.used[i] {
+			r.used[i] = true
+			return &r.fixtures[i], nil
+		}
+		last = i
+	}
+	if last >= 0 {
+		return &r.fixtures[last], nil
+	}
+	return nil, fmt.Errorf("no fixture for command: %s", strings.Join(args, " "))
+}
+
+// LookPath returns the path the session ran name from, or name itself if it
+// never ran, so replaying never depends on what is installed
+func (r *ReplayRunner) LookPath(name string) (string, error) {
+	for _, fixture := range r.fixtures {
+		if len(fixture.Args) > 0 && filepath.Base(fixture.Args[0]) == name {
+			return fixture.Args[0], nil
+		}
+	}
+	return name, nil
+}
+
+// Unused returns the fixtures no command has replayed yet
+func (r *ReplayRunner) Unused() []Fixture {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var unused []Fixture
+	for i, used := range r.used {
+		if !used {
+			unused = append(unused, r.fixtures[i])
+		}
+	}
+	return unused
+}
+
+// RunnerFromEnv selects the CommandRunner for the server.
+// MCP_REPLAY_COMMANDS names a fixture session to replay instead of running
+// anything, MCP_RECORD_COMMANDS a session file to record every command to.
+func RunnerFromEnv() (CommandRunner, error) {
+	if path := os.Getenv("MCP_REPLAY_COMMANDS"); path != "" {
+		fixtures, err := LoadFixtures(path)
+		if err != nil {
+			return nil, err
+		}
+		return NewReplayRunner(fixtures), nil
+	}
+	if path := os.Getenv("MCP_RECORD_COMMANDS"); path != "" {
+		return NewRecordingRunner(ExecRunner{}, path), nil
+	}
+	return ExecRunner{}, nil
+}
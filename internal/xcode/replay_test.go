@@ -0,0 +1,199 @@
+package xcode
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestExecRunner_Run(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	status, err := ExecRunner{}.Run(context.Background(), Command{
+		Args:   []string{"sh", "-c", `echo "$GREETING"; echo oops >&2; exit 3`},
+		Env:    []string{"GREETING=hello"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if status.ExitCode != 3 || status.Signal != 0 || status.Success() {
+		t.Errorf("status = %+v, want exit code 3", status)
+	}
+	if stdout.String() != "hello\n" || stderr.String() != "oops\n" {
+		t.Errorf("stdout = %q, stderr = %q", stdout.String(), stderr.String())
+	}
+
+	status, err = ExecRunner{}.Run(context.Background(), Command{Args: []string{"sh", "-c", "kill -SEGV $$"}})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if status.Signal != int(syscall.SIGSEGV) || status.ExitCode != -1 {
+		t.Errorf("status = %+v, want SIGSEGV", status)
+	}
+
+	if _, err := (ExecRunner{}).Run(context.Background(), Command{Args: []string{"no-such-command-xyz"}}); err == nil {
+		t.Error("expected an error for a command that cannot start")
+	}
+}
+
+func TestExecutor_Output(t *testing.T) {
+	executor := NewExecutor(&testLogger{})
+
+	output, err := executor.Output(context.Background(), "sh", "-c", "echo out; echo err >&2; exit 2")
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("expected *ExitError, got %v", err)
+	}
+	if exitErr.ExitCode != 2 || string(exitErr.Stderr) != "err\n" || string(output) != "out\n" {
+		t.Errorf("output = %q, error = %+v", output, exitErr)
+	}
+	if exitErr.Error() != "exit status 2" {
+		t.Errorf("Error() = %q", exitErr.Error())
+	}
+
+	combined, err := executor.CombinedOutput(context.Background(), "sh", "-c", "echo out; echo err >&2")
+	if err != nil {
+		t.Fatalf("CombinedOutput failed: %v", err)
+	}
+	if !strings.Contains(string(combined), "out\n") || !strings.Contains(string(combined), "err\n") {
+		t.Errorf("combined output = %q", combined)
+	}
+}
+
+func TestFixture_Matches(t *testing.T) {
+	fixture := &Fixture{Args: []string{"xcrun", "simctl", "io", "*", "screenshot", "/tmp/shot-*"}}
+
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{[]string{"xcrun", "simctl", "io", "ABC", "screenshot", "/tmp/shot-1.png"}, true},
+		{[]string{"xcrun", "simctl", "io", "DEF", "screenshot", "/tmp/shot-"}, true},
+		{[]string{"xcrun", "simctl", "io", "ABC", "screenshot", "/var/shot.png"}, false},
+		{[]string{"xcrun", "simctl", "io", "ABC", "screenshot"}, false},
+		{[]string{"xcrun", "simctl", "list", "ABC", "screenshot", "/tmp/shot-1.png"}, false},
+	}
+	for _, tt := range tests {
+		if got := fixture.Matches(tt.args); got != tt.want {
+			t.Errorf("Matches(%v) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestRecordingRunner_Replay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+	recorder := NewRecordingRunner(ExecRunner{}, path)
+	executor := NewExecutorWithRunner(&testLogger{}, recorder)
+
+	recorded, err := executor.ExecuteCommand(context.Background(), []string{"sh", "-c", "echo first; echo warn >&2; exit 65"})
+	if err != nil {
+		t.Fatalf("ExecuteCommand failed: %v", err)
+	}
+	if _, err := executor.Output(context.Background(), "echo", "second"); err != nil {
+		t.Fatalf("Output failed: %v", err)
+	}
+
+	fixtures, err := LoadFixtures(path)
+	if err != nil {
+		t.Fatalf("LoadFixtures failed: %v", err)
+	}
+	if len(fixtures) != 2 || len(recorder.Fixtures()) != 2 {
+		t.Fatalf("recorded %d fixtures, want 2", len(fixtures))
+	}
+
+	replayer := NewReplayRunner(fixtures)
+	executor = NewExecutorWithRunner(&testLogger{}, replayer)
+
+	replayed, err := executor.ExecuteCommand(context.Background(), []string{"sh", "-c", "echo first; echo warn >&2; exit 65"})
+	if err != nil {
+		t.Fatalf("ExecuteCommand failed: %v", err)
+	}
+	if replayed.ExitCode != recorded.ExitCode || replayed.StdoutOutput != recorded.StdoutOutput ||
+		replayed.StderrOutput != recorded.StderrOutput {
+		t.Errorf("replayed %+v, recorded %+v", replayed, recorded)
+	}
+	if unused := replayer.Unused(); len(unused) != 1 || unused[0].Args[1] != "second" {
+		t.Errorf("Unused() = %+v, want the echo fixture", unused)
+	}
+
+	output, err := executor.Output(context.Background(), "echo", "second")
+	if err != nil || string(output) != "second\n" {
+		t.Errorf("Output() = %q, %v", output, err)
+	}
+
+	if _, err := executor.Output(context.Background(), "echo", "third"); err == nil ||
+		!strings.Contains(err.Error(), "no fixture for command") {
+		t.Errorf("expected a missing fixture error, got %v", err)
+	}
+}
+
+func TestReplayRunner_Order(t *testing.T) {
+	replayer := NewReplayRunner([]Fixture{
+		{Args: []string{"xcrun", "simctl", "list"}, Stdout: "booting"},
+		{Args: []string{"xcrun", "simctl", "list"}, Stdout: "booted"},
+	})
+
+	var got []string
+	for i := 0; i < 3; i++ {
+		output, err := runOutput(context.Background(), replayer, []string{"xcrun", "simctl", "list"})
+		if err != nil {
+			t.Fatalf("runOutput failed: %v", err)
+		}
+		got = append(got, string(output))
+	}
+
+	// Fixtures answer in order, then the last one keeps answering
+	if want := "booting booted booted"; strings.Join(got, " ") != want {
+		t.Errorf("replayed %q, want %q", strings.Join(got, " "), want)
+	}
+}
+
+func TestRunnerFromEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+
+	t.Setenv("MCP_REPLAY_COMMANDS", "")
+	t.Setenv("MCP_RECORD_COMMANDS", path)
+	runner, err := RunnerFromEnv()
+	if err != nil {
+		t.Fatalf("RunnerFromEnv failed: %v", err)
+	}
+	if _, ok := runner.(*RecordingRunner); !ok {
+		t.Errorf("runner = %T, want *RecordingRunner", runner)
+	}
+
+	t.Setenv("MCP_REPLAY_COMMANDS", filepath.Join(t.TempDir(), "missing.json"))
+	if _, err := RunnerFromEnv(); err == nil {
+		t.Error("expected an error for a missing fixture session")
+	}
+
+	t.Setenv("MCP_REPLAY_COMMANDS", "")
+	t.Setenv("MCP_RECORD_COMMANDS", "")
+	runner, err = RunnerFromEnv()
+	if err != nil {
+		t.Fatalf("RunnerFromEnv failed: %v", err)
+	}
+	if _, ok := runner.(ExecRunner); !ok {
+		t.Errorf("runner = %T, want ExecRunner", runner)
+	}
+}
+
+func TestReplayRunner_LookPath(t *testing.T) {
+	replay := NewReplayRunner([]Fixture{
+		{Args: []string{"xcrun", "simctl", "list"}},
+		{Args: []string{"/Applications/Xcode.app/Contents/Developer/usr/bin/xcodebuild", "build"}},
+	})
+	executor := NewExecutorWithRunner(&testLogger{}, replay)
+
+	path, err := executor.FindXcodeCommand()
+	if err != nil || path != "/Applications/Xcode.app/Contents/Developer/usr/bin/xcodebuild" {
+		t.Errorf("FindXcodeCommand() = %q, %v, want the recorded path", path, err)
+	}
+	if path, _ := replay.LookPath("axe"); path != "axe" {
+		t.Errorf("LookPath(axe) = %q, want the bare name", path)
+	}
+}
@@ -0,0 +1,130 @@
+package xcode
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Command is a process for a CommandRunner to start. Env entries
+// (KEY=VALUE) are added to the server's own environment. Stdout and Stderr
+// receive the output as the command writes it; nil discards it.
+type Command struct {
+	Args   []string
+	Dir    string
+	Env    []string
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+func (c Command) String() string {
+	return strings.Join(c.Args, " ")
+}
+
+// ExitStatus reports how a command ended: with an exit code, or killed by
+// a signal, in which case ExitCode is -1 like os.ProcessState reports it
+type ExitStatus struct {
+	ExitCode int  `json:"exit_code"`
+	Signal   int  `json:"signal,omitempty"`
+	CoreDump bool `json:"core_dump,omitempty"`
+}
+
+func (s ExitStatus) Success() bool {
+	return s.ExitCode == 0 && s.Signal == 0
+}
+
+// CommandRunner runs external commands. Run returns once the command has
+// exited and all of its output has been written. A command that runs and
+// fails reports that through its status; an error means it could not be
+// run at all.
+type CommandRunner interface {
+	Run(ctx context.Context, cmd Command) (ExitStatus, error)
+}
+
+// CommandResolver is implemented by runners that decide where a command
+// lives instead of the local filesystem, such as a ReplayRunner
+type CommandResolver interface {
+	LookPath(name string) (string, error)
+}
+
+// ExitError reports a command that ran but did not succeed. Stderr holds
+// its error output when the caller did not stream it elsewhere.
+type ExitError struct {
+	ExitStatus
+	Stderr []byte
+}
+
+func (e *ExitError) Error() string {
+	if e.Signal != 0 {
+		return "signal: " + syscall.Signal(e.Signal).String()
+	}
+	return fmt.Sprintf("exit status %d", e.ExitCode)
+}
+
+// runOutput runs a command with runner and returns its standard output,
+// or an *ExitError carrying its standard error if it fails
+func runOutput(ctx context.Context, runner CommandRunner, args []string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	status, err := runner.Run(ctx, Command{Args: args, Stdout: &stdout, Stderr: &stderr})
+	if err != nil {
+		return nil, err
+	}
+	if !status.Success() {
+		return stdout.Bytes(), &ExitError{ExitStatus: status, Stderr: stderr.Bytes()}
+	}
+	return stdout.Bytes(), nil
+}
+
+// ExecRunner runs commands as local processes
+type ExecRunner struct{}
+
+func (ExecRunner) Run(ctx context.Context, c Command) (ExitStatus, error) {
+	if len(c.Args) == 0 {
+		return ExitStatus{}, fmt.Errorf("no command arguments provided")
+	}
+
+	cmd := exec.CommandContext(ctx, c.Args[0], c.Args[1:]...)
+	cmd.Dir = c.Dir
+	if len(c.Env) > 0 {
+		cmd.Env = append(os.Environ(), c.Env...)
+	}
+	cmd.Stdin = c.Stdin
+	cmd.Stdout = c.Stdout
+	cmd.Stderr = c.Stderr
+
+	// xcodebuild and simctl fork helpers (swift-frontend, clang, test runners)
+	// that outlive a plain kill of the parent. Run the command in its own
+	// process group so cancellation takes down the whole tree.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = 5 * time.Second
+
+	if err := cmd.Start(); err != nil {
+		return ExitStatus{}, err
+	}
+
+	// Wait returns only after every byte has been copied to the writers
+	// (or WaitDelay expires); the process state says how the command ended
+	err := cmd.Wait()
+	if cmd.ProcessState == nil {
+		return ExitStatus{}, err
+	}
+
+	status := ExitStatus{ExitCode: cmd.ProcessState.ExitCode()}
+	if ws, ok := cmd.ProcessState.Sys().(syscall.WaitStatus); ok {
+		if ws.Signaled() {
+			status.Signal = int(ws.Signal())
+		}
+		status.CoreDump = ws.CoreDump()
+	}
+	return status, nil
+}
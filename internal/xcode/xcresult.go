@@ -1,10 +1,10 @@
 package xcode
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
@@ -15,12 +15,15 @@ import (
 // XCResultParser parses Xcode result bundles (.xcresult) for accurate test results
 type XCResultParser struct {
 	xcresulttoolPath string
+	runner           CommandRunner
 }
 
-// NewXCResultParser creates a new xcresult parser
-func NewXCResultParser() *XCResultParser {
+// NewXCResultParser creates a new xcresult parser that runs xcresulttool
+// with runner
+func NewXCResultParser(runner CommandRunner) *XCResultParser {
 	return &XCResultParser{
 		xcresulttoolPath: "xcrun", // Uses xcrun to find xcresulttool
+		runner:           runner,
 	}
 }
 
@@ -37,14 +40,14 @@ type XCResultSummary struct {
 }
 
 // ParseResultBundle parses an xcresult bundle and returns structured test results
-func (p *XCResultParser) ParseResultBundle(bundlePath string) (*XCResultSummary, error) {
+func (p *XCResultParser) ParseResultBundle(ctx context.Context, bundlePath string) (*XCResultSummary, error) {
 	// Verify the bundle exists
 	if _, err := os.Stat(bundlePath); os.IsNotExist(err) {
 		return nil, fmt.Errorf("xcresult bundle not found: %s", bundlePath)
 	}
 
 	// Get the test results using xcresulttool
-	output, err := p.runXCResultTool(bundlePath, "get", "--format", "json")
+	output, err := p.runXCResultTool(ctx, bundlePath, "get", "--format", "json")
 	if err != nil {
 		return nil, fmt.Errorf("failed to read xcresult bundle: %w", err)
 	}
@@ -55,21 +58,21 @@ func (p *XCResultParser) ParseResultBundle(bundlePath string) (*XCResultSummary,
 		return nil, fmt.Errorf("failed to parse xcresult JSON: %w", err)
 	}
 
-	return p.extractTestSummary(result, bundlePath)
+	return p.extractTestSummary(ctx, result, bundlePath)
 }
 
 // runXCResultTool executes xcresulttool with the given arguments
 // Note: Modern Xcode requires --legacy flag for compatibility
-func (p *XCResultParser) runXCResultTool(bundlePath string, args ...string) ([]byte, error) {
-	fullArgs := append([]string{"xcresulttool"}, args...)
+func (p *XCResultParser) runXCResultTool(ctx context.Context, bundlePath string, args ...string) ([]byte, error) {
 	// Add --legacy flag for modern Xcode compatibility (required as of Xcode 16+)
-	fullArgs = append(fullArgs, "--legacy")
-	fullArgs = append(fullArgs, "--path", bundlePath)
+	return p.xcresulttool(ctx, append(args, "--legacy", "--path", bundlePath)...)
+}
 
-	cmd := exec.Command(p.xcresulttoolPath, fullArgs...)
-	output, err := cmd.Output()
+// xcresulttool executes xcresulttool with exactly the given arguments
+func (p *XCResultParser) xcresulttool(ctx context.Context, args ...string) ([]byte, error) {
+	output, err := runOutput(ctx, p.runner, append([]string{p.xcresulttoolPath, "xcresulttool"}, args...))
 	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
+		if exitErr, ok := err.(*ExitError); ok {
 			return nil, fmt.Errorf("xcresulttool failed: %s", string(exitErr.Stderr))
 		}
 		return nil, err
@@ -78,7 +81,7 @@ func (p *XCResultParser) runXCResultTool(bundlePath string, args ...string) ([]b
 }
 
 // extractTestSummary extracts test summary from the parsed xcresult JSON
-func (p *XCResultParser) extractTestSummary(result map[string]interface{}, bundlePath string) (*XCResultSummary, error) {
+func (p *XCResultParser) extractTestSummary(ctx context.Context, result map[string]interface{}, bundlePath string) (*XCResultSummary, error) {
 	summary := &XCResultSummary{
 		TestBundles:        []types.TestBundle{},
 		FailedTestDetails:  []types.TestCase{},
@@ -127,7 +130,7 @@ func (p *XCResultParser) extractTestSummary(result map[string]interface{}, bundl
 		}
 
 		// Fetch the detailed test results
-		testDetails, err := p.fetchTestDetails(bundlePath, idValue)
+		testDetails, err := p.fetchTestDetails(ctx, bundlePath, idValue)
 		if err != nil {
 			// Don't silently ignore - return error to caller
 			return nil, fmt.Errorf("failed to fetch test details for ID %s: %w", idValue, err)
@@ -141,8 +144,8 @@ func (p *XCResultParser) extractTestSummary(result map[string]interface{}, bundl
 }
 
 // fetchTestDetails fetches detailed test results by ID
-func (p *XCResultParser) fetchTestDetails(bundlePath, refID string) (map[string]interface{}, error) {
-	output, err := p.runXCResultTool(bundlePath, "get", "--format", "json", "--id", refID)
+func (p *XCResultParser) fetchTestDetails(ctx context.Context, bundlePath, refID string) (map[string]interface{}, error) {
+	output, err := p.runXCResultTool(ctx, bundlePath, "get", "--format", "json", "--id", refID)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,511 @@
+package xcode
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jontolof/xcode-build-mcp/pkg/types"
+)
+
+// InspectOptions selects what InspectResultBundle exports besides the report
+type InspectOptions struct {
+	AttachmentsDir string
+	OnlyFailures   bool
+	IncludeLogs    bool
+}
+
+// xcresultDevice is a run destination in xcresulttool output
+type xcresultDevice struct {
+	DeviceID     string `json:"deviceId"`
+	DeviceName   string `json:"deviceName"`
+	Architecture string `json:"architecture"`
+	ModelName    string `json:"modelName"`
+	Platform     string `json:"platform"`
+	OSVersion    string `json:"osVersion"`
+}
+
+// testResultsSummary is the output of xcresulttool get test-results summary
+type testResultsSummary struct {
+	Title                    string  `json:"title"`
+	StartTime                float64 `json:"startTime"`
+	FinishTime               float64 `json:"finishTime"`
+	Result                   string  `json:"result"`
+	TotalTestCount           int     `json:"totalTestCount"`
+	PassedTests              int     `json:"passedTests"`
+	FailedTests              int     `json:"failedTests"`
+	SkippedTests             int     `json:"skippedTests"`
+	ExpectedFailures         int     `json:"expectedFailures"`
+	DevicesAndConfigurations []struct {
+		Device                xcresultDevice `json:"device"`
+		TestPlanConfiguration struct {
+			ConfigurationName string `json:"configurationName"`
+		} `json:"testPlanConfiguration"`
+		PassedTests      int `json:"passedTests"`
+		FailedTests      int `json:"failedTests"`
+		SkippedTests     int `json:"skippedTests"`
+		ExpectedFailures int `json:"expectedFailures"`
+	} `json:"devicesAndConfigurations"`
+	TestFailures []struct {
+		TestName             string `json:"testName"`
+		TargetName           string `json:"targetName"`
+		FailureText          string `json:"failureText"`
+		TestIdentifierString string `json:"testIdentifierString"`
+	} `json:"testFailures"`
+}
+
+// testNode is a node of xcresulttool get test-results tests output
+type testNode struct {
+	NodeType          string     `json:"nodeType"`
+	NodeIdentifier    string     `json:"nodeIdentifier"`
+	Name              string     `json:"name"`
+	Result            string     `json:"result"`
+	DurationInSeconds float64    `json:"durationInSeconds"`
+	Children          []testNode `json:"children"`
+}
+
+// buildResults is the output of xcresulttool get build-results
+type buildResults struct {
+	Status           string          `json:"status"`
+	StartTime        float64         `json:"startTime"`
+	EndTime          float64         `json:"endTime"`
+	Destination      *xcresultDevice `json:"destination"`
+	Errors           []buildIssue    `json:"errors"`
+	Warnings         []buildIssue    `json:"warnings"`
+	AnalyzerWarnings []buildIssue    `json:"analyzerWarnings"`
+}
+
+type buildIssue struct {
+	IssueType  string `json:"issueType"`
+	Message    string `json:"message"`
+	TargetName string `json:"targetName"`
+	SourceURL  string `json:"sourceURL"`
+}
+
+// attachmentManifest is the manifest.json written by xcresulttool export attachments
+type attachmentManifest []struct {
+	TestIdentifier string `json:"testIdentifier"`
+	Attachments    []struct {
+		ExportedFileName           string  `json:"exportedFileName"`
+		SuggestedHumanReadableName string  `json:"suggestedHumanReadableName"`
+		IsAssociatedWithFailure    bool    `json:"isAssociatedWithFailure"`
+		Timestamp                  float64 `json:"timestamp"`
+		DeviceName                 string  `json:"deviceName"`
+	} `json:"attachments"`
+}
+
+// failureLocationRegex splits "LoginTests.swift:42: message" failure messages
+var failureLocationRegex = regexp.MustCompile(`(?s)^([^:\n]+):(\d+): (.*)$`)
+
+// InspectResultBundle reports the tests, failures, build issues, devices
+// and timing recorded in any result bundle. It needs the xcresulttool of
+// Xcode 16 or later for the full report and falls back to the legacy test
+// summary on older versions.
+func (p *XCResultParser) InspectResultBundle(ctx context.Context, bundlePath string, opts InspectOptions) (*types.XCResultResult, error) {
+	if _, err := os.Stat(bundlePath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("xcresult bundle not found: %s", bundlePath)
+	}
+
+	result := &types.XCResultResult{Path: bundlePath}
+
+	testsErr := p.inspectTests(ctx, bundlePath, result)
+	if testsErr != nil {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("no test results: %v", testsErr))
+	}
+
+	buildErr := p.inspectBuild(ctx, bundlePath, result)
+	if buildErr != nil {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("no build results: %v", buildErr))
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if testsErr != nil && buildErr != nil {
+		return nil, fmt.Errorf("failed to read xcresult bundle: %w", testsErr)
+	}
+
+	if opts.AttachmentsDir != "" {
+		if err := p.exportAttachments(ctx, bundlePath, opts, result); err != nil {
+			return nil, err
+		}
+	}
+
+	result.Success = !strings.EqualFold(result.Result, "failed") &&
+		result.TestSummary.FailedTests == 0 && len(result.BuildErrors) == 0
+	return result, nil
+}
+
+// inspectTests reads the test summary and test tree, or the legacy summary
+// when this xcresulttool predates the test-results commands
+func (p *XCResultParser) inspectTests(ctx context.Context, bundlePath string, result *types.XCResultResult) error {
+	data, err := p.xcresulttool(ctx, "get", "test-results", "summary", "--path", bundlePath)
+	if err != nil {
+		legacy, legacyErr := p.ParseResultBundle(ctx, bundlePath)
+		if legacyErr != nil {
+			return err
+		}
+		applyLegacySummary(legacy, result)
+		result.Warnings = append(result.Warnings,
+			"xcresulttool get test-results is unavailable (Xcode 16 or later is required), so failure locations and devices are missing")
+		return nil
+	}
+	if err := applyTestResultsSummary(data, result); err != nil {
+		return err
+	}
+
+	data, err = p.xcresulttool(ctx, "get", "test-results", "tests", "--path", bundlePath)
+	if err != nil {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("no test details: %v", err))
+		return nil
+	}
+	return applyTestNodes(data, result)
+}
+
+// inspectBuild reads the build issues of the bundle
+func (p *XCResultParser) inspectBuild(ctx context.Context, bundlePath string, result *types.XCResultResult) error {
+	data, err := p.xcresulttool(ctx, "get", "build-results", "--path", bundlePath)
+	if err != nil {
+		return err
+	}
+	return applyBuildResults(data, result)
+}
+
+// exportAttachments writes the test attachments, and optionally the
+// diagnostics logs, to opts.AttachmentsDir
+func (p *XCResultParser) exportAttachments(ctx context.Context, bundlePath string, opts InspectOptions, result *types.XCResultResult) error {
+	if err := os.MkdirAll(opts.AttachmentsDir, 0755); err != nil {
+		return fmt.Errorf("failed to create attachments directory: %w", err)
+	}
+
+	args := []string{"export", "attachments", "--path", bundlePath, "--output-path", opts.AttachmentsDir}
+	if opts.OnlyFailures {
+		args = append(args, "--only-failures")
+	}
+	if _, err := p.xcresulttool(ctx, args...); err != nil {
+		return fmt.Errorf("failed to export attachments: %w", err)
+	}
+
+	manifest, err := os.ReadFile(filepath.Join(opts.AttachmentsDir, "manifest.json"))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read attachments manifest: %w", err)
+	}
+	if err == nil {
+		if result.Attachments, err = parseAttachmentManifest(manifest, opts.AttachmentsDir); err != nil {
+			return err
+		}
+	}
+
+	if opts.IncludeLogs {
+		logsPath := filepath.Join(opts.AttachmentsDir, "Diagnostics")
+		if _, err := p.xcresulttool(ctx, "export", "diagnostics", "--path", bundlePath, "--output-path", logsPath); err != nil {
+			return fmt.Errorf("failed to export diagnostics: %w", err)
+		}
+		result.LogsPath = logsPath
+	}
+	return nil
+}
+
+// applyTestResultsSummary fills result from xcresulttool get test-results summary output
+func applyTestResultsSummary(data []byte, result *types.XCResultResult) error {
+	var summary testResultsSummary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return fmt.Errorf("failed to parse test results summary: %w", err)
+	}
+
+	result.Title = summary.Title
+	result.Result = summary.Result
+	setRunTimes(result, summary.StartTime, summary.FinishTime)
+
+	// Expected failures do not fail a run, so they count as skipped like
+	// they do for xcode_test
+	result.TestSummary.TotalTests = summary.TotalTestCount
+	result.TestSummary.PassedTests = summary.PassedTests
+	result.TestSummary.FailedTests = summary.FailedTests
+	result.TestSummary.SkippedTests = summary.SkippedTests + summary.ExpectedFailures
+
+	for _, entry := range summary.DevicesAndConfigurations {
+		result.Devices = append(result.Devices, types.XCResultDevice{
+			Name:          entry.Device.DeviceName,
+			ID:            entry.Device.DeviceID,
+			Model:         entry.Device.ModelName,
+			Platform:      entry.Device.Platform,
+			OSVersion:     entry.Device.OSVersion,
+			Architecture:  entry.Device.Architecture,
+			Configuration: entry.TestPlanConfiguration.ConfigurationName,
+			PassedTests:   entry.PassedTests,
+			FailedTests:   entry.FailedTests,
+			SkippedTests:  entry.SkippedTests + entry.ExpectedFailures,
+		})
+	}
+
+	for _, failure := range summary.TestFailures {
+		test := failure.TestIdentifierString
+		if test == "" {
+			test = failure.TestName
+		}
+		result.Failures = append(result.Failures, types.XCResultFailure{
+			Test:    test,
+			Target:  failure.TargetName,
+			Message: failure.FailureText,
+		})
+	}
+	return nil
+}
+
+// applyTestNodes fills the test details of result from xcresulttool get
+// test-results tests output. Its failure messages carry source locations,
+// so they replace the failures of the summary.
+func applyTestNodes(data []byte, result *types.XCResultResult) error {
+	var tree struct {
+		TestNodes []testNode `json:"testNodes"`
+	}
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return fmt.Errorf("failed to parse test results: %w", err)
+	}
+
+	var failures []types.XCResultFailure
+	var walk func(node testNode, bundle *types.TestBundle, suite string)
+	walk = func(node testNode, bundle *types.TestBundle, suite string) {
+		switch node.NodeType {
+		case "Unit test bundle", "UI test bundle":
+			testBundle := types.TestBundle{
+				Name:     node.Name,
+				Type:     "unit",
+				Executed: true,
+				Status:   strings.ToLower(node.Result),
+				Duration: seconds(node.DurationInSeconds),
+			}
+			if node.NodeType == "UI test bundle" {
+				testBundle.Type = "ui"
+			}
+			for _, child := range node.Children {
+				walk(child, &testBundle, suite)
+			}
+			result.TestSummary.TestBundles = append(result.TestSummary.TestBundles, testBundle)
+			return
+		case "Test Suite":
+			suite = node.Name
+		case "Test Case":
+			if bundle != nil {
+				bundle.TestCount++
+			}
+			testCase := types.TestCase{
+				Name:      node.Name,
+				ClassName: suite,
+				Status:    strings.ToLower(node.Result),
+				Duration:  seconds(node.DurationInSeconds),
+			}
+			test := node.NodeIdentifier
+			if test == "" {
+				test = suite + "/" + node.Name
+			}
+
+			for i, message := range failureMessages(node) {
+				failure := types.XCResultFailure{Test: test, Message: message}
+				if bundle != nil {
+					failure.Target = bundle.Name
+				}
+				if match := failureLocationRegex.FindStringSubmatch(message); match != nil {
+					failure.File = match[1]
+					failure.Line, _ = strconv.Atoi(match[2])
+					failure.Message = match[3]
+				}
+				if i == 0 {
+					testCase.Message = failure.Message
+					if failure.File != "" {
+						testCase.Location = fmt.Sprintf("%s:%d", failure.File, failure.Line)
+					}
+				}
+				if testCase.Status == "failed" {
+					failures = append(failures, failure)
+				}
+			}
+
+			switch testCase.Status {
+			case "failed":
+				result.TestSummary.FailedTestsDetails = append(result.TestSummary.FailedTestsDetails, testCase)
+			case "skipped", "expected failure":
+				result.TestSummary.SkippedTestsDetails = append(result.TestSummary.SkippedTestsDetails, testCase)
+			}
+			return
+		}
+
+		for _, child := range node.Children {
+			walk(child, bundle, suite)
+		}
+	}
+	for _, node := range tree.TestNodes {
+		walk(node, nil, "")
+	}
+
+	if len(failures) > 0 {
+		result.Failures = failures
+	}
+	return nil
+}
+
+// failureMessages collects the failure messages below a test case,
+// including those of its repetitions
+func failureMessages(node testNode) []string {
+	var messages []string
+	for _, child := range node.Children {
+		if child.NodeType == "Failure Message" {
+			messages = append(messages, child.Name)
+			continue
+		}
+		messages = append(messages, failureMessages(child)...)
+	}
+	return messages
+}
+
+// applyBuildResults fills the build issues of result from xcresulttool
+// get build-results output
+func applyBuildResults(data []byte, result *types.XCResultResult) error {
+	var build buildResults
+	if err := json.Unmarshal(data, &build); err != nil {
+		return fmt.Errorf("failed to parse build results: %w", err)
+	}
+
+	for _, issue := range build.Errors {
+		file, line, column := issueLocation(issue.SourceURL)
+		result.BuildErrors = append(result.BuildErrors, types.BuildError{
+			File:     file,
+			Line:     line,
+			Column:   column,
+			Message:  issue.Message,
+			Severity: "error",
+			Category: issue.IssueType,
+		})
+	}
+	for _, issue := range append(build.Warnings, build.AnalyzerWarnings...) {
+		file, line, column := issueLocation(issue.SourceURL)
+		result.BuildWarnings = append(result.BuildWarnings, types.BuildWarning{
+			File:     file,
+			Line:     line,
+			Column:   column,
+			Message:  issue.Message,
+			Category: issue.IssueType,
+		})
+	}
+
+	// A bundle without tests takes its outcome, timing and device from the build
+	if result.Result == "" && build.Status != "" {
+		result.Result = build.Status
+	}
+	if result.StartedAt == nil {
+		setRunTimes(result, build.StartTime, build.EndTime)
+	}
+	if len(result.Devices) == 0 && build.Destination != nil && build.Destination.DeviceName != "" {
+		result.Devices = append(result.Devices, types.XCResultDevice{
+			Name:         build.Destination.DeviceName,
+			ID:           build.Destination.DeviceID,
+			Model:        build.Destination.ModelName,
+			Platform:     build.Destination.Platform,
+			OSVersion:    build.Destination.OSVersion,
+			Architecture: build.Destination.Architecture,
+		})
+	}
+	return nil
+}
+
+// issueLocation reads the file and one-based line and column from a build
+// issue's sourceURL, such as
+// file:///src/App/Login.swift#StartingColumnNumber=4&StartingLineNumber=41,
+// whose numbers are zero-based
+func issueLocation(sourceURL string) (string, int, int) {
+	if sourceURL == "" {
+		return "", 0, 0
+	}
+	parsed, err := url.Parse(sourceURL)
+	if err != nil {
+		return sourceURL, 0, 0
+	}
+
+	fragment, _ := url.ParseQuery(parsed.Fragment)
+	line, column := 0, 0
+	if value, err := strconv.Atoi(fragment.Get("StartingLineNumber")); err == nil {
+		line = value + 1
+	}
+	if value, err := strconv.Atoi(fragment.Get("StartingColumnNumber")); err == nil {
+		column = value + 1
+	}
+	return parsed.Path, line, column
+}
+
+// parseAttachmentManifest lists the attachments exported to dir
+func parseAttachmentManifest(data []byte, dir string) ([]types.XCResultAttachment, error) {
+	var manifest attachmentManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse attachments manifest: %w", err)
+	}
+
+	var attachments []types.XCResultAttachment
+	for _, test := range manifest {
+		for _, attachment := range test.Attachments {
+			name := attachment.SuggestedHumanReadableName
+			if name == "" {
+				name = attachment.ExportedFileName
+			}
+			attachments = append(attachments, types.XCResultAttachment{
+				Test:      test.TestIdentifier,
+				Name:      name,
+				Path:      filepath.Join(dir, attachment.ExportedFileName),
+				Failure:   attachment.IsAssociatedWithFailure,
+				Device:    attachment.DeviceName,
+				Timestamp: epochTime(attachment.Timestamp),
+			})
+		}
+	}
+	return attachments, nil
+}
+
+// applyLegacySummary fills result from the legacy xcresulttool summary
+func applyLegacySummary(summary *XCResultSummary, result *types.XCResultResult) {
+	result.TestSummary.TotalTests = summary.TotalTests
+	result.TestSummary.PassedTests = summary.PassedTests
+	result.TestSummary.FailedTests = summary.FailedTestCount
+	result.TestSummary.SkippedTests = summary.SkippedTests
+	result.TestSummary.TestBundles = summary.TestBundles
+	result.TestSummary.FailedTestsDetails = summary.FailedTestDetails
+	result.TestSummary.SkippedTestsDetails = summary.SkippedTestDetails
+
+	for _, failure := range summary.FailedTestDetails {
+		result.Failures = append(result.Failures, types.XCResultFailure{
+			Test:    failure.ClassName + "/" + failure.Name,
+			Message: failure.Message,
+		})
+	}
+}
+
+// setRunTimes records the start and end of the run from epoch seconds
+func setRunTimes(result *types.XCResultResult, start, end float64) {
+	result.StartedAt = epochTime(start)
+	result.FinishedAt = epochTime(end)
+	if result.StartedAt != nil && result.FinishedAt != nil {
+		result.RunDuration = result.FinishedAt.Sub(*result.StartedAt)
+	}
+}
+
+// epochTime converts epoch seconds to a time, or nil for zero
+func epochTime(value float64) *time.Time {
+	if value <= 0 {
+		return nil
+	}
+	whole, fraction := math.Modf(value)
+	t := time.Unix(int64(whole), int64(math.Round(fraction*1e3))*int64(time.Millisecond)).UTC()
+	return &t
+}
+
+// seconds converts fractional seconds to a duration
+func seconds(value float64) time.Duration {
+	return time.Duration(math.Round(value * float64(time.Second)))
+}
@@ -0,0 +1,358 @@
+package xcode
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jontolof/xcode-build-mcp/pkg/types"
+)
+
+const testResultsSummaryJSON = `{
+  "title": "Test - App",
+  "startTime": 1718000000.25,
+  "finishTime": 1718000042.75,
+  "environmentDescription": "App · Run with Xcode 16",
+  "result": "Failed",
+  "totalTestCount": 5,
+  "passedTests": 2,
+  "failedTests": 1,
+  "skippedTests": 1,
+  "expectedFailures": 1,
+  "devicesAndConfigurations": [
+    {
+      "device": {
+        "deviceId": "6B7A1F2C",
+        "deviceName": "iPhone 15",
+        "architecture": "arm64",
+        "modelName": "iPhone 15",
+        "platform": "iOS Simulator",
+        "osVersion": "17.5"
+      },
+      "testPlanConfiguration": {"configurationId": "1", "configurationName": "Default"},
+      "passedTests": 2,
+      "failedTests": 1,
+      "skippedTests": 1,
+      "expectedFailures": 1
+    }
+  ],
+  "testFailures": [
+    {
+      "testName": "testInvalidPassword()",
+      "targetName": "AppTests",
+      "failureText": "XCTAssertEqual failed",
+      "testIdentifier": 3,
+      "testIdentifierString": "LoginTests/testInvalidPassword()"
+    }
+  ]
+}`
+
+const testNodesJSON = `{
+  "testNodes": [
+    {
+      "nodeType": "Test Plan",
+      "name": "App",
+      "result": "Failed",
+      "children": [
+        {
+          "nodeType": "Unit test bundle",
+          "name": "AppTests",
+          "result": "Failed",
+          "durationInSeconds": 1.5,
+          "children": [
+            {
+              "nodeType": "Test Suite",
+              "name": "LoginTests",
+              "result": "Failed",
+              "children": [
+                {
+                  "nodeType": "Test Case",
+                  "nodeIdentifier": "LoginTests/testInvalidPassword()",
+                  "name": "testInvalidPassword()",
+                  "result": "Failed",
+                  "durationInSeconds": 0.25,
+                  "children": [
+                    {
+                      "nodeType": "Failure Message",
+                      "name": "LoginTests.swift:42: XCTAssertEqual failed: (\"a\") is not equal to (\"b\")",
+                      "result": "Failed"
+                    }
+                  ]
+                },
+                {
+                  "nodeType": "Test Case",
+                  "nodeIdentifier": "LoginTests/testValidPassword()",
+                  "name": "testValidPassword()",
+                  "result": "Passed",
+                  "durationInSeconds": 0.5
+                },
+                {
+                  "nodeType": "Test Case",
+                  "nodeIdentifier": "LoginTests/testBiometrics()",
+                  "name": "testBiometrics()",
+                  "result": "Skipped",
+                  "children": [
+                    {"nodeType": "Failure Message", "name": "LoginTests.swift:60: Test skipped - No Face ID"}
+                  ]
+                }
+              ]
+            }
+          ]
+        },
+        {
+          "nodeType": "UI test bundle",
+          "name": "AppUITests",
+          "result": "Passed",
+          "children": [
+            {
+              "nodeType": "Test Suite",
+              "name": "LaunchTests",
+              "result": "Passed",
+              "children": [
+                {"nodeType": "Test Case", "name": "testLaunch()", "result": "Passed"}
+              ]
+            }
+          ]
+        }
+      ]
+    }
+  ]
+}`
+
+const buildResultsJSON = `{
+  "actionTitle": "Build App",
+  "status": "failed",
+  "startTime": 1718000000,
+  "endTime": 1718000010,
+  "warningCount": 1,
+  "errorCount": 1,
+  "destination": {"deviceName": "My Mac", "platform": "macOS", "architecture": "arm64"},
+  "errors": [
+    {
+      "issueType": "Swift Compiler Error",
+      "message": "Cannot find 'token' in scope",
+      "targetName": "App",
+      "sourceURL": "file:///src/App/Sources/Login.swift#EndingColumnNumber=12&EndingLineNumber=40&StartingColumnNumber=7&StartingLineNumber=40&Timestamp=740000000"
+    }
+  ],
+  "warnings": [
+    {"issueType": "Deprecation", "message": "'UIScreen.main' is deprecated", "targetName": "App"}
+  ],
+  "analyzerWarnings": []
+}`
+
+func TestApplyTestResultsSummary(t *testing.T) {
+	result := &types.XCResultResult{}
+	if err := applyTestResultsSummary([]byte(testResultsSummaryJSON), result); err != nil {
+		t.Fatalf("applyTestResultsSummary failed: %v", err)
+	}
+
+	if result.Title != "Test - App" || result.Result != "Failed" {
+		t.Errorf("Unexpected title or result: %q %q", result.Title, result.Result)
+	}
+	if result.StartedAt == nil || !result.StartedAt.Equal(time.Unix(1718000000, 250*int64(time.Millisecond))) {
+		t.Errorf("StartedAt = %v", result.StartedAt)
+	}
+	if result.RunDuration != 42500*time.Millisecond {
+		t.Errorf("RunDuration = %v", result.RunDuration)
+	}
+
+	summary := result.TestSummary
+	if summary.TotalTests != 5 || summary.PassedTests != 2 || summary.FailedTests != 1 || summary.SkippedTests != 2 {
+		t.Errorf("Unexpected counts: %+v", summary)
+	}
+
+	wantDevices := []types.XCResultDevice{{
+		Name:          "iPhone 15",
+		ID:            "6B7A1F2C",
+		Model:         "iPhone 15",
+		Platform:      "iOS Simulator",
+		OSVersion:     "17.5",
+		Architecture:  "arm64",
+		Configuration: "Default",
+		PassedTests:   2,
+		FailedTests:   1,
+		SkippedTests:  2,
+	}}
+	if !reflect.DeepEqual(result.Devices, wantDevices) {
+		t.Errorf("Devices = %+v, want %+v", result.Devices, wantDevices)
+	}
+
+	wantFailures := []types.XCResultFailure{{
+		Test:    "LoginTests/testInvalidPassword()",
+		Target:  "AppTests",
+		Message: "XCTAssertEqual failed",
+	}}
+	if !reflect.DeepEqual(result.Failures, wantFailures) {
+		t.Errorf("Failures = %+v, want %+v", result.Failures, wantFailures)
+	}
+}
+
+func TestApplyTestNodes(t *testing.T) {
+	result := &types.XCResultResult{}
+	if err := applyTestNodes([]byte(testNodesJSON), result); err != nil {
+		t.Fatalf("applyTestNodes failed: %v", err)
+	}
+
+	wantFailures := []types.XCResultFailure{{
+		Test:    "LoginTests/testInvalidPassword()",
+		Target:  "AppTests",
+		Message: `XCTAssertEqual failed: ("a") is not equal to ("b")`,
+		File:    "LoginTests.swift",
+		Line:    42,
+	}}
+	if !reflect.DeepEqual(result.Failures, wantFailures) {
+		t.Errorf("Failures = %+v, want %+v", result.Failures, wantFailures)
+	}
+
+	failed := result.TestSummary.FailedTestsDetails
+	if len(failed) != 1 || failed[0].ClassName != "LoginTests" || failed[0].Location != "LoginTests.swift:42" ||
+		failed[0].Duration != 250*time.Millisecond {
+		t.Errorf("Unexpected failed tests: %+v", failed)
+	}
+
+	skipped := result.TestSummary.SkippedTestsDetails
+	if len(skipped) != 1 || skipped[0].Name != "testBiometrics()" || skipped[0].Message != "Test skipped - No Face ID" {
+		t.Errorf("Unexpected skipped tests: %+v", skipped)
+	}
+
+	wantBundles := []types.TestBundle{
+		{Name: "AppTests", Type: "unit", Executed: true, Status: "failed", TestCount: 3, Duration: 1500 * time.Millisecond},
+		{Name: "AppUITests", Type: "ui", Executed: true, Status: "passed", TestCount: 1},
+	}
+	if !reflect.DeepEqual(result.TestSummary.TestBundles, wantBundles) {
+		t.Errorf("TestBundles = %+v, want %+v", result.TestSummary.TestBundles, wantBundles)
+	}
+}
+
+func TestApplyBuildResults(t *testing.T) {
+	result := &types.XCResultResult{}
+	if err := applyBuildResults([]byte(buildResultsJSON), result); err != nil {
+		t.Fatalf("applyBuildResults failed: %v", err)
+	}
+
+	wantErrors := []types.BuildError{{
+		File:     "/src/App/Sources/Login.swift",
+		Line:     41,
+		Column:   8,
+		Message:  "Cannot find 'token' in scope",
+		Severity: "error",
+		Category: "Swift Compiler Error",
+	}}
+	if !reflect.DeepEqual(result.BuildErrors, wantErrors) {
+		t.Errorf("BuildErrors = %+v, want %+v", result.BuildErrors, wantErrors)
+	}
+	if len(result.BuildWarnings) != 1 || result.BuildWarnings[0].File != "" || result.BuildWarnings[0].Category != "Deprecation" {
+		t.Errorf("Unexpected warnings: %+v", result.BuildWarnings)
+	}
+
+	// Without test results the build supplies outcome, timing and device
+	if result.Result != "failed" || result.RunDuration != 10*time.Second {
+		t.Errorf("Unexpected result %q and duration %v", result.Result, result.RunDuration)
+	}
+	if len(result.Devices) != 1 || result.Devices[0].Name != "My Mac" {
+		t.Errorf("Unexpected devices: %+v", result.Devices)
+	}
+
+	// Test results take precedence
+	result = &types.XCResultResult{Result: "Passed", Devices: []types.XCResultDevice{{Name: "iPhone 15"}}}
+	if err := applyBuildResults([]byte(buildResultsJSON), result); err != nil {
+		t.Fatalf("applyBuildResults failed: %v", err)
+	}
+	if result.Result != "Passed" || len(result.Devices) != 1 || result.Devices[0].Name != "iPhone 15" {
+		t.Errorf("Build results overrode test results: %+v", result)
+	}
+}
+
+func TestParseAttachmentManifest(t *testing.T) {
+	data := []byte(`[
+  {
+    "testIdentifier": "LoginTests/testInvalidPassword()",
+    "attachments": [
+      {
+        "exportedFileName": "0C4E.png",
+        "suggestedHumanReadableName": "Login screen_0_0C4E.png",
+        "isAssociatedWithFailure": true,
+        "timestamp": 1718000001.5,
+        "deviceName": "iPhone 15"
+      },
+      {"exportedFileName": "9A1B.txt"}
+    ]
+  }
+]`)
+
+	attachments, err := parseAttachmentManifest(data, "/tmp/attachments")
+	if err != nil {
+		t.Fatalf("parseAttachmentManifest failed: %v", err)
+	}
+
+	timestamp := time.Unix(1718000001, 500*int64(time.Millisecond)).UTC()
+	want := []types.XCResultAttachment{
+		{
+			Test:      "LoginTests/testInvalidPassword()",
+			Name:      "Login screen_0_0C4E.png",
+			Path:      "/tmp/attachments/0C4E.png",
+			Failure:   true,
+			Device:    "iPhone 15",
+			Timestamp: &timestamp,
+		},
+		{
+			Test: "LoginTests/testInvalidPassword()",
+			Name: "9A1B.txt",
+			Path: "/tmp/attachments/9A1B.txt",
+		},
+	}
+	if !reflect.DeepEqual(attachments, want) {
+		t.Errorf("parseAttachmentManifest() = %+v, want %+v", attachments, want)
+	}
+}
+
+func TestIssueLocation(t *testing.T) {
+	tests := []struct {
+		sourceURL    string
+		file         string
+		line, column int
+	}{
+		{"", "", 0, 0},
+		{"file:///src/App/Login.swift#StartingColumnNumber=0&StartingLineNumber=0", "/src/App/Login.swift", 1, 1},
+		{"file:///src/App/My%20View.swift", "/src/App/My View.swift", 0, 0},
+	}
+
+	for _, tt := range tests {
+		file, line, column := issueLocation(tt.sourceURL)
+		if file != tt.file || line != tt.line || column != tt.column {
+			t.Errorf("issueLocation(%q) = %q, %d, %d, want %q, %d, %d",
+				tt.sourceURL, file, line, column, tt.file, tt.line, tt.column)
+		}
+	}
+}
+
+// contextRunner fails every command with the error of its context, like a
+// process killed by cancellation, and counts the commands it was given
+type contextRunner struct {
+	commands int
+}
+
+func (r *contextRunner) Run(ctx context.Context, cmd Command) (ExitStatus, error) {
+	r.commands++
+	if err := ctx.Err(); err != nil {
+		return ExitStatus{}, err
+	}
+	return ExitStatus{}, nil
+}
+
+func TestInspectResultBundle_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	runner := &contextRunner{}
+	_, err := NewXCResultParser(runner).InspectResultBundle(ctx, t.TempDir(), InspectOptions{AttachmentsDir: t.TempDir()})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if runner.commands == 0 {
+		t.Error("Expected xcresulttool to be run with the cancelled context")
+	}
+}
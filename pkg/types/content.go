@@ -0,0 +1,69 @@
+package types
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ToolContent is one item of a tool result. Text items carry Text; image
+// items carry base64 encoded Data and its MimeType.
+type ToolContent struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	Data     string `json:"data,omitempty"`
+	MimeType string `json:"mimeType,omitempty"`
+}
+
+func TextContent(text string) ToolContent {
+	return ToolContent{Type: "text", Text: text}
+}
+
+func ImageContent(data []byte, mimeType string) ToolContent {
+	return ToolContent{
+		Type:     "image",
+		Data:     base64.StdEncoding.EncodeToString(data),
+		MimeType: mimeType,
+	}
+}
+
+// ToolResult is the outcome of a tool call. Content is what clients display;
+// Structured is the typed result sent as structuredContent and described by
+// the tool's output schema. IsError marks a call that ran but failed, such
+// as a build with compiler errors.
+type ToolResult struct {
+	Content    []ToolContent
+	Structured interface{}
+	IsError    bool
+}
+
+// NewToolResult returns a result whose text content is the JSON encoding of
+// structured
+func NewToolResult(structured interface{}, isError bool) (*ToolResult, error) {
+	data, err := json.Marshal(structured)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal result: %w", err)
+	}
+
+	return &ToolResult{
+		Content:    []ToolContent{TextContent(string(data))},
+		Structured: structured,
+		IsError:    isError,
+	}, nil
+}
+
+// Text joins the text content of the result. It returns "" for a nil result.
+func (r *ToolResult) Text() string {
+	if r == nil {
+		return ""
+	}
+
+	var texts []string
+	for _, item := range r.Content {
+		if item.Type == "text" {
+			texts = append(texts, item.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
@@ -1,25 +1,58 @@
 package types
 
 import (
+	"errors"
 	"fmt"
 )
 
 type ErrorCode string
 
 const (
-	ErrCodeInvalidParams     ErrorCode = "INVALID_PARAMS"
-	ErrCodeProjectNotFound   ErrorCode = "PROJECT_NOT_FOUND"
-	ErrCodeSchemeNotFound    ErrorCode = "SCHEME_NOT_FOUND"
-	ErrCodeBuildFailed       ErrorCode = "BUILD_FAILED"
-	ErrCodeTestFailed        ErrorCode = "TEST_FAILED"
-	ErrCodeSimulatorNotFound ErrorCode = "SIMULATOR_NOT_FOUND"
-	ErrCodeAppNotFound       ErrorCode = "APP_NOT_FOUND"
-	ErrCodeInstallFailed     ErrorCode = "INSTALL_FAILED"
-	ErrCodeLaunchFailed      ErrorCode = "LAUNCH_FAILED"
-	ErrCodeTimeout           ErrorCode = "TIMEOUT"
-	ErrCodeInternal          ErrorCode = "INTERNAL_ERROR"
+	ErrCodeInvalidParams      ErrorCode = "INVALID_PARAMS"
+	ErrCodeProjectNotFound    ErrorCode = "PROJECT_NOT_FOUND"
+	ErrCodeSchemeNotFound     ErrorCode = "SCHEME_NOT_FOUND"
+	ErrCodeBuildFailed        ErrorCode = "BUILD_FAILED"
+	ErrCodeTestFailed         ErrorCode = "TEST_FAILED"
+	ErrCodeSimulatorNotFound  ErrorCode = "SIMULATOR_NOT_FOUND"
+	ErrCodeSimulatorNotBooted ErrorCode = "SIMULATOR_NOT_BOOTED"
+	ErrCodeAppNotFound        ErrorCode = "APP_NOT_FOUND"
+	ErrCodeInstallFailed      ErrorCode = "INSTALL_FAILED"
+	ErrCodeLaunchFailed       ErrorCode = "LAUNCH_FAILED"
+	ErrCodeTimeout            ErrorCode = "TIMEOUT"
+	ErrCodeCommandFailed      ErrorCode = "COMMAND_FAILED"
+	ErrCodeBackendUnavailable ErrorCode = "BACKEND_UNAVAILABLE"
+	ErrCodeElementNotFound    ErrorCode = "ELEMENT_NOT_FOUND"
+	ErrCodeAssertionFailed    ErrorCode = "ASSERTION_FAILED"
+	ErrCodeXcodeNotFound      ErrorCode = "XCODE_NOT_FOUND"
+	ErrCodeInternal           ErrorCode = "INTERNAL_ERROR"
 )
 
+// remediationHints tell clients what to try next for each error code
+var remediationHints = map[ErrorCode]string{
+	ErrCodeInvalidParams:      "Check the arguments against the tool's inputSchema; details name the offending parameter.",
+	ErrCodeProjectNotFound:    "Run discover_projects to find the .xcodeproj or .xcworkspace, then pass it as project or workspace.",
+	ErrCodeSchemeNotFound:     "Run list_schemes to see the schemes available in the project.",
+	ErrCodeBuildFailed:        "Fix the reported compiler errors and rebuild; the fix_failing_build prompt can help.",
+	ErrCodeTestFailed:         "Inspect the failed tests; the triage_failing_tests prompt can help.",
+	ErrCodeSimulatorNotFound:  "Run list_simulators to find a valid UDID, or pass device_type to pick one automatically.",
+	ErrCodeSimulatorNotBooted: "Boot the simulator with simulator_control (action boot), then retry.",
+	ErrCodeAppNotFound:        "Build the app with xcode_build and pass the path of the .app bundle, or install it with install_app first.",
+	ErrCodeInstallFailed:      "Make sure the app was built for the simulator SDK (iphonesimulator) and the simulator is booted.",
+	ErrCodeLaunchFailed:       "Make sure the app is installed on the booted simulator and the bundle ID is correct.",
+	ErrCodeTimeout:            "Retry with a longer timeout, or check that the simulator is responsive.",
+	ErrCodeCommandFailed:      "Check that the Xcode command line tools are installed (xcode-select -p) and see the details for the command output.",
+	ErrCodeBackendUnavailable: "Install AXe (brew install cameroncooke/axe/axe) or idb, or set MCP_ACCESSIBILITY_BACKEND to the one installed.",
+	ErrCodeElementNotFound:    "Call describe_ui to see the identifiers and labels on screen, or scroll_to the element first.",
+	ErrCodeAssertionFailed:    "Compare the expected element with describe_ui output or a screenshot of the screen at that step.",
+	ErrCodeXcodeNotFound:      "Run list_xcode_versions to see the installed Xcode versions, then pass one of their versions or build numbers as xcode_version.",
+	ErrCodeInternal:           "Retry with MCP_LOG_LEVEL=debug and report the log if the problem persists.",
+}
+
+// Hint returns the remediation hint for the code, or "" if there is none
+func (c ErrorCode) Hint() string {
+	return remediationHints[c]
+}
+
 type XcodeError struct {
 	Code    ErrorCode              `json:"code"`
 	Message string                 `json:"message"`
@@ -34,6 +67,29 @@ func (e *XcodeError) Error() string {
 	return fmt.Sprintf("%s: %s", e.Code, e.Message)
 }
 
+func (e *XcodeError) Unwrap() error {
+	return e.Cause
+}
+
+// Data returns the error as the structured payload sent to clients: the code,
+// message, details and the code's remediation hint
+func (e *XcodeError) Data() map[string]interface{} {
+	data := map[string]interface{}{
+		"code":    e.Code,
+		"message": e.Message,
+	}
+	if len(e.Details) > 0 {
+		data["details"] = e.Details
+	}
+	if hint := e.Code.Hint(); hint != "" {
+		data["hint"] = hint
+	}
+	if e.Cause != nil {
+		data["cause"] = e.Cause.Error()
+	}
+	return data
+}
+
 func NewXcodeError(code ErrorCode, message string, details map[string]interface{}) *XcodeError {
 	return &XcodeError{
 		Code:    code,
@@ -93,14 +149,16 @@ func (w *BuildWarning) Error() string {
 }
 
 func IsXcodeError(err error, code ErrorCode) bool {
-	if xerr, ok := err.(*XcodeError); ok {
+	if xerr := ExtractXcodeError(err); xerr != nil {
 		return xerr.Code == code
 	}
 	return false
 }
 
+// ExtractXcodeError returns the first XcodeError in err's chain, or nil
 func ExtractXcodeError(err error) *XcodeError {
-	if xerr, ok := err.(*XcodeError); ok {
+	var xerr *XcodeError
+	if errors.As(err, &xerr) {
 		return xerr
 	}
 	return nil
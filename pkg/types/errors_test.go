@@ -2,6 +2,7 @@ package types
 
 import (
 	"errors"
+	"fmt"
 	"testing"
 )
 
@@ -224,3 +225,61 @@ func TestExtractXcodeError(t *testing.T) {
 		t.Error("Should return nil for non-XcodeError")
 	}
 }
+
+func TestExtractXcodeError_Wrapped(t *testing.T) {
+	xcodeErr := NewXcodeError(ErrCodeSimulatorNotBooted, "device is Shutdown", nil)
+	wrapped := fmt.Errorf("failed to launch: %w", xcodeErr)
+
+	if ExtractXcodeError(wrapped) != xcodeErr {
+		t.Error("Should find the XcodeError through fmt.Errorf wrapping")
+	}
+	if !IsXcodeError(wrapped, ErrCodeSimulatorNotBooted) {
+		t.Error("Should match the code of a wrapped XcodeError")
+	}
+
+	cause := errors.New("exit status 70")
+	if !errors.Is(NewXcodeErrorWithCause(ErrCodeCommandFailed, "simctl failed", cause, nil), cause) {
+		t.Error("Unwrap should expose the cause")
+	}
+}
+
+func TestErrorCode_Hint(t *testing.T) {
+	codes := []ErrorCode{
+		ErrCodeInvalidParams, ErrCodeProjectNotFound, ErrCodeSchemeNotFound, ErrCodeBuildFailed,
+		ErrCodeTestFailed, ErrCodeSimulatorNotFound, ErrCodeSimulatorNotBooted, ErrCodeAppNotFound,
+		ErrCodeInstallFailed, ErrCodeLaunchFailed, ErrCodeTimeout, ErrCodeCommandFailed, ErrCodeBackendUnavailable,
+		ErrCodeElementNotFound, ErrCodeAssertionFailed, ErrCodeXcodeNotFound, ErrCodeInternal,
+	}
+	for _, code := range codes {
+		if code.Hint() == "" {
+			t.Errorf("%s has no remediation hint", code)
+		}
+	}
+
+	if ErrorCode("UNKNOWN").Hint() != "" {
+		t.Error("Unknown codes should have no hint")
+	}
+}
+
+func TestXcodeError_Data(t *testing.T) {
+	err := NewXcodeErrorWithCause(ErrCodeSchemeNotFound, "scheme not found", errors.New("exit status 65"),
+		map[string]interface{}{"scheme": "App"})
+
+	data := err.Data()
+	if data["code"] != ErrCodeSchemeNotFound || data["message"] != "scheme not found" {
+		t.Errorf("Unexpected code or message: %v", data)
+	}
+	if data["hint"] != ErrCodeSchemeNotFound.Hint() {
+		t.Errorf("Expected the remediation hint, got %v", data["hint"])
+	}
+	if data["cause"] != "exit status 65" {
+		t.Errorf("Expected the cause message, got %v", data["cause"])
+	}
+	if details, ok := data["details"].(map[string]interface{}); !ok || details["scheme"] != "App" {
+		t.Errorf("Expected details to be kept, got %v", data["details"])
+	}
+
+	if _, ok := NewXcodeError(ErrCodeInternal, "boom", nil).Data()["details"]; ok {
+		t.Error("Empty details should be omitted")
+	}
+}
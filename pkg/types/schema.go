@@ -0,0 +1,110 @@
+package types
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+// SchemaFor derives a JSON schema from the Go type of v, following its json
+// struct tags. Fields without omitempty are required. Durations are integer
+// nanoseconds and nil slices, maps and pointers may be null, matching how
+// encoding/json writes them.
+func SchemaFor(v interface{}) map[string]interface{} {
+	return schemaForType(reflect.TypeOf(v), make(map[reflect.Type]bool))
+}
+
+// schemaForType builds the schema for t. visiting holds the structs being
+// expanded, so recursive types such as element trees stop at a plain object.
+func schemaForType(t reflect.Type, visiting map[reflect.Type]bool) map[string]interface{} {
+	if t.Kind() == reflect.Pointer {
+		return nullable(schemaForType(t.Elem(), visiting))
+	}
+
+	switch t {
+	case durationType:
+		return map[string]interface{}{"type": "integer", "description": "Duration in nanoseconds"}
+	case timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// []byte is written as a base64 string
+			return nullable(map[string]interface{}{"type": "string"})
+		}
+		return nullable(map[string]interface{}{"type": "array", "items": schemaForType(t.Elem(), visiting)})
+	case reflect.Array:
+		return map[string]interface{}{"type": "array", "items": schemaForType(t.Elem(), visiting)}
+	case reflect.Map:
+		return nullable(map[string]interface{}{"type": "object", "additionalProperties": schemaForType(t.Elem(), visiting)})
+	case reflect.Struct:
+		if visiting[t] {
+			return map[string]interface{}{"type": "object"}
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+		return schemaForStruct(t, visiting)
+	default:
+		// interface{} and anything else accepts any value
+		return map[string]interface{}{}
+	}
+}
+
+func schemaForStruct(t reflect.Type, visiting map[reflect.Type]bool) map[string]interface{} {
+	properties := make(map[string]interface{})
+	var required []string
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = schemaForType(field.Type, visiting)
+		if !strings.Contains(options, "omitempty") {
+			required = append(required, name)
+		}
+	}
+
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// nullable widens a schema's type to also accept null
+func nullable(schema map[string]interface{}) map[string]interface{} {
+	if typ, ok := schema["type"].(string); ok {
+		schema["type"] = []string{typ, "null"}
+	}
+	return schema
+}
@@ -0,0 +1,124 @@
+package types
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSchemaFor_BuildResult(t *testing.T) {
+	schema := SchemaFor(BuildResult{})
+
+	if schema["type"] != "object" {
+		t.Fatalf("Expected an object schema, got %v", schema["type"])
+	}
+
+	properties := schema["properties"].(map[string]interface{})
+	for _, name := range []string{"success", "duration", "errors", "crash_type", "process_state"} {
+		if _, ok := properties[name]; !ok {
+			t.Errorf("Missing property %s", name)
+		}
+	}
+
+	if got := properties["duration"].(map[string]interface{})["type"]; got != "integer" {
+		t.Errorf("duration type = %v, want integer", got)
+	}
+	if got := properties["crash_type"].(map[string]interface{})["type"]; got != "string" {
+		t.Errorf("crash_type type = %v, want string", got)
+	}
+
+	errorsSchema := properties["errors"].(map[string]interface{})
+	if !reflect.DeepEqual(errorsSchema["type"], []string{"array", "null"}) {
+		t.Errorf("errors type = %v, want nullable array", errorsSchema["type"])
+	}
+	items := errorsSchema["items"].(map[string]interface{})
+	if _, ok := items["properties"].(map[string]interface{})["message"]; !ok {
+		t.Error("errors items should describe BuildError")
+	}
+
+	required := schema["required"].([]string)
+	if !contains(required, "success") || !contains(required, "exit_code") {
+		t.Errorf("success and exit_code should be required, got %v", required)
+	}
+	if contains(required, "errors") || contains(required, "process_state") {
+		t.Errorf("omitempty fields should not be required, got %v", required)
+	}
+}
+
+func TestSchemaFor_TypeMapping(t *testing.T) {
+	type node struct {
+		Name     string            `json:"name"`
+		Children []node            `json:"children,omitempty"`
+		Labels   map[string]string `json:"labels,omitempty"`
+		Data     []byte            `json:"data,omitempty"`
+		Seen     time.Time         `json:"seen"`
+		Score    float64           `json:"score"`
+		Any      interface{}       `json:"any"`
+		Skipped  string            `json:"-"`
+		Untagged int
+		hidden   bool
+	}
+
+	properties := SchemaFor(&node{})["properties"].(map[string]interface{})
+
+	expect := map[string]interface{}{
+		"name":     "string",
+		"labels":   []string{"object", "null"},
+		"data":     []string{"string", "null"},
+		"seen":     "string",
+		"score":    "number",
+		"Untagged": "integer",
+	}
+	for name, want := range expect {
+		got := properties[name].(map[string]interface{})["type"]
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s type = %v, want %v", name, got, want)
+		}
+	}
+
+	if len(properties["any"].(map[string]interface{})) != 0 {
+		t.Error("interface{} fields should accept any value")
+	}
+	if _, ok := properties["Skipped"]; ok {
+		t.Error(`Fields tagged "-" should be skipped`)
+	}
+	if _, ok := properties["hidden"]; ok {
+		t.Error("Unexported fields should be skipped")
+	}
+
+	// Recursive types stop at a plain object instead of recursing forever
+	children := properties["children"].(map[string]interface{})
+	if got := children["items"].(map[string]interface{})["type"]; got != "object" {
+		t.Errorf("Recursive items type = %v, want object", got)
+	}
+}
+
+func TestToolResult_Text(t *testing.T) {
+	result, err := NewToolResult(&ScreenshotResult{Success: true, FilePath: "/tmp/a.png"}, false)
+	if err != nil {
+		t.Fatalf("NewToolResult failed: %v", err)
+	}
+	result.Content = append(result.Content, ImageContent([]byte{1, 2, 3}, "image/png"))
+
+	text := result.Text()
+	if text == "" || text[0] != '{' {
+		t.Errorf("Expected the JSON result as text, got %q", text)
+	}
+	if result.Structured == nil || result.IsError {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	var nilResult *ToolResult
+	if nilResult.Text() != "" {
+		t.Error("A nil result should have no text")
+	}
+}
+
+func contains(values []string, want string) bool {
+	for _, v := range values {
+		if v == want {
+			return true
+		}
+	}
+	return false
+}
@@ -70,6 +70,8 @@ type BuildParams struct {
 	Archive       bool              `json:"archive,omitempty"`
 	DerivedData   string            `json:"derived_data,omitempty"`
 	Environment   map[string]string `json:"environment,omitempty"`
+	DeveloperDir  string            `json:"developer_dir,omitempty"`
+	XcodeVersion  string            `json:"xcode_version,omitempty"`
 	ExtraArgs     []string          `json:"extra_args,omitempty"`
 }
 
@@ -96,8 +98,7 @@ type TestParams struct {
 	ProjectPath  string            `json:"project_path,omitempty"`
 	Workspace    string            `json:"workspace,omitempty"`
 	Project      string            `json:"project,omitempty"`
-	Scheme       string            `json:"scheme,omitempty"`
-	Target       string            `json:"target,omitempty"`
+	Scheme       string            `json:"scheme"`
 	TestPlan     string            `json:"test_plan,omitempty"`
 	SDK          string            `json:"sdk,omitempty"`
 	Destination  string            `json:"destination,omitempty"`
@@ -109,7 +110,24 @@ type TestParams struct {
 	ResultBundle string            `json:"result_bundle,omitempty"`
 	DerivedData  string            `json:"derived_data,omitempty"`
 	Environment  map[string]string `json:"environment,omitempty"`
+	DeveloperDir string            `json:"developer_dir,omitempty"`
+	XcodeVersion string            `json:"xcode_version,omitempty"`
 	ExtraArgs    []string          `json:"extra_args,omitempty"`
+
+	// Repetitions and timeouts; timeouts are in seconds and TestTimeouts
+	// nil leaves the test plan's setting alone
+	TestIterations      int   `json:"test_iterations,omitempty"`
+	RetryTestsOnFailure bool  `json:"retry_tests_on_failure,omitempty"`
+	TestTimeouts        *bool `json:"test_timeouts_enabled,omitempty"`
+	DefaultTestTimeout  int   `json:"default_test_timeout,omitempty"`
+	MaximumTestTimeout  int   `json:"maximum_test_timeout,omitempty"`
+
+	// Coverage report detail (summary, files, functions or uncovered) and
+	// the files to report on; CoverageChangedSince adds the files changed
+	// since a git ref
+	CoverageReport       string   `json:"coverage_report,omitempty"`
+	CoverageFiles        []string `json:"coverage_files,omitempty"`
+	CoverageChangedSince string   `json:"coverage_changed_since,omitempty"`
 }
 
 type TestResult struct {
@@ -163,27 +181,61 @@ type TestBundle struct {
 	Duration  time.Duration `json:"duration,omitempty"`
 }
 
+// Coverage is the code coverage of a test run in percent. xccov measures
+// line coverage only, so BranchCoverage stays zero for Xcode runs.
 type Coverage struct {
-	LineCoverage   float64        `json:"line_coverage"`
-	BranchCoverage float64        `json:"branch_coverage"`
-	FilesCoverage  []FileCoverage `json:"files_coverage"`
+	LineCoverage    float64          `json:"line_coverage"`
+	BranchCoverage  float64          `json:"branch_coverage"`
+	CoveredLines    int              `json:"covered_lines,omitempty"`
+	ExecutableLines int              `json:"executable_lines,omitempty"`
+	Targets         []TargetCoverage `json:"targets,omitempty"`
+	FilesCoverage   []FileCoverage   `json:"files_coverage"`
+}
+
+type TargetCoverage struct {
+	Name            string  `json:"name"`
+	LineCoverage    float64 `json:"line_coverage"`
+	CoveredLines    int     `json:"covered_lines"`
+	ExecutableLines int     `json:"executable_lines"`
 }
 
+// FileCoverage is the coverage of one source file. TotalLines counts its
+// executable lines; UncoveredRanges are the executable lines that never ran.
 type FileCoverage struct {
-	Path           string  `json:"path"`
-	LineCoverage   float64 `json:"line_coverage"`
-	BranchCoverage float64 `json:"branch_coverage"`
-	CoveredLines   int     `json:"covered_lines"`
-	TotalLines     int     `json:"total_lines"`
+	Path            string             `json:"path"`
+	Target          string             `json:"target,omitempty"`
+	LineCoverage    float64            `json:"line_coverage"`
+	BranchCoverage  float64            `json:"branch_coverage"`
+	CoveredLines    int                `json:"covered_lines"`
+	TotalLines      int                `json:"total_lines"`
+	Functions       []FunctionCoverage `json:"functions,omitempty"`
+	UncoveredRanges []LineRange        `json:"uncovered_ranges,omitempty"`
+}
+
+type FunctionCoverage struct {
+	Name            string  `json:"name"`
+	Line            int     `json:"line"`
+	LineCoverage    float64 `json:"line_coverage"`
+	CoveredLines    int     `json:"covered_lines"`
+	ExecutableLines int     `json:"executable_lines"`
+	ExecutionCount  int     `json:"execution_count"`
+}
+
+// LineRange is an inclusive range of source lines
+type LineRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
 }
 
 type CleanParams struct {
-	ProjectPath string `json:"project_path,omitempty"`
-	Workspace   string `json:"workspace,omitempty"`
-	Project     string `json:"project,omitempty"`
-	Target      string `json:"target,omitempty"`
-	DerivedData string `json:"derived_data,omitempty"`
-	CleanBuild  bool   `json:"clean_build,omitempty"`
+	ProjectPath  string `json:"project_path,omitempty"`
+	Workspace    string `json:"workspace,omitempty"`
+	Project      string `json:"project,omitempty"`
+	Target       string `json:"target,omitempty"`
+	DerivedData  string `json:"derived_data,omitempty"`
+	CleanBuild   bool   `json:"clean_build,omitempty"`
+	DeveloperDir string `json:"developer_dir,omitempty"`
+	XcodeVersion string `json:"xcode_version,omitempty"`
 }
 
 type CleanResult struct {
@@ -195,6 +247,81 @@ type CleanResult struct {
 	ExitCode       int           `json:"exit_code"`
 }
 
+type ArchiveParams struct {
+	ProjectPath              string   `json:"project_path,omitempty"`
+	Workspace                string   `json:"workspace,omitempty"`
+	Project                  string   `json:"project,omitempty"`
+	Scheme                   string   `json:"scheme"`
+	Configuration            string   `json:"configuration,omitempty"`
+	Destination              string   `json:"destination,omitempty"`
+	ArchivePath              string   `json:"archive_path,omitempty"`
+	DerivedData              string   `json:"derived_data,omitempty"`
+	AllowProvisioningUpdates bool     `json:"allow_provisioning_updates,omitempty"`
+	OutputMode               string   `json:"output_mode,omitempty"`
+	DeveloperDir             string   `json:"developer_dir,omitempty"`
+	XcodeVersion             string   `json:"xcode_version,omitempty"`
+	ExtraArgs                []string `json:"extra_args,omitempty"`
+}
+
+// ArchiveResult reports an xcodebuild archive run. The bundle fields come
+// from the Info.plist of the archive and are empty when archiving failed.
+type ArchiveResult struct {
+	Success        bool           `json:"success"`
+	Duration       time.Duration  `json:"duration"`
+	FilteredOutput string         `json:"filtered_output"`
+	Errors         []BuildError   `json:"errors,omitempty"`
+	Warnings       []BuildWarning `json:"warnings,omitempty"`
+	ExitCode       int            `json:"exit_code"`
+	ArchivePath    string         `json:"archive_path"`
+	AppPath        string         `json:"app_path,omitempty"`
+	DSYMPaths      []string       `json:"dsym_paths,omitempty"`
+	BundleID       string         `json:"bundle_id,omitempty"`
+	Version        string         `json:"version,omitempty"`
+	BuildNumber    string         `json:"build_number,omitempty"`
+	TeamID         string         `json:"team_id,omitempty"`
+	LogURI         string         `json:"log_uri,omitempty"`
+}
+
+// ExportParams describes an xcodebuild -exportArchive run. Either
+// ExportOptionsPlist names an existing ExportOptions.plist, or one is
+// generated from the remaining export options.
+type ExportParams struct {
+	ArchivePath              string            `json:"archive_path"`
+	ExportPath               string            `json:"export_path,omitempty"`
+	ExportOptionsPlist       string            `json:"export_options_plist,omitempty"`
+	Method                   string            `json:"method,omitempty"`
+	TeamID                   string            `json:"team_id,omitempty"`
+	SigningStyle             string            `json:"signing_style,omitempty"`
+	SigningCertificate       string            `json:"signing_certificate,omitempty"`
+	ProvisioningProfiles     map[string]string `json:"provisioning_profiles,omitempty"`
+	Destination              string            `json:"destination,omitempty"`
+	UploadSymbols            *bool             `json:"upload_symbols,omitempty"`
+	AllowProvisioningUpdates bool              `json:"allow_provisioning_updates,omitempty"`
+	OutputMode               string            `json:"output_mode,omitempty"`
+	DeveloperDir             string            `json:"developer_dir,omitempty"`
+	XcodeVersion             string            `json:"xcode_version,omitempty"`
+	ExtraArgs                []string          `json:"extra_args,omitempty"`
+}
+
+type ExportResult struct {
+	Success           bool          `json:"success"`
+	Duration          time.Duration `json:"duration"`
+	FilteredOutput    string        `json:"filtered_output"`
+	Errors            []BuildError  `json:"errors,omitempty"`
+	ExitCode          int           `json:"exit_code"`
+	ArchivePath       string        `json:"archive_path"`
+	ExportPath        string        `json:"export_path"`
+	ExportOptionsPath string        `json:"export_options_path"`
+	Method            string        `json:"method,omitempty"`
+	IPAPath           string        `json:"ipa_path,omitempty"`
+	ExportedPaths     []string      `json:"exported_paths,omitempty"`
+	DSYMPaths         []string      `json:"dsym_paths,omitempty"`
+	BundleID          string        `json:"bundle_id,omitempty"`
+	Version           string        `json:"version,omitempty"`
+	BuildNumber       string        `json:"build_number,omitempty"`
+	LogURI            string        `json:"log_uri,omitempty"`
+}
+
 type ProjectDiscovery struct {
 	MaxDepth      int      `json:"max_depth,omitempty"`
 	IncludeHidden bool     `json:"include_hidden,omitempty"`
@@ -297,10 +424,19 @@ type SchemesListResult struct {
 }
 
 type SchemeInfo struct {
-	Name         string   `json:"name"`
-	ProjectPath  string   `json:"project_path"`
-	SharedScheme bool     `json:"shared_scheme"`
-	Targets      []string `json:"targets,omitempty"`
+	Name         string         `json:"name"`
+	ProjectPath  string         `json:"project_path"`
+	SharedScheme bool           `json:"shared_scheme"`
+	Targets      []string       `json:"targets,omitempty"`
+	TestPlans    []TestPlanInfo `json:"test_plans,omitempty"`
+}
+
+type TestPlanInfo struct {
+	Name           string   `json:"name"`
+	Path           string   `json:"path"`
+	Default        bool     `json:"default,omitempty"`
+	Configurations []string `json:"configurations,omitempty"`
+	TestTargets    []string `json:"test_targets,omitempty"`
 }
 
 type LogCaptureParams struct {
@@ -330,10 +466,14 @@ type LogEntry struct {
 }
 
 type ScreenshotParams struct {
-	UDID       string `json:"udid,omitempty"`
-	DeviceType string `json:"device_type,omitempty"`
-	OutputPath string `json:"output_path,omitempty"`
-	Format     string `json:"format,omitempty"`
+	UDID         string `json:"udid,omitempty"`
+	DeviceType   string `json:"device_type,omitempty"`
+	OutputPath   string `json:"output_path,omitempty"`
+	Format       string `json:"format,omitempty"`
+	IncludeImage bool   `json:"include_image,omitempty"`
+	MaxDimension int    `json:"max_dimension,omitempty"`
+	ImageFormat  string `json:"image_format,omitempty"`
+	JPEGQuality  int    `json:"jpeg_quality,omitempty"`
 }
 
 type ScreenshotResult struct {
@@ -342,6 +482,50 @@ type ScreenshotResult struct {
 	FilePath   string        `json:"file_path"`
 	FileSize   int64         `json:"file_size,omitempty"`
 	Dimensions string        `json:"dimensions,omitempty"`
+	// Size of the image embedded in the tool result after downscaling
+	ImageDimensions string `json:"image_dimensions,omitempty"`
+	ImageSize       int    `json:"image_size,omitempty"`
+}
+
+// ImageRegion is a rectangle in image pixels
+type ImageRegion struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+type ScreenshotCompareParams struct {
+	UDID            string        `json:"udid,omitempty"`
+	DeviceType      string        `json:"device_type,omitempty"`
+	BaselinePath    string        `json:"baseline_path"`
+	ActualPath      string        `json:"actual_path,omitempty"`
+	DiffPath        string        `json:"diff_path,omitempty"`
+	MaskPath        string        `json:"mask_path,omitempty"`
+	Tolerance       int           `json:"tolerance"`
+	MaxMismatch     float64       `json:"max_mismatch"`
+	IgnoreRegions   []ImageRegion `json:"ignore_regions,omitempty"`
+	IgnoreStatusBar bool          `json:"ignore_status_bar,omitempty"`
+	UpdateBaseline  bool          `json:"update_baseline,omitempty"`
+	IncludeImage    bool          `json:"include_image"`
+}
+
+// ScreenshotCompareResult reports a comparison against a baseline. Regions
+// are the bounding boxes of the changed areas, in pixels.
+type ScreenshotCompareResult struct {
+	Success         bool          `json:"success"`
+	Duration        time.Duration `json:"duration"`
+	Match           bool          `json:"match"`
+	BaselinePath    string        `json:"baseline_path"`
+	ActualPath      string        `json:"actual_path,omitempty"`
+	DiffPath        string        `json:"diff_path,omitempty"`
+	Width           int           `json:"width,omitempty"`
+	Height          int           `json:"height,omitempty"`
+	ChangedPixels   int           `json:"changed_pixels"`
+	ComparedPixels  int           `json:"compared_pixels"`
+	MismatchPercent float64       `json:"mismatch_percent"`
+	Regions         []ImageRegion `json:"regions,omitempty"`
+	BaselineUpdated bool          `json:"baseline_updated,omitempty"`
 }
 
 type UIDescribeParams struct {
@@ -355,7 +539,9 @@ type UIDescribeParams struct {
 type UIDescribeResult struct {
 	Success      bool          `json:"success"`
 	Duration     time.Duration `json:"duration"`
-	UIHierarchy  interface{}   `json:"ui_hierarchy"`
+	Backend      string        `json:"backend,omitempty"`
+	UIHierarchy  string        `json:"ui_hierarchy,omitempty"`
+	Root         *UIElement    `json:"root,omitempty"`
 	ElementCount int           `json:"element_count"`
 }
 
@@ -364,17 +550,76 @@ type UIInteractParams struct {
 	DeviceType  string                 `json:"device_type,omitempty"`
 	Action      string                 `json:"action"`
 	Target      string                 `json:"target,omitempty"`
+	Element     ElementQuery           `json:"element,omitempty"`
 	Coordinates []float64              `json:"coordinates,omitempty"`
 	Text        string                 `json:"text,omitempty"`
+	Direction   string                 `json:"direction,omitempty"`
+	MaxScrolls  int                    `json:"max_scrolls,omitempty"`
 	Timeout     int                    `json:"timeout,omitempty"`
 	Parameters  map[string]interface{} `json:"parameters,omitempty"`
 }
 
 type UIInteractResult struct {
-	Success  bool          `json:"success"`
-	Duration time.Duration `json:"duration"`
-	Output   string        `json:"output"`
-	Found    bool          `json:"found,omitempty"`
+	Success     bool          `json:"success"`
+	Duration    time.Duration `json:"duration"`
+	Output      string        `json:"output"`
+	Found       bool          `json:"found,omitempty"`
+	Element     *UIElement    `json:"element,omitempty"`
+	Matches     int           `json:"matches,omitempty"`
+	Coordinates []float64     `json:"coordinates,omitempty"`
+	Scrolls     int           `json:"scrolls,omitempty"`
+}
+
+type WaitForElementParams struct {
+	UDID       string       `json:"udid,omitempty"`
+	DeviceType string       `json:"device_type,omitempty"`
+	Element    ElementQuery `json:"element"`
+	Condition  string       `json:"condition,omitempty"`
+	Timeout    float64      `json:"timeout,omitempty"`
+}
+
+// WaitForElementResult reports how long the condition took to hold. Element
+// is the matched element at the last poll, also when the wait timed out.
+type WaitForElementResult struct {
+	Success   bool          `json:"success"`
+	Duration  time.Duration `json:"duration"`
+	Output    string        `json:"output,omitempty"`
+	Condition string        `json:"condition"`
+	Element   *UIElement    `json:"element,omitempty"`
+	Matches   int           `json:"matches"`
+	Polls     int           `json:"polls"`
+}
+
+type UIFlowParams struct {
+	UDID          string                   `json:"udid,omitempty"`
+	BundleID      string                   `json:"bundle_id,omitempty"`
+	ScreenshotDir string                   `json:"screenshot_dir,omitempty"`
+	IncludeImages bool                     `json:"include_images"`
+	Steps         []map[string]interface{} `json:"steps"`
+}
+
+// UIFlowStepResult reports one step of a UI flow. Status is passed, failed,
+// or skipped for the steps after a failure.
+type UIFlowStepResult struct {
+	Index      int                    `json:"index"`
+	Action     string                 `json:"action"`
+	Name       string                 `json:"name,omitempty"`
+	Status     string                 `json:"status"`
+	Duration   time.Duration          `json:"duration"`
+	Output     string                 `json:"output,omitempty"`
+	Element    *UIElement             `json:"element,omitempty"`
+	Screenshot string                 `json:"screenshot,omitempty"`
+	Error      map[string]interface{} `json:"error,omitempty"`
+}
+
+type UIFlowResult struct {
+	Success  bool               `json:"success"`
+	Duration time.Duration      `json:"duration"`
+	UDID     string             `json:"udid,omitempty"`
+	Steps    []UIFlowStepResult `json:"steps"`
+	Passed   int                `json:"passed"`
+	Failed   int                `json:"failed"`
+	Skipped  int                `json:"skipped"`
 }
 
 type AppInfoParams struct {
@@ -395,3 +640,87 @@ type AppInfoResult struct {
 	Entitlements map[string]interface{} `json:"entitlements,omitempty"`
 	IconPaths    []string               `json:"icon_paths,omitempty"`
 }
+
+// XCResultParams names a result bundle to inspect. AttachmentsDir, when set,
+// receives the exported test attachments and, with IncludeLogs, the
+// diagnostics logs.
+type XCResultParams struct {
+	Path           string `json:"path"`
+	AttachmentsDir string `json:"attachments_dir,omitempty"`
+	OnlyFailures   bool   `json:"only_failures,omitempty"`
+	IncludeLogs    bool   `json:"include_logs,omitempty"`
+}
+
+// XCResultResult describes a result bundle. Success reports whether the
+// recorded run passed; Duration is the time spent inspecting and
+// RunDuration the length of the recorded run.
+type XCResultResult struct {
+	Success       bool                 `json:"success"`
+	Duration      time.Duration        `json:"duration"`
+	Path          string               `json:"path"`
+	Title         string               `json:"title,omitempty"`
+	Result        string               `json:"result,omitempty"`
+	StartedAt     *time.Time           `json:"started_at,omitempty"`
+	FinishedAt    *time.Time           `json:"finished_at,omitempty"`
+	RunDuration   time.Duration        `json:"run_duration,omitempty"`
+	TestSummary   TestSummary          `json:"test_summary"`
+	Failures      []XCResultFailure    `json:"failures,omitempty"`
+	BuildErrors   []BuildError         `json:"build_errors,omitempty"`
+	BuildWarnings []BuildWarning       `json:"build_warnings,omitempty"`
+	Devices       []XCResultDevice     `json:"devices,omitempty"`
+	Attachments   []XCResultAttachment `json:"attachments,omitempty"`
+	LogsPath      string               `json:"logs_path,omitempty"`
+	Warnings      []string             `json:"warnings,omitempty"`
+}
+
+// XCResultFailure is a failed test with the source location of the failure
+type XCResultFailure struct {
+	Test    string `json:"test"`
+	Target  string `json:"target,omitempty"`
+	Message string `json:"message"`
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+}
+
+type XCResultDevice struct {
+	Name          string `json:"name"`
+	ID            string `json:"id,omitempty"`
+	Model         string `json:"model,omitempty"`
+	Platform      string `json:"platform,omitempty"`
+	OSVersion     string `json:"os_version,omitempty"`
+	Architecture  string `json:"architecture,omitempty"`
+	Configuration string `json:"configuration,omitempty"`
+	PassedTests   int    `json:"passed_tests"`
+	FailedTests   int    `json:"failed_tests"`
+	SkippedTests  int    `json:"skipped_tests"`
+}
+
+type XCResultAttachment struct {
+	Test      string     `json:"test"`
+	Name      string     `json:"name"`
+	Path      string     `json:"path"`
+	Failure   bool       `json:"failure,omitempty"`
+	Device    string     `json:"device,omitempty"`
+	Timestamp *time.Time `json:"timestamp,omitempty"`
+}
+
+// XcodeInstallation is an Xcode bundle found on disk. DeveloperDir is what
+// DEVELOPER_DIR is set to when a call selects it with xcode_version.
+type XcodeInstallation struct {
+	Path         string   `json:"path"`
+	DeveloperDir string   `json:"developer_dir"`
+	Version      string   `json:"version"`
+	Build        string   `json:"build"`
+	Beta         bool     `json:"beta"`
+	Selected     bool     `json:"selected"`
+	SDKs         []string `json:"sdks"`
+}
+
+// XcodeVersionsResult lists the installed Xcode versions, newest first.
+// SelectedDeveloperDir is the one xcode-select (or DEVELOPER_DIR) points
+// at, which may be the command line tools rather than an Xcode bundle.
+type XcodeVersionsResult struct {
+	Installations        []XcodeInstallation `json:"installations"`
+	SelectedDeveloperDir string              `json:"selected_developer_dir,omitempty"`
+	Duration             time.Duration       `json:"duration"`
+}
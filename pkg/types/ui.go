@@ -0,0 +1,178 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// UIFrame is an element's frame in screen points
+type UIFrame struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// Center returns the midpoint of the frame, where taps are aimed
+func (f UIFrame) Center() (float64, float64) {
+	return f.X + f.Width/2, f.Y + f.Height/2
+}
+
+// UIElement is one node of the accessibility hierarchy, normalized from the
+// backend's output
+type UIElement struct {
+	Type       string       `json:"type"`
+	Label      string       `json:"label,omitempty"`
+	Identifier string       `json:"identifier,omitempty"`
+	Value      string       `json:"value,omitempty"`
+	Frame      UIFrame      `json:"frame"`
+	Traits     []string     `json:"traits,omitempty"`
+	Enabled    bool         `json:"enabled"`
+	Children   []*UIElement `json:"children,omitempty"`
+}
+
+// HasTrait reports whether the element carries the named trait
+func (e *UIElement) HasTrait(trait string) bool {
+	for _, t := range e.Traits {
+		if t == trait {
+			return true
+		}
+	}
+	return false
+}
+
+// Walk visits e and its descendants depth-first, passing each element's depth
+// below e. Returning false from fn skips the element's children.
+func (e *UIElement) Walk(fn func(element *UIElement, depth int) bool) {
+	e.walk(fn, 0)
+}
+
+func (e *UIElement) walk(fn func(*UIElement, int) bool, depth int) {
+	if !fn(e, depth) {
+		return
+	}
+	for _, child := range e.Children {
+		child.walk(fn, depth+1)
+	}
+}
+
+// Count returns the number of elements in the tree rooted at e
+func (e *UIElement) Count() int {
+	count := 0
+	e.Walk(func(*UIElement, int) bool {
+		count++
+		return true
+	})
+	return count
+}
+
+// Prune returns a copy of the tree without elements deeper than maxDepth
+// below e. A maxDepth below zero keeps the whole tree.
+func (e *UIElement) Prune(maxDepth int) *UIElement {
+	pruned := *e
+	if maxDepth == 0 {
+		pruned.Children = nil
+		return &pruned
+	}
+
+	pruned.Children = make([]*UIElement, 0, len(e.Children))
+	for _, child := range e.Children {
+		pruned.Children = append(pruned.Children, child.Prune(maxDepth-1))
+	}
+	if len(pruned.Children) == 0 {
+		pruned.Children = nil
+	}
+	return &pruned
+}
+
+// ElementQuery selects elements from the hierarchy. Every field that is set
+// must match; string comparisons ignore case.
+type ElementQuery struct {
+	Identifier    string   `json:"identifier,omitempty"`
+	Label         string   `json:"label,omitempty"`
+	LabelContains string   `json:"label_contains,omitempty"`
+	Type          string   `json:"type,omitempty"`
+	Value         string   `json:"value,omitempty"`
+	Traits        []string `json:"traits,omitempty"`
+	Enabled       *bool    `json:"enabled,omitempty"`
+	// Index picks among several matches in depth-first order
+	Index int `json:"index,omitempty"`
+}
+
+// IsZero reports whether the query has no criteria
+func (q ElementQuery) IsZero() bool {
+	return q.Identifier == "" && q.Label == "" && q.LabelContains == "" && q.Type == "" &&
+		q.Value == "" && len(q.Traits) == 0 && q.Enabled == nil
+}
+
+// Matches reports whether e satisfies every criterion of the query
+func (q ElementQuery) Matches(e *UIElement) bool {
+	if q.Identifier != "" && !strings.EqualFold(e.Identifier, q.Identifier) {
+		return false
+	}
+	if q.Label != "" && !strings.EqualFold(e.Label, q.Label) {
+		return false
+	}
+	if q.LabelContains != "" && !strings.Contains(strings.ToLower(e.Label), strings.ToLower(q.LabelContains)) {
+		return false
+	}
+	if q.Type != "" && !strings.EqualFold(e.Type, q.Type) {
+		return false
+	}
+	if q.Value != "" && !strings.EqualFold(e.Value, q.Value) {
+		return false
+	}
+	for _, trait := range q.Traits {
+		if !e.HasTrait(trait) {
+			return false
+		}
+	}
+	if q.Enabled != nil && e.Enabled != *q.Enabled {
+		return false
+	}
+	return true
+}
+
+// String describes the query for error messages, e.g.
+// identifier="login.submit" type="Button"
+func (q ElementQuery) String() string {
+	var parts []string
+	add := func(name, value string) {
+		if value != "" {
+			parts = append(parts, fmt.Sprintf("%s=%q", name, value))
+		}
+	}
+	add("identifier", q.Identifier)
+	add("label", q.Label)
+	add("label_contains", q.LabelContains)
+	add("type", q.Type)
+	add("value", q.Value)
+	if len(q.Traits) > 0 {
+		parts = append(parts, "traits="+strings.Join(q.Traits, ","))
+	}
+	if q.Enabled != nil {
+		parts = append(parts, fmt.Sprintf("enabled=%t", *q.Enabled))
+	}
+	if q.Index > 0 {
+		parts = append(parts, fmt.Sprintf("index=%d", q.Index))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Find returns the elements under e, including e, that match the query, in
+// depth-first order
+func (e *UIElement) Find(q ElementQuery) []*UIElement {
+	var matches []*UIElement
+	e.Walk(func(element *UIElement, depth int) bool {
+		if q.Matches(element) {
+			matches = append(matches, element)
+		}
+		return true
+	})
+	return matches
+}
+
+// Contains reports whether the point lies inside the frame
+func (f UIFrame) Contains(x, y float64) bool {
+	return x >= f.X && x < f.X+f.Width && y >= f.Y && y < f.Y+f.Height
+}
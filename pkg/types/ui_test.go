@@ -0,0 +1,80 @@
+package types
+
+import "testing"
+
+func sampleTree() *UIElement {
+	return &UIElement{
+		Type:    "Application",
+		Frame:   UIFrame{Width: 390, Height: 844},
+		Enabled: true,
+		Children: []*UIElement{
+			{Type: "Button", Label: "Sign In", Identifier: "login.submit", Traits: []string{"button"}, Enabled: true,
+				Frame: UIFrame{X: 20, Y: 300, Width: 350, Height: 50}},
+			{Type: "Cell", Label: "Settings", Enabled: true, Children: []*UIElement{
+				{Type: "Switch", Label: "Wi-Fi", Value: "1", Enabled: false, Traits: []string{"notEnabled"}},
+			}},
+		},
+	}
+}
+
+func TestElementQuery_Matches(t *testing.T) {
+	root := sampleTree()
+	disabled := false
+
+	tests := []struct {
+		name  string
+		query ElementQuery
+		want  int
+	}{
+		{"identifier ignores case", ElementQuery{Identifier: "LOGIN.SUBMIT"}, 1},
+		{"exact label", ElementQuery{Label: "sign in"}, 1},
+		{"label substring", ElementQuery{LabelContains: "IN"}, 2},
+		{"type and value", ElementQuery{Type: "switch", Value: "1"}, 1},
+		{"trait", ElementQuery{Traits: []string{"button"}}, 1},
+		{"disabled", ElementQuery{Enabled: &disabled}, 1},
+		{"conflicting criteria", ElementQuery{Type: "Button", Label: "Settings"}, 0},
+		{"empty query matches all", ElementQuery{}, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := len(root.Find(tt.query)); got != tt.want {
+				t.Errorf("Find(%s) returned %d elements, want %d", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestElementQuery_String(t *testing.T) {
+	enabled := true
+	query := ElementQuery{Identifier: "a", Type: "Button", Traits: []string{"button", "selected"}, Enabled: &enabled, Index: 1}
+	want := `identifier="a" type="Button" traits=button,selected enabled=true index=1`
+	if got := query.String(); got != want {
+		t.Errorf("String() = %s, want %s", got, want)
+	}
+	if !(ElementQuery{Index: 3}).IsZero() {
+		t.Error("A query with only an index has no criteria")
+	}
+}
+
+func TestUIElement_PruneAndCount(t *testing.T) {
+	root := sampleTree()
+
+	if got := root.Count(); got != 4 {
+		t.Errorf("Count() = %d, want 4", got)
+	}
+	if got := root.Prune(1).Count(); got != 3 {
+		t.Errorf("Prune(1).Count() = %d, want 3", got)
+	}
+	if got := root.Prune(-1).Count(); got != 4 {
+		t.Errorf("Prune(-1) should keep the whole tree, got %d", got)
+	}
+	if root.Count() != 4 {
+		t.Error("Prune must not modify the original tree")
+	}
+
+	x, y := root.Children[0].Frame.Center()
+	if x != 195 || y != 325 || !root.Frame.Contains(x, y) {
+		t.Errorf("Unexpected center (%v, %v)", x, y)
+	}
+}